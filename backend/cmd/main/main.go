@@ -107,6 +107,11 @@ func main() {
 	requestLogger := appLogger.New(bootstrapLogger, serviceName)
 	ctx = context.WithValue(ctx, appLogger.LoggerKey, requestLogger)
 
+	// Возврат в свободные предложений с истекшим сроком принятия, напоминания и просрочка сроков сдачи отчетов
+	sweeperCtx, stopSweeper := context.WithCancel(ctx)
+	secretGuestService.StartAssignmentHoldSweeper(sweeperCtx)
+	secretGuestService.StartReportDeadlineSweeper(sweeperCtx)
+
 	// GATEWAY
 	gtw, err := gateway.New(ctx, cfg, authHandlers, secretGuestHandler)
 	if err != nil {
@@ -131,6 +136,7 @@ func main() {
 	runLogger.Info(ctx, "Shutting down gracefully...")
 
 	// Дожидаемся завершения всех фоновых задач, запущенных сервисом
+	stopSweeper()
 	secretGuestService.Wait()
 	runLogger.Info(ctx, "All background tasks finished.")
 
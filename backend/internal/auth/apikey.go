@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Формат ключа: sgk_<43 символа base64url>. Префикс нужен, чтобы ключ было легко узнать в логах и конфигурации.
+const (
+	apiKeyPrefix       = "sgk_"
+	apiKeySecretSize   = 32
+	apiKeyDisplayChars = 12 // сколько первых символов ключа хранится и показывается в списке ключей
+)
+
+// GenerateAPIKey возвращает новый ключ(показывается один раз), его отображаемый префикс и хеш для хранения
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	secret := make([]byte, apiKeySecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:apiKeyDisplayChars], HashAPIKey(key), nil
+}
+
+// HashAPIKey - ключи высокоэнтропийные, поэтому достаточно sha256 без соли(и возможен поиск по хешу)
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(key)))
+	return hex.EncodeToString(sum[:])
+}
+
+func isAPIKeyFormat(key string) bool {
+	return strings.HasPrefix(key, apiKeyPrefix) && len(key) > apiKeyDisplayChars
+}
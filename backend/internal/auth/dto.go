@@ -3,6 +3,7 @@ package auth
 import (
 	"net/mail"
 	"strings"
+	"time"
 
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
 )
@@ -14,9 +15,41 @@ type GenerateTokenRequest struct {
 	Password string `json:"password"`
 }
 
+// При включенной 2FA вместо пары токенов возвращается mfa_token для второго шага
 type GenerateTokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken           string `json:"access_token,omitempty"`
+	RefreshToken          string `json:"refresh_token,omitempty"`
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken              string `json:"mfa_token,omitempty"`
+
+	// Пароль был сброшен администратором: до смены пароля через /auth/password доступ к API закрыт
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+}
+
+///////////////
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code" example:"123456"` // код из приложения или код восстановления
+}
+
+type MFAChallengeEnrollRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type MFAEnrollResponse struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri" example:"otpauth://totp/Secret%20Guest:admin?secret=..."`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type MFAStatusResponse struct {
+	Enabled bool `json:"enabled"`
 }
 
 ///////////////
@@ -27,14 +60,31 @@ type ValidateTokenRequest struct {
 
 // ВНУТРЕННЯЯ DTO для MIDDLEWARE
 type ValidatedUserDTO struct {
-	UserID   string
-	Username string
-	RoleID   int
+	UserID      string
+	Username    string
+	RoleID      int
+	Permissions []string
+	APIKeyID    string // заполнен, если запрос аутентифицирован API-ключом
+
+	// Заполнены, если администратор вошел от имени пользователя
+	ImpersonatorID       string
+	ImpersonatorUsername string
+	ReadOnly             bool
 }
 
 type ValidateTokenResponse struct {
+	UserID      string   `json:"user_id"`
+	Username    string   `json:"username"`
+	Permissions []string `json:"permissions" example:"reports.view,reports.approve"`
+
+	// Присутствует только в сессии входа от имени пользователя - фронтенд показывает по нему предупреждение
+	ImpersonatedBy *ImpersonatorDTO `json:"impersonated_by,omitempty"`
+}
+
+type ImpersonatorDTO struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
+	ReadOnly bool   `json:"read_only"`
 }
 
 ///////////////
@@ -62,6 +112,41 @@ type RegisterUserResponse struct {
 
 ///////////////
 
+type OIDCLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"` // адрес страницы входа провайдера, на который нужно перенаправить пользователя
+	State            string `json:"state"`
+}
+
+// Параметры, с которыми провайдер вернул пользователя на OIDC_REDIRECT_URL
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+///////////////
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+///////////////
+
+type ImpersonateUserRequest struct {
+	Reason string `json:"reason" example:"Обращение в поддержку #1234: не отображается задание"` // обязательна, пишется в журнал действий
+	Write  bool   `json:"write"`                                                                 // разрешить изменяющие запросы; по умолчанию только чтение
+}
+
+type ImpersonateUserResponse struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	UserID      string    `json:"user_id"`
+	Username    string    `json:"username"`
+	ReadOnly    bool      `json:"read_only"`
+}
+
+///////////////
+
 func (d *RegisterUserRequest) Validate() error {
 	if strings.TrimSpace(d.Username) == "" {
 		return models.ErrInvalidUsername
@@ -86,6 +171,46 @@ func (d *GenerateTokenRequest) Validate() error {
 	return nil
 }
 
+func (d *VerifyMFARequest) Validate() error {
+	if strings.TrimSpace(d.MFAToken) == "" {
+		return models.ErrInvalidToken
+	}
+	if strings.TrimSpace(d.Code) == "" {
+		return models.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (d *OIDCCallbackRequest) Validate() error {
+	if strings.TrimSpace(d.State) == "" {
+		return models.ErrOIDCInvalidState
+	}
+	if strings.TrimSpace(d.Code) == "" {
+		return models.ErrOIDCAuthFailed
+	}
+	return nil
+}
+
+const maxImpersonationReasonLength = 500
+
+func (d *ImpersonateUserRequest) Validate() error {
+	reason := strings.TrimSpace(d.Reason)
+	if reason == "" || len([]rune(reason)) > maxImpersonationReasonLength {
+		return models.ErrInvalidImpersonationReason
+	}
+	return nil
+}
+
+func (d *ChangePasswordRequest) Validate() error {
+	if strings.TrimSpace(d.CurrentPassword) == "" || strings.TrimSpace(d.NewPassword) == "" {
+		return models.ErrInvalidPassword
+	}
+	if d.CurrentPassword == d.NewPassword {
+		return models.ErrInvalidPassword
+	}
+	return nil
+}
+
 ////////
 
 type ErrorResponse struct {
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
 	"go.uber.org/zap"
@@ -28,9 +30,11 @@ func NewAuthHandlers(service *AuthService) *AuthHandlers {
 // @Accept       json
 // @Produce      json
 // @Param        credentials body auth.GenerateTokenRequest true "User Credentials"
+// @Description If two-factor authentication is enabled (or required by policy), the response contains mfa_token instead of the token pair.
 // @Success      200 {object} auth.GenerateTokenResponse
 // @Failure      400 {object} auth.ErrorResponse "Invalid request body or validation error"
 // @Failure      401 {object} auth.ErrorResponse "Invalid username or password"
+// @Failure      403 {object} auth.ErrorResponse "User is blocked"
 // @Failure      500 {object} auth.ErrorResponse "Internal server error"
 // @Router       /auth/token [post]
 func (h *AuthHandlers) GenerateToken(w http.ResponseWriter, r *http.Request) {
@@ -58,7 +62,7 @@ func (h *AuthHandlers) GenerateToken(w http.ResponseWriter, r *http.Request) {
 
 // ValidateToken
 // @Summary Validate an access token
-// @Description Validates the provided JWT access token.
+// @Description Validates the provided JWT access token. For an impersonation token the response contains impersonated_by.
 // @Tags         auth
 // @Produce      json
 // @Security     BearerAuth
@@ -89,8 +93,16 @@ func (h *AuthHandlers) ValidateToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	publicResponse := &ValidateTokenResponse{
-		UserID:   validatedUser.UserID,
-		Username: validatedUser.Username,
+		UserID:      validatedUser.UserID,
+		Username:    validatedUser.Username,
+		Permissions: validatedUser.Permissions,
+	}
+	if validatedUser.ImpersonatorID != "" {
+		publicResponse.ImpersonatedBy = &ImpersonatorDTO{
+			UserID:   validatedUser.ImpersonatorID,
+			Username: validatedUser.ImpersonatorUsername,
+			ReadOnly: validatedUser.ReadOnly,
+		}
 	}
 
 	h.writeJSONResponse(ctx, w, http.StatusOK, publicResponse)
@@ -165,6 +177,348 @@ func (h *AuthHandlers) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	h.writeJSONResponse(ctx, w, http.StatusCreated, resp)
 }
 
+// ChangePassword
+// @Summary Change own password
+// @Description Changes the password of the current user. Also works when the password was reset by an administrator and other endpoints are locked.
+// @Security BearerAuth
+// @Tags         auth
+// @Accept       json
+// @Param Authorization header string true "Bearer Access Token"
+// @Param        input body auth.ChangePasswordRequest true "Current and new password"
+// @Success      204 "No Content"
+// @Failure      400 {object} auth.ErrorResponse "Invalid request body or validation error"
+// @Failure      401 {object} auth.ErrorResponse "Invalid token or current password"
+// @Failure      403 {object} auth.ErrorResponse "User is blocked"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/password [post]
+func (h *AuthHandlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	accessToken, err := extractBearerToken(r)
+	if err != nil {
+		log.Warn(ctx, "Failed to extract bearer token", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var dto ChangePasswordRequest
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		log.Warn(ctx, "Failed to decode change password request", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.ChangePassword(ctx, accessToken, dto); err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyMFA
+// @Summary Complete login with a two-factor authentication code
+// @Description Exchanges the MFA challenge token and a TOTP (or recovery) code for a token pair. If 2FA enrollment was pending, a valid TOTP code also confirms the enrollment.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input body auth.VerifyMFARequest true "MFA challenge token and code"
+// @Success      200 {object} auth.GenerateTokenResponse
+// @Failure      400 {object} auth.ErrorResponse "Invalid request body or 2FA is not enrolled"
+// @Failure      401 {object} auth.ErrorResponse "Invalid or expired challenge token, or invalid code"
+// @Failure      429 {object} auth.ErrorResponse "Too many invalid codes, code entry is locked"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/token/mfa [post]
+func (h *AuthHandlers) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var dto VerifyMFARequest
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		log.Warn(ctx, "Failed to decode verify MFA request", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tokenResp, err := h.service.VerifyMFA(ctx, dto)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, tokenResp)
+}
+
+// EnrollMFAWithChallenge
+// @Summary Start forced 2FA enrollment during login
+// @Description Generates a TOTP secret, provisioning URI and recovery codes using the MFA challenge token (when 2FA is required by policy but not yet enrolled). Finish with POST /auth/token/mfa.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input body auth.MFAChallengeEnrollRequest true "MFA challenge token"
+// @Success      200 {object} auth.MFAEnrollResponse
+// @Failure      400 {object} auth.ErrorResponse "Invalid request body"
+// @Failure      401 {object} auth.ErrorResponse "Invalid or expired challenge token"
+// @Failure      409 {object} auth.ErrorResponse "2FA is already enabled"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/token/mfa/enroll [post]
+func (h *AuthHandlers) EnrollMFAWithChallenge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var dto MFAChallengeEnrollRequest
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		log.Warn(ctx, "Failed to decode MFA enroll request", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	resp, err := h.service.EnrollMFAWithChallenge(ctx, dto)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, resp)
+}
+
+// GetMFAStatus
+// @Summary Get own 2FA status
+// @Security BearerAuth
+// @Tags         auth
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} auth.MFAStatusResponse
+// @Failure      401 {object} auth.ErrorResponse "Unauthorized"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/mfa [get]
+func (h *AuthHandlers) GetMFAStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := h.userFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.GetMFAStatus(ctx, user.ID)
+	if err != nil {
+		h.handleServiceError(w, r, err, user.Username)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, resp)
+}
+
+// EnrollMFA
+// @Summary Start 2FA enrollment
+// @Description Generates a TOTP secret, otpauth provisioning URI (to be shown as a QR code) and one-time recovery codes. 2FA becomes active after POST /auth/mfa/confirm.
+// @Security BearerAuth
+// @Tags         auth
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} auth.MFAEnrollResponse
+// @Failure      401 {object} auth.ErrorResponse "Unauthorized"
+// @Failure      409 {object} auth.ErrorResponse "2FA is already enabled"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/mfa/enroll [post]
+func (h *AuthHandlers) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user, ok := h.userFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.EnrollMFA(ctx, user.ID)
+	if err != nil {
+		h.handleServiceError(w, r, err, user.Username)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, resp)
+}
+
+// ConfirmMFA
+// @Summary Confirm 2FA enrollment
+// @Description Activates 2FA after checking the first TOTP code from the authenticator app.
+// @Security BearerAuth
+// @Tags         auth
+// @Accept       json
+// @Param Authorization header string true "Bearer Access Token"
+// @Param        input body auth.MFACodeRequest true "TOTP code"
+// @Success      204 "No Content"
+// @Failure      400 {object} auth.ErrorResponse "Invalid request body or 2FA is not enrolled"
+// @Failure      401 {object} auth.ErrorResponse "Unauthorized or invalid code"
+// @Failure      409 {object} auth.ErrorResponse "2FA is already enabled"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/mfa/confirm [post]
+func (h *AuthHandlers) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	user, ok := h.userFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	var dto MFACodeRequest
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		log.Warn(ctx, "Failed to decode MFA confirm request", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.ConfirmMFA(ctx, user.ID, dto); err != nil {
+		h.handleServiceError(w, r, err, user.Username)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableMFA
+// @Summary Disable 2FA
+// @Description Disables 2FA after checking a TOTP or recovery code. Not allowed when 2FA is required for the user's role.
+// @Security BearerAuth
+// @Tags         auth
+// @Accept       json
+// @Param Authorization header string true "Bearer Access Token"
+// @Param        input body auth.MFACodeRequest true "TOTP or recovery code"
+// @Success      204 "No Content"
+// @Failure      400 {object} auth.ErrorResponse "Invalid request body or 2FA is not enrolled"
+// @Failure      401 {object} auth.ErrorResponse "Unauthorized or invalid code"
+// @Failure      403 {object} auth.ErrorResponse "2FA is required by policy"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/mfa/disable [post]
+func (h *AuthHandlers) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	user, ok := h.userFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	var dto MFACodeRequest
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		log.Warn(ctx, "Failed to decode MFA disable request", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.DisableMFA(ctx, user.ID, dto); err != nil {
+		h.handleServiceError(w, r, err, user.Username)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartOIDCLogin
+// @Summary Start OpenID Connect login
+// @Description Creates a login state (state, nonce, PKCE verifier) and returns the identity provider authorization URL. After login the provider redirects to OIDC_REDIRECT_URL with code and state, which must be sent to POST /auth/oidc/callback.
+// @Tags         auth
+// @Produce      json
+// @Success      200 {object} auth.OIDCLoginResponse
+// @Failure      404 {object} auth.ErrorResponse "OpenID Connect login is not configured"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/oidc/login [get]
+func (h *AuthHandlers) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	resp, err := h.service.StartOIDCLogin(ctx)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, resp)
+}
+
+// CompleteOIDCLogin
+// @Summary Complete OpenID Connect login
+// @Description Exchanges the authorization code for an ID token, links the provider account to an existing user (by previous link or verified email) or registers a new guest, and returns the same response as POST /auth/token.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        input body auth.OIDCCallbackRequest true "Authorization code and state from the provider redirect"
+// @Success      200 {object} auth.GenerateTokenResponse
+// @Failure      400 {object} auth.ErrorResponse "Invalid request body, unknown or expired state"
+// @Failure      401 {object} auth.ErrorResponse "Authentication at the provider failed"
+// @Failure      403 {object} auth.ErrorResponse "User is blocked"
+// @Failure      404 {object} auth.ErrorResponse "OpenID Connect login is not configured"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /auth/oidc/callback [post]
+func (h *AuthHandlers) CompleteOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var dto OIDCCallbackRequest
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		log.Warn(ctx, "Failed to decode OIDC callback request", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tokenResp, err := h.service.CompleteOIDCLogin(ctx, dto)
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, tokenResp)
+}
+
+// ImpersonateUser
+// @Summary Impersonate a guest (Admin)
+// @Description Issues a short-lived access token of the guest for support purposes. The session is read-only unless write=true: mutating requests are rejected with 403. Responses in the session carry X-Impersonated-By and X-Impersonation-Mode headers, POST /auth/validate returns impersonated_by. Token issuance and every request in the session are written to the audit log under the administrator with the guest as the entity. The token cannot be refreshed and cannot be used to change password, 2FA settings or delete the account.
+// @Security BearerAuth
+// @Tags         Users (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "User ID (UUID)"
+// @Param        input body auth.ImpersonateUserRequest true "Reason and access mode"
+// @Param        Authorization header string true "Bearer Access Token"
+// @Success      200 {object} auth.ImpersonateUserResponse
+// @Failure      400 {object} auth.ErrorResponse "Invalid request body, user ID or missing reason"
+// @Failure      401 {object} auth.ErrorResponse "Unauthorized"
+// @Failure      403 {object} auth.ErrorResponse "Forbidden or the user is not an active guest"
+// @Failure      404 {object} auth.ErrorResponse "User not found"
+// @Failure      409 {object} auth.ErrorResponse "Cannot impersonate yourself"
+// @Failure      500 {object} auth.ErrorResponse "Internal server error"
+// @Router       /admin/users/{id}/impersonate [post]
+func (h *AuthHandlers) ImpersonateUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actor, ok := h.userFromContext(w, r)
+	if !ok {
+		return
+	}
+
+	targetID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	var dto ImpersonateUserRequest
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		log.Warn(ctx, "Failed to decode impersonation request", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	resp, err := h.service.ImpersonateUser(ctx, actor.ID, targetID, dto)
+	if err != nil {
+		h.handleServiceError(w, r, err, actor.Username)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, resp)
+}
+
 func (h *AuthHandlers) handleServiceError(w http.ResponseWriter, r *http.Request, err error, logInfo ...string) {
 	ctx := r.Context()
 	log := logger.GetLoggerFromCtx(ctx)
@@ -182,6 +536,12 @@ func (h *AuthHandlers) handleServiceError(w http.ResponseWriter, r *http.Request
 		errors.Is(err, models.ErrInvalidEmail):
 		log.Info(ctx, "Request validation failed", logFields...)
 		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrOIDCInvalidState):
+		log.Info(ctx, "Unknown or expired OIDC state", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrInvalidImpersonationReason):
+		log.Info(ctx, "Impersonation requested without a valid reason", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
 
 	// 401 Unauthorized - Ошибки аутентификации
 	case errors.Is(err, models.ErrInvalidCredentials):
@@ -190,11 +550,58 @@ func (h *AuthHandlers) handleServiceError(w http.ResponseWriter, r *http.Request
 	case errors.Is(err, models.ErrInvalidToken):
 		log.Info(ctx, "Invalid token provided", logFields...)
 		h.writeErrorResponse(ctx, w, http.StatusUnauthorized, "Invalid or expired token")
+	case errors.Is(err, models.ErrInvalidAPIKey):
+		log.Info(ctx, "Invalid API key provided", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, models.ErrInvalidMFACode):
+		log.Info(ctx, "Invalid two-factor code provided", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, models.ErrOIDCAuthFailed):
+		log.Info(ctx, "OIDC authentication failed", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusUnauthorized, err.Error())
+
+	// 404 Not Found - вход через OIDC не настроен или пользователь не найден
+	case errors.Is(err, models.ErrOIDCDisabled):
+		log.Info(ctx, "OIDC login requested but not configured", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrUserNotFound):
+		log.Info(ctx, "User not found", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, "User not found")
+
+	// 400 Bad Request - 2FA не настроена
+	case errors.Is(err, models.ErrMFANotEnrolled), errors.Is(err, models.ErrMFAEnrollmentRequired):
+		log.Info(ctx, "Two-factor authentication is not enrolled", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+
+	// 403 Forbidden - ограничения политики
+	case errors.Is(err, models.ErrMFARequiredByPolicy):
+		log.Info(ctx, "Two-factor authentication is required by policy", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrUserBlocked):
+		log.Info(ctx, "Blocked user access attempt", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrPasswordChangeRequired):
+		log.Info(ctx, "Password change required before accessing API", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusForbidden, "Password change required: use POST /auth/password")
+	case errors.Is(err, models.ErrImpersonationNotAllowed), errors.Is(err, models.ErrServiceAccount):
+		log.Info(ctx, "Impersonation is not allowed", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
 
 	// 409 Conflict - Конфликт ресурсов
 	case errors.Is(err, models.ErrUserExists), errors.Is(err, models.ErrEmailExists):
 		log.Warn(ctx, "User registration conflict", logFields...)
 		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrMFAAlreadyEnabled):
+		log.Info(ctx, "Two-factor authentication already enabled", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrCannotModifySelf):
+		log.Info(ctx, "Attempt to impersonate own account", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+
+	// 429 Too Many Requests - перебор одноразовых кодов
+	case errors.Is(err, models.ErrMFALocked):
+		log.Warn(ctx, "Two-factor code entry is locked", logFields...)
+		h.writeErrorResponse(ctx, w, http.StatusTooManyRequests, err.Error())
 
 	default:
 		log.Error(ctx, "Unexpected service error", logFields...)
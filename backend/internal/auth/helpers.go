@@ -25,6 +25,26 @@ func extractBearerToken(r *http.Request) (string, error) {
 	return strings.TrimSpace(parts[1]), nil
 }
 
+// extractAuthorization возвращает схему(Bearer или ApiKey) и учетные данные из заголовка Authorization
+func extractAuthorization(r *http.Request) (string, string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", "", models.ErrAuthHeaderMissing
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 {
+		return "", "", models.ErrAuthHeaderInvalid
+	}
+
+	scheme := strings.ToLower(parts[0])
+	if scheme != authSchemeBearer && scheme != authSchemeAPIKey {
+		return "", "", models.ErrAuthHeaderInvalid
+	}
+
+	return scheme, strings.TrimSpace(parts[1]), nil
+}
+
 func (h *AuthHandlers) writeJSONResponse(ctx context.Context, w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -53,8 +73,27 @@ func (h *AuthHandlers) decodeJSONBody(ctx context.Context, r *http.Request, v in
 	return nil
 }
 
+// claimsFromToken возвращает claims обычного токена; токены с назначением(2FA, вход от имени пользователя) отклоняются
 func (s *AuthService) claimsFromToken(ctx context.Context, tokenString string) (*JWTClaims, error) {
 	log := logger.GetLoggerFromCtx(ctx)
+
+	claims, err := s.parseAccessClaims(ctx, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// Промежуточный токен 2FA не дает доступа к API, а токен входа от имени пользователя нельзя обновить
+	// или использовать для управления учетной записью
+	if claims.Purpose != "" {
+		log.Warn(ctx, "Token validation failed: token has a restricted purpose", zap.String("purpose", claims.Purpose))
+		return nil, models.ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (s *AuthService) parseAccessClaims(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	log := logger.GetLoggerFromCtx(ctx)
 	token := strings.TrimSpace(tokenString)
 
 	if token == "" {
@@ -86,6 +125,23 @@ func (s *AuthService) claimsFromToken(ctx context.Context, tokenString string) (
 	return claims, nil
 }
 
+func (s *AuthService) mfaClaimsFromToken(ctx context.Context, tokenString string) (*JWTClaims, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	claims, err := s.JWTService.ValidateToken(strings.TrimSpace(tokenString))
+	if err != nil {
+		log.Warn(ctx, "MFA challenge token validation failed", zap.Error(err))
+		return nil, models.ErrInvalidToken
+	}
+
+	if claims.Purpose != TokenPurposeMFAChallenge || claims.UserID == "" {
+		log.Warn(ctx, "Token is not an MFA challenge token", zap.String("purpose", claims.Purpose))
+		return nil, models.ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
 func (h *AuthHandlers) writeErrorResponse(ctx context.Context, w http.ResponseWriter, statusCode int, message string) {
 	// log := logger.GetLoggerFromCtx(ctx)
 	// log.Info(ctx, message)
@@ -93,3 +149,27 @@ func (h *AuthHandlers) writeErrorResponse(ctx context.Context, w http.ResponseWr
 	response := ErrorResponse{Message: message}
 	h.writeJSONResponse(ctx, w, statusCode, response)
 }
+
+func (h *AuthHandlers) userFromContext(w http.ResponseWriter, r *http.Request) (AuthenticatedUser, bool) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	user, ok := ctx.Value(UserKey).(AuthenticatedUser)
+	if !ok {
+		log.Error(ctx, "Authenticated user not found in context")
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error: user context missing")
+		return AuthenticatedUser{}, false
+	}
+
+	// Настройки безопасности учетной записи недоступны администратору, вошедшему от имени пользователя
+	if user.IsImpersonated() {
+		log.Warn(ctx, "Account security action attempted in impersonation session",
+			zap.String("impersonator_id", user.ImpersonatorID),
+			zap.String("user_id", user.ID),
+		)
+		h.writeErrorResponse(ctx, w, http.StatusForbidden, models.ErrImpersonationRestricted.Error())
+		return AuthenticatedUser{}, false
+	}
+
+	return user, true
+}
@@ -15,10 +15,27 @@ type JWTService struct {
 	refreshLifetime int
 }
 
+// Назначение токена. Пустое значение - обычный access/refresh токен
+const (
+	TokenPurposeMFAChallenge  = "mfa_challenge"
+	TokenPurposeImpersonation = "impersonation"
+)
+
+// TokenActor - администратор, который действует от имени пользователя(claim "act", RFC 8693)
+type TokenActor struct {
+	UserID   string `json:"sub"`
+	Username string `json:"username"`
+}
+
 type JWTClaims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	RoleID   int    `json:"role_id"`
+	Purpose  string `json:"purpose,omitempty"`
+
+	// Только для токенов входа от имени пользователя
+	Actor *TokenActor `json:"act,omitempty"`
+	Write bool        `json:"imp_write,omitempty"` // разрешены изменяющие запросы
 
 	jwt.RegisteredClaims
 }
@@ -41,14 +58,59 @@ func (s *JWTService) GenerateTokens(user *models.User) (string, string, error) {
 	return accessToken, refreshToken, nil
 }
 
+// GenerateMFAChallengeToken выдает короткоживущий токен, который можно обменять на пару токенов
+// только после ввода одноразового кода
+func (s *JWTService) GenerateMFAChallengeToken(user *models.User, lifetime time.Duration) (string, error) {
+	return s.generateTokenWithPurpose(user, lifetime, TokenPurposeMFAChallenge)
+}
+
+// GenerateImpersonationToken выдает короткоживущий access-токен пользователя target с указанием администратора actor.
+// Токен нельзя обновить через /auth/refresh
+func (s *JWTService) GenerateImpersonationToken(target, actor *models.User, lifetime time.Duration, write bool) (string, time.Time, error) {
+	expiresAt := time.Now().Add(lifetime)
+
+	claims := JWTClaims{
+		UserID:   target.ID.String(),
+		Username: target.Username,
+		RoleID:   target.RoleID,
+		Purpose:  TokenPurposeImpersonation,
+		Actor: &TokenActor{
+			UserID:   actor.ID.String(),
+			Username: actor.Username,
+		},
+		Write: write,
+
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	signed, err := token.SignedString([]byte(s.secretKey))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signed, expiresAt, nil
+}
+
 func (s *JWTService) generateToken(user *models.User, expiryTime time.Duration) (string, error) {
+	return s.generateTokenWithPurpose(user, expiryTime, "")
+}
+
+func (s *JWTService) generateTokenWithPurpose(user *models.User, expiryTime time.Duration, purpose string) (string, error) {
 
 	claims := JWTClaims{
 		UserID:   user.ID.String(),
 		Username: user.Username,
 		RoleID:   user.RoleID,
+		Purpose:  purpose,
 
 		RegisteredClaims: jwt.RegisteredClaims{
+			// iat нужен, чтобы аннулировать промежуточные токены, выданные до блокировки ввода кодов
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiryTime)),
 		},
 	}
@@ -4,16 +4,44 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
 	"go.uber.org/zap"
 )
 
 type AuthenticatedUser struct {
-	ID       string
-	Username string
-	RoleID   int
+	ID          string
+	Username    string
+	RoleID      int
+	Permissions []string
+	APIKeyID    string // запрос от интеграции по API-ключу
+
+	// Администратор, вошедший от имени пользователя
+	ImpersonatorID       string
+	ImpersonatorUsername string
+	ReadOnly             bool
 }
 
+// IsImpersonated - запрос выполняет администратор от имени пользователя
+func (u AuthenticatedUser) IsImpersonated() bool {
+	return u.ImpersonatorID != ""
+}
+
+func (u AuthenticatedUser) HasPermission(permission string) bool {
+	for _, p := range u.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Схемы заголовка Authorization: JWT пользователя или API-ключ сервисной учетной записи
+const (
+	authSchemeBearer = "bearer"
+	authSchemeAPIKey = "apikey"
+)
+
 type userKey struct{}
 
 var UserKey = userKey{}
@@ -23,33 +51,114 @@ func (h *AuthHandlers) AuthMiddleware(next http.Handler) http.Handler {
 		ctx := r.Context()
 		log := logger.GetLoggerFromCtx(ctx)
 
-		accessToken, err := extractBearerToken(r)
+		scheme, credentials, err := extractAuthorization(r)
 		if err != nil {
 			log.Warn(ctx, "Failed to extract token", zap.Error(err))
 			h.writeErrorResponse(ctx, w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
-		dto := ValidateTokenRequest{AccessToken: accessToken}
-
-		validatedUser, err := h.service.ValidateToken(ctx, dto)
+		var validatedUser *ValidatedUserDTO
+		if scheme == authSchemeAPIKey {
+			validatedUser, err = h.service.ValidateAPIKey(ctx, credentials)
+		} else {
+			validatedUser, err = h.service.ValidateToken(ctx, ValidateTokenRequest{AccessToken: credentials})
+		}
 		if err != nil {
 			h.handleServiceError(w, r, err)
 			return
 		}
 
 		authUserInfo := AuthenticatedUser{
-			ID:       validatedUser.UserID,
-			Username: validatedUser.Username,
-			RoleID:   validatedUser.RoleID,
+			ID:                   validatedUser.UserID,
+			Username:             validatedUser.Username,
+			RoleID:               validatedUser.RoleID,
+			Permissions:          validatedUser.Permissions,
+			APIKeyID:             validatedUser.APIKeyID,
+			ImpersonatorID:       validatedUser.ImpersonatorID,
+			ImpersonatorUsername: validatedUser.ImpersonatorUsername,
+			ReadOnly:             validatedUser.ReadOnly,
 		}
 		ctxWithUser := context.WithValue(ctx, UserKey, authUserInfo)
 
+		if authUserInfo.IsImpersonated() {
+			h.serveImpersonated(w, r.WithContext(ctxWithUser), next, authUserInfo)
+			return
+		}
+
 		next.ServeHTTP(w, r.WithContext(ctxWithUser))
 	})
 }
 
-func (h *AuthHandlers) RoleRequiredMiddleware(allowedRoleIDs ...int) func(http.Handler) http.Handler {
+// Заголовки ответа в сессии входа от имени пользователя
+const (
+	headerImpersonatedBy    = "X-Impersonated-By"
+	headerImpersonationMode = "X-Impersonation-Mode"
+)
+
+// serveImpersonated помечает ответ заголовками, запрещает изменяющие запросы в сессии только для чтения
+// и пишет каждый запрос в журнал действий от имени администратора с указанием пользователя
+func (h *AuthHandlers) serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler, user AuthenticatedUser) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	mode := "write"
+	if user.ReadOnly {
+		mode = "read-only"
+	}
+	w.Header().Set(headerImpersonatedBy, user.ImpersonatorUsername)
+	w.Header().Set(headerImpersonationMode, mode)
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	if user.ReadOnly && !isSafeMethod(r.Method) {
+		h.writeErrorResponse(ctx, recorder, http.StatusForbidden, models.ErrImpersonationReadOnly.Error())
+	} else {
+		next.ServeHTTP(recorder, r)
+	}
+
+	log.Info(ctx, "Impersonated request",
+		zap.String("impersonator_id", user.ImpersonatorID),
+		zap.String("user_id", user.ID),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.Int("status", recorder.status),
+	)
+
+	// Запись в журнал не должна зависеть от отмены запроса клиентом
+	if err := h.service.RecordImpersonatedRequest(context.WithoutCancel(ctx), user, r.Method, r.URL.Path, recorder.status); err != nil {
+		log.Error(ctx, "Failed to record impersonated request", zap.Error(err),
+			zap.String("impersonator_id", user.ImpersonatorID),
+			zap.String("user_id", user.ID),
+		)
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// PermissionRequired пропускает запрос, если у роли пользователя есть хотя бы одно из указанных прав
+func (h *AuthHandlers) PermissionRequired(permissions ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -57,30 +166,25 @@ func (h *AuthHandlers) RoleRequiredMiddleware(allowedRoleIDs ...int) func(http.H
 
 			user, ok := ctx.Value(UserKey).(AuthenticatedUser)
 			if !ok {
-				log.Error(ctx, "Authenticated user not found in context for a role-protected route")
+				log.Error(ctx, "Authenticated user not found in context for a permission-protected route")
 				h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error: user context missing")
 				return
 			}
 
-			isAllowed := false
-			for _, allowedRoleID := range allowedRoleIDs {
-				if user.RoleID == allowedRoleID {
-					isAllowed = true
-					break
+			for _, permission := range permissions {
+				if user.HasPermission(permission) {
+					next.ServeHTTP(w, r)
+					return
 				}
 			}
 
-			if !isAllowed {
-				log.Warn(ctx, "Forbidden access attempt by user",
-					zap.String("user_id", user.ID),
-					zap.Int("user_role_id", user.RoleID),
-					zap.String("path", r.URL.Path),
-				)
-				h.writeErrorResponse(ctx, w, http.StatusForbidden, "Forbidden: You do not have the required permissions")
-				return
-			}
-
-			next.ServeHTTP(w, r)
+			log.Warn(ctx, "Forbidden access attempt by user",
+				zap.String("user_id", user.ID),
+				zap.Int("user_role_id", user.RoleID),
+				zap.Strings("required_permissions", permissions),
+				zap.String("path", r.URL.Path),
+			)
+			h.writeErrorResponse(ctx, w, http.StatusForbidden, "Forbidden: You do not have the required permissions")
 		})
 	}
 }
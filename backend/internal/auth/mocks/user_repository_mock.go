@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
@@ -33,3 +34,108 @@ func (m *UserRepository) RegisterUser(ctx context.Context, user *models.User) er
 	args := m.Called(ctx, user)
 	return args.Error(0)
 }
+
+func (m *UserRepository) UpdateUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	args := m.Called(ctx, userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *UserRepository) GetUserTOTP(ctx context.Context, userID uuid.UUID) (*models.UserTOTP, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserTOTP), args.Error(1)
+}
+
+func (m *UserRepository) SaveUserTOTP(ctx context.Context, totp *models.UserTOTP, recoveryCodeHashes []string) error {
+	args := m.Called(ctx, totp, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *UserRepository) EnableUserTOTP(ctx context.Context, userID uuid.UUID, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *UserRepository) MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) error {
+	args := m.Called(ctx, userID, step)
+	return args.Error(0)
+}
+
+func (m *UserRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	args := m.Called(ctx, userID, codeHash)
+	return args.Error(0)
+}
+
+func (m *UserRepository) DeleteUserTOTP(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *UserRepository) RegisterMFAFailure(ctx context.Context, userID uuid.UUID, now time.Time, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	args := m.Called(ctx, userID, now, maxAttempts, lockedUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepository) ResetMFAFailures(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *UserRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *UserRepository) TouchAPIKey(ctx context.Context, keyID uuid.UUID) error {
+	args := m.Called(ctx, keyID)
+	return args.Error(0)
+}
+
+func (m *UserRepository) CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error {
+	args := m.Called(ctx, state)
+	return args.Error(0)
+}
+
+func (m *UserRepository) ConsumeOIDCState(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	args := m.Called(ctx, state)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.OIDCLoginState), args.Error(1)
+}
+
+func (m *UserRepository) FindUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	args := m.Called(ctx, provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserRepository) LinkUserIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+func (m *UserRepository) RegisterOIDCUser(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	args := m.Called(ctx, user, identity)
+	return args.Error(0)
+}
+
+func (m *UserRepository) RecordAuditLogEntry(ctx context.Context, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCConfig - настройки клиента OpenID Connect(relying party)
+type OIDCConfig struct {
+	ProviderName  string
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	StateLifetime time.Duration
+}
+
+// OIDCClient реализует authorization code flow с PKCE(S256).
+// Discovery-документ и ключи провайдера загружаются при первом обращении и кешируются.
+type OIDCClient struct {
+	cfg        OIDCConfig
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCIDTokenClaims - используемые claims из id_token
+type OIDCIDTokenClaims struct {
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+
+	jwt.RegisteredClaims
+}
+
+func NewOIDCClient(cfg OIDCConfig, httpClient *http.Client) *OIDCClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid"}
+	}
+	return &OIDCClient{cfg: cfg, httpClient: httpClient}
+}
+
+func (c *OIDCClient) ProviderName() string {
+	return c.cfg.ProviderName
+}
+
+// AuthorizationURL формирует адрес страницы входа провайдера
+func (c *OIDCClient) AuthorizationURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	discovery, err := c.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.cfg.ClientID)
+	params.Set("redirect_uri", c.cfg.RedirectURL)
+	params.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", pkceChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return discovery.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange обменивает authorization code на id_token
+func (c *OIDCClient) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	discovery, err := c.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if c.cfg.ClientSecret != "" {
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("token response does not contain id_token")
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// VerifyIDToken проверяет подпись(RS256, ключи из jwks_uri), issuer, audience, срок действия и nonce
+func (c *OIDCClient) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OIDCIDTokenClaims, error) {
+	discovery, err := c.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &OIDCIDTokenClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return c.getKey(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("id_token validation failed: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("id_token has empty subject")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	return claims, nil
+}
+
+func (c *OIDCClient) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	issuer := strings.TrimRight(c.cfg.IssuerURL, "/")
+	var discovery oidcDiscovery
+	if err := c.getJSON(ctx, issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to load OIDC discovery document: %w", err)
+	}
+
+	if strings.TrimRight(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("OIDC discovery issuer mismatch: %q", discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("OIDC discovery document is incomplete")
+	}
+
+	c.discovery = &discovery
+	return c.discovery, nil
+}
+
+// getKey возвращает ключ по kid; при неизвестном kid ключи перезагружаются(ротация у провайдера)
+func (c *OIDCClient) getKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.keys[kid]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	discovery, err := c.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := c.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to load OIDC keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := parseRSAPublicKey(k.N, k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	key, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("OIDC signing key %q not found", kid)
+	}
+	return key, nil
+}
+
+func (c *OIDCClient) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func parseRSAPublicKey(n, e string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(eBytes)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(nBytes), E: int(exponent.Int64())}, nil
+}
+
+// randomURLToken - случайная строка для state, nonce и PKCE code_verifier(43 символа base64url)
+func randomURLToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func pkceChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
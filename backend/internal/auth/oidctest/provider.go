@@ -0,0 +1,256 @@
+// Package oidctest - локальный провайдер OpenID Connect для тестов и локальной разработки.
+// Поддерживает discovery, JWKS, authorization code с PKCE(S256) и подписывает id_token ключом RS256.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "oidctest-key"
+
+// Identity - учетная запись пользователя у провайдера
+type Identity struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+type pendingCode struct {
+	identity      Identity
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+type Provider struct {
+	Server   *httptest.Server
+	ClientID string
+
+	// DefaultIdentity используется страницей /authorize, которая сразу подтверждает вход
+	DefaultIdentity Identity
+
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]pendingCode
+}
+
+func NewProvider(clientID string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+	}
+
+	p := &Provider{
+		ClientID: clientID,
+		DefaultIdentity: Identity{
+			Subject:           "test-subject",
+			Email:             "guest@example.com",
+			EmailVerified:     true,
+			PreferredUsername: "guest",
+		},
+		key:   key,
+		codes: make(map[string]pendingCode),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/jwks", p.handleJWKS)
+	mux.HandleFunc("/authorize", p.handleAuthorize)
+	mux.HandleFunc("/token", p.handleToken)
+	p.Server = httptest.NewServer(mux)
+
+	return p, nil
+}
+
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+// Authorize имитирует успешный вход пользователя на странице провайдера по адресу из AuthorizationURL
+// и возвращает authorization code
+func (p *Provider) Authorize(authorizationURL string, identity Identity) (string, error) {
+	u, err := url.Parse(authorizationURL)
+	if err != nil {
+		return "", err
+	}
+	return p.issueCode(u.Query(), identity)
+}
+
+// SignIDToken подписывает произвольные claims ключом провайдера
+func (p *Provider) SignIDToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(p.key)
+}
+
+func (p *Provider) issueCode(query url.Values, identity Identity) (string, error) {
+	if query.Get("response_type") != "code" {
+		return "", errors.New("unsupported response_type")
+	}
+	if query.Get("client_id") != p.ClientID {
+		return "", errors.New("unknown client_id")
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		return "", errors.New("PKCE S256 code challenge is required")
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = pendingCode{
+		identity:      identity,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		redirectURI:   query.Get("redirect_uri"),
+	}
+	p.mu.Unlock()
+
+	return code, nil
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	code, err := p.issueCode(query, p.DefaultIdentity)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	pending, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+
+	switch {
+	case r.PostForm.Get("grant_type") != "authorization_code", !ok:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case r.PostForm.Get("client_id") != p.ClientID:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	case r.PostForm.Get("redirect_uri") != pending.redirectURI:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != pending.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer(),
+		"aud":            p.ClientID,
+		"sub":            pending.identity.Subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          pending.nonce,
+		"email_verified": pending.identity.EmailVerified,
+	}
+	if pending.identity.Email != "" {
+		claims["email"] = pending.identity.Email
+	}
+	if pending.identity.PreferredUsername != "" {
+		claims["preferred_username"] = pending.identity.PreferredUsername
+	}
+	if pending.identity.Name != "" {
+		claims["name"] = pending.identity.Name
+	}
+
+	idToken, err := p.SignIDToken(claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	_, _ = rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -22,30 +23,57 @@ func NewUserRepository(db *pgxpool.Pool) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) FindUserByUsername(ctx context.Context, username string) (*models.User, error) {
-	log := logger.GetLoggerFromCtx(ctx)
-
-	var user models.User
-
-	query := `
+// userSelectQuery - общая выборка пользователя вместе с правами его роли; условие WHERE добавляется вызывающим кодом
+const userSelectQuery = `
 		SELECT 
 			u.id, 
 			u.username,
-			u.email,
+			COALESCE(u.email, ''),
 			u.password_hash,
 			u.role_id,
-			u.created_at
+			u.created_at,
+			EXISTS (SELECT 1 FROM user_totp t WHERE t.user_id = u.id AND t.enabled) AS mfa_enabled,
+			u.blocked_at,
+			u.blocked_reason,
+			u.password_change_required,
+			u.is_service_account,
+			ARRAY(
+				SELECT p.slug
+				FROM role_permissions rp
+				JOIN permissions p ON p.id = rp.permission_id
+				WHERE rp.role_id = u.role_id
+				ORDER BY p.slug
+			) AS permissions
 		FROM users u
-		WHERE u.username = $1
-	`
-	err := r.db.QueryRow(ctx, query, username).Scan(
+`
+
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
 		&user.RoleID,
 		&user.CreatedAt,
+		&user.MFAEnabled,
+		&user.BlockedAt,
+		&user.BlockedReason,
+		&user.PasswordChangeRequired,
+		&user.IsServiceAccount,
+		&user.Permissions,
 	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) FindUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := userSelectQuery + `WHERE u.username = $1`
+	user, err := scanUser(r.db.QueryRow(ctx, query, username))
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -64,33 +92,14 @@ func (r *UserRepository) FindUserByUsername(ctx context.Context, username string
 		return nil, models.ErrDataBaseQuery
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 func (r *UserRepository) FindUserByID(ctx context.Context, userId uuid.UUID) (*models.User, error) {
 	log := logger.GetLoggerFromCtx(ctx)
 
-	var user models.User
-
-	query := `
-		SELECT 
-			u.id, 
-			u.username,
-			u.email,
-			u.password_hash,
-			u.role_id,
-			u.created_at
-		FROM users u
-		WHERE u.id = $1
-	`
-	err := r.db.QueryRow(ctx, query, userId).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.RoleID,
-		&user.CreatedAt,
-	)
+	query := userSelectQuery + `WHERE u.id = $1`
+	user, err := scanUser(r.db.QueryRow(ctx, query, userId))
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -109,7 +118,7 @@ func (r *UserRepository) FindUserByID(ctx context.Context, userId uuid.UUID) (*m
 		return nil, models.ErrDataBaseQuery
 	}
 
-	return &user, nil
+	return user, nil
 }
 
 func (r *UserRepository) RegisterUser(ctx context.Context, user *models.User) error {
@@ -168,3 +177,471 @@ func (r *UserRepository) RegisterUser(ctx context.Context, user *models.User) er
 
 	return nil
 }
+
+func (r *UserRepository) GetUserTOTP(ctx context.Context, userID uuid.UUID) (*models.UserTOTP, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var totp models.UserTOTP
+
+	query := `
+		SELECT user_id, secret, enabled, created_at, confirmed_at, last_used_step,
+			failed_attempts, locked_until, challenges_valid_after
+		FROM user_totp
+		WHERE user_id = $1
+	`
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&totp.UserID,
+		&totp.Secret,
+		&totp.Enabled,
+		&totp.CreatedAt,
+		&totp.ConfirmedAt,
+		&totp.LastUsedStep,
+		&totp.FailedAttempts,
+		&totp.LockedUntil,
+		&totp.ChallengesValidAfter,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		log.Error(ctx, "Database query error on GetUserTOTP", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, models.ErrDataBaseQuery
+	}
+
+	return &totp, nil
+}
+
+// SaveUserTOTP сохраняет неподтвержденный секрет и заменяет коды восстановления
+func (r *UserRepository) SaveUserTOTP(ctx context.Context, totp *models.UserTOTP, recoveryCodeHashes []string) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return models.ErrDataBaseQuery
+	}
+	defer tx.Rollback(ctx)
+
+	upsertQuery := `
+		INSERT INTO user_totp (user_id, secret, enabled, created_at, confirmed_at, last_used_step)
+		VALUES ($1, $2, false, NOW(), NULL, NULL)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret,
+			created_at = EXCLUDED.created_at,
+			confirmed_at = NULL,
+			last_used_step = NULL
+		WHERE user_totp.enabled = false
+	`
+	tag, err := tx.Exec(ctx, upsertQuery, totp.UserID, totp.Secret)
+	if err != nil {
+		log.Error(ctx, "DB error on user TOTP upsert", zap.Error(err), zap.String("user_id", totp.UserID.String()))
+		return models.ErrDataBaseQuery
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrMFAAlreadyEnabled
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, totp.UserID); err != nil {
+		log.Error(ctx, "DB error on recovery codes delete", zap.Error(err), zap.String("user_id", totp.UserID.String()))
+		return models.ErrDataBaseQuery
+	}
+
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, totp.UserID, hash); err != nil {
+			log.Error(ctx, "DB error on recovery code insert", zap.Error(err), zap.String("user_id", totp.UserID.String()))
+			return models.ErrDataBaseQuery
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(ctx, "Failed to commit transaction", zap.Error(err))
+		return models.ErrDataBaseQuery
+	}
+
+	return nil
+}
+
+func (r *UserRepository) EnableUserTOTP(ctx context.Context, userID uuid.UUID, step int64) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		UPDATE user_totp
+		SET enabled = true, confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND enabled = false
+	`
+	tag, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		log.Error(ctx, "DB error on EnableUserTOTP", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrDataBaseQuery
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// MarkTOTPStepUsed запоминает использованный шаг; повторное использование того же кода отклоняется
+func (r *UserRepository) MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)
+	`
+	tag, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		log.Error(ctx, "DB error on MarkTOTPStepUsed", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrDataBaseQuery
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// RegisterMFAFailure засчитывает неверный одноразовый код. На maxAttempts-й ошибке подряд ввод кодов блокируется
+// до lockedUntil, счетчик сбрасывается, а промежуточные токены, выданные до now, аннулируются. Возвращает true,
+// если этот код привел к блокировке
+func (r *UserRepository) RegisterMFAFailure(ctx context.Context, userID uuid.UUID, now time.Time, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		UPDATE user_totp
+		SET
+			failed_attempts        = CASE WHEN failed_attempts + 1 >= $3 THEN 0 ELSE failed_attempts + 1 END,
+			locked_until           = CASE WHEN failed_attempts + 1 >= $3 THEN $4 ELSE locked_until END,
+			challenges_valid_after = CASE WHEN failed_attempts + 1 >= $3 THEN $2 ELSE challenges_valid_after END
+		WHERE user_id = $1
+		RETURNING failed_attempts = 0
+	`
+	var locked bool
+	err := r.db.QueryRow(ctx, query, userID, now, maxAttempts, lockedUntil).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, models.ErrNotFound
+		}
+		log.Error(ctx, "DB error on RegisterMFAFailure", zap.Error(err), zap.String("user_id", userID.String()))
+		return false, models.ErrDataBaseQuery
+	}
+
+	return locked, nil
+}
+
+// ResetMFAFailures сбрасывает счетчик неверных кодов после успешного ввода
+func (r *UserRepository) ResetMFAFailures(ctx context.Context, userID uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if _, err := r.db.Exec(ctx, `UPDATE user_totp SET failed_attempts = 0 WHERE user_id = $1`, userID); err != nil {
+		log.Error(ctx, "DB error on ResetMFAFailures", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrDataBaseQuery
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		log.Error(ctx, "DB error on UseRecoveryCode", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrDataBaseQuery
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) DeleteUserTOTP(ctx context.Context, userID uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return models.ErrDataBaseQuery
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		log.Error(ctx, "DB error on recovery codes delete", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrDataBaseQuery
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID); err != nil {
+		log.Error(ctx, "DB error on user TOTP delete", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrDataBaseQuery
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(ctx, "Failed to commit transaction", zap.Error(err))
+		return models.ErrDataBaseQuery
+	}
+
+	return nil
+}
+
+// UpdateUserPassword устанавливает новый пароль и снимает признак обязательной смены пароля
+func (r *UserRepository) UpdateUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		UPDATE users
+		SET password_hash = $2, password_change_required = false
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, userID, passwordHash)
+	if err != nil {
+		log.Error(ctx, "DB error on UpdateUserPassword", zap.Error(err), zap.String("user_id", userID.String()))
+		return models.ErrDataBaseQuery
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *UserRepository) FindAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var key models.APIKey
+
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, created_by, created_at, expires_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE key_hash = $1
+	`
+	err := r.db.QueryRow(ctx, query, keyHash).Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.CreatedBy,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrAPIKeyNotFound
+		}
+		log.Error(ctx, "Database query error on FindAPIKeyByHash", zap.Error(err))
+		return nil, models.ErrDataBaseQuery
+	}
+
+	return &key, nil
+}
+
+// TouchAPIKey обновляет время последнего использования не чаще раза в минуту, чтобы не писать в БД на каждый запрос
+func (r *UserRepository) TouchAPIKey(ctx context.Context, keyID uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		UPDATE api_keys
+		SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		  AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`
+	if _, err := r.db.Exec(ctx, query, keyID); err != nil {
+		log.Error(ctx, "DB error on TouchAPIKey", zap.Error(err), zap.String("api_key_id", keyID.String()))
+		return models.ErrDataBaseQuery
+	}
+
+	return nil
+}
+
+/////////////// OpenID Connect
+
+// CreateOIDCState сохраняет параметры начатого входа и попутно удаляет просроченные
+func (r *UserRepository) CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if _, err := r.db.Exec(ctx, `DELETE FROM oidc_login_states WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		log.Warn(ctx, "Failed to delete expired OIDC login states", zap.Error(err))
+	}
+
+	query := `
+		INSERT INTO oidc_login_states (state, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := r.db.Exec(ctx, query, state.State, state.Nonce, state.CodeVerifier, state.ExpiresAt); err != nil {
+		log.Error(ctx, "DB error on CreateOIDCState", zap.Error(err))
+		return models.ErrDataBaseQuery
+	}
+
+	return nil
+}
+
+// ConsumeOIDCState возвращает и удаляет state, поэтому один и тот же callback нельзя обработать дважды
+func (r *UserRepository) ConsumeOIDCState(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var loginState models.OIDCLoginState
+
+	query := `
+		DELETE FROM oidc_login_states
+		WHERE state = $1
+		RETURNING state, nonce, code_verifier, created_at, expires_at
+	`
+	err := r.db.QueryRow(ctx, query, state).Scan(
+		&loginState.State,
+		&loginState.Nonce,
+		&loginState.CodeVerifier,
+		&loginState.CreatedAt,
+		&loginState.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		log.Error(ctx, "DB error on ConsumeOIDCState", zap.Error(err))
+		return nil, models.ErrDataBaseQuery
+	}
+
+	return &loginState, nil
+}
+
+func (r *UserRepository) FindUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := userSelectQuery + `
+		JOIN user_identities ui ON ui.user_id = u.id
+		WHERE ui.provider = $1 AND ui.subject = $2
+	`
+	user, err := scanUser(r.db.QueryRow(ctx, query, provider, subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrUserNotFound
+		}
+		log.Error(ctx, "Database query error on FindUserByIdentity", zap.Error(err), zap.String("provider", provider))
+		return nil, models.ErrDataBaseQuery
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := userSelectQuery + `WHERE lower(u.email) = lower($1)`
+	user, err := scanUser(r.db.QueryRow(ctx, query, email))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrUserNotFound
+		}
+		log.Error(ctx, "Database query error on FindUserByEmail", zap.Error(err))
+		return nil, models.ErrDataBaseQuery
+	}
+
+	return user, nil
+}
+
+// LinkUserIdentity привязывает внешнюю учетную запись к пользователю или обновляет время последнего входа по ней
+func (r *UserRepository) LinkUserIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		INSERT INTO user_identities (id, user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (provider, subject) DO UPDATE
+		SET email = EXCLUDED.email,
+			last_login_at = EXCLUDED.last_login_at
+		WHERE user_identities.user_id = EXCLUDED.user_id
+	`
+	tag, err := r.db.Exec(ctx, query, identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		log.Error(ctx, "DB error on LinkUserIdentity", zap.Error(err), zap.String("user_id", identity.UserID.String()))
+		return models.ErrDataBaseQuery
+	}
+	if tag.RowsAffected() == 0 {
+		// subject уже привязан к другому пользователю
+		return models.ErrIdentityAlreadyLinked
+	}
+
+	return nil
+}
+
+// RegisterOIDCUser создает пользователя без пароля вместе с профилем и привязкой внешней учетной записи
+func (r *UserRepository) RegisterOIDCUser(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return models.ErrDataBaseQuery
+	}
+	defer tx.Rollback(ctx)
+
+	userQuery := `
+		INSERT INTO users (id, username, email, password_hash, role_id)
+		VALUES ($1, $2, NULLIF($3, ''), '!', $4)
+	`
+	if _, err := tx.Exec(ctx, userQuery, user.ID, user.Username, user.Email, user.RoleID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			if strings.Contains(pgErr.ConstraintName, "users_username_key") {
+				return models.ErrUserExists
+			}
+			if strings.Contains(pgErr.ConstraintName, "users_email_key") {
+				return models.ErrEmailExists
+			}
+		}
+		log.Error(ctx, "DB error on OIDC user insert", zap.Error(err), zap.String("username", user.Username))
+		return models.ErrDataBaseQuery
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO user_profiles (user_id) VALUES ($1)`, user.ID); err != nil {
+		log.Error(ctx, "DB error on profile insert", zap.Error(err), zap.String("user_id", user.ID.String()))
+		return models.ErrDataBaseQuery
+	}
+
+	identityQuery := `
+		INSERT INTO user_identities (id, user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
+	`
+	if _, err := tx.Exec(ctx, identityQuery, identity.ID, user.ID, identity.Provider, identity.Subject, identity.Email); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.ErrIdentityAlreadyLinked
+		}
+		log.Error(ctx, "DB error on user identity insert", zap.Error(err), zap.String("user_id", user.ID.String()))
+		return models.ErrDataBaseQuery
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Error(ctx, "Failed to commit transaction", zap.Error(err))
+		return models.ErrDataBaseQuery
+	}
+
+	return nil
+}
+
+/////////////// Журнал действий
+
+func (r *UserRepository) RecordAuditLogEntry(ctx context.Context, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, entity_type, entity_id, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := r.db.Exec(ctx, query, entry.ActorID, entry.Action, entry.EntityType, entry.EntityID, entry.Details); err != nil {
+		log.Error(ctx, "DB error on inserting audit log entry", zap.Error(err), zap.String("action", entry.Action))
+		return models.ErrDataBaseQuery
+	}
+
+	return nil
+}
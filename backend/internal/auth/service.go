@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/config"
@@ -17,11 +20,58 @@ type UserRepository interface {
 	FindUserByUsername(ctx context.Context, username string) (*models.User, error)
 	FindUserByID(ctx context.Context, userId uuid.UUID) (*models.User, error)
 	RegisterUser(ctx context.Context, user *models.User) error
+	UpdateUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+
+	// 2FA
+	GetUserTOTP(ctx context.Context, userID uuid.UUID) (*models.UserTOTP, error)
+	SaveUserTOTP(ctx context.Context, totp *models.UserTOTP, recoveryCodeHashes []string) error
+	EnableUserTOTP(ctx context.Context, userID uuid.UUID, step int64) error
+	MarkTOTPStepUsed(ctx context.Context, userID uuid.UUID, step int64) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+	DeleteUserTOTP(ctx context.Context, userID uuid.UUID) error
+	RegisterMFAFailure(ctx context.Context, userID uuid.UUID, now time.Time, maxAttempts int, lockedUntil time.Time) (bool, error)
+	ResetMFAFailures(ctx context.Context, userID uuid.UUID) error
+
+	// API-ключи
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	TouchAPIKey(ctx context.Context, keyID uuid.UUID) error
+
+	// OpenID Connect
+	CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error
+	ConsumeOIDCState(ctx context.Context, state string) (*models.OIDCLoginState, error)
+	FindUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (*models.User, error)
+	LinkUserIdentity(ctx context.Context, identity *models.UserIdentity) error
+	RegisterOIDCUser(ctx context.Context, user *models.User, identity *models.UserIdentity) error
+
+	// Журнал действий
+	RecordAuditLogEntry(ctx context.Context, entry *models.AuditLogEntry) error
 }
 
+// MFAPolicy - политика двухфакторной аутентификации
+type MFAPolicy struct {
+	RequiredForStaff  bool
+	ChallengeLifetime time.Duration
+	Issuer            string
+	// Сколько неверных кодов подряд допускается до блокировки ввода кодов на LockoutDuration
+	MaxFailedAttempts int
+	LockoutDuration   time.Duration
+}
+
+const (
+	defaultMFAChallengeLifetime  = 5 * time.Minute
+	defaultMFAMaxFailedAttempts  = 5
+	defaultMFALockoutDuration    = 15 * time.Minute
+	defaultTOTPIssuer            = "Secret Guest"
+	defaultImpersonationLifetime = 15 * time.Minute
+)
+
 type AuthService struct {
-	Repo       UserRepository
-	JWTService *JWTService
+	Repo                  UserRepository
+	JWTService            *JWTService
+	MFA                   MFAPolicy
+	OIDC                  *OIDCClient // nil, если вход через OIDC не настроен
+	ImpersonationLifetime time.Duration
 }
 
 func NewAuthService(cfg *config.Config, repo UserRepository) (*AuthService, error) {
@@ -36,9 +86,31 @@ func NewAuthService(cfg *config.Config, repo UserRepository) (*AuthService, erro
 
 	jwtService := NewJWTService(cfg.JWTSecretKey, cfg.JWTAccessTokenLifetime, cfg.JWTRefreshTokenLifetime)
 
+	var oidcClient *OIDCClient
+	if cfg.OIDCIssuerURL != "" {
+		oidcClient = NewOIDCClient(OIDCConfig{
+			ProviderName:  cfg.OIDCProviderName,
+			IssuerURL:     cfg.OIDCIssuerURL,
+			ClientID:      cfg.OIDCClientID,
+			ClientSecret:  cfg.OIDCClientSecret,
+			RedirectURL:   cfg.OIDCRedirectURL,
+			Scopes:        strings.Fields(cfg.OIDCScopes),
+			StateLifetime: time.Duration(cfg.OIDCStateLifetime) * time.Second,
+		}, nil)
+	}
+
 	return &AuthService{
 		Repo:       repo,
 		JWTService: jwtService,
+		MFA: MFAPolicy{
+			RequiredForStaff:  cfg.MFARequiredForStaff,
+			ChallengeLifetime: time.Duration(cfg.MFAChallengeLifetime) * time.Second,
+			Issuer:            cfg.TOTPIssuer,
+			MaxFailedAttempts: cfg.MFAMaxFailedAttempts,
+			LockoutDuration:   time.Duration(cfg.MFALockoutSeconds) * time.Second,
+		},
+		OIDC:                  oidcClient,
+		ImpersonationLifetime: time.Duration(cfg.ImpersonationTokenLifetime) * time.Second,
 	}, nil
 }
 
@@ -65,6 +137,11 @@ func (s *AuthService) GenerateToken(ctx context.Context, dto GenerateTokenReques
 		return nil, fmt.Errorf("failed to find user by username: %w", err)
 	}
 
+	if user.IsServiceAccount {
+		log.Info(ctx, "Password login attempt for service account", zap.String("username", dto.Username))
+		return nil, models.ErrInvalidCredentials
+	}
+
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(dto.Password))
 	if err != nil {
 		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
@@ -75,6 +152,28 @@ func (s *AuthService) GenerateToken(ctx context.Context, dto GenerateTokenReques
 		return nil, models.ErrInvalidCredentials
 	}
 
+	return s.completeLogin(ctx, user)
+}
+
+// completeLogin - общий завершающий шаг входа по паролю и через OIDC
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User) (*GenerateTokenResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if user.BlockedAt != nil {
+		log.Info(ctx, "Login attempt by blocked user", zap.String("user_id", user.ID.String()))
+		return nil, models.ErrUserBlocked
+	}
+
+	if user.MFAEnabled || s.isMFARequired(user) {
+		return s.issueMFAChallenge(ctx, user)
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User) (*GenerateTokenResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
 	accessToken, refreshToken, err := s.JWTService.GenerateTokens(user)
 	if err != nil {
 		err = fmt.Errorf("failed to generate JWT tokens for user %s: %w", user.ID.String(), err)
@@ -83,23 +182,80 @@ func (s *AuthService) GenerateToken(ctx context.Context, dto GenerateTokenReques
 	}
 
 	return &GenerateTokenResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:            accessToken,
+		RefreshToken:           refreshToken,
+		PasswordChangeRequired: user.PasswordChangeRequired,
+	}, nil
+}
+
+func (s *AuthService) issueMFAChallenge(ctx context.Context, user *models.User) (*GenerateTokenResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	lifetime := s.MFA.ChallengeLifetime
+	if lifetime <= 0 {
+		lifetime = defaultMFAChallengeLifetime
+	}
+
+	mfaToken, err := s.JWTService.GenerateMFAChallengeToken(user, lifetime)
+	if err != nil {
+		err = fmt.Errorf("failed to generate MFA challenge token for user %s: %w", user.ID.String(), err)
+		log.Error(ctx, err.Error())
+		return nil, err
+	}
+
+	log.Info(ctx, "MFA challenge issued",
+		zap.String("user_id", user.ID.String()),
+		zap.Bool("enrollment_required", !user.MFAEnabled),
+	)
+
+	return &GenerateTokenResponse{
+		MFARequired:           true,
+		MFAEnrollmentRequired: !user.MFAEnabled,
+		MFAToken:              mfaToken,
 	}, nil
+}
 
+// isMFARequired - персоналом считается любая роль, у которой есть хотя бы одно право
+func (s *AuthService) isMFARequired(user *models.User) bool {
+	return s.MFA.RequiredForStaff && len(user.Permissions) > 0
 }
 
 func (s *AuthService) ValidateToken(ctx context.Context, dto ValidateTokenRequest) (*ValidatedUserDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
 
-	user, claims, err := s.validateTokenAndGetUser(ctx, dto.AccessToken)
+	claims, err := s.parseAccessClaims(ctx, dto.AccessToken)
 	if err != nil {
 		return nil, err
 	}
 
+	if claims.Purpose == TokenPurposeImpersonation {
+		return s.validateImpersonationClaims(ctx, claims)
+	}
+	if claims.Purpose != "" {
+		log.Warn(ctx, "Token validation failed: token has a restricted purpose", zap.String("purpose", claims.Purpose))
+		return nil, models.ErrInvalidToken
+	}
+
+	user, err := s.getUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from token claims: %w", err)
+	}
+
+	if user.PasswordChangeRequired {
+		return nil, models.ErrPasswordChangeRequired
+	}
+
+	// Роль и права берутся из БД на каждый запрос, поэтому изменения ролей применяются без перевыпуска токенов
+	permissions := user.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+
 	response := &ValidatedUserDTO{
-		UserID:   user.ID.String(),
-		Username: user.Username,
-		RoleID:   claims.RoleID,
+		UserID:      user.ID.String(),
+		Username:    user.Username,
+		RoleID:      user.RoleID,
+		Permissions: permissions,
 	}
 
 	return response, nil
@@ -140,6 +296,37 @@ func (s *AuthService) validateTokenAndGetUser(ctx context.Context, token string)
 	return user, claims, nil
 }
 
+// ChangePassword - смена собственного пароля. Доступна и при сброшенном администратором пароле.
+func (s *AuthService) ChangePassword(ctx context.Context, accessToken string, dto ChangePasswordRequest) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	user, _, err := s.validateTokenAndGetUser(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(dto.CurrentPassword)); err != nil {
+		log.Info(ctx, "Current password mismatch on password change", zap.String("user_id", user.ID.String()))
+		return models.ErrInvalidCredentials
+	}
+
+	hashedPassword, err := hashPassword(dto.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.UpdateUserPassword(ctx, user.ID, hashedPassword); err != nil {
+		return fmt.Errorf("failed to update user password: %w", err)
+	}
+
+	log.Info(ctx, "User password changed", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
 func (s *AuthService) RegisterUser(ctx context.Context, dto RegisterUserRequest) error {
 	log := logger.GetLoggerFromCtx(ctx)
 
@@ -197,5 +384,737 @@ func (s *AuthService) getUserByID(ctx context.Context, userIDStr string) (*model
 		return nil, fmt.Errorf("repository error while finding user by id: %w", err)
 	}
 
+	if user.BlockedAt != nil {
+		log.Info(ctx, "Token rejected: user is blocked", zap.String("user_id", user.ID.String()))
+		return nil, models.ErrUserBlocked
+	}
+
 	return user, nil
 }
+
+/////////////// API-ключи
+
+// ValidateAPIKey проверяет ключ сервисной учетной записи. Доступны только права, которые есть
+// одновременно в scopes ключа и в текущей роли учетной записи.
+func (s *AuthService) ValidateAPIKey(ctx context.Context, rawKey string) (*ValidatedUserDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rawKey = strings.TrimSpace(rawKey)
+	if !isAPIKeyFormat(rawKey) {
+		return nil, models.ErrInvalidAPIKey
+	}
+
+	key, err := s.Repo.FindAPIKeyByHash(ctx, HashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, models.ErrAPIKeyNotFound) {
+			log.Info(ctx, "Unknown API key", zap.String("prefix", rawKey[:apiKeyDisplayChars]))
+			return nil, models.ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now())) {
+		log.Info(ctx, "Revoked or expired API key used", zap.String("api_key_id", key.ID.String()))
+		return nil, models.ErrInvalidAPIKey
+	}
+
+	user, err := s.getUserByID(ctx, key.UserID.String())
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidToken) {
+			return nil, models.ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if !user.IsServiceAccount {
+		log.Warn(ctx, "API key belongs to a regular user account", zap.String("api_key_id", key.ID.String()))
+		return nil, models.ErrInvalidAPIKey
+	}
+
+	if err := s.Repo.TouchAPIKey(ctx, key.ID); err != nil {
+		log.Warn(ctx, "Failed to update API key last usage", zap.Error(err), zap.String("api_key_id", key.ID.String()))
+	}
+
+	return &ValidatedUserDTO{
+		UserID:      user.ID.String(),
+		Username:    user.Username,
+		RoleID:      user.RoleID,
+		Permissions: intersectPermissions(user.Permissions, key.Scopes),
+		APIKeyID:    key.ID.String(),
+	}, nil
+}
+
+func intersectPermissions(permissions, scopes []string) []string {
+	allowed := make(map[string]struct{}, len(scopes))
+	for _, scope := range scopes {
+		allowed[scope] = struct{}{}
+	}
+
+	result := make([]string, 0, len(scopes))
+	for _, p := range permissions {
+		if _, ok := allowed[p]; ok {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+/////////////// Вход от имени пользователя
+
+// ImpersonateUser выдает администратору короткоживущий токен гостя для разбора обращений в поддержку.
+// По умолчанию сессия только для чтения; факт выдачи и каждый запрос в сессии пишутся в журнал действий.
+func (s *AuthService) ImpersonateUser(ctx context.Context, actorID string, targetID uuid.UUID, dto ImpersonateUserRequest) (*ImpersonateUserResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	actor, err := s.getUserByID(ctx, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get impersonating user: %w", err)
+	}
+
+	if actor.IsServiceAccount {
+		return nil, models.ErrServiceAccount
+	}
+
+	if actor.ID == targetID {
+		return nil, models.ErrCannotModifySelf
+	}
+
+	target, err := s.Repo.FindUserByID(ctx, targetID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to find user by id: %w", err)
+	}
+
+	if !isImpersonationTarget(target) {
+		log.Info(ctx, "Impersonation of a non-guest account rejected",
+			zap.String("actor_id", actor.ID.String()),
+			zap.String("user_id", target.ID.String()),
+		)
+		return nil, models.ErrImpersonationNotAllowed
+	}
+
+	lifetime := s.ImpersonationLifetime
+	if lifetime <= 0 {
+		lifetime = defaultImpersonationLifetime
+	}
+
+	token, expiresAt, err := s.JWTService.GenerateImpersonationToken(target, actor, lifetime, dto.Write)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate impersonation token for user %s: %w", target.ID.String(), err)
+	}
+
+	// Токен не выдается, если факт выдачи не удалось записать в журнал
+	entry := models.NewAuditLogEntry(actor.ID, models.AuditActionImpersonationStarted, models.AuditEntityUser, target.ID.String(), map[string]any{
+		"username":   target.Username,
+		"reason":     strings.TrimSpace(dto.Reason),
+		"write":      dto.Write,
+		"expires_at": expiresAt,
+	})
+	if err := s.Repo.RecordAuditLogEntry(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to record impersonation start: %w", err)
+	}
+
+	log.Info(ctx, "Impersonation token issued",
+		zap.String("actor_id", actor.ID.String()),
+		zap.String("user_id", target.ID.String()),
+		zap.Bool("write", dto.Write),
+	)
+
+	return &ImpersonateUserResponse{
+		AccessToken: token,
+		ExpiresAt:   expiresAt,
+		UserID:      target.ID.String(),
+		Username:    target.Username,
+		ReadOnly:    !dto.Write,
+	}, nil
+}
+
+// isImpersonationTarget - войти можно только под действующим гостем: без прав персонала, не сервисной и не удаленной учетной записью
+func isImpersonationTarget(user *models.User) bool {
+	return !user.IsServiceAccount && len(user.Permissions) == 0 && user.BlockedAt == nil && user.DeletedAt == nil
+}
+
+// validateImpersonationClaims повторно проверяет администратора на каждый запрос: блокировка или отзыв права
+// users.impersonate сразу завершают все выданные им сессии
+func (s *AuthService) validateImpersonationClaims(ctx context.Context, claims *JWTClaims) (*ValidatedUserDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if claims.Actor == nil || claims.Actor.UserID == "" {
+		log.Warn(ctx, "Impersonation token without actor claim")
+		return nil, models.ErrInvalidToken
+	}
+
+	actor, err := s.getUserByID(ctx, claims.Actor.UserID)
+	if err != nil {
+		if errors.Is(err, models.ErrUserBlocked) {
+			return nil, models.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get impersonating user: %w", err)
+	}
+
+	if !slices.Contains(actor.Permissions, models.PermissionUsersImpersonate) {
+		log.Info(ctx, "Impersonation token rejected: actor has lost permission", zap.String("actor_id", actor.ID.String()))
+		return nil, models.ErrInvalidToken
+	}
+
+	target, err := s.getUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from token claims: %w", err)
+	}
+
+	// Пользователь мог получить роль персонала после выдачи токена
+	if !isImpersonationTarget(target) {
+		log.Info(ctx, "Impersonation token rejected: target is no longer a guest", zap.String("user_id", target.ID.String()))
+		return nil, models.ErrInvalidToken
+	}
+
+	return &ValidatedUserDTO{
+		UserID:               target.ID.String(),
+		Username:             target.Username,
+		RoleID:               target.RoleID,
+		Permissions:          []string{},
+		ImpersonatorID:       actor.ID.String(),
+		ImpersonatorUsername: actor.Username,
+		ReadOnly:             !claims.Write,
+	}, nil
+}
+
+// RecordImpersonatedRequest пишет в журнал запрос, выполненный администратором от имени пользователя
+func (s *AuthService) RecordImpersonatedRequest(ctx context.Context, user AuthenticatedUser, method, path string, status int) error {
+	actorID, err := uuid.Parse(user.ImpersonatorID)
+	if err != nil {
+		return fmt.Errorf("invalid impersonator id %q: %w", user.ImpersonatorID, err)
+	}
+
+	targetID, err := uuid.Parse(user.ID)
+	if err != nil {
+		return fmt.Errorf("invalid impersonated user id %q: %w", user.ID, err)
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionImpersonatedRequest, models.AuditEntityUser, targetID.String(), map[string]any{
+		"username": user.Username,
+		"method":   method,
+		"path":     path,
+		"status":   status,
+		"write":    !user.ReadOnly,
+	})
+
+	return s.Repo.RecordAuditLogEntry(ctx, entry)
+}
+
+/////////////// OpenID Connect
+
+const (
+	defaultOIDCStateLifetime = 10 * time.Minute
+	maxUsernameLength        = 50
+)
+
+// StartOIDCLogin сохраняет state, nonce и PKCE code_verifier и возвращает адрес страницы входа провайдера
+func (s *AuthService) StartOIDCLogin(ctx context.Context) (*OIDCLoginResponse, error) {
+	if s.OIDC == nil {
+		return nil, models.ErrOIDCDisabled
+	}
+
+	state, err := randomURLToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomURLToken()
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := randomURLToken()
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := s.OIDC.AuthorizationURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OIDC authorization URL: %w", err)
+	}
+
+	lifetime := s.OIDC.cfg.StateLifetime
+	if lifetime <= 0 {
+		lifetime = defaultOIDCStateLifetime
+	}
+
+	loginState := &models.OIDCLoginState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(lifetime),
+	}
+	if err := s.Repo.CreateOIDCState(ctx, loginState); err != nil {
+		return nil, fmt.Errorf("failed to save OIDC login state: %w", err)
+	}
+
+	return &OIDCLoginResponse{
+		AuthorizationURL: authorizationURL,
+		State:            state,
+	}, nil
+}
+
+// CompleteOIDCLogin обменивает code на id_token, находит или создает пользователя и завершает вход
+// так же, как вход по паролю(блокировка, 2FA)
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, dto OIDCCallbackRequest) (*GenerateTokenResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if s.OIDC == nil {
+		return nil, models.ErrOIDCDisabled
+	}
+
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	loginState, err := s.Repo.ConsumeOIDCState(ctx, dto.State)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrOIDCInvalidState
+		}
+		return nil, fmt.Errorf("failed to get OIDC login state: %w", err)
+	}
+	if !loginState.ExpiresAt.After(time.Now()) {
+		return nil, models.ErrOIDCInvalidState
+	}
+
+	rawIDToken, err := s.OIDC.Exchange(ctx, dto.Code, loginState.CodeVerifier)
+	if err != nil {
+		log.Warn(ctx, "OIDC code exchange failed", zap.Error(err))
+		return nil, models.ErrOIDCAuthFailed
+	}
+
+	claims, err := s.OIDC.VerifyIDToken(ctx, rawIDToken, loginState.Nonce)
+	if err != nil {
+		log.Warn(ctx, "OIDC id_token rejected", zap.Error(err))
+		return nil, models.ErrOIDCAuthFailed
+	}
+
+	user, err := s.resolveOIDCUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsServiceAccount {
+		log.Warn(ctx, "OIDC login resolved to service account", zap.String("user_id", user.ID.String()))
+		return nil, models.ErrOIDCAuthFailed
+	}
+
+	return s.completeLogin(ctx, user)
+}
+
+// resolveOIDCUser ищет пользователя по привязанной учетной записи, затем по подтвержденному email;
+// если не найден - регистрирует нового гостя
+func (s *AuthService) resolveOIDCUser(ctx context.Context, claims *OIDCIDTokenClaims) (*models.User, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+	provider := s.OIDC.ProviderName()
+
+	identity := &models.UserIdentity{
+		ID:       uuid.New(),
+		Provider: provider,
+		Subject:  claims.Subject,
+	}
+	email := strings.TrimSpace(claims.Email)
+	if email != "" {
+		identity.Email = &email
+	}
+
+	user, err := s.Repo.FindUserByIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		identity.UserID = user.ID
+		if err := s.Repo.LinkUserIdentity(ctx, identity); err != nil {
+			log.Warn(ctx, "Failed to update OIDC identity last login", zap.Error(err), zap.String("user_id", user.ID.String()))
+		}
+		return user, nil
+	}
+	if !errors.Is(err, models.ErrUserNotFound) {
+		return nil, fmt.Errorf("failed to find user by OIDC identity: %w", err)
+	}
+
+	// Привязка к существующей учетной записи только по email, подтвержденному провайдером
+	if email != "" && claims.EmailVerified {
+		user, err := s.Repo.FindUserByEmail(ctx, email)
+		if err == nil {
+			identity.UserID = user.ID
+			if err := s.Repo.LinkUserIdentity(ctx, identity); err != nil {
+				if errors.Is(err, models.ErrIdentityAlreadyLinked) {
+					return nil, models.ErrOIDCAuthFailed
+				}
+				return nil, fmt.Errorf("failed to link OIDC identity: %w", err)
+			}
+			log.Info(ctx, "OIDC identity linked to existing user",
+				zap.String("user_id", user.ID.String()),
+				zap.String("provider", provider),
+			)
+			return user, nil
+		}
+		if !errors.Is(err, models.ErrUserNotFound) {
+			return nil, fmt.Errorf("failed to find user by email: %w", err)
+		}
+	}
+
+	return s.registerOIDCUser(ctx, claims, identity)
+}
+
+func (s *AuthService) registerOIDCUser(ctx context.Context, claims *OIDCIDTokenClaims, identity *models.UserIdentity) (*models.User, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	user := &models.User{
+		ID:           uuid.New(),
+		Username:     oidcUsername(claims),
+		PasswordHash: "!",
+		RoleID:       models.GuestRoleID,
+	}
+	// Неподтвержденный email не сохраняется: иначе им можно было бы занять чужой адрес
+	if identity.Email != nil && claims.EmailVerified {
+		user.Email = *identity.Email
+	}
+	identity.UserID = user.ID
+
+	baseUsername := user.Username
+	for attempt := 0; ; attempt++ {
+		err := s.Repo.RegisterOIDCUser(ctx, user, identity)
+		if err == nil {
+			break
+		}
+		switch {
+		case errors.Is(err, models.ErrUserExists) && attempt < 3:
+			suffix, err := randomURLToken()
+			if err != nil {
+				return nil, err
+			}
+			user.Username = withUsernameSuffix(baseUsername, strings.ToLower(suffix[:6]))
+		case errors.Is(err, models.ErrUserExists), errors.Is(err, models.ErrEmailExists), errors.Is(err, models.ErrIdentityAlreadyLinked):
+			log.Warn(ctx, "OIDC user provisioning conflict", zap.Error(err), zap.String("username", user.Username))
+			return nil, models.ErrOIDCAuthFailed
+		default:
+			return nil, fmt.Errorf("failed to register OIDC user: %w", err)
+		}
+	}
+
+	log.Info(ctx, "User registered via OIDC",
+		zap.String("user_id", user.ID.String()),
+		zap.String("username", user.Username),
+		zap.String("provider", identity.Provider),
+	)
+
+	return user, nil
+}
+
+// oidcUsername подбирает имя пользователя из preferred_username или локальной части email
+func oidcUsername(claims *OIDCIDTokenClaims) string {
+	candidates := []string{claims.PreferredUsername}
+	if at := strings.IndexByte(claims.Email, '@'); at > 0 {
+		candidates = append(candidates, claims.Email[:at])
+	}
+
+	for _, candidate := range candidates {
+		var b strings.Builder
+		for _, r := range strings.ToLower(candidate) {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+				b.WriteRune(r)
+			}
+		}
+		if username := b.String(); username != "" {
+			return withUsernameSuffix(username, "")
+		}
+	}
+
+	return "guest_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+}
+
+func withUsernameSuffix(username, suffix string) string {
+	if suffix != "" {
+		suffix = "_" + suffix
+	}
+	if len(username)+len(suffix) > maxUsernameLength {
+		username = username[:maxUsernameLength-len(suffix)]
+	}
+	return username + suffix
+}
+
+/////////////// 2FA
+
+// VerifyMFA - второй шаг входа: обмен промежуточного токена и одноразового кода на пару токенов.
+// Если 2FA еще не подтверждена(принудительная настройка), первый верный код завершает подключение.
+func (s *AuthService) VerifyMFA(ctx context.Context, dto VerifyMFARequest) (*GenerateTokenResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	claims, err := s.mfaClaimsFromToken(ctx, dto.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	totp, err := s.Repo.GetUserTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, models.ErrMFAEnrollmentRequired
+		}
+		return nil, fmt.Errorf("failed to get user TOTP settings: %w", err)
+	}
+
+	// После блокировки промежуточные токены, выданные до нее, использовать нельзя
+	if totp.ChallengesValidAfter != nil && (claims.IssuedAt == nil || !claims.IssuedAt.After(*totp.ChallengesValidAfter)) {
+		log.Info(ctx, "MFA challenge token was consumed by lockout", zap.String("user_id", user.ID.String()))
+		return nil, models.ErrInvalidToken
+	}
+
+	if !totp.Enabled {
+		if err := s.withMFAAttempts(ctx, totp, func() error { return s.confirmTOTP(ctx, totp, dto.Code) }); err != nil {
+			return nil, err
+		}
+		log.Info(ctx, "TOTP enrollment confirmed during login", zap.String("user_id", user.ID.String()))
+		return s.issueTokens(ctx, user)
+	}
+
+	if err := s.withMFAAttempts(ctx, totp, func() error { return s.checkMFACode(ctx, totp, dto.Code) }); err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// withMFAAttempts проверяет код с учетом блокировки: неверный код засчитывается, и после MaxFailedAttempts
+// ошибок подряд ввод кодов блокируется на LockoutDuration(вместе с выданными промежуточными токенами)
+func (s *AuthService) withMFAAttempts(ctx context.Context, totp *models.UserTOTP, check func() error) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	now := time.Now()
+	if totp.LockedUntil != nil && totp.LockedUntil.After(now) {
+		log.Info(ctx, "Two-factor code entry is locked", zap.String("user_id", totp.UserID.String()), zap.Time("locked_until", *totp.LockedUntil))
+		return models.ErrMFALocked
+	}
+
+	err := check()
+	if err == nil {
+		if totp.FailedAttempts > 0 {
+			if err := s.Repo.ResetMFAFailures(ctx, totp.UserID); err != nil {
+				return fmt.Errorf("failed to reset MFA failures: %w", err)
+			}
+		}
+		return nil
+	}
+	if !errors.Is(err, models.ErrInvalidMFACode) {
+		return err
+	}
+
+	maxAttempts := s.MFA.MaxFailedAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMFAMaxFailedAttempts
+	}
+	lockout := s.MFA.LockoutDuration
+	if lockout <= 0 {
+		lockout = defaultMFALockoutDuration
+	}
+
+	locked, regErr := s.Repo.RegisterMFAFailure(ctx, totp.UserID, now, maxAttempts, now.Add(lockout))
+	if regErr != nil {
+		return fmt.Errorf("failed to register MFA failure: %w", regErr)
+	}
+	if locked {
+		log.Warn(ctx, "Two-factor code entry locked after repeated failures", zap.String("user_id", totp.UserID.String()), zap.Duration("lockout", lockout))
+		return models.ErrMFALocked
+	}
+
+	return err
+}
+
+// EnrollMFAWithChallenge - настройка 2FA по промежуточному токену, когда политика требует 2FA,
+// а пользователь ее еще не подключил
+func (s *AuthService) EnrollMFAWithChallenge(ctx context.Context, dto MFAChallengeEnrollRequest) (*MFAEnrollResponse, error) {
+	claims, err := s.mfaClaimsFromToken(ctx, dto.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.getUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startTOTPEnrollment(ctx, user)
+}
+
+func (s *AuthService) EnrollMFA(ctx context.Context, userID string) (*MFAEnrollResponse, error) {
+	user, err := s.getUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startTOTPEnrollment(ctx, user)
+}
+
+func (s *AuthService) ConfirmMFA(ctx context.Context, userID string, dto MFACodeRequest) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	user, err := s.getUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	totp, err := s.Repo.GetUserTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrMFANotEnrolled
+		}
+		return fmt.Errorf("failed to get user TOTP settings: %w", err)
+	}
+
+	if totp.Enabled {
+		return models.ErrMFAAlreadyEnabled
+	}
+
+	if err := s.confirmTOTP(ctx, totp, dto.Code); err != nil {
+		return err
+	}
+
+	log.Info(ctx, "TOTP enrollment confirmed", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
+func (s *AuthService) DisableMFA(ctx context.Context, userID string, dto MFACodeRequest) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	user, err := s.getUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if s.isMFARequired(user) {
+		return models.ErrMFARequiredByPolicy
+	}
+
+	totp, err := s.Repo.GetUserTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.ErrMFANotEnrolled
+		}
+		return fmt.Errorf("failed to get user TOTP settings: %w", err)
+	}
+
+	if totp.Enabled {
+		if err := s.withMFAAttempts(ctx, totp, func() error { return s.checkMFACode(ctx, totp, dto.Code) }); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Repo.DeleteUserTOTP(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete user TOTP settings: %w", err)
+	}
+
+	log.Info(ctx, "TOTP disabled", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
+func (s *AuthService) GetMFAStatus(ctx context.Context, userID string) (*MFAStatusResponse, error) {
+	user, err := s.getUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAStatusResponse{Enabled: user.MFAEnabled}, nil
+}
+
+func (s *AuthService) startTOTPEnrollment(ctx context.Context, user *models.User) (*MFAEnrollResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if user.MFAEnabled {
+		return nil, models.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, recoveryHashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	totp := &models.UserTOTP{
+		UserID: user.ID,
+		Secret: secret,
+	}
+
+	// Повторный вызов до подтверждения заменяет секрет и коды восстановления
+	if err := s.Repo.SaveUserTOTP(ctx, totp, recoveryHashes); err != nil {
+		return nil, fmt.Errorf("failed to save user TOTP settings: %w", err)
+	}
+
+	issuer := s.MFA.Issuer
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+
+	log.Info(ctx, "TOTP enrollment started", zap.String("user_id", user.ID.String()))
+
+	return &MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(issuer, user.Username, secret),
+		RecoveryCodes:   recoveryCodes,
+	}, nil
+}
+
+func (s *AuthService) confirmTOTP(ctx context.Context, totp *models.UserTOTP, code string) error {
+	step, ok := verifyTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return models.ErrInvalidMFACode
+	}
+
+	if err := s.Repo.EnableUserTOTP(ctx, totp.UserID, step); err != nil {
+		return fmt.Errorf("failed to enable user TOTP: %w", err)
+	}
+
+	return nil
+}
+
+// checkMFACode принимает либо код из приложения, либо одноразовый код восстановления
+func (s *AuthService) checkMFACode(ctx context.Context, totp *models.UserTOTP, code string) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if isTOTPCodeFormat(strings.TrimSpace(code)) {
+		step, ok := verifyTOTP(totp.Secret, code, time.Now())
+		if !ok {
+			log.Info(ctx, "Invalid TOTP code provided", zap.String("user_id", totp.UserID.String()))
+			return models.ErrInvalidMFACode
+		}
+		if totp.LastUsedStep != nil && step <= *totp.LastUsedStep {
+			log.Warn(ctx, "TOTP code reuse attempt", zap.String("user_id", totp.UserID.String()))
+			return models.ErrInvalidMFACode
+		}
+		if err := s.Repo.MarkTOTPStepUsed(ctx, totp.UserID, step); err != nil {
+			if errors.Is(err, models.ErrInvalidMFACode) {
+				return err
+			}
+			return fmt.Errorf("failed to mark TOTP step as used: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.Repo.UseRecoveryCode(ctx, totp.UserID, hashRecoveryCode(code)); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			log.Info(ctx, "Invalid recovery code provided", zap.String("user_id", totp.UserID.String()))
+			return models.ErrInvalidMFACode
+		}
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	log.Info(ctx, "Recovery code used", zap.String("user_id", totp.UserID.String()))
+	return nil
+}
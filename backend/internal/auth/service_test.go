@@ -3,6 +3,10 @@ package auth_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -10,6 +14,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/auth"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/auth/mocks"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/auth/oidctest"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/config"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
 	"github.com/stretchr/testify/assert"
@@ -296,6 +301,46 @@ func TestAuthService_ValidateToken(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("token validation resolves current role permissions", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		accessToken, _, _ := service.JWTService.GenerateTokens(testUser)
+
+		// Права роли изменились после выпуска токена
+		withPermissions := *testUser
+		withPermissions.Permissions = []string{models.PermissionChecklistsView, models.PermissionChecklistsEdit}
+		mockRepo.On("FindUserByID", ctx, testUser.ID).Return(&withPermissions, nil)
+
+		// Act
+		validatedUser, err := service.ValidateToken(ctx, auth.ValidateTokenRequest{AccessToken: accessToken})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, withPermissions.Permissions, validatedUser.Permissions)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("token validation uses the current role from the database", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		accessToken, _, _ := service.JWTService.GenerateTokens(testUser)
+
+		// Роль изменилась после выпуска токена: в токене остается прежняя
+		changedRole := *testUser
+		changedRole.RoleID = models.ModeratorRoleID
+		mockRepo.On("FindUserByID", ctx, testUser.ID).Return(&changedRole, nil)
+
+		// Act
+		validatedUser, err := service.ValidateToken(ctx, auth.ValidateTokenRequest{AccessToken: accessToken})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, models.ModeratorRoleID, validatedUser.RoleID)
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("validation with invalid token", func(t *testing.T) {
 		// Arrange
 		mockRepo := new(mocks.UserRepository)
@@ -439,3 +484,1086 @@ func TestAuthService_RefreshToken(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestTOTPCode(t *testing.T) {
+	// Тестовые векторы RFC 6238 (SHA1, секрет "12345678901234567890"), последние 6 цифр
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for ts, expected := range cases {
+		code, err := auth.TOTPCode(secret, time.Unix(ts, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+}
+
+func TestAuthService_GenerateToken_MFA(t *testing.T) {
+	ctx := context.Background()
+	password := "password123"
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+	t.Run("user with enabled 2FA receives challenge token", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		user := &models.User{
+			ID:           uuid.New(),
+			Username:     "mfauser",
+			PasswordHash: string(hashedPassword),
+			RoleID:       models.GuestRoleID,
+			MFAEnabled:   true,
+		}
+		mockRepo.On("FindUserByUsername", ctx, "mfauser").Return(user, nil)
+
+		// Act
+		resp, err := service.GenerateToken(ctx, auth.GenerateTokenRequest{Username: "mfauser", Password: password})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, resp.MFARequired)
+		assert.False(t, resp.MFAEnrollmentRequired)
+		assert.NotEmpty(t, resp.MFAToken)
+		assert.Empty(t, resp.AccessToken)
+		assert.Empty(t, resp.RefreshToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("policy forces enrollment for staff", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		service.MFA.RequiredForStaff = true
+		moderator := &models.User{
+			ID:           uuid.New(),
+			Username:     "moderator",
+			PasswordHash: string(hashedPassword),
+			RoleID:       models.ModeratorRoleID,
+			Permissions:  []string{models.PermissionReportsView},
+		}
+		mockRepo.On("FindUserByUsername", ctx, "moderator").Return(moderator, nil)
+
+		// Act
+		resp, err := service.GenerateToken(ctx, auth.GenerateTokenRequest{Username: "moderator", Password: password})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.True(t, resp.MFARequired)
+		assert.True(t, resp.MFAEnrollmentRequired)
+		assert.Empty(t, resp.AccessToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("policy does not affect guests", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		service.MFA.RequiredForStaff = true
+		guest := &models.User{
+			ID:           uuid.New(),
+			Username:     "guest",
+			PasswordHash: string(hashedPassword),
+			RoleID:       models.GuestRoleID,
+		}
+		mockRepo.On("FindUserByUsername", ctx, "guest").Return(guest, nil)
+
+		// Act
+		resp, err := service.GenerateToken(ctx, auth.GenerateTokenRequest{Username: "guest", Password: password})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.False(t, resp.MFARequired)
+		assert.NotEmpty(t, resp.AccessToken)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_VerifyMFA(t *testing.T) {
+	ctx := context.Background()
+	secret := "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+	user := &models.User{
+		ID:         uuid.New(),
+		Username:   "mfauser",
+		RoleID:     models.AdminRoleID,
+		MFAEnabled: true,
+	}
+
+	challengeToken := func(service *auth.AuthService) string {
+		token, _ := service.JWTService.GenerateMFAChallengeToken(user, time.Minute)
+		return token
+	}
+
+	t.Run("successful verification with TOTP code", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		code, _ := auth.TOTPCode(secret, time.Now())
+
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("GetUserTOTP", ctx, user.ID).Return(&models.UserTOTP{UserID: user.ID, Secret: secret, Enabled: true}, nil)
+		mockRepo.On("MarkTOTPStepUsed", ctx, user.ID, mock.AnythingOfType("int64")).Return(nil)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: challengeToken(service), Code: code})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.NotEmpty(t, resp.RefreshToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid TOTP code", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		code, _ := auth.TOTPCode(secret, time.Now().Add(-10*time.Minute))
+
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("GetUserTOTP", ctx, user.ID).Return(&models.UserTOTP{UserID: user.ID, Secret: secret, Enabled: true}, nil)
+		mockRepo.On("RegisterMFAFailure", ctx, user.ID, mock.AnythingOfType("time.Time"), 5, mock.AnythingOfType("time.Time")).Return(false, nil)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: challengeToken(service), Code: code})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrInvalidMFACode)
+		mockRepo.AssertNotCalled(t, "MarkTOTPStepUsed", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("repeated invalid codes lock code entry", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		service.MFA.MaxFailedAttempts = 3
+		service.MFA.LockoutDuration = 10 * time.Minute
+		code, _ := auth.TOTPCode(secret, time.Now().Add(-10*time.Minute))
+
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("GetUserTOTP", ctx, user.ID).Return(&models.UserTOTP{UserID: user.ID, Secret: secret, Enabled: true, FailedAttempts: 2}, nil)
+		mockRepo.On("RegisterMFAFailure", ctx, user.ID, mock.AnythingOfType("time.Time"), 3,
+			mock.MatchedBy(func(lockedUntil time.Time) bool {
+				return lockedUntil.After(time.Now().Add(9*time.Minute)) && lockedUntil.Before(time.Now().Add(11*time.Minute))
+			})).Return(true, nil)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: challengeToken(service), Code: code})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrMFALocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("locked user cannot verify even a valid code", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		code, _ := auth.TOTPCode(secret, time.Now())
+		lockedUntil := time.Now().Add(5 * time.Minute)
+
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("GetUserTOTP", ctx, user.ID).Return(&models.UserTOTP{UserID: user.ID, Secret: secret, Enabled: true, LockedUntil: &lockedUntil}, nil)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: challengeToken(service), Code: code})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrMFALocked)
+		mockRepo.AssertNotCalled(t, "MarkTOTPStepUsed", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "RegisterMFAFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("challenge issued before lockout is consumed", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		token := challengeToken(service)
+		code, _ := auth.TOTPCode(secret, time.Now())
+		lockedAt := time.Now().Add(time.Second)
+		expiredLock := time.Now().Add(-time.Minute)
+
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("GetUserTOTP", ctx, user.ID).Return(&models.UserTOTP{
+			UserID: user.ID, Secret: secret, Enabled: true, LockedUntil: &expiredLock, ChallengesValidAfter: &lockedAt,
+		}, nil)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: token, Code: code})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrInvalidToken)
+		mockRepo.AssertNotCalled(t, "MarkTOTPStepUsed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("valid code resets failed attempts", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		code, _ := auth.TOTPCode(secret, time.Now())
+
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("GetUserTOTP", ctx, user.ID).Return(&models.UserTOTP{UserID: user.ID, Secret: secret, Enabled: true, FailedAttempts: 2}, nil)
+		mockRepo.On("MarkTOTPStepUsed", ctx, user.ID, mock.AnythingOfType("int64")).Return(nil)
+		mockRepo.On("ResetMFAFailures", ctx, user.ID).Return(nil)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: challengeToken(service), Code: code})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("successful verification with recovery code", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("GetUserTOTP", ctx, user.ID).Return(&models.UserTOTP{UserID: user.ID, Secret: secret, Enabled: true}, nil)
+		mockRepo.On("UseRecoveryCode", ctx, user.ID, mock.AnythingOfType("string")).Return(nil)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: challengeToken(service), Code: "ABCDE-FGHJK"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("pending enrollment is confirmed by the first code", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		code, _ := auth.TOTPCode(secret, time.Now())
+
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("GetUserTOTP", ctx, user.ID).Return(&models.UserTOTP{UserID: user.ID, Secret: secret, Enabled: false}, nil)
+		mockRepo.On("EnableUserTOTP", ctx, user.ID, mock.AnythingOfType("int64")).Return(nil)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: challengeToken(service), Code: code})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("access token cannot be used as challenge token", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		accessToken, _, _ := service.JWTService.GenerateTokens(user)
+
+		// Act
+		resp, err := service.VerifyMFA(ctx, auth.VerifyMFARequest{MFAToken: accessToken, Code: "123456"})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrInvalidToken)
+		mockRepo.AssertNotCalled(t, "FindUserByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("challenge token cannot be used as access token", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+
+		// Act
+		validatedUser, err := service.ValidateToken(ctx, auth.ValidateTokenRequest{AccessToken: challengeToken(service)})
+
+		// Assert
+		assert.Nil(t, validatedUser)
+		assert.ErrorIs(t, err, models.ErrInvalidToken)
+		mockRepo.AssertNotCalled(t, "FindUserByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthService_BlockedAndPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	password := "password123"
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	blockedAt := time.Now()
+
+	t.Run("blocked user cannot log in", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		user := &models.User{
+			ID:           uuid.New(),
+			Username:     "blocked",
+			PasswordHash: string(hashedPassword),
+			RoleID:       models.GuestRoleID,
+			BlockedAt:    &blockedAt,
+		}
+		mockRepo.On("FindUserByUsername", ctx, "blocked").Return(user, nil)
+
+		// Act
+		resp, err := service.GenerateToken(ctx, auth.GenerateTokenRequest{Username: "blocked", Password: password})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrUserBlocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("valid token of blocked user is rejected", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		user := &models.User{ID: uuid.New(), Username: "blocked", RoleID: models.GuestRoleID}
+		accessToken, _, _ := service.JWTService.GenerateTokens(user)
+
+		blockedUser := *user
+		blockedUser.BlockedAt = &blockedAt
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(&blockedUser, nil)
+
+		// Act
+		validatedUser, err := service.ValidateToken(ctx, auth.ValidateTokenRequest{AccessToken: accessToken})
+
+		// Assert
+		assert.Nil(t, validatedUser)
+		assert.ErrorIs(t, err, models.ErrUserBlocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("token is rejected until reset password is changed", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		user := &models.User{
+			ID:                     uuid.New(),
+			Username:               "resetuser",
+			PasswordHash:           string(hashedPassword),
+			RoleID:                 models.GuestRoleID,
+			PasswordChangeRequired: true,
+		}
+		accessToken, _, _ := service.JWTService.GenerateTokens(user)
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("UpdateUserPassword", ctx, user.ID, mock.AnythingOfType("string")).Return(nil)
+
+		// Act
+		validatedUser, validateErr := service.ValidateToken(ctx, auth.ValidateTokenRequest{AccessToken: accessToken})
+		changeErr := service.ChangePassword(ctx, accessToken, auth.ChangePasswordRequest{
+			CurrentPassword: password,
+			NewPassword:     "new-password-456",
+		})
+
+		// Assert
+		assert.Nil(t, validatedUser)
+		assert.ErrorIs(t, validateErr, models.ErrPasswordChangeRequired)
+		assert.NoError(t, changeErr)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("password change with wrong current password", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		user := &models.User{ID: uuid.New(), Username: "user", PasswordHash: string(hashedPassword), RoleID: models.GuestRoleID}
+		accessToken, _, _ := service.JWTService.GenerateTokens(user)
+		mockRepo.On("FindUserByID", ctx, user.ID).Return(user, nil)
+
+		// Act
+		err := service.ChangePassword(ctx, accessToken, auth.ChangePasswordRequest{
+			CurrentPassword: "wrong",
+			NewPassword:     "new-password-456",
+		})
+
+		// Assert
+		assert.ErrorIs(t, err, models.ErrInvalidCredentials)
+		mockRepo.AssertNotCalled(t, "UpdateUserPassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthHandlers_PermissionRequired(t *testing.T) {
+	handlers := auth.NewAuthHandlers(newTestAuthService(new(mocks.UserRepository)))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	serve := func(user *auth.AuthenticatedUser, permissions ...string) int {
+		req := httptest.NewRequest(http.MethodGet, "/staff/checklist_items", nil)
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserKey, *user))
+		}
+		rec := httptest.NewRecorder()
+		handlers.PermissionRequired(permissions...)(next).ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("user with permission is allowed", func(t *testing.T) {
+		// Arrange
+		user := &auth.AuthenticatedUser{ID: uuid.NewString(), RoleID: 4, Permissions: []string{models.PermissionChecklistsView}}
+
+		// Act
+		code := serve(user, models.PermissionChecklistsView)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, code)
+	})
+
+	t.Run("any of listed permissions is enough", func(t *testing.T) {
+		// Arrange
+		user := &auth.AuthenticatedUser{ID: uuid.NewString(), RoleID: 4, Permissions: []string{models.PermissionReportsApprove}}
+
+		// Act
+		code := serve(user, models.PermissionReportsView, models.PermissionReportsApprove)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, code)
+	})
+
+	t.Run("role id alone does not grant access", func(t *testing.T) {
+		// Arrange
+		user := &auth.AuthenticatedUser{ID: uuid.NewString(), RoleID: models.AdminRoleID}
+
+		// Act
+		code := serve(user, models.PermissionChecklistsEdit)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("missing user context", func(t *testing.T) {
+		// Act
+		code := serve(nil, models.PermissionChecklistsView)
+
+		// Assert
+		assert.Equal(t, http.StatusInternalServerError, code)
+	})
+}
+
+func TestAuthService_ValidateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	rawKey, prefix, keyHash, err := auth.GenerateAPIKey()
+	assert.NoError(t, err)
+
+	serviceAccount := &models.User{
+		ID:               uuid.New(),
+		Username:         "ota_feed",
+		RoleID:           4,
+		IsServiceAccount: true,
+		Permissions:      []string{models.PermissionReportsView, models.PermissionReservationsCreate, models.PermissionStatisticsView},
+	}
+	newKey := func() *models.APIKey {
+		return &models.APIKey{
+			ID:      uuid.New(),
+			UserID:  serviceAccount.ID,
+			Prefix:  prefix,
+			KeyHash: keyHash,
+			Scopes:  []string{models.PermissionReservationsCreate, models.PermissionUsersManage},
+		}
+	}
+
+	t.Run("valid key grants intersection of scopes and role permissions", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		key := newKey()
+		mockRepo.On("FindAPIKeyByHash", ctx, keyHash).Return(key, nil)
+		mockRepo.On("FindUserByID", ctx, serviceAccount.ID).Return(serviceAccount, nil)
+		mockRepo.On("TouchAPIKey", ctx, key.ID).Return(nil)
+
+		// Act
+		validated, err := service.ValidateAPIKey(ctx, rawKey)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, serviceAccount.ID.String(), validated.UserID)
+		assert.Equal(t, key.ID.String(), validated.APIKeyID)
+		assert.Equal(t, []string{models.PermissionReservationsCreate}, validated.Permissions)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("malformed key is rejected without lookup", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+
+		// Act
+		validated, err := service.ValidateAPIKey(ctx, "not-a-key")
+
+		// Assert
+		assert.Nil(t, validated)
+		assert.ErrorIs(t, err, models.ErrInvalidAPIKey)
+		mockRepo.AssertNotCalled(t, "FindAPIKeyByHash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		mockRepo.On("FindAPIKeyByHash", ctx, keyHash).Return(nil, models.ErrAPIKeyNotFound)
+
+		// Act
+		_, err := service.ValidateAPIKey(ctx, rawKey)
+
+		// Assert
+		assert.ErrorIs(t, err, models.ErrInvalidAPIKey)
+	})
+
+	t.Run("revoked and expired keys are rejected", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+
+		revoked := newKey()
+		revoked.RevokedAt = &past
+		expired := newKey()
+		expired.ExpiresAt = &past
+
+		for _, key := range []*models.APIKey{revoked, expired} {
+			// Arrange
+			mockRepo := new(mocks.UserRepository)
+			service := newTestAuthService(mockRepo)
+			mockRepo.On("FindAPIKeyByHash", ctx, keyHash).Return(key, nil)
+
+			// Act
+			_, err := service.ValidateAPIKey(ctx, rawKey)
+
+			// Assert
+			assert.ErrorIs(t, err, models.ErrInvalidAPIKey)
+			mockRepo.AssertNotCalled(t, "TouchAPIKey", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("blocked service account", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		blockedAt := time.Now()
+		blocked := *serviceAccount
+		blocked.BlockedAt = &blockedAt
+		mockRepo.On("FindAPIKeyByHash", ctx, keyHash).Return(newKey(), nil)
+		mockRepo.On("FindUserByID", ctx, serviceAccount.ID).Return(&blocked, nil)
+
+		// Act
+		_, err := service.ValidateAPIKey(ctx, rawKey)
+
+		// Assert
+		assert.ErrorIs(t, err, models.ErrUserBlocked)
+	})
+
+	t.Run("service account cannot log in with password", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		mockRepo.On("FindUserByUsername", ctx, serviceAccount.Username).Return(serviceAccount, nil)
+
+		// Act
+		resp, err := service.GenerateToken(ctx, auth.GenerateTokenRequest{Username: serviceAccount.Username, Password: "!"})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrInvalidCredentials)
+	})
+}
+
+func TestAuthHandlers_AuthMiddleware_APIKey(t *testing.T) {
+	rawKey, _, keyHash, _ := auth.GenerateAPIKey()
+	serviceAccount := &models.User{
+		ID:               uuid.New(),
+		Username:         "bi_export",
+		RoleID:           4,
+		IsServiceAccount: true,
+		Permissions:      []string{models.PermissionStatisticsView},
+	}
+	key := &models.APIKey{ID: uuid.New(), UserID: serviceAccount.ID, KeyHash: keyHash, Scopes: []string{models.PermissionStatisticsView}}
+
+	mockRepo := new(mocks.UserRepository)
+	mockRepo.On("FindAPIKeyByHash", mock.Anything, keyHash).Return(key, nil)
+	mockRepo.On("FindUserByID", mock.Anything, serviceAccount.ID).Return(serviceAccount, nil)
+	mockRepo.On("TouchAPIKey", mock.Anything, key.ID).Return(nil)
+	handlers := auth.NewAuthHandlers(newTestAuthService(mockRepo))
+
+	var got auth.AuthenticatedUser
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = r.Context().Value(auth.UserKey).(auth.AuthenticatedUser)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	protected := handlers.AuthMiddleware(handlers.PermissionRequired(models.PermissionStatisticsView)(next))
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/staff/statistics", nil)
+	req.Header.Set("Authorization", "ApiKey "+rawKey)
+	rec := httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, serviceAccount.ID.String(), got.ID)
+	assert.Equal(t, key.ID.String(), got.APIKeyID)
+}
+
+// startOIDCLogin начинает вход через мок-провайдер и возвращает сохраненный state и authorization code
+func startOIDCLogin(t *testing.T, ctx context.Context, service *auth.AuthService, mockRepo *mocks.UserRepository, provider *oidctest.Provider, identity oidctest.Identity) (*models.OIDCLoginState, string) {
+	t.Helper()
+
+	var saved *models.OIDCLoginState
+	mockRepo.On("CreateOIDCState", ctx, mock.AnythingOfType("*models.OIDCLoginState")).
+		Run(func(args mock.Arguments) { saved = args.Get(1).(*models.OIDCLoginState) }).
+		Return(nil).Once()
+
+	resp, err := service.StartOIDCLogin(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, saved.State, resp.State)
+	assert.Contains(t, resp.AuthorizationURL, "code_challenge_method=S256")
+
+	code, err := provider.Authorize(resp.AuthorizationURL, identity)
+	assert.NoError(t, err)
+
+	return saved, code
+}
+
+func TestAuthService_OIDCLogin(t *testing.T) {
+	ctx := context.Background()
+
+	provider, err := oidctest.NewProvider("secret-guest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer provider.Close()
+
+	newOIDCService := func(mockRepo *mocks.UserRepository) *auth.AuthService {
+		service := newTestAuthService(mockRepo)
+		service.OIDC = auth.NewOIDCClient(auth.OIDCConfig{
+			ProviderName: "ostrovok",
+			IssuerURL:    provider.Issuer(),
+			ClientID:     "secret-guest",
+			RedirectURL:  "http://localhost:3000/auth/oidc/callback",
+			Scopes:       []string{"openid", "email", "profile"},
+		}, nil)
+		return service
+	}
+
+	identity := oidctest.Identity{
+		Subject:           "ostrovok-42",
+		Email:             "Guest@Example.com",
+		EmailVerified:     true,
+		PreferredUsername: "Guest.Traveller",
+	}
+
+	existingUser := &models.User{
+		ID:       uuid.New(),
+		Username: "traveller",
+		Email:    "guest@example.com",
+		RoleID:   models.GuestRoleID,
+	}
+
+	t.Run("disabled when issuer is not configured", func(t *testing.T) {
+		// Arrange
+		service := newTestAuthService(new(mocks.UserRepository))
+
+		// Act
+		_, startErr := service.StartOIDCLogin(ctx)
+		_, completeErr := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: "c", State: "s"})
+
+		// Assert
+		assert.ErrorIs(t, startErr, models.ErrOIDCDisabled)
+		assert.ErrorIs(t, completeErr, models.ErrOIDCDisabled)
+	})
+
+	t.Run("returning user is found by linked identity", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newOIDCService(mockRepo)
+		state, code := startOIDCLogin(t, ctx, service, mockRepo, provider, identity)
+
+		mockRepo.On("ConsumeOIDCState", ctx, state.State).Return(state, nil)
+		mockRepo.On("FindUserByIdentity", ctx, "ostrovok", "ostrovok-42").Return(existingUser, nil)
+		mockRepo.On("LinkUserIdentity", ctx, mock.MatchedBy(func(i *models.UserIdentity) bool {
+			return i.UserID == existingUser.ID && i.Subject == "ostrovok-42"
+		})).Return(nil)
+
+		// Act
+		resp, err := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: code, State: state.State})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		mockRepo.AssertNotCalled(t, "FindUserByEmail", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("existing account is linked by verified email", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newOIDCService(mockRepo)
+		state, code := startOIDCLogin(t, ctx, service, mockRepo, provider, identity)
+
+		mockRepo.On("ConsumeOIDCState", ctx, state.State).Return(state, nil)
+		mockRepo.On("FindUserByIdentity", ctx, "ostrovok", "ostrovok-42").Return(nil, models.ErrUserNotFound)
+		mockRepo.On("FindUserByEmail", ctx, "Guest@Example.com").Return(existingUser, nil)
+		mockRepo.On("LinkUserIdentity", ctx, mock.MatchedBy(func(i *models.UserIdentity) bool {
+			return i.UserID == existingUser.ID && i.Provider == "ostrovok"
+		})).Return(nil)
+
+		// Act
+		resp, err := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: code, State: state.State})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		mockRepo.AssertNotCalled(t, "RegisterOIDCUser", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("new user is provisioned as guest", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newOIDCService(mockRepo)
+		state, code := startOIDCLogin(t, ctx, service, mockRepo, provider, identity)
+
+		mockRepo.On("ConsumeOIDCState", ctx, state.State).Return(state, nil)
+		mockRepo.On("FindUserByIdentity", ctx, "ostrovok", "ostrovok-42").Return(nil, models.ErrUserNotFound)
+		mockRepo.On("FindUserByEmail", ctx, "Guest@Example.com").Return(nil, models.ErrUserNotFound)
+		// Первое имя занято - сервис повторяет попытку со случайным суффиксом
+		mockRepo.On("RegisterOIDCUser", ctx, mock.MatchedBy(func(u *models.User) bool {
+			return u.Username == "guest.traveller"
+		}), mock.Anything).Return(models.ErrUserExists).Once()
+		mockRepo.On("RegisterOIDCUser", ctx, mock.MatchedBy(func(u *models.User) bool {
+			return u.RoleID == models.GuestRoleID && u.Email == "Guest@Example.com" &&
+				len(u.Username) == len("guest.traveller_")+6
+		}), mock.AnythingOfType("*models.UserIdentity")).Return(nil).Once()
+
+		// Act
+		resp, err := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: code, State: state.State})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unverified email is neither linked nor stored", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newOIDCService(mockRepo)
+		unverified := identity
+		unverified.Subject = "ostrovok-43"
+		unverified.EmailVerified = false
+		state, code := startOIDCLogin(t, ctx, service, mockRepo, provider, unverified)
+
+		mockRepo.On("ConsumeOIDCState", ctx, state.State).Return(state, nil)
+		mockRepo.On("FindUserByIdentity", ctx, "ostrovok", "ostrovok-43").Return(nil, models.ErrUserNotFound)
+		mockRepo.On("RegisterOIDCUser", ctx, mock.MatchedBy(func(u *models.User) bool {
+			return u.Email == ""
+		}), mock.Anything).Return(nil)
+
+		// Act
+		_, err := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: code, State: state.State})
+
+		// Assert
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "FindUserByEmail", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown or expired state", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newOIDCService(mockRepo)
+		expired := &models.OIDCLoginState{State: "expired", ExpiresAt: time.Now().Add(-time.Minute)}
+
+		mockRepo.On("ConsumeOIDCState", ctx, "unknown").Return(nil, models.ErrNotFound)
+		mockRepo.On("ConsumeOIDCState", ctx, "expired").Return(expired, nil)
+
+		// Act
+		_, unknownErr := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: "c", State: "unknown"})
+		_, expiredErr := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: "c", State: "expired"})
+
+		// Assert
+		assert.ErrorIs(t, unknownErr, models.ErrOIDCInvalidState)
+		assert.ErrorIs(t, expiredErr, models.ErrOIDCInvalidState)
+	})
+
+	t.Run("wrong PKCE verifier is rejected by provider", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newOIDCService(mockRepo)
+		state, code := startOIDCLogin(t, ctx, service, mockRepo, provider, identity)
+		tampered := *state
+		tampered.CodeVerifier = "another-verifier-another-verifier-another-v"
+
+		mockRepo.On("ConsumeOIDCState", ctx, state.State).Return(&tampered, nil)
+
+		// Act
+		resp, err := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: code, State: state.State})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrOIDCAuthFailed)
+		mockRepo.AssertNotCalled(t, "FindUserByIdentity", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("nonce mismatch is rejected", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newOIDCService(mockRepo)
+		state, code := startOIDCLogin(t, ctx, service, mockRepo, provider, identity)
+		tampered := *state
+		tampered.Nonce = "other-nonce"
+
+		mockRepo.On("ConsumeOIDCState", ctx, state.State).Return(&tampered, nil)
+
+		// Act
+		_, err := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: code, State: state.State})
+
+		// Assert
+		assert.ErrorIs(t, err, models.ErrOIDCAuthFailed)
+	})
+
+	t.Run("blocked linked user cannot log in", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newOIDCService(mockRepo)
+		state, code := startOIDCLogin(t, ctx, service, mockRepo, provider, identity)
+		blockedAt := time.Now()
+		blocked := *existingUser
+		blocked.BlockedAt = &blockedAt
+
+		mockRepo.On("ConsumeOIDCState", ctx, state.State).Return(state, nil)
+		mockRepo.On("FindUserByIdentity", ctx, "ostrovok", "ostrovok-42").Return(&blocked, nil)
+		mockRepo.On("LinkUserIdentity", ctx, mock.Anything).Return(nil)
+
+		// Act
+		_, err := service.CompleteOIDCLogin(ctx, auth.OIDCCallbackRequest{Code: code, State: state.State})
+
+		// Assert
+		assert.ErrorIs(t, err, models.ErrUserBlocked)
+	})
+}
+
+func TestAuthService_Impersonation(t *testing.T) {
+	ctx := context.Background()
+	admin := &models.User{
+		ID:          uuid.New(),
+		Username:    "admin",
+		RoleID:      models.AdminRoleID,
+		Permissions: []string{models.PermissionUsersManage, models.PermissionUsersImpersonate},
+	}
+	guest := &models.User{ID: uuid.New(), Username: "guest", RoleID: models.GuestRoleID}
+	request := auth.ImpersonateUserRequest{Reason: "support ticket #42"}
+
+	t.Run("read-only token is issued for a guest and audited", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		mockRepo.On("FindUserByID", ctx, admin.ID).Return(admin, nil)
+		mockRepo.On("FindUserByID", ctx, guest.ID).Return(guest, nil)
+		mockRepo.On("RecordAuditLogEntry", ctx, mock.MatchedBy(func(entry *models.AuditLogEntry) bool {
+			return entry.Action == models.AuditActionImpersonationStarted &&
+				*entry.ActorID == admin.ID && entry.EntityID == guest.ID.String()
+		})).Return(nil)
+
+		// Act
+		resp, err := service.ImpersonateUser(ctx, admin.ID.String(), guest.ID, request)
+
+		// Assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.True(t, resp.ReadOnly)
+		assert.Equal(t, guest.Username, resp.Username)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("reason is required", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+
+		// Act
+		resp, err := service.ImpersonateUser(ctx, admin.ID.String(), guest.ID, auth.ImpersonateUserRequest{Reason: "  "})
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrInvalidImpersonationReason)
+		mockRepo.AssertNotCalled(t, "FindUserByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("staff account cannot be impersonated", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		moderator := &models.User{
+			ID:          uuid.New(),
+			Username:    "moderator",
+			RoleID:      models.ModeratorRoleID,
+			Permissions: []string{models.PermissionReportsView},
+		}
+		mockRepo.On("FindUserByID", ctx, admin.ID).Return(admin, nil)
+		mockRepo.On("FindUserByID", ctx, moderator.ID).Return(moderator, nil)
+
+		// Act
+		resp, err := service.ImpersonateUser(ctx, admin.ID.String(), moderator.ID, request)
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrImpersonationNotAllowed)
+		mockRepo.AssertNotCalled(t, "RecordAuditLogEntry", mock.Anything, mock.Anything)
+	})
+
+	t.Run("cannot impersonate yourself", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		mockRepo.On("FindUserByID", ctx, admin.ID).Return(admin, nil)
+
+		// Act
+		resp, err := service.ImpersonateUser(ctx, admin.ID.String(), admin.ID, request)
+
+		// Assert
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, models.ErrCannotModifySelf)
+	})
+
+	t.Run("impersonation token resolves both identities", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		token, _, _ := service.JWTService.GenerateImpersonationToken(guest, admin, time.Minute, false)
+		mockRepo.On("FindUserByID", ctx, admin.ID).Return(admin, nil)
+		mockRepo.On("FindUserByID", ctx, guest.ID).Return(guest, nil)
+
+		// Act
+		validatedUser, err := service.ValidateToken(ctx, auth.ValidateTokenRequest{AccessToken: token})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, guest.ID.String(), validatedUser.UserID)
+		assert.Equal(t, admin.ID.String(), validatedUser.ImpersonatorID)
+		assert.Equal(t, admin.Username, validatedUser.ImpersonatorUsername)
+		assert.True(t, validatedUser.ReadOnly)
+		assert.Empty(t, validatedUser.Permissions)
+	})
+
+	t.Run("token stops working when the actor loses the permission", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		token, _, _ := service.JWTService.GenerateImpersonationToken(guest, admin, time.Minute, true)
+		changedRole := *admin
+		changedRole.Permissions = []string{models.PermissionUsersManage}
+		mockRepo.On("FindUserByID", ctx, admin.ID).Return(&changedRole, nil)
+
+		// Act
+		validatedUser, err := service.ValidateToken(ctx, auth.ValidateTokenRequest{AccessToken: token})
+
+		// Assert
+		assert.Nil(t, validatedUser)
+		assert.ErrorIs(t, err, models.ErrInvalidToken)
+	})
+
+	t.Run("impersonation token cannot be refreshed or used to change password", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		service := newTestAuthService(mockRepo)
+		token, _, _ := service.JWTService.GenerateImpersonationToken(guest, admin, time.Minute, true)
+
+		// Act
+		refreshResp, refreshErr := service.RefreshToken(ctx, auth.RefreshTokenRequest{RefreshToken: token})
+		changeErr := service.ChangePassword(ctx, token, auth.ChangePasswordRequest{CurrentPassword: "a", NewPassword: "b"})
+
+		// Assert
+		assert.Nil(t, refreshResp)
+		assert.ErrorIs(t, refreshErr, models.ErrInvalidToken)
+		assert.ErrorIs(t, changeErr, models.ErrInvalidToken)
+		mockRepo.AssertNotCalled(t, "FindUserByID", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthHandlers_ImpersonationMiddleware(t *testing.T) {
+	admin := &models.User{
+		ID:          uuid.New(),
+		Username:    "admin",
+		RoleID:      models.AdminRoleID,
+		Permissions: []string{models.PermissionUsersImpersonate},
+	}
+	guest := &models.User{ID: uuid.New(), Username: "guest", RoleID: models.GuestRoleID}
+
+	serve := func(mockRepo *mocks.UserRepository, token, method string) *httptest.ResponseRecorder {
+		service := newTestAuthService(mockRepo)
+		handlers := auth.NewAuthHandlers(service)
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		req := httptest.NewRequest(method, "/assignments/my", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handlers.AuthMiddleware(next).ServeHTTP(rec, req)
+		return rec
+	}
+
+	auditedWith := func(status int) any {
+		return mock.MatchedBy(func(entry *models.AuditLogEntry) bool {
+			return entry.Action == models.AuditActionImpersonatedRequest &&
+				*entry.ActorID == admin.ID &&
+				entry.EntityID == guest.ID.String() &&
+				strings.Contains(string(entry.Details), fmt.Sprintf(`"status":%d`, status))
+		})
+	}
+
+	t.Run("read-only session allows GET, flags and audits the response", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		token, _, _ := newTestAuthService(mockRepo).JWTService.GenerateImpersonationToken(guest, admin, time.Minute, false)
+		mockRepo.On("FindUserByID", mock.Anything, admin.ID).Return(admin, nil)
+		mockRepo.On("FindUserByID", mock.Anything, guest.ID).Return(guest, nil)
+		mockRepo.On("RecordAuditLogEntry", mock.Anything, auditedWith(http.StatusNoContent)).Return(nil)
+
+		// Act
+		rec := serve(mockRepo, token, http.MethodGet)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, admin.Username, rec.Header().Get("X-Impersonated-By"))
+		assert.Equal(t, "read-only", rec.Header().Get("X-Impersonation-Mode"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("read-only session rejects mutating requests", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		token, _, _ := newTestAuthService(mockRepo).JWTService.GenerateImpersonationToken(guest, admin, time.Minute, false)
+		mockRepo.On("FindUserByID", mock.Anything, admin.ID).Return(admin, nil)
+		mockRepo.On("FindUserByID", mock.Anything, guest.ID).Return(guest, nil)
+		mockRepo.On("RecordAuditLogEntry", mock.Anything, auditedWith(http.StatusForbidden)).Return(nil)
+
+		// Act
+		rec := serve(mockRepo, token, http.MethodPost)
+
+		// Assert
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("write session allows mutating requests", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.UserRepository)
+		token, _, _ := newTestAuthService(mockRepo).JWTService.GenerateImpersonationToken(guest, admin, time.Minute, true)
+		mockRepo.On("FindUserByID", mock.Anything, admin.ID).Return(admin, nil)
+		mockRepo.On("FindUserByID", mock.Anything, guest.ID).Return(guest, nil)
+		mockRepo.On("RecordAuditLogEntry", mock.Anything, auditedWith(http.StatusNoContent)).Return(nil)
+
+		// Act
+		rec := serve(mockRepo, token, http.MethodPost)
+
+		// Assert
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "write", rec.Header().Get("X-Impersonation-Mode"))
+		mockRepo.AssertExpectations(t)
+	})
+}
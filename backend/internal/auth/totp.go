@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры TOTP по RFC 6238 (совместимы с Google Authenticator и аналогами)
+const (
+	totpPeriod     = 30
+	totpDigits     = 6
+	totpSkewSteps  = 1 // допускаем расхождение часов на один шаг в обе стороны
+	totpSecretSize = 20
+
+	recoveryCodesCount = 10
+	recoveryCodeLength = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// totpProvisioningURI формирует otpauth:// URI, который клиент отображает в виде QR-кода
+func totpProvisioningURI(issuer, accountName, secret string) string {
+	label := url.PathEscape(issuer + ":" + accountName)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+func totpCodeForStep(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%mod), nil
+}
+
+// TOTPCode возвращает одноразовый код для момента времени t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	return totpCodeForStep(secret, totpStep(t))
+}
+
+// verifyTOTP проверяет код с учетом допустимого расхождения часов и возвращает совпавший шаг
+func verifyTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if !isTOTPCodeFormat(code) {
+		return 0, false
+	}
+
+	current := totpStep(now)
+	for delta := int64(-totpSkewSteps); delta <= totpSkewSteps; delta++ {
+		expected, err := totpCodeForStep(secret, current+delta)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return current + delta, true
+		}
+	}
+
+	return 0, false
+}
+
+func isTOTPCodeFormat(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRecoveryCodes возвращает коды для показа пользователю и их хеши для хранения
+func generateRecoveryCodes() ([]string, []string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	buf := make([]byte, recoveryCodeLength)
+	for i := 0; i < recoveryCodesCount; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		var sb strings.Builder
+		for j, b := range buf {
+			if j == recoveryCodeLength/2 {
+				sb.WriteByte('-')
+			}
+			sb.WriteByte(alphabet[int(b)%len(alphabet)])
+		}
+
+		code := sb.String()
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
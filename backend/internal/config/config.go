@@ -12,6 +12,23 @@ type Config struct {
 	JWTAccessTokenLifetime  int    `env:"JWT_ACCESS_TOKEN_LIFETIME_SECONDS" env-default:"900"`
 	JWTRefreshTokenLifetime int    `env:"JWT_REFRESH_TOKEN_LIFETIME_SECONDS" env-default:"604800"`
 
+	MFARequiredForStaff  bool `env:"MFA_REQUIRED_FOR_STAFF" env-default:"false"`
+	MFAChallengeLifetime int  `env:"MFA_CHALLENGE_TOKEN_LIFETIME_SECONDS" env-default:"300"`
+	// Сколько неверных одноразовых кодов подряд допускается до блокировки ввода кодов и на сколько секунд
+	MFAMaxFailedAttempts int    `env:"MFA_MAX_FAILED_ATTEMPTS" env-default:"5"`
+	MFALockoutSeconds    int    `env:"MFA_LOCKOUT_SECONDS" env-default:"900"`
+	TOTPIssuer           string `env:"TOTP_ISSUER" env-default:"Secret Guest"`
+
+	OIDCProviderName  string `env:"OIDC_PROVIDER_NAME" env-default:"ostrovok"`
+	OIDCIssuerURL     string `env:"OIDC_ISSUER_URL" env-default:""`
+	OIDCClientID      string `env:"OIDC_CLIENT_ID" env-default:""`
+	OIDCClientSecret  string `env:"OIDC_CLIENT_SECRET" env-default:""`
+	OIDCRedirectURL   string `env:"OIDC_REDIRECT_URL" env-default:"http://localhost:3000/auth/oidc/callback"`
+	OIDCScopes        string `env:"OIDC_SCOPES" env-default:"openid email profile"`
+	OIDCStateLifetime int    `env:"OIDC_STATE_LIFETIME_SECONDS" env-default:"600"`
+
+	ImpersonationTokenLifetime int `env:"IMPERSONATION_TOKEN_LIFETIME_SECONDS" env-default:"900"`
+
 	PostgresHost     string `env:"POSTGRES_HOST" env-default:"localhost"`
 	PostgresPort     int    `env:"POSTGRES_PORT" env-default:"5432"`
 	PostgresUser     string `env:"POSTGRES_USER" env-default:"myuser"`
@@ -19,10 +36,28 @@ type Config struct {
 	PostgresDB       string `env:"POSTGRES_DB" env-default:"mydb"`
 
 	AssignmentDeadlineHours int `env:"ASSIGNMENT_DEADLINE_HOURS" env-default:"24"`
+	// Срок удержания взятого предложения(accept_deadline) в минутах с момента, когда его можно принять(0 - без ограничения)
+	AssignmentHoldMinutes int `env:"ASSIGNMENT_HOLD_MINUTES" env-default:"120"`
+	// Период проверки истекших сроков удержания в секундах
+	AssignmentHoldSweepSeconds int `env:"ASSIGNMENT_HOLD_SWEEP_SECONDS" env-default:"60"`
+	// Допустимое расстояние от устройства до объекта при отметке заезда/выезда
+	StayCheckRadiusMeters int `env:"STAY_CHECK_RADIUS_METERS" env-default:"500"`
+	// Насколько раньше даты заезда можно отметить заезд и позже даты выезда - выезд
+	StayCheckToleranceHours int `env:"STAY_CHECK_TOLERANCE_HOURS" env-default:"12"`
+	// Срок сдачи отчета в часах после даты выезда
+	ReportDeadlineHoursAfterCheckout int `env:"REPORT_DEADLINE_HOURS_AFTER_CHECKOUT" env-default:"48"`
+	// За сколько часов до срока сдачи отчета напомнить гостю(по одному напоминанию на каждое значение)
+	ReportReminderHoursBeforeDue []int `env:"REPORT_REMINDER_HOURS_BEFORE_DUE" env-default:"24,2" env-separator:","`
+	// Период проверки сроков сдачи отчетов в секундах
+	ReportDeadlineSweepSeconds int `env:"REPORT_DEADLINE_SWEEP_SECONDS" env-default:"300"`
+	// Минимальный приоритет проверки объекта, при котором по бронированию OTA создается предложение(0 - всегда)
+	AssignmentPriorityThreshold float64 `env:"ASSIGNMENT_PRIORITY_THRESHOLD" env-default:"0"`
 
 	DefaultPageLimit int `env:"DEFAULT_PAGE_LIMIT" env-default:"20"`
 
 	FrontendURL string `env:"FRONTEND_URL" env-default:"http://localhost:3000"`
+	// Внешний адрес API, из него строятся ссылки на календарную ленту
+	PublicAPIURL string `env:"PUBLIC_API_URL" env-default:"http://localhost:8080"`
 
 	ImagekitPublicKey   string `env:"IMAGEKIT_PUBLIC_KEY"`
 	ImagekitPrivateKey  string `env:"IMAGEKIT_PRIVATE_KEY"`
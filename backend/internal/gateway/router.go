@@ -32,25 +32,39 @@ func NewRouter(ctx context.Context, cfg *config.Config, authHandlers *auth.AuthH
 	r.HandleFunc("/auth/validate", authHandlers.ValidateToken).Methods(http.MethodPost)
 	r.HandleFunc("/auth/refresh", authHandlers.RefreshToken).Methods(http.MethodPost)
 	r.HandleFunc("/auth/register", authHandlers.RegisterUser).Methods(http.MethodPost)
+	r.HandleFunc("/auth/password", authHandlers.ChangePassword).Methods(http.MethodPost)
+	r.HandleFunc("/auth/token/mfa", authHandlers.VerifyMFA).Methods(http.MethodPost)
+	r.HandleFunc("/auth/token/mfa/enroll", authHandlers.EnrollMFAWithChallenge).Methods(http.MethodPost)
+	r.HandleFunc("/auth/oidc/login", authHandlers.StartOIDCLogin).Methods(http.MethodGet)
+	r.HandleFunc("/auth/oidc/callback", authHandlers.CompleteOIDCLogin).Methods(http.MethodPost)
 
 	// - - - -  PUBLIC
-	// ....
+	r.HandleFunc("/calendar/{token}.ics", secretGuestHandler.GetCalendarFeed).Methods(http.MethodGet) // календарная лента по секретной ссылке
 
 	// - - - -  FOR AUTHENTICATED
 	protectedRouter := r.PathPrefix("/").Subrouter()
 	protectedRouter.Use(authHandlers.AuthMiddleware)
 
+	protectedRouter.HandleFunc("/auth/mfa", authHandlers.GetMFAStatus).Methods(http.MethodGet)        // auth
+	protectedRouter.HandleFunc("/auth/mfa/enroll", authHandlers.EnrollMFA).Methods(http.MethodPost)   // auth
+	protectedRouter.HandleFunc("/auth/mfa/confirm", authHandlers.ConfirmMFA).Methods(http.MethodPost) // auth
+	protectedRouter.HandleFunc("/auth/mfa/disable", authHandlers.DisableMFA).Methods(http.MethodPost) // auth
+
 	protectedRouter.HandleFunc("/listings", secretGuestHandler.GetListings).Methods(http.MethodGet)         // listings
 	protectedRouter.HandleFunc("/listings/{id}", secretGuestHandler.GetListingByID).Methods(http.MethodGet) // listings
 
-	protectedRouter.HandleFunc("/assignments/my", secretGuestHandler.GetMyAssignments).Methods(http.MethodGet)                   // assignments
-	protectedRouter.HandleFunc("/assignments/my/{id}", secretGuestHandler.GetMyAssignmentByID).Methods(http.MethodGet)           // assignments
-	protectedRouter.HandleFunc("/assignments/my/{id}/accept", secretGuestHandler.AcceptMyAssignment).Methods(http.MethodPatch)   // assignments
-	protectedRouter.HandleFunc("/assignments/my/{id}/decline", secretGuestHandler.DeclineMyAssignment).Methods(http.MethodPatch) // assignments
+	protectedRouter.HandleFunc("/assignments/my", secretGuestHandler.GetMyAssignments).Methods(http.MethodGet)                    // assignments
+	protectedRouter.HandleFunc("/assignments/my/{id}", secretGuestHandler.GetMyAssignmentByID).Methods(http.MethodGet)            // assignments
+	protectedRouter.HandleFunc("/assignments/my/{id}/accept", secretGuestHandler.AcceptMyAssignment).Methods(http.MethodPatch)    // assignments
+	protectedRouter.HandleFunc("/assignments/my/{id}/decline", secretGuestHandler.DeclineMyAssignment).Methods(http.MethodPatch)  // assignments
+	protectedRouter.HandleFunc("/assignments/my/{id}/checkin", secretGuestHandler.CheckInMyAssignment).Methods(http.MethodPost)   // отметка заезда
+	protectedRouter.HandleFunc("/assignments/my/{id}/checkout", secretGuestHandler.CheckOutMyAssignment).Methods(http.MethodPost) // отметка выезда
 
-	protectedRouter.HandleFunc("/assignments", secretGuestHandler.GetFreeAssignments).Methods(http.MethodGet)                  // assignments
-	protectedRouter.HandleFunc("/assignments/{id}", secretGuestHandler.GetFreeAssignmentsByID).Methods(http.MethodGet)         // assignments
-	protectedRouter.HandleFunc("/assignments/{id}/take", secretGuestHandler.TakeFreeAssignmentsByID).Methods(http.MethodPatch) // assignments
+	protectedRouter.HandleFunc("/assignments", secretGuestHandler.GetFreeAssignments).Methods(http.MethodGet)                       // assignments
+	protectedRouter.HandleFunc("/assignments/{id}", secretGuestHandler.GetFreeAssignmentsByID).Methods(http.MethodGet)              // assignments
+	protectedRouter.HandleFunc("/assignments/{id}/take", secretGuestHandler.TakeFreeAssignmentsByID).Methods(http.MethodPatch)      // assignments
+	protectedRouter.HandleFunc("/assignments/{id}/waitlist", secretGuestHandler.JoinAssignmentWaitlist).Methods(http.MethodPost)    // assignments
+	protectedRouter.HandleFunc("/assignments/{id}/waitlist", secretGuestHandler.LeaveAssignmentWaitlist).Methods(http.MethodDelete) // assignments
 
 	protectedRouter.HandleFunc("/reports/my", secretGuestHandler.GetMyReports).Methods(http.MethodGet)                 // reports
 	protectedRouter.HandleFunc("/reports/my/{id}", secretGuestHandler.GetMyReportByID).Methods(http.MethodGet)         // reports
@@ -58,75 +72,165 @@ func NewRouter(ctx context.Context, cfg *config.Config, authHandlers *auth.AuthH
 	protectedRouter.HandleFunc("/reports/my/{id}/submit", secretGuestHandler.SubmitMyReport).Methods(http.MethodPatch) // reports
 	protectedRouter.HandleFunc("/reports/my/{id}/refuse", secretGuestHandler.RefuseMyReport).Methods(http.MethodPatch) // reports
 
-	protectedRouter.HandleFunc("/profiles/my", secretGuestHandler.GetMyProfile).Methods(http.MethodGet) // profiles
+	protectedRouter.HandleFunc("/applications/my", secretGuestHandler.GetMyApplication).Methods(http.MethodGet)     // applications
+	protectedRouter.HandleFunc("/applications/my", secretGuestHandler.SubmitMyApplication).Methods(http.MethodPost) // applications
+
+	protectedRouter.HandleFunc("/profiles/my", secretGuestHandler.GetMyProfile).Methods(http.MethodGet)                              // profiles
+	protectedRouter.HandleFunc("/profiles/my", secretGuestHandler.UpdateMyProfile).Methods(http.MethodPatch)                         // profiles
+	protectedRouter.HandleFunc("/profiles/my", secretGuestHandler.DeleteMyAccount).Methods(http.MethodDelete)                        // profiles
+	protectedRouter.HandleFunc("/profiles/my/export", secretGuestHandler.ExportMyData).Methods(http.MethodGet)                       // profiles
+	protectedRouter.HandleFunc("/profiles/my/calendar/token", secretGuestHandler.RegenerateMyCalendarToken).Methods(http.MethodPost) // calendar
+
+	protectedRouter.HandleFunc("/profiles/my/rewards", secretGuestHandler.GetMyRewards).Methods(http.MethodGet) // rewards
+	protectedRouter.HandleFunc("/profiles/my/points", secretGuestHandler.GetMyPoints).Methods(http.MethodGet)   // points
+	protectedRouter.HandleFunc("/profiles/my/badges", secretGuestHandler.GetMyBadges).Methods(http.MethodGet)   // badges
 
 	protectedRouter.HandleFunc("/journal/my", secretGuestHandler.GetMyHistory).Methods(http.MethodGet) // journal
 
+	protectedRouter.HandleFunc("/leaderboard", secretGuestHandler.GetLeaderboard).Methods(http.MethodGet) // leaderboard
+
+	protectedRouter.HandleFunc("/notifications/my", secretGuestHandler.GetMyNotifications).Methods(http.MethodGet)                 // notifications
+	protectedRouter.HandleFunc("/notifications/my/{id}/read", secretGuestHandler.MarkMyNotificationRead).Methods(http.MethodPatch) // notifications
+
 	// - - - - UPLOADS
 	protectedRouter.HandleFunc("/uploads/generate-url", secretGuestHandler.GenerateUploadURL).Methods(http.MethodPost)
 
+	// Доступ к маршрутам персонала определяется правами роли(таблица role_permissions)
+	requirePermission := func(permission string, handler http.HandlerFunc) http.Handler {
+		return authHandlers.PermissionRequired(permission)(handler)
+	}
+
 	// - - - - FOR STAFF
 	staffRouter := protectedRouter.PathPrefix("/staff").Subrouter()
-	staffRouter.Use(authHandlers.RoleRequiredMiddleware(models.AdminRoleID, models.ModeratorRoleID))
 
-	staffRouter.HandleFunc("/statistics", secretGuestHandler.GetStatistics).Methods(http.MethodGet) // statistics
+	staffRouter.Handle("/statistics", requirePermission(models.PermissionStatisticsView, secretGuestHandler.GetStatistics)).Methods(http.MethodGet) // statistics
+
+	staffRouter.Handle("/sg_reservations", requirePermission(models.PermissionReservationsView, secretGuestHandler.GetAllOTAReservations)).Methods(http.MethodGet)                             // reservations
+	staffRouter.Handle("/sg_reservations/{id}", requirePermission(models.PermissionReservationsView, secretGuestHandler.GetOTAReservationByID)).Methods(http.MethodGet)                        // reservations
+	staffRouter.Handle("/sg_reservations/{id}/no-show", requirePermission(models.PermissionReservationsManage, secretGuestHandler.UpdateOTAReservationStatusNoShow)).Methods(http.MethodPatch) // reservations
+
+	staffRouter.Handle("/assignments", requirePermission(models.PermissionAssignmentsView, secretGuestHandler.GetAllAssignments)).Methods(http.MethodGet)                      // assignments
+	staffRouter.Handle("/assignments/{id}", requirePermission(models.PermissionAssignmentsView, secretGuestHandler.GetAssignmentByID_AsStaff)).Methods(http.MethodGet)         // assignments
+	staffRouter.Handle("/assignments", requirePermission(models.PermissionAssignmentsManage, secretGuestHandler.CreateAssignment)).Methods(http.MethodPost)                    // assignments
+	staffRouter.Handle("/assignments/{id}/cancel", requirePermission(models.PermissionAssignmentsManage, secretGuestHandler.CancelAssignment)).Methods(http.MethodPatch)       // assignments
+	staffRouter.Handle("/assignments/{id}/release", requirePermission(models.PermissionAssignmentsManage, secretGuestHandler.ReleaseAssignment)).Methods(http.MethodPatch)     // assignments
+	staffRouter.Handle("/assignments/{id}/reassign", requirePermission(models.PermissionAssignmentsManage, secretGuestHandler.ReassignAssignment)).Methods(http.MethodPatch)   // assignments
+	staffRouter.Handle("/assignments/{id}/extend", requirePermission(models.PermissionAssignmentsManage, secretGuestHandler.ExtendAssignment)).Methods(http.MethodPatch)       // assignments
+	staffRouter.Handle("/assignments/bulk/cancel", requirePermission(models.PermissionAssignmentsManage, secretGuestHandler.BulkCancelAssignments)).Methods(http.MethodPost)   // assignments
+	staffRouter.Handle("/assignments/bulk/extend", requirePermission(models.PermissionAssignmentsManage, secretGuestHandler.BulkExtendAssignments)).Methods(http.MethodPost)   // assignments
+	staffRouter.Handle("/assignments/bulk/release", requirePermission(models.PermissionAssignmentsManage, secretGuestHandler.BulkReleaseAssignments)).Methods(http.MethodPost) // assignments
+
+	staffRouter.Handle("/reports", requirePermission(models.PermissionReportsView, secretGuestHandler.GetAllReports)).Methods(http.MethodGet)                   // reports
+	staffRouter.Handle("/reports/{id}", requirePermission(models.PermissionReportsView, secretGuestHandler.GetReportByID_AsStaff)).Methods(http.MethodGet)      // reports
+	staffRouter.Handle("/reports/{id}/approve", requirePermission(models.PermissionReportsApprove, secretGuestHandler.ApproveReport)).Methods(http.MethodPatch) // reports
+	staffRouter.Handle("/reports/{id}/reject", requirePermission(models.PermissionReportsApprove, secretGuestHandler.RejectReport)).Methods(http.MethodPatch)   // reports
+
+	staffRouter.Handle("/users", requirePermission(models.PermissionUsersView, secretGuestHandler.GetAllUsers)).Methods(http.MethodGet)              // users
+	staffRouter.Handle("/users/{id}", requirePermission(models.PermissionUsersView, secretGuestHandler.GetUserByID_AsStaff)).Methods(http.MethodGet) // users
+
+	staffRouter.Handle("/applications", requirePermission(models.PermissionApplicationsReview, secretGuestHandler.GetGuestApplications)).Methods(http.MethodGet)                     // applications
+	staffRouter.Handle("/applications/{id}", requirePermission(models.PermissionApplicationsReview, secretGuestHandler.GetGuestApplicationByID)).Methods(http.MethodGet)             // applications
+	staffRouter.Handle("/applications/{id}/approve", requirePermission(models.PermissionApplicationsReview, secretGuestHandler.ApproveGuestApplication)).Methods(http.MethodPatch)   // applications
+	staffRouter.Handle("/applications/{id}/reject", requirePermission(models.PermissionApplicationsReview, secretGuestHandler.RejectGuestApplication)).Methods(http.MethodPatch)     // applications
+	staffRouter.Handle("/applications/{id}/waitlist", requirePermission(models.PermissionApplicationsReview, secretGuestHandler.WaitlistGuestApplication)).Methods(http.MethodPatch) // applications
 
-	staffRouter.HandleFunc("/sg_reservations", secretGuestHandler.GetAllOTAReservations).Methods(http.MethodGet)                           // reservations
-	staffRouter.HandleFunc("/sg_reservations/{id}", secretGuestHandler.GetOTAReservationByID).Methods(http.MethodGet)                      // reservations
-	staffRouter.HandleFunc("/sg_reservations/{id}/no-show", secretGuestHandler.UpdateOTAReservationStatusNoShow).Methods(http.MethodPatch) // reservations
+	staffRouter.Handle("/profiles", requirePermission(models.PermissionProfilesView, secretGuestHandler.GetAllProfiles)).Methods(http.MethodGet)                 // profiles
+	staffRouter.Handle("/profiles/{user_id}", requirePermission(models.PermissionProfilesView, secretGuestHandler.GetProfileByUserID)).Methods(http.MethodGet)   // profiles
+	staffRouter.Handle("/profiles/{user_id}/points", requirePermission(models.PermissionProfilesView, secretGuestHandler.GetUserPoints)).Methods(http.MethodGet) // points
 
-	staffRouter.HandleFunc("/assignments", secretGuestHandler.GetAllAssignments).Methods(http.MethodGet)              // assignments
-	staffRouter.HandleFunc("/assignments/{id}", secretGuestHandler.GetAssignmentByID_AsStaff).Methods(http.MethodGet) // assignments
-	staffRouter.HandleFunc("/assignments/{id}/cancel", secretGuestHandler.CancelAssignment).Methods(http.MethodPatch) // assignments
+	staffRouter.Handle("/rewards", requirePermission(models.PermissionRewardsView, secretGuestHandler.GetRewards)).Methods(http.MethodGet) // rewards
 
-	staffRouter.HandleFunc("/reports", secretGuestHandler.GetAllReports).Methods(http.MethodGet)                // reports
-	staffRouter.HandleFunc("/reports/{id}", secretGuestHandler.GetReportByID_AsStaff).Methods(http.MethodGet)   // reports
-	staffRouter.HandleFunc("/reports/{id}/approve", secretGuestHandler.ApproveReport).Methods(http.MethodPatch) // reports
-	staffRouter.HandleFunc("/reports/{id}/reject", secretGuestHandler.RejectReport).Methods(http.MethodPatch)   // reports
+	staffRouter.Handle("/listings", requirePermission(models.PermissionListingsView, secretGuestHandler.GetPrioritizedListings)).Methods(http.MethodGet)     // listings
+	staffRouter.Handle("/listings/{id}", requirePermission(models.PermissionListingsView, secretGuestHandler.GetPrioritizedListing)).Methods(http.MethodGet) // listings
 
-	staffRouter.HandleFunc("/users", secretGuestHandler.GetAllUsers).Methods(http.MethodGet) // users
+	staffRouter.Handle("/campaigns", requirePermission(models.PermissionCampaignsView, secretGuestHandler.GetCampaigns)).Methods(http.MethodGet)                      // campaigns
+	staffRouter.Handle("/campaigns/{id}", requirePermission(models.PermissionCampaignsView, secretGuestHandler.GetCampaign)).Methods(http.MethodGet)                  // campaigns
+	staffRouter.Handle("/campaigns/{id}/listings", requirePermission(models.PermissionCampaignsView, secretGuestHandler.GetCampaignListings)).Methods(http.MethodGet) // campaigns
 
-	staffRouter.HandleFunc("/profiles", secretGuestHandler.GetAllProfiles).Methods(http.MethodGet)               // profiles
-	staffRouter.HandleFunc("/profiles/{user_id}", secretGuestHandler.GetProfileByUserID).Methods(http.MethodGet) // profiles
+	staffRouter.Handle("/declines/analytics", requirePermission(models.PermissionAssignmentsView, secretGuestHandler.GetDeclineAnalytics)).Methods(http.MethodGet)             // assignment declines
+	staffRouter.Handle("/declines/repeated", requirePermission(models.PermissionAssignmentsView, secretGuestHandler.GetRepeatedlyDeclinedAssignments)).Methods(http.MethodGet) // assignment declines
 
 	///
 
-	staffRouter.HandleFunc("/answer_types", secretGuestHandler.GetAnswerTypes).Methods(http.MethodGet)                  // answer_types
-	staffRouter.HandleFunc("/answer_types/{id:[0-9]+}", secretGuestHandler.GetAnswerTypeByID).Methods(http.MethodGet)   // answer_types
-	staffRouter.HandleFunc("/answer_types", secretGuestHandler.CreateAnswerType).Methods(http.MethodPost)               // answer_types
-	staffRouter.HandleFunc("/answer_types/{id:[0-9]+}", secretGuestHandler.UpdateAnswerType).Methods(http.MethodPatch)  // answer_types
-	staffRouter.HandleFunc("/answer_types/{id:[0-9]+}", secretGuestHandler.DeleteAnswerType).Methods(http.MethodDelete) // answer_types
+	staffRouter.Handle("/answer_types", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetAnswerTypes)).Methods(http.MethodGet)                  // answer_types
+	staffRouter.Handle("/answer_types/{id:[0-9]+}", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetAnswerTypeByID)).Methods(http.MethodGet)   // answer_types
+	staffRouter.Handle("/answer_types", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.CreateAnswerType)).Methods(http.MethodPost)               // answer_types
+	staffRouter.Handle("/answer_types/{id:[0-9]+}", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.UpdateAnswerType)).Methods(http.MethodPatch)  // answer_types
+	staffRouter.Handle("/answer_types/{id:[0-9]+}", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.DeleteAnswerType)).Methods(http.MethodDelete) // answer_types
 
-	staffRouter.HandleFunc("/media_requirements", secretGuestHandler.GetMediaRequirements).Methods(http.MethodGet) // media_requirements
+	staffRouter.Handle("/media_requirements", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetMediaRequirements)).Methods(http.MethodGet) // media_requirements
 
-	staffRouter.HandleFunc("/listing_types", secretGuestHandler.GetListingTypes).Methods(http.MethodGet)                  // listing_types
-	staffRouter.HandleFunc("/listing_types/{id:[0-9]+}", secretGuestHandler.GetListingTypeByID).Methods(http.MethodGet)   // listing_types
-	staffRouter.HandleFunc("/listing_types", secretGuestHandler.CreateListingType).Methods(http.MethodPost)               // listing_types
-	staffRouter.HandleFunc("/listing_types/{id:[0-9]+}", secretGuestHandler.UpdateListingType).Methods(http.MethodPatch)  // listing_types
-	staffRouter.HandleFunc("/listing_types/{id:[0-9]+}", secretGuestHandler.DeleteListingType).Methods(http.MethodDelete) // listing_types
+	staffRouter.Handle("/listing_types", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetListingTypes)).Methods(http.MethodGet)                  // listing_types
+	staffRouter.Handle("/listing_types/{id:[0-9]+}", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetListingTypeByID)).Methods(http.MethodGet)   // listing_types
+	staffRouter.Handle("/listing_types", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.CreateListingType)).Methods(http.MethodPost)               // listing_types
+	staffRouter.Handle("/listing_types/{id:[0-9]+}", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.UpdateListingType)).Methods(http.MethodPatch)  // listing_types
+	staffRouter.Handle("/listing_types/{id:[0-9]+}", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.DeleteListingType)).Methods(http.MethodDelete) // listing_types
 
-	staffRouter.HandleFunc("/checklist_sections", secretGuestHandler.GetChecklistSections).Methods(http.MethodGet)                  // checklist_sections
-	staffRouter.HandleFunc("/checklist_sections/{id:[0-9]+}", secretGuestHandler.GetChecklistSectionByID).Methods(http.MethodGet)   // checklist_sections
-	staffRouter.HandleFunc("/checklist_sections", secretGuestHandler.CreateChecklistSection).Methods(http.MethodPost)               // checklist_sections
-	staffRouter.HandleFunc("/checklist_sections/{id:[0-9]+}", secretGuestHandler.UpdateChecklistSection).Methods(http.MethodPatch)  // checklist_sections
-	staffRouter.HandleFunc("/checklist_sections/{id:[0-9]+}", secretGuestHandler.DeleteChecklistSection).Methods(http.MethodDelete) // checklist_sections
+	staffRouter.Handle("/checklist_sections", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetChecklistSections)).Methods(http.MethodGet)                  // checklist_sections
+	staffRouter.Handle("/checklist_sections/{id:[0-9]+}", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetChecklistSectionByID)).Methods(http.MethodGet)   // checklist_sections
+	staffRouter.Handle("/checklist_sections", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.CreateChecklistSection)).Methods(http.MethodPost)               // checklist_sections
+	staffRouter.Handle("/checklist_sections/{id:[0-9]+}", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.UpdateChecklistSection)).Methods(http.MethodPatch)  // checklist_sections
+	staffRouter.Handle("/checklist_sections/{id:[0-9]+}", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.DeleteChecklistSection)).Methods(http.MethodDelete) // checklist_sections
 
-	staffRouter.HandleFunc("/checklist_items", secretGuestHandler.GetChecklistItems).Methods(http.MethodGet)                  // checklist_items
-	staffRouter.HandleFunc("/checklist_items/{id:[0-9]+}", secretGuestHandler.GetChecklistItemByID).Methods(http.MethodGet)   // checklist_items
-	staffRouter.HandleFunc("/checklist_items", secretGuestHandler.CreateChecklistItem).Methods(http.MethodPost)               // checklist_items
-	staffRouter.HandleFunc("/checklist_items/{id:[0-9]+}", secretGuestHandler.UpdateChecklistItem).Methods(http.MethodPatch)  // checklist_items
-	staffRouter.HandleFunc("/checklist_items/{id:[0-9]+}", secretGuestHandler.DeleteChecklistItem).Methods(http.MethodDelete) // checklist_items
+	staffRouter.Handle("/checklist_items", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetChecklistItems)).Methods(http.MethodGet)                  // checklist_items
+	staffRouter.Handle("/checklist_items/{id:[0-9]+}", requirePermission(models.PermissionChecklistsView, secretGuestHandler.GetChecklistItemByID)).Methods(http.MethodGet)   // checklist_items
+	staffRouter.Handle("/checklist_items", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.CreateChecklistItem)).Methods(http.MethodPost)               // checklist_items
+	staffRouter.Handle("/checklist_items/{id:[0-9]+}", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.UpdateChecklistItem)).Methods(http.MethodPatch)  // checklist_items
+	staffRouter.Handle("/checklist_items/{id:[0-9]+}", requirePermission(models.PermissionChecklistsEdit, secretGuestHandler.DeleteChecklistItem)).Methods(http.MethodDelete) // checklist_items
 
 	// staffRouter.HandleFunc("/journal", secretGuestHandler.GetJournal).Methods(http.MethodGet) // journal
 
 	///
 
-	// - - - - FOR ONLY ADMINS
+	// - - - - FOR ONLY ADMINS(по умолчанию эти права есть только у роли admin)
 	adminRouter := protectedRouter.PathPrefix("/admin").Subrouter()
-	adminRouter.Use(authHandlers.RoleRequiredMiddleware(models.AdminRoleID))
 
-	adminRouter.HandleFunc("/listings", secretGuestHandler.CreateListing).Methods(http.MethodPost)               // listings
-	adminRouter.HandleFunc("/sg_reservations", secretGuestHandler.CreateOTAReservation).Methods(http.MethodPost) // reservations
+	adminRouter.Handle("/listings", requirePermission(models.PermissionListingsCreate, secretGuestHandler.CreateListing)).Methods(http.MethodPost)                   // listings
+	adminRouter.Handle("/sg_reservations", requirePermission(models.PermissionReservationsCreate, secretGuestHandler.CreateOTAReservation)).Methods(http.MethodPost) // reservations
+
+	adminRouter.Handle("/users/{id}/role", requirePermission(models.PermissionUsersManage, secretGuestHandler.ChangeUserRole)).Methods(http.MethodPatch)             // users
+	adminRouter.Handle("/users/{id}/block", requirePermission(models.PermissionUsersManage, secretGuestHandler.BlockUser)).Methods(http.MethodPatch)                 // users
+	adminRouter.Handle("/users/{id}/unblock", requirePermission(models.PermissionUsersManage, secretGuestHandler.UnblockUser)).Methods(http.MethodPatch)             // users
+	adminRouter.Handle("/users/{id}/reset-password", requirePermission(models.PermissionUsersManage, secretGuestHandler.ResetUserPassword)).Methods(http.MethodPost) // users
+	adminRouter.Handle("/users/{id}/impersonate", requirePermission(models.PermissionUsersImpersonate, authHandlers.ImpersonateUser)).Methods(http.MethodPost)       // users
+
+	adminRouter.Handle("/audit_log", requirePermission(models.PermissionAuditView, secretGuestHandler.GetAuditLog)).Methods(http.MethodGet) // audit_log
+
+	adminRouter.Handle("/permissions", requirePermission(models.PermissionRolesManage, secretGuestHandler.GetPermissions)).Methods(http.MethodGet)                       // roles
+	adminRouter.Handle("/roles", requirePermission(models.PermissionRolesManage, secretGuestHandler.GetRoles)).Methods(http.MethodGet)                                   // roles
+	adminRouter.Handle("/roles", requirePermission(models.PermissionRolesManage, secretGuestHandler.CreateRole)).Methods(http.MethodPost)                                // roles
+	adminRouter.Handle("/roles/{id:[0-9]+}", requirePermission(models.PermissionRolesManage, secretGuestHandler.UpdateRole)).Methods(http.MethodPatch)                   // roles
+	adminRouter.Handle("/roles/{id:[0-9]+}", requirePermission(models.PermissionRolesManage, secretGuestHandler.DeleteRole)).Methods(http.MethodDelete)                  // roles
+	adminRouter.Handle("/roles/{id:[0-9]+}/permissions", requirePermission(models.PermissionRolesManage, secretGuestHandler.SetRolePermissions)).Methods(http.MethodPut) // roles
+
+	adminRouter.Handle("/service_accounts", requirePermission(models.PermissionServiceAccountsManage, secretGuestHandler.CreateServiceAccount)).Methods(http.MethodPost)       // service_accounts
+	adminRouter.Handle("/service_accounts/{id}/api_keys", requirePermission(models.PermissionServiceAccountsManage, secretGuestHandler.GetAPIKeys)).Methods(http.MethodGet)    // service_accounts
+	adminRouter.Handle("/service_accounts/{id}/api_keys", requirePermission(models.PermissionServiceAccountsManage, secretGuestHandler.CreateAPIKey)).Methods(http.MethodPost) // service_accounts
+	adminRouter.Handle("/api_keys/{id}/revoke", requirePermission(models.PermissionServiceAccountsManage, secretGuestHandler.RevokeAPIKey)).Methods(http.MethodPatch)          // service_accounts
+
+	adminRouter.Handle("/rewards/export", requirePermission(models.PermissionRewardsManage, secretGuestHandler.ExportRewards)).Methods(http.MethodGet)                                // rewards
+	adminRouter.Handle("/rewards/payouts", requirePermission(models.PermissionRewardsManage, secretGuestHandler.PayRewards)).Methods(http.MethodPost)                                 // rewards
+	adminRouter.Handle("/rewards/{id}/void", requirePermission(models.PermissionRewardsManage, secretGuestHandler.VoidReward)).Methods(http.MethodPatch)                              // rewards
+	adminRouter.Handle("/reward_policies", requirePermission(models.PermissionRewardsManage, secretGuestHandler.GetRewardPolicies)).Methods(http.MethodGet)                           // rewards
+	adminRouter.Handle("/reward_policies/{listing_type_id:[0-9]+}", requirePermission(models.PermissionRewardsManage, secretGuestHandler.UpdateRewardPolicy)).Methods(http.MethodPut) // rewards
+
+	adminRouter.Handle("/point_rules", requirePermission(models.PermissionPointsManage, secretGuestHandler.GetPointRules)).Methods(http.MethodGet)                  // points
+	adminRouter.Handle("/point_rules/{event_type}", requirePermission(models.PermissionPointsManage, secretGuestHandler.UpdatePointRule)).Methods(http.MethodPatch) // points
+	adminRouter.Handle("/points/recompute", requirePermission(models.PermissionPointsManage, secretGuestHandler.RecomputePoints)).Methods(http.MethodPost)          // points
+	adminRouter.Handle("/rank_tiers", requirePermission(models.PermissionPointsManage, secretGuestHandler.GetRankTiers)).Methods(http.MethodGet)                    // points
+	adminRouter.Handle("/rank_tiers", requirePermission(models.PermissionPointsManage, secretGuestHandler.ReplaceRankTiers)).Methods(http.MethodPut)                // points
+
+	adminRouter.Handle("/badges", requirePermission(models.PermissionBadgesManage, secretGuestHandler.GetBadges)).Methods(http.MethodGet)                 // badges
+	adminRouter.Handle("/badges", requirePermission(models.PermissionBadgesManage, secretGuestHandler.CreateBadge)).Methods(http.MethodPost)              // badges
+	adminRouter.Handle("/badges/evaluate", requirePermission(models.PermissionBadgesManage, secretGuestHandler.EvaluateBadges)).Methods(http.MethodPost)  // badges
+	adminRouter.Handle("/badges/{id:[0-9]+}", requirePermission(models.PermissionBadgesManage, secretGuestHandler.UpdateBadge)).Methods(http.MethodPatch) // badges
+
+	adminRouter.Handle("/listing_priority_weights", requirePermission(models.PermissionListingsPriority, secretGuestHandler.GetListingTypePriorityWeights)).Methods(http.MethodGet)                            // listings
+	adminRouter.Handle("/listing_priority_weights/{listing_type_id:[0-9]+}", requirePermission(models.PermissionListingsPriority, secretGuestHandler.UpdateListingTypePriorityWeight)).Methods(http.MethodPut) // listings
+	adminRouter.Handle("/listing_complaints", requirePermission(models.PermissionComplaintsCreate, secretGuestHandler.CreateListingComplaint)).Methods(http.MethodPost)                                        // listings
+
+	adminRouter.Handle("/campaigns", requirePermission(models.PermissionCampaignsManage, secretGuestHandler.CreateCampaign)).Methods(http.MethodPost)       // campaigns
+	adminRouter.Handle("/campaigns/{id}", requirePermission(models.PermissionCampaignsManage, secretGuestHandler.UpdateCampaign)).Methods(http.MethodPatch) // campaigns
 
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
@@ -138,8 +242,9 @@ func newCorsMiddleware(cfg *config.Config) mux.MiddlewareFunc {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", cfg.FrontendURL)
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Impersonated-By, X-Impersonation-Mode")
 
 			if r.Method == http.MethodOptions {
 				w.WriteHeader(http.StatusNoContent)
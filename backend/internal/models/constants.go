@@ -1,11 +1,45 @@
 package models
 
+import "time"
+
 const (
 	AdminRoleID     = 1
 	ModeratorRoleID = 2
 	GuestRoleID     = 3
 )
 
+// Права доступа(таблица permissions)
+const (
+	PermissionStatisticsView        = "statistics.view"
+	PermissionListingsCreate        = "listings.create"
+	PermissionReservationsView      = "reservations.view"
+	PermissionReservationsCreate    = "reservations.create"
+	PermissionReservationsManage    = "reservations.manage"
+	PermissionAssignmentsView       = "assignments.view"
+	PermissionAssignmentsManage     = "assignments.manage"
+	PermissionReportsView           = "reports.view"
+	PermissionReportsApprove        = "reports.approve"
+	PermissionUsersView             = "users.view"
+	PermissionUsersManage           = "users.manage"
+	PermissionProfilesView          = "profiles.view"
+	PermissionChecklistsView        = "checklists.view"
+	PermissionChecklistsEdit        = "checklists.edit"
+	PermissionAuditView             = "audit.view"
+	PermissionRolesManage           = "roles.manage"
+	PermissionServiceAccountsManage = "service_accounts.manage"
+	PermissionApplicationsReview    = "applications.review"
+	PermissionUsersImpersonate      = "users.impersonate"
+	PermissionRewardsView           = "rewards.view"
+	PermissionRewardsManage         = "rewards.manage"
+	PermissionPointsManage          = "points.manage"
+	PermissionBadgesManage          = "badges.manage"
+	PermissionCampaignsView         = "campaigns.view"
+	PermissionCampaignsManage       = "campaigns.manage"
+	PermissionListingsView          = "listings.view"
+	PermissionListingsPriority      = "listings.priority.manage"
+	PermissionComplaintsCreate      = "complaints.create"
+)
+
 const (
 	MediaRequirementNone     = "none"
 	MediaRequirementOptional = "optional"
@@ -29,6 +63,113 @@ const (
 	ReportStatusApproved         = 5 // Одобрен
 	ReportStatusRejected         = 6 // Отклонен
 	ReportStatusGenerationFailed = 7 // Ошибка генерации
+	ReportStatusOverdue          = 8 // Не сдан в срок
+)
+
+const (
+	RewardStatusPending  = 1 // Ожидает проверки отчета
+	RewardStatusApproved = 2 // К выплате
+	RewardStatusPaid     = 3 // Выплачено
+	RewardStatusVoided   = 4 // Аннулировано
+
+	// Доля стоимости брони для типов объектов без настроенной политики - бесплатное проживание
+	DefaultRewardPercent = 100
+)
+
+// События, за которые начисляются очки(таблица point_rules)
+const (
+	PointEventAssignmentAccepted = "assignment_accepted"
+	PointEventReportSubmitted    = "report_submitted"
+	PointEventReportApproved     = "report_approved"
+	PointEventReportRejected     = "report_rejected"
+	PointEventReportLate         = "report_late"    // отчет сдан после due_at, но не позже due_at + grace_hours
+	PointEventReportOverdue      = "report_overdue" // черновик не сдан к сроку(reports.due_at)
+
+	// Язык названий рангов, если нужного перевода нет
+	DefaultLanguage = "ru"
+)
+
+// Показатели гостя для условий значков(badges.rule), считаются по point_events
+const (
+	BadgeMetricAcceptedAssignments   = "accepted_assignments"
+	BadgeMetricSubmittedReports      = "submitted_reports"
+	BadgeMetricApprovedReports       = "approved_reports"
+	BadgeMetricRejectedReports       = "rejected_reports"
+	BadgeMetricLateReports           = "late_reports"
+	BadgeMetricOnTimeApprovedReports = "on_time_approved_reports" // одобренные и сданные без опоздания
+	BadgeMetricCities                = "cities"                   // разные города в одобренных отчетах
+	BadgeMetricApprovedStreak        = "approved_streak"          // одобренные отчеты после последнего отклонения
+	BadgeMetricPoints                = "points"
+	BadgeMetricMaxQualityScore       = "max_quality_score" // лучшая оценка качества(0-100) среди одобренных отчетов
+)
+
+// Причины отказа от предложения(таблица decline_reasons)
+const (
+	DeclineReasonDates       = 1 // Не подходят даты
+	DeclineReasonLocation    = 2 // Не подходит расположение
+	DeclineReasonListingType = 3 // Не подходит тип объекта
+	DeclineReasonOther       = 4 // Другое, с комментарием
+
+	DeclineReasonUnspecified = "unspecified" // ключ в аналитике для отказов без причины
+)
+
+// Группировки аналитики отказов
+const (
+	DeclineGroupByReason  = "reason"
+	DeclineGroupByCity    = "city"
+	DeclineGroupByListing = "listing"
+
+	DefaultRepeatedDeclines = 2 // с какого числа отказов предложение считается повторно отклоненным
+)
+
+// MaxBulkAssignments - сколько предложений можно обработать одной массовой операцией персонала
+const MaxBulkAssignments = 500
+
+// Типы уведомлений(notifications.type)
+const (
+	NotificationAssignmentInvited     = "assignment.invited"          // персонал пригласил гостя на предложение
+	NotificationAssignmentHoldExpired = "assignment.hold_expired"     // срок принятия истек, предложение вернулось в свободные
+	NotificationAssignmentReleased    = "assignment.released"         // персонал снял гостя с предложения
+	NotificationWaitlistOffered       = "assignment.waitlist_offered" // предложение из листа ожидания закреплено за гостем
+	NotificationAssignmentCancelled   = "assignment.cancelled"        // персонал отменил предложение гостя
+	NotificationReportDueSoon         = "report.due_soon"             // приближается срок сдачи отчета
+	NotificationReportOverdue         = "report.overdue"              // отчет не сдан в срок
+)
+
+// Периоды таблицы лидеров: текущая календарная неделя(с понедельника), текущий месяц, все время. Границы - по UTC
+const (
+	LeaderboardPeriodWeek  = "week"
+	LeaderboardPeriodMonth = "month"
+	LeaderboardPeriodAll   = "all"
+)
+
+// Приоритет проверки объекта: сумма трех составляющих(до 100 баллов), умноженная на вес типа объекта в процентах
+const (
+	// Давность последнего одобренного отчета: линейно до максимума за ListingPriorityStaleDays дней, без отчетов - максимум
+	ListingPriorityRecencyPoints = 40
+	ListingPriorityStaleDays     = 180
+	// Последняя оценка качества(0-100): чем ниже, тем больше баллов; без оценки - половина
+	ListingPriorityQualityPoints = 30
+	// Жалобы от OTA за ListingPriorityComplaintDays дней: линейно до максимума при ListingPriorityMaxComplaints
+	ListingPriorityComplaintPoints = 30
+	ListingPriorityComplaintDays   = 90
+	ListingPriorityMaxComplaints   = 5
+
+	DefaultListingPriorityWeight = 100
+)
+
+// Отметка заезда/выезда: время устройства не должно расходиться с серверным больше, чем на StayCheckMaxClockSkew
+const StayCheckMaxClockSkew = 15 * time.Minute
+
+// Смещение от UTC во времени устройства считается смещением объекта(гость у объекта), поэтому должно быть близко
+// к солнечному смещению по долготе объекта(долгота/15 часов): поясное время отличается от него не больше, чем на пару часов
+const StayCheckMaxOffsetDeviation = 4 * time.Hour
+
+const (
+	GuestApplicationStatusPending    = 1 // На рассмотрении
+	GuestApplicationStatusApproved   = 2 // Одобрена
+	GuestApplicationStatusRejected   = 3 // Отклонена
+	GuestApplicationStatusWaitlisted = 4 // Лист ожидания
 )
 
 const (
@@ -37,3 +178,64 @@ const (
 	OTAReservationStatusBooked = 3 // Забронировано
 	OTAReservationStatusNoShow = 4 // Скрыто
 )
+
+// Журнал действий персонала
+const (
+	AuditEntityUser   = "user"
+	AuditEntityRole   = "role"
+	AuditEntityAPIKey = "api_key"
+
+	AuditEntityGuestApplication = "guest_application"
+	AuditEntityReward           = "reward"
+	AuditEntityRewardPolicy     = "reward_policy"
+	AuditEntityPointRule        = "point_rule"
+	AuditEntityRankTiers        = "rank_tiers"
+	AuditEntityBadge            = "badge"
+	AuditEntityAssignment       = "assignment"
+	AuditEntityCampaign         = "campaign"
+	AuditEntityListingType      = "listing_type"
+
+	AuditActionUserRoleChanged   = "user.role_changed"
+	AuditActionUserBlocked       = "user.blocked"
+	AuditActionUserUnblocked     = "user.unblocked"
+	AuditActionUserPasswordReset = "user.password_reset"
+	AuditActionUserDeleted       = "user.deleted" // удаление учетной записи самим пользователем
+
+	AuditActionImpersonationStarted = "impersonation.started"
+	AuditActionImpersonatedRequest  = "impersonation.request" // запрос, выполненный от имени пользователя
+
+	AuditActionRoleCreated            = "role.created"
+	AuditActionRoleUpdated            = "role.updated"
+	AuditActionRolePermissionsChanged = "role.permissions_changed"
+	AuditActionRoleDeleted            = "role.deleted"
+
+	AuditActionServiceAccountCreated = "service_account.created"
+	AuditActionAPIKeyCreated         = "api_key.created"
+	AuditActionAPIKeyRevoked         = "api_key.revoked"
+
+	AuditActionApplicationApproved   = "guest_application.approved"
+	AuditActionApplicationRejected   = "guest_application.rejected"
+	AuditActionApplicationWaitlisted = "guest_application.waitlisted"
+
+	AuditActionRewardPaid          = "reward.paid"
+	AuditActionRewardVoided        = "reward.voided"
+	AuditActionRewardPolicyUpdated = "reward_policy.updated"
+
+	AuditActionPointRuleUpdated  = "point_rule.updated"
+	AuditActionRankTiersReplaced = "rank_tiers.replaced"
+	AuditActionPointsRecomputed  = "points.recomputed"
+
+	AuditActionBadgeCreated = "badge.created"
+	AuditActionBadgeUpdated = "badge.updated"
+
+	AuditActionAssignmentCreated    = "assignment.created"
+	AuditActionAssignmentReleased   = "assignment.released"
+	AuditActionAssignmentCancelled  = "assignment.cancelled"
+	AuditActionAssignmentReassigned = "assignment.reassigned"
+	AuditActionAssignmentExtended   = "assignment.extended"
+
+	AuditActionCampaignCreated = "campaign.created"
+	AuditActionCampaignUpdated = "campaign.updated"
+
+	AuditActionListingPriorityWeightUpdated = "listing_type.priority_weight_updated"
+)
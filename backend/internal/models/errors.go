@@ -1,6 +1,12 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // TO DO: провести ревизию ошибок
 
@@ -15,16 +21,61 @@ var (
 	ErrJwtSecretKey = errors.New("missing JWT secret key in config")
 	ErrJwtLifitime  = errors.New("invalid token lifetimes")
 
-	ErrUserExists         = errors.New("user with this username already exists")
-	ErrEmailExists        = errors.New("user with this email already exists")
-	ErrUserNotFound       = errors.New("user not found")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInvalidEmail       = errors.New("invalid email")
-	ErrInvalidPassword    = errors.New("invalid password")
-	ErrInvalidUsername    = errors.New("invalid username")
+	ErrUserExists             = errors.New("user with this username already exists")
+	ErrEmailExists            = errors.New("user with this email already exists")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrInvalidCredentials     = errors.New("invalid credentials")
+	ErrInvalidEmail           = errors.New("invalid email")
+	ErrInvalidPassword        = errors.New("invalid password")
+	ErrInvalidUsername        = errors.New("invalid username")
+	ErrUserBlocked            = errors.New("user is blocked")
+	ErrPasswordChangeRequired = errors.New("password change required")
+	ErrRoleNotFound           = errors.New("role not found")
+	ErrRoleProtected          = errors.New("built-in role cannot be modified this way")
+	ErrPermissionNotFound     = errors.New("permission not found")
+	ErrInvalidRoleName        = errors.New("invalid role name")
+	ErrCannotModifySelf       = errors.New("this action cannot be applied to your own account")
+	ErrOIDCDisabled           = errors.New("OpenID Connect login is not configured")
+	ErrOIDCInvalidState       = errors.New("invalid or expired OpenID Connect login state")
+	ErrOIDCAuthFailed         = errors.New("OpenID Connect authentication failed")
+	ErrIdentityAlreadyLinked  = errors.New("external account is already linked to another user")
+	ErrServiceAccount         = errors.New("this action is not supported for service accounts")
+	ErrUserDeleted            = errors.New("user account has been deleted")
+	ErrAccountNotDeletable    = errors.New("only guest accounts can be deleted by their owner")
+	ErrNotServiceAccount      = errors.New("user is not a service account")
+	ErrInvalidAPIKey          = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound         = errors.New("API key not found")
+	ErrApplicationNotFound    = errors.New("participation application not found")
+	ErrApplicationNotApproved = errors.New("participation application is not approved")
+	ErrApplicationReviewed    = errors.New("participation application has already been reviewed")
+	ErrInvalidAPIKeyScope     = errors.New("API key scopes must be a non-empty subset of the service account permissions")
+
+	ErrImpersonationNotAllowed    = errors.New("impersonation is only allowed for active guest accounts")
+	ErrImpersonationReadOnly      = errors.New("impersonation session is read-only")
+	ErrImpersonationRestricted    = errors.New("this action is not available in an impersonation session")
+	ErrInvalidImpersonationReason = errors.New("impersonation reason is required and must not exceed 500 characters")
+
+	ErrRewardNotFound      = errors.New("reward not found")
+	ErrRewardNotPayable    = errors.New("only approved rewards can be paid")
+	ErrRewardNotVoidable   = errors.New("only pending or approved rewards can be voided")
+	ErrInvalidRewardPolicy = errors.New("invalid reward policy")
+
+	ErrPointRuleNotFound = errors.New("point rule not found")
+	ErrInvalidRankTiers  = errors.New("rank tiers must have unique slugs and thresholds and include a tier starting at 0 points")
+
+	ErrBadgeNotFound    = errors.New("badge not found")
+	ErrBadgeSlugExists  = errors.New("badge with this slug already exists")
+	ErrInvalidBadgeRule = errors.New("invalid badge rule")
 
 	ErrDataBaseQuery = errors.New("database query error")
 
+	ErrInvalidMFACode        = errors.New("invalid two-factor authentication code")
+	ErrMFANotEnrolled        = errors.New("two-factor authentication is not enrolled")
+	ErrMFAAlreadyEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrMFARequiredByPolicy   = errors.New("two-factor authentication is required for this role")
+	ErrMFAEnrollmentRequired = errors.New("two-factor authentication enrollment is required")
+	ErrMFALocked             = errors.New("too many invalid two-factor authentication codes, try again later")
+
 	ErrAuthHeaderMissing = errors.New("authorization header is required")
 	ErrAuthHeaderInvalid = errors.New("invalid authorization header format")
 
@@ -39,13 +90,40 @@ var (
 	ErrAssignmentCannotBeCreated = errors.New("assignment cannot be created")
 	ErrAssignmentNotFound        = errors.New("assignment not found")
 
-	ErrAssignmentCannotBeAccepted = errors.New("assignment cannot be accepted")
-	ErrAssignmentCannotBeDeclined = errors.New("assignment cannot be declined")
-	ErrAssignmentCannotBeTaken    = errors.New("assignment cannot be taken")
+	ErrAssignmentCannotBeAccepted   = errors.New("assignment cannot be accepted")
+	ErrAssignmentCannotBeDeclined   = errors.New("assignment cannot be declined")
+	ErrAssignmentCannotBeTaken      = errors.New("assignment cannot be taken")
+	ErrAssignmentCannotBeCancelled  = errors.New("assignment cannot be cancelled")
+	ErrAssignmentCannotBeReassigned = errors.New("assignment cannot be reassigned")
+	ErrAssignmentCannotBeExtended   = errors.New("assignment cannot be extended")
+
+	ErrGuestHasActiveAssignment = errors.New("guest already has an active assignment")
+	ErrInvalidAssignmentDates   = errors.New("invalid assignment dates")
+	ErrAssignmentDatesOverlap   = errors.New("assignment dates overlap with another assignment of the guest")
+	ErrAssignmentNotHeld        = errors.New("assignment is not held by a guest")
+
+	ErrStayCheckNotAllowed  = errors.New("stay check is not allowed for this assignment")
+	ErrStayCheckOutOfWindow = errors.New("stay check time is outside the reservation dates")
+	ErrStayCheckTooFar      = errors.New("device is too far from the listing")
+
+	ErrWaitlistNotAvailable = errors.New("assignment is not held by another guest, waitlist is not available")
+	ErrAlreadyInWaitlist    = errors.New("already in the waitlist of this assignment")
+	ErrNotInWaitlist        = errors.New("not in the waitlist of this assignment")
+
+	ErrCalendarTokenNotFound = errors.New("calendar token not found")
+
+	ErrNotificationNotFound = errors.New("notification not found")
+
+	ErrCampaignNotFound     = errors.New("campaign not found")
+	ErrInvalidCampaign      = errors.New("invalid campaign")
+	ErrCampaignNotAvailable = errors.New("campaign is inactive, finished or out of quota or budget")
+
+	ErrComplaintDuplicate = errors.New("complaint with this external_id already exists")
 
 	ErrReportNotFound         = errors.New("report not found")
 	ErrForbidden              = errors.New("forbidden")
 	ErrReportNotEditable      = errors.New("report not editable")
+	ErrReportOverdue          = errors.New("report is past its due date")
 	ErrValidationFailed       = errors.New("validation failed")
 	ErrReportCannotBeApproved = errors.New("report cannot be approved")
 	ErrReportCannotBeRejected = errors.New("report cannot be rejected")
@@ -59,3 +137,23 @@ var (
 	ErrInUse               = errors.New("resource is in use and cannot be deleted")
 	ErrForeignKeyViolation = errors.New("foreign key constraint violated")
 )
+
+// AssignmentOverlapError - даты предложения пересекаются с другим предложением гостя(Offered или Accepted).
+// AssignmentID пустой, если пересечение обнаружено только ограничением БД при гонке
+type AssignmentOverlapError struct {
+	AssignmentID uuid.UUID
+	CheckinDate  time.Time
+	CheckoutDate time.Time
+}
+
+func (e *AssignmentOverlapError) Error() string {
+	if e.AssignmentID == uuid.Nil {
+		return ErrAssignmentDatesOverlap.Error()
+	}
+	return fmt.Sprintf("%s: assignment %s (%s - %s)", ErrAssignmentDatesOverlap, e.AssignmentID,
+		e.CheckinDate.Format(time.DateOnly), e.CheckoutDate.Format(time.DateOnly))
+}
+
+func (e *AssignmentOverlapError) Unwrap() error {
+	return ErrAssignmentDatesOverlap
+}
@@ -16,6 +16,376 @@ type User struct {
 	RoleID       int       `json:"role_id"`
 	CreatedAt    time.Time `json:"created_at"`
 	RoleName     string    `json:"role_name" db:"role_name"`
+	MFAEnabled   bool      `json:"mfa_enabled" db:"-"`
+
+	BlockedAt              *time.Time `json:"blocked_at" db:"blocked_at"`
+	BlockedReason          *string    `json:"blocked_reason" db:"blocked_reason"`
+	PasswordChangeRequired bool       `json:"password_change_required" db:"password_change_required"`
+
+	Permissions []string `json:"permissions" db:"-"` // права роли пользователя
+
+	IsServiceAccount bool `json:"is_service_account" db:"is_service_account"` // учетная запись интеграции, вход только по API-ключу
+
+	DeletedAt *time.Time `json:"deleted_at" db:"deleted_at"` // учетная запись удалена пользователем, данные обезличены
+}
+
+// Role - роль пользователя
+type Role struct {
+	ID          int      `db:"id"`
+	Name        string   `db:"name"`
+	Description *string  `db:"description"`
+	Permissions []string `db:"-"`
+}
+
+// Permission - право доступа
+type Permission struct {
+	ID          int     `db:"id"`
+	Slug        string  `db:"slug"`
+	Description *string `db:"description"`
+}
+
+// UserTOTP - настройки двухфакторной аутентификации пользователя
+type UserTOTP struct {
+	UserID       uuid.UUID  `db:"user_id"`
+	Secret       string     `db:"secret"`
+	Enabled      bool       `db:"enabled"`
+	CreatedAt    time.Time  `db:"created_at"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep *int64     `db:"last_used_step"`
+
+	// Защита от перебора кодов
+	FailedAttempts       int        `db:"failed_attempts"`
+	LockedUntil          *time.Time `db:"locked_until"`
+	ChallengesValidAfter *time.Time `db:"challenges_valid_after"` // промежуточные токены, выданные не позже, недействительны
+}
+
+// UserIdentity - учетная запись внешнего провайдера OIDC, привязанная к пользователю
+type UserIdentity struct {
+	ID          uuid.UUID  `db:"id"`
+	UserID      uuid.UUID  `db:"user_id"`
+	Provider    string     `db:"provider"`
+	Subject     string     `db:"subject"`
+	Email       *string    `db:"email"`
+	CreatedAt   time.Time  `db:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at"`
+}
+
+// OIDCLoginState - параметры начатого входа через OIDC, нужные для обработки callback
+type OIDCLoginState struct {
+	State        string    `db:"state"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// GuestApplication - заявка на участие в программе тайных гостей
+type GuestApplication struct {
+	ID              uuid.UUID  `db:"id"`
+	UserID          uuid.UUID  `db:"user_id"`
+	Username        string     `db:"username"`
+	StatusID        int        `db:"status_id"`
+	Status          StatusInfo `db:"-"`
+	Motivation      string     `db:"motivation"`
+	TravelFrequency *string    `db:"travel_frequency"`
+	HomeCity        string     `db:"home_city"`
+	SocialLinks     []string   `db:"social_links"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	ReviewedBy      *uuid.UUID `db:"reviewed_by"`
+	ReviewedAt      *time.Time `db:"reviewed_at"`
+	ReviewComment   *string    `db:"review_comment"`
+}
+
+// Reward - начисление тайному гостю за задание(скидка или бесплатное проживание).
+// Сумма считается от стоимости брони задания по политике типа объекта и фиксируется при принятии задания.
+type Reward struct {
+	ID              uuid.UUID  `db:"id"`
+	AssignmentID    uuid.UUID  `db:"assignment_id"`
+	ReportID        *uuid.UUID `db:"report_id"`
+	UserID          *uuid.UUID `db:"user_id"` // NULL после удаления учетной записи
+	Username        string     `db:"username"`
+	ListingID       uuid.UUID  `db:"listing_id"`
+	ListingTitle    string     `db:"listing_title"`
+	ListingTypeID   int        `db:"listing_type_id"`
+	Amount          int        `db:"amount"`
+	Currency        string     `db:"currency"`
+	Percent         int        `db:"percent"`
+	StatusID        int        `db:"status_id"`
+	Status          StatusInfo `db:"-"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+	ApprovedAt      *time.Time `db:"approved_at"`
+	PaidAt          *time.Time `db:"paid_at"`
+	PaidBy          *uuid.UUID `db:"paid_by"`
+	PayoutReference *string    `db:"payout_reference"`
+	VoidedAt        *time.Time `db:"voided_at"`
+	VoidedBy        *uuid.UUID `db:"voided_by"`
+	VoidReason      *string    `db:"void_reason"`
+}
+
+// RewardTotal - сумма начислений пользователя в одном статусе и одной валюте
+type RewardTotal struct {
+	StatusID int        `db:"status_id"`
+	Status   StatusInfo `db:"-"`
+	Currency string     `db:"currency"`
+	Amount   int        `db:"amount"`
+	Count    int        `db:"count"`
+}
+
+// RewardPolicy - правило вознаграждения для типа объекта
+type RewardPolicy struct {
+	ListingTypeID   int        `db:"listing_type_id"`
+	ListingTypeSlug string     `db:"listing_type_slug"`
+	ListingTypeName string     `db:"listing_type_name"`
+	Percent         int        `db:"percent"`    // доля стоимости брони, 100 - бесплатное проживание
+	MaxAmount       *int       `db:"max_amount"` // ограничение суммы в валюте брони
+	UpdatedAt       *time.Time `db:"updated_at"` // NULL - политика не настроена, действует значение по умолчанию
+}
+
+// PointRule - вес события в очках гостя, отрицательный - штраф
+type PointRule struct {
+	EventType  string     `db:"event_type"`
+	Name       string     `db:"name"`
+	Points     int        `db:"points"`
+	GraceHours *int       `db:"grace_hours"` // только для report_late: сколько часов после срока сдачи отчет еще принимается
+	UpdatedAt  *time.Time `db:"updated_at"`
+}
+
+// RankTier - ранг, который гость получает при сумме очков не меньше MinPoints
+type RankTier struct {
+	ID        int               `db:"id"`
+	Slug      string            `db:"slug"`
+	MinPoints int               `db:"min_points"`
+	Names     map[string]string `db:"names"` // язык(ISO 639-1) -> название
+}
+
+// PointEvent - начисление очков гостю за событие
+type PointEvent struct {
+	ID           uuid.UUID  `db:"id"`
+	UserID       uuid.UUID  `db:"user_id"`
+	EventType    string     `db:"event_type"`
+	EventName    string     `db:"event_name"`
+	Points       int        `db:"points"`
+	AssignmentID *uuid.UUID `db:"assignment_id"`
+	ReportID     *uuid.UUID `db:"report_id"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+// Badge - значок гостя, выдается при выполнении условия Rule
+type Badge struct {
+	ID           int               `db:"id"`
+	Slug         string            `db:"slug"`
+	Names        map[string]string `db:"names"`
+	Descriptions map[string]string `db:"descriptions"`
+	Rule         BadgeRule         `db:"rule"`
+	IsActive     bool              `db:"is_active"`
+	CreatedAt    time.Time         `db:"created_at"`
+	UpdatedAt    *time.Time        `db:"updated_at"`
+}
+
+// BadgeRule - условие значка: сравнение показателя(Metric Op Value) или группа условий All/Any
+type BadgeRule struct {
+	All    []BadgeRule `json:"all,omitempty"`
+	Any    []BadgeRule `json:"any,omitempty"`
+	Metric string      `json:"metric,omitempty"`
+	Op     string      `json:"op,omitempty"`
+	Value  int         `json:"value,omitempty"`
+}
+
+// UserBadge - значок, полученный гостем
+type UserBadge struct {
+	Badge
+	EarnedAt time.Time `db:"earned_at"`
+}
+
+// Campaign - кампания проверок объектов
+type Campaign struct {
+	ID      uuid.UUID `db:"id"`
+	Name    string    `db:"name"`
+	Purpose string    `db:"purpose"`
+
+	// Отбор объектов
+	ListingTypeIDs   []int   `db:"listing_type_ids"`
+	City             *string `db:"city"`
+	NotInspectedDays *int    `db:"not_inspected_days"`
+
+	StartsAt  time.Time  `db:"starts_at"`
+	EndsAt    time.Time  `db:"ends_at"`
+	Quota     *int       `db:"quota"`
+	Budget    *int       `db:"budget"`
+	Currency  *string    `db:"currency"`
+	IsActive  bool       `db:"is_active"`
+	CreatedBy *uuid.UUID `db:"created_by"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+
+	Progress CampaignProgress `db:"-"`
+}
+
+// CampaignProgress - выполнение кампании
+type CampaignProgress struct {
+	TargetListings      int `db:"target_listings"` // объектов подходит под отбор сейчас
+	Assignments         int `db:"assignments"`     // предложений в кампании
+	AcceptedAssignments int `db:"accepted_assignments"`
+	ApprovedReports     int `db:"approved_reports"`
+	InspectedListings   int `db:"inspected_listings"` // объектов с одобренным отчетом по кампании
+	Spent               int `db:"spent"`              // вознаграждения в валюте кампании, кроме аннулированных
+}
+
+// CampaignListing - объект под отбором кампании
+type CampaignListing struct {
+	ListingID       uuid.UUID  `db:"listing_id"`
+	Title           string     `db:"title"`
+	City            string     `db:"city"`
+	ListingTypeID   int        `db:"listing_type_id"`
+	LastInspectedAt *time.Time `db:"last_inspected_at"` // последний одобренный отчет
+	Assignments     int        `db:"assignments"`       // предложений по объекту в кампании
+	Priority        float64    `db:"priority"`          // приоритет проверки, см. ListingPriority
+}
+
+// DeclineReason - причина отказа от предложения
+type DeclineReason struct {
+	ID   int    `db:"id"`
+	Slug string `db:"slug"`
+	Name string `db:"name"`
+}
+
+// DeclineStat - отказы от предложений в одной группе аналитики(причина, город или объект)
+type DeclineStat struct {
+	Key         string         `db:"key"`
+	Label       string         `db:"label"`
+	Declines    int            `db:"declines"`
+	Assignments int            `db:"assignments"` // разных предложений с отказами
+	Guests      int            `db:"guests"`      // разных отказавшихся гостей
+	ByReason    map[string]int `db:"-"`           // slug причины(или unspecified) - число отказов
+}
+
+// DeclinedAssignment - открытое предложение, от которого отказывались несколько раз
+type DeclinedAssignment struct {
+	AssignmentID   uuid.UUID      `db:"assignment_id"`
+	ListingID      uuid.UUID      `db:"listing_id"`
+	ListingTitle   string         `db:"listing_title"`
+	City           string         `db:"city"`
+	Purpose        string         `db:"purpose"`
+	CheckinDate    time.Time      `db:"checkin_date"`
+	CheckoutDate   time.Time      `db:"checkout_date"`
+	ExpiresAt      time.Time      `db:"expires_at"`
+	CampaignID     *uuid.UUID     `db:"campaign_id"`
+	Declines       int            `db:"declines"`
+	LastDeclinedAt *time.Time     `db:"last_declined_at"`
+	ByReason       map[string]int `db:"-"`
+	Comments       []string       `db:"comments"` // комментарии к отказам, сначала новые
+}
+
+// ExpiredAssignmentHold - предложение Offered, закрепленное за гостем, у которого истек срок принятия(accept_deadline)
+type ExpiredAssignmentHold struct {
+	AssignmentID   uuid.UUID  `db:"id"`
+	ReporterID     uuid.UUID  `db:"reporter_id"`
+	ListingTitle   string     `db:"listing_title"`
+	City           string     `db:"city"`
+	CheckinDate    time.Time  `db:"checkin_date"`
+	AcceptDeadline time.Time  `db:"accept_deadline"`
+	ExpiresAt      time.Time  `db:"expires_at"`
+	InvitedAt      *time.Time `db:"invited_at"` // не nil - приглашение персонала
+}
+
+// DueReport - черновик отчета, у которого подходит или прошел срок сдачи
+type DueReport struct {
+	ReportID      uuid.UUID `db:"id"`
+	ReporterID    uuid.UUID `db:"reporter_id"`
+	AssignmentID  uuid.UUID `db:"assignment_id"`
+	ListingTitle  string    `db:"listing_title"`
+	City          string    `db:"city"`
+	DueAt         time.Time `db:"due_at"`
+	RemindersSent int       `db:"due_reminders_sent"`
+}
+
+// StayCheck - отметка заезда или выезда гостя с координатами устройства
+type StayCheck struct {
+	At             time.Time // по часам устройства
+	Latitude       float64
+	Longitude      float64
+	DistanceMeters *float64 // до объекта; nil - у объекта нет координат
+}
+
+// AssignmentStay - подтверждение проживания по принятому предложению
+type AssignmentStay struct {
+	CheckIn  *StayCheck
+	CheckOut *StayCheck
+}
+
+// CalendarStay - принятое предложение гостя для календарной ленты
+type CalendarStay struct {
+	AssignmentID  uuid.UUID `db:"assignment_id"`
+	CheckinDate   time.Time `db:"checkin_date"`
+	CheckoutDate  time.Time `db:"checkout_date"`
+	BookingNumber *string   `db:"booking_number"` // nil - предложение создано персоналом без брони
+	ListingTitle  string    `db:"listing_title"`
+	Address       string    `db:"listing_address"`
+	City          string    `db:"listing_city"`
+	Country       string    `db:"listing_country"`
+	// Срок сдачи отчета по проживанию(nil - отчета еще нет или у него нет срока)
+	ReportDueAt *time.Time `db:"report_due_at"`
+}
+
+// WaitlistEntry - гость в листе ожидания предложения
+type WaitlistEntry struct {
+	AssignmentID uuid.UUID `db:"assignment_id"`
+	UserID       uuid.UUID `db:"user_id"`
+	CreatedAt    time.Time `db:"created_at"`
+	Position     int       `db:"position"` // с 1
+}
+
+// WaitlistOffer - как закрепить освободившееся предложение за следующим гостем из листа ожидания.
+// UserID уведомления заполняет репозиторий, когда выбран гость
+type WaitlistOffer struct {
+	AcceptDeadline *time.Time
+	Notification   *Notification
+}
+
+// Notification - уведомление пользователя в приложении
+type Notification struct {
+	ID        uuid.UUID       `db:"id"`
+	UserID    uuid.UUID       `db:"user_id"`
+	Type      string          `db:"type"`
+	Title     string          `db:"title"`
+	Body      string          `db:"body"`
+	Payload   json.RawMessage `db:"payload"`
+	CreatedAt time.Time       `db:"created_at"`
+	ReadAt    *time.Time      `db:"read_at"`
+}
+
+// LeaderboardEntry - место гостя в таблице лидеров за период
+type LeaderboardEntry struct {
+	Position       int             `db:"position"` // одинаковые очки - одинаковое место
+	UserID         uuid.UUID       `db:"user_id"`
+	Username       string          `db:"username"`
+	AdditionalInfo json.RawMessage `db:"additional_info"`
+	Points         int             `db:"points"`
+}
+
+// PointBreakdownItem - сумма очков гостя по одному типу событий
+type PointBreakdownItem struct {
+	EventType string `db:"event_type"`
+	EventName string `db:"event_name"`
+	Count     int    `db:"count"`
+	Points    int    `db:"points"`
+}
+
+// APIKey - API-ключ сервисной учетной записи. Сам ключ не хранится, только его хеш.
+type APIKey struct {
+	ID         uuid.UUID  `db:"id"`
+	UserID     uuid.UUID  `db:"user_id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash"`
+	Scopes     []string   `db:"scopes"`
+	CreatedBy  *uuid.UUID `db:"created_by"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
 }
 
 //================================
@@ -35,6 +405,37 @@ type Listing struct {
 	Longitude     float64     `db:"longitude"`
 	CreatedAt     time.Time   `db:"created_at"`
 	ListingType   ListingType `db:"-"`
+
+	Priority *ListingPriority `db:"-"` // заполняется только в выборках с приоритетом
+}
+
+// ListingPriority - приоритет проверки объекта тайным гостем и из чего он сложился
+type ListingPriority struct {
+	Score             float64    `db:"score"`
+	LastInspectedAt   *time.Time `db:"last_inspected_at"`  // последний одобренный отчет
+	LastQualityScore  *int       `db:"last_quality_score"` // оценка последнего отчета, где она есть
+	Complaints        int        `db:"complaints"`         // жалобы за ListingPriorityComplaintDays дней
+	ListingTypeWeight int        `db:"listing_type_weight"`
+}
+
+// ListingTypePriorityWeight - вес типа объекта в приоритете проверки, в процентах
+type ListingTypePriorityWeight struct {
+	ListingTypeID   int    `db:"listing_type_id"`
+	ListingTypeSlug string `db:"listing_type_slug"`
+	ListingTypeName string `db:"listing_type_name"`
+	Weight          int    `db:"weight"`
+}
+
+// ListingComplaint - жалоба на объект, переданная OTA
+type ListingComplaint struct {
+	ID         uuid.UUID  `db:"id"`
+	ListingID  uuid.UUID  `db:"listing_id"`
+	ExternalID *string    `db:"external_id"`
+	Category   string     `db:"category"`
+	Comment    *string    `db:"comment"`
+	ReceivedAt time.Time  `db:"received_at"`
+	CreatedBy  *uuid.UUID `db:"created_by"`
+	CreatedAt  time.Time  `db:"created_at"`
 }
 
 // ListingType - тип объекта размещения
@@ -96,6 +497,13 @@ type Assignment struct {
 	ListingID  uuid.UUID `db:"listing_id"`
 	ReporterID uuid.UUID `db:"reporter_id"`
 	StatusID   int       `db:"status_id"`
+
+	// Ручное предложение от персонала
+	CreatedBy      *uuid.UUID `db:"created_by"`
+	InvitedAt      *time.Time `db:"invited_at"`
+	AcceptDeadline *time.Time `db:"accept_deadline"` // срок принятия для приглашенного гостя
+
+	CampaignID *uuid.UUID `db:"campaign_id"`
 }
 
 // ================================
@@ -149,6 +557,10 @@ type Report struct {
 	CreatedAt   time.Time  `db:"created_at"`
 	UpdatedAt   *time.Time `db:"updated_at"`
 	SubmittedAt *time.Time `db:"submitted_at"`
+	// Оценка качества объекта модератором при одобрении(0-100)
+	QualityScore *int `db:"quality_score"`
+	// Срок сдачи отчета(nil - у отчета нет даты выезда)
+	DueAt *time.Time `db:"due_at"`
 
 	Listing  ListingShortInfo `db:"-"`
 	Reporter UserShortInfo    `db:"-"`
@@ -247,12 +659,31 @@ type UserProfile struct {
 	RegisteredAt          time.Time       `db:"registered_at"`
 	LastActiveAt          *time.Time      `db:"last_active_at"`
 	AdditionalInfo        json.RawMessage `db:"additional_info"`
+	Points                int             `db:"points"` // сумма point_events
+	Badges                []*UserBadge    `db:"-"`
 
 	// Поля из таблицы users
 	Username string `db:"username"`
 	Email    string `db:"email"`
 }
 
+// ProfileInfo - схема user_profiles.additional_info: данные, которые гость заполняет сам
+type ProfileInfo struct {
+	DisplayName             string               `json:"display_name,omitempty"`
+	AvatarURL               string               `json:"avatar_url,omitempty"`
+	HomeCity                string               `json:"home_city,omitempty"`
+	PreferredListingTypeIDs []int                `json:"preferred_listing_type_ids,omitempty"`
+	Availability            []AvailabilityPeriod `json:"availability,omitempty"`
+	Languages               []string             `json:"languages,omitempty"`
+	HideFromLeaderboard     bool                 `json:"hide_from_leaderboard,omitempty"` // не показывать в таблице лидеров
+}
+
+// AvailabilityPeriod - период, в который гость готов к поездке. Даты в формате 2006-01-02, включительно
+type AvailabilityPeriod struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
 type Statistics struct {
 	// OTA бронирования
 	TotalOtaReservations   int `db:"total_ota_reservations"`
@@ -271,3 +702,33 @@ type Statistics struct {
 	TotalSg      int `db:"total_sg"`
 	NewSgLast24h int `db:"new_sg_last_24h"`
 }
+
+//================================
+
+// AuditLogEntry - запись журнала действий персонала
+type AuditLogEntry struct {
+	ID            uuid.UUID       `db:"id"`
+	ActorID       *uuid.UUID      `db:"actor_id"`
+	ActorUsername *string         `db:"-"`
+	Action        string          `db:"action"`
+	EntityType    string          `db:"entity_type"`
+	EntityID      string          `db:"entity_id"`
+	Details       json.RawMessage `db:"details"`
+	CreatedAt     time.Time       `db:"created_at"`
+}
+
+// NewAuditLogEntry - запись журнала о действии actorID над сущностью, details сохраняются как jsonb
+func NewAuditLogEntry(actorID uuid.UUID, action, entityType, entityID string, details map[string]any) *AuditLogEntry {
+	entry := &AuditLogEntry{
+		ActorID:    &actorID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if len(details) > 0 {
+		if raw, err := json.Marshal(details); err == nil {
+			entry.Details = raw
+		}
+	}
+	return entry
+}
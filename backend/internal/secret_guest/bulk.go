@@ -0,0 +1,134 @@
+package secret_guest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Массовые операции персонала с предложениями
+
+// BulkCancelAssignments отменяет предложения по списку или фильтру, как CancelAssignment для каждого
+func (s *SecretGuestService) BulkCancelAssignments(ctx context.Context, actorID uuid.UUID, dto BulkAssignmentsRequestDTO) (*BulkAssignmentsResponse, error) {
+	return s.runBulkAssignments(ctx, dto, func(ctx context.Context, assignmentID, bulkID uuid.UUID) error {
+		return s.cancelAssignment(ctx, actorID, assignmentID, &bulkID)
+	})
+}
+
+// BulkReleaseAssignments возвращает закрепленные за гостями предложения в свободные(или следующему из листа ожидания)
+func (s *SecretGuestService) BulkReleaseAssignments(ctx context.Context, actorID uuid.UUID, dto BulkAssignmentsRequestDTO) (*BulkAssignmentsResponse, error) {
+	return s.runBulkAssignments(ctx, dto, func(ctx context.Context, assignmentID, bulkID uuid.UUID) error {
+		return s.releaseAssignment(ctx, actorID, assignmentID, &bulkID)
+	})
+}
+
+// BulkExtendAssignments переносит срок действия предложений на один и тот же expires_at
+func (s *SecretGuestService) BulkExtendAssignments(ctx context.Context, actorID uuid.UUID, dto BulkExtendAssignmentsRequestDTO) (*BulkAssignmentsResponse, error) {
+	return s.runBulkAssignments(ctx, dto.BulkAssignmentsRequestDTO, func(ctx context.Context, assignmentID, bulkID uuid.UUID) error {
+		return s.extendAssignment(ctx, actorID, assignmentID, dto.ExpiresAt, &bulkID)
+	})
+}
+
+// runBulkAssignments применяет op к каждому предложению отдельно: ошибка по одному предложению не останавливает остальные.
+// Записи журнала одной операции связаны общим bulk_id
+func (s *SecretGuestService) runBulkAssignments(ctx context.Context, dto BulkAssignmentsRequestDTO, op func(ctx context.Context, assignmentID, bulkID uuid.UUID) error) (*BulkAssignmentsResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	ids, err := s.resolveBulkAssignmentIDs(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	bulkID := uuid.New()
+	response := &BulkAssignmentsResponse{
+		BulkID:  bulkID,
+		Results: make([]*BulkAssignmentResultDTO, 0, len(ids)),
+	}
+	for _, id := range ids {
+		result := &BulkAssignmentResultDTO{AssignmentID: id, OK: true}
+		if err := op(ctx, id, bulkID); err != nil {
+			result.OK = false
+			result.Error = bulkAssignmentError(err)
+			response.Failed++
+			log.Info(ctx, "Bulk assignment operation failed for item", zap.String("assignment_id", id.String()), zap.Error(err))
+		} else {
+			response.Succeeded++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	log.Info(ctx, "Bulk assignment operation finished",
+		zap.String("bulk_id", bulkID.String()),
+		zap.Int("succeeded", response.Succeeded),
+		zap.Int("failed", response.Failed),
+	)
+	return response, nil
+}
+
+// resolveBulkAssignmentIDs - предложения массовой операции: либо явный список, либо непустой фильтр,
+// не больше MaxBulkAssignments
+func (s *SecretGuestService) resolveBulkAssignmentIDs(ctx context.Context, dto BulkAssignmentsRequestDTO) ([]uuid.UUID, error) {
+	if (len(dto.IDs) > 0) == (dto.Filter != nil) {
+		return nil, fmt.Errorf("%w: exactly one of ids or filter must be set", models.ErrValidationFailed)
+	}
+
+	if len(dto.IDs) > 0 {
+		seen := make(map[uuid.UUID]bool, len(dto.IDs))
+		ids := make([]uuid.UUID, 0, len(dto.IDs))
+		for _, id := range dto.IDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+
+	f := dto.Filter
+	filter := repository.BulkAssignmentsFilter{
+		StatusIDs:      f.StatusIDs,
+		ListingTypeIDs: f.ListingTypeIDs,
+		City:           strings.TrimSpace(f.City),
+		ReporterID:     f.ReporterID,
+		CampaignID:     f.CampaignID,
+		ExpiresBefore:  f.ExpiresBefore,
+	}
+	if len(filter.StatusIDs) == 0 && len(filter.ListingTypeIDs) == 0 && filter.City == "" &&
+		filter.ReporterID == nil && filter.CampaignID == nil && filter.ExpiresBefore == nil {
+		return nil, fmt.Errorf("%w: filter must have at least one condition", models.ErrValidationFailed)
+	}
+
+	ids, err := s.repo.GetAssignmentIDs(ctx, filter, models.MaxBulkAssignments+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment ids by filter: %w", err)
+	}
+	if len(ids) > models.MaxBulkAssignments {
+		return nil, fmt.Errorf("%w: filter matches more than %d assignments", models.ErrValidationFailed, models.MaxBulkAssignments)
+	}
+	return ids, nil
+}
+
+// bulkAssignmentError - причина неудачи по одному предложению массовой операции. Внутренние ошибки не раскрываются
+func bulkAssignmentError(err error) string {
+	if errors.Is(err, models.ErrInvalidAssignmentDates) {
+		return err.Error()
+	}
+	for _, known := range []error{
+		models.ErrAssignmentNotFound,
+		models.ErrAssignmentCannotBeCancelled,
+		models.ErrAssignmentCannotBeExtended,
+		models.ErrAssignmentNotHeld,
+	} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	return "internal error"
+}
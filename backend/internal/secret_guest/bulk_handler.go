@@ -0,0 +1,113 @@
+package secret_guest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// bulk assignments
+
+// @Summary      Bulk Cancel Assignments (Staff)
+// @Security     BearerAuth
+// @Description  Cancels offered and accepted assignments given by an ID list or by a filter (exactly one of them, at most 500 assignments), each one as the single cancel endpoint does. A failure of one assignment does not stop the others: the response has a result per assignment. Audit log entries of one operation share bulk_id.
+// @Tags         Assignments (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        body body secret_guest.BulkAssignmentsRequestDTO true "Assignment IDs or filter"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.BulkAssignmentsResponse
+// @Failure      400 {object} ErrorResponse "Invalid request body, empty filter or too many assignments"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/assignments/bulk/cancel [post]
+func (h *SecretGuestHandler) BulkCancelAssignments(w http.ResponseWriter, r *http.Request) {
+	var dto BulkAssignmentsRequestDTO
+	h.handleBulkAssignments(w, r, &dto, func(ctx context.Context, actorID uuid.UUID) (*BulkAssignmentsResponse, error) {
+		return h.service.BulkCancelAssignments(ctx, actorID, dto)
+	})
+}
+
+// @Summary      Bulk Release Assignments (Staff)
+// @Security     BearerAuth
+// @Description  Returns assignments held by guests to the pool (or to the next guest from the waitlist) given by an ID list or by a filter, each one as the single release endpoint does. The response has a result per assignment, audit log entries share bulk_id.
+// @Tags         Assignments (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        body body secret_guest.BulkAssignmentsRequestDTO true "Assignment IDs or filter"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.BulkAssignmentsResponse
+// @Failure      400 {object} ErrorResponse "Invalid request body, empty filter or too many assignments"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/assignments/bulk/release [post]
+func (h *SecretGuestHandler) BulkReleaseAssignments(w http.ResponseWriter, r *http.Request) {
+	var dto BulkAssignmentsRequestDTO
+	h.handleBulkAssignments(w, r, &dto, func(ctx context.Context, actorID uuid.UUID) (*BulkAssignmentsResponse, error) {
+		return h.service.BulkReleaseAssignments(ctx, actorID, dto)
+	})
+}
+
+// @Summary      Bulk Extend Assignments (Staff)
+// @Security     BearerAuth
+// @Description  Moves expires_at of offered assignments given by an ID list or by a filter to the same date, each one as the single extend endpoint does. The response has a result per assignment, audit log entries share bulk_id.
+// @Tags         Assignments (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        body body secret_guest.BulkExtendAssignmentsRequestDTO true "Assignment IDs or filter and the new expiration date"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.BulkAssignmentsResponse
+// @Failure      400 {object} ErrorResponse "Invalid request body, empty filter or too many assignments"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/assignments/bulk/extend [post]
+func (h *SecretGuestHandler) BulkExtendAssignments(w http.ResponseWriter, r *http.Request) {
+	var dto BulkExtendAssignmentsRequestDTO
+	h.handleBulkAssignments(w, r, &dto, func(ctx context.Context, actorID uuid.UUID) (*BulkAssignmentsResponse, error) {
+		return h.service.BulkExtendAssignments(ctx, actorID, dto)
+	})
+}
+
+// handleBulkAssignments - общая часть массовых операций: разбор и проверка тела в dto, затем run
+func (h *SecretGuestHandler) handleBulkAssignments(w http.ResponseWriter, r *http.Request, dto any,
+	run func(ctx context.Context, actorID uuid.UUID) (*BulkAssignmentsResponse, error)) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.decodeJSONBody(ctx, r, dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, dto); err != nil {
+		log.Warn(ctx, "Validation failed for bulk assignment operation", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	response, err := run(ctx, actorID)
+	if err != nil {
+		if errors.Is(err, models.ErrValidationFailed) {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Error(ctx, "Failed to run bulk assignment operation", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, response)
+}
@@ -0,0 +1,142 @@
+package secret_guest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Календарь проживаний
+
+// calendarFeedPastDays - за сколько дней назад проживания еще попадают в календарную ленту
+const calendarFeedPastDays = 90
+
+// reportDeadline - срок сдачи отчета по проживанию, для которого отчет еще не создан
+func (s *SecretGuestService) reportDeadline(checkout time.Time) time.Time {
+	return checkout.Add(s.reportDueWindow())
+}
+
+// hashCalendarToken - токены высокоэнтропийные, как API-ключи, поэтому хранится sha256 без соли
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RegenerateMyCalendarToken выпускает новую ссылку на календарную ленту гостя. Прежняя ссылка перестает работать,
+// сам токен не хранится - ссылку можно получить только в ответе
+func (s *SecretGuestService) RegenerateMyCalendarToken(ctx context.Context, userID uuid.UUID) (*CalendarTokenResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	createdAt, err := s.repo.SaveCalendarToken(ctx, userID, hashCalendarToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save calendar token for user %s: %w", userID.String(), err)
+	}
+
+	log.Info(ctx, "Calendar token regenerated", zap.String("user_id", userID.String()))
+
+	return &CalendarTokenResponseDTO{
+		URL:       strings.TrimRight(s.cfg.PublicAPIURL, "/") + "/calendar/" + token + ".ics",
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// GetCalendarFeed - лента iCalendar(RFC 5545) с принятыми предложениями владельца токена: проживание и срок сдачи отчета.
+// Даты в базе хранятся без часового пояса(местное время объекта), поэтому в ленте они "плавающие"
+func (s *SecretGuestService) GetCalendarFeed(ctx context.Context, token string) ([]byte, error) {
+	userID, err := s.repo.GetUserIDByCalendarToken(ctx, hashCalendarToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar token: %w", err)
+	}
+
+	stays, err := s.repo.GetCalendarStays(ctx, userID, time.Now().AddDate(0, 0, -calendarFeedPastDays))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calendar stays for user %s: %w", userID.String(), err)
+	}
+
+	const localFormat = "20060102T150405"
+	stamp := time.Now().UTC().Format(localFormat) + "Z"
+
+	var b strings.Builder
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//Secret Guest//Stays//RU")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:"+escapeICSText("Тайный гость: проживания"))
+
+	for _, stay := range stays {
+		deadline := s.reportDeadline(stay.CheckoutDate)
+		if stay.ReportDueAt != nil {
+			deadline = *stay.ReportDueAt
+		}
+		location := strings.Join([]string{stay.Address, stay.City, stay.Country}, ", ")
+
+		description := "Срок сдачи отчета: " + deadline.Format("02.01.2006 15:04")
+		if stay.BookingNumber != nil && *stay.BookingNumber != "" {
+			description = "Номер брони: " + *stay.BookingNumber + "\n" + description
+		}
+
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+stay.AssignmentID.String()+"@secret-guest")
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		writeICSLine(&b, "DTSTART:"+stay.CheckinDate.Format(localFormat))
+		writeICSLine(&b, "DTEND:"+stay.CheckoutDate.Format(localFormat))
+		writeICSLine(&b, "SUMMARY:"+escapeICSText("Проживание: "+stay.ListingTitle))
+		writeICSLine(&b, "LOCATION:"+escapeICSText(location))
+		writeICSLine(&b, "DESCRIPTION:"+escapeICSText(description))
+		writeICSLine(&b, "END:VEVENT")
+
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+stay.AssignmentID.String()+"-report@secret-guest")
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		// Срок - момент времени: без DTEND и DURATION событие заканчивается в момент начала(RFC 5545, 3.6.1)
+		writeICSLine(&b, "DTSTART:"+deadline.Format(localFormat))
+		writeICSLine(&b, "SUMMARY:"+escapeICSText("Срок сдачи отчета: "+stay.ListingTitle))
+		writeICSLine(&b, "LOCATION:"+escapeICSText(location))
+		writeICSLine(&b, "END:VEVENT")
+	}
+
+	writeICSLine(&b, "END:VCALENDAR")
+	return []byte(b.String()), nil
+}
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICSText(text string) string {
+	return icsTextEscaper.Replace(text)
+}
+
+// writeICSLine пишет строку ленты, перенося ее по 75 байт(перенос - CRLF и пробел), не разрывая символы UTF-8
+func writeICSLine(b *strings.Builder, line string) {
+	const maxOctets = 75
+
+	limit := maxOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxOctets - 1 // с учетом пробела в начале продолжения
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
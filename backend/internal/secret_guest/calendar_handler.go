@@ -0,0 +1,77 @@
+package secret_guest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// calendar
+
+// @Summary      Regenerate My Calendar Link
+// @Security     BearerAuth
+// @Description  Issues a new secret link to the iCalendar feed of the user's accepted assignments and invalidates the previous one. The token is not stored, the link is shown only in this response.
+// @Tags         Profiles (User)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.CalendarTokenResponseDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /profiles/my/calendar/token [post]
+func (h *SecretGuestHandler) RegenerateMyCalendarToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := h.service.RegenerateMyCalendarToken(ctx, userID)
+	if err != nil {
+		log.Error(ctx, "Failed to regenerate calendar token", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, token)
+}
+
+// @Summary      Calendar Feed of Stays
+// @Description  Public iCalendar feed by the secret token from the calendar link: accepted assignments for stay dates (check-in and check-out, listing address, booking number) and report deadlines (REPORT_DEADLINE_HOURS_AFTER_CHECKOUT after check-out). Stays that ended more than 90 days ago are not included.
+// @Tags         Calendar
+// @Produce      text/calendar
+// @Param        token path string true "Calendar token"
+// @Success      200 {string} string "iCalendar feed"
+// @Failure      404 {object} ErrorResponse "Calendar not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /calendar/{token}.ics [get]
+func (h *SecretGuestHandler) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	token := mux.Vars(r)["token"]
+
+	feed, err := h.service.GetCalendarFeed(ctx, token)
+	if err != nil {
+		if errors.Is(err, models.ErrCalendarTokenNotFound) {
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Calendar not found")
+			return
+		}
+		log.Error(ctx, "Failed to build calendar feed", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="stays.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(feed); err != nil {
+		log.Warn(ctx, "Failed to write calendar feed", zap.Error(err))
+	}
+}
@@ -0,0 +1,294 @@
+package secret_guest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Кампании проверок
+
+// campaignAcceptsAssignment - в кампанию можно добавить предложение с заездом checkinDate
+func campaignAcceptsAssignment(c *models.Campaign, checkinDate time.Time) bool {
+	if !c.IsActive {
+		return false
+	}
+	day := campaignDate(checkinDate)
+	if day.Before(c.StartsAt) || day.After(c.EndsAt) {
+		return false
+	}
+	if c.Quota != nil && c.Progress.Assignments >= *c.Quota {
+		return false
+	}
+	if c.Budget != nil && c.Progress.Spent >= *c.Budget {
+		return false
+	}
+	return true
+}
+
+// validateCampaign проверяет согласованность полей кампании после создания или изменения
+func validateCampaign(c *models.Campaign) error {
+	if c.EndsAt.Before(c.StartsAt) {
+		return fmt.Errorf("%w: ends_at must not be before starts_at", models.ErrInvalidCampaign)
+	}
+	if c.Budget != nil && c.Currency == nil {
+		return fmt.Errorf("%w: currency is required with budget", models.ErrInvalidCampaign)
+	}
+	return nil
+}
+
+// campaignDate отбрасывает время: период кампании задается датами
+func campaignDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// positiveOrNil - 0 в запросе на изменение снимает ограничение
+func positiveOrNil(v int) *int {
+	if v <= 0 {
+		return nil
+	}
+	return &v
+}
+
+func toCampaignResponseDTO(c *models.Campaign) *CampaignResponseDTO {
+	progress := CampaignProgressDTO{
+		TargetListings:      c.Progress.TargetListings,
+		Assignments:         c.Progress.Assignments,
+		AcceptedAssignments: c.Progress.AcceptedAssignments,
+		ApprovedReports:     c.Progress.ApprovedReports,
+		InspectedListings:   c.Progress.InspectedListings,
+		Spent:               c.Progress.Spent,
+	}
+	if c.Quota != nil {
+		left := max(*c.Quota-c.Progress.Assignments, 0)
+		progress.QuotaLeft = &left
+	}
+	if c.Budget != nil {
+		left := max(*c.Budget-c.Progress.Spent, 0)
+		progress.BudgetLeft = &left
+	}
+
+	listingTypeIDs := c.ListingTypeIDs
+	if listingTypeIDs == nil {
+		listingTypeIDs = []int{}
+	}
+
+	return &CampaignResponseDTO{
+		ID:               c.ID,
+		Name:             c.Name,
+		Purpose:          c.Purpose,
+		ListingTypeIDs:   listingTypeIDs,
+		City:             c.City,
+		NotInspectedDays: c.NotInspectedDays,
+		StartsAt:         c.StartsAt,
+		EndsAt:           c.EndsAt,
+		Quota:            c.Quota,
+		Budget:           c.Budget,
+		Currency:         c.Currency,
+		IsActive:         c.IsActive,
+		Progress:         progress,
+		CreatedAt:        c.CreatedAt,
+		UpdatedAt:        c.UpdatedAt,
+	}
+}
+
+func (s *SecretGuestService) GetCampaigns(ctx context.Context, dto GetCampaignsRequestDTO) (*CampaignsResponse, error) {
+	filter := repository.CampaignsFilter{
+		ActiveOnly: dto.ActiveOnly,
+		Limit:      dto.Limit,
+		Offset:     (dto.Page - 1) * dto.Limit,
+	}
+
+	campaigns, total, err := s.repo.GetCampaigns(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaigns from repository: %w", err)
+	}
+
+	responseDTOs := make([]*CampaignResponseDTO, 0, len(campaigns))
+	for _, c := range campaigns {
+		responseDTOs = append(responseDTOs, toCampaignResponseDTO(c))
+	}
+
+	return &CampaignsResponse{
+		Campaigns: responseDTOs,
+		Total:     total,
+		Page:      dto.Page,
+	}, nil
+}
+
+func (s *SecretGuestService) GetCampaignByID(ctx context.Context, campaignID uuid.UUID) (*CampaignResponseDTO, error) {
+	campaign, err := s.repo.GetCampaignByID(ctx, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign by id %s: %w", campaignID.String(), err)
+	}
+	return toCampaignResponseDTO(campaign), nil
+}
+
+func (s *SecretGuestService) GetCampaignListings(ctx context.Context, campaignID uuid.UUID, page, limit int) (*CampaignListingsResponse, error) {
+	if _, err := s.repo.GetCampaignByID(ctx, campaignID); err != nil {
+		return nil, fmt.Errorf("failed to get campaign by id %s: %w", campaignID.String(), err)
+	}
+
+	listings, total, err := s.repo.GetCampaignListings(ctx, campaignID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign listings from repository: %w", err)
+	}
+
+	responseDTOs := make([]*CampaignListingDTO, 0, len(listings))
+	for _, l := range listings {
+		responseDTOs = append(responseDTOs, &CampaignListingDTO{
+			ListingID:       l.ListingID,
+			Title:           l.Title,
+			City:            l.City,
+			ListingTypeID:   l.ListingTypeID,
+			LastInspectedAt: l.LastInspectedAt,
+			Assignments:     l.Assignments,
+			Priority:        l.Priority,
+		})
+	}
+
+	return &CampaignListingsResponse{
+		Listings: responseDTOs,
+		Total:    total,
+		Page:     page,
+	}, nil
+}
+
+func (s *SecretGuestService) CreateCampaign(ctx context.Context, actorID uuid.UUID, dto CreateCampaignRequestDTO) (*CampaignResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	listingTypeIDs, err := s.normalizeListingTypeIDs(ctx, dto.ListingTypeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	campaign := &models.Campaign{
+		Name:             strings.TrimSpace(dto.Name),
+		Purpose:          strings.TrimSpace(dto.Purpose),
+		ListingTypeIDs:   listingTypeIDs,
+		NotInspectedDays: dto.NotInspectedDays,
+		StartsAt:         campaignDate(dto.StartsAt),
+		EndsAt:           campaignDate(dto.EndsAt),
+		Quota:            dto.Quota,
+		Budget:           dto.Budget,
+		IsActive:         true,
+		CreatedBy:        &actorID,
+	}
+	if dto.City != nil && strings.TrimSpace(*dto.City) != "" {
+		city := strings.TrimSpace(*dto.City)
+		campaign.City = &city
+	}
+	if dto.Currency != nil {
+		currency := strings.ToUpper(*dto.Currency)
+		campaign.Currency = &currency
+	}
+	if dto.IsActive != nil {
+		campaign.IsActive = *dto.IsActive
+	}
+	if err := validateCampaign(campaign); err != nil {
+		return nil, err
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionCampaignCreated, models.AuditEntityCampaign, "", map[string]any{
+		"name":               campaign.Name,
+		"listing_type_ids":   campaign.ListingTypeIDs,
+		"city":               campaign.City,
+		"not_inspected_days": campaign.NotInspectedDays,
+		"starts_at":          campaign.StartsAt,
+		"ends_at":            campaign.EndsAt,
+		"quota":              campaign.Quota,
+		"budget":             campaign.Budget,
+		"currency":           campaign.Currency,
+	})
+
+	if err := s.repo.CreateCampaign(ctx, campaign, entry); err != nil {
+		return nil, fmt.Errorf("failed to create campaign in repository: %w", err)
+	}
+
+	log.Info(ctx, "Campaign created", zap.String("actor_id", actorID.String()), zap.String("campaign_id", campaign.ID.String()))
+
+	return s.GetCampaignByID(ctx, campaign.ID)
+}
+
+func (s *SecretGuestService) UpdateCampaign(ctx context.Context, actorID, campaignID uuid.UUID, dto UpdateCampaignRequestDTO) (*CampaignResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	campaign, err := s.repo.GetCampaignByID(ctx, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign by id %s: %w", campaignID.String(), err)
+	}
+
+	details := map[string]any{}
+	if dto.Name != nil {
+		campaign.Name = strings.TrimSpace(*dto.Name)
+		details["name"] = campaign.Name
+	}
+	if dto.Purpose != nil {
+		campaign.Purpose = strings.TrimSpace(*dto.Purpose)
+		details["purpose"] = campaign.Purpose
+	}
+	if dto.ListingTypeIDs != nil {
+		listingTypeIDs, err := s.normalizeListingTypeIDs(ctx, *dto.ListingTypeIDs)
+		if err != nil {
+			return nil, err
+		}
+		campaign.ListingTypeIDs = listingTypeIDs
+		details["listing_type_ids"] = campaign.ListingTypeIDs
+	}
+	if dto.City != nil {
+		campaign.City = nil
+		if city := strings.TrimSpace(*dto.City); city != "" {
+			campaign.City = &city
+		}
+		details["city"] = campaign.City
+	}
+	if dto.NotInspectedDays != nil {
+		campaign.NotInspectedDays = positiveOrNil(*dto.NotInspectedDays)
+		details["not_inspected_days"] = campaign.NotInspectedDays
+	}
+	if dto.StartsAt != nil {
+		campaign.StartsAt = campaignDate(*dto.StartsAt)
+		details["starts_at"] = campaign.StartsAt
+	}
+	if dto.EndsAt != nil {
+		campaign.EndsAt = campaignDate(*dto.EndsAt)
+		details["ends_at"] = campaign.EndsAt
+	}
+	if dto.Quota != nil {
+		campaign.Quota = positiveOrNil(*dto.Quota)
+		details["quota"] = campaign.Quota
+	}
+	if dto.Budget != nil {
+		campaign.Budget = positiveOrNil(*dto.Budget)
+		details["budget"] = campaign.Budget
+	}
+	if dto.Currency != nil {
+		currency := strings.ToUpper(*dto.Currency)
+		campaign.Currency = &currency
+		details["currency"] = campaign.Currency
+	}
+	if dto.IsActive != nil {
+		campaign.IsActive = *dto.IsActive
+		details["is_active"] = campaign.IsActive
+	}
+	if err := validateCampaign(campaign); err != nil {
+		return nil, err
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionCampaignUpdated, models.AuditEntityCampaign, campaignID.String(), details)
+
+	if err := s.repo.UpdateCampaign(ctx, campaign, entry); err != nil {
+		return nil, fmt.Errorf("failed to update campaign: %w", err)
+	}
+
+	log.Info(ctx, "Campaign updated", zap.String("actor_id", actorID.String()), zap.String("campaign_id", campaignID.String()))
+
+	return s.GetCampaignByID(ctx, campaignID)
+}
@@ -0,0 +1,222 @@
+package secret_guest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// campaigns
+
+// @Summary      Get Campaigns (Staff)
+// @Security     BearerAuth
+// @Description  Returns inspection campaigns, newest first, with progress: listings matching the campaign selection now, assignments in the campaign, accepted assignments, approved reports, inspected listings and spent rewards, plus the quota and budget left.
+// @Tags         Campaigns (Staff)
+// @Produce      json
+// @Param        active query bool false "Only active campaigns"
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.CampaignsResponse
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/campaigns [get]
+func (h *SecretGuestHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	page, limit := h.parsePagination(r)
+	dto := GetCampaignsRequestDTO{
+		ActiveOnly: r.URL.Query().Get("active") == "true",
+		Page:       page,
+		Limit:      limit,
+	}
+
+	campaigns, err := h.service.GetCampaigns(ctx, dto)
+	if err != nil {
+		h.handleCampaignError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, campaigns)
+}
+
+// @Summary      Get Campaign (Staff)
+// @Security     BearerAuth
+// @Description  Returns an inspection campaign with its progress.
+// @Tags         Campaigns (Staff)
+// @Produce      json
+// @Param        id path string true "Campaign ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.CampaignResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid campaign ID format"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Campaign not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/campaigns/{id} [get]
+func (h *SecretGuestHandler) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	campaignID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	campaign, err := h.service.GetCampaignByID(ctx, campaignID)
+	if err != nil {
+		h.handleCampaignError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, campaign)
+}
+
+// @Summary      Get Campaign Listings (Staff)
+// @Security     BearerAuth
+// @Description  Returns listings matching the campaign selection (listing types, city, no approved report for not_inspected_days), highest inspection priority first, with the number of campaign assignments per listing.
+// @Tags         Campaigns (Staff)
+// @Produce      json
+// @Param        id path string true "Campaign ID" format(uuid)
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.CampaignListingsResponse
+// @Failure      400 {object} ErrorResponse "Invalid campaign ID format"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Campaign not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/campaigns/{id}/listings [get]
+func (h *SecretGuestHandler) GetCampaignListings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	campaignID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	page, limit := h.parsePagination(r)
+	listings, err := h.service.GetCampaignListings(ctx, campaignID, page, limit)
+	if err != nil {
+		h.handleCampaignError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, listings)
+}
+
+// @Summary      Create Campaign (Admin)
+// @Security     BearerAuth
+// @Description  Creates an inspection campaign: a named goal with a listing selection (listing types, city, no approved report for not_inspected_days), a period, an optional quota of assignments and an optional budget for rewards. New OTA reservations for matching listings with check-in within the period are tagged to the campaign while it is active and within quota and budget; the assignment purpose is taken from the campaign. The action is recorded in the audit log.
+// @Tags         Campaigns (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.CreateCampaignRequestDTO true "Campaign"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.CampaignResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid campaign"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/campaigns [post]
+func (h *SecretGuestHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto CreateCampaignRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for campaign", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	campaign, err := h.service.CreateCampaign(ctx, actorID, dto)
+	if err != nil {
+		h.handleCampaignError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, campaign)
+}
+
+// @Summary      Update Campaign (Admin)
+// @Security     BearerAuth
+// @Description  Partially updates an inspection campaign. 0 in quota, budget or not_inspected_days and an empty city remove the limit. Assignments already tagged to the campaign are kept. The action is recorded in the audit log.
+// @Tags         Campaigns (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Campaign ID" format(uuid)
+// @Param        input body secret_guest.UpdateCampaignRequestDTO true "Campaign fields to update"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.CampaignResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid campaign"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Campaign not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/campaigns/{id} [patch]
+func (h *SecretGuestHandler) UpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	campaignID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto UpdateCampaignRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for campaign", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	campaign, err := h.service.UpdateCampaign(ctx, actorID, campaignID, dto)
+	if err != nil {
+		h.handleCampaignError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, campaign)
+}
+
+func (h *SecretGuestHandler) handleCampaignError(ctx context.Context, w http.ResponseWriter, err error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	switch {
+	case errors.Is(err, models.ErrCampaignNotFound):
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, "Campaign not found")
+	case errors.Is(err, models.ErrInvalidCampaign):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrValidationFailed):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+	default:
+		log.Error(ctx, "Failed to process campaign", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+	}
+}
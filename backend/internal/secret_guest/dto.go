@@ -139,14 +139,67 @@ type OTAReservationsResponse struct {
 }
 
 // ================================
+// CreateAssignmentRequestDTO - ручное создание предложения персоналом, без бронирования от OTA
 type CreateAssignmentRequestDTO struct {
-	Code       uuid.UUID `json:"code" validate:"required,uuid"`
-	ListingID  uuid.UUID `json:"listing_id" validate:"required,uuid"`
-	ReporterID uuid.UUID `json:"reporter_id" validate:"required,uuid"`
-	Purpose    string    `json:"purpose" validate:"required"`
-	ExpiresAt  time.Time `json:"expires_at" validate:"required"`
+	ListingID    uuid.UUID `json:"listing_id" validate:"required"`
+	Purpose      string    `json:"purpose" validate:"required_without=CampaignID,max=500"`
+	CheckinDate  time.Time `json:"checkin_date" validate:"required"`
+	CheckoutDate time.Time `json:"checkout_date" validate:"required"`
+	// После этой даты предложение неактивно, по умолчанию - дата заезда
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Приглашение конкретного гостя: предложение сразу закрепляется за ним
+	ReporterID *uuid.UUID `json:"reporter_id,omitempty"`
+	// Срок принятия приглашения, по умолчанию - expires_at
+	AcceptDeadline *time.Time `json:"accept_deadline,omitempty"`
+	// Кампания проверок; purpose можно не указывать - возьмется из кампании
+	CampaignID *uuid.UUID `json:"campaign_id,omitempty"`
 }
 
+// ReassignAssignmentRequestDTO - закрепление открытого предложения за другим гостем
+type ReassignAssignmentRequestDTO struct {
+	ReporterID uuid.UUID `json:"reporter_id" validate:"required"`
+	// Срок принятия приглашения, по умолчанию - expires_at
+	AcceptDeadline *time.Time `json:"accept_deadline,omitempty"`
+}
+
+type ExtendAssignmentRequestDTO struct {
+	// Новый срок действия: позже текущего и до даты выезда
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+// BulkAssignmentsFilterDTO - отбор предложений для массовой операции, хотя бы одно условие
+type BulkAssignmentsFilterDTO struct {
+	StatusIDs      []int      `json:"status_ids,omitempty" validate:"omitempty,dive,gte=1,lte=5"`
+	ListingTypeIDs []int      `json:"listing_type_ids,omitempty" validate:"omitempty,dive,gte=1"`
+	City           string     `json:"city,omitempty" validate:"max=100"`
+	ReporterID     *uuid.UUID `json:"reporter_id,omitempty"`
+	CampaignID     *uuid.UUID `json:"campaign_id,omitempty"`
+	ExpiresBefore  *time.Time `json:"expires_before,omitempty"`
+}
+
+// BulkAssignmentsRequestDTO - предложения массовой операции: либо ids, либо filter
+type BulkAssignmentsRequestDTO struct {
+	IDs    []uuid.UUID               `json:"ids,omitempty" validate:"omitempty,max=500"`
+	Filter *BulkAssignmentsFilterDTO `json:"filter,omitempty"`
+}
+
+type BulkExtendAssignmentsRequestDTO struct {
+	BulkAssignmentsRequestDTO
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
+}
+
+type BulkAssignmentResultDTO struct {
+	AssignmentID uuid.UUID `json:"assignment_id"`
+	OK           bool      `json:"ok"`
+	Error        string    `json:"error,omitempty"`
+}
+
+type BulkAssignmentsResponse struct {
+	BulkID    uuid.UUID                  `json:"bulk_id"` // bulk_id в записях журнала аудита
+	Succeeded int                        `json:"succeeded"`
+	Failed    int                        `json:"failed"`
+	Results   []*BulkAssignmentResultDTO `json:"results"`
+}
 type GetMyAssignmentsRequestDTO struct {
 	UserID uuid.UUID
 	Page   int
@@ -155,6 +208,7 @@ type GetMyAssignmentsRequestDTO struct {
 }
 
 type GetFreeAssignmentsRequestDTO struct {
+	UserID         uuid.UUID
 	Page           int
 	Limit          int
 	ListingTypeIDs []int
@@ -192,6 +246,15 @@ type AssignmentResponseDTO struct {
 
 	ExpiresAt time.Time  `json:"expires_at"`
 	TakedAt   *time.Time `json:"taked_at,omitempty"`
+
+	// Для приглашения персоналом и взятого гостем предложения: после accept_deadline предложение возвращается в свободные
+	InvitedAt       *time.Time `json:"invited_at,omitempty"`
+	AcceptDeadline  *time.Time `json:"accept_deadline,omitempty"`
+	HoldSecondsLeft *int64     `json:"hold_seconds_left,omitempty"` // сколько секунд осталось до accept_deadline
+
+	CampaignID *uuid.UUID `json:"campaign_id,omitempty"`
+
+	Stay *AssignmentStayDTO `json:"stay,omitempty"` // только для персонала
 }
 
 type AssignmentsResponse struct {
@@ -200,6 +263,97 @@ type AssignmentsResponse struct {
 	Page        int                      `json:"page"`
 }
 
+type WaitlistEntryResponseDTO struct {
+	AssignmentID uuid.UUID `json:"assignment_id"`
+	Position     int       `json:"position"` // с 1
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type StayCheckRequestDTO struct {
+	Latitude  *float64  `json:"latitude" validate:"required,gte=-90,lte=90" example:"55.7558"`
+	Longitude *float64  `json:"longitude" validate:"required,gte=-180,lte=180" example:"37.6173"`
+	Timestamp time.Time `json:"timestamp" validate:"required" example:"2025-10-20T14:05:00+03:00"` // время устройства
+}
+
+type StayCheckDTO struct {
+	At             time.Time `json:"at"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	DistanceMeters *float64  `json:"distance_m,omitempty"` // до объекта; нет, если у объекта не указаны координаты
+}
+
+// AssignmentStayDTO - отметки заезда и выезда гостя по принятому предложению
+type AssignmentStayDTO struct {
+	CheckIn  *StayCheckDTO `json:"check_in,omitempty"`
+	CheckOut *StayCheckDTO `json:"check_out,omitempty"`
+}
+
+type CalendarTokenResponseDTO struct {
+	URL       string    `json:"url"` // ссылка на ленту .ics, показывается только при выпуске
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeclineAssignmentRequestDTO struct {
+	// Причина отказа: dates, location, listing_type, other(нужен comment)
+	Reason  string `json:"reason,omitempty" validate:"omitempty,oneof=dates location listing_type other" example:"dates"`
+	Comment string `json:"comment,omitempty" validate:"max=1000"`
+}
+
+type GetDeclineAnalyticsRequestDTO struct {
+	GroupBy string // reason, city, listing
+	From    *time.Time
+	To      *time.Time
+	Page    int
+	Limit   int
+}
+
+type DeclineStatDTO struct {
+	Key         string         `json:"key" example:"dates"` // slug причины, город или ID объекта
+	Label       string         `json:"label" example:"Не подходят даты"`
+	Declines    int            `json:"declines"`
+	Assignments int            `json:"assignments"`
+	Guests      int            `json:"guests"`
+	ByReason    map[string]int `json:"by_reason"`
+}
+
+type DeclineAnalyticsResponse struct {
+	GroupBy string            `json:"group_by"`
+	Groups  []*DeclineStatDTO `json:"groups"`
+	Total   int               `json:"total"`
+	Page    int               `json:"page"`
+}
+
+type GetDeclinedAssignmentsRequestDTO struct {
+	MinDeclines int
+	Page        int
+	Limit       int
+}
+
+type DeclinedAssignmentDTO struct {
+	AssignmentID   uuid.UUID                  `json:"assignment_id"`
+	Listing        DeclinedAssignmentListing  `json:"listing"`
+	Purpose        string                     `json:"purpose"`
+	Dates          AssignmentReservationDates `json:"dates"`
+	ExpiresAt      time.Time                  `json:"expires_at"`
+	CampaignID     *uuid.UUID                 `json:"campaign_id,omitempty"`
+	Declines       int                        `json:"declines"`
+	LastDeclinedAt *time.Time                 `json:"last_declined_at,omitempty"`
+	ByReason       map[string]int             `json:"by_reason"`
+	Comments       []string                   `json:"comments"`
+}
+
+type DeclinedAssignmentListing struct {
+	ID    uuid.UUID `json:"id"`
+	Title string    `json:"title"`
+	City  string    `json:"city"`
+}
+
+type DeclinedAssignmentsResponse struct {
+	Assignments []*DeclinedAssignmentDTO `json:"assignments"`
+	Total       int                      `json:"total"`
+	Page        int                      `json:"page"`
+}
+
 //================================
 
 type ListingShortResponse struct {
@@ -268,7 +422,16 @@ type ReportResponseDTO struct {
 	UpdatedAt   *time.Time `json:"updated_at"`
 	SubmittedAt *time.Time `json:"submitted_at"`
 
+	// Оценка качества объекта модератором при одобрении(0-100)
+	QualityScore *int `json:"quality_score,omitempty"`
+
+	// Срок сдачи отчета; не сданный к сроку черновик становится просроченным
+	DueAt          *time.Time `json:"due_at,omitempty"`
+	DueSecondsLeft *int64     `json:"due_seconds_left,omitempty"` // сколько секунд осталось до due_at(для черновика)
+
 	ChecklistSchema models.ChecklistSchema `json:"checklist_schema"`
+
+	Stay *AssignmentStayDTO `json:"stay,omitempty"` // только для персонала
 }
 
 type ReportsResponse struct {
@@ -277,6 +440,11 @@ type ReportsResponse struct {
 	Page    int                  `json:"page"`
 }
 
+type ApproveReportRequestDTO struct {
+	// Оценка качества объекта: 0 - очень плохо, 100 - отлично. Учитывается в приоритете проверки объекта
+	QualityScore *int `json:"quality_score,omitempty" validate:"omitempty,gte=0,lte=100" example:"80"`
+}
+
 //////
 
 type UpdateReportRequestDTO struct {
@@ -570,17 +738,27 @@ type UpdateChecklistItemRequestDTO struct {
 // ================================
 
 type GetAllUsersRequestDTO struct {
-	Page  int
-	Limit int
+	Page           int
+	Limit          int
+	Search         string
+	RoleIDs        []int
+	Blocked        *bool
+	ServiceAccount *bool
 }
 
 type UserResponseDTO struct {
-	ID        uuid.UUID `json:"id"`
-	Username  string    `json:"username"`
-	Email     *string   `json:"email"`
-	RoleID    int       `json:"role_id"`
-	RoleName  string    `json:"role_name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID                     uuid.UUID  `json:"id"`
+	Username               string     `json:"username"`
+	Email                  *string    `json:"email"`
+	RoleID                 int        `json:"role_id"`
+	RoleName               string     `json:"role_name"`
+	CreatedAt              time.Time  `json:"created_at"`
+	IsBlocked              bool       `json:"is_blocked"`
+	BlockedAt              *time.Time `json:"blocked_at,omitempty"`
+	BlockedReason          *string    `json:"blocked_reason,omitempty"`
+	PasswordChangeRequired bool       `json:"password_change_required"`
+	IsServiceAccount       bool       `json:"is_service_account"`
+	DeletedAt              *time.Time `json:"deleted_at,omitempty"`
 }
 
 type UsersResponse struct {
@@ -589,26 +767,623 @@ type UsersResponse struct {
 	Page  int                `json:"page"`
 }
 
+type ChangeUserRoleRequestDTO struct {
+	RoleID int `json:"role_id" validate:"required,gt=0"`
+}
+
+type BlockUserRequestDTO struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type ResetUserPasswordResponseDTO struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+// ================================
+
+type GetAuditLogRequestDTO struct {
+	Page       int
+	Limit      int
+	ActorID    *uuid.UUID
+	EntityType string
+	EntityID   string
+	Actions    []string
+}
+
+type AuditLogEntryResponseDTO struct {
+	ID            uuid.UUID       `json:"id"`
+	ActorID       *uuid.UUID      `json:"actor_id"`
+	ActorUsername *string         `json:"actor_username"`
+	Action        string          `json:"action"`
+	EntityType    string          `json:"entity_type"`
+	EntityID      string          `json:"entity_id,omitempty"`
+	Details       json.RawMessage `json:"details,omitempty" swaggertype:"object"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+type AuditLogResponse struct {
+	Entries []*AuditLogEntryResponseDTO `json:"entries"`
+	Total   int                         `json:"total"`
+	Page    int                         `json:"page"`
+}
+
+// ================================
+
+type PermissionResponseDTO struct {
+	ID          int     `json:"id"`
+	Slug        string  `json:"slug" example:"reports.approve"`
+	Description *string `json:"description"`
+}
+
+type RoleResponseDTO struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+	IsBuiltIn   bool     `json:"is_built_in"` // admin, moderator, secret_guest
+}
+
+type CreateRoleRequestDTO struct {
+	Name        string   `json:"name" validate:"required,max=50" example:"checklist_editor"`
+	Description *string  `json:"description,omitempty"`
+	Permissions []string `json:"permissions" validate:"dive,required"`
+}
+
+type UpdateRoleRequestDTO struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,max=50"`
+	Description *string `json:"description,omitempty"`
+}
+
+type SetRolePermissionsRequestDTO struct {
+	Permissions []string `json:"permissions" validate:"required,dive,required" example:"reports.view,reports.approve"`
+}
+
+// ================================
+
+type CreateServiceAccountRequestDTO struct {
+	Username string `json:"username" validate:"required,max=50" example:"ota_feed"`
+	RoleID   int    `json:"role_id" validate:"required,gt=0"`
+}
+
+type CreateAPIKeyRequestDTO struct {
+	Name      string     `json:"name" validate:"required,max=100" example:"BI export"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required" example:"statistics.view,reports.view"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // без срока действия, если не указан
+}
+
+type APIKeyResponseDTO struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" example:"sgk_AbCdEfGh"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	IsActive   bool       `json:"is_active"`
+}
+
+// CreateAPIKeyResponseDTO - ключ возвращается только при создании, повторно получить его нельзя
+type CreateAPIKeyResponseDTO struct {
+	APIKeyResponseDTO
+	Key string `json:"key" example:"sgk_AbCdEfGh..."`
+}
+
+// ================================
+
+type SubmitGuestApplicationRequestDTO struct {
+	Motivation      string   `json:"motivation" validate:"required,min=20,max=2000"`
+	TravelFrequency string   `json:"travel_frequency" validate:"required,oneof=rarely few_times_a_year monthly weekly" example:"few_times_a_year"`
+	HomeCity        string   `json:"home_city" validate:"required,max=100" example:"Москва"`
+	SocialLinks     []string `json:"social_links,omitempty" validate:"max=5,dive,url" example:"https://t.me/traveller"`
+}
+
+type GuestApplicationResponseDTO struct {
+	ID              uuid.UUID      `json:"id"`
+	UserID          uuid.UUID      `json:"user_id"`
+	Username        string         `json:"username"`
+	Status          StatusResponse `json:"status"`
+	Motivation      string         `json:"motivation"`
+	TravelFrequency *string        `json:"travel_frequency"`
+	HomeCity        string         `json:"home_city"`
+	SocialLinks     []string       `json:"social_links"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	ReviewedBy      *uuid.UUID     `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time     `json:"reviewed_at,omitempty"`
+	ReviewComment   *string        `json:"review_comment,omitempty"`
+}
+
+type GetGuestApplicationsRequestDTO struct {
+	Page      int
+	Limit     int
+	StatusIDs []int
+}
+
+type GuestApplicationsResponse struct {
+	Applications []*GuestApplicationResponseDTO `json:"applications"`
+	Total        int                            `json:"total"`
+	Page         int                            `json:"page"`
+}
+
+type ReviewGuestApplicationRequestDTO struct {
+	Comment *string `json:"comment,omitempty" validate:"omitempty,max=1000"`
+}
+
+// ================================
+
+type RewardResponseDTO struct {
+	ID              uuid.UUID      `json:"id"`
+	AssignmentID    uuid.UUID      `json:"assignment_id"`
+	ReportID        *uuid.UUID     `json:"report_id,omitempty"`
+	UserID          *uuid.UUID     `json:"user_id,omitempty"`
+	Username        string         `json:"username,omitempty"`
+	ListingID       uuid.UUID      `json:"listing_id"`
+	ListingTitle    string         `json:"listing_title"`
+	ListingTypeID   int            `json:"listing_type_id"`
+	Amount          int            `json:"amount" example:"12000"` // в валюте брони
+	Currency        string         `json:"currency" example:"RUB"`
+	Percent         int            `json:"percent" example:"100"` // доля стоимости брони по политике на момент начисления
+	Status          StatusResponse `json:"status"`
+	CreatedAt       time.Time      `json:"created_at"`
+	ApprovedAt      *time.Time     `json:"approved_at,omitempty"`
+	PaidAt          *time.Time     `json:"paid_at,omitempty"`
+	PayoutReference *string        `json:"payout_reference,omitempty"`
+	VoidedAt        *time.Time     `json:"voided_at,omitempty"`
+	VoidReason      *string        `json:"void_reason,omitempty"`
+}
+
+type RewardTotalDTO struct {
+	Status   StatusResponse `json:"status"`
+	Currency string         `json:"currency" example:"RUB"`
+	Amount   int            `json:"amount"`
+	Count    int            `json:"count"`
+}
+
+type GetRewardsRequestDTO struct {
+	Page         int
+	Limit        int
+	UserID       *uuid.UUID
+	StatusIDs    []int
+	ApprovedFrom *time.Time
+	ApprovedTo   *time.Time
+}
+
+type RewardsResponse struct {
+	Rewards []*RewardResponseDTO `json:"rewards"`
+	Total   int                  `json:"total"`
+	Page    int                  `json:"page"`
+}
+
+// MyRewardsResponse - начисления пользователя и итоги по статусам во всех валютах
+type MyRewardsResponse struct {
+	Rewards []*RewardResponseDTO `json:"rewards"`
+	Totals  []*RewardTotalDTO    `json:"totals"`
+	Total   int                  `json:"total"`
+	Page    int                  `json:"page"`
+}
+
+type PayRewardsRequestDTO struct {
+	RewardIDs       []uuid.UUID `json:"reward_ids" validate:"required,min=1,max=100,unique"`
+	PayoutReference string      `json:"payout_reference" validate:"required,max=200" example:"ПП-2025-0142"` // номер платежного поручения или ваучера
+}
+
+type VoidRewardRequestDTO struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// RewardsExport - CSV-файл начислений для бухгалтерии
+type RewardsExport struct {
+	FileName string
+	Content  []byte
+}
+
+type RewardPolicyResponseDTO struct {
+	ListingType ListingTypeResponse `json:"listing_type"`
+	Percent     int                 `json:"percent" example:"100"`
+	MaxAmount   *int                `json:"max_amount,omitempty"`
+	IsDefault   bool                `json:"is_default"` // политика не настроена, действует бесплатное проживание
+	UpdatedAt   *time.Time          `json:"updated_at,omitempty"`
+}
+
+type UpdateRewardPolicyRequestDTO struct {
+	// Доля стоимости брони: 100 - бесплатное проживание, меньше - скидка
+	Percent *int `json:"percent" validate:"required,gte=0,lte=100" example:"50"`
+	// Ограничение суммы в валюте брони, null - без ограничения
+	MaxAmount *int `json:"max_amount,omitempty" validate:"omitempty,gte=0" example:"10000"`
+}
+
+type RankDTO struct {
+	Slug      string `json:"slug" example:"experienced"`
+	Name      string `json:"name" example:"Опытный"`
+	MinPoints int    `json:"min_points" example:"100"`
+}
+
+type PointBreakdownDTO struct {
+	EventType string `json:"event_type" example:"report_approved"`
+	Name      string `json:"name" example:"Одобрение отчета"`
+	Count     int    `json:"count"`
+	Points    int    `json:"points"`
+}
+
+type PointEventDTO struct {
+	ID           uuid.UUID  `json:"id"`
+	EventType    string     `json:"event_type" example:"assignment_accepted"`
+	Name         string     `json:"name" example:"Принятие предложения"`
+	Points       int        `json:"points" example:"10"`
+	AssignmentID *uuid.UUID `json:"assignment_id,omitempty"`
+	ReportID     *uuid.UUID `json:"report_id,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type GetPointsRequestDTO struct {
+	Page  int
+	Limit int
+	Lang  string
+}
+
+// PointsExplanationResponse - откуда у гостя очки и сколько осталось до следующего ранга
+type PointsExplanationResponse struct {
+	Points           int                  `json:"points"`
+	Rank             RankDTO              `json:"rank"`
+	NextRank         *RankDTO             `json:"next_rank,omitempty"`
+	PointsToNextRank *int                 `json:"points_to_next_rank,omitempty"`
+	Breakdown        []*PointBreakdownDTO `json:"breakdown"`
+	Events           []*PointEventDTO     `json:"events"`
+	Total            int                  `json:"total"` // количество событий
+	Page             int                  `json:"page"`
+}
+
+type PointRuleResponseDTO struct {
+	EventType  string     `json:"event_type" example:"report_late"`
+	Name       string     `json:"name" example:"Сдача отчета после срока"`
+	Points     int        `json:"points" example:"-5"`
+	GraceHours *int       `json:"grace_hours,omitempty" example:"72"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+type UpdatePointRuleRequestDTO struct {
+	// Отрицательное значение - штраф
+	Points *int `json:"points" validate:"required,gte=-1000,lte=1000" example:"-5"`
+	// Только для report_late: сколько часов после срока сдачи(due_at) отчет еще можно сдать со штрафом
+	GraceHours *int `json:"grace_hours,omitempty" validate:"omitempty,gte=0,lte=720" example:"72"`
+}
+
+type RankTierDTO struct {
+	Slug      string            `json:"slug" validate:"required,max=50" example:"experienced"`
+	MinPoints int               `json:"min_points" validate:"gte=0" example:"100"`
+	Names     map[string]string `json:"names" validate:"required,min=1,dive,keys,len=2,endkeys,required,max=100"`
+}
+
+type ReplaceRankTiersRequestDTO struct {
+	Tiers []RankTierDTO `json:"tiers" validate:"required,min=1,max=50,dive"`
+}
+
+type RecomputePointsResponse struct {
+	UpdatedEvents int64 `json:"updated_events"`
+}
+
+// EarnedBadgeDTO - полученный значок, название и описание на языке запроса
+type EarnedBadgeDTO struct {
+	ID          int       `json:"id"`
+	Slug        string    `json:"slug" example:"ten_cities"`
+	Name        string    `json:"name" example:"10 городов"`
+	Description string    `json:"description,omitempty"`
+	EarnedAt    time.Time `json:"earned_at"`
+}
+
+// MyBadgeDTO - значок из каталога с отметкой о получении
+type MyBadgeDTO struct {
+	ID          int        `json:"id"`
+	Slug        string     `json:"slug" example:"ten_cities"`
+	Name        string     `json:"name" example:"10 городов"`
+	Description string     `json:"description,omitempty"`
+	Earned      bool       `json:"earned"`
+	EarnedAt    *time.Time `json:"earned_at,omitempty"`
+}
+
+type BadgeResponseDTO struct {
+	ID           int               `json:"id"`
+	Slug         string            `json:"slug"`
+	Names        map[string]string `json:"names"`
+	Descriptions map[string]string `json:"descriptions"`
+	Rule         models.BadgeRule  `json:"rule"`
+	IsActive     bool              `json:"is_active"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    *time.Time        `json:"updated_at,omitempty"`
+}
+
+type CreateBadgeRequestDTO struct {
+	Slug         string            `json:"slug" validate:"required,max=50" example:"ten_cities"`
+	Names        map[string]string `json:"names" validate:"required,min=1,dive,keys,len=2,endkeys,required,max=100"`
+	Descriptions map[string]string `json:"descriptions,omitempty" validate:"omitempty,dive,keys,len=2,endkeys,max=500"`
+	// Условие: {"metric": "cities", "op": ">=", "value": 10} или {"all": [...]} / {"any": [...]}
+	Rule     *models.BadgeRule `json:"rule" validate:"required"`
+	IsActive *bool             `json:"is_active,omitempty"`
+}
+
+// UpdateBadgeRequestDTO - частичное обновление, slug не меняется
+type UpdateBadgeRequestDTO struct {
+	Names        *map[string]string `json:"names,omitempty" validate:"omitempty,min=1,dive,keys,len=2,endkeys,required,max=100"`
+	Descriptions *map[string]string `json:"descriptions,omitempty" validate:"omitempty,dive,keys,len=2,endkeys,max=500"`
+	Rule         *models.BadgeRule  `json:"rule,omitempty"`
+	IsActive     *bool              `json:"is_active,omitempty"`
+}
+
+type EvaluateBadgesResponse struct {
+	Users   int `json:"users"`   // проверено гостей
+	Awarded int `json:"awarded"` // выдано значков
+}
+
+type GetLeaderboardRequestDTO struct {
+	Period string // week, month, all
+	City   string
+	Page   int
+	Limit  int
+}
+
+type LeaderboardEntryDTO struct {
+	Position    int       `json:"position"`
+	UserID      uuid.UUID `json:"user_id"`
+	DisplayName string    `json:"display_name"` // имя из профиля или username
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+	HomeCity    string    `json:"home_city,omitempty"`
+	Points      int       `json:"points"` // очки за период
+}
+
+type LeaderboardResponse struct {
+	Period  string                 `json:"period"`
+	From    *string                `json:"from,omitempty"` // первый день периода(YYYY-MM-DD), нет для all
+	City    string                 `json:"city,omitempty"`
+	Entries []*LeaderboardEntryDTO `json:"entries"`
+	Total   int                    `json:"total"`
+	Page    int                    `json:"page"`
+	// Место текущего гостя; нет, если у него нет очков за период или он скрыт из таблицы
+	Me *LeaderboardEntryDTO `json:"me,omitempty"`
+}
+
+type GetMyNotificationsRequestDTO struct {
+	UserID     uuid.UUID
+	UnreadOnly bool
+	Page       int
+	Limit      int
+}
+
+type NotificationResponseDTO struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Body      string          `json:"body"`
+	Payload   json.RawMessage `json:"payload,omitempty" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+	ReadAt    *time.Time      `json:"read_at,omitempty"`
+}
+
+type NotificationsResponse struct {
+	Notifications []*NotificationResponseDTO `json:"notifications"`
+	Total         int                        `json:"total"`
+	Unread        int                        `json:"unread"`
+	Page          int                        `json:"page"`
+}
+
+type ListingPriorityDTO struct {
+	Score             float64    `json:"score" example:"72.5"`
+	LastInspectedAt   *time.Time `json:"last_inspected_at,omitempty"`  // последний одобренный отчет
+	LastQualityScore  *int       `json:"last_quality_score,omitempty"` // оценка последнего отчета, где она есть
+	Complaints        int        `json:"complaints"`                   // жалобы от OTA за 90 дней
+	ListingTypeWeight int        `json:"listing_type_weight" example:"100"`
+}
+
+type GetPrioritizedListingsRequestDTO struct {
+	City           string
+	ListingTypeIDs []int
+	MinScore       *float64
+	Page           int
+	Limit          int
+}
+
+type PrioritizedListingDTO struct {
+	Listing  ListingResponseDTO `json:"listing"`
+	Priority ListingPriorityDTO `json:"priority"`
+}
+
+type PrioritizedListingsResponse struct {
+	Listings []*PrioritizedListingDTO `json:"listings"`
+	Total    int                      `json:"total"`
+	Page     int                      `json:"page"`
+}
+
+type ListingTypePriorityWeightDTO struct {
+	ListingType ListingTypeResponse `json:"listing_type"`
+	Weight      int                 `json:"weight" example:"100"`
+}
+
+type UpdateListingTypePriorityWeightRequestDTO struct {
+	// Вес типа объекта в процентах: 100 - без изменений, 0 - объекты типа не проверяются по приоритету
+	Weight *int `json:"weight" validate:"required,gte=0,lte=1000" example:"150"`
+}
+
+type CreateListingComplaintRequestDTO struct {
+	ListingCode uuid.UUID `json:"listing_code" validate:"required"` // ID объекта в OTA(reservation.listing.id)
+	ExternalID  *string   `json:"external_id,omitempty" validate:"omitempty,max=100"`
+	Category    string    `json:"category" validate:"required,max=100" example:"cleanliness"`
+	Comment     *string   `json:"comment,omitempty" validate:"omitempty,max=2000"`
+	// Когда жалоба поступила в OTA, по умолчанию - время приема
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+}
+
+type ListingComplaintResponseDTO struct {
+	ID         uuid.UUID `json:"id"`
+	ListingID  uuid.UUID `json:"listing_id"`
+	ExternalID *string   `json:"external_id,omitempty"`
+	Category   string    `json:"category"`
+	Comment    *string   `json:"comment,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CampaignProgressDTO struct {
+	TargetListings      int  `json:"target_listings"`
+	Assignments         int  `json:"assignments"`
+	AcceptedAssignments int  `json:"accepted_assignments"`
+	ApprovedReports     int  `json:"approved_reports"`
+	InspectedListings   int  `json:"inspected_listings"`
+	Spent               int  `json:"spent"`
+	QuotaLeft           *int `json:"quota_left,omitempty"`
+	BudgetLeft          *int `json:"budget_left,omitempty"`
+}
+
+type CampaignResponseDTO struct {
+	ID               uuid.UUID           `json:"id"`
+	Name             string              `json:"name"`
+	Purpose          string              `json:"purpose"`
+	ListingTypeIDs   []int               `json:"listing_type_ids"`
+	City             *string             `json:"city,omitempty"`
+	NotInspectedDays *int                `json:"not_inspected_days,omitempty"`
+	StartsAt         time.Time           `json:"starts_at"`
+	EndsAt           time.Time           `json:"ends_at"`
+	Quota            *int                `json:"quota,omitempty"`
+	Budget           *int                `json:"budget,omitempty"`
+	Currency         *string             `json:"currency,omitempty"`
+	IsActive         bool                `json:"is_active"`
+	Progress         CampaignProgressDTO `json:"progress"`
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        *time.Time          `json:"updated_at,omitempty"`
+}
+
+type GetCampaignsRequestDTO struct {
+	ActiveOnly bool
+	Page       int
+	Limit      int
+}
+
+type CampaignsResponse struct {
+	Campaigns []*CampaignResponseDTO `json:"campaigns"`
+	Total     int                    `json:"total"`
+	Page      int                    `json:"page"`
+}
+
+type CreateCampaignRequestDTO struct {
+	Name    string `json:"name" validate:"required,max=200"`
+	Purpose string `json:"purpose" validate:"required,max=500"`
+	// Отбор объектов: пустой список типов - любые, city - любой, not_inspected_days - без одобренного отчета за N дней
+	ListingTypeIDs   []int   `json:"listing_type_ids,omitempty"`
+	City             *string `json:"city,omitempty" validate:"omitempty,max=100"`
+	NotInspectedDays *int    `json:"not_inspected_days,omitempty" validate:"omitempty,min=1"`
+
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+	EndsAt   time.Time `json:"ends_at" validate:"required"`
+	Quota    *int      `json:"quota,omitempty" validate:"omitempty,min=1"`
+	Budget   *int      `json:"budget,omitempty" validate:"omitempty,min=1"`
+	Currency *string   `json:"currency,omitempty" validate:"omitempty,len=3"`
+	IsActive *bool     `json:"is_active,omitempty"`
+}
+
+// UpdateCampaignRequestDTO - частичное обновление. 0 в quota, budget, not_inspected_days и пустой city снимают ограничение
+type UpdateCampaignRequestDTO struct {
+	Name             *string `json:"name,omitempty" validate:"omitempty,min=1,max=200"`
+	Purpose          *string `json:"purpose,omitempty" validate:"omitempty,min=1,max=500"`
+	ListingTypeIDs   *[]int  `json:"listing_type_ids,omitempty"`
+	City             *string `json:"city,omitempty" validate:"omitempty,max=100"`
+	NotInspectedDays *int    `json:"not_inspected_days,omitempty" validate:"omitempty,min=0"`
+
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	Quota    *int       `json:"quota,omitempty" validate:"omitempty,min=0"`
+	Budget   *int       `json:"budget,omitempty" validate:"omitempty,min=0"`
+	Currency *string    `json:"currency,omitempty" validate:"omitempty,len=3"`
+	IsActive *bool      `json:"is_active,omitempty"`
+}
+
+type CampaignListingDTO struct {
+	ListingID       uuid.UUID  `json:"listing_id"`
+	Title           string     `json:"title"`
+	City            string     `json:"city"`
+	ListingTypeID   int        `json:"listing_type_id"`
+	LastInspectedAt *time.Time `json:"last_inspected_at,omitempty"`
+	Assignments     int        `json:"assignments"`
+	Priority        float64    `json:"priority"`
+}
+
+type CampaignListingsResponse struct {
+	Listings []*CampaignListingDTO `json:"listings"`
+	Total    int                   `json:"total"`
+	Page     int                   `json:"page"`
+}
+
 // ================================
 
 type ProfileResponseDTO struct {
-	ID                    uuid.UUID       `json:"id"`
-	UserID                uuid.UUID       `json:"user_id"`
-	Username              string          `json:"username"`
-	Email                 string          `json:"email"`
-	AcceptedOffersCount   int             `json:"accepted_offers_count"`
-	SubmittedReportsCount int             `json:"submitted_reports_count"`
-	CorrectReportsCount   int             `json:"correct_reports_count"`
-	RegisteredAt          time.Time       `json:"registered_at"`
-	LastActiveAt          *time.Time      `json:"last_active_at,omitempty"`
-	AdditionalInfo        json.RawMessage `json:"additional_info,omitempty" swaggertype:"object"`
-	Points                int             `json:"points"`
-	Rank                  string          `json:"rank"`
+	ID                    uuid.UUID         `json:"id"`
+	UserID                uuid.UUID         `json:"user_id"`
+	Username              string            `json:"username"`
+	Email                 string            `json:"email"`
+	AcceptedOffersCount   int               `json:"accepted_offers_count"`
+	SubmittedReportsCount int               `json:"submitted_reports_count"`
+	CorrectReportsCount   int               `json:"correct_reports_count"`
+	RegisteredAt          time.Time         `json:"registered_at"`
+	LastActiveAt          *time.Time        `json:"last_active_at,omitempty"`
+	AdditionalInfo        ProfileInfoDTO    `json:"additional_info"`
+	Points                int               `json:"points"`
+	Rank                  string            `json:"rank"` // название ранга на языке запроса
+	RankSlug              string            `json:"rank_slug"`
+	Badges                []*EarnedBadgeDTO `json:"badges"`
+}
+
+type ProfileInfoDTO struct {
+	DisplayName             string                  `json:"display_name,omitempty"`
+	AvatarURL               string                  `json:"avatar_url,omitempty"`
+	HomeCity                string                  `json:"home_city,omitempty"`
+	PreferredListingTypeIDs []int                   `json:"preferred_listing_type_ids"`
+	Availability            []AvailabilityPeriodDTO `json:"availability"`
+	Languages               []string                `json:"languages"`
+	HideFromLeaderboard     bool                    `json:"hide_from_leaderboard"`
+}
+
+type AvailabilityPeriodDTO struct {
+	From string `json:"from" validate:"required,datetime=2006-01-02" example:"2025-11-01"`
+	To   string `json:"to" validate:"required,datetime=2006-01-02" example:"2025-11-15"`
+}
+
+// UpdateMyProfileRequestDTO - частичное обновление: не переданные поля не меняются,
+// пустая строка или пустой список очищают значение
+type UpdateMyProfileRequestDTO struct {
+	DisplayName *string `json:"display_name,omitempty" validate:"omitempty,max=50"`
+	// Путь файла, загруженного через /uploads/generate-url (users/{user_id}/...)
+	AvatarPath              *string                  `json:"avatar_path,omitempty" validate:"omitempty,max=255"`
+	HomeCity                *string                  `json:"home_city,omitempty" validate:"omitempty,max=100"`
+	PreferredListingTypeIDs *[]int                   `json:"preferred_listing_type_ids,omitempty" validate:"omitempty,max=10,dive,gt=0"`
+	Availability            *[]AvailabilityPeriodDTO `json:"availability,omitempty" validate:"omitempty,max=10,dive"`
+	// Коды языков ISO 639-1
+	Languages *[]string `json:"languages,omitempty" validate:"omitempty,max=10,dive,len=2,lowercase,alpha"`
+	// Не показывать гостя в таблице лидеров
+	HideFromLeaderboard *bool `json:"hide_from_leaderboard,omitempty"`
+}
+
+type DeleteMyAccountRequestDTO struct {
+	// Текущий пароль. Не требуется для учетных записей без пароля(вход через OIDC)
+	Password string `json:"password"`
+}
+
+// PersonalDataExport - ZIP-архив с персональными данными пользователя
+type PersonalDataExport struct {
+	FileName string
+	Content  []byte
+}
+
+type ExportedMediaFile struct {
+	ReportID  uuid.UUID `json:"report_id"`
+	ItemSlug  string    `json:"item_slug"`
+	URL       string    `json:"url"`
+	MediaType string    `json:"media_type"`
 }
 
 type GetAllProfilesRequestDTO struct {
 	Page  int
 	Limit int
+	Lang  string
 }
 
 type ProfilesResponse struct {
@@ -1,12 +1,14 @@
 package secret_guest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -120,6 +122,18 @@ func (h *SecretGuestHandler) parsePagination(r *http.Request) (page, limit int)
 	return page, limit
 }
 
+// requestLanguage - язык названий(ранги и т.п.): параметр lang, иначе первый язык из Accept-Language.
+// Пустая строка - язык по умолчанию.
+func (h *SecretGuestHandler) requestLanguage(r *http.Request) string {
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang, _, _ = strings.Cut(r.Header.Get("Accept-Language"), ",")
+	}
+	lang, _, _ = strings.Cut(strings.TrimSpace(lang), ";")
+	lang, _, _ = strings.Cut(lang, "-")
+	return strings.ToLower(lang)
+}
+
 func (h *SecretGuestHandler) parseFilterParams(r *http.Request) (*uuid.UUID, []int, []int) {
 	ctx := r.Context()
 	log := logger.GetLoggerFromCtx(ctx)
@@ -499,7 +513,7 @@ func (h *SecretGuestHandler) UpdateOTAReservationStatusNoShow(w http.ResponseWri
 
 // @Summary      Get Free Assignments
 // @Security     BearerAuth
-// @Description  Returns a paginated list of "free" assignments that can be taken by any user.
+// @Description  Returns a paginated list of "free" assignments that can be taken by any guest with an approved participation application. Assignments matching the travel preferences from the guest profile (availability dates, preferred listing types, home city) come first.
 // @Tags         Assignments (User)
 // @Produce      json
 // @Param        page query int false "Page number for pagination" default(1)
@@ -509,17 +523,24 @@ func (h *SecretGuestHandler) UpdateOTAReservationStatusNoShow(w http.ResponseWri
 // @Param Authorization header string true "Bearer Access Token"
 // @Success      200 {object} secret_guest.AssignmentsResponse
 // @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Participation application is not approved"
 // @Failure      500 {object} ErrorResponse "Internal server error"
 // @Router       /assignments [get]
 func (h *SecretGuestHandler) GetFreeAssignments(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLoggerFromCtx(ctx)
 
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
 	page, limit := h.parsePagination(r)
 	_, _, listingTypeIDs := h.parseFilterParams(r)
 
     city := h.parseCity(w, r)
 	dto := GetFreeAssignmentsRequestDTO{
+		UserID:         userID,
 		Page:           page,
 		Limit:          limit,
 		ListingTypeIDs: listingTypeIDs,
@@ -528,6 +549,10 @@ func (h *SecretGuestHandler) GetFreeAssignments(w http.ResponseWriter, r *http.R
 
 	assignments, err := h.service.GetFreeAssignments(ctx, dto)
 	if err != nil {
+		if errors.Is(err, models.ErrApplicationNotApproved) {
+			h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
+			return
+		}
 		log.Error(ctx, "Failed to get free assignments", zap.Error(err))
 		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
 		return
@@ -546,6 +571,7 @@ func (h *SecretGuestHandler) GetFreeAssignments(w http.ResponseWriter, r *http.R
 // @Success      200 {object} secret_guest.AssignmentResponseDTO
 // @Failure      400 {object} ErrorResponse "Invalid assignment ID format"
 // @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Participation application is not approved"
 // @Failure      404 {object} ErrorResponse "Assignment not found or is not available"
 // @Failure      500 {object} ErrorResponse "Internal server error"
 // @Router       /assignments/{id} [get]
@@ -553,16 +579,23 @@ func (h *SecretGuestHandler) GetFreeAssignmentsByID(w http.ResponseWriter, r *ht
 	ctx := r.Context()
 	log := logger.GetLoggerFromCtx(ctx)
 
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
 	assignmentID, ok := h.parseUUIDFromPath(w, r, "id")
 	if !ok {
 		return
 	}
 
-	assignment, err := h.service.GetFreeAssignmentsByID(ctx, assignmentID)
+	assignment, err := h.service.GetFreeAssignmentsByID(ctx, userID, assignmentID)
 	if err != nil {
 		if errors.Is(err, models.ErrAssignmentNotFound) {
 			log.Info(ctx, "Assignment not found by ID", zap.String("assignment_id", assignmentID.String()))
 			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Assignment not found")
+		} else if errors.Is(err, models.ErrApplicationNotApproved) {
+			h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
 		} else {
 			log.Error(ctx, "Failed to get assignment by ID as secret guest", zap.Error(err))
 			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
@@ -789,12 +822,14 @@ func (h *SecretGuestHandler) AcceptMyAssignment(w http.ResponseWriter, r *http.R
 
 // @Summary      Decline My Assignment
 // @Security     BearerAuth
-// @Description  Decline an assignment offer.
+// @Description  Decline an assignment offer. The body is optional: the reason is one of dates, location, listing_type or other, a comment is required for other. Declines without a reason are counted as unspecified in the staff analytics.
 // @Tags         Assignments (User)
+// @Accept       json
 // @Param        id path string true "Assignment ID" format(uuid)
+// @Param        body body secret_guest.DeclineAssignmentRequestDTO false "Decline reason"
 // @Param Authorization header string true "Bearer Access Token"
 // @Success      204 "No Content"
-// @Failure      400 {object} ErrorResponse "Invalid assignment ID format"
+// @Failure      400 {object} ErrorResponse "Invalid assignment ID format or decline reason"
 // @Failure      401 {object} ErrorResponse "Unauthorized"
 // @Failure      404 {object} ErrorResponse "Assignment not found or does not belong to user"
 // @Failure      409 {object} ErrorResponse "Assignment cannot be declined (e.g., wrong status)"
@@ -814,9 +849,26 @@ func (h *SecretGuestHandler) DeclineMyAssignment(w http.ResponseWriter, r *http.
 		return
 	}
 
-	err := h.service.DeclineMyAssignment(ctx, userID, assignmentID)
+	// Тело запроса необязательно
+	var dto DeclineAssignmentRequestDTO
+	if r.ContentLength != 0 {
+		if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for assignment decline", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	err := h.service.DeclineMyAssignment(ctx, userID, assignmentID, dto)
 	if err != nil {
 		switch {
+		case errors.Is(err, models.ErrValidationFailed):
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
 		case errors.Is(err, models.ErrAssignmentNotFound), errors.Is(err, models.ErrForbidden):
 			log.Info(ctx, "Assignment not found by ID", zap.String("assignment_id", assignmentID.String()))
 			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Assignment not found or access denied")
@@ -834,17 +886,111 @@ func (h *SecretGuestHandler) DeclineMyAssignment(w http.ResponseWriter, r *http.
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// @Summary      Check In to My Assignment
+// @Security     BearerAuth
+// @Description  Records the guest's arrival at the listing for an accepted assignment. The device coordinates must be within STAY_CHECK_RADIUS_METERS of the listing (not checked if the listing has no coordinates), the device timestamp must be close to the server time and fall between check-in date minus STAY_CHECK_TOLERANCE_HOURS and the check-out date. Check-in can be recorded only once.
+// @Tags         Assignments (User)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Assignment ID" format(uuid)
+// @Param        body body secret_guest.StayCheckRequestDTO true "Device coordinates and time"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.AssignmentStayDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body or time outside the allowed window"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      404 {object} ErrorResponse "Assignment not found or does not belong to user"
+// @Failure      409 {object} ErrorResponse "Assignment is not accepted or check-in is already recorded"
+// @Failure      422 {object} ErrorResponse "Device is too far from the listing"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /assignments/my/{id}/checkin [post]
+func (h *SecretGuestHandler) CheckInMyAssignment(w http.ResponseWriter, r *http.Request) {
+	h.handleStayCheck(w, r, "check-in", h.service.CheckInMyAssignment)
+}
+
+// @Summary      Check Out of My Assignment
+// @Security     BearerAuth
+// @Description  Records the guest's departure after the check-in. The same location and clock checks apply, the device timestamp must fall between the check-in date and the check-out date plus STAY_CHECK_TOLERANCE_HOURS and not be earlier than the check-in. Check-out can be recorded only once.
+// @Tags         Assignments (User)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Assignment ID" format(uuid)
+// @Param        body body secret_guest.StayCheckRequestDTO true "Device coordinates and time"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.AssignmentStayDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body or time outside the allowed window"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      404 {object} ErrorResponse "Assignment not found or does not belong to user"
+// @Failure      409 {object} ErrorResponse "Assignment is not accepted, no check-in or check-out is already recorded"
+// @Failure      422 {object} ErrorResponse "Device is too far from the listing"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /assignments/my/{id}/checkout [post]
+func (h *SecretGuestHandler) CheckOutMyAssignment(w http.ResponseWriter, r *http.Request) {
+	h.handleStayCheck(w, r, "check-out", h.service.CheckOutMyAssignment)
+}
+
+// handleStayCheck - общая часть отметок заезда и выезда
+func (h *SecretGuestHandler) handleStayCheck(w http.ResponseWriter, r *http.Request, kind string,
+	check func(ctx context.Context, userID, assignmentID uuid.UUID, dto StayCheckRequestDTO) (*AssignmentStayDTO, error)) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	assignmentID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto StayCheckRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for assignment "+kind, zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	stay, err := check(ctx, userID, assignmentID, dto)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrValidationFailed), errors.Is(err, models.ErrStayCheckOutOfWindow):
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrStayCheckTooFar):
+			log.Info(ctx, "Assignment "+kind+" is too far from the listing", zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, models.ErrAssignmentNotFound), errors.Is(err, models.ErrForbidden):
+			log.Info(ctx, "Assignment not found by ID", zap.String("assignment_id", assignmentID.String()))
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Assignment not found or access denied")
+		case errors.Is(err, models.ErrStayCheckNotAllowed):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, "Assignment "+kind+" is not allowed")
+		default:
+			log.Error(ctx, "Failed to record assignment "+kind, zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, stay)
+}
+
 // @Summary      Take a Free Assignment
 // @Security     BearerAuth
-// @Description  Allows a user to take a free assignment, assigning it to themselves. The assignment status becomes 'offered' to this specific user.
+// @Description  Allows a user to take a free assignment, assigning it to themselves. The assignment status becomes 'offered' to this specific user. The assignment is held for ASSIGNMENT_HOLD_MINUTES from the moment it can be accepted (ASSIGNMENT_DEADLINE_HOURS before check-in), but not later than expires_at: accept_deadline and hold_seconds_left are shown in the assignment. After the deadline the assignment returns to the free pool and the user gets an assignment.hold_expired notification.
 // @Tags         Assignments (User)
 // @Param        id path string true "Assignment ID" format(uuid)
 // @Param Authorization header string true "Bearer Access Token"
 // @Success      204 "No Content"
 // @Failure      400 {object} ErrorResponse "Invalid assignment ID format"
 // @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Participation application is not approved"
 // @Failure      404 {object} ErrorResponse "Assignment not found or not available"
-// @Failure      409 {object} ErrorResponse "Assignment cannot be taken (e.g., already taken, or user has other active offers)"
+// @Failure      409 {object} ErrorResponse "Assignment cannot be taken (e.g., already taken, user has other active offers or an assignment with overlapping dates)"
 // @Failure      500 {object} ErrorResponse "Internal server error"
 // @Router       /assignments/{id}/take [patch]
 func (h *SecretGuestHandler) TakeFreeAssignmentsByID(w http.ResponseWriter, r *http.Request) {
@@ -863,15 +1009,22 @@ func (h *SecretGuestHandler) TakeFreeAssignmentsByID(w http.ResponseWriter, r *h
 
 	err := h.service.TakeFreeAssignmentsByID(ctx, userID, assignmentID)
 	if err != nil {
+		var overlapErr *models.AssignmentOverlapError
 		switch {
+		case errors.Is(err, models.ErrApplicationNotApproved):
+			log.Info(ctx, "Assignment take attempt without approved application", zap.String("user_id", userID.String()))
+			h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
 		case errors.Is(err, models.ErrAssignmentNotFound), errors.Is(err, models.ErrForbidden):
 			log.Info(ctx, "Assignment not found by ID", zap.String("assignment_id", assignmentID.String()))
 			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Assignment not found or access denied")
-		case errors.Is(err, models.ErrAssignmentCannotBeDeclined):
+		case errors.Is(err, models.ErrAssignmentCannotBeDeclined), errors.Is(err, models.ErrAssignmentCannotBeTaken):
 			log.Info(ctx, "Assignment can not be taked", zap.Error(err))
 			h.writeErrorResponse(ctx, w, http.StatusConflict, "Assignment can not be taked")
 
-		case strings.Contains(err.Error(), "already has"):
+		case errors.As(err, &overlapErr):
+			log.Info(ctx, "Assignment dates overlap", zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusConflict, overlapErr.Error())
+		case errors.Is(err, models.ErrGuestHasActiveAssignment):
 			log.Info(ctx, "User already has active assignments", zap.Error(err))
 			h.writeErrorResponse(ctx, w, http.StatusConflict, "User already has active assignments")
 
@@ -886,9 +1039,122 @@ func (h *SecretGuestHandler) TakeFreeAssignmentsByID(w http.ResponseWriter, r *h
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// @Summary      Create Assignment (Staff)
+// @Security     BearerAuth
+// @Description  Creates an assignment for a listing without an OTA reservation. expires_at defaults to the check-in date. If reporter_id is set, the assignment is bound to this approved guest as an invitation: the guest is notified and must accept it before accept_deadline (defaults to expires_at), otherwise it can no longer be accepted. A guest can have only one active assignment. Manual assignments have no booking price, so no reward is accrued. If campaign_id is set, the assignment is counted in this campaign; it must be active, cover the check-in date and be within quota and budget, and purpose defaults to the campaign purpose. The action is recorded in the audit log.
+// @Tags         Assignments (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.CreateAssignmentRequestDTO true "Assignment"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.AssignmentResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body or dates"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Listing or campaign not found"
+// @Failure      409 {object} ErrorResponse "Guest is not approved, already has an active assignment or an assignment with overlapping dates, or the campaign is not available"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/assignments [post]
+func (h *SecretGuestHandler) CreateAssignment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto CreateAssignmentRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for assignment", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	assignment, err := h.service.CreateAssignment(ctx, actorID, dto)
+	if err != nil {
+		var overlapErr *models.AssignmentOverlapError
+		switch {
+		case errors.Is(err, models.ErrInvalidAssignmentDates):
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrListingNotFound), errors.Is(err, models.ErrNotFound):
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Listing not found")
+		case errors.Is(err, models.ErrApplicationNotApproved):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, "Guest application is not approved")
+		case errors.Is(err, models.ErrGuestHasActiveAssignment):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+		case errors.As(err, &overlapErr):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, overlapErr.Error())
+		case errors.Is(err, models.ErrCampaignNotFound):
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Campaign not found")
+		case errors.Is(err, models.ErrCampaignNotAvailable):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+		case errors.Is(err, models.ErrForeignKeyViolation):
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Listing or guest does not exist")
+		default:
+			log.Error(ctx, "Failed to create assignment", zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, assignment)
+}
+
+// @Summary      Release Assignment (Staff)
+// @Security     BearerAuth
+// @Description  Unbinds the guest from an offered assignment held by them (taken or invited), for example to reassign it. The guest gets an assignment.released notification. The assignment is offered to the first eligible guest from its waitlist with a new hold window, otherwise it returns to the free pool. The action is recorded in the audit log.
+// @Tags         Assignments (Staff)
+// @Produce      json
+// @Param        id path string true "Assignment ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.AssignmentResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid assignment ID format"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Assignment not found"
+// @Failure      409 {object} ErrorResponse "Assignment is not held by a guest"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/assignments/{id}/release [patch]
+func (h *SecretGuestHandler) ReleaseAssignment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	assignmentID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	assignment, err := h.service.ReleaseAssignment(ctx, actorID, assignmentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrAssignmentNotFound):
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Assignment not found")
+		case errors.Is(err, models.ErrAssignmentNotHeld):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+		default:
+			log.Error(ctx, "Failed to release assignment", zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, assignment)
+}
+
 // @Summary      Cancel Assignment
 // @Security     BearerAuth
-// @Description  Cancel an assignment offer.
+// @Description  Cancel an offered or accepted assignment. The waitlist of the assignment is cleared, the guest holding it gets an assignment.cancelled notification. The action is recorded in the audit log.
 // @Tags         Assignments (Staff)
 // @Param        id path string true "Assignment ID" format(uuid)
 // @Param Authorization header string true "Bearer Access Token"
@@ -903,17 +1169,24 @@ func (h *SecretGuestHandler) CancelAssignment(w http.ResponseWriter, r *http.Req
 	ctx := r.Context()
 	log := logger.GetLoggerFromCtx(ctx)
 
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
 	assignmentID, ok := h.parseUUIDFromPath(w, r, "id")
 	if !ok {
 		return
 	}
 
-	err := h.service.CancelAssignment(ctx, assignmentID)
+	err := h.service.CancelAssignment(ctx, actorID, assignmentID)
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrAssignmentNotFound), errors.Is(err, models.ErrForbidden):
 			log.Info(ctx, "Assignment not found by ID", zap.String("assignment_id", assignmentID.String()))
 			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Assignment not found or access denied")
+		case errors.Is(err, models.ErrAssignmentCannotBeCancelled):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, "Assignment cannot be cancelled")
 		default:
 			log.Error(ctx, "Failed to cancel assignment", zap.Error(err))
 			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
@@ -925,6 +1198,135 @@ func (h *SecretGuestHandler) CancelAssignment(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// @Summary      Reassign Assignment (Staff)
+// @Security     BearerAuth
+// @Description  Binds an open offered assignment (free or held by another guest) to the given guest as an invitation with an accept deadline (by default expires_at, the same rules as for manual invitations). The previous guest gets an assignment.released notification, the new one gets assignment.invited. The guest application must be approved, the guest must have no other offered assignment and no overlapping stays. The action is recorded in the audit log.
+// @Tags         Assignments (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Assignment ID" format(uuid)
+// @Param        body body secret_guest.ReassignAssignmentRequestDTO true "New guest"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.AssignmentResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body or accept deadline"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Assignment not found"
+// @Failure      409 {object} ErrorResponse "Assignment cannot be reassigned, guest is not approved, has an active assignment or overlapping stay"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/assignments/{id}/reassign [patch]
+func (h *SecretGuestHandler) ReassignAssignment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	assignmentID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto ReassignAssignmentRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for assignment reassignment", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	assignment, err := h.service.ReassignAssignment(ctx, actorID, assignmentID, dto)
+	if err != nil {
+		var overlapErr *models.AssignmentOverlapError
+		switch {
+		case errors.Is(err, models.ErrInvalidAssignmentDates):
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrAssignmentNotFound):
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Assignment not found")
+		case errors.Is(err, models.ErrApplicationNotApproved):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, "Guest application is not approved")
+		case errors.Is(err, models.ErrAssignmentCannotBeReassigned), errors.Is(err, models.ErrGuestHasActiveAssignment):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+		case errors.As(err, &overlapErr):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, overlapErr.Error())
+		default:
+			log.Error(ctx, "Failed to reassign assignment", zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, assignment)
+}
+
+// @Summary      Extend Assignment (Staff)
+// @Security     BearerAuth
+// @Description  Moves expires_at of an offered assignment, including an already expired one. The new date must be in the future, later than the current one and before the check-out date. The accept deadline of a held assignment is recomputed from the new date: a staff invite lasts until expires_at, a guest hold gets ASSIGNMENT_HOLD_MINUTES from the start of the accept window. The action is recorded in the audit log.
+// @Tags         Assignments (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Assignment ID" format(uuid)
+// @Param        body body secret_guest.ExtendAssignmentRequestDTO true "New expiration date"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.AssignmentResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body or expiration date"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Assignment not found"
+// @Failure      409 {object} ErrorResponse "Assignment is not offered"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/assignments/{id}/extend [patch]
+func (h *SecretGuestHandler) ExtendAssignment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	assignmentID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto ExtendAssignmentRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for assignment extension", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	assignment, err := h.service.ExtendAssignment(ctx, actorID, assignmentID, dto)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidAssignmentDates):
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrAssignmentNotFound):
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Assignment not found")
+		case errors.Is(err, models.ErrAssignmentCannotBeExtended):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+		default:
+			log.Error(ctx, "Failed to extend assignment", zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, assignment)
+}
+
 // reports
 
 // @Summary      Get My Reports
@@ -1078,7 +1480,7 @@ func (h *SecretGuestHandler) UpdateMyReport(w http.ResponseWriter, r *http.Reque
 // @Failure      400 {object} ErrorResponse "Invalid report ID or report is not complete"
 // @Failure      401 {object} ErrorResponse "Unauthorized"
 // @Failure      404 {object} ErrorResponse "Report not found or does not belong to user"
-// @Failure      409 {object} ErrorResponse "Report is not in a draft state"
+// @Failure      409 {object} ErrorResponse "Report is not in a draft state or is past its due date"
 // @Failure      500 {object} ErrorResponse "Internal server error"
 // @Router       /reports/my/{id}/submit [patch]
 func (h *SecretGuestHandler) SubmitMyReport(w http.ResponseWriter, r *http.Request) {
@@ -1103,6 +1505,9 @@ func (h *SecretGuestHandler) SubmitMyReport(w http.ResponseWriter, r *http.Reque
 		} else if errors.Is(err, models.ErrReportNotEditable) {
 			log.Info(ctx, "Report is not in a draft state and cannot be submitted", zap.String("report_id", reportID.String()))
 			h.writeErrorResponse(ctx, w, http.StatusConflict, "Report is not in a draft state and cannot be submitted")
+		} else if errors.Is(err, models.ErrReportOverdue) {
+			log.Info(ctx, "Report is past its due date and cannot be submitted", zap.String("report_id", reportID.String()))
+			h.writeErrorResponse(ctx, w, http.StatusConflict, "Report is past its due date and cannot be submitted")
 		} else {
 			log.Error(ctx, "Failed to submit my report", zap.Error(err))
 			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
@@ -1239,12 +1644,14 @@ func (h *SecretGuestHandler) GetReportByID_AsStaff(w http.ResponseWriter, r *htt
 
 // @Summary      Approve a Report (Staff)
 // @Security     BearerAuth
-// @Description  Approves a submitted secret guest report. Available for staff only.
+// @Description  Approves a submitted secret guest report. Available for staff only. The optional quality_score (0-100) rates the listing and is used in its inspection priority.
 // @Tags         Reports (Staff)
+// @Accept       json
 // @Param        id path string true "Report ID" format(uuid)
+// @Param        input body secret_guest.ApproveReportRequestDTO false "Listing quality score"
 // @Param Authorization header string true "Bearer Access Token"
 // @Success      204 "No Content"
-// @Failure      400 {object} ErrorResponse "Invalid report ID format"
+// @Failure      400 {object} ErrorResponse "Invalid report ID format or request body"
 // @Failure      401 {object} ErrorResponse "Unauthorized"
 // @Failure      403 {object} ErrorResponse "Forbidden"
 // @Failure      404 {object} ErrorResponse "Report not found"
@@ -1265,7 +1672,22 @@ func (h *SecretGuestHandler) ApproveReport(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	err := h.service.ApproveReport(ctx, staffID, reportID)
+	// Тело запроса необязательно
+	var dto ApproveReportRequestDTO
+	if r.ContentLength != 0 {
+		if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for report approval", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	err := h.service.ApproveReport(ctx, staffID, reportID, dto.QualityScore)
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrReportNotFound):
@@ -2407,25 +2829,65 @@ func (h *SecretGuestHandler) GenerateUploadURL(w http.ResponseWriter, r *http.Re
 
 // @Summary      Get All Users (Staff)
 // @Security     BearerAuth
-// @Description  Returns a paginated list of all users. Available for staff only.
+// @Description  Returns a paginated list of users with optional search by username/email, role and block state. Available for staff only.
 // @Tags         Users (Staff)
 // @Produce      json
 // @Param        page query int false "Page number for pagination" default(1)
 // @Param        limit query int false "Number of items per page" default(50)
+// @Param        search query string false "Substring of username or email"
+// @Param        role_id query []int false "Filter by role IDs" collectionFormat(multi)
+// @Param        blocked query bool false "Filter by block state"
+// @Param        service_account query bool false "Filter service accounts (true) or regular users (false)"
 // @Param Authorization header string true "Bearer Access Token"
 // @Success      200 {object} secret_guest.UsersResponse
 // @Failure      401 {object} ErrorResponse "Unauthorized"
 // @Failure      403 {object} ErrorResponse "Forbidden"
 // @Failure      500 {object} ErrorResponse "Internal server error"
-// @Router       /users [get]
+// @Router       /staff/users [get]
 func (h *SecretGuestHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLoggerFromCtx(ctx)
 
 	page, limit := h.parsePagination(r)
+	queryParams := r.URL.Query()
+
+	var roleIDs []int
+	for _, idStr := range queryParams["role_id"] {
+		roleID, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Warn(ctx, "Invalid role_id value in query parameter, value ignored", zap.String("role_id", idStr))
+			continue
+		}
+		roleIDs = append(roleIDs, roleID)
+	}
+
+	var blocked *bool
+	if blockedStr := queryParams.Get("blocked"); blockedStr != "" {
+		parsed, err := strconv.ParseBool(blockedStr)
+		if err != nil {
+			log.Warn(ctx, "Invalid blocked value in query parameter, filter ignored", zap.String("blocked", blockedStr))
+		} else {
+			blocked = &parsed
+		}
+	}
+
+	var serviceAccount *bool
+	if serviceAccountStr := queryParams.Get("service_account"); serviceAccountStr != "" {
+		parsed, err := strconv.ParseBool(serviceAccountStr)
+		if err != nil {
+			log.Warn(ctx, "Invalid service_account value in query parameter, filter ignored", zap.String("service_account", serviceAccountStr))
+		} else {
+			serviceAccount = &parsed
+		}
+	}
+
 	dto := GetAllUsersRequestDTO{
-		Page:  page,
-		Limit: limit,
+		Page:           page,
+		Limit:          limit,
+		Search:         queryParams.Get("search"),
+		RoleIDs:        roleIDs,
+		Blocked:        blocked,
+		ServiceAccount: serviceAccount,
 	}
 
 	users, err := h.service.GetAllUsers(ctx, dto)
@@ -2438,40 +2900,1629 @@ func (h *SecretGuestHandler) GetAllUsers(w http.ResponseWriter, r *http.Request)
 	h.writeJSONResponse(ctx, w, http.StatusOK, users)
 }
 
-// profiles
-
-// @Summary      Get My Profile
+// @Summary      Get User by ID (Staff)
 // @Security     BearerAuth
-// @Description  Returns the profile information for the currently authenticated user.
-// @Tags         Profiles (User)
+// @Description  Returns a single user with role and block state. Available for staff only.
+// @Tags         Users (Staff)
 // @Produce      json
-// @Param        Authorization header string true "Bearer Access Token"
-// @Success      200 {object} secret_guest.ProfileResponseDTO
+// @Param        id path string true "User ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.UserResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid user ID format"
 // @Failure      401 {object} ErrorResponse "Unauthorized"
-// @Failure      404 {object} ErrorResponse "Profile not found"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "User not found"
 // @Failure      500 {object} ErrorResponse "Internal server error"
-// @Router       /profiles/my [get]
-func (h *SecretGuestHandler) GetMyProfile(w http.ResponseWriter, r *http.Request) {
+// @Router       /staff/users/{id} [get]
+func (h *SecretGuestHandler) GetUserByID_AsStaff(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := logger.GetLoggerFromCtx(ctx)
 
-	userID, ok := h.parseUserAndID(w, r)
+	userID, ok := h.parseUUIDFromPath(w, r, "id")
 	if !ok {
 		return
 	}
 
-	profile, err := h.service.GetMyProfile(ctx, userID)
+	user, err := h.service.GetUserByID_AsStaff(ctx, userID)
 	if err != nil {
-		if errors.Is(err, models.ErrNotFound) {
-			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Profile not found")
+		if errors.Is(err, models.ErrUserNotFound) {
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "User not found")
 		} else {
-			log.Error(ctx, "Failed to get my profile", zap.Error(err), zap.String("user_id", userID.String()))
+			log.Error(ctx, "Failed to get user by ID", zap.Error(err), zap.String("user_id", userID.String()))
 			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
 		}
 		return
 	}
 
-	h.writeJSONResponse(ctx, w, http.StatusOK, profile)
+	h.writeJSONResponse(ctx, w, http.StatusOK, user)
+}
+
+// @Summary      Change User Role (Admin)
+// @Security     BearerAuth
+// @Description  Changes the role of a user (e.g. promotes a guest to moderator). The action is recorded in the audit log.
+// @Tags         Users (Admin)
+// @Accept       json
+// @Param        id path string true "User ID" format(uuid)
+// @Param        input body secret_guest.ChangeUserRoleRequestDTO true "New role"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid payload, user ID or role"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "User not found"
+// @Failure      409 {object} ErrorResponse "Cannot change own role"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/users/{id}/role [patch]
+func (h *SecretGuestHandler) ChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto ChangeUserRoleRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for change user role", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	err := h.service.ChangeUserRole(ctx, actorID, userID, dto)
+	if err != nil {
+		h.handleUserAdminError(ctx, w, err, userID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Block User (Admin)
+// @Security     BearerAuth
+// @Description  Blocks a user with a reason. Blocked users cannot log in and their existing tokens are rejected. The action is recorded in the audit log.
+// @Tags         Users (Admin)
+// @Accept       json
+// @Param        id path string true "User ID" format(uuid)
+// @Param        input body secret_guest.BlockUserRequestDTO true "Block reason"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid payload or user ID"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "User not found"
+// @Failure      409 {object} ErrorResponse "Cannot block own account"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/users/{id}/block [patch]
+func (h *SecretGuestHandler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto BlockUserRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for block user", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	err := h.service.BlockUser(ctx, actorID, userID, dto)
+	if err != nil {
+		h.handleUserAdminError(ctx, w, err, userID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Unblock User (Admin)
+// @Security     BearerAuth
+// @Description  Removes the block from a user. The action is recorded in the audit log.
+// @Tags         Users (Admin)
+// @Param        id path string true "User ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid user ID"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "User not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/users/{id}/unblock [patch]
+func (h *SecretGuestHandler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.service.UnblockUser(ctx, actorID, userID)
+	if err != nil {
+		h.handleUserAdminError(ctx, w, err, userID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Force Password Reset (Admin)
+// @Security     BearerAuth
+// @Description  Sets a random temporary password and returns it once. Until the user changes it via POST /auth/password, all other endpoints reject the user's tokens. The action is recorded in the audit log.
+// @Tags         Users (Admin)
+// @Produce      json
+// @Param        id path string true "User ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.ResetUserPasswordResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid user ID"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "User not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/users/{id}/reset-password [post]
+func (h *SecretGuestHandler) ResetUserPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.ResetUserPassword(ctx, actorID, userID)
+	if err != nil {
+		h.handleUserAdminError(ctx, w, err, userID)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, resp)
+}
+
+func (h *SecretGuestHandler) handleUserAdminError(ctx context.Context, w http.ResponseWriter, err error, userID uuid.UUID) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		log.Info(ctx, "User not found", zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, "User not found")
+	case errors.Is(err, models.ErrRoleNotFound):
+		log.Info(ctx, "Role not found", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Role not found")
+	case errors.Is(err, models.ErrAPIKeyNotFound):
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, "API key not found")
+	case errors.Is(err, models.ErrValidationFailed):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+	case errors.Is(err, models.ErrInvalidUsername), errors.Is(err, models.ErrInvalidAPIKeyScope):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrUserExists):
+		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrCannotModifySelf):
+		log.Info(ctx, "Attempt to apply admin action to own account", zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrServiceAccount), errors.Is(err, models.ErrNotServiceAccount), errors.Is(err, models.ErrUserDeleted):
+		log.Info(ctx, "Admin action is not applicable to this account type", zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+	default:
+		log.Error(ctx, "Failed to apply admin action to user", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// audit_log
+
+// @Summary      Get Audit Log (Admin)
+// @Security     BearerAuth
+// @Description  Returns staff actions recorded in the audit log, newest first.
+// @Tags         Audit (Admin)
+// @Produce      json
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param        actor_id query string false "Filter by acting user ID" format(uuid)
+// @Param        entity_type query string false "Filter by entity type (e.g. user)"
+// @Param        entity_id query string false "Filter by entity ID"
+// @Param        action query []string false "Filter by actions (e.g. user.blocked)" collectionFormat(multi)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.AuditLogResponse
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/audit_log [get]
+func (h *SecretGuestHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	page, limit := h.parsePagination(r)
+	queryParams := r.URL.Query()
+
+	var actorID *uuid.UUID
+	if actorIDStr := queryParams.Get("actor_id"); actorIDStr != "" {
+		parsed, err := uuid.Parse(actorIDStr)
+		if err != nil {
+			log.Warn(ctx, "Invalid actor_id format in query param, filter ignored", zap.String("actor_id", actorIDStr))
+		} else {
+			actorID = &parsed
+		}
+	}
+
+	dto := GetAuditLogRequestDTO{
+		Page:       page,
+		Limit:      limit,
+		ActorID:    actorID,
+		EntityType: queryParams.Get("entity_type"),
+		EntityID:   queryParams.Get("entity_id"),
+		Actions:    queryParams["action"],
+	}
+
+	resp, err := h.service.GetAuditLog(ctx, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to get audit log", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, resp)
+}
+
+// roles
+
+// @Summary      Get Permissions (Admin)
+// @Security     BearerAuth
+// @Description  Returns all permissions that can be assigned to roles.
+// @Tags         Roles (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.PermissionResponseDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/permissions [get]
+func (h *SecretGuestHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	permissions, err := h.service.GetPermissions(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to get permissions", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, permissions)
+}
+
+// @Summary      Get Roles (Admin)
+// @Security     BearerAuth
+// @Description  Returns all roles with their permissions.
+// @Tags         Roles (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.RoleResponseDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/roles [get]
+func (h *SecretGuestHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	roles, err := h.service.GetRoles(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to get roles", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, roles)
+}
+
+// @Summary      Create Role (Admin)
+// @Security     BearerAuth
+// @Description  Creates a new role with the given set of permissions. Role name must be lowercase latin letters, digits and underscores.
+// @Tags         Roles (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.CreateRoleRequestDTO true "Role data"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.RoleResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid payload or unknown permission"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      409 {object} ErrorResponse "Role with this name already exists"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/roles [post]
+func (h *SecretGuestHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto CreateRoleRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for create role", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	role, err := h.service.CreateRole(ctx, actorID, dto)
+	if err != nil {
+		h.handleRoleAdminError(ctx, w, err, 0)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, role)
+}
+
+// @Summary      Update Role (Admin)
+// @Security     BearerAuth
+// @Description  Updates role name and/or description. Built-in roles cannot be renamed.
+// @Tags         Roles (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Role ID"
+// @Param        input body secret_guest.UpdateRoleRequestDTO true "Fields to update"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.RoleResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid payload or role ID"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden or built-in role"
+// @Failure      404 {object} ErrorResponse "Role not found"
+// @Failure      409 {object} ErrorResponse "Role with this name already exists"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/roles/{id} [patch]
+func (h *SecretGuestHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	roleID, ok := h.parseIntFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto UpdateRoleRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for update role", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	role, err := h.service.UpdateRole(ctx, actorID, roleID, dto)
+	if err != nil {
+		h.handleRoleAdminError(ctx, w, err, roleID)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, role)
+}
+
+// @Summary      Set Role Permissions (Admin)
+// @Security     BearerAuth
+// @Description  Replaces the full set of role permissions. Changes apply to the next request of every user with this role. Permissions of the admin role cannot be changed.
+// @Tags         Roles (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Role ID"
+// @Param        input body secret_guest.SetRolePermissionsRequestDTO true "Permission slugs"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.RoleResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid payload, role ID or unknown permission"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden or admin role"
+// @Failure      404 {object} ErrorResponse "Role not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/roles/{id}/permissions [put]
+func (h *SecretGuestHandler) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	roleID, ok := h.parseIntFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto SetRolePermissionsRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for set role permissions", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	role, err := h.service.SetRolePermissions(ctx, actorID, roleID, dto)
+	if err != nil {
+		h.handleRoleAdminError(ctx, w, err, roleID)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, role)
+}
+
+// @Summary      Delete Role (Admin)
+// @Security     BearerAuth
+// @Description  Deletes a custom role. Built-in roles and roles assigned to users cannot be deleted.
+// @Tags         Roles (Admin)
+// @Param        id path int true "Role ID"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid role ID"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden or built-in role"
+// @Failure      404 {object} ErrorResponse "Role not found"
+// @Failure      409 {object} ErrorResponse "Role is assigned to users"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/roles/{id} [delete]
+func (h *SecretGuestHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	roleID, ok := h.parseIntFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRole(ctx, actorID, roleID); err != nil {
+		h.handleRoleAdminError(ctx, w, err, roleID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SecretGuestHandler) handleRoleAdminError(ctx context.Context, w http.ResponseWriter, err error, roleID int) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	switch {
+	case errors.Is(err, models.ErrRoleNotFound):
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, "Role not found")
+	case errors.Is(err, models.ErrInvalidRoleName), errors.Is(err, models.ErrPermissionNotFound):
+		log.Info(ctx, "Invalid role payload", zap.Error(err), zap.Int("role_id", roleID))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrRoleProtected):
+		log.Info(ctx, "Attempt to modify protected role", zap.Int("role_id", roleID))
+		h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrDuplicate):
+		h.writeErrorResponse(ctx, w, http.StatusConflict, "Role with this name already exists")
+	case errors.Is(err, models.ErrInUse):
+		h.writeErrorResponse(ctx, w, http.StatusConflict, "Role is assigned to users and cannot be deleted")
+	default:
+		log.Error(ctx, "Failed to apply admin action to role", zap.Error(err), zap.Int("role_id", roleID))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// service_accounts
+
+// @Summary      Create Service Account (Admin)
+// @Security     BearerAuth
+// @Description  Creates a service account for an integration (OTA feed, BI export). Service accounts cannot log in with a password and authenticate with API keys only. Use GET /staff/users?service_account=true to list them.
+// @Tags         Service Accounts (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.CreateServiceAccountRequestDTO true "Service account data"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.UserResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid payload or role"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      409 {object} ErrorResponse "Username already exists"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/service_accounts [post]
+func (h *SecretGuestHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto CreateServiceAccountRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for create service account", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	user, err := h.service.CreateServiceAccount(ctx, actorID, dto)
+	if err != nil {
+		h.handleUserAdminError(ctx, w, err, uuid.Nil)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, user)
+}
+
+// @Summary      Get Service Account API Keys (Admin)
+// @Security     BearerAuth
+// @Description  Returns API keys of a service account (without the secret part).
+// @Tags         Service Accounts (Admin)
+// @Produce      json
+// @Param        id path string true "Service account user ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.APIKeyResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid user ID"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "User not found"
+// @Failure      409 {object} ErrorResponse "User is not a service account"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/service_accounts/{id}/api_keys [get]
+func (h *SecretGuestHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	keys, err := h.service.GetAPIKeys(ctx, userID)
+	if err != nil {
+		h.handleUserAdminError(ctx, w, err, userID)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, keys)
+}
+
+// @Summary      Create API Key (Admin)
+// @Security     BearerAuth
+// @Description  Issues a new API key for a service account. The key is returned only once; clients send it as "Authorization: ApiKey <key>". Scopes are permission slugs and must be granted to the service account role.
+// @Tags         Service Accounts (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Service account user ID" format(uuid)
+// @Param        input body secret_guest.CreateAPIKeyRequestDTO true "API key data"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.CreateAPIKeyResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid payload, scopes or expiration date"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "User not found"
+// @Failure      409 {object} ErrorResponse "User is not a service account"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/service_accounts/{id}/api_keys [post]
+func (h *SecretGuestHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto CreateAPIKeyRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for create API key", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(ctx, actorID, userID, dto)
+	if err != nil {
+		h.handleUserAdminError(ctx, w, err, userID)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, key)
+}
+
+// @Summary      Revoke API Key (Admin)
+// @Security     BearerAuth
+// @Description  Revokes an API key. Requests with a revoked key are rejected immediately.
+// @Tags         Service Accounts (Admin)
+// @Param        id path string true "API key ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid API key ID"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "API key not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/api_keys/{id}/revoke [patch]
+func (h *SecretGuestHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	keyID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(ctx, actorID, keyID); err != nil {
+		h.handleUserAdminError(ctx, w, err, uuid.Nil)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// guest_applications
+
+// @Summary      Get My Application
+// @Security     BearerAuth
+// @Description  Returns the participation application of the current user with its review status.
+// @Tags         Applications (User)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.GuestApplicationResponseDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      404 {object} ErrorResponse "Application has not been submitted"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /applications/my [get]
+func (h *SecretGuestHandler) GetMyApplication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	application, err := h.service.GetMyApplication(ctx, userID)
+	if err != nil {
+		h.handleGuestApplicationError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, application)
+}
+
+// @Summary      Submit My Application
+// @Security     BearerAuth
+// @Description  Submits an application to participate in the programme. While the application is pending or waitlisted it can be resubmitted with updated data; approved or rejected applications cannot be changed. Only guests with an approved application can see and take free assignments.
+// @Tags         Applications (User)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.SubmitGuestApplicationRequestDTO true "Application data"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.GuestApplicationResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      409 {object} ErrorResponse "Application has already been reviewed"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /applications/my [post]
+func (h *SecretGuestHandler) SubmitMyApplication(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto SubmitGuestApplicationRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for guest application", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	application, err := h.service.SubmitMyApplication(ctx, userID, dto)
+	if err != nil {
+		h.handleGuestApplicationError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, application)
+}
+
+// @Summary      Get Applications Queue (Staff)
+// @Security     BearerAuth
+// @Description  Returns participation applications, oldest first. Without status_id filter returns the review queue (pending and waitlisted).
+// @Tags         Applications (Staff)
+// @Produce      json
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param        status_id query []int false "Filter by status IDs (1 - pending, 2 - approved, 3 - rejected, 4 - waitlisted)" collectionFormat(multi)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.GuestApplicationsResponse
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/applications [get]
+func (h *SecretGuestHandler) GetGuestApplications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	page, limit := h.parsePagination(r)
+
+	var statusIDs []int
+	for _, idStr := range r.URL.Query()["status_id"] {
+		statusID, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Warn(ctx, "Invalid status_id value in query parameter, value ignored", zap.String("status_id", idStr))
+			continue
+		}
+		statusIDs = append(statusIDs, statusID)
+	}
+
+	dto := GetGuestApplicationsRequestDTO{
+		Page:      page,
+		Limit:     limit,
+		StatusIDs: statusIDs,
+	}
+
+	applications, err := h.service.GetGuestApplications(ctx, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to get guest applications", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, applications)
+}
+
+// @Summary      Get Application by ID (Staff)
+// @Security     BearerAuth
+// @Description  Returns a single participation application.
+// @Tags         Applications (Staff)
+// @Produce      json
+// @Param        id path string true "Application ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.GuestApplicationResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid application ID"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Application not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/applications/{id} [get]
+func (h *SecretGuestHandler) GetGuestApplicationByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	applicationID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	application, err := h.service.GetGuestApplicationByID(ctx, applicationID)
+	if err != nil {
+		h.handleGuestApplicationError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, application)
+}
+
+// @Summary      Approve Application (Staff)
+// @Security     BearerAuth
+// @Description  Approves a pending or waitlisted application: the guest gets access to free assignments. The action is recorded in the audit log.
+// @Tags         Applications (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Application ID" format(uuid)
+// @Param        input body secret_guest.ReviewGuestApplicationRequestDTO false "Review comment"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.GuestApplicationResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Application not found"
+// @Failure      409 {object} ErrorResponse "Application has already been reviewed or is own application"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/applications/{id}/approve [patch]
+func (h *SecretGuestHandler) ApproveGuestApplication(w http.ResponseWriter, r *http.Request) {
+	h.reviewGuestApplication(w, r, models.GuestApplicationStatusApproved)
+}
+
+// @Summary      Reject Application (Staff)
+// @Security     BearerAuth
+// @Description  Rejects a pending or waitlisted application. The comment is visible to the applicant. The action is recorded in the audit log.
+// @Tags         Applications (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Application ID" format(uuid)
+// @Param        input body secret_guest.ReviewGuestApplicationRequestDTO false "Review comment"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.GuestApplicationResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Application not found"
+// @Failure      409 {object} ErrorResponse "Application has already been reviewed or is own application"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/applications/{id}/reject [patch]
+func (h *SecretGuestHandler) RejectGuestApplication(w http.ResponseWriter, r *http.Request) {
+	h.reviewGuestApplication(w, r, models.GuestApplicationStatusRejected)
+}
+
+// @Summary      Waitlist Application (Staff)
+// @Security     BearerAuth
+// @Description  Moves a pending application to the waitlist. Waitlisted applications stay in the review queue. The action is recorded in the audit log.
+// @Tags         Applications (Staff)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Application ID" format(uuid)
+// @Param        input body secret_guest.ReviewGuestApplicationRequestDTO false "Review comment"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.GuestApplicationResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Application not found"
+// @Failure      409 {object} ErrorResponse "Application has already been reviewed or is own application"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/applications/{id}/waitlist [patch]
+func (h *SecretGuestHandler) WaitlistGuestApplication(w http.ResponseWriter, r *http.Request) {
+	h.reviewGuestApplication(w, r, models.GuestApplicationStatusWaitlisted)
+}
+
+func (h *SecretGuestHandler) reviewGuestApplication(w http.ResponseWriter, r *http.Request, statusID int) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	applicationID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	// Тело запроса необязательно
+	var dto ReviewGuestApplicationRequestDTO
+	if r.ContentLength != 0 {
+		if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for guest application review", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	application, err := h.service.ReviewGuestApplication(ctx, actorID, applicationID, statusID, dto)
+	if err != nil {
+		h.handleGuestApplicationError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, application)
+}
+
+func (h *SecretGuestHandler) handleGuestApplicationError(ctx context.Context, w http.ResponseWriter, err error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	switch {
+	case errors.Is(err, models.ErrApplicationNotFound):
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrValidationFailed):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+	case errors.Is(err, models.ErrApplicationReviewed), errors.Is(err, models.ErrCannotModifySelf):
+		log.Info(ctx, "Guest application cannot be changed", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+	default:
+		log.Error(ctx, "Failed to process guest application", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// assignment declines
+
+// @Summary      Get Decline Analytics (Staff)
+// @Security     BearerAuth
+// @Description  Returns assignment offer declines grouped by reason, city or listing, the groups with the most declines first. Declines made without a reason are counted as "unspecified".
+// @Tags         Declines (Staff)
+// @Produce      json
+// @Param        group_by query string false "Grouping" Enums(reason, city, listing) default(reason)
+// @Param        from query string false "Declined from date (inclusive)" format(date)
+// @Param        to query string false "Declined to date (inclusive)" format(date)
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.DeclineAnalyticsResponse
+// @Failure      400 {object} ErrorResponse "Invalid group_by or dates"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/declines/analytics [get]
+func (h *SecretGuestHandler) GetDeclineAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+	query := r.URL.Query()
+
+	page, limit := h.parsePagination(r)
+	dto := GetDeclineAnalyticsRequestDTO{
+		GroupBy: query.Get("group_by"),
+		Page:    page,
+		Limit:   limit,
+	}
+
+	if fromStr := query.Get("from"); fromStr != "" {
+		from, err := time.Parse(time.DateOnly, fromStr)
+		if err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid from, expected YYYY-MM-DD")
+			return
+		}
+		dto.From = &from
+	}
+
+	if toStr := query.Get("to"); toStr != "" {
+		to, err := time.Parse(time.DateOnly, toStr)
+		if err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid to, expected YYYY-MM-DD")
+			return
+		}
+		to = to.Add(24 * time.Hour)
+		dto.To = &to
+	}
+
+	response, err := h.service.GetDeclineAnalytics(ctx, dto)
+	if err != nil {
+		if errors.Is(err, models.ErrValidationFailed) {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Error(ctx, "Failed to get decline analytics", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, response)
+}
+
+// @Summary      Get Repeatedly Declined Assignments (Staff)
+// @Security     BearerAuth
+// @Description  Returns assignments that are still offered and not expired but were already declined by guests at least min_declines times, with the reasons and comments. These offers are candidates for changing the purpose or the reward.
+// @Tags         Declines (Staff)
+// @Produce      json
+// @Param        min_declines query int false "Minimum number of declines" default(2)
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.DeclinedAssignmentsResponse
+// @Failure      400 {object} ErrorResponse "Invalid min_declines"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/declines/repeated [get]
+func (h *SecretGuestHandler) GetRepeatedlyDeclinedAssignments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	page, limit := h.parsePagination(r)
+	dto := GetDeclinedAssignmentsRequestDTO{
+		Page:  page,
+		Limit: limit,
+	}
+
+	if minStr := r.URL.Query().Get("min_declines"); minStr != "" {
+		minDeclines, err := strconv.Atoi(minStr)
+		if err != nil || minDeclines < 1 {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid min_declines")
+			return
+		}
+		dto.MinDeclines = minDeclines
+	}
+
+	response, err := h.service.GetRepeatedlyDeclinedAssignments(ctx, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to get repeatedly declined assignments", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, response)
+}
+
+// listing priority
+
+// @Summary      Get Prioritized Listings (Staff)
+// @Security     BearerAuth
+// @Description  Returns listings with their inspection priority, highest first. The score (up to 100 before the listing type weight) adds up: time since the last approved report (up to 40 points at 180 days or if never inspected), the last quality score set by moderators (up to 30 points, the lower the quality the higher; 15 without a score) and complaints pushed by the OTA in the last 90 days (up to 30 points at 5 complaints). The sum is multiplied by the listing type weight in percent.
+// @Tags         Listings (Staff)
+// @Produce      json
+// @Param        city query string false "City search"
+// @Param        listing_type_id query []int false "Filter by one or more listing type IDs" collectionFormat(multi)
+// @Param        min_score query number false "Only listings with a score not lower than this"
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.PrioritizedListingsResponse
+// @Failure      400 {object} ErrorResponse "Invalid min_score"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/listings [get]
+func (h *SecretGuestHandler) GetPrioritizedListings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+	queryParams := r.URL.Query()
+
+	page, limit := h.parsePagination(r)
+	_, _, listingTypeIDs := h.parseFilterParams(r)
+
+	dto := GetPrioritizedListingsRequestDTO{
+		City:           queryParams.Get("city"),
+		ListingTypeIDs: listingTypeIDs,
+		Page:           page,
+		Limit:          limit,
+	}
+	if minScoreStr := queryParams.Get("min_score"); minScoreStr != "" {
+		minScore, err := strconv.ParseFloat(minScoreStr, 64)
+		if err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid min_score")
+			return
+		}
+		dto.MinScore = &minScore
+	}
+
+	listings, err := h.service.GetPrioritizedListings(ctx, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to get prioritized listings", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, listings)
+}
+
+// @Summary      Get Prioritized Listing (Staff)
+// @Security     BearerAuth
+// @Description  Returns a listing with its inspection priority and the components it is made of.
+// @Tags         Listings (Staff)
+// @Produce      json
+// @Param        id path string true "Listing ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.PrioritizedListingDTO
+// @Failure      400 {object} ErrorResponse "Invalid listing ID format"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Listing not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/listings/{id} [get]
+func (h *SecretGuestHandler) GetPrioritizedListing(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	listingID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	listing, err := h.service.GetPrioritizedListing(ctx, listingID)
+	if err != nil {
+		if errors.Is(err, models.ErrListingNotFound) {
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Listing not found")
+			return
+		}
+		log.Error(ctx, "Failed to get prioritized listing", zap.Error(err), zap.String("listing_id", listingID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, listing)
+}
+
+// @Summary      Get Listing Type Priority Weights (Admin)
+// @Security     BearerAuth
+// @Description  Returns the weight of every listing type in the inspection priority, in percent (100 by default).
+// @Tags         Listings (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.ListingTypePriorityWeightDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/listing_priority_weights [get]
+func (h *SecretGuestHandler) GetListingTypePriorityWeights(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	weights, err := h.service.GetListingTypePriorityWeights(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to get listing type priority weights", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, weights)
+}
+
+// @Summary      Update Listing Type Priority Weight (Admin)
+// @Security     BearerAuth
+// @Description  Sets the weight of a listing type in the inspection priority, in percent: 150 raises the priority of such listings by half, 0 excludes them from priority-based inspections. The action is recorded in the audit log.
+// @Tags         Listings (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        listing_type_id path int true "Listing type ID"
+// @Param        input body secret_guest.UpdateListingTypePriorityWeightRequestDTO true "Weight"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.ListingTypePriorityWeightDTO
+// @Failure      400 {object} ErrorResponse "Invalid request"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Listing type not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/listing_priority_weights/{listing_type_id} [put]
+func (h *SecretGuestHandler) UpdateListingTypePriorityWeight(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	listingTypeID, ok := h.parseIntFromPath(w, r, "listing_type_id")
+	if !ok {
+		return
+	}
+
+	var dto UpdateListingTypePriorityWeightRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for listing type priority weight", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	weight, err := h.service.UpdateListingTypePriorityWeight(ctx, actorID, listingTypeID, dto)
+	if err != nil {
+		if errors.Is(err, models.ErrListingTypeNotFound) {
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Listing type not found")
+			return
+		}
+		log.Error(ctx, "Failed to update listing type priority weight", zap.Error(err), zap.Int("listing_type_id", listingTypeID))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, weight)
+}
+
+// @Summary      Create Listing Complaint (Admin)
+// @Security     BearerAuth
+// @Description  Receives a guest complaint about a listing from the OTA (by the OTA listing ID, as in reservations). Complaints of the last 90 days raise the inspection priority of the listing. A repeated complaint with the same external_id is rejected. Intended for the OTA integration with an API key with the complaints.create scope.
+// @Tags         Listings (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.CreateListingComplaintRequestDTO true "Complaint"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.ListingComplaintResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Listing not found"
+// @Failure      409 {object} ErrorResponse "Complaint with this external_id already exists"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/listing_complaints [post]
+func (h *SecretGuestHandler) CreateListingComplaint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto CreateListingComplaintRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for listing complaint", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	complaint, err := h.service.CreateListingComplaint(ctx, actorID, dto)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrListingNotFound):
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Listing not found")
+		case errors.Is(err, models.ErrComplaintDuplicate):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+		default:
+			log.Error(ctx, "Failed to create listing complaint", zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, complaint)
+}
+
+// notifications
+
+// @Summary      Get My Notifications
+// @Security     BearerAuth
+// @Description  Returns notifications of the current user, newest first, and the number of unread ones. For example, an invitation to an assignment from staff (type assignment.invited, payload has assignment_id and accept_deadline).
+// @Tags         Notifications
+// @Produce      json
+// @Param        unread query bool false "Only unread notifications"
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.NotificationsResponse
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /notifications/my [get]
+func (h *SecretGuestHandler) GetMyNotifications(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit := h.parsePagination(r)
+	dto := GetMyNotificationsRequestDTO{
+		UserID:     userID,
+		UnreadOnly: r.URL.Query().Get("unread") == "true",
+		Page:       page,
+		Limit:      limit,
+	}
+
+	notifications, err := h.service.GetMyNotifications(ctx, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to get my notifications", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, notifications)
+}
+
+// @Summary      Mark My Notification Read
+// @Security     BearerAuth
+// @Description  Marks a notification of the current user as read.
+// @Tags         Notifications
+// @Param        id path string true "Notification ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid notification ID format"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      404 {object} ErrorResponse "Notification not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /notifications/my/{id}/read [patch]
+func (h *SecretGuestHandler) MarkMyNotificationRead(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	notificationID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.MarkMyNotificationRead(ctx, userID, notificationID); err != nil {
+		if errors.Is(err, models.ErrNotificationNotFound) {
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Notification not found")
+			return
+		}
+		log.Error(ctx, "Failed to mark notification read", zap.Error(err), zap.String("notification_id", notificationID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// leaderboard
+
+// @Summary      Get Leaderboard
+// @Security     BearerAuth
+// @Description  Returns guests ordered by points earned in the period: the current week (from Monday), the current month or all time, UTC. Guests with equal points share a position. Can be filtered by home city. Guests who hid themselves in the profile (hide_from_leaderboard) are not shown. The position of the current guest is returned in me.
+// @Tags         Leaderboard
+// @Produce      json
+// @Param        period query string false "Period" Enums(week, month, all) default(all)
+// @Param        city query string false "Home city of guests, case-insensitive"
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.LeaderboardResponse
+// @Failure      400 {object} ErrorResponse "Invalid period"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /leaderboard [get]
+func (h *SecretGuestHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit := h.parsePagination(r)
+	dto := GetLeaderboardRequestDTO{
+		Period: r.URL.Query().Get("period"),
+		City:   r.URL.Query().Get("city"),
+		Page:   page,
+		Limit:  limit,
+	}
+
+	leaderboard, err := h.service.GetLeaderboard(ctx, userID, dto)
+	if err != nil {
+		if errors.Is(err, models.ErrValidationFailed) {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid period, expected week, month or all")
+			return
+		}
+		log.Error(ctx, "Failed to get leaderboard", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, leaderboard)
+}
+
+// profiles
+
+// @Summary      Get My Profile
+// @Security     BearerAuth
+// @Description  Returns the profile information for the currently authenticated user. Points are the sum of the point history, badges are the earned ones. The rank and badge names are localized by the lang parameter or Accept-Language header.
+// @Tags         Profiles (User)
+// @Produce      json
+// @Param        lang query string false "Language of the rank name (ISO 639-1), defaults to Accept-Language"
+// @Param        Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.ProfileResponseDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      404 {object} ErrorResponse "Profile not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /profiles/my [get]
+func (h *SecretGuestHandler) GetMyProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	profile, err := h.service.GetMyProfile(ctx, userID, h.requestLanguage(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Profile not found")
+		} else {
+			log.Error(ctx, "Failed to get my profile", zap.Error(err), zap.String("user_id", userID.String()))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, profile)
+}
+
+// @Summary      Update My Profile
+// @Security     BearerAuth
+// @Description  Partially updates the profile of the current user: display name, avatar, home city, preferred listing types, travel availability, languages and hiding from the leaderboard. Omitted fields are left unchanged, an empty string or list clears the value. The avatar is set by the path of a file uploaded via /uploads/generate-url. Preferences are used to rank free assignments.
+// @Tags         Profiles (User)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.UpdateMyProfileRequestDTO true "Profile fields to update"
+// @Param        Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.ProfileResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      404 {object} ErrorResponse "Profile not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /profiles/my [patch]
+func (h *SecretGuestHandler) UpdateMyProfile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto UpdateMyProfileRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for profile update", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	profile, err := h.service.UpdateMyProfile(ctx, userID, dto, h.requestLanguage(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotFound):
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Profile not found")
+		case errors.Is(err, models.ErrValidationFailed):
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+		default:
+			log.Error(ctx, "Failed to update my profile", zap.Error(err), zap.String("user_id", userID.String()))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, profile)
+}
+
+// @Summary      Export My Data
+// @Security     BearerAuth
+// @Description  Returns a ZIP archive with the personal data of the current user: account, profile, participation application, assignments, reports, links to media uploaded to reports and rewards (JSON files).
+// @Tags         Profiles (User)
+// @Produce      application/zip
+// @Param        Authorization header string true "Bearer Access Token"
+// @Success      200 {file} file "ZIP archive"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /profiles/my/export [get]
+func (h *SecretGuestHandler) ExportMyData(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.service.ExportMyData(ctx, userID)
+	if err != nil {
+		log.Error(ctx, "Failed to export personal data", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	log.Info(ctx, "Personal data exported", zap.String("user_id", userID.String()))
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(export.Content)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(export.Content); err != nil {
+		log.Error(ctx, "Failed to write personal data export", zap.Error(err))
+	}
+}
+
+// @Summary      Delete My Account
+// @Security     BearerAuth
+// @Description  Deletes the account of the current guest. Personal data is anonymized, all sessions stop working, taken assignments return to the free list, unfinished reports are refused. Approved and submitted reports are kept without a link to the author. The current password is required for accounts with a password. Staff and service accounts cannot be deleted this way.
+// @Tags         Profiles (User)
+// @Accept       json
+// @Param        input body secret_guest.DeleteMyAccountRequestDTO true "Confirmation"
+// @Param        Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid request body"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Invalid password, account cannot be deleted or impersonation session"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /profiles/my [delete]
+func (h *SecretGuestHandler) DeleteMyAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	// Удалить учетную запись может только сам пользователь, но не администратор, вошедший от его имени
+	if user, _ := ctx.Value(auth.UserKey).(auth.AuthenticatedUser); user.IsImpersonated() {
+		h.writeErrorResponse(ctx, w, http.StatusForbidden, models.ErrImpersonationRestricted.Error())
+		return
+	}
+
+	var dto DeleteMyAccountRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.DeleteMyAccount(ctx, userID, dto); err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidCredentials):
+			h.writeErrorResponse(ctx, w, http.StatusForbidden, "Invalid password")
+		case errors.Is(err, models.ErrAccountNotDeletable), errors.Is(err, models.ErrServiceAccount):
+			h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
+		case errors.Is(err, models.ErrUserNotFound):
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, "User not found")
+		default:
+			log.Error(ctx, "Failed to delete account", zap.Error(err), zap.String("user_id", userID.String()))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // @Summary      Get All Profiles (Staff)
@@ -2479,6 +4530,7 @@ func (h *SecretGuestHandler) GetMyProfile(w http.ResponseWriter, r *http.Request
 // @Description  Returns a paginated list of all user profiles. Available for staff only.
 // @Tags         Profiles (Staff)
 // @Produce      json
+// @Param        lang query string false "Language of the rank name (ISO 639-1), defaults to Accept-Language"
 // @Param        page query int false "Page number for pagination" default(1)
 // @Param        limit query int false "Number of items per page" default(50)
 // @Param        Authorization header string true "Bearer Access Token"
@@ -2495,6 +4547,7 @@ func (h *SecretGuestHandler) GetAllProfiles(w http.ResponseWriter, r *http.Reque
 	dto := GetAllProfilesRequestDTO{
 		Page:  page,
 		Limit: limit,
+		Lang:  h.requestLanguage(r),
 	}
 
 	profiles, err := h.service.GetAllProfiles(ctx, dto)
@@ -2530,7 +4583,7 @@ func (h *SecretGuestHandler) GetProfileByUserID(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	profile, err := h.service.GetMyProfile(ctx, userID) // Reusing the same service method as for GetMyProfile
+	profile, err := h.service.GetMyProfile(ctx, userID, h.requestLanguage(r)) // Reusing the same service method as for GetMyProfile
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			h.writeErrorResponse(ctx, w, http.StatusNotFound, "Profile not found")
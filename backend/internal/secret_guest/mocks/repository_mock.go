@@ -0,0 +1,1073 @@
+package mocks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+// SecretGuestRepository is a mock for the SecretGuestRepository interface
+type SecretGuestRepository struct {
+	mock.Mock
+}
+
+func (m *SecretGuestRepository) CreateListing(ctx context.Context, listing *models.Listing) (uuid.UUID, error) {
+	args := m.Called(ctx, listing)
+	r0 := args.Get(0).(uuid.UUID)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetListings(ctx context.Context, filter repository.ListingsFilter) ([]*models.Listing, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.Listing
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Listing)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetListingByID(ctx context.Context, id uuid.UUID) (*models.Listing, error) {
+	args := m.Called(ctx, id)
+	var r0 *models.Listing
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Listing)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetListingByCode(ctx context.Context, code uuid.UUID) (*models.Listing, error) {
+	args := m.Called(ctx, code)
+	var r0 *models.Listing
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Listing)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateOTAReservation(ctx context.Context, reservation *models.OTAReservation) (uuid.UUID, error) {
+	args := m.Called(ctx, reservation)
+	r0 := args.Get(0).(uuid.UUID)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetOTAReservations(ctx context.Context, filter repository.OTAReservationsFilter) ([]*models.OTAReservation, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.OTAReservation
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.OTAReservation)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetOTAReservationByID(ctx context.Context, id uuid.UUID) (*models.OTAReservation, error) {
+	args := m.Called(ctx, id)
+	var r0 *models.OTAReservation
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.OTAReservation)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateOTAReservationStatus(ctx context.Context, reservationID uuid.UUID, statusID int) error {
+	args := m.Called(ctx, reservationID, statusID)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) CreateAssignment(ctx context.Context, assignment *models.Assignment) (uuid.UUID, error) {
+	args := m.Called(ctx, assignment)
+	r0 := args.Get(0).(uuid.UUID)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateManualAssignment(ctx context.Context, assignment *models.Assignment, notification *models.Notification, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, assignment, notification, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetAssignments(ctx context.Context, filter repository.AssignmentsFilter) ([]*models.Assignment, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.Assignment
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Assignment)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetAssignmentByID(ctx context.Context, assignmentID uuid.UUID) (*models.Assignment, error) {
+	args := m.Called(ctx, assignmentID)
+	var r0 *models.Assignment
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Assignment)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetFreeAssignments(ctx context.Context, filter repository.AssignmentsFilter) ([]*models.Assignment, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.Assignment
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Assignment)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetAssignmentByIDAndOwner(ctx context.Context, assignmentID, reporterID uuid.UUID) (*models.Assignment, error) {
+	args := m.Called(ctx, assignmentID, reporterID)
+	var r0 *models.Assignment
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Assignment)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CancelAssignment(ctx context.Context, assignmentID uuid.UUID, notification *models.Notification, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, assignmentID, notification, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) ReassignAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, acceptDeadline *time.Time, now time.Time, released, invited *models.Notification, entry *models.AuditLogEntry) (*uuid.UUID, error) {
+	args := m.Called(ctx, assignmentID, reporterID, acceptDeadline, now, released, invited, entry)
+	var r0 *uuid.UUID
+	if v := args.Get(0); v != nil {
+		r0 = v.(*uuid.UUID)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) ExtendAssignment(ctx context.Context, assignmentID uuid.UUID, reporterID *uuid.UUID, expiresAt time.Time, acceptDeadline *time.Time, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, assignmentID, reporterID, expiresAt, acceptDeadline, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetAssignmentIDs(ctx context.Context, filter repository.BulkAssignmentsFilter, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, filter, limit)
+	var r0 []uuid.UUID
+	if v := args.Get(0); v != nil {
+		r0 = v.([]uuid.UUID)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) ReleaseAssignment(ctx context.Context, assignmentID uuid.UUID, notification *models.Notification, offer *models.WaitlistOffer, entry *models.AuditLogEntry) (*uuid.UUID, error) {
+	args := m.Called(ctx, assignmentID, notification, offer, entry)
+	var r0 *uuid.UUID
+	if v := args.Get(0); v != nil {
+		r0 = v.(*uuid.UUID)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, acceptedAt time.Time, dueAfterCheckout time.Duration, reward *models.Reward) (*models.Report, error) {
+	args := m.Called(ctx, assignmentID, reporterID, acceptedAt, dueAfterCheckout, reward)
+	var r0 *models.Report
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Report)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) DeclineMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, declinedAt time.Time, reasonID *int, comment *string, offer *models.WaitlistOffer) (*uuid.UUID, error) {
+	args := m.Called(ctx, assignmentID, reporterID, declinedAt, reasonID, comment, offer)
+	var r0 *uuid.UUID
+	if v := args.Get(0); v != nil {
+		r0 = v.(*uuid.UUID)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) TakeFreeAssignmentsByID(ctx context.Context, assignmentID, userID uuid.UUID, takenAt time.Time, acceptDeadline *time.Time) error {
+	args := m.Called(ctx, assignmentID, userID, takenAt, acceptDeadline)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetReports(ctx context.Context, filter repository.ReportsFilter) ([]*models.Report, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.Report
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Report)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetReportByID(ctx context.Context, reportID uuid.UUID) (*models.Report, error) {
+	args := m.Called(ctx, reportID)
+	var r0 *models.Report
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Report)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetReportByIDAndOwner(ctx context.Context, reportID, reporterID uuid.UUID) (*models.Report, error) {
+	args := m.Called(ctx, reportID, reporterID)
+	var r0 *models.Report
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Report)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateMyReportContent(ctx context.Context, reportID, reporterID uuid.UUID, currentStatusID int, schema models.ChecklistSchema) error {
+	args := m.Called(ctx, reportID, reporterID, currentStatusID, schema)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) UpdateMyReportStatus(ctx context.Context, reportID, reporterID uuid.UUID, currentStatusID, newStatusID int) error {
+	args := m.Called(ctx, reportID, reporterID, currentStatusID, newStatusID)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) UpdateReportStatusAsStaff(ctx context.Context, reportID uuid.UUID, currentStatusID, newStatusID int) error {
+	args := m.Called(ctx, reportID, currentStatusID, newStatusID)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) ReviewReport(ctx context.Context, reportID uuid.UUID, newStatusID int, reviewerID uuid.UUID, qualityScore *int) (*uuid.UUID, error) {
+	args := m.Called(ctx, reportID, newStatusID, reviewerID, qualityScore)
+	var r0 *uuid.UUID
+	if v := args.Get(0); v != nil {
+		r0 = v.(*uuid.UUID)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetListingTypeID(ctx context.Context, listingID uuid.UUID) (int, error) {
+	args := m.Called(ctx, listingID)
+	r0 := args.Get(0).(int)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetChecklistTemplate(ctx context.Context, listingTypeID int) ([]*models.ChecklistSection, []*models.ChecklistItem, error) {
+	args := m.Called(ctx, listingTypeID)
+	var r0 []*models.ChecklistSection
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.ChecklistSection)
+	}
+	var r1 []*models.ChecklistItem
+	if v := args.Get(1); v != nil {
+		r1 = v.([]*models.ChecklistItem)
+	}
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) UpdateReportSchema(ctx context.Context, reportID uuid.UUID, schema models.ChecklistSchema) error {
+	args := m.Called(ctx, reportID, schema)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetAnswerTypes(ctx context.Context, filter repository.AnswerTypesFilter) ([]*models.AnswerType, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.AnswerType
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.AnswerType)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetAnswerTypeByID(ctx context.Context, id int) (*models.AnswerType, error) {
+	args := m.Called(ctx, id)
+	var r0 *models.AnswerType
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.AnswerType)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateAnswerType(ctx context.Context, at *models.AnswerType) (*models.AnswerType, error) {
+	args := m.Called(ctx, at)
+	var r0 *models.AnswerType
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.AnswerType)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateAnswerType(ctx context.Context, id int, at *models.AnswerType, metaSetted bool) error {
+	args := m.Called(ctx, id, at, metaSetted)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) DeleteAnswerType(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetMediaRequirements(ctx context.Context, filter repository.MediaRequirementsFilter) ([]*models.MediaRequirement, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.MediaRequirement
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.MediaRequirement)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetListingTypes(ctx context.Context, filter repository.ListingTypesFilter) ([]*models.ListingType, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.ListingType
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.ListingType)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetListingTypeByID(ctx context.Context, id int) (*models.ListingType, error) {
+	args := m.Called(ctx, id)
+	var r0 *models.ListingType
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.ListingType)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateListingType(ctx context.Context, lt *models.ListingType) (*models.ListingType, error) {
+	args := m.Called(ctx, lt)
+	var r0 *models.ListingType
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.ListingType)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateListingType(ctx context.Context, id int, lt *models.ListingType) error {
+	args := m.Called(ctx, id, lt)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) DeleteListingType(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetChecklistSections(ctx context.Context, filter repository.ChecklistSectionsFilter) ([]*models.ChecklistSection, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.ChecklistSection
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.ChecklistSection)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetChecklistSectionByID(ctx context.Context, id int) (*models.ChecklistSection, error) {
+	args := m.Called(ctx, id)
+	var r0 *models.ChecklistSection
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.ChecklistSection)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateChecklistSection(ctx context.Context, cs *models.ChecklistSection) (*models.ChecklistSection, error) {
+	args := m.Called(ctx, cs)
+	var r0 *models.ChecklistSection
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.ChecklistSection)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateChecklistSection(ctx context.Context, id int, csUpd *models.ChecklistSectionUpdate) error {
+	args := m.Called(ctx, id, csUpd)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) DeleteChecklistSection(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetChecklistItems(ctx context.Context, filter repository.ChecklistItemsFilter) ([]*models.ChecklistItem, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.ChecklistItem
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.ChecklistItem)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetChecklistItemByID(ctx context.Context, id int) (*models.ChecklistItem, error) {
+	args := m.Called(ctx, id)
+	var r0 *models.ChecklistItem
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.ChecklistItem)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateChecklistItem(ctx context.Context, item *models.ChecklistItem) (*models.ChecklistItem, error) {
+	args := m.Called(ctx, item)
+	var r0 *models.ChecklistItem
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.ChecklistItem)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateChecklistItem(ctx context.Context, id int, item *models.ChecklistItemUpdate) error {
+	args := m.Called(ctx, id, item)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) DeleteChecklistItem(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetAllUsers(ctx context.Context, filter repository.UsersFilter) ([]*models.User, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.User
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.User)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	var r0 *models.User
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.User)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateUserRole(ctx context.Context, userID uuid.UUID, roleID int, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, userID, roleID, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) SetUserBlocked(ctx context.Context, userID uuid.UUID, reason *string, blockedBy uuid.UUID, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, userID, reason, blockedBy, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) ResetUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, userID, passwordHash, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) CreateAuditLogEntry(ctx context.Context, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetAuditLog(ctx context.Context, filter repository.AuditLogFilter) ([]*models.AuditLogEntry, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.AuditLogEntry
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.AuditLogEntry)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetPermissions(ctx context.Context) ([]*models.Permission, error) {
+	args := m.Called(ctx)
+	var r0 []*models.Permission
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Permission)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetRoles(ctx context.Context) ([]*models.Role, error) {
+	args := m.Called(ctx)
+	var r0 []*models.Role
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Role)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetRoleByID(ctx context.Context, roleID int) (*models.Role, error) {
+	args := m.Called(ctx, roleID)
+	var r0 *models.Role
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Role)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateRole(ctx context.Context, role *models.Role, entry *models.AuditLogEntry) (int, error) {
+	args := m.Called(ctx, role, entry)
+	r0 := args.Get(0).(int)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateRole(ctx context.Context, roleID int, name, description *string, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, roleID, name, description, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) SetRolePermissions(ctx context.Context, roleID int, permissions []string, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, roleID, permissions, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) DeleteRole(ctx context.Context, roleID int, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, roleID, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) CreateServiceAccount(ctx context.Context, user *models.User, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, user, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	args := m.Called(ctx, userID)
+	var r0 []*models.APIKey
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.APIKey)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetAPIKeyByID(ctx context.Context, keyID uuid.UUID) (*models.APIKey, error) {
+	args := m.Called(ctx, keyID)
+	var r0 *models.APIKey
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.APIKey)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateAPIKey(ctx context.Context, key *models.APIKey, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, key, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) RevokeAPIKey(ctx context.Context, keyID uuid.UUID, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, keyID, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetGuestApplications(ctx context.Context, filter repository.GuestApplicationsFilter) ([]*models.GuestApplication, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.GuestApplication
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.GuestApplication)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetGuestApplicationByID(ctx context.Context, applicationID uuid.UUID) (*models.GuestApplication, error) {
+	args := m.Called(ctx, applicationID)
+	var r0 *models.GuestApplication
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.GuestApplication)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetGuestApplicationByUserID(ctx context.Context, userID uuid.UUID) (*models.GuestApplication, error) {
+	args := m.Called(ctx, userID)
+	var r0 *models.GuestApplication
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.GuestApplication)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) SaveGuestApplication(ctx context.Context, application *models.GuestApplication) error {
+	args := m.Called(ctx, application)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) ReviewGuestApplication(ctx context.Context, applicationID uuid.UUID, statusID int, reviewerID uuid.UUID, comment *string, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, applicationID, statusID, reviewerID, comment, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetRewards(ctx context.Context, filter repository.RewardsFilter) ([]*models.Reward, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.Reward
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Reward)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetRewardByID(ctx context.Context, rewardID uuid.UUID) (*models.Reward, error) {
+	args := m.Called(ctx, rewardID)
+	var r0 *models.Reward
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Reward)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetRewardTotals(ctx context.Context, userID uuid.UUID) ([]*models.RewardTotal, error) {
+	args := m.Called(ctx, userID)
+	var r0 []*models.RewardTotal
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.RewardTotal)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) PayRewards(ctx context.Context, rewardIDs []uuid.UUID, paidBy uuid.UUID, reference string, entries []*models.AuditLogEntry) error {
+	args := m.Called(ctx, rewardIDs, paidBy, reference, entries)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) VoidReward(ctx context.Context, rewardID, voidedBy uuid.UUID, reason string, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, rewardID, voidedBy, reason, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetRewardPolicies(ctx context.Context) ([]*models.RewardPolicy, error) {
+	args := m.Called(ctx)
+	var r0 []*models.RewardPolicy
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.RewardPolicy)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetRewardPolicy(ctx context.Context, listingTypeID int) (*models.RewardPolicy, error) {
+	args := m.Called(ctx, listingTypeID)
+	var r0 *models.RewardPolicy
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.RewardPolicy)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) SaveRewardPolicy(ctx context.Context, policy *models.RewardPolicy, actorID uuid.UUID, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, policy, actorID, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetUserProfileByID(ctx context.Context, userID uuid.UUID) (*models.UserProfile, error) {
+	args := m.Called(ctx, userID)
+	var r0 *models.UserProfile
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.UserProfile)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdateUserProfileInfo(ctx context.Context, userID uuid.UUID, info json.RawMessage) error {
+	args := m.Called(ctx, userID, info)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) DeleteUserAccount(ctx context.Context, userID uuid.UUID, anonymizedUsername string, offers map[uuid.UUID]*models.WaitlistOffer, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, userID, anonymizedUsername, offers, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetAllUserProfiles(ctx context.Context, limit, offset int) ([]*models.UserProfile, int, error) {
+	args := m.Called(ctx, limit, offset)
+	var r0 []*models.UserProfile
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.UserProfile)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetPointEvents(ctx context.Context, filter repository.PointEventsFilter) ([]*models.PointEvent, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.PointEvent
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.PointEvent)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetPointBreakdown(ctx context.Context, userID uuid.UUID) ([]*models.PointBreakdownItem, error) {
+	args := m.Called(ctx, userID)
+	var r0 []*models.PointBreakdownItem
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.PointBreakdownItem)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) RecomputePoints(ctx context.Context, entry *models.AuditLogEntry) (int64, error) {
+	args := m.Called(ctx, entry)
+	r0 := args.Get(0).(int64)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetPointRules(ctx context.Context) ([]*models.PointRule, error) {
+	args := m.Called(ctx)
+	var r0 []*models.PointRule
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.PointRule)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetPointRule(ctx context.Context, eventType string) (*models.PointRule, error) {
+	args := m.Called(ctx, eventType)
+	var r0 *models.PointRule
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.PointRule)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) UpdatePointRule(ctx context.Context, rule *models.PointRule, actorID uuid.UUID, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, rule, actorID, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetRankTiers(ctx context.Context) ([]*models.RankTier, error) {
+	args := m.Called(ctx)
+	var r0 []*models.RankTier
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.RankTier)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) ReplaceRankTiers(ctx context.Context, tiers []*models.RankTier, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, tiers, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetBadges(ctx context.Context, onlyActive bool) ([]*models.Badge, error) {
+	args := m.Called(ctx, onlyActive)
+	var r0 []*models.Badge
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Badge)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetBadgeByID(ctx context.Context, badgeID int) (*models.Badge, error) {
+	args := m.Called(ctx, badgeID)
+	var r0 *models.Badge
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Badge)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) CreateBadge(ctx context.Context, badge *models.Badge, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, badge, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) UpdateBadge(ctx context.Context, badge *models.Badge, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, badge, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetUserBadges(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]*models.UserBadge, error) {
+	args := m.Called(ctx, userIDs)
+	var r0 map[uuid.UUID][]*models.UserBadge
+	if v := args.Get(0); v != nil {
+		r0 = v.(map[uuid.UUID][]*models.UserBadge)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) AwardBadges(ctx context.Context, userID uuid.UUID, badgeIDs []int) ([]int, error) {
+	args := m.Called(ctx, userID, badgeIDs)
+	var r0 []int
+	if v := args.Get(0); v != nil {
+		r0 = v.([]int)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetBadgeMetrics(ctx context.Context, userID uuid.UUID) (map[string]int, error) {
+	args := m.Called(ctx, userID)
+	var r0 map[string]int
+	if v := args.Get(0); v != nil {
+		r0 = v.(map[string]int)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetUsersWithPointEvents(ctx context.Context) ([]uuid.UUID, error) {
+	args := m.Called(ctx)
+	var r0 []uuid.UUID
+	if v := args.Get(0); v != nil {
+		r0 = v.([]uuid.UUID)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetLeaderboard(ctx context.Context, filter repository.LeaderboardFilter) ([]*models.LeaderboardEntry, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.LeaderboardEntry
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.LeaderboardEntry)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetLeaderboardEntry(ctx context.Context, filter repository.LeaderboardFilter, userID uuid.UUID) (*models.LeaderboardEntry, error) {
+	args := m.Called(ctx, filter, userID)
+	var r0 *models.LeaderboardEntry
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.LeaderboardEntry)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetExpiredAssignmentHolds(ctx context.Context, now time.Time, limit int) ([]*models.ExpiredAssignmentHold, error) {
+	args := m.Called(ctx, now, limit)
+	var r0 []*models.ExpiredAssignmentHold
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.ExpiredAssignmentHold)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) ReleaseAssignmentHold(ctx context.Context, hold *models.ExpiredAssignmentHold, notification *models.Notification, offer *models.WaitlistOffer) (*uuid.UUID, error) {
+	args := m.Called(ctx, hold, notification, offer)
+	var r0 *uuid.UUID
+	if v := args.Get(0); v != nil {
+		r0 = v.(*uuid.UUID)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) JoinAssignmentWaitlist(ctx context.Context, assignmentID, userID uuid.UUID) (*models.WaitlistEntry, error) {
+	args := m.Called(ctx, assignmentID, userID)
+	var r0 *models.WaitlistEntry
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.WaitlistEntry)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) LeaveAssignmentWaitlist(ctx context.Context, assignmentID, userID uuid.UUID) error {
+	args := m.Called(ctx, assignmentID, userID)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) CheckInAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, check *models.StayCheck) error {
+	args := m.Called(ctx, assignmentID, reporterID, check)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) CheckOutAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, check *models.StayCheck) error {
+	args := m.Called(ctx, assignmentID, reporterID, check)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetAssignmentStay(ctx context.Context, assignmentID uuid.UUID) (*models.AssignmentStay, error) {
+	args := m.Called(ctx, assignmentID)
+	var r0 *models.AssignmentStay
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.AssignmentStay)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) SaveCalendarToken(ctx context.Context, userID uuid.UUID, tokenHash string) (time.Time, error) {
+	args := m.Called(ctx, userID, tokenHash)
+	r0 := args.Get(0).(time.Time)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetUserIDByCalendarToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	args := m.Called(ctx, tokenHash)
+	r0 := args.Get(0).(uuid.UUID)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetCalendarStays(ctx context.Context, userID uuid.UUID, since time.Time) ([]*models.CalendarStay, error) {
+	args := m.Called(ctx, userID, since)
+	var r0 []*models.CalendarStay
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.CalendarStay)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetReportsDueSoon(ctx context.Context, now, dueBefore time.Time, maxReminders, limit int) ([]*models.DueReport, error) {
+	args := m.Called(ctx, now, dueBefore, maxReminders, limit)
+	var r0 []*models.DueReport
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.DueReport)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) MarkReportReminded(ctx context.Context, reportID uuid.UUID, remindersSent int, notification *models.Notification) error {
+	args := m.Called(ctx, reportID, remindersSent, notification)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetOverdueReports(ctx context.Context, now time.Time, limit int) ([]*models.DueReport, error) {
+	args := m.Called(ctx, now, limit)
+	var r0 []*models.DueReport
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.DueReport)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) MarkReportOverdue(ctx context.Context, reportID uuid.UUID, now time.Time, notification *models.Notification) error {
+	args := m.Called(ctx, reportID, now, notification)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) BackfillReportDueDates(ctx context.Context, now time.Time, dueAfterCheckout time.Duration) (int64, error) {
+	args := m.Called(ctx, now, dueAfterCheckout)
+	r0 := args.Get(0).(int64)
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetDeclineAnalytics(ctx context.Context, filter repository.DeclineAnalyticsFilter) ([]*models.DeclineStat, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.DeclineStat
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.DeclineStat)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetRepeatedlyDeclinedAssignments(ctx context.Context, minDeclines, limit, offset int) ([]*models.DeclinedAssignment, int, error) {
+	args := m.Called(ctx, minDeclines, limit, offset)
+	var r0 []*models.DeclinedAssignment
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.DeclinedAssignment)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetPrioritizedListings(ctx context.Context, filter repository.PrioritizedListingsFilter) ([]*models.Listing, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.Listing
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Listing)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetListingPriority(ctx context.Context, listingID uuid.UUID) (*models.ListingPriority, error) {
+	args := m.Called(ctx, listingID)
+	var r0 *models.ListingPriority
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.ListingPriority)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetListingTypePriorityWeights(ctx context.Context) ([]*models.ListingTypePriorityWeight, error) {
+	args := m.Called(ctx)
+	var r0 []*models.ListingTypePriorityWeight
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.ListingTypePriorityWeight)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) SaveListingTypePriorityWeight(ctx context.Context, listingTypeID, weight int, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, listingTypeID, weight, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) CreateListingComplaint(ctx context.Context, complaint *models.ListingComplaint) error {
+	args := m.Called(ctx, complaint)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetCampaigns(ctx context.Context, filter repository.CampaignsFilter) ([]*models.Campaign, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.Campaign
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Campaign)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) GetCampaignByID(ctx context.Context, campaignID uuid.UUID) (*models.Campaign, error) {
+	args := m.Called(ctx, campaignID)
+	var r0 *models.Campaign
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Campaign)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) FindCampaignForListing(ctx context.Context, listingID uuid.UUID, checkinDate time.Time) (*models.Campaign, error) {
+	args := m.Called(ctx, listingID, checkinDate)
+	var r0 *models.Campaign
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Campaign)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetCampaignListings(ctx context.Context, campaignID uuid.UUID, limit, offset int) ([]*models.CampaignListing, int, error) {
+	args := m.Called(ctx, campaignID, limit, offset)
+	var r0 []*models.CampaignListing
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.CampaignListing)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
+
+func (m *SecretGuestRepository) CreateCampaign(ctx context.Context, campaign *models.Campaign, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, campaign, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) UpdateCampaign(ctx context.Context, campaign *models.Campaign, entry *models.AuditLogEntry) error {
+	args := m.Called(ctx, campaign, entry)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetNotifications(ctx context.Context, filter repository.NotificationsFilter) ([]*models.Notification, int, int, error) {
+	args := m.Called(ctx, filter)
+	var r0 []*models.Notification
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Notification)
+	}
+	r1 := args.Get(1).(int)
+	r2 := args.Get(2).(int)
+	return r0, r1, r2, args.Error(3)
+}
+
+func (m *SecretGuestRepository) MarkNotificationRead(ctx context.Context, notificationID, userID uuid.UUID) error {
+	args := m.Called(ctx, notificationID, userID)
+	return args.Error(0)
+}
+
+func (m *SecretGuestRepository) GetStatistics(ctx context.Context) (*models.Statistics, error) {
+	args := m.Called(ctx)
+	var r0 *models.Statistics
+	if v := args.Get(0); v != nil {
+		r0 = v.(*models.Statistics)
+	}
+	return r0, args.Error(1)
+}
+
+func (m *SecretGuestRepository) GetUserHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Report, int, error) {
+	args := m.Called(ctx, userID, limit, offset)
+	var r0 []*models.Report
+	if v := args.Get(0); v != nil {
+		r0 = v.([]*models.Report)
+	}
+	r1 := args.Get(1).(int)
+	return r0, r1, args.Error(2)
+}
@@ -0,0 +1,582 @@
+package secret_guest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Очки и ранги гостей
+
+func toPointEventDTO(e *models.PointEvent) *PointEventDTO {
+	return &PointEventDTO{
+		ID:           e.ID,
+		EventType:    e.EventType,
+		Name:         e.EventName,
+		Points:       e.Points,
+		AssignmentID: e.AssignmentID,
+		ReportID:     e.ReportID,
+		CreatedAt:    e.CreatedAt,
+	}
+}
+
+func toPointRuleResponseDTO(rule *models.PointRule) *PointRuleResponseDTO {
+	return &PointRuleResponseDTO{
+		EventType:  rule.EventType,
+		Name:       rule.Name,
+		Points:     rule.Points,
+		GraceHours: rule.GraceHours,
+		UpdatedAt:  rule.UpdatedAt,
+	}
+}
+
+// GetUserPoints объясняет очки гостя: сумма, ранг, сколько до следующего, разбивка по событиям и история
+func (s *SecretGuestService) GetUserPoints(ctx context.Context, userID uuid.UUID, dto GetPointsRequestDTO) (*PointsExplanationResponse, error) {
+	breakdown, err := s.repo.GetPointBreakdown(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point breakdown from repository: %w", err)
+	}
+
+	tiers, err := s.repo.GetRankTiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rank tiers from repository: %w", err)
+	}
+
+	events, total, err := s.repo.GetPointEvents(ctx, repository.PointEventsFilter{
+		UserID: userID,
+		Limit:  dto.Limit,
+		Offset: (dto.Page - 1) * dto.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point events from repository: %w", err)
+	}
+
+	response := &PointsExplanationResponse{
+		Breakdown: make([]*PointBreakdownDTO, 0, len(breakdown)),
+		Events:    make([]*PointEventDTO, 0, len(events)),
+		Total:     total,
+		Page:      dto.Page,
+	}
+
+	for _, item := range breakdown {
+		response.Points += item.Points
+		response.Breakdown = append(response.Breakdown, &PointBreakdownDTO{
+			EventType: item.EventType,
+			Name:      item.EventName,
+			Count:     item.Count,
+			Points:    item.Points,
+		})
+	}
+
+	current, next := resolveRank(tiers, response.Points)
+	if current != nil {
+		response.Rank = *toRankDTO(current, dto.Lang)
+	}
+	if next != nil {
+		response.NextRank = toRankDTO(next, dto.Lang)
+		pointsToNext := next.MinPoints - response.Points
+		response.PointsToNextRank = &pointsToNext
+	}
+
+	for _, e := range events {
+		response.Events = append(response.Events, toPointEventDTO(e))
+	}
+
+	return response, nil
+}
+
+func (s *SecretGuestService) GetPointRules(ctx context.Context) ([]*PointRuleResponseDTO, error) {
+	rules, err := s.repo.GetPointRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point rules from repository: %w", err)
+	}
+
+	responseDTOs := make([]*PointRuleResponseDTO, 0, len(rules))
+	for _, rule := range rules {
+		responseDTOs = append(responseDTOs, toPointRuleResponseDTO(rule))
+	}
+	return responseDTOs, nil
+}
+
+// UpdatePointRule меняет вес события. Уже начисленные очки меняются только при пересчете(RecomputePoints).
+func (s *SecretGuestService) UpdatePointRule(ctx context.Context, actorID uuid.UUID, eventType string, dto UpdatePointRuleRequestDTO) (*PointRuleResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if dto.Points == nil {
+		return nil, models.ErrValidationFailed
+	}
+
+	current, err := s.repo.GetPointRule(ctx, eventType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point rule: %w", err)
+	}
+
+	rule := &models.PointRule{
+		EventType:  eventType,
+		Points:     *dto.Points,
+		GraceHours: current.GraceHours,
+	}
+	if eventType == models.PointEventReportLate && dto.GraceHours != nil {
+		rule.GraceHours = dto.GraceHours
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionPointRuleUpdated, models.AuditEntityPointRule, eventType, map[string]any{
+		"old_points":      current.Points,
+		"new_points":      rule.Points,
+		"old_grace_hours": current.GraceHours,
+		"new_grace_hours": rule.GraceHours,
+	})
+
+	if err := s.repo.UpdatePointRule(ctx, rule, actorID, entry); err != nil {
+		return nil, fmt.Errorf("failed to update point rule: %w", err)
+	}
+
+	log.Info(ctx, "Point rule updated", zap.String("actor_id", actorID.String()), zap.String("event_type", eventType), zap.Int("points", rule.Points))
+
+	updated, err := s.repo.GetPointRule(ctx, eventType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point rule: %w", err)
+	}
+	return toPointRuleResponseDTO(updated), nil
+}
+
+// RecomputePoints применяет текущие веса правил ко всей истории начислений
+func (s *SecretGuestService) RecomputePoints(ctx context.Context, actorID uuid.UUID) (*RecomputePointsResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rules, err := s.repo.GetPointRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point rules from repository: %w", err)
+	}
+
+	weights := make(map[string]int, len(rules))
+	for _, rule := range rules {
+		weights[rule.EventType] = rule.Points
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionPointsRecomputed, models.AuditEntityPointRule, "", map[string]any{
+		"weights": weights,
+	})
+
+	updated, err := s.repo.RecomputePoints(ctx, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recompute points: %w", err)
+	}
+
+	log.Info(ctx, "Points recomputed", zap.String("actor_id", actorID.String()), zap.Int64("updated_events", updated))
+	return &RecomputePointsResponse{UpdatedEvents: updated}, nil
+}
+
+func (s *SecretGuestService) GetRankTiers(ctx context.Context) ([]*RankTierDTO, error) {
+	tiers, err := s.repo.GetRankTiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rank tiers from repository: %w", err)
+	}
+
+	responseDTOs := make([]*RankTierDTO, 0, len(tiers))
+	for _, t := range tiers {
+		responseDTOs = append(responseDTOs, &RankTierDTO{Slug: t.Slug, MinPoints: t.MinPoints, Names: t.Names})
+	}
+	return responseDTOs, nil
+}
+
+// ReplaceRankTiers заменяет набор рангов. Ранг с порогом 0 обязателен, чтобы ранг был у каждого гостя.
+func (s *SecretGuestService) ReplaceRankTiers(ctx context.Context, actorID uuid.UUID, dto ReplaceRankTiersRequestDTO) ([]*RankTierDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	slugs := make(map[string]struct{}, len(dto.Tiers))
+	thresholds := make(map[int]struct{}, len(dto.Tiers))
+	tiers := make([]*models.RankTier, 0, len(dto.Tiers))
+	for _, t := range dto.Tiers {
+		slug := strings.TrimSpace(t.Slug)
+		if _, ok := slugs[slug]; ok {
+			return nil, models.ErrInvalidRankTiers
+		}
+		if _, ok := thresholds[t.MinPoints]; ok {
+			return nil, models.ErrInvalidRankTiers
+		}
+		slugs[slug] = struct{}{}
+		thresholds[t.MinPoints] = struct{}{}
+
+		names := make(map[string]string, len(t.Names))
+		for lang, name := range t.Names {
+			names[strings.ToLower(lang)] = strings.TrimSpace(name)
+		}
+		tiers = append(tiers, &models.RankTier{Slug: slug, MinPoints: t.MinPoints, Names: names})
+	}
+	if _, ok := thresholds[0]; !ok {
+		return nil, models.ErrInvalidRankTiers
+	}
+
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinPoints < tiers[j].MinPoints })
+
+	details := make([]map[string]any, 0, len(tiers))
+	for _, t := range tiers {
+		details = append(details, map[string]any{"slug": t.Slug, "min_points": t.MinPoints})
+	}
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionRankTiersReplaced, models.AuditEntityRankTiers, "", map[string]any{
+		"tiers": details,
+	})
+
+	if err := s.repo.ReplaceRankTiers(ctx, tiers, entry); err != nil {
+		return nil, fmt.Errorf("failed to replace rank tiers: %w", err)
+	}
+
+	log.Info(ctx, "Rank tiers replaced", zap.String("actor_id", actorID.String()), zap.Int("count", len(tiers)))
+	return s.GetRankTiers(ctx)
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Значки гостей
+
+var badgeMetrics = map[string]struct{}{
+	models.BadgeMetricAcceptedAssignments:   {},
+	models.BadgeMetricSubmittedReports:      {},
+	models.BadgeMetricApprovedReports:       {},
+	models.BadgeMetricRejectedReports:       {},
+	models.BadgeMetricLateReports:           {},
+	models.BadgeMetricOnTimeApprovedReports: {},
+	models.BadgeMetricCities:                {},
+	models.BadgeMetricApprovedStreak:        {},
+	models.BadgeMetricPoints:                {},
+	models.BadgeMetricMaxQualityScore:       {},
+}
+
+// maxBadgeRuleDepth ограничивает вложенность групп all/any
+const maxBadgeRuleDepth = 3
+
+// validateBadgeRule: лист - известный показатель и оператор сравнения, группа - ровно один из all/any с условиями
+func validateBadgeRule(rule models.BadgeRule, depth int) error {
+	if depth > maxBadgeRuleDepth {
+		return fmt.Errorf("%w: nesting is deeper than %d", models.ErrInvalidBadgeRule, maxBadgeRuleDepth)
+	}
+
+	isGroup := len(rule.All) > 0 || len(rule.Any) > 0
+	if isGroup {
+		if len(rule.All) > 0 && len(rule.Any) > 0 {
+			return fmt.Errorf("%w: a group must use either all or any", models.ErrInvalidBadgeRule)
+		}
+		if rule.Metric != "" || rule.Op != "" {
+			return fmt.Errorf("%w: a group cannot have metric or op", models.ErrInvalidBadgeRule)
+		}
+		for _, child := range append(rule.All, rule.Any...) {
+			if err := validateBadgeRule(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, ok := badgeMetrics[rule.Metric]; !ok {
+		return fmt.Errorf("%w: unknown metric %q", models.ErrInvalidBadgeRule, rule.Metric)
+	}
+	switch rule.Op {
+	case ">=", ">", "=", "<=", "<":
+	default:
+		return fmt.Errorf("%w: unknown op %q", models.ErrInvalidBadgeRule, rule.Op)
+	}
+	return nil
+}
+
+func evaluateBadgeRule(rule models.BadgeRule, metrics map[string]int) bool {
+	switch {
+	case len(rule.All) > 0:
+		for _, child := range rule.All {
+			if !evaluateBadgeRule(child, metrics) {
+				return false
+			}
+		}
+		return true
+	case len(rule.Any) > 0:
+		for _, child := range rule.Any {
+			if evaluateBadgeRule(child, metrics) {
+				return true
+			}
+		}
+		return false
+	}
+
+	value := metrics[rule.Metric]
+	switch rule.Op {
+	case ">=":
+		return value >= rule.Value
+	case ">":
+		return value > rule.Value
+	case "=":
+		return value == rule.Value
+	case "<=":
+		return value <= rule.Value
+	case "<":
+		return value < rule.Value
+	}
+	return false
+}
+
+// awardBadges проверяет условия активных значков по текущим показателям гостя и выдает выполненные.
+// Возвращает количество выданных сейчас значков.
+func (s *SecretGuestService) awardBadges(ctx context.Context, badges []*models.Badge, userID uuid.UUID) (int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	metrics, err := s.repo.GetBadgeMetrics(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get badge metrics: %w", err)
+	}
+
+	var badgeIDs []int
+	for _, b := range badges {
+		if evaluateBadgeRule(b.Rule, metrics) {
+			badgeIDs = append(badgeIDs, b.ID)
+		}
+	}
+	if len(badgeIDs) == 0 {
+		return 0, nil
+	}
+
+	awarded, err := s.repo.AwardBadges(ctx, userID, badgeIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to award badges: %w", err)
+	}
+	if len(awarded) > 0 {
+		log.Info(ctx, "Badges awarded", zap.String("user_id", userID.String()), zap.Ints("badge_ids", awarded))
+	}
+	return len(awarded), nil
+}
+
+// awardBadgesAfterTransition выдает значки после смены статуса задания или отчета.
+// Ошибка не отменяет уже выполненный переход: значки будут выданы при следующем переходе или перепроверке.
+func (s *SecretGuestService) awardBadgesAfterTransition(ctx context.Context, userID uuid.UUID) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	badges, err := s.repo.GetBadges(ctx, true)
+	if err == nil {
+		_, err = s.awardBadges(ctx, badges, userID)
+	}
+	if err != nil {
+		log.Error(ctx, "Failed to award badges", zap.Error(err), zap.String("user_id", userID.String()))
+	}
+}
+
+// attachProfileBadges загружает полученные значки профилей одним запросом
+func (s *SecretGuestService) attachProfileBadges(ctx context.Context, profiles ...*models.UserProfile) error {
+	userIDs := make([]uuid.UUID, 0, len(profiles))
+	for _, p := range profiles {
+		userIDs = append(userIDs, p.UserID)
+	}
+
+	badges, err := s.repo.GetUserBadges(ctx, userIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get user badges from repository: %w", err)
+	}
+
+	for _, p := range profiles {
+		p.Badges = badges[p.UserID]
+	}
+	return nil
+}
+
+func toEarnedBadgeDTOs(badges []*models.UserBadge, lang string) []*EarnedBadgeDTO {
+	result := make([]*EarnedBadgeDTO, 0, len(badges))
+	for _, b := range badges {
+		result = append(result, &EarnedBadgeDTO{
+			ID:          b.ID,
+			Slug:        b.Slug,
+			Name:        localizedName(b.Names, lang),
+			Description: localizedName(b.Descriptions, lang),
+			EarnedAt:    b.EarnedAt,
+		})
+	}
+	return result
+}
+
+func toBadgeResponseDTO(b *models.Badge) *BadgeResponseDTO {
+	descriptions := b.Descriptions
+	if descriptions == nil {
+		descriptions = map[string]string{}
+	}
+	return &BadgeResponseDTO{
+		ID:           b.ID,
+		Slug:         b.Slug,
+		Names:        b.Names,
+		Descriptions: descriptions,
+		Rule:         b.Rule,
+		IsActive:     b.IsActive,
+		CreatedAt:    b.CreatedAt,
+		UpdatedAt:    b.UpdatedAt,
+	}
+}
+
+// GetMyBadges возвращает полученные значки и выдаваемые сейчас, которых у гостя еще нет
+func (s *SecretGuestService) GetMyBadges(ctx context.Context, userID uuid.UUID, lang string) ([]*MyBadgeDTO, error) {
+	earned, err := s.repo.GetUserBadges(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user badges from repository: %w", err)
+	}
+
+	active, err := s.repo.GetBadges(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get badges from repository: %w", err)
+	}
+
+	result := make([]*MyBadgeDTO, 0, len(active))
+	earnedIDs := make(map[int]struct{}, len(earned[userID]))
+	for _, b := range earned[userID] {
+		earnedAt := b.EarnedAt
+		earnedIDs[b.ID] = struct{}{}
+		result = append(result, &MyBadgeDTO{
+			ID:          b.ID,
+			Slug:        b.Slug,
+			Name:        localizedName(b.Names, lang),
+			Description: localizedName(b.Descriptions, lang),
+			Earned:      true,
+			EarnedAt:    &earnedAt,
+		})
+	}
+	for _, b := range active {
+		if _, ok := earnedIDs[b.ID]; ok {
+			continue
+		}
+		result = append(result, &MyBadgeDTO{
+			ID:          b.ID,
+			Slug:        b.Slug,
+			Name:        localizedName(b.Names, lang),
+			Description: localizedName(b.Descriptions, lang),
+		})
+	}
+
+	return result, nil
+}
+
+func (s *SecretGuestService) GetBadges(ctx context.Context) ([]*BadgeResponseDTO, error) {
+	badges, err := s.repo.GetBadges(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get badges from repository: %w", err)
+	}
+
+	responseDTOs := make([]*BadgeResponseDTO, 0, len(badges))
+	for _, b := range badges {
+		responseDTOs = append(responseDTOs, toBadgeResponseDTO(b))
+	}
+	return responseDTOs, nil
+}
+
+func (s *SecretGuestService) CreateBadge(ctx context.Context, actorID uuid.UUID, dto CreateBadgeRequestDTO) (*BadgeResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if dto.Rule == nil {
+		return nil, models.ErrInvalidBadgeRule
+	}
+	if err := validateBadgeRule(*dto.Rule, 1); err != nil {
+		return nil, err
+	}
+
+	badge := &models.Badge{
+		Slug:         strings.TrimSpace(dto.Slug),
+		Names:        dto.Names,
+		Descriptions: dto.Descriptions,
+		Rule:         *dto.Rule,
+		IsActive:     dto.IsActive == nil || *dto.IsActive,
+	}
+	if badge.Descriptions == nil {
+		badge.Descriptions = map[string]string{}
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionBadgeCreated, models.AuditEntityBadge, "", map[string]any{
+		"slug":      badge.Slug,
+		"rule":      badge.Rule,
+		"is_active": badge.IsActive,
+	})
+
+	if err := s.repo.CreateBadge(ctx, badge, entry); err != nil {
+		return nil, fmt.Errorf("failed to create badge: %w", err)
+	}
+
+	log.Info(ctx, "Badge created", zap.String("actor_id", actorID.String()), zap.Int("badge_id", badge.ID), zap.String("slug", badge.Slug))
+
+	created, err := s.repo.GetBadgeByID(ctx, badge.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get badge: %w", err)
+	}
+	return toBadgeResponseDTO(created), nil
+}
+
+// UpdateBadge меняет значок. Уже выданные значки не отзываются.
+func (s *SecretGuestService) UpdateBadge(ctx context.Context, actorID uuid.UUID, badgeID int, dto UpdateBadgeRequestDTO) (*BadgeResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	badge, err := s.repo.GetBadgeByID(ctx, badgeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get badge: %w", err)
+	}
+
+	details := map[string]any{}
+	if dto.Names != nil {
+		badge.Names = *dto.Names
+		details["names"] = badge.Names
+	}
+	if dto.Descriptions != nil {
+		badge.Descriptions = *dto.Descriptions
+		details["descriptions"] = badge.Descriptions
+	}
+	if dto.Rule != nil {
+		if err := validateBadgeRule(*dto.Rule, 1); err != nil {
+			return nil, err
+		}
+		details["old_rule"] = badge.Rule
+		badge.Rule = *dto.Rule
+		details["new_rule"] = badge.Rule
+	}
+	if dto.IsActive != nil {
+		badge.IsActive = *dto.IsActive
+		details["is_active"] = badge.IsActive
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionBadgeUpdated, models.AuditEntityBadge, strconv.Itoa(badgeID), details)
+
+	if err := s.repo.UpdateBadge(ctx, badge, entry); err != nil {
+		return nil, fmt.Errorf("failed to update badge: %w", err)
+	}
+
+	log.Info(ctx, "Badge updated", zap.String("actor_id", actorID.String()), zap.Int("badge_id", badgeID))
+
+	updated, err := s.repo.GetBadgeByID(ctx, badgeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get badge: %w", err)
+	}
+	return toBadgeResponseDTO(updated), nil
+}
+
+// EvaluateBadges перепроверяет значки у всех гостей с историей событий, например после добавления значка
+func (s *SecretGuestService) EvaluateBadges(ctx context.Context, actorID uuid.UUID) (*EvaluateBadgesResponse, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	badges, err := s.repo.GetBadges(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get badges from repository: %w", err)
+	}
+
+	userIDs, err := s.repo.GetUsersWithPointEvents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users from repository: %w", err)
+	}
+
+	response := &EvaluateBadgesResponse{Users: len(userIDs)}
+	for _, userID := range userIDs {
+		awarded, err := s.awardBadges(ctx, badges, userID)
+		if err != nil {
+			return nil, err
+		}
+		response.Awarded += awarded
+	}
+
+	log.Info(ctx, "Badges evaluated", zap.String("actor_id", actorID.String()), zap.Int("users", response.Users), zap.Int("awarded", response.Awarded))
+	return response, nil
+}
@@ -0,0 +1,468 @@
+package secret_guest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// points
+
+// @Summary      Get My Points
+// @Security     BearerAuth
+// @Description  Explains the points of the current guest: total, current and next rank, points by event type and the point history, newest first. Rank names are localized by the lang parameter or Accept-Language header.
+// @Tags         Points (User)
+// @Produce      json
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param        lang query string false "Language of rank names (ISO 639-1), defaults to Accept-Language"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.PointsExplanationResponse
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /profiles/my/points [get]
+func (h *SecretGuestHandler) GetMyPoints(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	h.writeUserPoints(w, r, userID)
+}
+
+// @Summary      Get User Points (Staff)
+// @Security     BearerAuth
+// @Description  Explains the points of a guest: total, current and next rank, points by event type and the point history.
+// @Tags         Points (Staff)
+// @Produce      json
+// @Param        user_id path string true "User ID" format(uuid)
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param        lang query string false "Language of rank names (ISO 639-1), defaults to Accept-Language"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.PointsExplanationResponse
+// @Failure      400 {object} ErrorResponse "Invalid User ID format"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/profiles/{user_id}/points [get]
+func (h *SecretGuestHandler) GetUserPoints(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.parseUUIDFromPath(w, r, "user_id")
+	if !ok {
+		return
+	}
+
+	h.writeUserPoints(w, r, userID)
+}
+
+func (h *SecretGuestHandler) writeUserPoints(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	page, limit := h.parsePagination(r)
+	dto := GetPointsRequestDTO{
+		Page:  page,
+		Limit: limit,
+		Lang:  h.requestLanguage(r),
+	}
+
+	points, err := h.service.GetUserPoints(ctx, userID, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to get user points", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, points)
+}
+
+// @Summary      Get Point Rules (Admin)
+// @Security     BearerAuth
+// @Description  Returns point weights for events: assignment accepted, report submitted, approved, rejected and submitted late. Negative values are penalties.
+// @Tags         Points (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.PointRuleResponseDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/point_rules [get]
+func (h *SecretGuestHandler) GetPointRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rules, err := h.service.GetPointRules(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to get point rules", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, rules)
+}
+
+// @Summary      Update Point Rule (Admin)
+// @Security     BearerAuth
+// @Description  Changes the point weight of an event type. Applies to new events; already earned points change only after recompute. grace_hours is used only by report_late: hours after the report due date during which a late report is still accepted with the penalty. The action is recorded in the audit log.
+// @Tags         Points (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        event_type path string true "Event type" Enums(assignment_accepted, report_submitted, report_approved, report_rejected, report_late)
+// @Param        input body secret_guest.UpdatePointRuleRequestDTO true "Rule"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.PointRuleResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request body"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Point rule not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/point_rules/{event_type} [patch]
+func (h *SecretGuestHandler) UpdatePointRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	eventType := mux.Vars(r)["event_type"]
+
+	var dto UpdatePointRuleRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for point rule", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	rule, err := h.service.UpdatePointRule(ctx, actorID, eventType, dto)
+	if err != nil {
+		h.handlePointsError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, rule)
+}
+
+// @Summary      Recompute Points (Admin)
+// @Security     BearerAuth
+// @Description  Applies current point weights to the whole point history, so totals and ranks of all guests follow the current rules. Late submissions are not re-evaluated against a changed grace period. The action is recorded in the audit log.
+// @Tags         Points (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.RecomputePointsResponse
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/points/recompute [post]
+func (h *SecretGuestHandler) RecomputePoints(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.RecomputePoints(ctx, actorID)
+	if err != nil {
+		h.handlePointsError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, result)
+}
+
+// @Summary      Get Rank Tiers (Admin)
+// @Security     BearerAuth
+// @Description  Returns rank tiers ordered by threshold with names in all languages.
+// @Tags         Points (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.RankTierDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/rank_tiers [get]
+func (h *SecretGuestHandler) GetRankTiers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tiers, err := h.service.GetRankTiers(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to get rank tiers", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, tiers)
+}
+
+// @Summary      Replace Rank Tiers (Admin)
+// @Security     BearerAuth
+// @Description  Replaces all rank tiers. Slugs and thresholds must be unique and one tier must start at 0 points. Names are keyed by language (ISO 639-1). The action is recorded in the audit log.
+// @Tags         Points (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.ReplaceRankTiersRequestDTO true "Rank tiers"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.RankTierDTO
+// @Failure      400 {object} ErrorResponse "Invalid rank tiers"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/rank_tiers [put]
+func (h *SecretGuestHandler) ReplaceRankTiers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto ReplaceRankTiersRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for rank tiers", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	tiers, err := h.service.ReplaceRankTiers(ctx, actorID, dto)
+	if err != nil {
+		h.handlePointsError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, tiers)
+}
+
+func (h *SecretGuestHandler) handlePointsError(ctx context.Context, w http.ResponseWriter, err error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	switch {
+	case errors.Is(err, models.ErrPointRuleNotFound):
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrInvalidRankTiers):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrValidationFailed):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+	default:
+		log.Error(ctx, "Failed to process points settings", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// badges
+
+// @Summary      Get My Badges
+// @Security     BearerAuth
+// @Description  Returns badges of the current guest: earned ones with the date they were earned, then active badges not earned yet. Names and descriptions are localized by the lang parameter or Accept-Language header.
+// @Tags         Badges (User)
+// @Produce      json
+// @Param        lang query string false "Language of badge names (ISO 639-1), defaults to Accept-Language"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.MyBadgeDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /profiles/my/badges [get]
+func (h *SecretGuestHandler) GetMyBadges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	badges, err := h.service.GetMyBadges(ctx, userID, h.requestLanguage(r))
+	if err != nil {
+		log.Error(ctx, "Failed to get my badges", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, badges)
+}
+
+// @Summary      Get Badges (Admin)
+// @Security     BearerAuth
+// @Description  Returns all badges, including inactive ones, with names and descriptions in all languages and their rules.
+// @Tags         Badges (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.BadgeResponseDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/badges [get]
+func (h *SecretGuestHandler) GetBadges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	badges, err := h.service.GetBadges(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to get badges", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, badges)
+}
+
+// @Summary      Create Badge (Admin)
+// @Security     BearerAuth
+// @Description  Creates a badge. The rule is a condition on guest metrics ({"metric": "cities", "op": ">=", "value": 10}) or a group of conditions ({"all": [...]} or {"any": [...]}). Metrics: accepted_assignments, submitted_reports, approved_reports, rejected_reports, late_reports, on_time_approved_reports, cities, approved_streak, points. Badges are awarded on the next assignment or report transition, or by evaluate. The action is recorded in the audit log.
+// @Tags         Badges (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        input body secret_guest.CreateBadgeRequestDTO true "Badge"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.BadgeResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid badge rule"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      409 {object} ErrorResponse "Badge with this slug already exists"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/badges [post]
+func (h *SecretGuestHandler) CreateBadge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto CreateBadgeRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for badge", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	badge, err := h.service.CreateBadge(ctx, actorID, dto)
+	if err != nil {
+		h.handleBadgeError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, badge)
+}
+
+// @Summary      Update Badge (Admin)
+// @Security     BearerAuth
+// @Description  Partially updates a badge: names, descriptions, rule and activity. The slug cannot be changed. Already earned badges are kept. The action is recorded in the audit log.
+// @Tags         Badges (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        id path int true "Badge ID"
+// @Param        input body secret_guest.UpdateBadgeRequestDTO true "Badge fields to update"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.BadgeResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid badge rule"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Badge not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/badges/{id} [patch]
+func (h *SecretGuestHandler) UpdateBadge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	badgeID, ok := h.parseIntFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto UpdateBadgeRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for badge", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	badge, err := h.service.UpdateBadge(ctx, actorID, badgeID, dto)
+	if err != nil {
+		h.handleBadgeError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, badge)
+}
+
+// @Summary      Evaluate Badges (Admin)
+// @Security     BearerAuth
+// @Description  Checks active badge rules for all guests with a point history and awards the badges they have earned, e.g. after a new badge is created.
+// @Tags         Badges (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.EvaluateBadgesResponse
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/badges/evaluate [post]
+func (h *SecretGuestHandler) EvaluateBadges(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.EvaluateBadges(ctx, actorID)
+	if err != nil {
+		h.handleBadgeError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, result)
+}
+
+func (h *SecretGuestHandler) handleBadgeError(ctx context.Context, w http.ResponseWriter, err error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	switch {
+	case errors.Is(err, models.ErrBadgeNotFound):
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrBadgeSlugExists):
+		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrInvalidBadgeRule):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrValidationFailed):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+	default:
+		log.Error(ctx, "Failed to process badge", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+	}
+}
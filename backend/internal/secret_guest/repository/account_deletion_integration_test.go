@@ -0,0 +1,84 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUserAccountAnonymizes(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	base := time.Now().AddDate(0, 11, 0).Truncate(24 * time.Hour)
+	guestID := f.createGuest()
+
+	// Одобренный отчет остается в оценке объекта, черновик и начисление по нему - нет
+	approved := f.createDraftReport(guestID, base, 48*time.Hour)
+	_, err := f.pool.Exec(ctx, `
+		UPDATE reports SET status_id = $2, checklist_schema = '{"sections": []}' WHERE id = $1
+	`, approved, models.ReportStatusApproved)
+	require.NoError(t, err)
+	draft := f.acceptWithReward(guestID, base.AddDate(0, 0, 5))
+	_, err = f.pool.Exec(ctx, `UPDATE reports SET checklist_schema = '{"sections": []}' WHERE id = $1`, draft)
+	require.NoError(t, err)
+
+	taken := f.createFreeAssignment(base.AddDate(0, 0, 10), 2)
+	require.NoError(t, f.repo.TakeFreeAssignmentsByID(ctx, taken, guestID, time.Now(), nil))
+
+	anonymizedUsername := "deleted_" + strings.ReplaceAll(guestID.String(), "-", "")[:12]
+	entry := models.NewAuditLogEntry(guestID, models.AuditActionUserDeleted, models.AuditEntityUser, guestID.String(), nil)
+	require.NoError(t, f.repo.DeleteUserAccount(ctx, guestID, anonymizedUsername, nil, entry))
+
+	var (
+		username, passwordHash string
+		email                  *string
+		deletedAt, blockedAt   *time.Time
+	)
+	require.NoError(t, f.pool.QueryRow(ctx, `
+		SELECT username, email, password_hash, deleted_at, blocked_at FROM users WHERE id = $1
+	`, guestID).Scan(&username, &email, &passwordHash, &deletedAt, &blockedAt))
+	assert.Equal(t, anonymizedUsername, username)
+	assert.Nil(t, email)
+	assert.Equal(t, "!", passwordHash)
+	assert.NotNil(t, deletedAt)
+	assert.NotNil(t, blockedAt)
+
+	report := func(id uuid.UUID) (reporterID *uuid.UUID, statusID int, hasContent bool) {
+		require.NoError(t, f.pool.QueryRow(ctx, `
+			SELECT reporter_id, status_id, checklist_schema IS NOT NULL FROM reports WHERE id = $1
+		`, id).Scan(&reporterID, &statusID, &hasContent))
+		return
+	}
+
+	reporterID, statusID, hasContent := report(approved)
+	assert.Nil(t, reporterID)
+	assert.Equal(t, models.ReportStatusApproved, statusID)
+	assert.True(t, hasContent)
+
+	reporterID, statusID, hasContent = report(draft)
+	assert.Nil(t, reporterID)
+	assert.Equal(t, models.ReportStatusRefused, statusID)
+	assert.False(t, hasContent)
+
+	var rewardStatusID int
+	require.NoError(t, f.pool.QueryRow(ctx, `
+		SELECT rw.status_id FROM rewards rw JOIN reports r ON r.assignment_id = rw.assignment_id WHERE r.id = $1
+	`, draft).Scan(&rewardStatusID))
+	assert.Equal(t, models.RewardStatusVoided, rewardStatusID)
+
+	var takenReporterID *uuid.UUID
+	require.NoError(t, f.pool.QueryRow(ctx, `SELECT reporter_id FROM assignments WHERE id = $1`, taken).Scan(&takenReporterID))
+	assert.Nil(t, takenReporterID)
+
+	// Повторное удаление - учетной записи уже нет
+	err = f.repo.DeleteUserAccount(ctx, guestID, anonymizedUsername, nil, entry)
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestApplicationLifecycle(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	guestID, reviewerID := f.createGuest(), f.createGuest()
+
+	review := func(applicationID uuid.UUID, statusID int, comment *string) error {
+		entry := models.NewAuditLogEntry(reviewerID, models.AuditActionApplicationApproved, models.AuditEntityGuestApplication, applicationID.String(), nil)
+		return f.repo.ReviewGuestApplication(ctx, applicationID, statusID, reviewerID, comment, entry)
+	}
+
+	// Новая заявка попадает на рассмотрение
+	application := &models.GuestApplication{ID: uuid.New(), UserID: guestID, Motivation: "люблю путешествовать", HomeCity: "Testcity"}
+	require.NoError(t, f.repo.SaveGuestApplication(ctx, application))
+	saved, err := f.repo.GetGuestApplicationByUserID(ctx, guestID)
+	require.NoError(t, err)
+	assert.Equal(t, application.ID, saved.ID)
+	assert.Equal(t, models.GuestApplicationStatusPending, saved.StatusID)
+
+	// Повторная подача обновляет ту же заявку, а не создает новую
+	resubmitted := &models.GuestApplication{ID: uuid.New(), UserID: guestID, Motivation: "езжу каждый месяц", HomeCity: "Testcity", SocialLinks: []string{"https://example.com/me"}}
+	require.NoError(t, f.repo.SaveGuestApplication(ctx, resubmitted))
+	assert.Equal(t, application.ID, resubmitted.ID)
+
+	// Из листа ожидания заявку еще можно править и одобрить
+	require.NoError(t, review(application.ID, models.GuestApplicationStatusWaitlisted, nil))
+	require.NoError(t, f.repo.SaveGuestApplication(ctx, &models.GuestApplication{ID: uuid.New(), UserID: guestID, Motivation: "дополнил анкету", HomeCity: "Testcity"}))
+	saved, err = f.repo.GetGuestApplicationByUserID(ctx, guestID)
+	require.NoError(t, err)
+	assert.Equal(t, models.GuestApplicationStatusWaitlisted, saved.StatusID)
+	assert.Equal(t, "дополнил анкету", saved.Motivation)
+
+	require.NoError(t, review(application.ID, models.GuestApplicationStatusApproved, ptr("добро пожаловать")))
+	saved, err = f.repo.GetGuestApplicationByUserID(ctx, guestID)
+	require.NoError(t, err)
+	assert.Equal(t, models.GuestApplicationStatusApproved, saved.StatusID)
+	if assert.NotNil(t, saved.ReviewedBy) {
+		assert.Equal(t, reviewerID, *saved.ReviewedBy)
+	}
+	assert.NotNil(t, saved.ReviewedAt)
+	assert.Equal(t, ptr("добро пожаловать"), saved.ReviewComment)
+
+	// Рассмотренную заявку нельзя ни править, ни пересмотреть
+	err = f.repo.SaveGuestApplication(ctx, &models.GuestApplication{ID: uuid.New(), UserID: guestID, Motivation: "после одобрения", HomeCity: "Testcity"})
+	assert.ErrorIs(t, err, models.ErrApplicationReviewed)
+	assert.ErrorIs(t, review(application.ID, models.GuestApplicationStatusRejected, nil), models.ErrApplicationReviewed)
+
+	saved, err = f.repo.GetGuestApplicationByUserID(ctx, guestID)
+	require.NoError(t, err)
+	assert.Equal(t, models.GuestApplicationStatusApproved, saved.StatusID)
+	assert.Equal(t, "дополнил анкету", saved.Motivation)
+
+	assert.ErrorIs(t, review(uuid.New(), models.GuestApplicationStatusApproved, nil), models.ErrApplicationNotFound)
+}
@@ -0,0 +1,99 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createOfferAt создает свободное предложение по объекту listingID на даты [checkin, checkin+nights)
+func (f *fixture) createOfferAt(listingID uuid.UUID, checkin time.Time, nights int) uuid.UUID {
+	id := uuid.New()
+	_, err := f.pool.Exec(context.Background(), `
+		INSERT INTO assignments (id, checkin_date, checkout_date, listing_id, purpose, expires_at, status_id)
+		VALUES ($1, $2, $3, $4, 'Ranking test', $2, $5)
+	`, id, checkin, checkin.AddDate(0, 0, nights), listingID, models.AssignmentStatusOffered)
+	require.NoError(f.t, err)
+	return id
+}
+
+func TestFreeAssignmentsRanking(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	base := time.Now().AddDate(1, 2, 0).Truncate(24 * time.Hour)
+
+	// Объекты теста в своих городах: выборка ограничена фильтром по городу
+	prefix := "Rankcity-" + uuid.NewString()[:8]
+	homeListing, _ := f.createListingIn(prefix+"-home", base.AddDate(0, 1, 0))
+	otherListing, _ := f.createListingIn(prefix+"-other", base.AddDate(0, 1, 5))
+	var otherTypeID int
+	require.NoError(t, f.pool.QueryRow(ctx, `SELECT listing_type_id FROM listings WHERE id = $1`, otherListing).Scan(&otherTypeID))
+
+	// Домашний объект другого типа, иначе его предложения тоже получат вес предпочитаемого типа
+	var homeTypeID int
+	require.NoError(t, f.pool.QueryRow(ctx, `SELECT listing_type_id FROM listings WHERE id = $1`, homeListing).Scan(&homeTypeID))
+	if homeTypeID == otherTypeID {
+		var anotherTypeID int
+		err := f.pool.QueryRow(ctx, `SELECT id FROM listing_types WHERE id <> $1 ORDER BY id LIMIT 1`, otherTypeID).Scan(&anotherTypeID)
+		if err != nil {
+			t.Skip("test needs at least two listing types")
+		}
+		_, err = f.pool.Exec(ctx, `UPDATE listings SET listing_type_id = $2 WHERE id = $1`, homeListing, anotherTypeID)
+		require.NoError(t, err)
+	}
+
+	homeAvailable := f.createOfferAt(homeListing, base, 2)                    // даты(4) + город(1)
+	otherAvailable := f.createOfferAt(otherListing, base.AddDate(0, 0, 3), 2) // даты(4) + тип(2)
+	homeLater := f.createOfferAt(homeListing, base.AddDate(0, 0, 20), 2)      // город(1)
+	otherLater := f.createOfferAt(otherListing, base.AddDate(0, 0, 25), 2)    // тип(2)
+	// Выезд позже конца периода доступности - даты не подходят
+	homeOverlapping := f.createOfferAt(homeListing, base.AddDate(0, 0, 9), 3) // город(1)
+
+	filter := repository.AssignmentsFilter{
+		StatusIDs: []int{models.AssignmentStatusOffered},
+		City:      prefix,
+		Limit:     100,
+		Ranking: &repository.AssignmentRanking{
+			HomeCity:       prefix + "-HOME",
+			ListingTypeIDs: []int{otherTypeID},
+			AvailableFrom:  []time.Time{base.AddDate(0, 0, -1), base.AddDate(0, 2, 0)},
+			AvailableTo:    []time.Time{base.AddDate(0, 0, 10), base.AddDate(0, 3, 0)},
+		},
+	}
+
+	assignments, _, err := f.repo.GetFreeAssignments(ctx, filter)
+	require.NoError(t, err)
+
+	order := make([]uuid.UUID, 0, len(assignments))
+	for _, a := range assignments {
+		order = append(order, a.ID)
+	}
+	require.Len(t, order, 7)
+	assert.Equal(t, []uuid.UUID{otherAvailable, homeAvailable}, order[:2])
+
+	// Дальше предпочтительный тип(2) выше домашнего города(1), предложения без совпадений - последние
+	position := make(map[uuid.UUID]int, len(order))
+	for i, id := range order {
+		position[id] = i
+	}
+	for _, id := range []uuid.UUID{homeLater, homeOverlapping} {
+		assert.Less(t, position[otherLater], position[id])
+	}
+
+	t.Run("without preferences newest first", func(t *testing.T) {
+		filter.Ranking = nil
+		assignments, _, err := f.repo.GetFreeAssignments(ctx, filter)
+		require.NoError(t, err)
+		for i := 1; i < len(assignments); i++ {
+			assert.False(t, assignments[i].CreatedAt.After(assignments[i-1].CreatedAt))
+		}
+	})
+}
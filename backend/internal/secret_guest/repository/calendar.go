@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// calendar
+
+// SaveCalendarToken сохраняет хеш нового токена календарной ленты пользователя, заменяя прежний
+func (r *SecretGuestRepository) SaveCalendarToken(ctx context.Context, userID uuid.UUID, tokenHash string) (time.Time, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var createdAt time.Time
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO calendar_tokens (user_id, token_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			token_hash = EXCLUDED.token_hash,
+			created_at = CURRENT_TIMESTAMP
+		RETURNING created_at
+	`, userID, tokenHash).Scan(&createdAt)
+	if err != nil {
+		log.Error(ctx, "Failed to save calendar token", zap.Error(err), zap.String("user_id", userID.String()))
+		return time.Time{}, err
+	}
+	return createdAt, nil
+}
+
+// GetUserIDByCalendarToken - владелец токена календарной ленты. Токены заблокированных и удаленных пользователей не действуют
+func (r *SecretGuestRepository) GetUserIDByCalendarToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var userID uuid.UUID
+	err := r.db.QueryRow(ctx, `
+		SELECT t.user_id
+		FROM calendar_tokens t
+		JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL AND u.blocked_at IS NULL
+		WHERE t.token_hash = $1
+	`, tokenHash).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, models.ErrCalendarTokenNotFound
+		}
+		log.Error(ctx, "Failed to get calendar token", zap.Error(err))
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// GetCalendarStays - принятые предложения гостя с датой выезда не раньше since, по дате заезда
+func (r *SecretGuestRepository) GetCalendarStays(ctx context.Context, userID uuid.UUID, since time.Time) ([]*models.CalendarStay, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			a.id as "assignment_id",
+			a.checkin_date,
+			a.checkout_date,
+			res.booking_number,
+			l.title as "listing_title",
+			l.address as "listing_address",
+			l.city as "listing_city",
+			l.country as "listing_country",
+			rep.due_at as "report_due_at"
+		FROM assignments a
+		JOIN listings l ON l.id = a.listing_id
+		LEFT JOIN ota_sg_reservations res ON res.id = a.ota_sg_reservation_id
+		LEFT JOIN reports rep ON rep.assignment_id = a.id
+		WHERE a.reporter_id = $1 AND a.status_id = $2 AND a.checkout_date >= $3
+		ORDER BY a.checkin_date
+	`, userID, models.AssignmentStatusAccepted, since)
+	if err != nil {
+		log.Error(ctx, "Failed to query calendar stays", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, err
+	}
+
+	stays, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[models.CalendarStay])
+	if err != nil {
+		log.Error(ctx, "Failed to collect calendar stays", zap.Error(err))
+		return nil, err
+	}
+	return stays, nil
+}
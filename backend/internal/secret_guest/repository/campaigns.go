@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// campaigns
+
+// campaignListingCondition - объект l подходит под отбор кампании c
+var campaignListingCondition = fmt.Sprintf(`
+	(cardinality(c.listing_type_ids) = 0 OR l.listing_type_id = ANY(c.listing_type_ids))
+	AND (c.city IS NULL OR lower(l.city) = lower(c.city))
+	AND (c.not_inspected_days IS NULL OR NOT EXISTS (
+		SELECT 1 FROM reports rp
+		WHERE rp.listing_id = l.id AND rp.status_id = %d
+			AND rp.submitted_at > CURRENT_TIMESTAMP - make_interval(days => c.not_inspected_days)
+	))
+`, models.ReportStatusApproved)
+
+// campaignSpentQuery - вознаграждения по предложениям кампании c в ее валюте, кроме аннулированных
+var campaignSpentQuery = fmt.Sprintf(`
+	SELECT COALESCE(SUM(rw.amount), 0)
+	FROM rewards rw
+	JOIN assignments a ON a.id = rw.assignment_id
+	WHERE a.campaign_id = c.id AND rw.currency = c.currency AND rw.status_id <> %d
+`, models.RewardStatusVoided)
+
+var campaignSelectQuery = fmt.Sprintf(`
+	SELECT
+		c.id, c.name, c.purpose,
+		c.listing_type_ids, c.city, c.not_inspected_days,
+		c.starts_at, c.ends_at, c.quota, c.budget, c.currency,
+		c.is_active, c.created_by, c.created_at, c.updated_at,
+
+		(SELECT COUNT(*) FROM listings l WHERE %[1]s),
+		(SELECT COUNT(*) FROM assignments a WHERE a.campaign_id = c.id),
+		(SELECT COUNT(*) FROM assignments a WHERE a.campaign_id = c.id AND a.accepted_at IS NOT NULL),
+		(SELECT COUNT(*) FROM reports rp JOIN assignments a ON a.id = rp.assignment_id WHERE a.campaign_id = c.id AND rp.status_id = %[2]d),
+		(SELECT COUNT(DISTINCT rp.listing_id) FROM reports rp JOIN assignments a ON a.id = rp.assignment_id WHERE a.campaign_id = c.id AND rp.status_id = %[2]d),
+		(%[3]s)
+	FROM campaigns c
+`, campaignListingCondition, models.ReportStatusApproved, campaignSpentQuery)
+
+func scanCampaign(row pgx.Row) (*models.Campaign, error) {
+	var c models.Campaign
+	err := row.Scan(
+		&c.ID, &c.Name, &c.Purpose,
+		&c.ListingTypeIDs, &c.City, &c.NotInspectedDays,
+		&c.StartsAt, &c.EndsAt, &c.Quota, &c.Budget, &c.Currency,
+		&c.IsActive, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt,
+
+		&c.Progress.TargetListings,
+		&c.Progress.Assignments,
+		&c.Progress.AcceptedAssignments,
+		&c.Progress.ApprovedReports,
+		&c.Progress.InspectedListings,
+		&c.Progress.Spent,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+type CampaignsFilter struct {
+	ActiveOnly bool
+	Limit      int
+	Offset     int
+}
+
+func (r *SecretGuestRepository) GetCampaigns(ctx context.Context, filter CampaignsFilter) ([]*models.Campaign, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM campaigns c WHERE NOT $1 OR c.is_active`, filter.ActiveOnly).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to count campaigns", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.Campaign{}, 0, nil
+	}
+
+	query := campaignSelectQuery + `
+		WHERE NOT $1 OR c.is_active
+		ORDER BY c.created_at DESC, c.id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, filter.ActiveOnly, filter.Limit, filter.Offset)
+	if err != nil {
+		log.Error(ctx, "Failed to query campaigns", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	campaigns := make([]*models.Campaign, 0, filter.Limit)
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			log.Error(ctx, "Failed to scan campaign row", zap.Error(err))
+			return nil, total, err
+		}
+		campaigns = append(campaigns, c)
+	}
+
+	return campaigns, total, rows.Err()
+}
+
+func (r *SecretGuestRepository) GetCampaignByID(ctx context.Context, campaignID uuid.UUID) (*models.Campaign, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	c, err := scanCampaign(r.db.QueryRow(ctx, campaignSelectQuery+` WHERE c.id = $1`, campaignID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrCampaignNotFound
+		}
+		log.Error(ctx, "Failed to query campaign by ID", zap.Error(err), zap.String("campaign_id", campaignID.String()))
+		return nil, err
+	}
+	return c, nil
+}
+
+// FindCampaignForListing - активная кампания, под отбор которой подходит объект, с датой заезда в периоде кампании
+// и неисчерпанными квотой и бюджетом. Из нескольких - та, что заканчивается раньше. models.ErrCampaignNotFound - такой нет
+func (r *SecretGuestRepository) FindCampaignForListing(ctx context.Context, listingID uuid.UUID, checkinDate time.Time) (*models.Campaign, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := campaignSelectQuery + fmt.Sprintf(`
+		JOIN listings l ON l.id = $1
+		WHERE c.is_active
+			AND $2::date BETWEEN c.starts_at AND c.ends_at
+			AND %s
+			AND (c.quota IS NULL OR (SELECT COUNT(*) FROM assignments a WHERE a.campaign_id = c.id) < c.quota)
+			AND (c.budget IS NULL OR (%s) < c.budget)
+		ORDER BY c.ends_at, c.created_at
+		LIMIT 1
+	`, campaignListingCondition, campaignSpentQuery)
+
+	c, err := scanCampaign(r.db.QueryRow(ctx, query, listingID, checkinDate))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrCampaignNotFound
+		}
+		log.Error(ctx, "Failed to find campaign for listing", zap.Error(err), zap.String("listing_id", listingID.String()))
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCampaignListings - объекты под отбором кампании, сначала самые приоритетные для проверки
+func (r *SecretGuestRepository) GetCampaignListings(ctx context.Context, campaignID uuid.UUID, limit, offset int) ([]*models.CampaignListing, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	countQuery := `SELECT COUNT(*) FROM campaigns c JOIN listings l ON ` + campaignListingCondition + ` WHERE c.id = $1`
+	var total int
+	if err := r.db.QueryRow(ctx, countQuery, campaignID).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to count campaign listings", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.CampaignListing{}, 0, nil
+	}
+
+	query := `
+		SELECT
+			l.id, l.title, COALESCE(l.city, ''), l.listing_type_id, li.at,
+			(SELECT COUNT(*) FROM assignments a WHERE a.listing_id = l.id AND a.campaign_id = c.id),
+			` + listingPriorityScore + ` AS score
+		FROM campaigns c
+		JOIN listings l ON ` + campaignListingCondition + `
+		JOIN listing_types lt ON lt.id = l.listing_type_id
+		` + listingPriorityJoins + `
+		WHERE c.id = $1
+		ORDER BY score DESC, li.at NULLS FIRST, l.title
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, campaignID, limit, offset)
+	if err != nil {
+		log.Error(ctx, "Failed to query campaign listings", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	listings := make([]*models.CampaignListing, 0, limit)
+	for rows.Next() {
+		var cl models.CampaignListing
+		if err := rows.Scan(&cl.ListingID, &cl.Title, &cl.City, &cl.ListingTypeID, &cl.LastInspectedAt, &cl.Assignments, &cl.Priority); err != nil {
+			log.Error(ctx, "Failed to scan campaign listing row", zap.Error(err))
+			return nil, total, err
+		}
+		listings = append(listings, &cl)
+	}
+
+	return listings, total, rows.Err()
+}
+
+func (r *SecretGuestRepository) CreateCampaign(ctx context.Context, c *models.Campaign, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO campaigns (name, purpose, listing_type_ids, city, not_inspected_days, starts_at, ends_at, quota, budget, currency, is_active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, query,
+		c.Name, c.Purpose, c.ListingTypeIDs, c.City, c.NotInspectedDays,
+		c.StartsAt, c.EndsAt, c.Quota, c.Budget, c.Currency, c.IsActive, c.CreatedBy,
+	).Scan(&c.ID)
+	if err != nil {
+		log.Error(ctx, "DB error on creating campaign", zap.Error(err))
+		return err
+	}
+
+	entry.EntityID = c.ID.String()
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *SecretGuestRepository) UpdateCampaign(ctx context.Context, c *models.Campaign, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE campaigns
+		SET name = $2, purpose = $3, listing_type_ids = $4, city = $5, not_inspected_days = $6,
+			starts_at = $7, ends_at = $8, quota = $9, budget = $10, currency = $11, is_active = $12,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	ct, err := tx.Exec(ctx, query,
+		c.ID, c.Name, c.Purpose, c.ListingTypeIDs, c.City, c.NotInspectedDays,
+		c.StartsAt, c.EndsAt, c.Quota, c.Budget, c.Currency, c.IsActive,
+	)
+	if err != nil {
+		log.Error(ctx, "DB error on updating campaign", zap.Error(err), zap.String("campaign_id", c.ID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrCampaignNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,177 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createCampaign создает кампанию; период и город по умолчанию покрывают объект теста и checkin
+func (f *fixture) createCampaign(checkin time.Time, modify func(c *models.Campaign)) *models.Campaign {
+	city := "Testcity"
+	c := &models.Campaign{
+		Name:     "Campaign test",
+		Purpose:  "Campaign test purpose",
+		City:     &city,
+		StartsAt: checkin.AddDate(0, 0, -10),
+		EndsAt:   checkin.AddDate(0, 0, 10),
+		IsActive: true,
+	}
+	if modify != nil {
+		modify(c)
+	}
+
+	entry := models.NewAuditLogEntry(f.createGuest(), models.AuditActionCampaignCreated, models.AuditEntityCampaign, "", nil)
+	require.NoError(f.t, f.repo.CreateCampaign(context.Background(), c, entry))
+	f.t.Cleanup(func() {
+		_, _ = f.pool.Exec(context.Background(), `DELETE FROM campaigns WHERE id = $1`, c.ID)
+	})
+	return c
+}
+
+// addToCampaign засчитывает предложение в кампанию
+func (f *fixture) addToCampaign(assignmentID, campaignID uuid.UUID) {
+	_, err := f.pool.Exec(context.Background(), `UPDATE assignments SET campaign_id = $2 WHERE id = $1`, assignmentID, campaignID)
+	require.NoError(f.t, err)
+}
+
+func (f *fixture) findCampaign(checkin time.Time) *uuid.UUID {
+	c, err := f.repo.FindCampaignForListing(context.Background(), f.listingID, checkin)
+	if err != nil {
+		require.ErrorIs(f.t, err, models.ErrCampaignNotFound)
+		return nil
+	}
+	return &c.ID
+}
+
+func TestFindCampaignForListingTargeting(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	// Даты заезда далеко в будущем: посторонние кампании из БД под них не попадают
+	base := time.Date(2090, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var listingTypeID int
+	require.NoError(t, f.pool.QueryRow(ctx, `SELECT listing_type_id FROM listings WHERE id = $1`, f.listingID).Scan(&listingTypeID))
+
+	cases := []struct {
+		name   string
+		modify func(c *models.Campaign)
+		want   bool
+	}{
+		{"matches city and period", nil, true},
+		{"matches listing type", func(c *models.Campaign) { c.ListingTypeIDs = []int{listingTypeID} }, true},
+		{"other listing type", func(c *models.Campaign) { c.ListingTypeIDs = []int{listingTypeID + 1000} }, false},
+		{"other city", func(c *models.Campaign) { c.City = ptr("Othercity") }, false},
+		{"city is compared case-insensitively", func(c *models.Campaign) { c.City = ptr("TESTCITY") }, true},
+		{"inactive", func(c *models.Campaign) { c.IsActive = false }, false},
+		{"checkin before the period", func(c *models.Campaign) { c.StartsAt = c.StartsAt.AddDate(0, 0, 11) }, false},
+		{"checkin after the period", func(c *models.Campaign) { c.EndsAt = c.EndsAt.AddDate(0, 0, -11) }, false},
+		{"checkin on the last day", func(c *models.Campaign) { c.EndsAt = c.EndsAt.AddDate(0, 0, -10) }, true},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checkin := base.AddDate(0, 1+i, 0)
+			c := f.createCampaign(checkin, tc.modify)
+
+			got := f.findCampaign(checkin)
+			if tc.want {
+				require.NotNil(t, got)
+				assert.Equal(t, c.ID, *got)
+			} else {
+				assert.Nil(t, got)
+			}
+		})
+	}
+
+	t.Run("recently inspected listing", func(t *testing.T) {
+		checkin := base.AddDate(1, 0, 0)
+		c := f.createCampaign(checkin, func(c *models.Campaign) { c.NotInspectedDays = ptr(30) })
+		require.NotNil(t, f.findCampaign(checkin))
+
+		guestID := f.createGuest()
+		reportID := f.createDraftReport(guestID, checkin.AddDate(0, 0, 20), time.Hour)
+		_, err := f.pool.Exec(ctx, `UPDATE reports SET status_id = $2, submitted_at = NOW() WHERE id = $1`, reportID, models.ReportStatusApproved)
+		require.NoError(t, err)
+
+		assert.Nil(t, f.findCampaign(checkin), "campaign %s must skip a listing approved within not_inspected_days", c.ID)
+	})
+}
+
+func TestFindCampaignForListingLimits(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	base := time.Date(2091, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("quota exhaustion", func(t *testing.T) {
+		checkin := base
+		c := f.createCampaign(checkin, func(c *models.Campaign) { c.Quota = ptr(2) })
+
+		f.addToCampaign(f.createFreeAssignment(checkin, 2), c.ID)
+		require.NotNil(t, f.findCampaign(checkin))
+
+		f.addToCampaign(f.createFreeAssignment(checkin.AddDate(0, 0, 3), 2), c.ID)
+		assert.Nil(t, f.findCampaign(checkin))
+	})
+
+	t.Run("budget exhaustion", func(t *testing.T) {
+		checkin := base.AddDate(0, 2, 0)
+		// Вознаграждение acceptWithReward - 3000 RUB
+		c := f.createCampaign(checkin, func(c *models.Campaign) {
+			c.Budget = ptr(3000)
+			c.Currency = ptr("RUB")
+		})
+		require.NotNil(t, f.findCampaign(checkin))
+
+		guestID := f.createGuest()
+		reportID := f.acceptWithReward(guestID, checkin)
+		var assignmentID uuid.UUID
+		require.NoError(t, f.pool.QueryRow(ctx, `SELECT assignment_id FROM reports WHERE id = $1`, reportID).Scan(&assignmentID))
+		f.addToCampaign(assignmentID, c.ID)
+		assert.Nil(t, f.findCampaign(checkin))
+
+		// Аннулированное вознаграждение бюджет не расходует
+		_, err := f.pool.Exec(ctx, `UPDATE rewards SET status_id = $2 WHERE assignment_id = $1`, assignmentID, models.RewardStatusVoided)
+		require.NoError(t, err)
+		assert.NotNil(t, f.findCampaign(checkin))
+	})
+
+	t.Run("budget in another currency is not spent", func(t *testing.T) {
+		checkin := base.AddDate(0, 4, 0)
+		c := f.createCampaign(checkin, func(c *models.Campaign) {
+			c.Budget = ptr(3000)
+			c.Currency = ptr("EUR")
+		})
+
+		guestID := f.createGuest()
+		reportID := f.acceptWithReward(guestID, checkin)
+		var assignmentID uuid.UUID
+		require.NoError(t, f.pool.QueryRow(ctx, `SELECT assignment_id FROM reports WHERE id = $1`, reportID).Scan(&assignmentID))
+		f.addToCampaign(assignmentID, c.ID)
+		assert.NotNil(t, f.findCampaign(checkin))
+	})
+
+	t.Run("overlapping campaigns", func(t *testing.T) {
+		checkin := base.AddDate(0, 6, 0)
+		later := f.createCampaign(checkin, func(c *models.Campaign) { c.EndsAt = c.EndsAt.AddDate(0, 0, 5) })
+		sooner := f.createCampaign(checkin, func(c *models.Campaign) { c.Quota = ptr(1) })
+
+		// Из подходящих - та, что заканчивается раньше
+		got := f.findCampaign(checkin)
+		require.NotNil(t, got)
+		assert.Equal(t, sooner.ID, *got)
+
+		// После исчерпания ее квоты бронирования идут в следующую
+		f.addToCampaign(f.createFreeAssignment(checkin, 2), sooner.ID)
+		got = f.findCampaign(checkin)
+		require.NotNil(t, got)
+		assert.Equal(t, later.ID, *got)
+	})
+}
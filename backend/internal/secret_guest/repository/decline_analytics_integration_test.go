@@ -0,0 +1,167 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addDecline записывает отказ гостя от предложения в момент at
+func (f *fixture) addDecline(assignmentID, guestID uuid.UUID, reasonID *int, comment *string, at time.Time) {
+	_, err := f.pool.Exec(context.Background(), `
+		INSERT INTO assignment_declines (assignment_id, reporter_id, taked_at, declined_at, reason_id, comment)
+		VALUES ($1, $2, $3, $3, $4, $5)
+	`, assignmentID, guestID, at, reasonID, comment)
+	require.NoError(f.t, err)
+}
+
+// createListingIn создает еще один объект в городе city и свободное предложение по нему
+func (f *fixture) createListingIn(city string, checkin time.Time) (listingID, assignmentID uuid.UUID) {
+	ctx := context.Background()
+	listingID, assignmentID = uuid.New(), uuid.New()
+
+	_, err := f.pool.Exec(ctx, `
+		INSERT INTO listings (id, code, title, listing_type_id, city)
+		SELECT $1, $2, 'Decline analytics listing', listing_type_id, $3 FROM listings WHERE id = $4
+	`, listingID, uuid.New(), city, f.listingID)
+	require.NoError(f.t, err)
+	_, err = f.pool.Exec(ctx, `
+		INSERT INTO assignments (id, checkin_date, checkout_date, listing_id, purpose, expires_at, status_id)
+		VALUES ($1, $2, $3, $4, 'Decline analytics', $2, $5)
+	`, assignmentID, checkin, checkin.AddDate(0, 0, 2), listingID, models.AssignmentStatusOffered)
+	require.NoError(f.t, err)
+
+	f.t.Cleanup(func() {
+		ctx := context.Background()
+		_, _ = f.pool.Exec(ctx, `DELETE FROM assignments WHERE listing_id = $1`, listingID)
+		_, _ = f.pool.Exec(ctx, `DELETE FROM listings WHERE id = $1`, listingID)
+	})
+	return listingID, assignmentID
+}
+
+func TestGetDeclineAnalytics(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	checkin := time.Now().AddDate(1, 0, 0).Truncate(24 * time.Hour)
+	g1, g2, g3 := f.createGuest(), f.createGuest(), f.createGuest()
+
+	// Отказы теста попадают в свое окно времени в прошлом, чтобы не смешиваться с другими данными БД
+	from := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(rand.IntN(200000)) * time.Hour)
+	to := from.Add(time.Hour)
+
+	testcity := f.createFreeAssignment(checkin, 2)
+	f.addDecline(testcity, g1, ptr(models.DeclineReasonDates), nil, from)
+	f.addDecline(testcity, g2, ptr(models.DeclineReasonDates), nil, from.Add(10*time.Minute))
+	f.addDecline(testcity, g1, nil, nil, from.Add(20*time.Minute))
+	// Вне окна
+	f.addDecline(testcity, g3, ptr(models.DeclineReasonLocation), nil, to)
+	f.addDecline(testcity, g3, ptr(models.DeclineReasonLocation), nil, from.Add(-time.Minute))
+
+	otherCity := "Othercity-" + uuid.NewString()[:8]
+	otherListingID, other := f.createListingIn(otherCity, checkin)
+	f.addDecline(other, g3, ptr(models.DeclineReasonOther), ptr("далеко от работы"), from.Add(30*time.Minute))
+
+	analytics := func(groupBy string) (map[string]*models.DeclineStat, int) {
+		stats, total, err := f.repo.GetDeclineAnalytics(ctx, repository.DeclineAnalyticsFilter{GroupBy: groupBy, From: &from, To: &to, Limit: 100})
+		require.NoError(t, err)
+		byKey := make(map[string]*models.DeclineStat, len(stats))
+		for _, st := range stats {
+			byKey[st.Key] = st
+		}
+		return byKey, total
+	}
+
+	t.Run("by reason", func(t *testing.T) {
+		stats, total := analytics(models.DeclineGroupByReason)
+		assert.Equal(t, 3, total)
+		require.Len(t, stats, 3)
+		assert.Equal(t, 2, stats["dates"].Declines)
+		assert.Equal(t, 2, stats["dates"].Guests)
+		assert.Equal(t, 1, stats[models.DeclineReasonUnspecified].Declines)
+		assert.Equal(t, "Не указана", stats[models.DeclineReasonUnspecified].Label)
+		assert.Equal(t, 1, stats["other"].Declines)
+	})
+
+	t.Run("by city", func(t *testing.T) {
+		stats, total := analytics(models.DeclineGroupByCity)
+		assert.Equal(t, 2, total)
+		require.Contains(t, stats, "Testcity")
+		assert.Equal(t, 3, stats["Testcity"].Declines)
+		assert.Equal(t, 1, stats["Testcity"].Assignments)
+		assert.Equal(t, 2, stats["Testcity"].Guests)
+		assert.Equal(t, map[string]int{"dates": 2, models.DeclineReasonUnspecified: 1}, stats["Testcity"].ByReason)
+		require.Contains(t, stats, otherCity)
+		assert.Equal(t, map[string]int{"other": 1}, stats[otherCity].ByReason)
+	})
+
+	t.Run("by listing", func(t *testing.T) {
+		stats, total := analytics(models.DeclineGroupByListing)
+		assert.Equal(t, 2, total)
+		require.Contains(t, stats, f.listingID.String())
+		assert.Equal(t, 3, stats[f.listingID.String()].Declines)
+		require.Contains(t, stats, otherListingID.String())
+		assert.Equal(t, "Decline analytics listing", stats[otherListingID.String()].Label)
+	})
+
+	t.Run("ordered by declines", func(t *testing.T) {
+		stats, _, err := f.repo.GetDeclineAnalytics(ctx, repository.DeclineAnalyticsFilter{GroupBy: models.DeclineGroupByCity, From: &from, To: &to, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		assert.Equal(t, "Testcity", stats[0].Key)
+	})
+
+	t.Run("unknown grouping", func(t *testing.T) {
+		_, _, err := f.repo.GetDeclineAnalytics(ctx, repository.DeclineAnalyticsFilter{GroupBy: "guest", Limit: 10})
+		assert.ErrorIs(t, err, models.ErrValidationFailed)
+	})
+}
+
+func TestGetRepeatedlyDeclinedAssignments(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	checkin := time.Now().AddDate(1, 1, 0).Truncate(24 * time.Hour)
+	g1, g2, g3 := f.createGuest(), f.createGuest(), f.createGuest()
+	at := time.Now().Add(-time.Hour)
+
+	repeated := f.createFreeAssignment(checkin, 2)
+	f.addDecline(repeated, g1, ptr(models.DeclineReasonDates), nil, at)
+	f.addDecline(repeated, g2, ptr(models.DeclineReasonOther), ptr("старый комментарий"), at.Add(time.Minute))
+	f.addDecline(repeated, g3, ptr(models.DeclineReasonOther), ptr("новый комментарий"), at.Add(2*time.Minute))
+
+	once := f.createFreeAssignment(checkin.AddDate(0, 0, 3), 2)
+	f.addDecline(once, g1, nil, nil, at)
+
+	// Срок предложения прошел - менять его уже поздно
+	expired := f.createFreeAssignment(time.Now().AddDate(0, 0, -3).Truncate(24*time.Hour), 2)
+	for _, g := range []uuid.UUID{g1, g2, g3} {
+		f.addDecline(expired, g, nil, nil, at)
+	}
+
+	assignments, total, err := f.repo.GetRepeatedlyDeclinedAssignments(ctx, 3, 10000, 0)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, total, 1)
+
+	found := make(map[uuid.UUID]*models.DeclinedAssignment)
+	for _, a := range assignments {
+		found[a.AssignmentID] = a
+	}
+	assert.NotContains(t, found, once)
+	assert.NotContains(t, found, expired)
+	require.Contains(t, found, repeated)
+
+	a := found[repeated]
+	assert.Equal(t, 3, a.Declines)
+	assert.Equal(t, f.listingID, a.ListingID)
+	assert.Equal(t, "Testcity", a.City)
+	assert.Equal(t, map[string]int{"dates": 1, "other": 2}, a.ByReason)
+	assert.Equal(t, []string{"новый комментарий", "старый комментарий"}, a.Comments)
+}
@@ -0,0 +1,121 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeclineRecordsReason(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	base := time.Now().AddDate(0, 10, 0).Truncate(24 * time.Hour)
+
+	cases := []struct {
+		name     string
+		reasonID *int
+		comment  *string
+	}{
+		{"without reason", nil, nil},
+		{"reason", ptr(models.DeclineReasonDates), nil},
+		{"other with comment", ptr(models.DeclineReasonOther), ptr("уезжаю в командировку")},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			guestID := f.createGuest()
+			assignmentID := f.createFreeAssignment(base.AddDate(0, 0, 3*i), 2)
+			takenAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+			require.NoError(t, f.repo.TakeFreeAssignmentsByID(ctx, assignmentID, guestID, takenAt, nil))
+
+			waitlistUserID, err := f.repo.DeclineMyAssignment(ctx, assignmentID, guestID, time.Now(), tc.reasonID, tc.comment, nil)
+			require.NoError(t, err)
+			assert.Nil(t, waitlistUserID)
+
+			var reasonID *int
+			var comment *string
+			var gotTakenAt time.Time
+			require.NoError(t, f.pool.QueryRow(ctx, `
+				SELECT reason_id, comment, taked_at FROM assignment_declines WHERE assignment_id = $1 AND reporter_id = $2
+			`, assignmentID, guestID).Scan(&reasonID, &comment, &gotTakenAt))
+			assert.Equal(t, tc.reasonID, reasonID)
+			assert.Equal(t, tc.comment, comment)
+			assert.True(t, takenAt.Equal(gotTakenAt))
+
+			// Предложение вернулось в свободные
+			var reporterID *uuid.UUID
+			require.NoError(t, f.pool.QueryRow(ctx, `SELECT reporter_id FROM assignments WHERE id = $1`, assignmentID).Scan(&reporterID))
+			assert.Nil(t, reporterID)
+		})
+	}
+}
+
+func TestDeclineOffersToFirstEligibleWaitlistedGuest(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	base := time.Now().AddDate(0, 11, 0).Truncate(24 * time.Hour)
+
+	holderID := f.createGuest()
+	assignmentID := f.createFreeAssignment(base, 2)
+	require.NoError(t, f.repo.TakeFreeAssignmentsByID(ctx, assignmentID, holderID, time.Now(), nil))
+
+	// Без одобренной заявки
+	notApproved := f.createGuest()
+	// Уже держит другое предложение Offered
+	busy := f.createGuest()
+	f.approveGuest(busy)
+	require.NoError(t, f.repo.TakeFreeAssignmentsByID(ctx, f.createFreeAssignment(base.AddDate(0, 0, 10), 2), busy, time.Now(), nil))
+	// Принятое предложение на пересекающиеся даты
+	overlapping := f.createGuest()
+	f.approveGuest(overlapping)
+	accepted := f.createFreeAssignment(base.AddDate(0, 0, -1), 2)
+	require.NoError(t, f.repo.TakeFreeAssignmentsByID(ctx, accepted, overlapping, time.Now(), nil))
+	_, err := f.repo.AcceptMyAssignment(ctx, accepted, overlapping, time.Now(), 48*time.Hour, nil)
+	require.NoError(t, err)
+	eligible := f.createGuest()
+	f.approveGuest(eligible)
+	next := f.createGuest()
+	f.approveGuest(next)
+
+	for _, userID := range []uuid.UUID{notApproved, busy, overlapping, eligible, next} {
+		_, err := f.repo.JoinAssignmentWaitlist(ctx, assignmentID, userID)
+		require.NoError(t, err)
+	}
+
+	deadline := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	reasonID := models.DeclineReasonLocation
+	waitlistUserID, err := f.repo.DeclineMyAssignment(ctx, assignmentID, holderID, time.Now(), &reasonID, nil, &models.WaitlistOffer{
+		AcceptDeadline: &deadline,
+		Notification:   &models.Notification{Type: models.NotificationWaitlistOffered, Title: "t", Body: "b"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, waitlistUserID)
+	assert.Equal(t, eligible, *waitlistUserID)
+
+	var reporterID uuid.UUID
+	var acceptDeadline time.Time
+	require.NoError(t, f.pool.QueryRow(ctx, `
+		SELECT reporter_id, accept_deadline FROM assignments WHERE id = $1
+	`, assignmentID).Scan(&reporterID, &acceptDeadline))
+	assert.Equal(t, eligible, reporterID)
+	assert.True(t, deadline.Equal(acceptDeadline))
+
+	// Пропущенные гости остаются в листе, получивший предложение - выходит из него
+	var waiting []uuid.UUID
+	rows, err := f.pool.Query(ctx, `SELECT user_id FROM assignment_waitlist WHERE assignment_id = $1 ORDER BY created_at`, assignmentID)
+	require.NoError(t, err)
+	for rows.Next() {
+		var id uuid.UUID
+		require.NoError(t, rows.Scan(&id))
+		waiting = append(waiting, id)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []uuid.UUID{notApproved, busy, overlapping, next}, waiting)
+}
@@ -0,0 +1,109 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createRankedGuest создает гостя с профилем(домашний город, скрытие из таблицы) и очками по дням
+func (f *fixture) createRankedGuest(city string, hidden bool, points map[time.Time]int) uuid.UUID {
+	ctx := context.Background()
+	guestID := f.createGuest()
+
+	info, err := json.Marshal(map[string]any{"home_city": city, "hide_from_leaderboard": hidden})
+	require.NoError(f.t, err)
+	_, err = f.pool.Exec(ctx, `INSERT INTO user_profiles (user_id, additional_info) VALUES ($1, $2)`, guestID, info)
+	require.NoError(f.t, err)
+
+	for day, n := range points {
+		_, err := f.pool.Exec(ctx, `INSERT INTO user_points_daily (user_id, day, points) VALUES ($1, $2, $3)`, guestID, day, n)
+		require.NoError(f.t, err)
+	}
+	return guestID
+}
+
+func TestLeaderboard(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	// Отдельный город теста: таблица лидеров строится по всем гостям БД
+	city := "Leadercity-" + uuid.NewString()[:8]
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	old := today.AddDate(0, 0, -70)
+
+	recent := f.createRankedGuest(city, false, map[time.Time]int{today: 50, old: 100})
+	thisWeek := f.createRankedGuest(strings.ToUpper(city), false, map[time.Time]int{today: 80})
+	veteran := f.createRankedGuest(city, false, map[time.Time]int{old: 150})
+	hidden := f.createRankedGuest(city, true, map[time.Time]int{today: 500})
+	negative := f.createRankedGuest(city, false, map[time.Time]int{today: 10, old: -20})
+	deleted := f.createRankedGuest(city, false, map[time.Time]int{today: 300})
+	_, err := f.pool.Exec(ctx, `UPDATE users SET deleted_at = NOW() WHERE id = $1`, deleted)
+	require.NoError(t, err)
+	f.createRankedGuest("Othercity", false, map[time.Time]int{today: 1000})
+
+	board := func(from *time.Time) ([]*models.LeaderboardEntry, int) {
+		entries, total, err := f.repo.GetLeaderboard(ctx, repository.LeaderboardFilter{From: from, City: city, Limit: 100})
+		require.NoError(t, err)
+		return entries, total
+	}
+	positions := func(entries []*models.LeaderboardEntry) map[uuid.UUID][2]int {
+		m := make(map[uuid.UUID][2]int, len(entries))
+		for _, e := range entries {
+			m[e.UserID] = [2]int{e.Position, e.Points}
+		}
+		return m
+	}
+
+	t.Run("all time", func(t *testing.T) {
+		entries, total := board(nil)
+		assert.Equal(t, 3, total)
+		// Одинаковые очки - одинаковое место
+		assert.Equal(t, map[uuid.UUID][2]int{
+			recent:   {1, 150},
+			veteran:  {1, 150},
+			thisWeek: {3, 80},
+		}, positions(entries))
+	})
+
+	t.Run("period", func(t *testing.T) {
+		from := today.AddDate(0, 0, -7)
+		entries, total := board(&from)
+		assert.Equal(t, 3, total)
+		assert.Equal(t, map[uuid.UUID][2]int{
+			thisWeek: {1, 80},
+			recent:   {2, 50},
+			negative: {3, 10},
+		}, positions(entries))
+	})
+
+	t.Run("own entry", func(t *testing.T) {
+		me, err := f.repo.GetLeaderboardEntry(ctx, repository.LeaderboardFilter{City: city}, thisWeek)
+		require.NoError(t, err)
+		assert.Equal(t, 3, me.Position)
+
+		for _, id := range []uuid.UUID{hidden, deleted, negative} {
+			_, err := f.repo.GetLeaderboardEntry(ctx, repository.LeaderboardFilter{City: city}, id)
+			assert.ErrorIs(t, err, models.ErrNotFound)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		entries, total, err := f.repo.GetLeaderboard(ctx, repository.LeaderboardFilter{City: city, Limit: 1, Offset: 2})
+		require.NoError(t, err)
+		assert.Equal(t, 3, total)
+		require.Len(t, entries, 1)
+		assert.Equal(t, thisWeek, entries[0].UserID)
+	})
+}
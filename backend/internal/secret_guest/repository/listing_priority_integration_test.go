@@ -0,0 +1,66 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addComplaint добавляет жалобу на объект теста, поступившую ago назад
+func (f *fixture) addComplaint(ago time.Duration) {
+	_, err := f.pool.Exec(context.Background(), `
+		INSERT INTO listing_complaints (listing_id, category, received_at) VALUES ($1, 'cleanliness', NOW()::timestamp - make_interval(secs => $2))
+	`, f.listingID, ago.Seconds())
+	require.NoError(f.t, err)
+}
+
+func TestListingPriorityScore(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	var weight int
+	require.NoError(t, f.pool.QueryRow(ctx, `
+		SELECT lt.priority_weight FROM listings l JOIN listing_types lt ON lt.id = l.listing_type_id WHERE l.id = $1
+	`, f.listingID).Scan(&weight))
+	scaled := func(points float64) float64 { return points * float64(weight) / 100 }
+	day := 24 * time.Hour
+
+	// Без отчетов и жалоб: полная давность и половина баллов за качество
+	priority, err := f.repo.GetListingPriority(ctx, f.listingID)
+	require.NoError(t, err)
+	assert.InDelta(t, scaled(models.ListingPriorityRecencyPoints+models.ListingPriorityQualityPoints/2.0), priority.Score, 0.1)
+	assert.Nil(t, priority.LastInspectedAt)
+	assert.Equal(t, weight, priority.ListingTypeWeight)
+
+	// Жалобы учитываются за ListingPriorityComplaintDays дней и не больше ListingPriorityMaxComplaints
+	for i := 0; i <= models.ListingPriorityMaxComplaints; i++ {
+		f.addComplaint(time.Duration(i) * day)
+	}
+	f.addComplaint((models.ListingPriorityComplaintDays + 10) * day)
+	priority, err = f.repo.GetListingPriority(ctx, f.listingID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ListingPriorityMaxComplaints+1, priority.Complaints)
+	assert.InDelta(t, scaled(models.ListingPriorityRecencyPoints+models.ListingPriorityQualityPoints/2.0+models.ListingPriorityComplaintPoints), priority.Score, 0.1)
+
+	// Одобренный отчет половину ListingPriorityStaleDays назад с оценкой 80
+	guestID := f.createGuest()
+	reportID := f.createDraftReport(guestID, time.Now().AddDate(0, 9, 0).Truncate(day), time.Hour)
+	_, err = f.pool.Exec(ctx, fmt.Sprintf(`
+		UPDATE reports SET status_id = $2, quality_score = 80, submitted_at = NOW()::timestamp - INTERVAL '%d days' WHERE id = $1
+	`, models.ListingPriorityStaleDays/2), reportID, models.ReportStatusApproved)
+	require.NoError(t, err)
+
+	priority, err = f.repo.GetListingPriority(ctx, f.listingID)
+	require.NoError(t, err)
+	require.NotNil(t, priority.LastQualityScore)
+	assert.Equal(t, 80, *priority.LastQualityScore)
+	want := models.ListingPriorityRecencyPoints/2.0 + models.ListingPriorityQualityPoints*20/100.0 + models.ListingPriorityComplaintPoints
+	assert.InDelta(t, scaled(want), priority.Score, 0.2)
+}
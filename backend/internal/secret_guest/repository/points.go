@@ -0,0 +1,614 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// point_events
+
+// addDailyPointsQuery добавляет очки вставленных событий(CTE inserted) к счетчикам по дням для таблицы лидеров
+const addDailyPointsQuery = `
+		INSERT INTO user_points_daily (user_id, day, points)
+		SELECT user_id, created_at::date, SUM(points)
+		FROM inserted
+		GROUP BY user_id, created_at::date
+		ON CONFLICT (user_id, day) DO UPDATE SET points = user_points_daily.points + EXCLUDED.points
+	`
+
+// recordAssignmentPointEvent начисляет очки за событие по заданию по текущему весу правила.
+// Повторное событие того же типа по заданию не начисляется.
+func recordAssignmentPointEvent(ctx context.Context, db dbExecutor, userID, assignmentID uuid.UUID, reportID *uuid.UUID, eventType string) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		WITH inserted AS (
+			INSERT INTO point_events (user_id, event_type, points, assignment_id, report_id)
+			SELECT $1, pr.event_type, pr.points, $3, $4
+			FROM point_rules pr
+			WHERE pr.event_type = $2
+			ON CONFLICT DO NOTHING
+			RETURNING user_id, created_at, points
+		)
+		` + addDailyPointsQuery
+	if _, err := db.Exec(ctx, query, userID, eventType, assignmentID, reportID); err != nil {
+		log.Error(ctx, "DB error on recording point event", zap.Error(err), zap.String("event_type", eventType), zap.String("assignment_id", assignmentID.String()))
+		return err
+	}
+	return nil
+}
+
+// recordReportPointEvents начисляет очки автору отчета при смене статуса отчета: сдача(и штраф за опоздание), одобрение, отклонение
+func recordReportPointEvents(ctx context.Context, db dbExecutor, reportID uuid.UUID, reportStatusID int) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var eventTypes []string
+	switch reportStatusID {
+	case models.ReportStatusSubmitted:
+		eventTypes = []string{models.PointEventReportSubmitted, models.PointEventReportLate}
+	case models.ReportStatusApproved:
+		eventTypes = []string{models.PointEventReportApproved}
+	case models.ReportStatusRejected:
+		eventTypes = []string{models.PointEventReportRejected}
+	case models.ReportStatusOverdue:
+		eventTypes = []string{models.PointEventReportOverdue}
+	default:
+		return nil
+	}
+
+	// Штраф за опоздание - только если отчет сдан после срока(в окне report_late)
+	query := `
+		WITH inserted AS (
+			INSERT INTO point_events (user_id, event_type, points, assignment_id, report_id)
+			SELECT r.reporter_id, pr.event_type, pr.points, r.assignment_id, r.id
+			FROM reports r
+			JOIN point_rules pr ON pr.event_type = $2
+			WHERE r.id = $1
+				AND r.reporter_id IS NOT NULL
+				AND (pr.event_type <> $3 OR (r.due_at IS NOT NULL AND r.submitted_at > r.due_at))
+			ON CONFLICT DO NOTHING
+			RETURNING user_id, created_at, points
+		)
+		` + addDailyPointsQuery
+	for _, eventType := range eventTypes {
+		if _, err := db.Exec(ctx, query, reportID, eventType, models.PointEventReportLate); err != nil {
+			log.Error(ctx, "DB error on recording report point event", zap.Error(err), zap.String("event_type", eventType), zap.String("report_id", reportID.String()))
+			return err
+		}
+	}
+	return nil
+}
+
+type PointEventsFilter struct {
+	UserID uuid.UUID
+	Limit  int
+	Offset int
+}
+
+func (r *SecretGuestRepository) GetPointEvents(ctx context.Context, filter PointEventsFilter) ([]*models.PointEvent, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM point_events WHERE user_id = $1`, filter.UserID).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to count point events", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.PointEvent{}, 0, nil
+	}
+
+	query := `
+		SELECT pe.id, pe.user_id, pe.event_type, pr.name, pe.points, pe.assignment_id, pe.report_id, pe.created_at
+		FROM point_events pe
+		JOIN point_rules pr ON pr.event_type = pe.event_type
+		WHERE pe.user_id = $1
+		ORDER BY pe.created_at DESC, pe.id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Query(ctx, query, filter.UserID, filter.Limit, filter.Offset)
+	if err != nil {
+		log.Error(ctx, "Failed to query point events", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	events := make([]*models.PointEvent, 0, filter.Limit)
+	for rows.Next() {
+		var e models.PointEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.EventType, &e.EventName, &e.Points, &e.AssignmentID, &e.ReportID, &e.CreatedAt); err != nil {
+			log.Error(ctx, "Failed to scan point event row", zap.Error(err))
+			return nil, total, err
+		}
+		events = append(events, &e)
+	}
+
+	return events, total, rows.Err()
+}
+
+// GetPointBreakdown - очки гостя по типам событий, в порядке правил
+func (r *SecretGuestRepository) GetPointBreakdown(ctx context.Context, userID uuid.UUID) ([]*models.PointBreakdownItem, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		SELECT pr.event_type, pr.name, COUNT(pe.id), COALESCE(SUM(pe.points), 0)
+		FROM point_rules pr
+		JOIN point_events pe ON pe.event_type = pr.event_type AND pe.user_id = $1
+		GROUP BY pr.event_type, pr.name
+		ORDER BY pr.event_type
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		log.Error(ctx, "Failed to query point breakdown", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*models.PointBreakdownItem{}
+	for rows.Next() {
+		var item models.PointBreakdownItem
+		if err := rows.Scan(&item.EventType, &item.EventName, &item.Count, &item.Points); err != nil {
+			log.Error(ctx, "Failed to scan point breakdown row", zap.Error(err))
+			return nil, err
+		}
+		items = append(items, &item)
+	}
+
+	return items, rows.Err()
+}
+
+// RecomputePoints применяет текущие веса правил ко всей истории начислений, возвращает число измененных событий
+func (r *SecretGuestRepository) RecomputePoints(ctx context.Context, entry *models.AuditLogEntry) (int64, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE point_events pe
+		SET points = pr.points, recomputed_at = CURRENT_TIMESTAMP
+		FROM point_rules pr
+		WHERE pr.event_type = pe.event_type AND pe.points <> pr.points
+	`
+	ct, err := tx.Exec(ctx, query)
+	if err != nil {
+		log.Error(ctx, "DB error on recomputing points", zap.Error(err))
+		return 0, err
+	}
+
+	// Счетчики по дням пересобираются целиком: веса изменились у событий за любые дни
+	if _, err := tx.Exec(ctx, `DELETE FROM user_points_daily`); err != nil {
+		log.Error(ctx, "DB error on clearing daily points", zap.Error(err))
+		return 0, err
+	}
+	dailyQuery := `
+		INSERT INTO user_points_daily (user_id, day, points)
+		SELECT user_id, created_at::date, SUM(points)
+		FROM point_events
+		GROUP BY user_id, created_at::date
+	`
+	if _, err := tx.Exec(ctx, dailyQuery); err != nil {
+		log.Error(ctx, "DB error on rebuilding daily points", zap.Error(err))
+		return 0, err
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// point_rules
+
+func (r *SecretGuestRepository) GetPointRules(ctx context.Context) ([]*models.PointRule, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rows, err := r.db.Query(ctx, `SELECT event_type, name, points, grace_hours, updated_at FROM point_rules ORDER BY event_type`)
+	if err != nil {
+		log.Error(ctx, "Failed to query point rules", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []*models.PointRule{}
+	for rows.Next() {
+		var rule models.PointRule
+		if err := rows.Scan(&rule.EventType, &rule.Name, &rule.Points, &rule.GraceHours, &rule.UpdatedAt); err != nil {
+			log.Error(ctx, "Failed to scan point rule row", zap.Error(err))
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *SecretGuestRepository) GetPointRule(ctx context.Context, eventType string) (*models.PointRule, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var rule models.PointRule
+	err := r.db.QueryRow(ctx,
+		`SELECT event_type, name, points, grace_hours, updated_at FROM point_rules WHERE event_type = $1`,
+		eventType,
+	).Scan(&rule.EventType, &rule.Name, &rule.Points, &rule.GraceHours, &rule.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrPointRuleNotFound
+		}
+		log.Error(ctx, "DB error on getting point rule", zap.Error(err), zap.String("event_type", eventType))
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// UpdatePointRule меняет вес события. Начисленные ранее очки не меняются до пересчета.
+func (r *SecretGuestRepository) UpdatePointRule(ctx context.Context, rule *models.PointRule, actorID uuid.UUID, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE point_rules
+		SET points = $2, grace_hours = $3, updated_at = CURRENT_TIMESTAMP, updated_by = $4
+		WHERE event_type = $1
+	`
+	ct, err := tx.Exec(ctx, query, rule.EventType, rule.Points, rule.GraceHours, actorID)
+	if err != nil {
+		log.Error(ctx, "DB error on updating point rule", zap.Error(err), zap.String("event_type", rule.EventType))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrPointRuleNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// rank_tiers
+
+// GetRankTiers возвращает ранги по возрастанию порога
+func (r *SecretGuestRepository) GetRankTiers(ctx context.Context) ([]*models.RankTier, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rows, err := r.db.Query(ctx, `SELECT id, slug, min_points, names FROM rank_tiers ORDER BY min_points`)
+	if err != nil {
+		log.Error(ctx, "Failed to query rank tiers", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	tiers := []*models.RankTier{}
+	for rows.Next() {
+		var t models.RankTier
+		if err := rows.Scan(&t.ID, &t.Slug, &t.MinPoints, &t.Names); err != nil {
+			log.Error(ctx, "Failed to scan rank tier row", zap.Error(err))
+			return nil, err
+		}
+		tiers = append(tiers, &t)
+	}
+
+	return tiers, rows.Err()
+}
+
+// ReplaceRankTiers заменяет набор рангов целиком
+func (r *SecretGuestRepository) ReplaceRankTiers(ctx context.Context, tiers []*models.RankTier, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM rank_tiers`); err != nil {
+		log.Error(ctx, "DB error on deleting rank tiers", zap.Error(err))
+		return err
+	}
+
+	for _, t := range tiers {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO rank_tiers (slug, min_points, names) VALUES ($1, $2, $3)`,
+			t.Slug, t.MinPoints, t.Names,
+		); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return models.ErrInvalidRankTiers
+			}
+			log.Error(ctx, "DB error on inserting rank tier", zap.Error(err), zap.String("slug", t.Slug))
+			return err
+		}
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// badges
+
+const badgeSelectQuery = `SELECT b.id, b.slug, b.names, COALESCE(b.descriptions, '{}'::jsonb), b.rule, b.is_active, b.created_at, b.updated_at FROM badges b`
+
+func scanBadge(row pgx.Row, b *models.Badge, extra ...any) error {
+	return row.Scan(append([]any{&b.ID, &b.Slug, &b.Names, &b.Descriptions, &b.Rule, &b.IsActive, &b.CreatedAt, &b.UpdatedAt}, extra...)...)
+}
+
+// GetBadges возвращает значки по порядку создания, onlyActive - только выдаваемые
+func (r *SecretGuestRepository) GetBadges(ctx context.Context, onlyActive bool) ([]*models.Badge, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := badgeSelectQuery
+	if onlyActive {
+		query += " WHERE b.is_active"
+	}
+	query += " ORDER BY b.id"
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		log.Error(ctx, "Failed to query badges", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	badges := []*models.Badge{}
+	for rows.Next() {
+		var b models.Badge
+		if err := scanBadge(rows, &b); err != nil {
+			log.Error(ctx, "Failed to scan badge row", zap.Error(err))
+			return nil, err
+		}
+		badges = append(badges, &b)
+	}
+
+	return badges, rows.Err()
+}
+
+func (r *SecretGuestRepository) GetBadgeByID(ctx context.Context, badgeID int) (*models.Badge, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var b models.Badge
+	if err := scanBadge(r.db.QueryRow(ctx, badgeSelectQuery+" WHERE b.id = $1", badgeID), &b); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrBadgeNotFound
+		}
+		log.Error(ctx, "DB error on getting badge", zap.Error(err), zap.Int("badge_id", badgeID))
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+func (r *SecretGuestRepository) CreateBadge(ctx context.Context, badge *models.Badge, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO badges (slug, names, descriptions, rule, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, query, badge.Slug, badge.Names, badge.Descriptions, badge.Rule, badge.IsActive).Scan(&badge.ID, &badge.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.ErrBadgeSlugExists
+		}
+		log.Error(ctx, "DB error on creating badge", zap.Error(err), zap.String("slug", badge.Slug))
+		return err
+	}
+
+	entry.EntityID = strconv.Itoa(badge.ID)
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *SecretGuestRepository) UpdateBadge(ctx context.Context, badge *models.Badge, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE badges
+		SET names = $2, descriptions = $3, rule = $4, is_active = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	ct, err := tx.Exec(ctx, query, badge.ID, badge.Names, badge.Descriptions, badge.Rule, badge.IsActive)
+	if err != nil {
+		log.Error(ctx, "DB error on updating badge", zap.Error(err), zap.Int("badge_id", badge.ID))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrBadgeNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetUserBadges возвращает полученные значки гостей, новые сверху
+func (r *SecretGuestRepository) GetUserBadges(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]*models.UserBadge, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	result := make(map[uuid.UUID][]*models.UserBadge, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	query := `
+		SELECT b.id, b.slug, b.names, COALESCE(b.descriptions, '{}'::jsonb), b.rule, b.is_active, b.created_at, b.updated_at, ub.earned_at, ub.user_id
+		FROM user_badges ub
+		JOIN badges b ON b.id = ub.badge_id
+		WHERE ub.user_id = ANY($1)
+		ORDER BY ub.earned_at DESC, b.id
+	`
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err != nil {
+		log.Error(ctx, "Failed to query user badges", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ub models.UserBadge
+		var userID uuid.UUID
+		if err := scanBadge(rows, &ub.Badge, &ub.EarnedAt, &userID); err != nil {
+			log.Error(ctx, "Failed to scan user badge row", zap.Error(err))
+			return nil, err
+		}
+		result[userID] = append(result[userID], &ub)
+	}
+
+	return result, rows.Err()
+}
+
+// AwardBadges выдает значки гостю, уже полученные не меняются. Возвращает id выданных сейчас значков.
+func (r *SecretGuestRepository) AwardBadges(ctx context.Context, userID uuid.UUID, badgeIDs []int) ([]int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		INSERT INTO user_badges (user_id, badge_id)
+		SELECT $1, unnest($2::integer[])
+		ON CONFLICT DO NOTHING
+		RETURNING badge_id
+	`
+	rows, err := r.db.Query(ctx, query, userID, badgeIDs)
+	if err != nil {
+		log.Error(ctx, "DB error on awarding badges", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	awarded := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		awarded = append(awarded, id)
+	}
+
+	return awarded, rows.Err()
+}
+
+// GetBadgeMetrics считает показатели гостя для условий значков по истории событий
+func (r *SecretGuestRepository) GetBadgeMetrics(ctx context.Context, userID uuid.UUID) (map[string]int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE pe.event_type = $2),
+			COUNT(*) FILTER (WHERE pe.event_type = $3),
+			COUNT(*) FILTER (WHERE pe.event_type = $4),
+			COUNT(*) FILTER (WHERE pe.event_type = $5),
+			COUNT(*) FILTER (WHERE pe.event_type = $6),
+			COUNT(*) FILTER (WHERE pe.event_type = $4 AND NOT EXISTS (
+				SELECT 1 FROM point_events late
+				WHERE late.user_id = pe.user_id AND late.assignment_id = pe.assignment_id AND late.event_type = $6
+			)),
+			COUNT(DISTINCT lower(l.city)) FILTER (WHERE pe.event_type = $4 AND l.city <> ''),
+			COUNT(*) FILTER (WHERE pe.event_type = $4 AND pe.created_at > COALESCE(
+				(SELECT MAX(rej.created_at) FROM point_events rej WHERE rej.user_id = pe.user_id AND rej.event_type = $5),
+				'-infinity'::timestamp
+			)),
+			COALESCE(SUM(pe.points), 0),
+			COALESCE(MAX(r.quality_score) FILTER (WHERE pe.event_type = $4), 0)
+		FROM point_events pe
+		LEFT JOIN reports r ON r.id = pe.report_id
+		LEFT JOIN listings l ON l.id = r.listing_id
+		WHERE pe.user_id = $1
+	`
+
+	var accepted, submitted, approved, rejected, late, onTime, cities, streak, points, maxQuality int
+	err := r.db.QueryRow(ctx, query, userID,
+		models.PointEventAssignmentAccepted,
+		models.PointEventReportSubmitted,
+		models.PointEventReportApproved,
+		models.PointEventReportRejected,
+		models.PointEventReportLate,
+	).Scan(&accepted, &submitted, &approved, &rejected, &late, &onTime, &cities, &streak, &points, &maxQuality)
+	if err != nil {
+		log.Error(ctx, "DB error on getting badge metrics", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, err
+	}
+
+	return map[string]int{
+		models.BadgeMetricAcceptedAssignments:   accepted,
+		models.BadgeMetricSubmittedReports:      submitted,
+		models.BadgeMetricApprovedReports:       approved,
+		models.BadgeMetricRejectedReports:       rejected,
+		models.BadgeMetricLateReports:           late,
+		models.BadgeMetricOnTimeApprovedReports: onTime,
+		models.BadgeMetricCities:                cities,
+		models.BadgeMetricApprovedStreak:        streak,
+		models.BadgeMetricPoints:                points,
+		models.BadgeMetricMaxQualityScore:       maxQuality,
+	}, nil
+}
+
+// GetUsersWithPointEvents - гости, у которых есть история событий(для перепроверки значков)
+func (r *SecretGuestRepository) GetUsersWithPointEvents(ctx context.Context) ([]uuid.UUID, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rows, err := r.db.Query(ctx, `SELECT DISTINCT user_id FROM point_events`)
+	if err != nil {
+		log.Error(ctx, "Failed to query users with point events", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
+}
@@ -0,0 +1,69 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setPointRule меняет вес события на время теста
+func (f *fixture) setPointRule(eventType string, points int) {
+	ctx := context.Background()
+	rule, err := f.repo.GetPointRule(ctx, eventType)
+	require.NoError(f.t, err)
+
+	actorID := f.createGuest()
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionPointRuleUpdated, models.AuditEntityPointRule, eventType, nil)
+	require.NoError(f.t, f.repo.UpdatePointRule(ctx, &models.PointRule{EventType: eventType, Points: points, GraceHours: rule.GraceHours}, actorID, entry))
+	f.t.Cleanup(func() {
+		_, _ = f.pool.Exec(context.Background(), `
+			UPDATE point_rules SET points = $2, grace_hours = $3, updated_by = NULL WHERE event_type = $1
+		`, eventType, rule.Points, rule.GraceHours)
+	})
+}
+
+func TestPointHistoryUsesEditableRules(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	f.setPointRule(models.PointEventAssignmentAccepted, 7)
+	f.setPointRule(models.PointEventReportSubmitted, 11)
+
+	guestID := f.createGuest()
+	reportID := f.createDraftReport(guestID, time.Now().AddDate(0, 8, 0).Truncate(24*time.Hour), time.Hour)
+	require.NoError(t, f.repo.UpdateMyReportStatus(ctx, reportID, guestID, models.ReportStatusDraft, models.ReportStatusSubmitted))
+
+	// Начисление берет вес правила на момент события
+	events, total, err := f.repo.GetPointEvents(ctx, repository.PointEventsFilter{UserID: guestID, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+	points := map[string]int{}
+	for _, e := range events {
+		points[e.EventType] = e.Points
+		assert.Equal(t, reportID, *e.ReportID)
+	}
+	assert.Equal(t, map[string]int{models.PointEventAssignmentAccepted: 7, models.PointEventReportSubmitted: 11}, points)
+
+	// Новый вес не меняет уже начисленные очки без пересчета
+	f.setPointRule(models.PointEventReportSubmitted, 20)
+	breakdown, err := f.repo.GetPointBreakdown(ctx, guestID)
+	require.NoError(t, err)
+	sum := 0
+	for _, item := range breakdown {
+		assert.Equal(t, 1, item.Count)
+		sum += item.Points
+	}
+	assert.Equal(t, 18, sum)
+
+	// Повторная запись того же события не дублирует начисление
+	_, err = f.pool.Exec(ctx, `UPDATE reports SET status_id = $2 WHERE id = $1`, reportID, models.ReportStatusDraft)
+	require.NoError(t, err)
+	require.NoError(t, f.repo.UpdateMyReportStatus(ctx, reportID, guestID, models.ReportStatusDraft, models.ReportStatusSubmitted))
+	assert.Equal(t, 1, f.pointEvents(reportID, models.PointEventReportSubmitted))
+}
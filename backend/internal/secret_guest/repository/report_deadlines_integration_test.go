@@ -0,0 +1,178 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createDraftReport создает принятое предложение гостя с черновиком отчета, срок сдачи которого - dueIn от текущего
+// времени БД(отрицательный - срок прошел)
+func (f *fixture) createDraftReport(guestID uuid.UUID, checkin time.Time, dueIn time.Duration) uuid.UUID {
+	ctx := context.Background()
+
+	assignmentID := f.createFreeAssignment(checkin, 2)
+	require.NoError(f.t, f.repo.TakeFreeAssignmentsByID(ctx, assignmentID, guestID, time.Now(), nil))
+	report, err := f.repo.AcceptMyAssignment(ctx, assignmentID, guestID, time.Now(), 48*time.Hour, nil)
+	require.NoError(f.t, err)
+
+	_, err = f.pool.Exec(ctx, `
+		UPDATE reports SET status_id = $2, due_at = NOW()::timestamp + make_interval(secs => $3) WHERE id = $1
+	`, report.ID, models.ReportStatusDraft, dueIn.Seconds())
+	require.NoError(f.t, err)
+
+	return report.ID
+}
+
+// lateWindow - окно поздней сдачи после срока(grace_hours правила report_late)
+func (f *fixture) lateWindow() time.Duration {
+	var hours *int
+	require.NoError(f.t, f.pool.QueryRow(context.Background(),
+		`SELECT grace_hours FROM point_rules WHERE event_type = $1`, models.PointEventReportLate,
+	).Scan(&hours))
+	if hours == nil {
+		return 0
+	}
+	return time.Duration(*hours) * time.Hour
+}
+
+// dbNow - текущее время БД в том же виде, что и timestamp-колонки
+func (f *fixture) dbNow() time.Time {
+	var now time.Time
+	require.NoError(f.t, f.pool.QueryRow(context.Background(), `SELECT NOW()::timestamp`).Scan(&now))
+	return now
+}
+
+func (f *fixture) pointEvents(reportID uuid.UUID, eventType string) int {
+	var n int
+	require.NoError(f.t, f.pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM point_events WHERE report_id = $1 AND event_type = $2`, reportID, eventType,
+	).Scan(&n))
+	return n
+}
+
+func TestSubmitReportDeadline(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	window := f.lateWindow()
+	base := time.Now().AddDate(0, 4, 0).Truncate(24 * time.Hour)
+
+	cases := []struct {
+		name     string
+		dueIn    time.Duration
+		wantErr  error
+		wantLate int
+	}{
+		{"before due date", time.Hour, nil, 0},
+		{"late within the window", -time.Minute, nil, 1},
+		{"after the late window", -window - time.Hour, models.ErrReportOverdue, 0},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			guestID := f.createGuest()
+			reportID := f.createDraftReport(guestID, base.AddDate(0, 0, 3*i), tc.dueIn)
+
+			err := f.repo.UpdateMyReportStatus(ctx, reportID, guestID, models.ReportStatusDraft, models.ReportStatusSubmitted)
+
+			var statusID int
+			require.NoError(t, f.pool.QueryRow(ctx, `SELECT status_id FROM reports WHERE id = $1`, reportID).Scan(&statusID))
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.Equal(t, models.ReportStatusDraft, statusID)
+				assert.Equal(t, 0, f.pointEvents(reportID, models.PointEventReportSubmitted))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, models.ReportStatusSubmitted, statusID)
+			assert.Equal(t, 1, f.pointEvents(reportID, models.PointEventReportSubmitted))
+			assert.Equal(t, tc.wantLate, f.pointEvents(reportID, models.PointEventReportLate))
+		})
+	}
+
+	t.Run("not a draft", func(t *testing.T) {
+		guestID := f.createGuest()
+		reportID := f.createDraftReport(guestID, base.AddDate(0, 0, 30), -window-time.Hour)
+		_, err := f.pool.Exec(ctx, `UPDATE reports SET status_id = $2 WHERE id = $1`, reportID, models.ReportStatusRefused)
+		require.NoError(t, err)
+
+		err = f.repo.UpdateMyReportStatus(ctx, reportID, guestID, models.ReportStatusDraft, models.ReportStatusSubmitted)
+		assert.ErrorIs(t, err, models.ErrReportNotEditable)
+	})
+}
+
+func TestOverdueReportsAfterLateWindow(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	window := f.lateWindow()
+	base := time.Now().AddDate(0, 5, 0).Truncate(24 * time.Hour)
+
+	guestID := f.createGuest()
+	inWindow := f.createDraftReport(guestID, base, -time.Minute)
+	overdue := f.createDraftReport(guestID, base.AddDate(0, 0, 3), -window-time.Hour)
+
+	reports, err := f.repo.GetOverdueReports(ctx, f.dbNow(), 1000)
+	require.NoError(t, err)
+	found := map[uuid.UUID]bool{}
+	for _, r := range reports {
+		found[r.ReportID] = true
+	}
+	assert.True(t, found[overdue])
+	assert.False(t, found[inWindow])
+
+	notification := &models.Notification{UserID: guestID, Type: models.NotificationReportOverdue, Title: "t", Body: "b"}
+
+	// Черновик в окне поздней сдачи не переводится, даже если его передали
+	assert.ErrorIs(t, f.repo.MarkReportOverdue(ctx, inWindow, f.dbNow(), notification), models.ErrReportNotFound)
+
+	require.NoError(t, f.repo.MarkReportOverdue(ctx, overdue, f.dbNow(), notification))
+
+	var statusID int
+	require.NoError(t, f.pool.QueryRow(ctx, `SELECT status_id FROM reports WHERE id = $1`, overdue).Scan(&statusID))
+	assert.Equal(t, models.ReportStatusOverdue, statusID)
+	assert.Equal(t, 1, f.pointEvents(overdue, models.PointEventReportOverdue))
+
+	// Повторный проход не переводит отчет второй раз
+	assert.ErrorIs(t, f.repo.MarkReportOverdue(ctx, overdue, f.dbNow(), notification), models.ErrReportNotFound)
+}
+
+func TestBackfillReportDueDates(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	base := time.Now().AddDate(0, 6, 0).Truncate(24 * time.Hour)
+
+	guestID := f.createGuest()
+	draft := f.createDraftReport(guestID, base, time.Hour)
+	submitted := f.createDraftReport(guestID, base.AddDate(0, 0, 3), time.Hour)
+	_, err := f.pool.Exec(ctx, `UPDATE reports SET status_id = $2 WHERE id = $1`, submitted, models.ReportStatusSubmitted)
+	require.NoError(t, err)
+	_, err = f.pool.Exec(ctx, `UPDATE reports SET due_at = NULL WHERE id = ANY($1)`, []uuid.UUID{draft, submitted})
+	require.NoError(t, err)
+
+	// Выезд черновика в будущем: срок - от даты выезда, как у новых отчетов
+	now := f.dbNow()
+	updated, err := f.repo.BackfillReportDueDates(ctx, now, 36*time.Hour)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, updated, int64(2))
+
+	var draftDue, draftCheckout, submittedDue, submittedCheckout time.Time
+	require.NoError(t, f.pool.QueryRow(ctx, `SELECT due_at, checkout_date FROM reports WHERE id = $1`, draft).Scan(&draftDue, &draftCheckout))
+	require.NoError(t, f.pool.QueryRow(ctx, `SELECT due_at, checkout_date FROM reports WHERE id = $1`, submitted).Scan(&submittedDue, &submittedCheckout))
+	assert.True(t, draftCheckout.Add(36*time.Hour).Equal(draftDue))
+	assert.True(t, submittedCheckout.Add(36*time.Hour).Equal(submittedDue))
+
+	// Черновик с прошедшим выездом получает полный срок от текущего момента
+	_, err = f.pool.Exec(ctx, `UPDATE reports SET due_at = NULL, checkout_date = $2 WHERE id = $1`, draft, now.AddDate(0, 0, -10))
+	require.NoError(t, err)
+	_, err = f.repo.BackfillReportDueDates(ctx, now, 36*time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, f.pool.QueryRow(ctx, `SELECT due_at FROM reports WHERE id = $1`, draft).Scan(&draftDue))
+	assert.True(t, now.Add(36*time.Hour).Equal(draftDue))
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -453,6 +454,51 @@ type AssignmentsFilter struct {
 	Limit          int
 	Offset         int
 	City           string
+
+	// Ranking - предпочтения гостя из профиля, по которым сортируются свободные предложения
+	Ranking *AssignmentRanking
+}
+
+// AssignmentRanking - веса: даты поездки попадают в период доступности гостя(4),
+// предпочитаемый тип объекта(2), объект в домашнем городе гостя(1)
+type AssignmentRanking struct {
+	HomeCity       string
+	ListingTypeIDs []int
+	AvailableFrom  []time.Time
+	AvailableTo    []time.Time
+}
+
+// buildAssignmentRankingScore возвращает SQL-выражение оценки предложения(пустое, если предпочтений нет)
+func buildAssignmentRankingScore(ranking *AssignmentRanking, paramCount int) (string, []interface{}, int) {
+	if ranking == nil {
+		return "", nil, paramCount
+	}
+
+	terms := []string{}
+	args := []interface{}{}
+
+	if len(ranking.AvailableFrom) > 0 && len(ranking.AvailableFrom) == len(ranking.AvailableTo) {
+		terms = append(terms, fmt.Sprintf(`CASE WHEN EXISTS (
+			SELECT 1 FROM unnest($%d::date[], $%d::date[]) AS p(date_from, date_to)
+			WHERE a.checkin_date::date >= p.date_from AND a.checkout_date::date <= p.date_to
+		) THEN 4 ELSE 0 END`, paramCount, paramCount+1))
+		args = append(args, ranking.AvailableFrom, ranking.AvailableTo)
+		paramCount += 2
+	}
+
+	if len(ranking.ListingTypeIDs) > 0 {
+		terms = append(terms, fmt.Sprintf("CASE WHEN l.listing_type_id = ANY($%d) THEN 2 ELSE 0 END", paramCount))
+		args = append(args, ranking.ListingTypeIDs)
+		paramCount++
+	}
+
+	if ranking.HomeCity != "" {
+		terms = append(terms, fmt.Sprintf("CASE WHEN lower(l.city) = lower($%d) THEN 1 ELSE 0 END", paramCount))
+		args = append(args, ranking.HomeCity)
+		paramCount++
+	}
+
+	return strings.Join(terms, " + "), args, paramCount
 }
 
 func buildAssignmentWhereClause(filter AssignmentsFilter) (string, []interface{}, int) {
@@ -519,6 +565,7 @@ func (r *SecretGuestRepository) scanAssignment(row pgx.Row) (*models.Assignment,
 		&a.AcceptedAt,
 
 		&a.TakedAt,
+		&a.CreatedBy, &a.InvitedAt, &a.AcceptDeadline, &a.CampaignID,
 
 		&a.Listing.ID,
 		&a.Listing.Code,
@@ -560,8 +607,9 @@ func (r *SecretGuestRepository) CreateAssignment(ctx context.Context, assignment
 			listing_id,
 			purpose,
 			created_at,
-			expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			expires_at,
+			campaign_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id;
 	`
 
@@ -576,6 +624,7 @@ func (r *SecretGuestRepository) CreateAssignment(ctx context.Context, assignment
 		assignment.Purpose,
 		assignment.CreatedAt,
 		assignment.ExpiresAt,
+		assignment.CampaignID,
 	)
 
 	var id uuid.UUID
@@ -588,6 +637,108 @@ func (r *SecretGuestRepository) CreateAssignment(ctx context.Context, assignment
 	return id, nil
 }
 
+// CreateManualAssignment создает предложение персонала. Если задан reporter_id, предложение сразу закреплено за гостем,
+// а гостю отправляется уведомление(notification)
+func (r *SecretGuestRepository) CreateManualAssignment(ctx context.Context, assignment *models.Assignment, notification *models.Notification, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var reporterID *uuid.UUID
+	if assignment.ReporterID != uuid.Nil {
+		reporterID = &assignment.ReporterID
+
+		if err := lockGuestAssignments(ctx, tx, assignment.ReporterID); err != nil {
+			return err
+		}
+
+		// Как при взятии свободного предложения: у гостя одно активное предложение
+		var count int
+		err = tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM assignments WHERE reporter_id = $1 AND status_id = $2
+		`, assignment.ReporterID, models.AssignmentStatusOffered).Scan(&count)
+		if err != nil {
+			log.Error(ctx, "Failed to check existing offered assignments", zap.Error(err))
+			return err
+		}
+		if count > 0 {
+			return models.ErrGuestHasActiveAssignment
+		}
+
+		if err := checkAssignmentOverlap(ctx, tx, assignment.ID, assignment.ReporterID, assignment.CheckinDate, assignment.CheckoutDate); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO assignments (
+			id,
+			checkin_date,
+			checkout_date,
+
+			listing_id,
+			purpose,
+			created_at,
+			expires_at,
+
+			reporter_id,
+			taked_at,
+			created_by,
+			invited_at,
+			accept_deadline,
+			campaign_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+	_, err = tx.Exec(ctx, query,
+		assignment.ID,
+		assignment.CheckinDate,
+		assignment.CheckoutDate,
+
+		assignment.ListingID,
+		assignment.Purpose,
+		assignment.CreatedAt,
+		assignment.ExpiresAt,
+
+		reporterID,
+		assignment.TakedAt,
+		assignment.CreatedBy,
+		assignment.InvitedAt,
+		assignment.AcceptDeadline,
+		assignment.CampaignID,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ErrForeignKeyViolation
+		}
+		if errors.As(err, &pgErr) && pgErr.Code == "23P01" { // exclusion_violation
+			return &models.AssignmentOverlapError{}
+		}
+		if guestOfferedViolation(err) {
+			return models.ErrGuestHasActiveAssignment
+		}
+		log.Error(ctx, "Failed to create manual assignment", zap.Error(err), zap.String("listing_id", assignment.ListingID.String()))
+		return err
+	}
+
+	if notification != nil {
+		if err := insertNotification(ctx, tx, notification); err != nil {
+			return err
+		}
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (r *SecretGuestRepository) GetAssignments(ctx context.Context, filter AssignmentsFilter) ([]*models.Assignment, int, error) {
 
 	log := logger.GetLoggerFromCtx(ctx)
@@ -635,6 +786,7 @@ func (r *SecretGuestRepository) GetAssignments(ctx context.Context, filter Assig
 			a.accepted_at,
 
 			a.taked_at,
+			a.created_by, a.invited_at, a.accept_deadline, a.campaign_id,
 
 			l.code as "listing_code",
 			l.title as "listing_title",
@@ -705,6 +857,7 @@ func (r *SecretGuestRepository) GetAssignments(ctx context.Context, filter Assig
 			&a.AcceptedAt,
 
 			&a.TakedAt,
+			&a.CreatedBy, &a.InvitedAt, &a.AcceptDeadline, &a.CampaignID,
 
 			&a.Listing.Code,
 			&a.Listing.Title,
@@ -795,6 +948,7 @@ func (r *SecretGuestRepository) GetFreeAssignments(ctx context.Context, filter A
 			a.accepted_at,
 
 			a.taked_at,
+			a.created_by, a.invited_at, a.accept_deadline, a.campaign_id,
 
 			l.id,
 			l.code as "listing_code",
@@ -836,7 +990,15 @@ func (r *SecretGuestRepository) GetFreeAssignments(ctx context.Context, filter A
         args = append(args, "%" + filter.City + "%")
         paramCount++
     }
-	query += fmt.Sprintf(" ORDER BY a.created_at DESC LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+
+	orderBy := "a.created_at DESC"
+	score, scoreArgs, paramCount := buildAssignmentRankingScore(filter.Ranking, paramCount)
+	if score != "" {
+		orderBy = "(" + score + ") DESC, " + orderBy
+		args = append(args, scoreArgs...)
+	}
+
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", orderBy, paramCount, paramCount+1)
 	args = append(args, filter.Limit, filter.Offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
@@ -866,6 +1028,7 @@ func (r *SecretGuestRepository) GetFreeAssignments(ctx context.Context, filter A
 			&a.AcceptedAt,
 
 			&a.TakedAt,
+			&a.CreatedBy, &a.InvitedAt, &a.AcceptDeadline, &a.CampaignID,
 
 			&a.Listing.ID,
 			&a.Listing.Code,
@@ -920,6 +1083,7 @@ func (r *SecretGuestRepository) GetAssignmentByID(ctx context.Context, assignmen
 			a.listing_id, a.reporter_id, a.status_id,
 			a.purpose, a.created_at, a.expires_at, a.accepted_at,
 			a.taked_at,
+			a.created_by, a.invited_at, a.accept_deadline, a.campaign_id,
 
 			l.id,
 			l.code as "listing_code",
@@ -958,19 +1122,46 @@ func (r *SecretGuestRepository) GetAssignmentByID(ctx context.Context, assignmen
 	return assignment, nil
 }
 
-func (r *SecretGuestRepository) CancelAssignment(ctx context.Context, assignmentID uuid.UUID) error {
+// CancelAssignment отменяет предложение Offered или Accepted, убирает его лист ожидания и уведомляет гостя,
+// если предложение было за ним закреплено(notification.UserID заполняется здесь)
+func (r *SecretGuestRepository) CancelAssignment(ctx context.Context, assignmentID uuid.UUID, notification *models.Notification, entry *models.AuditLogEntry) error {
 	log := logger.GetLoggerFromCtx(ctx)
 
-	updateQuery := `
-		UPDATE assignments SET status_id = $1 WHERE id = $2
-	`
-
-	ct, err := r.db.Exec(ctx, updateQuery,
-		models.AssignmentStatusCancelled, // new assignment status
-		assignmentID,
-	)
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
 
+	var reporterID *uuid.UUID
+	err = tx.QueryRow(ctx, `
+		WITH prev AS (
+			SELECT id, reporter_id
+			FROM assignments
+			WHERE id = $1 AND status_id = ANY($2)
+			FOR UPDATE
+		)
+		UPDATE assignments a
+		SET status_id = $3
+		FROM prev
+		WHERE a.id = prev.id
+		RETURNING prev.reporter_id
+	`, assignmentID, []int{models.AssignmentStatusOffered, models.AssignmentStatusAccepted}, models.AssignmentStatusCancelled).Scan(&reporterID)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM assignments WHERE id = $1)`, assignmentID).Scan(&exists); err != nil {
+				return err
+			}
+			if !exists {
+				log.Warn(ctx, "Attempt to cancel assignment failed: assignment not found",
+					zap.String("assignment_id", assignmentID.String()),
+				)
+				return models.ErrAssignmentNotFound
+			}
+			return models.ErrAssignmentCannotBeCancelled
+		}
 		log.Error(ctx, "DB error on cancelling assignment",
 			zap.Error(err),
 			zap.String("assignment_id", assignmentID.String()),
@@ -978,14 +1169,282 @@ func (r *SecretGuestRepository) CancelAssignment(ctx context.Context, assignment
 		return err
 	}
 
-	if ct.RowsAffected() == 0 {
-		log.Warn(ctx, "Attempt to cancel assignment failed: assignment not found",
-			zap.String("assignment_id", assignmentID.String()),
+	if _, err := tx.Exec(ctx, `DELETE FROM assignment_waitlist WHERE assignment_id = $1`, assignmentID); err != nil {
+		log.Error(ctx, "Failed to clear waitlist of cancelled assignment", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return err
+	}
+
+	if notification != nil && reporterID != nil {
+		notification.UserID = *reporterID
+		if err := insertNotification(ctx, tx, notification); err != nil {
+			return err
+		}
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReleaseAssignment снимает гостя с закрепленного за ним предложения Offered(персонал освобождает для другого гостя),
+// уведомляет его и предлагает предложение следующему гостю из листа ожидания. Возвращает выбранного из листа гостя или nil
+func (r *SecretGuestRepository) ReleaseAssignment(ctx context.Context, assignmentID uuid.UUID, notification *models.Notification, offer *models.WaitlistOffer, entry *models.AuditLogEntry) (*uuid.UUID, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var reporterID uuid.UUID
+	err = tx.QueryRow(ctx, `
+		WITH prev AS (
+			SELECT id, reporter_id
+			FROM assignments
+			WHERE id = $1 AND status_id = $2 AND reporter_id IS NOT NULL
+			FOR UPDATE
 		)
-		return models.ErrAssignmentNotFound
+		UPDATE assignments a
+		SET
+			reporter_id     = NULL,
+			taked_at        = NULL,
+			invited_at      = NULL,
+			accept_deadline = NULL
+		FROM prev
+		WHERE a.id = prev.id
+		RETURNING prev.reporter_id
+	`, assignmentID, models.AssignmentStatusOffered).Scan(&reporterID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrAssignmentNotHeld
+		}
+		log.Error(ctx, "DB error on releasing assignment", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return nil, err
 	}
 
-	return nil
+	if notification != nil {
+		notification.UserID = reporterID
+		if err := insertNotification(ctx, tx, notification); err != nil {
+			return nil, err
+		}
+	}
+
+	waitlistUserID, err := offerAssignmentToWaitlist(ctx, tx, assignmentID, offer, time.Now())
+	if err != nil {
+		log.Error(ctx, "Failed to offer released assignment to waitlist", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return nil, err
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return waitlistUserID, nil
+}
+
+// ReassignAssignment закрепляет открытое предложение Offered за другим гостем как приглашение со сроком принятия acceptDeadline.
+// Прежний гость(если был) получает released, новый - invited(UserID уведомлений заполняются здесь). Возвращает прежнего гостя или nil
+func (r *SecretGuestRepository) ReassignAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, acceptDeadline *time.Time, now time.Time,
+	released, invited *models.Notification, entry *models.AuditLogEntry) (*uuid.UUID, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockGuestAssignments(ctx, tx, reporterID); err != nil {
+		return nil, err
+	}
+
+	var previousID *uuid.UUID
+	var checkin, checkout time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT reporter_id, checkin_date, checkout_date
+		FROM assignments
+		WHERE id = $1 AND status_id = $2 AND expires_at > $3
+		FOR UPDATE
+	`, assignmentID, models.AssignmentStatusOffered, now).Scan(&previousID, &checkin, &checkout)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrAssignmentCannotBeReassigned
+		}
+		log.Error(ctx, "Failed to get assignment for reassignment", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return nil, err
+	}
+	if previousID != nil && *previousID == reporterID {
+		return nil, fmt.Errorf("%w: assignment is already held by this guest", models.ErrAssignmentCannotBeReassigned)
+	}
+
+	// Как при приглашении: у гостя одно активное предложение и без пересечения дат
+	var count int
+	err = tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM assignments WHERE reporter_id = $1 AND status_id = $2
+	`, reporterID, models.AssignmentStatusOffered).Scan(&count)
+	if err != nil {
+		log.Error(ctx, "Failed to check existing offered assignments", zap.Error(err))
+		return nil, err
+	}
+	if count > 0 {
+		return nil, models.ErrGuestHasActiveAssignment
+	}
+	if err := checkAssignmentOverlap(ctx, tx, assignmentID, reporterID, checkin, checkout); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE assignments
+		SET
+			reporter_id     = $2,
+			taked_at        = $3,
+			invited_at      = $3,
+			accept_deadline = $4
+		WHERE id = $1
+	`, assignmentID, reporterID, now, acceptDeadline)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23P01" { // exclusion_violation
+			return nil, &models.AssignmentOverlapError{}
+		}
+		if guestOfferedViolation(err) {
+			return nil, models.ErrGuestHasActiveAssignment
+		}
+		log.Error(ctx, "DB error on reassigning assignment", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM assignment_waitlist WHERE assignment_id = $1 AND user_id = $2`, assignmentID, reporterID); err != nil {
+		log.Error(ctx, "Failed to remove guest from waitlist", zap.Error(err))
+		return nil, err
+	}
+
+	if released != nil && previousID != nil {
+		released.UserID = *previousID
+		if err := insertNotification(ctx, tx, released); err != nil {
+			return nil, err
+		}
+	}
+	if invited != nil {
+		invited.UserID = reporterID
+		if err := insertNotification(ctx, tx, invited); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return previousID, nil
+}
+
+// ExtendAssignment переносит срок действия(expires_at) предложения Offered и в том же обновлении - срок принятия
+// acceptDeadline. reporterID - гость, для которого посчитан срок(nil - свободное предложение): если предложение
+// закреплено за другим, возвращается ErrAssignmentCannotBeExtended
+func (r *SecretGuestRepository) ExtendAssignment(ctx context.Context, assignmentID uuid.UUID, reporterID *uuid.UUID, expiresAt time.Time, acceptDeadline *time.Time, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE assignments SET expires_at = $3, accept_deadline = $4
+		WHERE id = $1 AND status_id = $2 AND reporter_id IS NOT DISTINCT FROM $5
+	`, assignmentID, models.AssignmentStatusOffered, expiresAt, acceptDeadline, reporterID)
+	if err != nil {
+		log.Error(ctx, "DB error on extending assignment", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrAssignmentCannotBeExtended
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// BulkAssignmentsFilter - отбор предложений для массовой операции персонала
+type BulkAssignmentsFilter struct {
+	StatusIDs      []int
+	ListingTypeIDs []int
+	City           string
+	ReporterID     *uuid.UUID
+	CampaignID     *uuid.UUID
+	ExpiresBefore  *time.Time
+}
+
+// GetAssignmentIDs - идентификаторы предложений по фильтру, не больше limit, по дате создания
+func (r *SecretGuestRepository) GetAssignmentIDs(ctx context.Context, filter BulkAssignmentsFilter, limit int) ([]uuid.UUID, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	conditions := []string{}
+	args := []any{}
+	if len(filter.StatusIDs) > 0 {
+		args = append(args, filter.StatusIDs)
+		conditions = append(conditions, fmt.Sprintf("a.status_id = ANY($%d)", len(args)))
+	}
+	if len(filter.ListingTypeIDs) > 0 {
+		args = append(args, filter.ListingTypeIDs)
+		conditions = append(conditions, fmt.Sprintf("l.listing_type_id = ANY($%d)", len(args)))
+	}
+	if filter.City != "" {
+		args = append(args, "%"+filter.City+"%")
+		conditions = append(conditions, fmt.Sprintf("l.city ILIKE $%d", len(args)))
+	}
+	if filter.ReporterID != nil {
+		args = append(args, *filter.ReporterID)
+		conditions = append(conditions, fmt.Sprintf("a.reporter_id = $%d", len(args)))
+	}
+	if filter.CampaignID != nil {
+		args = append(args, *filter.CampaignID)
+		conditions = append(conditions, fmt.Sprintf("a.campaign_id = $%d", len(args)))
+	}
+	if filter.ExpiresBefore != nil {
+		args = append(args, *filter.ExpiresBefore)
+		conditions = append(conditions, fmt.Sprintf("a.expires_at < $%d", len(args)))
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit)
+	rows, err := r.db.Query(ctx, `
+		SELECT a.id
+		FROM assignments a
+		JOIN listings l ON l.id = a.listing_id
+	`+whereClause+fmt.Sprintf(" ORDER BY a.created_at, a.id LIMIT $%d", len(args)), args...)
+	if err != nil {
+		log.Error(ctx, "Failed to query assignment ids", zap.Error(err), zap.Any("filter", filter))
+		return nil, err
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		log.Error(ctx, "Failed to collect assignment ids", zap.Error(err))
+		return nil, err
+	}
+	return ids, nil
 }
 
 func (r *SecretGuestRepository) GetAssignmentByIDAndOwner(ctx context.Context, assignmentID, reporterID uuid.UUID) (*models.Assignment, error) {
@@ -1002,6 +1461,7 @@ func (r *SecretGuestRepository) GetAssignmentByIDAndOwner(ctx context.Context, a
 
 			a.listing_id, a.reporter_id, a.status_id, a.purpose, a.created_at, a.expires_at, a.accepted_at,
 			a.taked_at,
+			a.created_by, a.invited_at, a.accept_deadline, a.campaign_id,
 
 			l.id,
 			l.code as "listing_code",
@@ -1037,7 +1497,9 @@ func (r *SecretGuestRepository) GetAssignmentByIDAndOwner(ctx context.Context, a
 	return assignment, nil
 }
 
-func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, acceptedAt time.Time) (*models.Report, error) {
+// AcceptMyAssignment принимает задание, создает по нему отчет со сроком сдачи dueAfterCheckout после выезда
+// и начисление(если reward не nil)
+func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, acceptedAt time.Time, dueAfterCheckout time.Duration, reward *models.Reward) (*models.Report, error) {
 
 	//TODO: Переписать!!!
 
@@ -1048,8 +1510,13 @@ func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignme
 	}
 	defer tx.Rollback(ctx)
 
-	updateQuery := `
-		UPDATE assignments
+	// Принятие освобождает слот Offered гостя: без блокировки параллельное взятие видит его предложение еще в Offered
+	if err := lockGuestAssignments(ctx, tx, reporterID); err != nil {
+		return nil, err
+	}
+
+	updateQuery := `
+		UPDATE assignments
 		SET
 			status_id = $1,
 			accepted_at = $2
@@ -1057,6 +1524,7 @@ func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignme
 			id = $3
 			AND reporter_id = $4
 			AND status_id = $5
+			AND (accept_deadline IS NULL OR accept_deadline >= $2)
 		RETURNING
 			listing_id,
 			purpose,
@@ -1068,7 +1536,7 @@ func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignme
 
 	var listingID uuid.UUID
 	var purpose string
-	var otaSgReservationID uuid.UUID
+	var otaSgReservationID *uuid.UUID
 	var pricing json.RawMessage
 	var guests json.RawMessage
 	var checkinDate time.Time
@@ -1095,21 +1563,21 @@ func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignme
 		return nil, err
 	}
 
-	// ДЕргаем документ-основание - бронирование из ota_sg_reservations
-	var otaID uuid.UUID
-	var bookingNumber string
+	// ДЕргаем документ-основание - бронирование из ota_sg_reservations.
+	// У предложений, созданных персоналом вручную, брони нет - ota_id и booking_number в отчете пустые
+	var otaID *uuid.UUID
+	var bookingNumber *string
 
-	if otaSgReservationID != uuid.Nil {
+	if otaSgReservationID != nil {
 		err = tx.QueryRow(ctx,
 			`SELECT ota_id, booking_number FROM ota_sg_reservations WHERE id = $1`,
-			otaSgReservationID,
+			*otaSgReservationID,
 		).Scan(&otaID, &bookingNumber)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		log.Error(ctx, "Assignment has no linked OTA reservation", zap.String("assignment_id", assignmentID.String()))
-		return nil, fmt.Errorf("assignment %s has no linked OTA reservation", assignmentID)
+		log.Info(ctx, "Accepting assignment without OTA reservation", zap.String("assignment_id", assignmentID.String()))
 	}
 	//////
 
@@ -1122,15 +1590,23 @@ func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignme
 		ReporterID:   reporterID,
 		StatusID:     models.ReportStatusGenerating,
 		BookingDetails: models.BookingDetails{
-			OTAID:              otaID,
-			BookingNumber:      bookingNumber,
-			OtaSgReservationID: otaSgReservationID,
-			Pricing:            pricing,
-			Guests:             guests,
-			CheckinDate:        checkinDate,
-			CheckoutDate:       checkoutDate,
+			Pricing:      pricing,
+			Guests:       guests,
+			CheckinDate:  checkinDate,
+			CheckoutDate: checkoutDate,
 		},
 	}
+	dueAt := checkoutDate.Add(dueAfterCheckout)
+	report.DueAt = &dueAt
+	if otaID != nil {
+		report.BookingDetails.OTAID = *otaID
+	}
+	if bookingNumber != nil {
+		report.BookingDetails.BookingNumber = *bookingNumber
+	}
+	if otaSgReservationID != nil {
+		report.BookingDetails.OtaSgReservationID = *otaSgReservationID
+	}
 
 	insertQuery := `
 		INSERT INTO reports (
@@ -1146,8 +1622,9 @@ func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignme
 			pricing,
 			guests,
 			checkin_date,
-			checkout_date
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+			checkout_date,
+			due_at
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
 	`
 	if _, err := tx.Exec(ctx, insertQuery,
 		report.ID,
@@ -1156,18 +1633,30 @@ func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignme
 		report.Purpose,
 		report.ReporterID,
 		report.StatusID,
-		report.BookingDetails.OTAID,
-		report.BookingDetails.BookingNumber,
-		report.BookingDetails.OtaSgReservationID,
+		otaID,
+		bookingNumber,
+		otaSgReservationID,
 		report.BookingDetails.Pricing,
 		report.BookingDetails.Guests,
 		report.BookingDetails.CheckinDate,
 		report.BookingDetails.CheckoutDate,
+		report.DueAt,
 	); err != nil {
 		log.Error(ctx, "Failed to create report in transaction", zap.Error(err))
 		return nil, err
 	}
 
+	if reward != nil {
+		reward.ReportID = &report.ID
+		if err := insertReward(ctx, tx, reward); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordAssignmentPointEvent(ctx, tx, reporterID, assignmentID, &report.ID, models.PointEventAssignmentAccepted); err != nil {
+		return nil, err
+	}
+
 	// Коммитим транзакцию
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
@@ -1176,70 +1665,141 @@ func (r *SecretGuestRepository) AcceptMyAssignment(ctx context.Context, assignme
 	return report, nil
 }
 
-func (r *SecretGuestRepository) DeclineMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, takedAt, declinedAt time.Time) error {
+// DeclineMyAssignment регистрирует отказ гостя(reasonID - причина из decline_reasons, nil - не указана), возвращает
+// предложение в свободные и предлагает его следующему гостю из листа ожидания(offer). Возвращает выбранного из листа гостя или nil
+func (r *SecretGuestRepository) DeclineMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, declinedAt time.Time, reasonID *int, comment *string, offer *models.WaitlistOffer) (*uuid.UUID, error) {
 	log := logger.GetLoggerFromCtx(ctx)
 
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
-		return err
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
-	// Обновляем статус задания и очищаем reporter_id
+	// Обновляем статус задания и очищаем reporter_id. taked_at берем из той же строки под блокировкой,
+	// чтобы параллельный отказ/повторное взятие не подменили его
 	updateQuery := `
-		UPDATE assignments
+		WITH prev AS (
+			SELECT id, taked_at
+			FROM assignments
+			WHERE
+				id = $2
+				AND reporter_id = $3
+				AND status_id = $4
+			FOR UPDATE
+		)
+		UPDATE assignments a
 		SET
-			status_id   = $1,
-			reporter_id = NULL,
-			taked_at    = NULL
-		WHERE
-			id = $2
-			AND reporter_id = $3
-			AND status_id = $4
+			status_id       = $1,
+			reporter_id     = NULL,
+			taked_at        = NULL,
+			invited_at      = NULL,
+			accept_deadline = NULL
+		FROM prev
+		WHERE a.id = prev.id
+		RETURNING prev.taked_at
 	`
 
-	ct, err := tx.Exec(ctx, updateQuery,
+	var takedAt *time.Time
+	err = tx.QueryRow(ctx, updateQuery,
 		// models.AssignmentStatusDeclined,
 		models.AssignmentStatusOffered, // Возвращаем в статус "предложено"
 		assignmentID,
 		reporterID,
 		models.AssignmentStatusOffered,
-	)
-	if err != nil {
+	).Scan(&takedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		log.Error(ctx, "DB error on declining assignment", zap.Error(err))
-		return err
+		return nil, err
 	}
-	if ct.RowsAffected() == 0 {
+	if errors.Is(err, pgx.ErrNoRows) {
 		log.Warn(ctx, "Attempt to decline assignment failed: conditions not met",
 			zap.String("assignment_id", assignmentID.String()),
 			zap.String("reporter_id", reporterID.String()),
 			zap.Int("required_status_id", models.AssignmentStatusOffered),
 		)
-		return models.ErrAssignmentCannotBeDeclined
+		return nil, models.ErrAssignmentCannotBeDeclined
 	}
 
 	// TODO: Возможно, стоит переписать всё в рамках одной транзакции.
 	// Регистрируем отказ
 	insertQuery := `
-		INSERT INTO assignment_declines (assignment_id, reporter_id, taked_at, declined_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO assignment_declines (assignment_id, reporter_id, taked_at, declined_at, reason_id, comment)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
-	_, err = tx.Exec(ctx, insertQuery, assignmentID, reporterID, takedAt, declinedAt)
+	_, err = tx.Exec(ctx, insertQuery, assignmentID, reporterID, takedAt, declinedAt, reasonID, comment)
 	if err != nil {
 		log.Error(ctx, "Failed to insert assignment decline history", zap.Error(err))
-		return err
+		return nil, err
+	}
+
+	waitlistUserID, err := offerAssignmentToWaitlist(ctx, tx, assignmentID, offer, declinedAt)
+	if err != nil {
+		log.Error(ctx, "Failed to offer declined assignment to waitlist", zap.Error(err))
+		return nil, err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		log.Error(ctx, "Failed to commit decline transaction", zap.Error(err))
-		return err
+		return nil, err
 	}
 
+	return waitlistUserID, nil
+}
+
+// lockGuestAssignments сериализует транзакции, меняющие предложения гостя(взятие, принятие, приглашение), до конца транзакции.
+// Без нее два параллельных запроса одного гостя проходят проверки count/пересечения дат одновременно
+func lockGuestAssignments(ctx context.Context, tx pgx.Tx, reporterID uuid.UUID) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1::text, 0))`, reporterID); err != nil {
+		return fmt.Errorf("failed to lock guest assignments: %w", err)
+	}
 	return nil
 }
 
-func (r *SecretGuestRepository) TakeFreeAssignmentsByID(ctx context.Context, assignmentID, userID uuid.UUID, takenAt time.Time) error {
+// tryLockGuestAssignments - как lockGuestAssignments, но без ожидания: false, если гостя держит другая транзакция
+func tryLockGuestAssignments(ctx context.Context, tx pgx.Tx, reporterID uuid.UUID) (bool, error) {
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtextextended($1::text, 0))`, reporterID).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to lock guest assignments: %w", err)
+	}
+	return locked, nil
+}
+
+// guestOfferedViolation - нарушение assignments_reporter_offered_key: у гостя уже есть предложение Offered
+func guestOfferedViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "assignments_reporter_offered_key"
+}
+
+// checkAssignmentOverlap проверяет, что даты [checkin, checkout) не пересекаются с другими(кроме assignmentID) предложениями гостя
+// в статусе Offered или Accepted. Гонки дополнительно закрывает ограничение assignments_reporter_stay_excl
+func checkAssignmentOverlap(ctx context.Context, tx pgx.Tx, assignmentID, reporterID uuid.UUID, checkin, checkout time.Time) error {
+	var clash models.AssignmentOverlapError
+	err := tx.QueryRow(ctx, `
+		SELECT id, checkin_date, checkout_date
+		FROM assignments
+		WHERE reporter_id = $1
+			AND id <> $2
+			AND status_id = ANY($3)
+			AND tsrange(checkin_date, checkout_date) && tsrange($4, $5)
+		ORDER BY checkin_date
+		LIMIT 1
+	`, reporterID, assignmentID, []int{models.AssignmentStatusOffered, models.AssignmentStatusAccepted}, checkin, checkout).Scan(
+		&clash.AssignmentID, &clash.CheckinDate, &clash.CheckoutDate,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check overlapping assignments: %w", err)
+	}
+	return &clash
+}
+
+// TakeFreeAssignmentsByID закрепляет свободное предложение за гостем. acceptDeadline - срок удержания(nil - без ограничения),
+// после него предложение возвращается в свободные
+func (r *SecretGuestRepository) TakeFreeAssignmentsByID(ctx context.Context, assignmentID, userID uuid.UUID, takenAt time.Time, acceptDeadline *time.Time) error {
 
 	// TODO: добавить временную метку taken_at в assignments, или отдльную таблицу и т.п.
 
@@ -1249,6 +1809,10 @@ func (r *SecretGuestRepository) TakeFreeAssignmentsByID(ctx context.Context, ass
 	}
 	defer tx.Rollback(ctx)
 
+	if err := lockGuestAssignments(ctx, tx, userID); err != nil {
+		return err
+	}
+
 	// Проверяем, что у пользователя нет других предложений Offered
 	var count int
 	err = tx.QueryRow(ctx, `
@@ -1263,8 +1827,19 @@ func (r *SecretGuestRepository) TakeFreeAssignmentsByID(ctx context.Context, ass
 		return fmt.Errorf("failed to check existing offered assignments: %w", err)
 	}
 	if count > 0 {
-		// return models.ErrAssignmentCannotBeTaken
-		return fmt.Errorf("user %s already has %d active assignments", userID, count)
+		return fmt.Errorf("%w: user %s has %d offered assignments", models.ErrGuestHasActiveAssignment, userID, count)
+	}
+
+	var checkin, checkout time.Time
+	err = tx.QueryRow(ctx, `SELECT checkin_date, checkout_date FROM assignments WHERE id = $1`, assignmentID).Scan(&checkin, &checkout)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrAssignmentCannotBeTaken
+		}
+		return fmt.Errorf("failed to get assignment dates: %w", err)
+	}
+	if err := checkAssignmentOverlap(ctx, tx, assignmentID, userID, checkin, checkout); err != nil {
+		return err
 	}
 
 	updateQuery := `
@@ -1272,7 +1847,8 @@ func (r *SecretGuestRepository) TakeFreeAssignmentsByID(ctx context.Context, ass
 		SET
 			reporter_id = $1,
 			status_id = $2,
-			taked_at = $3
+			taked_at = $3,
+			accept_deadline = $6
 		WHERE
 			id = $4
 		  	AND status_id = $5
@@ -1287,15 +1863,28 @@ func (r *SecretGuestRepository) TakeFreeAssignmentsByID(ctx context.Context, ass
 		takenAt,
 		assignmentID,
 		models.AssignmentStatusOffered, // текущий статус
+		acceptDeadline,
 	).Scan(&id)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return models.ErrAssignmentCannotBeTaken
 		}
+		if guestOfferedViolation(err) {
+			return models.ErrGuestHasActiveAssignment
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23P01" { // exclusion_violation
+			return &models.AssignmentOverlapError{}
+		}
 		return fmt.Errorf("failed to update assignment: %w", err)
 	}
 
+	// Гость мог встать в лист ожидания, пока предложение было занято
+	if _, err := tx.Exec(ctx, `DELETE FROM assignment_waitlist WHERE assignment_id = $1 AND user_id = $2`, assignmentID, userID); err != nil {
+		return fmt.Errorf("failed to remove guest from waitlist: %w", err)
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -1310,8 +1899,10 @@ type ReportsFilter struct {
 	ReporterID     *uuid.UUID
 	StatusIDs      []int
 	ListingTypeIDs []int
-	Limit          int
-	Offset         int
+	// Сначала отчеты с ближайшим сроком сдачи(для черновиков гостя)
+	OrderByDueAt bool
+	Limit        int
+	Offset       int
 }
 
 func buildReportWhereClause(filter ReportsFilter) (string, []interface{}, int) {
@@ -1378,6 +1969,8 @@ func (r *SecretGuestRepository) scanReport(row pgx.Row) (*models.Report, error)
 		&rep.CreatedAt,
 		&rep.UpdatedAt,
 		&rep.SubmittedAt,
+		&rep.QualityScore,
+		&rep.DueAt,
 
 		&rep.ChecklistSchema,
 
@@ -1443,7 +2036,7 @@ func (r *SecretGuestRepository) GetReports(ctx context.Context, filter ReportsFi
 			r.assignment_id,
 
 			r.ota_id,
-			r.booking_number,
+			COALESCE(r.booking_number, ''),
 			r.ota_sg_reservation_id,
 			r.pricing,
 			r.guests,
@@ -1455,6 +2048,8 @@ func (r *SecretGuestRepository) GetReports(ctx context.Context, filter ReportsFi
 			r.created_at,
 			r.updated_at,
 			r.submitted_at,
+			r.quality_score,
+			r.due_at,
 
 			r.checklist_schema,
 
@@ -1470,7 +2065,7 @@ func (r *SecretGuestRepository) GetReports(ctx context.Context, filter ReportsFi
 			l.address as "listing_address", l.city as "listing_city", l.country as "listing_country",
 			l.latitude as "listing_latitude", l.longitude as "listing_longitude",
 
-			u.username as "reporter_username",
+			COALESCE(u.username, '') as "reporter_username",
 
 			s.slug as "status_slug",
 			s.name as "status_name"
@@ -1487,7 +2082,11 @@ func (r *SecretGuestRepository) GetReports(ctx context.Context, filter ReportsFi
 		query += " WHERE " + whereClause
 	}
 
-	query += fmt.Sprintf(" ORDER BY r.created_at DESC LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+	orderBy := "r.created_at DESC"
+	if filter.OrderByDueAt {
+		orderBy = "r.due_at NULLS LAST, r.created_at DESC"
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", orderBy, paramCount, paramCount+1)
 	args = append(args, filter.Limit, filter.Offset)
 
 	rows, err := r.db.Query(ctx, query, args...)
@@ -1518,6 +2117,8 @@ func (r *SecretGuestRepository) GetReports(ctx context.Context, filter ReportsFi
 			&r.CreatedAt,
 			&r.UpdatedAt,
 			&r.SubmittedAt,
+			&r.QualityScore,
+			&r.DueAt,
 
 			&r.ChecklistSchema,
 
@@ -1568,7 +2169,7 @@ func (r *SecretGuestRepository) GetReportByID(ctx context.Context, reportID uuid
 			r.assignment_id,
 
 			r.ota_id,
-			r.booking_number,
+			COALESCE(r.booking_number, ''),
 			r.ota_sg_reservation_id,
 			r.pricing,
 			r.guests,
@@ -1576,7 +2177,7 @@ func (r *SecretGuestRepository) GetReportByID(ctx context.Context, reportID uuid
 			r.checkout_date,
 
 			r.listing_id, r.reporter_id, r.status_id, r.purpose,
-			r.created_at, r.updated_at, r.submitted_at, r.checklist_schema,
+			r.created_at, r.updated_at, r.submitted_at, r.quality_score, r.due_at, r.checklist_schema,
 
 			l.ID,
 			l.code as "listing_code",
@@ -1590,11 +2191,11 @@ func (r *SecretGuestRepository) GetReportByID(ctx context.Context, reportID uuid
 			l.address as "listing_address", l.city as "listing_city", l.country as "listing_country",
 			l.latitude as "listing_latitude", l.longitude as "listing_longitude",
 
-			u.username as "reporter_username",
+			COALESCE(u.username, '') as "reporter_username",
 			s.slug as "status_slug", s.name as "status_name"
 		FROM reports r
 		JOIN listings l ON r.listing_id = l.id
-		JOIN users u ON r.reporter_id = u.id
+		LEFT JOIN users u ON r.reporter_id = u.id
 		JOIN report_statuses s ON r.status_id = s.id
 		JOIN listing_types lt ON l.listing_type_id = lt.id
 		WHERE r.id = $1
@@ -1620,7 +2221,7 @@ func (r *SecretGuestRepository) GetReportByIDAndOwner(ctx context.Context, repor
 			r.assignment_id,
 
 			r.ota_id,
-			r.booking_number,
+			COALESCE(r.booking_number, ''),
 			r.ota_sg_reservation_id,
 			r.pricing,
 			r.guests,
@@ -1628,7 +2229,7 @@ func (r *SecretGuestRepository) GetReportByIDAndOwner(ctx context.Context, repor
 			r.checkout_date,
 
 			r.listing_id, r.reporter_id, r.status_id, r.purpose,
-			r.created_at, r.updated_at, r.submitted_at,
+			r.created_at, r.updated_at, r.submitted_at, r.quality_score, r.due_at,
 
 			r.checklist_schema,
 
@@ -1717,18 +2318,89 @@ func (r *SecretGuestRepository) UpdateMyReportStatus(ctx context.Context, report
 
 	query := `UPDATE reports SET status_id = $1, updated_at = NOW() WHERE id = $2 AND reporter_id = $3 AND status_id = $4`
 
+	// Сдать можно до срока или со штрафом report_late в окне после него: позже черновик ждет перевода в "Просрочен"
 	if newStatusID == models.ReportStatusSubmitted {
-		query = `UPDATE reports SET status_id = $1, updated_at = NOW(), submitted_at = NOW() WHERE id = $2 AND reporter_id = $3 AND status_id = $4`
+		query = `
+			UPDATE reports SET status_id = $1, updated_at = NOW(), submitted_at = NOW()
+			WHERE id = $2 AND reporter_id = $3 AND status_id = $4 AND (due_at IS NULL OR due_at + ` + reportLateWindowSQL + ` > NOW())`
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback(ctx)
 
-	ct, err := r.db.Exec(ctx, query, newStatusID, reportID, reporterID, currentStatusID)
+	ct, err := tx.Exec(ctx, query, newStatusID, reportID, reporterID, currentStatusID)
 	if err != nil {
 		return err
 	}
 	if ct.RowsAffected() == 0 {
+		if newStatusID == models.ReportStatusSubmitted {
+			var overdue bool
+			err := tx.QueryRow(ctx,
+				`SELECT due_at IS NOT NULL AND due_at + `+reportLateWindowSQL+` <= NOW() FROM reports WHERE id = $1 AND reporter_id = $2 AND status_id = $3`,
+				reportID, reporterID, currentStatusID,
+			).Scan(&overdue)
+			if err == nil && overdue {
+				return models.ErrReportOverdue
+			}
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return err
+			}
+		}
 		return models.ErrReportNotEditable
 	}
-	return nil
+
+	// При отказе от заполнения отчета начисление аннулируется
+	if err := settleReportReward(ctx, tx, reportID, newStatusID, nil); err != nil {
+		return err
+	}
+
+	if err := recordReportPointEvents(ctx, tx, reportID, newStatusID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ReviewReport - решение персонала по сданному отчету: перевод в newStatusID с оценкой объекта qualityScore(nil - без оценки).
+// Начисление по отчету и очки автора меняются в той же транзакции. Возвращает автора отчета(nil, если учетная запись удалена)
+func (r *SecretGuestRepository) ReviewReport(ctx context.Context, reportID uuid.UUID, newStatusID int, reviewerID uuid.UUID, qualityScore *int) (*uuid.UUID, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var reporterID *uuid.UUID
+	query := `UPDATE reports SET status_id = $1, quality_score = $4, updated_at = NOW() WHERE id = $2 AND status_id = $3 RETURNING reporter_id`
+	err = tx.QueryRow(ctx, query, newStatusID, reportID, models.ReportStatusSubmitted, qualityScore).Scan(&reporterID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if newStatusID == models.ReportStatusApproved {
+				return nil, models.ErrReportCannotBeApproved
+			}
+			return nil, models.ErrReportCannotBeRejected
+		}
+		return nil, err
+	}
+
+	if err := settleReportReward(ctx, tx, reportID, newStatusID, &reviewerID); err != nil {
+		return nil, err
+	}
+
+	if err := recordReportPointEvents(ctx, tx, reportID, newStatusID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return reporterID, nil
 }
 
 func (r *SecretGuestRepository) UpdateReportStatusAsStaff(ctx context.Context, reportID uuid.UUID, currentStatusID, newStatusID int) error {
@@ -2663,12 +3335,83 @@ func (r *SecretGuestRepository) DeleteChecklistItem(ctx context.Context, id int)
 
 // users
 
-func (r *SecretGuestRepository) GetAllUsers(ctx context.Context, limit, offset int) ([]*models.User, int, error) {
+type UsersFilter struct {
+	Search         string // подстрока username или email
+	RoleIDs        []int
+	Blocked        *bool
+	ServiceAccount *bool
+	Limit          int
+	Offset         int
+}
+
+func buildUsersWhereClause(filter UsersFilter) (string, []interface{}, int) {
+	conditions := []string{}
+	args := []interface{}{}
+	paramCount := 1
+
+	if filter.Search != "" {
+		conditions = append(conditions, fmt.Sprintf("(u.username ILIKE $%d OR u.email ILIKE $%d)", paramCount, paramCount))
+		args = append(args, "%"+filter.Search+"%")
+		paramCount++
+	}
+
+	if len(filter.RoleIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("u.role_id = ANY($%d)", paramCount))
+		args = append(args, filter.RoleIDs)
+		paramCount++
+	}
+
+	if filter.Blocked != nil {
+		if *filter.Blocked {
+			conditions = append(conditions, "u.blocked_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "u.blocked_at IS NULL")
+		}
+	}
+
+	if filter.ServiceAccount != nil {
+		conditions = append(conditions, fmt.Sprintf("u.is_service_account = $%d", paramCount))
+		args = append(args, *filter.ServiceAccount)
+		paramCount++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return whereClause, args, paramCount
+}
+
+const userSelectColumns = `
+			u.id,
+			u.username,
+			COALESCE(u.email, ''),
+			u.password_hash,
+			u.role_id,
+			u.created_at,
+			r.name as role_name,
+			u.blocked_at,
+			u.blocked_reason,
+			u.password_change_required,
+			u.is_service_account,
+			u.deleted_at`
+
+func scanUser(row pgx.Row, u *models.User) error {
+	return row.Scan(
+		&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.RoleID, &u.CreatedAt, &u.RoleName,
+		&u.BlockedAt, &u.BlockedReason, &u.PasswordChangeRequired, &u.IsServiceAccount, &u.DeletedAt,
+	)
+}
+
+func (r *SecretGuestRepository) GetAllUsers(ctx context.Context, filter UsersFilter) ([]*models.User, int, error) {
 	log := logger.GetLoggerFromCtx(ctx)
 
-	countQuery := `SELECT COUNT(*) FROM users;`
+	whereClause, args, paramCount := buildUsersWhereClause(filter)
+
+	countQuery := `SELECT COUNT(*) FROM users u` + whereClause
 	var total int
-	err := r.db.QueryRow(ctx, countQuery).Scan(&total)
+	err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		log.Error(ctx, "Failed to query total users count", zap.Error(err))
 		return nil, 0, err
@@ -2679,21 +3422,13 @@ func (r *SecretGuestRepository) GetAllUsers(ctx context.Context, limit, offset i
 	}
 
 	query := `
-		SELECT
-			u.id,
-			u.username,
-			u.email,
-			u.password_hash,
-			u.role_id,
-			u.created_at,
-			r.name as role_name
+		SELECT` + userSelectColumns + `
 		FROM users u
-		JOIN roles r ON u.role_id = r.id
-		ORDER BY u.created_at DESC
-		LIMIT $1 OFFSET $2;
-	`
+		JOIN roles r ON u.role_id = r.id` + whereClause +
+		fmt.Sprintf(" ORDER BY u.created_at DESC LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+	args = append(args, filter.Limit, filter.Offset)
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
 		log.Error(ctx, "Failed to query users", zap.Error(err))
 		return nil, total, err
@@ -2703,7 +3438,7 @@ func (r *SecretGuestRepository) GetAllUsers(ctx context.Context, limit, offset i
 	users := make([]*models.User, 0)
 	for rows.Next() {
 		var u models.User
-		err = rows.Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.RoleID, &u.CreatedAt, &u.RoleName)
+		err = scanUser(rows, &u)
 		if err != nil {
 			log.Error(ctx, "Failed to scan user row with role", zap.Error(err))
 			return nil, total, err
@@ -2719,50 +3454,1916 @@ func (r *SecretGuestRepository) GetAllUsers(ctx context.Context, limit, offset i
 	return users, total, nil
 }
 
-// profiles
-
-func (r *SecretGuestRepository) GetUserProfileByID(ctx context.Context, userID uuid.UUID) (*models.UserProfile, error) {
+func (r *SecretGuestRepository) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
 	log := logger.GetLoggerFromCtx(ctx)
+
 	query := `
-		SELECT
-			up.id,
-			up.user_id,
-			up.accepted_offers_count,
-			up.submitted_reports_count,
-			up.correct_reports_count,
-			up.registered_at,
-			up.last_active_at,
-			up.additional_info,
-			u.username,
-			u.email
-		FROM user_profiles up
-		JOIN users u ON up.user_id = u.id
-		WHERE up.user_id = $1
+		SELECT` + userSelectColumns + `
+		FROM users u
+		JOIN roles r ON u.role_id = r.id
+		WHERE u.id = $1
 	`
-	row := r.db.QueryRow(ctx, query, userID)
 
-	var p models.UserProfile
-	err := row.Scan(
-		&p.ID,
-		&p.UserID,
-		&p.AcceptedOffersCount,
-		&p.SubmittedReportsCount,
-		&p.CorrectReportsCount,
-		&p.RegisteredAt,
-		&p.LastActiveAt,
-		&p.AdditionalInfo,
-		&p.Username,
-		&p.Email,
-	)
-	if err != nil {
+	var u models.User
+	if err := scanUser(r.db.QueryRow(ctx, query, userID), &u); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			log.Info(ctx, "User profile not found by user ID", zap.String("user_id", userID.String()))
-			return nil, models.ErrNotFound
+			return nil, models.ErrUserNotFound
 		}
-		log.Error(ctx, "Failed to query user profile by ID", zap.Error(err), zap.String("user_id", userID.String()))
+		log.Error(ctx, "Failed to get user by ID", zap.Error(err), zap.String("user_id", userID.String()))
 		return nil, err
 	}
-	return &p, nil
+
+	return &u, nil
+}
+
+// UpdateUserRole меняет роль пользователя и пишет запись в журнал в одной транзакции
+func (r *SecretGuestRepository) UpdateUserRole(ctx context.Context, userID uuid.UUID, roleID int, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `UPDATE users SET role_id = $2 WHERE id = $1`, userID, roleID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ErrRoleNotFound
+		}
+		log.Error(ctx, "DB error on updating user role", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetUserBlocked блокирует(reason != nil) или разблокирует пользователя
+func (r *SecretGuestRepository) SetUserBlocked(ctx context.Context, userID uuid.UUID, reason *string, blockedBy uuid.UUID, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var query string
+	var args []interface{}
+	if reason != nil {
+		query = `UPDATE users SET blocked_at = NOW(), blocked_reason = $2, blocked_by = $3 WHERE id = $1`
+		args = []interface{}{userID, *reason, blockedBy}
+	} else {
+		query = `UPDATE users SET blocked_at = NULL, blocked_reason = NULL, blocked_by = NULL WHERE id = $1`
+		args = []interface{}{userID}
+	}
+
+	ct, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "DB error on updating user block state", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ResetUserPassword устанавливает временный пароль и требует его смены при следующем входе
+func (r *SecretGuestRepository) ResetUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `UPDATE users SET password_hash = $2, password_change_required = true WHERE id = $1`, userID, passwordHash)
+	if err != nil {
+		log.Error(ctx, "DB error on resetting user password", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteUserAccount удаляет учетную запись по запросу пользователя в одной транзакции:
+// персональные данные обезличиваются, незавершенная работа освобождается,
+// одобренные и ожидающие проверки отчеты сохраняются без привязки к автору
+func (r *SecretGuestRepository) DeleteUserAccount(ctx context.Context, userID uuid.UUID, anonymizedUsername string, offers map[uuid.UUID]*models.WaitlistOffer, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE users
+		SET
+			username = $2,
+			email = NULL,
+			password_hash = '!',
+			password_change_required = false,
+			blocked_at = NOW(),
+			blocked_reason = 'account deleted',
+			blocked_by = NULL,
+			deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL`,
+		userID, anonymizedUsername,
+	)
+	if err != nil {
+		log.Error(ctx, "DB error on anonymizing user", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrUserNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM assignment_waitlist WHERE user_id = $1`, userID); err != nil {
+		log.Error(ctx, "DB error on removing user from waitlists", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+
+	// Взятые, но не принятые предложения возвращаются в свободные(вместе со сроком принятия)
+	// и предлагаются следующему гостю из листа ожидания
+	rows, err := tx.Query(ctx, `
+		UPDATE assignments SET reporter_id = NULL, taked_at = NULL, invited_at = NULL, accept_deadline = NULL
+		WHERE reporter_id = $1 AND status_id = $2
+		RETURNING id`,
+		userID, models.AssignmentStatusOffered,
+	)
+	if err != nil {
+		log.Error(ctx, "DB error on releasing taken assignments", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+	released, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		log.Error(ctx, "Failed to collect released assignments", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+	for _, assignmentID := range released {
+		if _, err := offerAssignmentToWaitlist(ctx, tx, assignmentID, offers[assignmentID], time.Now()); err != nil {
+			log.Error(ctx, "Failed to offer released assignment to waitlist", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+			return err
+		}
+	}
+
+	statements := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		// Незаконченные отчеты - отказ от заполнения
+		{"refuse unfinished reports", `
+			UPDATE reports SET status_id = $2, updated_at = NOW()
+			WHERE reporter_id = $1 AND status_id = ANY($3)`,
+			[]interface{}{userID, models.ReportStatusRefused, []int{models.ReportStatusGenerating, models.ReportStatusDraft, models.ReportStatusGenerationFailed}}},
+		// Содержимое отчетов, которые не пойдут в оценку объектов, не храним
+		{"clear unused report content", `
+			UPDATE reports SET checklist_schema = NULL
+			WHERE reporter_id = $1 AND status_id <> ALL($2)`,
+			[]interface{}{userID, []int{models.ReportStatusSubmitted, models.ReportStatusApproved}}},
+		// Начисления по незаконченным отчетам аннулируются, одобренные к выплате остаются за бухгалтерией
+		{"void pending rewards", `
+			UPDATE rewards SET status_id = $2, voided_at = NOW(), void_reason = 'account deleted', updated_at = NOW()
+			WHERE user_id = $1 AND status_id = $3`,
+			[]interface{}{userID, models.RewardStatusVoided, models.RewardStatusPending}},
+		// Координаты устройства при заезде и выезде - точное местоположение гостя, время отметок остается для истории предложения
+		{"clear stay locations", `
+			UPDATE assignments SET checked_in_latitude = NULL, checked_in_longitude = NULL, checked_in_distance_m = NULL,
+				checked_out_latitude = NULL, checked_out_longitude = NULL, checked_out_distance_m = NULL
+			WHERE reporter_id = $1`,
+			[]interface{}{userID}},
+		{"detach reports", `UPDATE reports SET reporter_id = NULL WHERE reporter_id = $1`, []interface{}{userID}},
+		{"clear profile", `UPDATE user_profiles SET additional_info = NULL, last_active_at = NULL WHERE user_id = $1`, []interface{}{userID}},
+		{"delete application", `DELETE FROM guest_applications WHERE user_id = $1`, []interface{}{userID}},
+		{"delete identities", `DELETE FROM user_identities WHERE user_id = $1`, []interface{}{userID}},
+		{"delete calendar token", `DELETE FROM calendar_tokens WHERE user_id = $1`, []interface{}{userID}},
+		{"delete totp", `DELETE FROM user_totp WHERE user_id = $1`, []interface{}{userID}},
+		{"delete recovery codes", `DELETE FROM user_recovery_codes WHERE user_id = $1`, []interface{}{userID}},
+	}
+
+	for _, st := range statements {
+		if _, err := tx.Exec(ctx, st.query, st.args...); err != nil {
+			log.Error(ctx, "DB error on deleting user account", zap.String("step", st.name), zap.Error(err), zap.String("user_id", userID.String()))
+			return err
+		}
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// audit_log
+
+// dbExecutor - общий интерфейс пула и транзакции для запросов без результата
+type dbExecutor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func insertAuditLogEntry(ctx context.Context, db dbExecutor, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, entity_type, entity_id, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := db.Exec(ctx, query, entry.ActorID, entry.Action, entry.EntityType, entry.EntityID, entry.Details)
+	if err != nil {
+		log.Error(ctx, "DB error on inserting audit log entry", zap.Error(err), zap.String("action", entry.Action))
+		return err
+	}
+
+	return nil
+}
+
+func (r *SecretGuestRepository) CreateAuditLogEntry(ctx context.Context, entry *models.AuditLogEntry) error {
+	return insertAuditLogEntry(ctx, r.db, entry)
+}
+
+type AuditLogFilter struct {
+	ActorID    *uuid.UUID
+	EntityType string
+	EntityID   string
+	Actions    []string
+	Limit      int
+	Offset     int
+}
+
+func buildAuditLogWhereClause(filter AuditLogFilter) (string, []interface{}, int) {
+	conditions := []string{}
+	args := []interface{}{}
+	paramCount := 1
+
+	if filter.ActorID != nil {
+		conditions = append(conditions, fmt.Sprintf("al.actor_id = $%d", paramCount))
+		args = append(args, *filter.ActorID)
+		paramCount++
+	}
+	if filter.EntityType != "" {
+		conditions = append(conditions, fmt.Sprintf("al.entity_type = $%d", paramCount))
+		args = append(args, filter.EntityType)
+		paramCount++
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, fmt.Sprintf("al.entity_id = $%d", paramCount))
+		args = append(args, filter.EntityID)
+		paramCount++
+	}
+	if len(filter.Actions) > 0 {
+		conditions = append(conditions, fmt.Sprintf("al.action = ANY($%d)", paramCount))
+		args = append(args, filter.Actions)
+		paramCount++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return whereClause, args, paramCount
+}
+
+func (r *SecretGuestRepository) GetAuditLog(ctx context.Context, filter AuditLogFilter) ([]*models.AuditLogEntry, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	whereClause, args, paramCount := buildAuditLogWhereClause(filter)
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM audit_log al"+whereClause, args...).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to query total audit log count", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.AuditLogEntry{}, 0, nil
+	}
+
+	query := `
+		SELECT
+			al.id,
+			al.actor_id,
+			u.username,
+			al.action,
+			al.entity_type,
+			COALESCE(al.entity_id, ''),
+			al.details,
+			al.created_at
+		FROM audit_log al
+		LEFT JOIN users u ON al.actor_id = u.id` + whereClause +
+		fmt.Sprintf(" ORDER BY al.created_at DESC LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "Failed to query audit log", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.AuditLogEntry, 0)
+	for rows.Next() {
+		var e models.AuditLogEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorUsername, &e.Action, &e.EntityType, &e.EntityID, &e.Details, &e.CreatedAt); err != nil {
+			log.Error(ctx, "Failed to scan audit log row", zap.Error(err))
+			return nil, total, err
+		}
+		entries = append(entries, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error(ctx, "Error after iterating over audit log rows", zap.Error(err))
+		return nil, total, err
+	}
+
+	return entries, total, nil
+}
+
+// roles
+
+const roleSelectQuery = `
+	SELECT
+		r.id,
+		r.name,
+		r.description,
+		ARRAY(
+			SELECT p.slug
+			FROM role_permissions rp
+			JOIN permissions p ON p.id = rp.permission_id
+			WHERE rp.role_id = r.id
+			ORDER BY p.slug
+		) AS permissions
+	FROM roles r
+`
+
+func (r *SecretGuestRepository) GetPermissions(ctx context.Context) ([]*models.Permission, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rows, err := r.db.Query(ctx, `SELECT id, slug, description FROM permissions ORDER BY slug`)
+	if err != nil {
+		log.Error(ctx, "DB error on getting permissions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := make([]*models.Permission, 0)
+	for rows.Next() {
+		var p models.Permission
+		if err := rows.Scan(&p.ID, &p.Slug, &p.Description); err != nil {
+			log.Error(ctx, "Failed to scan permission row", zap.Error(err))
+			return nil, err
+		}
+		permissions = append(permissions, &p)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (r *SecretGuestRepository) GetRoles(ctx context.Context) ([]*models.Role, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rows, err := r.db.Query(ctx, roleSelectQuery+` ORDER BY r.id`)
+	if err != nil {
+		log.Error(ctx, "DB error on getting roles", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]*models.Role, 0)
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.Permissions); err != nil {
+			log.Error(ctx, "Failed to scan role row", zap.Error(err))
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *SecretGuestRepository) GetRoleByID(ctx context.Context, roleID int) (*models.Role, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var role models.Role
+	err := r.db.QueryRow(ctx, roleSelectQuery+` WHERE r.id = $1`, roleID).Scan(
+		&role.ID, &role.Name, &role.Description, &role.Permissions,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrRoleNotFound
+		}
+		log.Error(ctx, "DB error on getting role", zap.Error(err), zap.Int("role_id", roleID))
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// CreateRole создает роль вместе с набором прав и пишет запись в журнал в одной транзакции
+func (r *SecretGuestRepository) CreateRole(ctx context.Context, role *models.Role, entry *models.AuditLogEntry) (int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var roleID int
+	err = tx.QueryRow(ctx,
+		`INSERT INTO roles (name, description) VALUES ($1, $2) RETURNING id`,
+		role.Name, role.Description,
+	).Scan(&roleID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return 0, models.ErrDuplicate
+		}
+		log.Error(ctx, "DB error on creating role", zap.Error(err), zap.String("name", role.Name))
+		return 0, err
+	}
+
+	if err := replaceRolePermissions(ctx, tx, roleID, role.Permissions); err != nil {
+		return 0, err
+	}
+
+	entry.EntityID = strconv.Itoa(roleID)
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return roleID, nil
+}
+
+// UpdateRole меняет название и описание роли(nil - без изменений)
+func (r *SecretGuestRepository) UpdateRole(ctx context.Context, roleID int, name, description *string, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE roles
+		SET name = COALESCE($2, name),
+			description = COALESCE($3, description)
+		WHERE id = $1
+	`
+	ct, err := tx.Exec(ctx, query, roleID, name, description)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.ErrDuplicate
+		}
+		log.Error(ctx, "DB error on updating role", zap.Error(err), zap.Int("role_id", roleID))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrRoleNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetRolePermissions полностью заменяет набор прав роли
+func (r *SecretGuestRepository) SetRolePermissions(ctx context.Context, roleID int, permissions []string, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Блокируем строку роли, чтобы параллельное удаление не оставило "висячих" прав
+	var lockedID int
+	err = tx.QueryRow(ctx, `SELECT id FROM roles WHERE id = $1 FOR UPDATE`, roleID).Scan(&lockedID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrRoleNotFound
+		}
+		log.Error(ctx, "DB error on locking role", zap.Error(err), zap.Int("role_id", roleID))
+		return err
+	}
+
+	if err := replaceRolePermissions(ctx, tx, roleID, permissions); err != nil {
+		return err
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeleteRole удаляет роль. Роль, назначенную пользователям, удалить нельзя(ErrInUse).
+func (r *SecretGuestRepository) DeleteRole(ctx context.Context, roleID int, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `DELETE FROM roles WHERE id = $1`, roleID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ErrInUse
+		}
+		log.Error(ctx, "DB error on deleting role", zap.Error(err), zap.Int("role_id", roleID))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrRoleNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func replaceRolePermissions(ctx context.Context, tx pgx.Tx, roleID int, permissions []string) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		log.Error(ctx, "DB error on clearing role permissions", zap.Error(err), zap.Int("role_id", roleID))
+		return err
+	}
+
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT $1, p.id FROM permissions p WHERE p.slug = ANY($2)
+	`
+	ct, err := tx.Exec(ctx, query, roleID, permissions)
+	if err != nil {
+		log.Error(ctx, "DB error on inserting role permissions", zap.Error(err), zap.Int("role_id", roleID))
+		return err
+	}
+
+	// Слаги уникальны(проверяется в сервисе), поэтому несовпадение количества означает неизвестное право
+	if int(ct.RowsAffected()) != len(permissions) {
+		return models.ErrPermissionNotFound
+	}
+
+	return nil
+}
+
+// service_accounts
+
+// CreateServiceAccount создает сервисную учетную запись. Пароль не задается(хеш-заглушка не совпадет ни с одним паролем).
+func (r *SecretGuestRepository) CreateServiceAccount(ctx context.Context, user *models.User, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO users (id, username, password_hash, role_id, is_service_account)
+		VALUES ($1, $2, '!', $3, true)
+	`
+	_, err = tx.Exec(ctx, query, user.ID, user.Username, user.RoleID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return models.ErrUserExists
+			case "23503":
+				return models.ErrRoleNotFound
+			}
+		}
+		log.Error(ctx, "DB error on creating service account", zap.Error(err), zap.String("username", user.Username))
+		return err
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+const apiKeySelectColumns = `
+			k.id,
+			k.user_id,
+			k.name,
+			k.prefix,
+			k.scopes,
+			k.created_by,
+			k.created_at,
+			k.expires_at,
+			k.last_used_at,
+			k.revoked_at`
+
+func scanAPIKey(row pgx.Row, k *models.APIKey) error {
+	return row.Scan(
+		&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedBy,
+		&k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt,
+	)
+}
+
+func (r *SecretGuestRepository) GetAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		SELECT` + apiKeySelectColumns + `
+		FROM api_keys k
+		WHERE k.user_id = $1
+		ORDER BY k.created_at DESC
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		log.Error(ctx, "DB error on getting API keys", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		var k models.APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			log.Error(ctx, "Failed to scan API key row", zap.Error(err))
+			return nil, err
+		}
+		keys = append(keys, &k)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *SecretGuestRepository) GetAPIKeyByID(ctx context.Context, keyID uuid.UUID) (*models.APIKey, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		SELECT` + apiKeySelectColumns + `
+		FROM api_keys k
+		WHERE k.id = $1
+	`
+	var k models.APIKey
+	if err := scanAPIKey(r.db.QueryRow(ctx, query, keyID), &k); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrAPIKeyNotFound
+		}
+		log.Error(ctx, "DB error on getting API key", zap.Error(err), zap.String("api_key_id", keyID.String()))
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+func (r *SecretGuestRepository) CreateAPIKey(ctx context.Context, key *models.APIKey, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at
+	`
+	err = tx.QueryRow(ctx, query,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.CreatedBy, key.ExpiresAt,
+	).Scan(&key.CreatedAt)
+	if err != nil {
+		log.Error(ctx, "DB error on creating API key", zap.Error(err), zap.String("user_id", key.UserID.String()))
+		return err
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RevokeAPIKey отзывает ключ. Повторный отзыв не меняет дату первого отзыва.
+func (r *SecretGuestRepository) RevokeAPIKey(ctx context.Context, keyID uuid.UUID, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE id = $1`, keyID)
+	if err != nil {
+		log.Error(ctx, "DB error on revoking API key", zap.Error(err), zap.String("api_key_id", keyID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrAPIKeyNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// guest_applications
+
+type GuestApplicationsFilter struct {
+	StatusIDs []int
+	Limit     int
+	Offset    int
+}
+
+const guestApplicationSelectQuery = `
+	SELECT
+		ga.id,
+		ga.user_id,
+		u.username,
+		ga.status_id,
+		s.slug as "status_slug",
+		s.name as "status_name",
+		ga.motivation,
+		ga.travel_frequency,
+		ga.home_city,
+		ga.social_links,
+		ga.created_at,
+		ga.updated_at,
+		ga.reviewed_by,
+		ga.reviewed_at,
+		ga.review_comment
+	FROM guest_applications ga
+	JOIN users u ON ga.user_id = u.id
+	JOIN guest_application_statuses s ON ga.status_id = s.id
+`
+
+func scanGuestApplication(row pgx.Row, a *models.GuestApplication) error {
+	err := row.Scan(
+		&a.ID, &a.UserID, &a.Username, &a.StatusID, &a.Status.Slug, &a.Status.Name,
+		&a.Motivation, &a.TravelFrequency, &a.HomeCity, &a.SocialLinks,
+		&a.CreatedAt, &a.UpdatedAt, &a.ReviewedBy, &a.ReviewedAt, &a.ReviewComment,
+	)
+	a.Status.ID = a.StatusID
+	return err
+}
+
+func (r *SecretGuestRepository) GetGuestApplications(ctx context.Context, filter GuestApplicationsFilter) ([]*models.GuestApplication, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	whereClause := ""
+	args := []interface{}{}
+	paramCount := 1
+	if len(filter.StatusIDs) > 0 {
+		whereClause = fmt.Sprintf(" WHERE ga.status_id = ANY($%d)", paramCount)
+		args = append(args, filter.StatusIDs)
+		paramCount++
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM guest_applications ga"+whereClause, args...).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to query total guest applications count", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.GuestApplication{}, 0, nil
+	}
+
+	// Очередь на рассмотрение: сначала самые старые заявки
+	query := guestApplicationSelectQuery + whereClause +
+		fmt.Sprintf(" ORDER BY ga.created_at ASC LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "Failed to query guest applications", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	applications := make([]*models.GuestApplication, 0, filter.Limit)
+	for rows.Next() {
+		var a models.GuestApplication
+		if err := scanGuestApplication(rows, &a); err != nil {
+			log.Error(ctx, "Failed to scan guest application row", zap.Error(err))
+			return nil, total, err
+		}
+		applications = append(applications, &a)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error(ctx, "Error after iterating over guest application rows", zap.Error(err))
+		return nil, total, err
+	}
+
+	return applications, total, nil
+}
+
+func (r *SecretGuestRepository) GetGuestApplicationByID(ctx context.Context, applicationID uuid.UUID) (*models.GuestApplication, error) {
+	return r.getGuestApplication(ctx, "ga.id", applicationID)
+}
+
+func (r *SecretGuestRepository) GetGuestApplicationByUserID(ctx context.Context, userID uuid.UUID) (*models.GuestApplication, error) {
+	return r.getGuestApplication(ctx, "ga.user_id", userID)
+}
+
+func (r *SecretGuestRepository) getGuestApplication(ctx context.Context, column string, id uuid.UUID) (*models.GuestApplication, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var a models.GuestApplication
+	if err := scanGuestApplication(r.db.QueryRow(ctx, guestApplicationSelectQuery+" WHERE "+column+" = $1", id), &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrApplicationNotFound
+		}
+		log.Error(ctx, "DB error on getting guest application", zap.Error(err), zap.String(column, id.String()))
+		return nil, err
+	}
+
+	return &a, nil
+}
+
+// SaveGuestApplication создает заявку или обновляет ее, пока она не рассмотрена(на рассмотрении или в листе ожидания)
+func (r *SecretGuestRepository) SaveGuestApplication(ctx context.Context, a *models.GuestApplication) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		INSERT INTO guest_applications (id, user_id, status_id, motivation, travel_frequency, home_city, social_links)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE
+		SET motivation = EXCLUDED.motivation,
+			travel_frequency = EXCLUDED.travel_frequency,
+			home_city = EXCLUDED.home_city,
+			social_links = EXCLUDED.social_links,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE guest_applications.status_id = ANY($8)
+		RETURNING id
+	`
+	openStatuses := []int{models.GuestApplicationStatusPending, models.GuestApplicationStatusWaitlisted}
+	err := r.db.QueryRow(ctx, query,
+		a.ID, a.UserID, models.GuestApplicationStatusPending, a.Motivation, a.TravelFrequency, a.HomeCity, a.SocialLinks, openStatuses,
+	).Scan(&a.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrApplicationReviewed
+		}
+		log.Error(ctx, "DB error on saving guest application", zap.Error(err), zap.String("user_id", a.UserID.String()))
+		return err
+	}
+
+	return nil
+}
+
+// ReviewGuestApplication меняет статус заявки. Решение можно принять по заявке на рассмотрении или в листе ожидания.
+func (r *SecretGuestRepository) ReviewGuestApplication(ctx context.Context, applicationID uuid.UUID, statusID int, reviewerID uuid.UUID, comment *string, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var currentStatusID int
+	err = tx.QueryRow(ctx, `SELECT status_id FROM guest_applications WHERE id = $1 FOR UPDATE`, applicationID).Scan(&currentStatusID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrApplicationNotFound
+		}
+		log.Error(ctx, "DB error on locking guest application", zap.Error(err), zap.String("application_id", applicationID.String()))
+		return err
+	}
+	if currentStatusID != models.GuestApplicationStatusPending && currentStatusID != models.GuestApplicationStatusWaitlisted {
+		return models.ErrApplicationReviewed
+	}
+
+	query := `
+		UPDATE guest_applications
+		SET status_id = $2, reviewed_by = $3, reviewed_at = CURRENT_TIMESTAMP, review_comment = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, query, applicationID, statusID, reviewerID, comment); err != nil {
+		log.Error(ctx, "DB error on reviewing guest application", zap.Error(err), zap.String("application_id", applicationID.String()))
+		return err
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// assignment holds
+
+// GetExpiredAssignmentHolds возвращает закрепленные за гостями предложения Offered, срок принятия которых истек к now
+func (r *SecretGuestRepository) GetExpiredAssignmentHolds(ctx context.Context, now time.Time, limit int) ([]*models.ExpiredAssignmentHold, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		SELECT a.id, a.reporter_id, l.title, COALESCE(l.city, ''), a.checkin_date, a.accept_deadline, a.expires_at, a.invited_at
+		FROM assignments a
+		JOIN listings l ON l.id = a.listing_id
+		WHERE a.status_id = $1
+			AND a.reporter_id IS NOT NULL
+			AND a.accept_deadline < $2
+		ORDER BY a.accept_deadline
+		LIMIT $3
+	`
+	rows, err := r.db.Query(ctx, query, models.AssignmentStatusOffered, now, limit)
+	if err != nil {
+		log.Error(ctx, "Failed to query expired assignment holds", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	holds := make([]*models.ExpiredAssignmentHold, 0)
+	for rows.Next() {
+		var h models.ExpiredAssignmentHold
+		if err := rows.Scan(&h.AssignmentID, &h.ReporterID, &h.ListingTitle, &h.City, &h.CheckinDate, &h.AcceptDeadline, &h.ExpiresAt, &h.InvitedAt); err != nil {
+			log.Error(ctx, "Failed to scan expired assignment hold", zap.Error(err))
+			return nil, err
+		}
+		holds = append(holds, &h)
+	}
+
+	return holds, rows.Err()
+}
+
+// ReleaseAssignmentHold возвращает предложение в свободные, уведомляет гостя и предлагает предложение следующему гостю
+// из листа ожидания. Если гость успел принять или отказаться(или срок продлили), возвращает models.ErrAssignmentNotFound
+func (r *SecretGuestRepository) ReleaseAssignmentHold(ctx context.Context, hold *models.ExpiredAssignmentHold, notification *models.Notification, offer *models.WaitlistOffer) (*uuid.UUID, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE assignments
+		SET
+			reporter_id     = NULL,
+			taked_at        = NULL,
+			invited_at      = NULL,
+			accept_deadline = NULL
+		WHERE
+			id = $1
+			AND reporter_id = $2
+			AND status_id = $3
+			AND accept_deadline = $4
+	`, hold.AssignmentID, hold.ReporterID, models.AssignmentStatusOffered, hold.AcceptDeadline)
+	if err != nil {
+		log.Error(ctx, "DB error on releasing assignment hold", zap.Error(err), zap.String("assignment_id", hold.AssignmentID.String()))
+		return nil, err
+	}
+	if ct.RowsAffected() == 0 {
+		return nil, models.ErrAssignmentNotFound
+	}
+
+	if err := insertNotification(ctx, tx, notification); err != nil {
+		return nil, err
+	}
+
+	waitlistUserID, err := offerAssignmentToWaitlist(ctx, tx, hold.AssignmentID, offer, time.Now())
+	if err != nil {
+		log.Error(ctx, "Failed to offer released assignment to waitlist", zap.Error(err), zap.String("assignment_id", hold.AssignmentID.String()))
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return waitlistUserID, nil
+}
+
+// stay checks
+
+// CheckInAssignment отмечает заезд гостя по принятому предложению. Повторная отметка - models.ErrStayCheckNotAllowed
+func (r *SecretGuestRepository) CheckInAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, check *models.StayCheck) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	ct, err := r.db.Exec(ctx, `
+		UPDATE assignments
+		SET
+			checked_in_at         = $1,
+			checked_in_latitude   = $2,
+			checked_in_longitude  = $3,
+			checked_in_distance_m = $4
+		WHERE
+			id = $5
+			AND reporter_id = $6
+			AND status_id = $7
+			AND checked_in_at IS NULL
+	`, check.At, check.Latitude, check.Longitude, check.DistanceMeters, assignmentID, reporterID, models.AssignmentStatusAccepted)
+	if err != nil {
+		log.Error(ctx, "DB error on assignment check-in", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrStayCheckNotAllowed
+	}
+	return nil
+}
+
+// CheckOutAssignment отмечает выезд гостя: только после отметки заезда и не раньше нее, один раз
+func (r *SecretGuestRepository) CheckOutAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, check *models.StayCheck) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	ct, err := r.db.Exec(ctx, `
+		UPDATE assignments
+		SET
+			checked_out_at         = $1,
+			checked_out_latitude   = $2,
+			checked_out_longitude  = $3,
+			checked_out_distance_m = $4
+		WHERE
+			id = $5
+			AND reporter_id = $6
+			AND status_id = $7
+			AND checked_in_at IS NOT NULL
+			AND checked_in_at <= $1
+			AND checked_out_at IS NULL
+	`, check.At, check.Latitude, check.Longitude, check.DistanceMeters, assignmentID, reporterID, models.AssignmentStatusAccepted)
+	if err != nil {
+		log.Error(ctx, "DB error on assignment check-out", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrStayCheckNotAllowed
+	}
+	return nil
+}
+
+// GetAssignmentStay возвращает отметки заезда и выезда по предложению(пустые, если гость их не делал)
+func (r *SecretGuestRepository) GetAssignmentStay(ctx context.Context, assignmentID uuid.UUID) (*models.AssignmentStay, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var inAt, outAt *time.Time
+	var inLat, inLon, outLat, outLon float64
+	var inDistance, outDistance *float64
+	err := r.db.QueryRow(ctx, `
+		SELECT
+			checked_in_at, COALESCE(checked_in_latitude, 0), COALESCE(checked_in_longitude, 0), checked_in_distance_m,
+			checked_out_at, COALESCE(checked_out_latitude, 0), COALESCE(checked_out_longitude, 0), checked_out_distance_m
+		FROM assignments
+		WHERE id = $1
+	`, assignmentID).Scan(&inAt, &inLat, &inLon, &inDistance, &outAt, &outLat, &outLon, &outDistance)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrAssignmentNotFound
+		}
+		log.Error(ctx, "Failed to get assignment stay", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return nil, err
+	}
+
+	stay := &models.AssignmentStay{}
+	if inAt != nil {
+		stay.CheckIn = &models.StayCheck{At: *inAt, Latitude: inLat, Longitude: inLon, DistanceMeters: inDistance}
+	}
+	if outAt != nil {
+		stay.CheckOut = &models.StayCheck{At: *outAt, Latitude: outLat, Longitude: outLon, DistanceMeters: outDistance}
+	}
+	return stay, nil
+}
+
+// report deadlines
+
+// reportLateWindowSQL - сколько после due_at черновик еще можно сдать со штрафом report_late(point_rules.grace_hours).
+// Черновик переводится в "Просрочен" только после этого окна
+var reportLateWindowSQL = fmt.Sprintf(`COALESCE(
+	(SELECT make_interval(hours => COALESCE(grace_hours, 0)) FROM point_rules WHERE event_type = '%s'),
+	INTERVAL '0'
+)`, models.PointEventReportLate)
+
+const dueReportSelectQuery = `
+	SELECT r.id, r.reporter_id, r.assignment_id, l.title, COALESCE(l.city, ''), r.due_at, r.due_reminders_sent
+	FROM reports r
+	JOIN listings l ON l.id = r.listing_id
+`
+
+func scanDueReports(ctx context.Context, rows pgx.Rows) ([]*models.DueReport, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+	defer rows.Close()
+
+	reports := make([]*models.DueReport, 0)
+	for rows.Next() {
+		var d models.DueReport
+		if err := rows.Scan(&d.ReportID, &d.ReporterID, &d.AssignmentID, &d.ListingTitle, &d.City, &d.DueAt, &d.RemindersSent); err != nil {
+			log.Error(ctx, "Failed to scan due report", zap.Error(err))
+			return nil, err
+		}
+		reports = append(reports, &d)
+	}
+
+	return reports, rows.Err()
+}
+
+// GetReportsDueSoon возвращает черновики, срок сдачи которых еще не прошел, но наступит не позже dueBefore,
+// и по которым отправлено меньше maxReminders напоминаний
+func (r *SecretGuestRepository) GetReportsDueSoon(ctx context.Context, now, dueBefore time.Time, maxReminders, limit int) ([]*models.DueReport, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := dueReportSelectQuery + `
+		WHERE r.status_id = $1
+			AND r.reporter_id IS NOT NULL
+			AND r.due_at > $2
+			AND r.due_at <= $3
+			AND r.due_reminders_sent < $4
+		ORDER BY r.due_at
+		LIMIT $5
+	`
+	rows, err := r.db.Query(ctx, query, models.ReportStatusDraft, now, dueBefore, maxReminders, limit)
+	if err != nil {
+		log.Error(ctx, "Failed to query reports due soon", zap.Error(err))
+		return nil, err
+	}
+	return scanDueReports(ctx, rows)
+}
+
+// MarkReportReminded запоминает, сколько напоминаний о сроке отправлено по отчету, и уведомляет гостя.
+// Если отчет уже сдан или напоминание отправлено другим проходом, возвращает models.ErrReportNotFound
+func (r *SecretGuestRepository) MarkReportReminded(ctx context.Context, reportID uuid.UUID, remindersSent int, notification *models.Notification) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE reports SET due_reminders_sent = $3
+		WHERE id = $1 AND status_id = $2 AND due_reminders_sent < $3
+	`, reportID, models.ReportStatusDraft, remindersSent)
+	if err != nil {
+		log.Error(ctx, "DB error on marking report reminded", zap.Error(err), zap.String("report_id", reportID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrReportNotFound
+	}
+
+	if err := insertNotification(ctx, tx, notification); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetOverdueReports возвращает черновики, срок сдачи которых прошел вместе с окном поздней сдачи
+func (r *SecretGuestRepository) GetOverdueReports(ctx context.Context, now time.Time, limit int) ([]*models.DueReport, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := dueReportSelectQuery + `
+		WHERE r.status_id = $1
+			AND r.reporter_id IS NOT NULL
+			AND r.due_at + ` + reportLateWindowSQL + ` <= $2
+		ORDER BY r.due_at
+		LIMIT $3
+	`
+	rows, err := r.db.Query(ctx, query, models.ReportStatusDraft, now, limit)
+	if err != nil {
+		log.Error(ctx, "Failed to query overdue reports", zap.Error(err))
+		return nil, err
+	}
+	return scanDueReports(ctx, rows)
+}
+
+// MarkReportOverdue переводит черновик с прошедшими сроком и окном поздней сдачи в статус "Просрочен": аннулирует начисление,
+// записывает штраф report_overdue и уведомляет гостя. Если гость успел сдать отчет или отказаться
+// (или срок сдвинули), возвращает models.ErrReportNotFound
+func (r *SecretGuestRepository) MarkReportOverdue(ctx context.Context, reportID uuid.UUID, now time.Time, notification *models.Notification) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `
+		UPDATE reports SET status_id = $3, overdue_at = $4, updated_at = $4
+		WHERE id = $1 AND status_id = $2 AND due_at + `+reportLateWindowSQL+` <= $4
+	`, reportID, models.ReportStatusDraft, models.ReportStatusOverdue, now)
+	if err != nil {
+		log.Error(ctx, "DB error on marking report overdue", zap.Error(err), zap.String("report_id", reportID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrReportNotFound
+	}
+
+	if err := settleReportReward(ctx, tx, reportID, models.ReportStatusOverdue, nil); err != nil {
+		return err
+	}
+	if err := recordReportPointEvents(ctx, tx, reportID, models.ReportStatusOverdue); err != nil {
+		return err
+	}
+	if err := insertNotification(ctx, tx, notification); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// BackfillReportDueDates проставляет срок сдачи отчетам без due_at: дата выезда + dueAfterCheckout.
+// Незаконченным черновикам срок считается не раньше now, чтобы они не стали просроченными сразу
+func (r *SecretGuestRepository) BackfillReportDueDates(ctx context.Context, now time.Time, dueAfterCheckout time.Duration) (int64, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	ct, err := r.db.Exec(ctx, `
+		UPDATE reports SET due_at = CASE
+				WHEN status_id = $1 THEN GREATEST(checkout_date, $2) + make_interval(secs => $3)
+				ELSE checkout_date + make_interval(secs => $3)
+			END
+		WHERE due_at IS NULL
+	`, models.ReportStatusDraft, now, dueAfterCheckout.Seconds())
+	if err != nil {
+		log.Error(ctx, "DB error on backfilling report due dates", zap.Error(err))
+		return 0, err
+	}
+	return ct.RowsAffected(), nil
+}
+
+// assignment declines
+
+// declineByReasonQuery - число отказов по причинам в группе(jsonb: slug причины или unspecified - число)
+var declineByReasonQuery = fmt.Sprintf(`
+	(SELECT jsonb_object_agg(x.reason, x.n) FROM (
+		SELECT reason, COUNT(*) AS n FROM unnest(array_agg(COALESCE(dr.slug, '%s'))) AS reason GROUP BY reason
+	) x)
+`, models.DeclineReasonUnspecified)
+
+type DeclineAnalyticsFilter struct {
+	GroupBy string
+	From    *time.Time
+	To      *time.Time
+	Limit   int
+	Offset  int
+}
+
+// GetDeclineAnalytics - отказы от предложений по группам filter.GroupBy, сначала группы с большим числом отказов
+func (r *SecretGuestRepository) GetDeclineAnalytics(ctx context.Context, filter DeclineAnalyticsFilter) ([]*models.DeclineStat, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var key, label string
+	switch filter.GroupBy {
+	case models.DeclineGroupByReason:
+		key, label = fmt.Sprintf(`COALESCE(dr.slug, '%s')`, models.DeclineReasonUnspecified), `COALESCE(dr.name, 'Не указана')`
+	case models.DeclineGroupByCity:
+		key, label = `l.city`, `l.city`
+	case models.DeclineGroupByListing:
+		key, label = `l.id::text`, `l.title`
+	default:
+		return nil, 0, models.ErrValidationFailed
+	}
+
+	baseQuery := `
+		FROM assignment_declines ad
+		JOIN assignments a ON a.id = ad.assignment_id
+		JOIN listings l ON l.id = a.listing_id
+		LEFT JOIN decline_reasons dr ON dr.id = ad.reason_id
+		WHERE ad.declined_at IS NOT NULL
+			AND ($1::timestamp IS NULL OR ad.declined_at >= $1)
+			AND ($2::timestamp IS NULL OR ad.declined_at < $2)
+	`
+
+	var total int
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(DISTINCT `+key+`) `+baseQuery, filter.From, filter.To).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to count decline analytics groups", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.DeclineStat{}, 0, nil
+	}
+
+	query := `
+		SELECT
+			` + key + ` AS key, ` + label + ` AS label,
+			COUNT(*), COUNT(DISTINCT ad.assignment_id), COUNT(DISTINCT ad.reporter_id),
+			` + declineByReasonQuery + `
+		` + baseQuery + `
+		GROUP BY 1, 2
+		ORDER BY 3 DESC, 2
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, filter.From, filter.To, filter.Limit, filter.Offset)
+	if err != nil {
+		log.Error(ctx, "Failed to query decline analytics", zap.Error(err), zap.String("group_by", filter.GroupBy))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	stats := make([]*models.DeclineStat, 0, filter.Limit)
+	for rows.Next() {
+		var st models.DeclineStat
+		if err := rows.Scan(&st.Key, &st.Label, &st.Declines, &st.Assignments, &st.Guests, &st.ByReason); err != nil {
+			log.Error(ctx, "Failed to scan decline analytics row", zap.Error(err))
+			return nil, total, err
+		}
+		stats = append(stats, &st)
+	}
+
+	return stats, total, rows.Err()
+}
+
+// GetRepeatedlyDeclinedAssignments - еще открытые предложения, от которых отказались не меньше minDeclines раз
+func (r *SecretGuestRepository) GetRepeatedlyDeclinedAssignments(ctx context.Context, minDeclines, limit, offset int) ([]*models.DeclinedAssignment, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	baseQuery := `
+		WITH d AS (
+			SELECT
+				ad.assignment_id,
+				COUNT(*) AS declines,
+				MAX(ad.declined_at) AS last_declined_at,
+				` + declineByReasonQuery + ` AS by_reason,
+				COALESCE(array_agg(ad.comment ORDER BY ad.declined_at DESC) FILTER (WHERE ad.comment IS NOT NULL), '{}') AS comments
+			FROM assignment_declines ad
+			LEFT JOIN decline_reasons dr ON dr.id = ad.reason_id
+			GROUP BY ad.assignment_id
+			HAVING COUNT(*) >= $1
+		)
+	`
+	fromClause := `
+		FROM d
+		JOIN assignments a ON a.id = d.assignment_id
+		JOIN listings l ON l.id = a.listing_id
+		WHERE a.status_id = $2 AND a.expires_at > CURRENT_TIMESTAMP
+	`
+
+	var total int
+	if err := r.db.QueryRow(ctx, baseQuery+`SELECT COUNT(*)`+fromClause, minDeclines, models.AssignmentStatusOffered).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to count repeatedly declined assignments", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.DeclinedAssignment{}, 0, nil
+	}
+
+	query := baseQuery + `
+		SELECT
+			a.id, l.id, l.title, l.city, a.purpose, a.checkin_date, a.checkout_date, a.expires_at, a.campaign_id,
+			d.declines, d.last_declined_at, d.by_reason, d.comments
+	` + fromClause + `
+		ORDER BY d.declines DESC, d.last_declined_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, minDeclines, models.AssignmentStatusOffered, limit, offset)
+	if err != nil {
+		log.Error(ctx, "Failed to query repeatedly declined assignments", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	assignments := make([]*models.DeclinedAssignment, 0, limit)
+	for rows.Next() {
+		var da models.DeclinedAssignment
+		if err := rows.Scan(
+			&da.AssignmentID, &da.ListingID, &da.ListingTitle, &da.City, &da.Purpose,
+			&da.CheckinDate, &da.CheckoutDate, &da.ExpiresAt, &da.CampaignID,
+			&da.Declines, &da.LastDeclinedAt, &da.ByReason, &da.Comments,
+		); err != nil {
+			log.Error(ctx, "Failed to scan repeatedly declined assignment row", zap.Error(err))
+			return nil, total, err
+		}
+		assignments = append(assignments, &da)
+	}
+
+	return assignments, total, rows.Err()
+}
+
+// listing priority
+
+// listingPriorityJoins - составляющие приоритета проверки объекта l. Ожидает listing_types lt
+var listingPriorityJoins = fmt.Sprintf(`
+	LEFT JOIN LATERAL (
+		SELECT MAX(rp.submitted_at) AS at FROM reports rp WHERE rp.listing_id = l.id AND rp.status_id = %[1]d
+	) li ON true
+	LEFT JOIN LATERAL (
+		SELECT rp.quality_score AS score FROM reports rp
+		WHERE rp.listing_id = l.id AND rp.status_id = %[1]d AND rp.quality_score IS NOT NULL
+		ORDER BY rp.submitted_at DESC
+		LIMIT 1
+	) lq ON true
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS cnt FROM listing_complaints lc
+		WHERE lc.listing_id = l.id AND lc.received_at > CURRENT_TIMESTAMP - make_interval(days => %[2]d)
+	) lcc ON true
+`, models.ReportStatusApproved, models.ListingPriorityComplaintDays)
+
+// listingPriorityScore - приоритет проверки, формула описана у models.ListingPriorityRecencyPoints
+var listingPriorityScore = fmt.Sprintf(`
+	ROUND(((
+		CASE WHEN li.at IS NULL THEN %[1]d
+			ELSE %[1]d * LEAST(EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - li.at) / 86400, %[2]d) / %[2]d END
+		+ CASE WHEN lq.score IS NULL THEN %[3]d / 2.0
+			ELSE %[3]d * (100 - lq.score) / 100.0 END
+		+ %[4]d * LEAST(lcc.cnt, %[5]d) / %[5]d.0
+	) * lt.priority_weight / 100.0)::numeric, 1)::float8
+`,
+	models.ListingPriorityRecencyPoints, models.ListingPriorityStaleDays,
+	models.ListingPriorityQualityPoints,
+	models.ListingPriorityComplaintPoints, models.ListingPriorityMaxComplaints,
+)
+
+// listingPriorityColumns - поля models.ListingPriority в порядке scanListingPriority
+var listingPriorityColumns = listingPriorityScore + `, li.at, lq.score, lcc.cnt, lt.priority_weight`
+
+func scanListingPriority(p *models.ListingPriority) []any {
+	return []any{&p.Score, &p.LastInspectedAt, &p.LastQualityScore, &p.Complaints, &p.ListingTypeWeight}
+}
+
+type PrioritizedListingsFilter struct {
+	City           string
+	ListingTypeIDs []int
+	MinScore       *float64
+	Limit          int
+	Offset         int
+}
+
+// GetPrioritizedListings - объекты с приоритетом проверки, сначала самые приоритетные
+func (r *SecretGuestRepository) GetPrioritizedListings(ctx context.Context, filter PrioritizedListingsFilter) ([]*models.Listing, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	conditions := []string{}
+	args := []any{}
+	if filter.City != "" {
+		args = append(args, "%"+filter.City+"%")
+		conditions = append(conditions, fmt.Sprintf("l.city ILIKE $%d", len(args)))
+	}
+	if len(filter.ListingTypeIDs) > 0 {
+		args = append(args, filter.ListingTypeIDs)
+		conditions = append(conditions, fmt.Sprintf("l.listing_type_id = ANY($%d)", len(args)))
+	}
+	if filter.MinScore != nil {
+		args = append(args, *filter.MinScore)
+		conditions = append(conditions, fmt.Sprintf("l.score >= $%d", len(args)))
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	baseQuery := `
+		WITH lp AS (
+			SELECT
+				l.id, l.code, l.title, l.description, l.main_picture, l.listing_type_id, l.address, l.city, l.country,
+				l.latitude, l.longitude, l.created_at,
+				lt.id AS listing_type_ref, lt.slug AS listing_type_slug, lt.name AS listing_type_name,
+				` + listingPriorityScore + ` AS score,
+				li.at AS last_inspected_at, lq.score AS last_quality_score, lcc.cnt AS complaints, lt.priority_weight
+			FROM listings l
+			JOIN listing_types lt ON lt.id = l.listing_type_id
+			` + listingPriorityJoins + `
+		)
+	`
+
+	var total int
+	if err := r.db.QueryRow(ctx, baseQuery+`SELECT COUNT(*) FROM lp l`+whereClause, args...).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to count prioritized listings", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.Listing{}, 0, nil
+	}
+
+	query := baseQuery + `
+		SELECT
+			l.id, l.code, l.title, l.description, l.main_picture, l.listing_type_id, l.address, l.city, l.country,
+			l.latitude, l.longitude, l.created_at,
+			l.listing_type_ref, l.listing_type_slug, l.listing_type_name,
+			l.score, l.last_inspected_at, l.last_quality_score, l.complaints, l.priority_weight
+		FROM lp l` + whereClause + fmt.Sprintf(`
+		ORDER BY l.score DESC, l.last_inspected_at NULLS FIRST, l.id
+		LIMIT $%d OFFSET $%d
+	`, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "Failed to query prioritized listings", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	listings := make([]*models.Listing, 0, filter.Limit)
+	for rows.Next() {
+		l := models.Listing{Priority: &models.ListingPriority{}}
+		dest := []any{
+			&l.ID, &l.Code, &l.Title, &l.Description, &l.MainPicture, &l.ListingTypeID, &l.Address, &l.City, &l.Country,
+			&l.Latitude, &l.Longitude, &l.CreatedAt,
+			&l.ListingType.ID, &l.ListingType.Slug, &l.ListingType.Name,
+		}
+		if err := rows.Scan(append(dest, scanListingPriority(l.Priority)...)...); err != nil {
+			log.Error(ctx, "Failed to scan prioritized listing row", zap.Error(err))
+			return nil, total, err
+		}
+		listings = append(listings, &l)
+	}
+
+	return listings, total, rows.Err()
+}
+
+func (r *SecretGuestRepository) GetListingPriority(ctx context.Context, listingID uuid.UUID) (*models.ListingPriority, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		SELECT ` + listingPriorityColumns + `
+		FROM listings l
+		JOIN listing_types lt ON lt.id = l.listing_type_id
+		` + listingPriorityJoins + `
+		WHERE l.id = $1
+	`
+	var p models.ListingPriority
+	if err := r.db.QueryRow(ctx, query, listingID).Scan(scanListingPriority(&p)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrListingNotFound
+		}
+		log.Error(ctx, "Failed to query listing priority", zap.Error(err), zap.String("listing_id", listingID.String()))
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *SecretGuestRepository) GetListingTypePriorityWeights(ctx context.Context) ([]*models.ListingTypePriorityWeight, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rows, err := r.db.Query(ctx, `SELECT id, slug, name, priority_weight FROM listing_types ORDER BY id`)
+	if err != nil {
+		log.Error(ctx, "Failed to query listing type priority weights", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	weights, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[models.ListingTypePriorityWeight])
+	if err != nil {
+		log.Error(ctx, "Failed to scan listing type priority weights", zap.Error(err))
+		return nil, err
+	}
+	return weights, nil
+}
+
+func (r *SecretGuestRepository) SaveListingTypePriorityWeight(ctx context.Context, listingTypeID, weight int, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	ct, err := tx.Exec(ctx, `UPDATE listing_types SET priority_weight = $2 WHERE id = $1`, listingTypeID, weight)
+	if err != nil {
+		log.Error(ctx, "DB error on saving listing type priority weight", zap.Error(err), zap.Int("listing_type_id", listingTypeID))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrListingTypeNotFound
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *SecretGuestRepository) CreateListingComplaint(ctx context.Context, c *models.ListingComplaint) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		INSERT INTO listing_complaints (listing_id, external_id, category, comment, received_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRow(ctx, query, c.ListingID, c.ExternalID, c.Category, c.Comment, c.ReceivedAt, c.CreatedBy).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return models.ErrComplaintDuplicate
+			case "23503":
+				return models.ErrListingNotFound
+			}
+		}
+		log.Error(ctx, "DB error on creating listing complaint", zap.Error(err), zap.String("listing_id", c.ListingID.String()))
+		return err
+	}
+	return nil
+}
+
+// notifications
+
+func insertNotification(ctx context.Context, db dbExecutor, n *models.Notification) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		INSERT INTO notifications (user_id, type, title, body, payload)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := db.Exec(ctx, query, n.UserID, n.Type, n.Title, n.Body, n.Payload); err != nil {
+		log.Error(ctx, "DB error on inserting notification", zap.Error(err), zap.String("type", n.Type), zap.String("user_id", n.UserID.String()))
+		return err
+	}
+	return nil
+}
+
+type NotificationsFilter struct {
+	UserID     uuid.UUID
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+// GetNotifications возвращает уведомления пользователя(новые сверху), их количество по фильтру и число непрочитанных
+func (r *SecretGuestRepository) GetNotifications(ctx context.Context, filter NotificationsFilter) ([]*models.Notification, int, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var total, unread int
+	countQuery := `
+		SELECT
+			COUNT(*) FILTER (WHERE NOT $2 OR read_at IS NULL),
+			COUNT(*) FILTER (WHERE read_at IS NULL)
+		FROM notifications
+		WHERE user_id = $1
+	`
+	if err := r.db.QueryRow(ctx, countQuery, filter.UserID, filter.UnreadOnly).Scan(&total, &unread); err != nil {
+		log.Error(ctx, "Failed to count notifications", zap.Error(err))
+		return nil, 0, 0, err
+	}
+	if total == 0 {
+		return []*models.Notification{}, 0, unread, nil
+	}
+
+	query := `
+		SELECT id, user_id, type, title, body, payload, created_at, read_at
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, filter.UserID, filter.UnreadOnly, filter.Limit, filter.Offset)
+	if err != nil {
+		log.Error(ctx, "Failed to query notifications", zap.Error(err))
+		return nil, total, unread, err
+	}
+	defer rows.Close()
+
+	notifications := make([]*models.Notification, 0, filter.Limit)
+	for rows.Next() {
+		var n models.Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.Payload, &n.CreatedAt, &n.ReadAt); err != nil {
+			log.Error(ctx, "Failed to scan notification row", zap.Error(err))
+			return nil, total, unread, err
+		}
+		notifications = append(notifications, &n)
+	}
+
+	return notifications, total, unread, rows.Err()
+}
+
+// MarkNotificationRead отмечает уведомление пользователя прочитанным, повторная отметка не меняет дату
+func (r *SecretGuestRepository) MarkNotificationRead(ctx context.Context, notificationID, userID uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+		WHERE id = $1 AND user_id = $2
+	`
+	ct, err := r.db.Exec(ctx, query, notificationID, userID)
+	if err != nil {
+		log.Error(ctx, "DB error on marking notification read", zap.Error(err), zap.String("notification_id", notificationID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrNotificationNotFound
+	}
+	return nil
+}
+
+// leaderboard
+
+type LeaderboardFilter struct {
+	From   *time.Time // начало периода(день), nil - за все время
+	City   string     // домашний город из профиля, без учета регистра
+	Limit  int
+	Offset int
+}
+
+// leaderboardQuery - гости с положительной суммой очков за период, кроме удаленных и скрывших себя из таблицы
+const leaderboardQuery = `
+	WITH totals AS (
+		SELECT d.user_id, SUM(d.points) AS points
+		FROM user_points_daily d
+		WHERE $1::date IS NULL OR d.day >= $1::date
+		GROUP BY d.user_id
+	), board AS (
+		SELECT
+			RANK() OVER (ORDER BY t.points DESC) AS position,
+			t.user_id,
+			u.username,
+			up.additional_info,
+			t.points
+		FROM totals t
+		JOIN users u ON u.id = t.user_id AND u.deleted_at IS NULL
+		JOIN user_profiles up ON up.user_id = t.user_id
+		WHERE t.points > 0
+			AND COALESCE((up.additional_info->>'hide_from_leaderboard')::boolean, false) = false
+			AND ($2 = '' OR lower(up.additional_info->>'home_city') = lower($2))
+	)
+`
+
+func (r *SecretGuestRepository) GetLeaderboard(ctx context.Context, filter LeaderboardFilter) ([]*models.LeaderboardEntry, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var total int
+	if err := r.db.QueryRow(ctx, leaderboardQuery+`SELECT COUNT(*) FROM board`, filter.From, filter.City).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to count leaderboard", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.LeaderboardEntry{}, 0, nil
+	}
+
+	query := leaderboardQuery + `
+		SELECT position, user_id, username, additional_info, points
+		FROM board
+		ORDER BY position, username
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Query(ctx, query, filter.From, filter.City, filter.Limit, filter.Offset)
+	if err != nil {
+		log.Error(ctx, "Failed to query leaderboard", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.LeaderboardEntry, 0, filter.Limit)
+	for rows.Next() {
+		var e models.LeaderboardEntry
+		if err := rows.Scan(&e.Position, &e.UserID, &e.Username, &e.AdditionalInfo, &e.Points); err != nil {
+			log.Error(ctx, "Failed to scan leaderboard row", zap.Error(err))
+			return nil, total, err
+		}
+		entries = append(entries, &e)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// GetLeaderboardEntry - место гостя в таблице лидеров, models.ErrNotFound - гостя в таблице нет
+func (r *SecretGuestRepository) GetLeaderboardEntry(ctx context.Context, filter LeaderboardFilter, userID uuid.UUID) (*models.LeaderboardEntry, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := leaderboardQuery + `
+		SELECT position, user_id, username, additional_info, points
+		FROM board
+		WHERE user_id = $3
+	`
+	var e models.LeaderboardEntry
+	err := r.db.QueryRow(ctx, query, filter.From, filter.City, userID).Scan(&e.Position, &e.UserID, &e.Username, &e.AdditionalInfo, &e.Points)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrNotFound
+		}
+		log.Error(ctx, "Failed to query leaderboard entry", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+// profiles
+
+func (r *SecretGuestRepository) GetUserProfileByID(ctx context.Context, userID uuid.UUID) (*models.UserProfile, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+	query := `
+		SELECT
+			up.id,
+			up.user_id,
+			up.accepted_offers_count,
+			up.submitted_reports_count,
+			up.correct_reports_count,
+			up.registered_at,
+			up.last_active_at,
+			up.additional_info,
+			COALESCE((SELECT SUM(pe.points) FROM point_events pe WHERE pe.user_id = up.user_id), 0),
+			u.username,
+			COALESCE(u.email, '')
+		FROM user_profiles up
+		JOIN users u ON up.user_id = u.id
+		WHERE up.user_id = $1
+	`
+	row := r.db.QueryRow(ctx, query, userID)
+
+	var p models.UserProfile
+	err := row.Scan(
+		&p.ID,
+		&p.UserID,
+		&p.AcceptedOffersCount,
+		&p.SubmittedReportsCount,
+		&p.CorrectReportsCount,
+		&p.RegisteredAt,
+		&p.LastActiveAt,
+		&p.AdditionalInfo,
+		&p.Points,
+		&p.Username,
+		&p.Email,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Info(ctx, "User profile not found by user ID", zap.String("user_id", userID.String()))
+			return nil, models.ErrNotFound
+		}
+		log.Error(ctx, "Failed to query user profile by ID", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *SecretGuestRepository) UpdateUserProfileInfo(ctx context.Context, userID uuid.UUID, info json.RawMessage) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `UPDATE user_profiles SET additional_info = $2 WHERE user_id = $1`
+
+	cmdTag, err := r.db.Exec(ctx, query, userID, info)
+	if err != nil {
+		log.Error(ctx, "Failed to update user profile info", zap.Error(err), zap.String("user_id", userID.String()))
+		return err
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	return nil
 }
 
 func (r *SecretGuestRepository) GetAllUserProfiles(ctx context.Context, limit, offset int) ([]*models.UserProfile, int, error) {
@@ -2790,8 +5391,9 @@ func (r *SecretGuestRepository) GetAllUserProfiles(ctx context.Context, limit, o
 			up.registered_at,
 			up.last_active_at,
 			up.additional_info,
+			COALESCE((SELECT SUM(pe.points) FROM point_events pe WHERE pe.user_id = up.user_id), 0),
 			u.username,
-			u.email
+			COALESCE(u.email, '')
 		FROM user_profiles up
 		JOIN users u ON up.user_id = u.id
 		ORDER BY up.registered_at DESC
@@ -2817,6 +5419,7 @@ func (r *SecretGuestRepository) GetAllUserProfiles(ctx context.Context, limit, o
 			&p.RegisteredAt,
 			&p.LastActiveAt,
 			&p.AdditionalInfo,
+			&p.Points,
 			&p.Username,
 			&p.Email,
 		)
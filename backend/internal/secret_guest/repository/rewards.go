@@ -0,0 +1,450 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// rewards
+
+type RewardsFilter struct {
+	UserID       *uuid.UUID
+	StatusIDs    []int
+	ApprovedFrom *time.Time // период одобрения начислений(для выгрузки в бухгалтерию)
+	ApprovedTo   *time.Time
+	Limit        int
+	Offset       int
+}
+
+const rewardSelectQuery = `
+	SELECT
+		rw.id,
+		rw.assignment_id,
+		rw.report_id,
+		rw.user_id,
+		COALESCE(u.username, ''),
+		rw.listing_id,
+		l.title,
+		rw.listing_type_id,
+		rw.amount,
+		rw.currency,
+		rw.percent,
+		rw.status_id,
+		s.slug as "status_slug",
+		s.name as "status_name",
+		rw.created_at,
+		rw.updated_at,
+		rw.approved_at,
+		rw.paid_at,
+		rw.paid_by,
+		rw.payout_reference,
+		rw.voided_at,
+		rw.voided_by,
+		rw.void_reason
+	FROM rewards rw
+	LEFT JOIN users u ON rw.user_id = u.id
+	JOIN listings l ON rw.listing_id = l.id
+	JOIN reward_statuses s ON rw.status_id = s.id
+`
+
+func scanReward(row pgx.Row, rw *models.Reward) error {
+	err := row.Scan(
+		&rw.ID, &rw.AssignmentID, &rw.ReportID, &rw.UserID, &rw.Username,
+		&rw.ListingID, &rw.ListingTitle, &rw.ListingTypeID,
+		&rw.Amount, &rw.Currency, &rw.Percent,
+		&rw.StatusID, &rw.Status.Slug, &rw.Status.Name,
+		&rw.CreatedAt, &rw.UpdatedAt, &rw.ApprovedAt,
+		&rw.PaidAt, &rw.PaidBy, &rw.PayoutReference,
+		&rw.VoidedAt, &rw.VoidedBy, &rw.VoidReason,
+	)
+	rw.Status.ID = rw.StatusID
+	return err
+}
+
+func buildRewardsWhereClause(filter RewardsFilter) (string, []interface{}, int) {
+	conditions := []string{}
+	args := []interface{}{}
+	paramCount := 1
+
+	if filter.UserID != nil {
+		conditions = append(conditions, fmt.Sprintf("rw.user_id = $%d", paramCount))
+		args = append(args, *filter.UserID)
+		paramCount++
+	}
+	if len(filter.StatusIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("rw.status_id = ANY($%d)", paramCount))
+		args = append(args, filter.StatusIDs)
+		paramCount++
+	}
+	if filter.ApprovedFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("rw.approved_at >= $%d", paramCount))
+		args = append(args, *filter.ApprovedFrom)
+		paramCount++
+	}
+	if filter.ApprovedTo != nil {
+		conditions = append(conditions, fmt.Sprintf("rw.approved_at < $%d", paramCount))
+		args = append(args, *filter.ApprovedTo)
+		paramCount++
+	}
+
+	if len(conditions) == 0 {
+		return "", args, paramCount
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, paramCount
+}
+
+func (r *SecretGuestRepository) GetRewards(ctx context.Context, filter RewardsFilter) ([]*models.Reward, int, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	whereClause, args, paramCount := buildRewardsWhereClause(filter)
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM rewards rw"+whereClause, args...).Scan(&total); err != nil {
+		log.Error(ctx, "Failed to query total rewards count", zap.Error(err))
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*models.Reward{}, 0, nil
+	}
+
+	query := rewardSelectQuery + whereClause +
+		fmt.Sprintf(" ORDER BY rw.created_at DESC, rw.id LIMIT $%d OFFSET $%d", paramCount, paramCount+1)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		log.Error(ctx, "Failed to query rewards", zap.Error(err))
+		return nil, total, err
+	}
+	defer rows.Close()
+
+	rewards := make([]*models.Reward, 0, filter.Limit)
+	for rows.Next() {
+		var rw models.Reward
+		if err := scanReward(rows, &rw); err != nil {
+			log.Error(ctx, "Failed to scan reward row", zap.Error(err))
+			return nil, total, err
+		}
+		rewards = append(rewards, &rw)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Error(ctx, "Error after iterating over reward rows", zap.Error(err))
+		return nil, total, err
+	}
+
+	return rewards, total, nil
+}
+
+func (r *SecretGuestRepository) GetRewardByID(ctx context.Context, rewardID uuid.UUID) (*models.Reward, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var rw models.Reward
+	if err := scanReward(r.db.QueryRow(ctx, rewardSelectQuery+" WHERE rw.id = $1", rewardID), &rw); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrRewardNotFound
+		}
+		log.Error(ctx, "DB error on getting reward", zap.Error(err), zap.String("reward_id", rewardID.String()))
+		return nil, err
+	}
+
+	return &rw, nil
+}
+
+// GetRewardTotals - суммы начислений пользователя по статусам и валютам
+func (r *SecretGuestRepository) GetRewardTotals(ctx context.Context, userID uuid.UUID) ([]*models.RewardTotal, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		SELECT rw.status_id, s.slug, s.name, rw.currency, SUM(rw.amount)::integer, COUNT(*)::integer
+		FROM rewards rw
+		JOIN reward_statuses s ON rw.status_id = s.id
+		WHERE rw.user_id = $1
+		GROUP BY rw.status_id, s.slug, s.name, rw.currency
+		ORDER BY rw.status_id, rw.currency
+	`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		log.Error(ctx, "Failed to query reward totals", zap.Error(err), zap.String("user_id", userID.String()))
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := []*models.RewardTotal{}
+	for rows.Next() {
+		var t models.RewardTotal
+		if err := rows.Scan(&t.StatusID, &t.Status.Slug, &t.Status.Name, &t.Currency, &t.Amount, &t.Count); err != nil {
+			log.Error(ctx, "Failed to scan reward total row", zap.Error(err))
+			return nil, err
+		}
+		t.Status.ID = t.StatusID
+		totals = append(totals, &t)
+	}
+
+	return totals, rows.Err()
+}
+
+// PayRewards отмечает начисления выплаченными одной операцией: если хотя бы одно не найдено
+// или не в статусе "К выплате", ничего не меняется
+func (r *SecretGuestRepository) PayRewards(ctx context.Context, rewardIDs []uuid.UUID, paidBy uuid.UUID, reference string, entries []*models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT id, status_id FROM rewards WHERE id = ANY($1) FOR UPDATE`, rewardIDs)
+	if err != nil {
+		log.Error(ctx, "DB error on locking rewards", zap.Error(err))
+		return err
+	}
+	found := 0
+	for rows.Next() {
+		var id uuid.UUID
+		var statusID int
+		if err := rows.Scan(&id, &statusID); err != nil {
+			rows.Close()
+			log.Error(ctx, "Failed to scan locked reward row", zap.Error(err))
+			return err
+		}
+		if statusID != models.RewardStatusApproved {
+			rows.Close()
+			return fmt.Errorf("reward %s: %w", id, models.ErrRewardNotPayable)
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if found != len(rewardIDs) {
+		return models.ErrRewardNotFound
+	}
+
+	query := `
+		UPDATE rewards
+		SET status_id = $2, paid_at = CURRENT_TIMESTAMP, paid_by = $3, payout_reference = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ANY($1)
+	`
+	if _, err := tx.Exec(ctx, query, rewardIDs, models.RewardStatusPaid, paidBy, reference); err != nil {
+		log.Error(ctx, "DB error on paying rewards", zap.Error(err))
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// VoidReward аннулирует начисление, которое еще не выплачено
+func (r *SecretGuestRepository) VoidReward(ctx context.Context, rewardID, voidedBy uuid.UUID, reason string, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var currentStatusID int
+	err = tx.QueryRow(ctx, `SELECT status_id FROM rewards WHERE id = $1 FOR UPDATE`, rewardID).Scan(&currentStatusID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ErrRewardNotFound
+		}
+		log.Error(ctx, "DB error on locking reward", zap.Error(err), zap.String("reward_id", rewardID.String()))
+		return err
+	}
+	if currentStatusID != models.RewardStatusPending && currentStatusID != models.RewardStatusApproved {
+		return models.ErrRewardNotVoidable
+	}
+
+	query := `
+		UPDATE rewards
+		SET status_id = $2, voided_at = CURRENT_TIMESTAMP, voided_by = $3, void_reason = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, query, rewardID, models.RewardStatusVoided, voidedBy, reason); err != nil {
+		log.Error(ctx, "DB error on voiding reward", zap.Error(err), zap.String("reward_id", rewardID.String()))
+		return err
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// insertReward - начисление создается в транзакции принятия задания
+func insertReward(ctx context.Context, db dbExecutor, rw *models.Reward) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	query := `
+		INSERT INTO rewards (id, assignment_id, report_id, user_id, listing_id, listing_type_id, amount, currency, percent, status_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := db.Exec(ctx, query,
+		rw.ID, rw.AssignmentID, rw.ReportID, rw.UserID, rw.ListingID, rw.ListingTypeID,
+		rw.Amount, rw.Currency, rw.Percent, models.RewardStatusPending,
+	)
+	if err != nil {
+		log.Error(ctx, "DB error on inserting reward", zap.Error(err), zap.String("assignment_id", rw.AssignmentID.String()))
+		return err
+	}
+
+	return nil
+}
+
+// settleReportReward переводит ожидающее начисление по отчету в "К выплате" или аннулирует его
+// вслед за решением по отчету. Отчетов без начисления(созданных до введения начислений) это не касается.
+func settleReportReward(ctx context.Context, db dbExecutor, reportID uuid.UUID, reportStatusID int, actorID *uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var query string
+	var args []interface{}
+	switch reportStatusID {
+	case models.ReportStatusApproved:
+		query = `
+			UPDATE rewards
+			SET status_id = $2, approved_at = CURRENT_TIMESTAMP, approved_by = $3, updated_at = CURRENT_TIMESTAMP
+			WHERE report_id = $1 AND status_id = $4
+		`
+		args = []interface{}{reportID, models.RewardStatusApproved, actorID, models.RewardStatusPending}
+	case models.ReportStatusRejected, models.ReportStatusRefused, models.ReportStatusOverdue:
+		reason := "report rejected"
+		switch reportStatusID {
+		case models.ReportStatusRefused:
+			reason = "report refused by guest"
+		case models.ReportStatusOverdue:
+			reason = "report overdue"
+		}
+		query = `
+			UPDATE rewards
+			SET status_id = $2, voided_at = CURRENT_TIMESTAMP, voided_by = $3, void_reason = $5, updated_at = CURRENT_TIMESTAMP
+			WHERE report_id = $1 AND status_id = $4
+		`
+		args = []interface{}{reportID, models.RewardStatusVoided, actorID, models.RewardStatusPending, reason}
+	default:
+		return nil
+	}
+
+	if _, err := db.Exec(ctx, query, args...); err != nil {
+		log.Error(ctx, "DB error on settling report reward", zap.Error(err), zap.String("report_id", reportID.String()))
+		return err
+	}
+	return nil
+}
+
+// reward_policies
+
+const rewardPolicySelectQuery = `
+	SELECT
+		lt.id,
+		lt.slug,
+		lt.name,
+		COALESCE(rp.percent, $1),
+		rp.max_amount,
+		rp.updated_at
+	FROM listing_types lt
+	LEFT JOIN reward_policies rp ON rp.listing_type_id = lt.id
+`
+
+func scanRewardPolicy(row pgx.Row, p *models.RewardPolicy) error {
+	return row.Scan(&p.ListingTypeID, &p.ListingTypeSlug, &p.ListingTypeName, &p.Percent, &p.MaxAmount, &p.UpdatedAt)
+}
+
+// GetRewardPolicies возвращает политику для каждого типа объекта, в том числе ненастроенную(по умолчанию)
+func (r *SecretGuestRepository) GetRewardPolicies(ctx context.Context) ([]*models.RewardPolicy, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	rows, err := r.db.Query(ctx, rewardPolicySelectQuery+" ORDER BY lt.id", models.DefaultRewardPercent)
+	if err != nil {
+		log.Error(ctx, "Failed to query reward policies", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []*models.RewardPolicy{}
+	for rows.Next() {
+		var p models.RewardPolicy
+		if err := scanRewardPolicy(rows, &p); err != nil {
+			log.Error(ctx, "Failed to scan reward policy row", zap.Error(err))
+			return nil, err
+		}
+		policies = append(policies, &p)
+	}
+
+	return policies, rows.Err()
+}
+
+func (r *SecretGuestRepository) GetRewardPolicy(ctx context.Context, listingTypeID int) (*models.RewardPolicy, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var p models.RewardPolicy
+	err := scanRewardPolicy(r.db.QueryRow(ctx, rewardPolicySelectQuery+" WHERE lt.id = $2", models.DefaultRewardPercent, listingTypeID), &p)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrListingTypeNotFound
+		}
+		log.Error(ctx, "DB error on getting reward policy", zap.Error(err), zap.Int("listing_type_id", listingTypeID))
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// SaveRewardPolicy задает политику типа объекта. Уже созданные начисления не пересчитываются.
+func (r *SecretGuestRepository) SaveRewardPolicy(ctx context.Context, policy *models.RewardPolicy, actorID uuid.UUID, entry *models.AuditLogEntry) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO reward_policies (listing_type_id, percent, max_amount, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (listing_type_id) DO UPDATE
+		SET percent = EXCLUDED.percent,
+			max_amount = EXCLUDED.max_amount,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	if _, err := tx.Exec(ctx, query, policy.ListingTypeID, policy.Percent, policy.MaxAmount, actorID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.ErrListingTypeNotFound
+		}
+		log.Error(ctx, "DB error on saving reward policy", zap.Error(err), zap.Int("listing_type_id", policy.ListingTypeID))
+		return err
+	}
+
+	if err := insertAuditLogEntry(ctx, tx, entry); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,139 @@
+//go:build integration
+
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acceptWithReward принимает новое предложение гостя с ожидающим начислением и возвращает отчет по нему
+func (f *fixture) acceptWithReward(guestID uuid.UUID, checkin time.Time) uuid.UUID {
+	ctx := context.Background()
+
+	var listingTypeID int
+	require.NoError(f.t, f.pool.QueryRow(ctx, `SELECT listing_type_id FROM listings WHERE id = $1`, f.listingID).Scan(&listingTypeID))
+
+	assignmentID := f.createFreeAssignment(checkin, 2)
+	require.NoError(f.t, f.repo.TakeFreeAssignmentsByID(ctx, assignmentID, guestID, time.Now(), nil))
+	report, err := f.repo.AcceptMyAssignment(ctx, assignmentID, guestID, time.Now(), 48*time.Hour, &models.Reward{
+		ID:            uuid.New(),
+		AssignmentID:  assignmentID,
+		UserID:        &guestID,
+		ListingID:     f.listingID,
+		ListingTypeID: listingTypeID,
+		Amount:        3000,
+		Currency:      "RUB",
+		Percent:       30,
+	})
+	require.NoError(f.t, err)
+
+	f.t.Cleanup(func() {
+		_, _ = f.pool.Exec(context.Background(), `DELETE FROM rewards WHERE assignment_id = $1`, assignmentID)
+	})
+	return report.ID
+}
+
+func TestReportRewardSettlement(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	staffID := f.createGuest()
+	base := time.Now().AddDate(0, 7, 0).Truncate(24 * time.Hour)
+
+	cases := []struct {
+		name         string
+		fromStatusID int
+		settle       func(reportID, guestID uuid.UUID) error
+		wantStatusID int
+		wantReason   *string
+		wantActor    bool
+	}{
+		{
+			name:         "approve",
+			fromStatusID: models.ReportStatusSubmitted,
+			settle: func(reportID, _ uuid.UUID) error {
+				_, err := f.repo.ReviewReport(ctx, reportID, models.ReportStatusApproved, staffID, nil)
+				return err
+			},
+			wantStatusID: models.RewardStatusApproved,
+			wantActor:    true,
+		},
+		{
+			name:         "reject",
+			fromStatusID: models.ReportStatusSubmitted,
+			settle: func(reportID, _ uuid.UUID) error {
+				_, err := f.repo.ReviewReport(ctx, reportID, models.ReportStatusRejected, staffID, nil)
+				return err
+			},
+			wantStatusID: models.RewardStatusVoided,
+			wantReason:   ptr("report rejected"),
+			wantActor:    true,
+		},
+		{
+			name:         "refuse by guest",
+			fromStatusID: models.ReportStatusDraft,
+			settle: func(reportID, guestID uuid.UUID) error {
+				return f.repo.UpdateMyReportStatus(ctx, reportID, guestID, models.ReportStatusDraft, models.ReportStatusRefused)
+			},
+			wantStatusID: models.RewardStatusVoided,
+			wantReason:   ptr("report refused by guest"),
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			guestID := f.createGuest()
+			reportID := f.acceptWithReward(guestID, base.AddDate(0, 0, 3*i))
+			_, err := f.pool.Exec(ctx, `UPDATE reports SET status_id = $2 WHERE id = $1`, reportID, tc.fromStatusID)
+			require.NoError(t, err)
+
+			require.NoError(t, tc.settle(reportID, guestID))
+
+			var statusID int
+			var reason *string
+			var approvedBy, voidedBy *uuid.UUID
+			require.NoError(t, f.pool.QueryRow(ctx, `
+				SELECT status_id, void_reason, approved_by, voided_by FROM rewards WHERE report_id = $1
+			`, reportID).Scan(&statusID, &reason, &approvedBy, &voidedBy))
+
+			assert.Equal(t, tc.wantStatusID, statusID)
+			assert.Equal(t, tc.wantReason, reason)
+			actor := approvedBy
+			if tc.wantStatusID == models.RewardStatusVoided {
+				actor = voidedBy
+			}
+			if tc.wantActor {
+				require.NotNil(t, actor)
+				assert.Equal(t, staffID, *actor)
+			} else {
+				assert.Nil(t, actor)
+			}
+		})
+	}
+
+	t.Run("settled reward is not changed by a repeated review", func(t *testing.T) {
+		guestID := f.createGuest()
+		reportID := f.acceptWithReward(guestID, base.AddDate(0, 0, 30))
+		_, err := f.pool.Exec(ctx, `UPDATE reports SET status_id = $2 WHERE id = $1`, reportID, models.ReportStatusSubmitted)
+		require.NoError(t, err)
+
+		_, err = f.repo.ReviewReport(ctx, reportID, models.ReportStatusApproved, staffID, nil)
+		require.NoError(t, err)
+		_, err = f.repo.ReviewReport(ctx, reportID, models.ReportStatusRejected, staffID, nil)
+		assert.ErrorIs(t, err, models.ErrReportCannotBeRejected)
+
+		var statusID int
+		require.NoError(t, f.pool.QueryRow(ctx, `SELECT status_id FROM rewards WHERE report_id = $1`, reportID).Scan(&statusID))
+		assert.Equal(t, models.RewardStatusApproved, statusID)
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// assignment waitlist
+
+// JoinAssignmentWaitlist ставит гостя в конец листа ожидания предложения, закрепленного за другим гостем
+func (r *SecretGuestRepository) JoinAssignmentWaitlist(ctx context.Context, assignmentID, userID uuid.UUID) (*models.WaitlistEntry, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	// Позиция считается по снимку до вставки: все, кто уже в листе, плюс сам гость
+	query := `
+		WITH ins AS (
+			INSERT INTO assignment_waitlist (assignment_id, user_id)
+			SELECT a.id, $2
+			FROM assignments a
+			WHERE a.id = $1
+				AND a.status_id = $3
+				AND a.reporter_id IS NOT NULL
+				AND a.reporter_id <> $2
+				AND a.expires_at > CURRENT_TIMESTAMP
+			RETURNING assignment_id, user_id, created_at
+		)
+		SELECT ins.assignment_id, ins.user_id, ins.created_at,
+			(SELECT COUNT(*) FROM assignment_waitlist w WHERE w.assignment_id = ins.assignment_id) + 1
+		FROM ins
+	`
+	var entry models.WaitlistEntry
+	err := r.db.QueryRow(ctx, query, assignmentID, userID, models.AssignmentStatusOffered).Scan(
+		&entry.AssignmentID, &entry.UserID, &entry.CreatedAt, &entry.Position,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrWaitlistNotAvailable
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, models.ErrAlreadyInWaitlist
+		}
+		log.Error(ctx, "Failed to join assignment waitlist", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (r *SecretGuestRepository) LeaveAssignmentWaitlist(ctx context.Context, assignmentID, userID uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	ct, err := r.db.Exec(ctx, `DELETE FROM assignment_waitlist WHERE assignment_id = $1 AND user_id = $2`, assignmentID, userID)
+	if err != nil {
+		log.Error(ctx, "Failed to leave assignment waitlist", zap.Error(err), zap.String("assignment_id", assignmentID.String()))
+		return err
+	}
+	if ct.RowsAffected() == 0 {
+		return models.ErrNotInWaitlist
+	}
+	return nil
+}
+
+// offerAssignmentToWaitlist закрепляет освободившееся предложение за первым подходящим гостем из листа ожидания:
+// гость не заблокирован и не удален, заявка одобрена, нет другого предложения Offered и пересечения дат. Гости, занятые параллельной транзакцией или
+// пока неподходящие, остаются в листе. Возвращает выбранного гостя или nil
+func offerAssignmentToWaitlist(ctx context.Context, tx pgx.Tx, assignmentID uuid.UUID, offer *models.WaitlistOffer, now time.Time) (*uuid.UUID, error) {
+	if offer == nil {
+		return nil, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT w.user_id, a.checkin_date, a.checkout_date
+		FROM assignment_waitlist w
+		JOIN assignments a ON a.id = w.assignment_id
+		JOIN users u ON u.id = w.user_id AND u.blocked_at IS NULL AND u.deleted_at IS NULL
+		JOIN guest_applications ga ON ga.user_id = w.user_id AND ga.status_id = $2
+		WHERE w.assignment_id = $1
+			AND a.status_id = $3
+			AND a.reporter_id IS NULL
+			AND a.expires_at > $4
+		ORDER BY w.created_at
+	`, assignmentID, models.GuestApplicationStatusApproved, models.AssignmentStatusOffered, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment waitlist: %w", err)
+	}
+	type candidate struct {
+		userID            uuid.UUID
+		checkin, checkout time.Time
+	}
+	candidates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (candidate, error) {
+		var c candidate
+		err := row.Scan(&c.userID, &c.checkin, &c.checkout)
+		return c, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan assignment waitlist: %w", err)
+	}
+
+	for _, c := range candidates {
+		locked, err := tryLockGuestAssignments(ctx, tx, c.userID)
+		if err != nil {
+			return nil, err
+		}
+		if !locked {
+			continue
+		}
+
+		var offered int
+		err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM assignments WHERE reporter_id = $1 AND status_id = $2`,
+			c.userID, models.AssignmentStatusOffered).Scan(&offered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing offered assignments: %w", err)
+		}
+		if offered > 0 {
+			continue
+		}
+
+		err = checkAssignmentOverlap(ctx, tx, assignmentID, c.userID, c.checkin, c.checkout)
+		if errors.Is(err, models.ErrAssignmentDatesOverlap) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.Exec(ctx, `
+			UPDATE assignments
+			SET reporter_id = $1, taked_at = $2, accept_deadline = $3
+			WHERE id = $4 AND reporter_id IS NULL AND status_id = $5
+		`, c.userID, now, offer.AcceptDeadline, assignmentID, models.AssignmentStatusOffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to offer assignment to waitlisted guest: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM assignment_waitlist WHERE assignment_id = $1 AND user_id = $2`, assignmentID, c.userID); err != nil {
+			return nil, fmt.Errorf("failed to remove guest from waitlist: %w", err)
+		}
+
+		if offer.Notification != nil {
+			notification := *offer.Notification
+			notification.UserID = c.userID
+			if err := insertNotification(ctx, tx, &notification); err != nil {
+				return nil, err
+			}
+		}
+
+		return &c.userID, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,378 @@
+package secret_guest
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Начисления тайным гостям
+
+// assignmentPricing - поля стоимости брони, от которых считается вознаграждение
+type assignmentPricing struct {
+	Currency string `json:"currency"`
+	Total    *int   `json:"total"`
+}
+
+// calculateReward - доля стоимости брони по политике типа объекта с учетом ограничения суммы.
+// Сумма округляется вниз до целых единиц валюты. Бронь без стоимости - ошибка, а не нулевое начисление
+func calculateReward(pricing json.RawMessage, policy *models.RewardPolicy) (int, string, error) {
+	var p assignmentPricing
+	if err := json.Unmarshal(pricing, &p); err != nil {
+		return 0, "", fmt.Errorf("failed to parse assignment pricing: %w", err)
+	}
+	if p.Currency == "" || p.Total == nil || *p.Total < 0 {
+		return 0, "", fmt.Errorf("assignment pricing has no currency, no total or negative total")
+	}
+
+	amount := *p.Total * policy.Percent / 100
+	if policy.MaxAmount != nil && amount > *policy.MaxAmount {
+		amount = *policy.MaxAmount
+	}
+
+	return amount, p.Currency, nil
+}
+
+// newAssignmentReward готовит начисление за принимаемое задание. Ошибка расчета не мешает принять задание:
+// начисление не создается, а проблема пишется в лог для разбора.
+func (s *SecretGuestService) newAssignmentReward(ctx context.Context, a *models.Assignment, userID uuid.UUID) *models.Reward {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	// У предложений, созданных персоналом вручную, нет брони - вознаграждение не начисляется
+	if len(a.Pricing) == 0 {
+		log.Info(ctx, "Assignment has no pricing, reward is not created", zap.String("assignment_id", a.ID.String()))
+		return nil
+	}
+
+	policy, err := s.repo.GetRewardPolicy(ctx, a.Listing.ListingTypeID)
+	if err != nil {
+		log.Error(ctx, "Failed to get reward policy, reward is not created",
+			zap.Error(err),
+			zap.String("assignment_id", a.ID.String()),
+			zap.Int("listing_type_id", a.Listing.ListingTypeID),
+		)
+		return nil
+	}
+
+	amount, currency, err := calculateReward(a.Pricing, policy)
+	if err != nil {
+		log.Error(ctx, "Failed to calculate reward, reward is not created", zap.Error(err), zap.String("assignment_id", a.ID.String()))
+		return nil
+	}
+
+	return &models.Reward{
+		ID:            uuid.New(),
+		AssignmentID:  a.ID,
+		UserID:        &userID,
+		ListingID:     a.ListingID,
+		ListingTypeID: a.Listing.ListingTypeID,
+		Amount:        amount,
+		Currency:      currency,
+		Percent:       policy.Percent,
+	}
+}
+
+func toRewardResponseDTO(rw *models.Reward) *RewardResponseDTO {
+	return &RewardResponseDTO{
+		ID:            rw.ID,
+		AssignmentID:  rw.AssignmentID,
+		ReportID:      rw.ReportID,
+		UserID:        rw.UserID,
+		Username:      rw.Username,
+		ListingID:     rw.ListingID,
+		ListingTitle:  rw.ListingTitle,
+		ListingTypeID: rw.ListingTypeID,
+		Amount:        rw.Amount,
+		Currency:      rw.Currency,
+		Percent:       rw.Percent,
+		Status: StatusResponse{
+			ID:   rw.Status.ID,
+			Slug: rw.Status.Slug,
+			Name: rw.Status.Name,
+		},
+		CreatedAt:       rw.CreatedAt,
+		ApprovedAt:      rw.ApprovedAt,
+		PaidAt:          rw.PaidAt,
+		PayoutReference: rw.PayoutReference,
+		VoidedAt:        rw.VoidedAt,
+		VoidReason:      rw.VoidReason,
+	}
+}
+
+func toRewardPolicyResponseDTO(p *models.RewardPolicy) *RewardPolicyResponseDTO {
+	return &RewardPolicyResponseDTO{
+		ListingType: ListingTypeResponse{
+			ID:   p.ListingTypeID,
+			Slug: p.ListingTypeSlug,
+			Name: p.ListingTypeName,
+		},
+		Percent:   p.Percent,
+		MaxAmount: p.MaxAmount,
+		IsDefault: p.UpdatedAt == nil,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
+
+func toRewardsFilter(dto GetRewardsRequestDTO) repository.RewardsFilter {
+	return repository.RewardsFilter{
+		UserID:       dto.UserID,
+		StatusIDs:    dto.StatusIDs,
+		ApprovedFrom: dto.ApprovedFrom,
+		ApprovedTo:   dto.ApprovedTo,
+		Limit:        dto.Limit,
+		Offset:       (dto.Page - 1) * dto.Limit,
+	}
+}
+
+func (s *SecretGuestService) GetMyRewards(ctx context.Context, userID uuid.UUID, dto GetRewardsRequestDTO) (*MyRewardsResponse, error) {
+	dto.UserID = &userID
+	dto.ApprovedFrom, dto.ApprovedTo = nil, nil
+
+	rewards, err := s.GetRewards(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.repo.GetRewardTotals(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward totals from repository: %w", err)
+	}
+
+	totalDTOs := make([]*RewardTotalDTO, 0, len(totals))
+	for _, t := range totals {
+		totalDTOs = append(totalDTOs, &RewardTotalDTO{
+			Status:   StatusResponse{ID: t.Status.ID, Slug: t.Status.Slug, Name: t.Status.Name},
+			Currency: t.Currency,
+			Amount:   t.Amount,
+			Count:    t.Count,
+		})
+	}
+
+	return &MyRewardsResponse{
+		Rewards: rewards.Rewards,
+		Totals:  totalDTOs,
+		Total:   rewards.Total,
+		Page:    rewards.Page,
+	}, nil
+}
+
+func (s *SecretGuestService) GetRewards(ctx context.Context, dto GetRewardsRequestDTO) (*RewardsResponse, error) {
+	rewards, total, err := s.repo.GetRewards(ctx, toRewardsFilter(dto))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rewards from repository: %w", err)
+	}
+
+	responseDTOs := make([]*RewardResponseDTO, 0, len(rewards))
+	for _, rw := range rewards {
+		responseDTOs = append(responseDTOs, toRewardResponseDTO(rw))
+	}
+
+	return &RewardsResponse{
+		Rewards: responseDTOs,
+		Total:   total,
+		Page:    dto.Page,
+	}, nil
+}
+
+// rewardsCSVHeader - колонки выгрузки для бухгалтерии
+var rewardsCSVHeader = []string{
+	"reward_id", "status", "user_id", "username", "amount", "currency", "percent",
+	"listing_id", "listing_title", "assignment_id", "report_id",
+	"created_at", "approved_at", "paid_at", "payout_reference",
+}
+
+const csvTimeLayout = "2006-01-02 15:04:05"
+
+// ExportRewardsCSV выгружает начисления по фильтру без постраничного ограничения.
+// По умолчанию - начисления к выплате.
+func (s *SecretGuestService) ExportRewardsCSV(ctx context.Context, dto GetRewardsRequestDTO) (*RewardsExport, error) {
+	if len(dto.StatusIDs) == 0 {
+		dto.StatusIDs = []int{models.RewardStatusApproved}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(rewardsCSVHeader); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(csvTimeLayout)
+	}
+	formatID := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		return id.String()
+	}
+
+	filter := toRewardsFilter(dto)
+	filter.Limit = exportPageSize
+	for offset := 0; ; offset += exportPageSize {
+		filter.Offset = offset
+		page, total, err := s.repo.GetRewards(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rewards from repository: %w", err)
+		}
+		for _, rw := range page {
+			payoutReference := ""
+			if rw.PayoutReference != nil {
+				payoutReference = *rw.PayoutReference
+			}
+			record := []string{
+				rw.ID.String(), rw.Status.Slug, formatID(rw.UserID), rw.Username,
+				strconv.Itoa(rw.Amount), rw.Currency, strconv.Itoa(rw.Percent),
+				rw.ListingID.String(), rw.ListingTitle, rw.AssignmentID.String(), formatID(rw.ReportID),
+				rw.CreatedAt.Format(csvTimeLayout), formatTime(rw.ApprovedAt), formatTime(rw.PaidAt), payoutReference,
+			}
+			if err := w.Write(record); err != nil {
+				return nil, fmt.Errorf("failed to write CSV record: %w", err)
+			}
+		}
+		if len(page) == 0 || offset+exportPageSize >= total {
+			break
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to flush CSV: %w", err)
+	}
+
+	return &RewardsExport{
+		FileName: fmt.Sprintf("rewards-%s.csv", time.Now().Format("20060102-150405")),
+		Content:  buf.Bytes(),
+	}, nil
+}
+
+// PayRewards отмечает начисления к выплате выплаченными с номером платежа
+func (s *SecretGuestService) PayRewards(ctx context.Context, actorID uuid.UUID, dto PayRewardsRequestDTO) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	reference := strings.TrimSpace(dto.PayoutReference)
+	if reference == "" {
+		return models.ErrValidationFailed
+	}
+
+	entries := make([]*models.AuditLogEntry, 0, len(dto.RewardIDs))
+	for _, id := range dto.RewardIDs {
+		entries = append(entries, models.NewAuditLogEntry(actorID, models.AuditActionRewardPaid, models.AuditEntityReward, id.String(), map[string]any{
+			"payout_reference": reference,
+		}))
+	}
+
+	if err := s.repo.PayRewards(ctx, dto.RewardIDs, actorID, reference, entries); err != nil {
+		return fmt.Errorf("failed to pay rewards: %w", err)
+	}
+
+	log.Info(ctx, "Rewards paid",
+		zap.String("actor_id", actorID.String()),
+		zap.Int("count", len(dto.RewardIDs)),
+		zap.String("payout_reference", reference),
+	)
+	return nil
+}
+
+func (s *SecretGuestService) VoidReward(ctx context.Context, actorID, rewardID uuid.UUID, dto VoidRewardRequestDTO) (*RewardResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	reason := strings.TrimSpace(dto.Reason)
+	if reason == "" {
+		return nil, models.ErrValidationFailed
+	}
+
+	reward, err := s.repo.GetRewardByID(ctx, rewardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward: %w", err)
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionRewardVoided, models.AuditEntityReward, rewardID.String(), map[string]any{
+		"user_id":        reward.UserID,
+		"from_status_id": reward.StatusID,
+		"amount":         reward.Amount,
+		"currency":       reward.Currency,
+		"reason":         reason,
+	})
+
+	if err := s.repo.VoidReward(ctx, rewardID, actorID, reason, entry); err != nil {
+		return nil, fmt.Errorf("failed to void reward: %w", err)
+	}
+
+	log.Info(ctx, "Reward voided", zap.String("actor_id", actorID.String()), zap.String("reward_id", rewardID.String()))
+
+	updated, err := s.repo.GetRewardByID(ctx, rewardID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward: %w", err)
+	}
+	return toRewardResponseDTO(updated), nil
+}
+
+func (s *SecretGuestService) GetRewardPolicies(ctx context.Context) ([]*RewardPolicyResponseDTO, error) {
+	policies, err := s.repo.GetRewardPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward policies from repository: %w", err)
+	}
+
+	responseDTOs := make([]*RewardPolicyResponseDTO, 0, len(policies))
+	for _, p := range policies {
+		responseDTOs = append(responseDTOs, toRewardPolicyResponseDTO(p))
+	}
+	return responseDTOs, nil
+}
+
+// UpdateRewardPolicy задает политику вознаграждения для типа объекта. Действует для заданий, принятых после изменения.
+func (s *SecretGuestService) UpdateRewardPolicy(ctx context.Context, actorID uuid.UUID, listingTypeID int, dto UpdateRewardPolicyRequestDTO) (*RewardPolicyResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if dto.Percent == nil {
+		return nil, models.ErrInvalidRewardPolicy
+	}
+
+	current, err := s.repo.GetRewardPolicy(ctx, listingTypeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward policy: %w", err)
+	}
+
+	policy := &models.RewardPolicy{
+		ListingTypeID: listingTypeID,
+		Percent:       *dto.Percent,
+		MaxAmount:     dto.MaxAmount,
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionRewardPolicyUpdated, models.AuditEntityRewardPolicy, strconv.Itoa(listingTypeID), map[string]any{
+		"old_percent":    current.Percent,
+		"old_max_amount": current.MaxAmount,
+		"new_percent":    policy.Percent,
+		"new_max_amount": policy.MaxAmount,
+	})
+
+	if err := s.repo.SaveRewardPolicy(ctx, policy, actorID, entry); err != nil {
+		return nil, fmt.Errorf("failed to save reward policy: %w", err)
+	}
+
+	log.Info(ctx, "Reward policy updated",
+		zap.String("actor_id", actorID.String()),
+		zap.Int("listing_type_id", listingTypeID),
+		zap.Int("percent", policy.Percent),
+	)
+
+	updated, err := s.repo.GetRewardPolicy(ctx, listingTypeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reward policy: %w", err)
+	}
+	return toRewardPolicyResponseDTO(updated), nil
+}
@@ -0,0 +1,382 @@
+package secret_guest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// rewards
+
+// @Summary      Get My Rewards
+// @Security     BearerAuth
+// @Description  Returns reward ledger entries of the current guest, newest first, and totals by status and currency. A reward is created when an assignment is accepted (pending), approved together with the report, then paid or voided by staff.
+// @Tags         Rewards (User)
+// @Produce      json
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param        status_id query []int false "Filter by status IDs (1 - pending, 2 - approved, 3 - paid, 4 - voided)" collectionFormat(multi)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.MyRewardsResponse
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /profiles/my/rewards [get]
+func (h *SecretGuestHandler) GetMyRewards(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit := h.parsePagination(r)
+	dto := GetRewardsRequestDTO{
+		Page:      page,
+		Limit:     limit,
+		StatusIDs: h.parseRewardStatusIDs(r),
+	}
+
+	rewards, err := h.service.GetMyRewards(ctx, userID, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to get my rewards", zap.Error(err), zap.String("user_id", userID.String()))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, rewards)
+}
+
+// @Summary      Get Rewards (Staff)
+// @Security     BearerAuth
+// @Description  Returns reward ledger entries, newest first.
+// @Tags         Rewards (Staff)
+// @Produce      json
+// @Param        page query int false "Page number for pagination" default(1)
+// @Param        limit query int false "Number of items per page" default(50)
+// @Param        status_id query []int false "Filter by status IDs (1 - pending, 2 - approved, 3 - paid, 4 - voided)" collectionFormat(multi)
+// @Param        user_id query string false "Filter by guest ID" format(uuid)
+// @Param        approved_from query string false "Approved on or after the date (YYYY-MM-DD)"
+// @Param        approved_to query string false "Approved on or before the date (YYYY-MM-DD)"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.RewardsResponse
+// @Failure      400 {object} ErrorResponse "Invalid filter"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /staff/rewards [get]
+func (h *SecretGuestHandler) GetRewards(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	dto, ok := h.parseRewardsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	rewards, err := h.service.GetRewards(ctx, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to get rewards", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, rewards)
+}
+
+// @Summary      Export Rewards to CSV (Admin)
+// @Security     BearerAuth
+// @Description  Returns a CSV file with reward ledger entries for finance. Accepts the same filters as the staff list, without pagination. Without status_id filter exports approved rewards awaiting payout.
+// @Tags         Rewards (Admin)
+// @Produce      text/csv
+// @Param        status_id query []int false "Filter by status IDs (1 - pending, 2 - approved, 3 - paid, 4 - voided)" collectionFormat(multi)
+// @Param        user_id query string false "Filter by guest ID" format(uuid)
+// @Param        approved_from query string false "Approved on or after the date (YYYY-MM-DD)"
+// @Param        approved_to query string false "Approved on or before the date (YYYY-MM-DD)"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {file} file "CSV file"
+// @Failure      400 {object} ErrorResponse "Invalid filter"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/rewards/export [get]
+func (h *SecretGuestHandler) ExportRewards(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	dto, ok := h.parseRewardsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.service.ExportRewardsCSV(ctx, dto)
+	if err != nil {
+		log.Error(ctx, "Failed to export rewards", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.FileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(export.Content)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(export.Content); err != nil {
+		log.Error(ctx, "Failed to write rewards export", zap.Error(err))
+	}
+}
+
+// @Summary      Pay Rewards (Admin)
+// @Security     BearerAuth
+// @Description  Marks approved rewards as paid with a payout reference. All rewards are paid in one transaction: if any of them is not found or not approved, nothing changes. Each payout is recorded in the audit log.
+// @Tags         Rewards (Admin)
+// @Accept       json
+// @Param        input body secret_guest.PayRewardsRequestDTO true "Rewards and payout reference"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid request body"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Reward not found"
+// @Failure      409 {object} ErrorResponse "Reward is not approved for payout"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/rewards/payouts [post]
+func (h *SecretGuestHandler) PayRewards(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	var dto PayRewardsRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for reward payout", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := h.service.PayRewards(ctx, actorID, dto); err != nil {
+		h.handleRewardError(ctx, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Void Reward (Admin)
+// @Security     BearerAuth
+// @Description  Voids a pending or approved reward with a reason. Paid rewards cannot be voided. The action is recorded in the audit log.
+// @Tags         Rewards (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Reward ID" format(uuid)
+// @Param        input body secret_guest.VoidRewardRequestDTO true "Reason"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.RewardResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Reward not found"
+// @Failure      409 {object} ErrorResponse "Reward cannot be voided"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/rewards/{id}/void [patch]
+func (h *SecretGuestHandler) VoidReward(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	rewardID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var dto VoidRewardRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for reward void", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	reward, err := h.service.VoidReward(ctx, actorID, rewardID, dto)
+	if err != nil {
+		h.handleRewardError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, reward)
+}
+
+// @Summary      Get Reward Policies (Admin)
+// @Security     BearerAuth
+// @Description  Returns the reward policy for every listing type: the share of the booking total paid to the guest and an optional cap. Listing types without a saved policy use the default (100%, no cap).
+// @Tags         Rewards (Admin)
+// @Produce      json
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {array} secret_guest.RewardPolicyResponseDTO
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/reward_policies [get]
+func (h *SecretGuestHandler) GetRewardPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	policies, err := h.service.GetRewardPolicies(ctx)
+	if err != nil {
+		log.Error(ctx, "Failed to get reward policies", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, policies)
+}
+
+// @Summary      Update Reward Policy (Admin)
+// @Security     BearerAuth
+// @Description  Sets the reward policy for a listing type. Applies to assignments accepted after the change; existing rewards are not recalculated. The action is recorded in the audit log.
+// @Tags         Rewards (Admin)
+// @Accept       json
+// @Produce      json
+// @Param        listing_type_id path int true "Listing type ID"
+// @Param        input body secret_guest.UpdateRewardPolicyRequestDTO true "Policy"
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      200 {object} secret_guest.RewardPolicyResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid request"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Forbidden"
+// @Failure      404 {object} ErrorResponse "Listing type not found"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /admin/reward_policies/{listing_type_id} [put]
+func (h *SecretGuestHandler) UpdateRewardPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	actorID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	listingTypeID, ok := h.parseIntFromPath(w, r, "listing_type_id")
+	if !ok {
+		return
+	}
+
+	var dto UpdateRewardPolicyRequestDTO
+	if err := h.decodeJSONBody(ctx, r, &dto); err != nil {
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := validation.StructCtx(ctx, &dto); err != nil {
+		log.Warn(ctx, "Validation failed for reward policy", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	policy, err := h.service.UpdateRewardPolicy(ctx, actorID, listingTypeID, dto)
+	if err != nil {
+		h.handleRewardError(ctx, w, err)
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusOK, policy)
+}
+
+func (h *SecretGuestHandler) parseRewardStatusIDs(r *http.Request) []int {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var statusIDs []int
+	for _, idStr := range r.URL.Query()["status_id"] {
+		statusID, err := strconv.Atoi(idStr)
+		if err != nil {
+			log.Warn(ctx, "Invalid status_id value in query parameter, value ignored", zap.String("status_id", idStr))
+			continue
+		}
+		statusIDs = append(statusIDs, statusID)
+	}
+	return statusIDs
+}
+
+// parseRewardsFilter разбирает фильтры списка начислений. Границы дат включительные.
+func (h *SecretGuestHandler) parseRewardsFilter(w http.ResponseWriter, r *http.Request) (GetRewardsRequestDTO, bool) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	page, limit := h.parsePagination(r)
+	dto := GetRewardsRequestDTO{
+		Page:      page,
+		Limit:     limit,
+		StatusIDs: h.parseRewardStatusIDs(r),
+	}
+
+	if userIDStr := query.Get("user_id"); userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid user_id")
+			return dto, false
+		}
+		dto.UserID = &userID
+	}
+
+	if fromStr := query.Get("approved_from"); fromStr != "" {
+		from, err := time.Parse(time.DateOnly, fromStr)
+		if err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid approved_from, expected YYYY-MM-DD")
+			return dto, false
+		}
+		dto.ApprovedFrom = &from
+	}
+
+	if toStr := query.Get("approved_to"); toStr != "" {
+		to, err := time.Parse(time.DateOnly, toStr)
+		if err != nil {
+			h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid approved_to, expected YYYY-MM-DD")
+			return dto, false
+		}
+		to = to.Add(24 * time.Hour)
+		dto.ApprovedTo = &to
+	}
+
+	return dto, true
+}
+
+func (h *SecretGuestHandler) handleRewardError(ctx context.Context, w http.ResponseWriter, err error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	switch {
+	case errors.Is(err, models.ErrRewardNotFound), errors.Is(err, models.ErrListingTypeNotFound):
+		h.writeErrorResponse(ctx, w, http.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrValidationFailed), errors.Is(err, models.ErrInvalidRewardPolicy):
+		h.writeErrorResponse(ctx, w, http.StatusBadRequest, "Invalid request body")
+	case errors.Is(err, models.ErrRewardNotPayable), errors.Is(err, models.ErrRewardNotVoidable):
+		log.Info(ctx, "Reward cannot be changed", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+	default:
+		log.Error(ctx, "Failed to process reward", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+	}
+}
@@ -1,21 +1,31 @@
 package secret_guest
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/auth"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/config"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/storage"
 	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type SecretGuestRepository interface {
@@ -34,14 +44,19 @@ type SecretGuestRepository interface {
 
 	// assignments
 	CreateAssignment(ctx context.Context, assignment *models.Assignment) (uuid.UUID, error)
+	CreateManualAssignment(ctx context.Context, assignment *models.Assignment, notification *models.Notification, entry *models.AuditLogEntry) error
 	GetAssignments(ctx context.Context, filter repository.AssignmentsFilter) ([]*models.Assignment, int, error)
 	GetAssignmentByID(ctx context.Context, assignmentID uuid.UUID) (*models.Assignment, error)
 	GetFreeAssignments(ctx context.Context, filter repository.AssignmentsFilter) ([]*models.Assignment, int, error)
 	GetAssignmentByIDAndOwner(ctx context.Context, assignmentID, reporterID uuid.UUID) (*models.Assignment, error)
-	CancelAssignment(ctx context.Context, assignmentID uuid.UUID) error
-	AcceptMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, acceptedAt time.Time) (*models.Report, error)
-	DeclineMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, takedAt, declinedAt time.Time) error
-	TakeFreeAssignmentsByID(ctx context.Context, assignmentID, userID uuid.UUID, takenAt time.Time) error
+	CancelAssignment(ctx context.Context, assignmentID uuid.UUID, notification *models.Notification, entry *models.AuditLogEntry) error
+	ReassignAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, acceptDeadline *time.Time, now time.Time, released, invited *models.Notification, entry *models.AuditLogEntry) (*uuid.UUID, error)
+	ExtendAssignment(ctx context.Context, assignmentID uuid.UUID, reporterID *uuid.UUID, expiresAt time.Time, acceptDeadline *time.Time, entry *models.AuditLogEntry) error
+	GetAssignmentIDs(ctx context.Context, filter repository.BulkAssignmentsFilter, limit int) ([]uuid.UUID, error)
+	ReleaseAssignment(ctx context.Context, assignmentID uuid.UUID, notification *models.Notification, offer *models.WaitlistOffer, entry *models.AuditLogEntry) (*uuid.UUID, error)
+	AcceptMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, acceptedAt time.Time, dueAfterCheckout time.Duration, reward *models.Reward) (*models.Report, error)
+	DeclineMyAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, declinedAt time.Time, reasonID *int, comment *string, offer *models.WaitlistOffer) (*uuid.UUID, error)
+	TakeFreeAssignmentsByID(ctx context.Context, assignmentID, userID uuid.UUID, takenAt time.Time, acceptDeadline *time.Time) error
 
 	// reports
 	GetReports(ctx context.Context, filter repository.ReportsFilter) ([]*models.Report, int, error)
@@ -50,6 +65,7 @@ type SecretGuestRepository interface {
 	UpdateMyReportContent(ctx context.Context, reportID, reporterID uuid.UUID, currentStatusID int, schema models.ChecklistSchema) error
 	UpdateMyReportStatus(ctx context.Context, reportID, reporterID uuid.UUID, currentStatusID, newStatusID int) error
 	UpdateReportStatusAsStaff(ctx context.Context, reportID uuid.UUID, currentStatusID, newStatusID int) error
+	ReviewReport(ctx context.Context, reportID uuid.UUID, newStatusID int, reviewerID uuid.UUID, qualityScore *int) (*uuid.UUID, error)
 
 	/////
 	GetListingTypeID(ctx context.Context, listingID uuid.UUID) (int, error)
@@ -90,12 +106,127 @@ type SecretGuestRepository interface {
 	DeleteChecklistItem(ctx context.Context, id int) error
 
 	// users
-	GetAllUsers(ctx context.Context, limit, offset int) ([]*models.User, int, error)
+	GetAllUsers(ctx context.Context, filter repository.UsersFilter) ([]*models.User, int, error)
+	GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error)
+	UpdateUserRole(ctx context.Context, userID uuid.UUID, roleID int, entry *models.AuditLogEntry) error
+	SetUserBlocked(ctx context.Context, userID uuid.UUID, reason *string, blockedBy uuid.UUID, entry *models.AuditLogEntry) error
+	ResetUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string, entry *models.AuditLogEntry) error
+
+	// audit_log
+	CreateAuditLogEntry(ctx context.Context, entry *models.AuditLogEntry) error
+	GetAuditLog(ctx context.Context, filter repository.AuditLogFilter) ([]*models.AuditLogEntry, int, error)
+
+	// roles
+	GetPermissions(ctx context.Context) ([]*models.Permission, error)
+	GetRoles(ctx context.Context) ([]*models.Role, error)
+	GetRoleByID(ctx context.Context, roleID int) (*models.Role, error)
+	CreateRole(ctx context.Context, role *models.Role, entry *models.AuditLogEntry) (int, error)
+	UpdateRole(ctx context.Context, roleID int, name, description *string, entry *models.AuditLogEntry) error
+	SetRolePermissions(ctx context.Context, roleID int, permissions []string, entry *models.AuditLogEntry) error
+	DeleteRole(ctx context.Context, roleID int, entry *models.AuditLogEntry) error
+
+	// service_accounts
+	CreateServiceAccount(ctx context.Context, user *models.User, entry *models.AuditLogEntry) error
+	GetAPIKeysByUserID(ctx context.Context, userID uuid.UUID) ([]*models.APIKey, error)
+	GetAPIKeyByID(ctx context.Context, keyID uuid.UUID) (*models.APIKey, error)
+	CreateAPIKey(ctx context.Context, key *models.APIKey, entry *models.AuditLogEntry) error
+	RevokeAPIKey(ctx context.Context, keyID uuid.UUID, entry *models.AuditLogEntry) error
+
+	// guest_applications
+	GetGuestApplications(ctx context.Context, filter repository.GuestApplicationsFilter) ([]*models.GuestApplication, int, error)
+	GetGuestApplicationByID(ctx context.Context, applicationID uuid.UUID) (*models.GuestApplication, error)
+	GetGuestApplicationByUserID(ctx context.Context, userID uuid.UUID) (*models.GuestApplication, error)
+	SaveGuestApplication(ctx context.Context, application *models.GuestApplication) error
+	ReviewGuestApplication(ctx context.Context, applicationID uuid.UUID, statusID int, reviewerID uuid.UUID, comment *string, entry *models.AuditLogEntry) error
+
+	// rewards
+	GetRewards(ctx context.Context, filter repository.RewardsFilter) ([]*models.Reward, int, error)
+	GetRewardByID(ctx context.Context, rewardID uuid.UUID) (*models.Reward, error)
+	GetRewardTotals(ctx context.Context, userID uuid.UUID) ([]*models.RewardTotal, error)
+	PayRewards(ctx context.Context, rewardIDs []uuid.UUID, paidBy uuid.UUID, reference string, entries []*models.AuditLogEntry) error
+	VoidReward(ctx context.Context, rewardID, voidedBy uuid.UUID, reason string, entry *models.AuditLogEntry) error
+	GetRewardPolicies(ctx context.Context) ([]*models.RewardPolicy, error)
+	GetRewardPolicy(ctx context.Context, listingTypeID int) (*models.RewardPolicy, error)
+	SaveRewardPolicy(ctx context.Context, policy *models.RewardPolicy, actorID uuid.UUID, entry *models.AuditLogEntry) error
 
 	// profiles
 	GetUserProfileByID(ctx context.Context, userID uuid.UUID) (*models.UserProfile, error)
+	UpdateUserProfileInfo(ctx context.Context, userID uuid.UUID, info json.RawMessage) error
+	DeleteUserAccount(ctx context.Context, userID uuid.UUID, anonymizedUsername string, offers map[uuid.UUID]*models.WaitlistOffer, entry *models.AuditLogEntry) error
 	GetAllUserProfiles(ctx context.Context, limit, offset int) ([]*models.UserProfile, int, error)
 
+	// points
+	GetPointEvents(ctx context.Context, filter repository.PointEventsFilter) ([]*models.PointEvent, int, error)
+	GetPointBreakdown(ctx context.Context, userID uuid.UUID) ([]*models.PointBreakdownItem, error)
+	RecomputePoints(ctx context.Context, entry *models.AuditLogEntry) (int64, error)
+	GetPointRules(ctx context.Context) ([]*models.PointRule, error)
+	GetPointRule(ctx context.Context, eventType string) (*models.PointRule, error)
+	UpdatePointRule(ctx context.Context, rule *models.PointRule, actorID uuid.UUID, entry *models.AuditLogEntry) error
+	GetRankTiers(ctx context.Context) ([]*models.RankTier, error)
+	ReplaceRankTiers(ctx context.Context, tiers []*models.RankTier, entry *models.AuditLogEntry) error
+
+	// badges
+	GetBadges(ctx context.Context, onlyActive bool) ([]*models.Badge, error)
+	GetBadgeByID(ctx context.Context, badgeID int) (*models.Badge, error)
+	CreateBadge(ctx context.Context, badge *models.Badge, entry *models.AuditLogEntry) error
+	UpdateBadge(ctx context.Context, badge *models.Badge, entry *models.AuditLogEntry) error
+	GetUserBadges(ctx context.Context, userIDs []uuid.UUID) (map[uuid.UUID][]*models.UserBadge, error)
+	AwardBadges(ctx context.Context, userID uuid.UUID, badgeIDs []int) ([]int, error)
+	GetBadgeMetrics(ctx context.Context, userID uuid.UUID) (map[string]int, error)
+	GetUsersWithPointEvents(ctx context.Context) ([]uuid.UUID, error)
+
+	// leaderboard
+	GetLeaderboard(ctx context.Context, filter repository.LeaderboardFilter) ([]*models.LeaderboardEntry, int, error)
+	GetLeaderboardEntry(ctx context.Context, filter repository.LeaderboardFilter, userID uuid.UUID) (*models.LeaderboardEntry, error)
+
+	// assignment holds
+	GetExpiredAssignmentHolds(ctx context.Context, now time.Time, limit int) ([]*models.ExpiredAssignmentHold, error)
+	ReleaseAssignmentHold(ctx context.Context, hold *models.ExpiredAssignmentHold, notification *models.Notification, offer *models.WaitlistOffer) (*uuid.UUID, error)
+
+	// assignment waitlist
+	JoinAssignmentWaitlist(ctx context.Context, assignmentID, userID uuid.UUID) (*models.WaitlistEntry, error)
+	LeaveAssignmentWaitlist(ctx context.Context, assignmentID, userID uuid.UUID) error
+
+	// stay checks
+	CheckInAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, check *models.StayCheck) error
+	CheckOutAssignment(ctx context.Context, assignmentID, reporterID uuid.UUID, check *models.StayCheck) error
+	GetAssignmentStay(ctx context.Context, assignmentID uuid.UUID) (*models.AssignmentStay, error)
+
+	// calendar
+	SaveCalendarToken(ctx context.Context, userID uuid.UUID, tokenHash string) (time.Time, error)
+	GetUserIDByCalendarToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	GetCalendarStays(ctx context.Context, userID uuid.UUID, since time.Time) ([]*models.CalendarStay, error)
+
+	// report deadlines
+	GetReportsDueSoon(ctx context.Context, now, dueBefore time.Time, maxReminders, limit int) ([]*models.DueReport, error)
+	MarkReportReminded(ctx context.Context, reportID uuid.UUID, remindersSent int, notification *models.Notification) error
+	GetOverdueReports(ctx context.Context, now time.Time, limit int) ([]*models.DueReport, error)
+	MarkReportOverdue(ctx context.Context, reportID uuid.UUID, now time.Time, notification *models.Notification) error
+	BackfillReportDueDates(ctx context.Context, now time.Time, dueAfterCheckout time.Duration) (int64, error)
+
+	// assignment declines
+	GetDeclineAnalytics(ctx context.Context, filter repository.DeclineAnalyticsFilter) ([]*models.DeclineStat, int, error)
+	GetRepeatedlyDeclinedAssignments(ctx context.Context, minDeclines, limit, offset int) ([]*models.DeclinedAssignment, int, error)
+
+	// listing priority
+	GetPrioritizedListings(ctx context.Context, filter repository.PrioritizedListingsFilter) ([]*models.Listing, int, error)
+	GetListingPriority(ctx context.Context, listingID uuid.UUID) (*models.ListingPriority, error)
+	GetListingTypePriorityWeights(ctx context.Context) ([]*models.ListingTypePriorityWeight, error)
+	SaveListingTypePriorityWeight(ctx context.Context, listingTypeID, weight int, entry *models.AuditLogEntry) error
+	CreateListingComplaint(ctx context.Context, complaint *models.ListingComplaint) error
+
+	// campaigns
+	GetCampaigns(ctx context.Context, filter repository.CampaignsFilter) ([]*models.Campaign, int, error)
+	GetCampaignByID(ctx context.Context, campaignID uuid.UUID) (*models.Campaign, error)
+	FindCampaignForListing(ctx context.Context, listingID uuid.UUID, checkinDate time.Time) (*models.Campaign, error)
+	GetCampaignListings(ctx context.Context, campaignID uuid.UUID, limit, offset int) ([]*models.CampaignListing, int, error)
+	CreateCampaign(ctx context.Context, campaign *models.Campaign, entry *models.AuditLogEntry) error
+	UpdateCampaign(ctx context.Context, campaign *models.Campaign, entry *models.AuditLogEntry) error
+
+	// notifications
+	GetNotifications(ctx context.Context, filter repository.NotificationsFilter) ([]*models.Notification, int, int, error)
+	MarkNotificationRead(ctx context.Context, notificationID, userID uuid.UUID) error
+
 	// statistics
 	GetStatistics(ctx context.Context) (*models.Statistics, error)
 
@@ -217,30 +348,149 @@ func toListingResponseDTO(l *models.Listing) *ListingResponseDTO {
 
 // assignments
 
-// func (s *SecretGuestService) CreateAssignment(ctx context.Context, dto CreateAssignmentRequestDTO) (*AssignmentResponseDTO, error) {
-//
-// 	assignment := models.Assignment{
-// 		Purpose:    dto.Purpose,
-// 		ListingID:  dto.ListingID,
-// 		ReporterID: dto.ReporterID,
-// 		ExpiresAt:  dto.ExpiresAt,
-// 	}
-//
-// 	assignmentID, err := s.repo.CreateAssignment(ctx, &assignment)
-// 	if err != nil {
-// 		return nil, fmt.Errorf("failed to create assignment in repository: %w", err)
-// 	}
-//
-// 	dbAssignment, err := s.repo.GetAssignmentByID(ctx, assignmentID)
-// 	if err != nil {
-// 		return nil, fmt.Errorf("failed to get assignment by id %s from repository: %w", assignmentID.String(), err)
-// 	}
-//
-// 	return toAssignmentResponseDTO(dbAssignment), nil
-// }
+// newInvitedNotification - уведомление гостю о приглашении персонала на предложение
+func newInvitedNotification(assignmentID uuid.UUID, listingTitle, city string, checkin, deadline time.Time) (*models.Notification, error) {
+	payload, err := json.Marshal(map[string]any{
+		"assignment_id":   assignmentID,
+		"accept_deadline": deadline,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+	return &models.Notification{
+		Type:  models.NotificationAssignmentInvited,
+		Title: "Приглашение на проверку",
+		Body: fmt.Sprintf("Вас пригласили проверить «%s» (%s), заезд %s. Примите предложение до %s",
+			listingTitle, city, checkin.Format("02.01.2006"), deadline.Format("02.01.2006 15:04")),
+		Payload: payload,
+	}, nil
+}
+
+// inviteAcceptDeadline - срок принятия приглашения персонала(по умолчанию expires_at)
+func (s *SecretGuestService) inviteAcceptDeadline(requested *time.Time, expiresAt, now time.Time) (time.Time, error) {
+	deadline := expiresAt
+	if requested != nil {
+		deadline = *requested
+	}
+	// Принять предложение можно только за AssignmentDeadlineHours до заезда - срок должен захватывать это окно
+	acceptOpensAt := expiresAt.Add(-time.Duration(s.cfg.AssignmentDeadlineHours) * time.Hour)
+	if !deadline.After(now) || deadline.After(expiresAt) || deadline.Before(acceptOpensAt) {
+		return time.Time{}, fmt.Errorf("%w: accept_deadline must be in the future, not later than expires_at and not earlier than %d hours before it",
+			models.ErrInvalidAssignmentDates, s.cfg.AssignmentDeadlineHours)
+	}
+	return deadline, nil
+}
+
+// CreateAssignment создает предложение вручную(без бронирования от OTA). Если указан гость, предложение
+// сразу закрепляется за ним как приглашение со сроком принятия, гость получает уведомление
+func (s *SecretGuestService) CreateAssignment(ctx context.Context, actorID uuid.UUID, dto CreateAssignmentRequestDTO) (*AssignmentResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	now := time.Now()
+	if !dto.CheckoutDate.After(dto.CheckinDate) {
+		return nil, fmt.Errorf("%w: checkout_date must be after checkin_date", models.ErrInvalidAssignmentDates)
+	}
+
+	expiresAt := dto.CheckinDate
+	if dto.ExpiresAt != nil {
+		expiresAt = *dto.ExpiresAt
+	}
+	if !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", models.ErrInvalidAssignmentDates)
+	}
+
+	listing, err := s.repo.GetListingByID(ctx, dto.ListingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing by id %s: %w", dto.ListingID.String(), err)
+	}
+
+	assignment := models.Assignment{
+		ID:           uuid.New(),
+		ListingID:    listing.ID,
+		CheckinDate:  dto.CheckinDate,
+		CheckoutDate: dto.CheckoutDate,
+		Purpose:      strings.TrimSpace(dto.Purpose),
+		CreatedAt:    now,
+		ExpiresAt:    expiresAt,
+		CreatedBy:    &actorID,
+	}
+
+	if dto.CampaignID != nil {
+		campaign, err := s.repo.GetCampaignByID(ctx, *dto.CampaignID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get campaign by id %s: %w", dto.CampaignID.String(), err)
+		}
+		if !campaignAcceptsAssignment(campaign, dto.CheckinDate) {
+			return nil, models.ErrCampaignNotAvailable
+		}
+		assignment.CampaignID = &campaign.ID
+		if assignment.Purpose == "" {
+			assignment.Purpose = campaign.Purpose
+		}
+	}
+
+	var notification *models.Notification
+	if dto.ReporterID != nil {
+		if err := s.ensureApprovedGuest(ctx, *dto.ReporterID); err != nil {
+			return nil, err
+		}
+
+		deadline, err := s.inviteAcceptDeadline(dto.AcceptDeadline, expiresAt, now)
+		if err != nil {
+			return nil, err
+		}
+
+		assignment.ReporterID = *dto.ReporterID
+		assignment.TakedAt = &now
+		assignment.InvitedAt = &now
+		assignment.AcceptDeadline = &deadline
+
+		notification, err = newInvitedNotification(assignment.ID, listing.Title, listing.City, dto.CheckinDate, deadline)
+		if err != nil {
+			return nil, err
+		}
+		notification.UserID = *dto.ReporterID
+	}
+
+	details := map[string]any{
+		"listing_id":    assignment.ListingID,
+		"checkin_date":  assignment.CheckinDate,
+		"checkout_date": assignment.CheckoutDate,
+		"expires_at":    assignment.ExpiresAt,
+	}
+	if dto.ReporterID != nil {
+		details["reporter_id"] = assignment.ReporterID
+		details["accept_deadline"] = assignment.AcceptDeadline
+	}
+	if assignment.CampaignID != nil {
+		details["campaign_id"] = assignment.CampaignID
+	}
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionAssignmentCreated, models.AuditEntityAssignment, assignment.ID.String(), details)
+
+	if err := s.repo.CreateManualAssignment(ctx, &assignment, notification, entry); err != nil {
+		return nil, fmt.Errorf("failed to create assignment in repository: %w", err)
+	}
+
+	log.Info(ctx, "Assignment created by staff",
+		zap.String("actor_id", actorID.String()),
+		zap.String("assignment_id", assignment.ID.String()),
+		zap.Bool("invited", dto.ReporterID != nil),
+	)
+
+	dbAssignment, err := s.repo.GetAssignmentByID(ctx, assignment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment by id %s from repository: %w", assignment.ID.String(), err)
+	}
+
+	return toAssignmentResponseDTO(dbAssignment), nil
+}
 
 func (s *SecretGuestService) GetFreeAssignments(ctx context.Context, dto GetFreeAssignmentsRequestDTO) (*AssignmentsResponse, error) {
 
+	if err := s.ensureApprovedGuest(ctx, dto.UserID); err != nil {
+		return nil, err
+	}
+
 	filter := repository.AssignmentsFilter{
 		StatusIDs:      []int{models.AssignmentStatusOffered}, // только Offered
 		ListingTypeIDs: dto.ListingTypeIDs,
@@ -249,6 +499,12 @@ func (s *SecretGuestService) GetFreeAssignments(ctx context.Context, dto GetFree
 		City:           dto.City,
 	}
 
+	ranking, err := s.getAssignmentRanking(ctx, dto.UserID)
+	if err != nil {
+		return nil, err
+	}
+	filter.Ranking = ranking
+
 	// return s.getAssignmentsWithFilter(ctx, filter, dto.Page)
 
 	dbAssignments, total, err := s.repo.GetFreeAssignments(ctx, filter)
@@ -270,7 +526,11 @@ func (s *SecretGuestService) GetFreeAssignments(ctx context.Context, dto GetFree
 
 }
 
-func (s *SecretGuestService) GetFreeAssignmentsByID(ctx context.Context, assignmentID uuid.UUID) (*AssignmentResponseDTO, error) {
+func (s *SecretGuestService) GetFreeAssignmentsByID(ctx context.Context, userID, assignmentID uuid.UUID) (*AssignmentResponseDTO, error) {
+	if err := s.ensureApprovedGuest(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	assignment, err := s.repo.GetAssignmentByID(ctx, assignmentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
@@ -288,7 +548,17 @@ func (s *SecretGuestService) GetFreeAssignmentsByID(ctx context.Context, assignm
 
 func (s *SecretGuestService) TakeFreeAssignmentsByID(ctx context.Context, userID, assignmentID uuid.UUID) error {
 
-	err := s.repo.TakeFreeAssignmentsByID(ctx, assignmentID, userID, time.Now())
+	if err := s.ensureApprovedGuest(ctx, userID); err != nil {
+		return err
+	}
+
+	assignment, err := s.repo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
+	}
+
+	now := time.Now()
+	err = s.repo.TakeFreeAssignmentsByID(ctx, assignmentID, userID, now, s.assignmentHoldDeadline(now, assignment.ExpiresAt))
 	if err != nil {
 		return fmt.Errorf("failed to take assignment %s for user %s: %w", assignmentID.String(), userID.String(), err)
 	}
@@ -391,7 +661,22 @@ func toAssignmentResponseDTO(a *models.Assignment) *AssignmentResponseDTO {
 		AcceptedAt: a.AcceptedAt,
 		ExpiresAt:  a.ExpiresAt,
 		TakedAt:    a.TakedAt,
+
+		InvitedAt:       a.InvitedAt,
+		AcceptDeadline:  a.AcceptDeadline,
+		HoldSecondsLeft: holdSecondsLeft(a),
+
+		CampaignID: a.CampaignID,
+	}
+}
+
+// holdSecondsLeft - обратный отсчет до окончания срока принятия закрепленного за гостем предложения
+func holdSecondsLeft(a *models.Assignment) *int64 {
+	if a.ReporterID == uuid.Nil || a.StatusID != models.AssignmentStatusOffered || a.AcceptDeadline == nil {
+		return nil
 	}
+	left := max(int64(time.Until(*a.AcceptDeadline).Seconds()), 0)
+	return &left
 }
 
 func (s *SecretGuestService) GetMyAssignmentByID(ctx context.Context, userID, assignmentID uuid.UUID) (*AssignmentResponseDTO, error) {
@@ -416,7 +701,15 @@ func (s *SecretGuestService) GetAssignmentByID_AsStaff(ctx context.Context, assi
 	if err != nil {
 		return nil, fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
 	}
-	return toAssignmentResponseDTO(assignment), nil
+
+	stay, err := s.getAssignmentStayDTO(ctx, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := toAssignmentResponseDTO(assignment)
+	dto.Stay = stay
+	return dto, nil
 }
 
 func (s *SecretGuestService) AcceptMyAssignment(ctx context.Context, userID, assignmentID uuid.UUID) error {
@@ -437,17 +730,38 @@ func (s *SecretGuestService) AcceptMyAssignment(ctx context.Context, userID, ass
 		return fmt.Errorf("accept is allowed only within %d hours before check-in", s.cfg.AssignmentDeadlineHours)
 	}
 
-	report, err := s.repo.AcceptMyAssignment(ctx, assignmentID, userID, now)
+	reward := s.newAssignmentReward(ctx, assignment, userID)
+
+	report, err := s.repo.AcceptMyAssignment(ctx, assignmentID, userID, now, s.reportDueWindow(), reward)
 	if err != nil {
 		return fmt.Errorf("failed to accept assignment %s for user %s: %w", assignmentID.String(), userID.String(), err)
 	}
 
+	s.awardBadgesAfterTransition(ctx, userID)
+
 	// Генерация схемы отчета
 	s.generateChecklistSchemaForReport(ctx, report)
 	return nil
 }
 
-func (s *SecretGuestService) DeclineMyAssignment(ctx context.Context, userID, assignmentID uuid.UUID) error {
+// DeclineMyAssignment - отказ гостя от предложения с необязательной причиной; для причины other нужен комментарий
+func (s *SecretGuestService) DeclineMyAssignment(ctx context.Context, userID, assignmentID uuid.UUID, dto DeclineAssignmentRequestDTO) error {
+	var reasonID *int
+	if dto.Reason != "" {
+		id, ok := declineReasonIDs[dto.Reason]
+		if !ok {
+			return fmt.Errorf("%w: unknown decline reason", models.ErrValidationFailed)
+		}
+		reasonID = &id
+	}
+
+	var comment *string
+	if c := strings.TrimSpace(dto.Comment); c != "" {
+		comment = &c
+	}
+	if dto.Reason == "other" && comment == nil {
+		return fmt.Errorf("%w: comment is required for reason other", models.ErrValidationFailed)
+	}
 
 	// как в GetMyAssignmentByID
 	assignment, err := s.repo.GetAssignmentByIDAndOwner(ctx, assignmentID, userID)
@@ -458,22 +772,65 @@ func (s *SecretGuestService) DeclineMyAssignment(ctx context.Context, userID, as
 		return models.ErrAssignmentNotFound
 	}
 
-	takedAt := assignment.TakedAt
-	declinedAt := time.Now()
+	now := time.Now()
+	offer, err := s.newWaitlistOffer(assignment.ID, assignment.Listing.Title, assignment.Listing.City, assignment.CheckinDate, assignment.ExpiresAt, now)
+	if err != nil {
+		return err
+	}
 
-	err = s.repo.DeclineMyAssignment(ctx, assignmentID, userID, *takedAt, declinedAt)
+	waitlistUserID, err := s.repo.DeclineMyAssignment(ctx, assignmentID, userID, now, reasonID, comment, offer)
 	if err != nil {
 		return fmt.Errorf("failed to decline assignment %s for user %s: %w", assignmentID.String(), userID.String(), err)
 	}
+	if waitlistUserID != nil {
+		logger.GetLoggerFromCtx(ctx).Info(ctx, "Declined assignment offered to waitlisted guest",
+			zap.String("assignment_id", assignmentID.String()),
+			zap.String("waitlist_user_id", waitlistUserID.String()),
+		)
+	}
 	return nil
 }
 
-func (s *SecretGuestService) CancelAssignment(ctx context.Context, assignmentID uuid.UUID) error {
+// CancelAssignment - персонал отменяет предложение Offered или Accepted. Гость, за которым оно закреплено, получает уведомление
+func (s *SecretGuestService) CancelAssignment(ctx context.Context, actorID, assignmentID uuid.UUID) error {
+	return s.cancelAssignment(ctx, actorID, assignmentID, nil)
+}
+
+// cancelAssignment - bulkID связывает записи журнала одной массовой операции(nil - одиночная)
+func (s *SecretGuestService) cancelAssignment(ctx context.Context, actorID, assignmentID uuid.UUID, bulkID *uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	assignment, err := s.repo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
+	}
 
-	err := s.repo.CancelAssignment(ctx, assignmentID)
+	payload, err := json.Marshal(map[string]any{"assignment_id": assignment.ID})
 	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+	notification := &models.Notification{
+		Type:  models.NotificationAssignmentCancelled,
+		Title: "Предложение отменено",
+		Body: fmt.Sprintf("Предложение на проверку «%s» (%s), заезд %s, отменено",
+			assignment.Listing.Title, assignment.Listing.City, assignment.CheckinDate.Format("02.01.2006")),
+		Payload: payload,
+	}
+
+	details := map[string]any{"status_id": assignment.StatusID}
+	if assignment.ReporterID != uuid.Nil {
+		details["reporter_id"] = assignment.ReporterID
+	}
+	if bulkID != nil {
+		details["bulk_id"] = bulkID
+	}
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionAssignmentCancelled, models.AuditEntityAssignment, assignment.ID.String(), details)
+
+	if err := s.repo.CancelAssignment(ctx, assignmentID, notification, entry); err != nil {
 		return fmt.Errorf("failed to cancel assignment %s: %w", assignmentID.String(), err)
 	}
+
+	log.Info(ctx, "Assignment cancelled by staff", zap.String("assignment_id", assignmentID.String()), zap.String("actor_id", actorID.String()))
 	return nil
 }
 
@@ -561,7 +918,9 @@ func (s *SecretGuestService) HandleOTAReservation(ctx context.Context, dto OTARe
 
 	// TODO: Нужно вынести создание предложения в отдельную фоновую задачу
 	if dto.Reservation.Status == "reserved" {
-		s.createAssignmentFromOTAReservation(ctx, reservationID)
+		if s.needsAssignment(ctx, listingID, dto.Reservation.Dates.Checkin) {
+			s.createAssignmentFromOTAReservation(ctx, reservationID)
+		}
 	} else {
 		log := logger.GetLoggerFromCtx(ctx)
 		log.Info(ctx, "OTA reservation status is not reserved(assignment not created)", zap.Error(err))
@@ -608,6 +967,19 @@ func (s *SecretGuestService) createAssignmentFromOTAReservation(ctx context.Cont
 			ExpiresAt: otaReservation.CheckinDate,
 		}
 
+		// Бронирование по объекту из активной кампании засчитывается в нее
+		campaign, err := s.repo.FindCampaignForListing(taskCtx, otaReservation.ListingID, otaReservation.CheckinDate)
+		switch {
+		case err == nil:
+			assignment.CampaignID = &campaign.ID
+			assignment.Purpose = campaign.Purpose
+		case !errors.Is(err, models.ErrCampaignNotFound):
+			log.Error(taskCtx, "Failed to find campaign for OTA reservation, creating assignment without campaign",
+				zap.String("reservation_id", reservationID.String()),
+				zap.Error(err),
+			)
+		}
+
 		assignmentID, err := s.repo.CreateAssignment(taskCtx, &assignment)
 		if err != nil {
 			log.Error(taskCtx, "Failed to create assignment from OTA reservation", zap.Error(err))
@@ -712,10 +1084,11 @@ func (s *SecretGuestService) GetMyReports(ctx context.Context, dto GetMyReportsR
 	workStatuses := []int{models.ReportStatusDraft} // only draft
 
 	filter := repository.ReportsFilter{
-		ReporterID: &dto.UserID,
-		StatusIDs:  workStatuses,
-		Limit:      dto.Limit,
-		Offset:     (dto.Page - 1) * dto.Limit,
+		ReporterID:   &dto.UserID,
+		StatusIDs:    workStatuses,
+		OrderByDueAt: true,
+		Limit:        dto.Limit,
+		Offset:       (dto.Page - 1) * dto.Limit,
 	}
 
 	return s.getReportsWithFilter(ctx, filter, dto.Page)
@@ -782,10 +1155,22 @@ func toReportResponseDTO(r *models.Report) *ReportResponseDTO {
 		CreatedAt:       r.CreatedAt,
 		UpdatedAt:       r.UpdatedAt,
 		SubmittedAt:     r.SubmittedAt,
+		QualityScore:    r.QualityScore,
+		DueAt:           r.DueAt,
+		DueSecondsLeft:  dueSecondsLeft(r),
 		ChecklistSchema: r.ChecklistSchema,
 	}
 }
 
+// dueSecondsLeft - сколько секунд осталось до срока сдачи, только для незаконченных отчетов
+func dueSecondsLeft(r *models.Report) *int64 {
+	if r.DueAt == nil || (r.StatusID != models.ReportStatusDraft && r.StatusID != models.ReportStatusGenerating) {
+		return nil
+	}
+	left := max(int64(time.Until(*r.DueAt).Seconds()), 0)
+	return &left
+}
+
 func (s *SecretGuestService) GetMyReportByID(ctx context.Context, userID, reportID uuid.UUID) (*ReportResponseDTO, error) {
 	report, err := s.repo.GetReportByIDAndOwner(ctx, reportID, userID)
 	if err != nil {
@@ -825,6 +1210,7 @@ func (s *SecretGuestService) SubmitMyReport(ctx context.Context, userID, reportI
 	if err != nil {
 		return fmt.Errorf("failed to submit report %s by user %s: %w", reportID.String(), userID.String(), err)
 	}
+	s.awardBadgesAfterTransition(ctx, userID)
 	return nil
 }
 
@@ -844,22 +1230,36 @@ func (s *SecretGuestService) GetReportByID_AsStaff(ctx context.Context, reportID
 		return nil, fmt.Errorf("failed to get report by id %s: %w", reportID.String(), err)
 	}
 
-	return toReportResponseDTO(report), nil
+	stay, err := s.getAssignmentStayDTO(ctx, report.AssignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := toReportResponseDTO(report)
+	dto.Stay = stay
+	return dto, nil
 }
 
-func (s *SecretGuestService) ApproveReport(ctx context.Context, staffID, reportID uuid.UUID) error {
-	err := s.repo.UpdateReportStatusAsStaff(ctx, reportID, models.ReportStatusSubmitted, models.ReportStatusApproved)
+// ApproveReport одобряет отчет. qualityScore - оценка качества объекта, учитывается в приоритете проверки
+func (s *SecretGuestService) ApproveReport(ctx context.Context, staffID, reportID uuid.UUID, qualityScore *int) error {
+	reporterID, err := s.repo.ReviewReport(ctx, reportID, models.ReportStatusApproved, staffID, qualityScore)
 	if err != nil {
 		return fmt.Errorf("failed to approve report %s by staff %s: %w", reportID.String(), staffID.String(), err)
 	}
+	if reporterID != nil {
+		s.awardBadgesAfterTransition(ctx, *reporterID)
+	}
 	return nil
 }
 
 func (s *SecretGuestService) RejectReport(ctx context.Context, staffID, reportID uuid.UUID) error {
-	err := s.repo.UpdateReportStatusAsStaff(ctx, reportID, models.ReportStatusSubmitted, models.ReportStatusRejected)
+	reporterID, err := s.repo.ReviewReport(ctx, reportID, models.ReportStatusRejected, staffID, nil)
 	if err != nil {
 		return fmt.Errorf("failed to reject report %s by staff %s: %w", reportID.String(), staffID.String(), err)
 	}
+	if reporterID != nil {
+		s.awardBadgesAfterTransition(ctx, *reporterID)
+	}
 	return nil
 }
 
@@ -1263,7 +1663,16 @@ func (s *SecretGuestService) GetAllUsers(ctx context.Context, dto GetAllUsersReq
 
 	offset := (dto.Page - 1) * dto.Limit
 
-	dbUsers, total, err := s.repo.GetAllUsers(ctx, dto.Limit, offset)
+	filter := repository.UsersFilter{
+		Search:         strings.TrimSpace(dto.Search),
+		RoleIDs:        dto.RoleIDs,
+		Blocked:        dto.Blocked,
+		ServiceAccount: dto.ServiceAccount,
+		Limit:          dto.Limit,
+		Offset:         offset,
+	}
+
+	dbUsers, total, err := s.repo.GetAllUsers(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users from repository: %w", err)
 	}
@@ -1281,237 +1690,2622 @@ func (s *SecretGuestService) GetAllUsers(ctx context.Context, dto GetAllUsersReq
 	return response, nil
 }
 
-func toUserResponseDTO(u *models.User) *UserResponseDTO {
-	if u == nil {
-		return nil
+func (s *SecretGuestService) GetUserByID_AsStaff(ctx context.Context, userID uuid.UUID) (*UserResponseDTO, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from repository: %w", err)
 	}
+	return toUserResponseDTO(user), nil
+}
 
-	var email *string
-	if u.Email != "" {
-		email = &u.Email
+func (s *SecretGuestService) ChangeUserRole(ctx context.Context, actorID, userID uuid.UUID, dto ChangeUserRoleRequestDTO) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if actorID == userID {
+		return models.ErrCannotModifySelf
 	}
 
-	return &UserResponseDTO{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     email,
-		RoleID:    u.RoleID,
-		RoleName:  u.RoleName,
-		CreatedAt: u.CreatedAt,
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user from repository: %w", err)
 	}
-}
 
-// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+	if user.DeletedAt != nil {
+		return models.ErrUserDeleted
+	}
 
-// Генерация схемы отчета
+	if user.RoleID == dto.RoleID {
+		return nil
+	}
 
-func (s *SecretGuestService) generateChecklistSchemaForReport(ctx context.Context, report *models.Report) {
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionUserRoleChanged, models.AuditEntityUser, userID.String(), map[string]any{
+		"old_role_id": user.RoleID,
+		"new_role_id": dto.RoleID,
+	})
 
-		bgCtx := context.Background()
-		bgCtx = logger.ContextWithLogger(bgCtx, logger.GetLoggerFromCtx(ctx))
-		log := logger.GetLoggerFromCtx(bgCtx)
+	if err := s.repo.UpdateUserRole(ctx, userID, dto.RoleID, entry); err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
 
-		taskCtx, cancel := context.WithTimeout(bgCtx, 1*time.Minute)
-		defer cancel()
+	log.Info(ctx, "User role changed",
+		zap.String("actor_id", actorID.String()),
+		zap.String("user_id", userID.String()),
+		zap.Int("old_role_id", user.RoleID),
+		zap.Int("new_role_id", dto.RoleID),
+	)
+	return nil
+}
 
-		log.Info(taskCtx, "Starting background generation of checklist schema", zap.String("report_id", report.ID.String()))
+func (s *SecretGuestService) BlockUser(ctx context.Context, actorID, userID uuid.UUID, dto BlockUserRequestDTO) error {
+	log := logger.GetLoggerFromCtx(ctx)
 
-		// Вспомогательная функция для обработки сбоев
-		handleFailure := func(err error, message string) {
-			log.Error(taskCtx, message, zap.Error(err), zap.String("report_id", report.ID.String()))
+	if actorID == userID {
+		return models.ErrCannotModifySelf
+	}
 
-			updateErr := s.repo.UpdateReportStatusAsStaff(taskCtx, report.ID, models.ReportStatusGenerating, models.ReportStatusGenerationFailed)
-			if updateErr != nil {
-				log.Error(taskCtx, "Failed to update report status to generation_failed", zap.Error(updateErr), zap.String("report_id", report.ID.String()))
-			}
-		}
+	reason := strings.TrimSpace(dto.Reason)
+	if reason == "" {
+		return models.ErrValidationFailed
+	}
 
-		listingTypeID, err := s.repo.GetListingTypeID(taskCtx, report.ListingID)
-		if err != nil {
-			handleFailure(err, "Failed to get listing type ID for schema generation")
-			return
-		}
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionUserBlocked, models.AuditEntityUser, userID.String(), map[string]any{
+		"reason": reason,
+	})
 
-		dbSections, dbItems, err := s.repo.GetChecklistTemplate(taskCtx, listingTypeID)
-		if err != nil {
-			handleFailure(err, "Failed to get checklist template from repo")
-			return
-		}
+	if err := s.repo.SetUserBlocked(ctx, userID, &reason, actorID, entry); err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
 
-		itemsBySection := make(map[int][]*ItemSchema)
-		for _, dbItem := range dbItems {
-			answer := &Answer{}
-			item := &ItemSchema{
-				ID:          dbItem.ID,
-				Slug:        dbItem.Slug,
-				Title:       dbItem.Title,
-				Description: dbItem.Description,
-				AnswerTypes: &AnswerTypesSchema{
-					Slug: dbItem.AnswerTypeSlug,
-					Name: dbItem.AnswerTypeName,
-					Meta: dbItem.AnswerTypeMeta,
-				},
-				MediaRequirement: dbItem.MediaRequirementSlug,
-				SortOrder:        dbItem.SortOrder,
-				Answer:           answer,
-			}
+	log.Info(ctx, "User blocked", zap.String("actor_id", actorID.String()), zap.String("user_id", userID.String()))
+	return nil
+}
 
-			emptyString := ""
-			answer.Result = &emptyString
-			answer.Comment = &emptyString
+func (s *SecretGuestService) UnblockUser(ctx context.Context, actorID, userID uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
 
-			if dbItem.MediaRequirementSlug != models.MediaRequirementNone {
-				item.MediaAllowedTypes = dbItem.MediaAllowedTypes
-				maxFiles := dbItem.MediaMaxFiles
-				item.MediaMaxFiles = &maxFiles
-				answer.Media = []*MediaFile{}
-			}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user from repository: %w", err)
+	}
 
-			itemsBySection[dbItem.SectionID] = append(itemsBySection[dbItem.SectionID], item)
-		}
+	// Удаленную учетную запись нельзя вернуть, ее данные уже обезличены
+	if user.DeletedAt != nil {
+		return models.ErrUserDeleted
+	}
 
-		// Собираем секции, добавляя в них сгруппированные пункты
-		schemaSections := make([]*SectionSchema, 0, len(dbSections))
-		for _, dbSection := range dbSections {
-			section := &SectionSchema{
-				ID:        dbSection.ID,
-				Slug:      dbSection.Slug,
-				Title:     dbSection.Title,
-				SortOrder: dbSection.SortOrder,
-				Items:     itemsBySection[dbSection.ID],
-			}
-			schemaSections = append(schemaSections, section)
-		}
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionUserUnblocked, models.AuditEntityUser, userID.String(), nil)
 
-		finalSchema := &ChecklistSchema{
-			Version:  "1.0",
-			Sections: schemaSections,
-		}
+	if err := s.repo.SetUserBlocked(ctx, userID, nil, actorID, entry); err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
 
-		schemaBytes, err := json.Marshal(finalSchema)
-		if err != nil {
-			handleFailure(err, "Failed to marshal checklist schema to JSON")
-			return
-		}
+	log.Info(ctx, "User unblocked", zap.String("actor_id", actorID.String()), zap.String("user_id", userID.String()))
+	return nil
+}
 
-		// для мапы
-		var schemaForDB models.ChecklistSchema
-		if err := json.Unmarshal(schemaBytes, &schemaForDB); err != nil {
-			handleFailure(err, "Failed to unmarshal checklist schema to map for DB")
-			return
-		}
-		if err := s.repo.UpdateReportSchema(taskCtx, report.ID, schemaForDB); err != nil {
-			handleFailure(err, "Failed to save checklist schema to DB")
-			return
-		}
+// ResetUserPassword генерирует временный пароль. Пользователь сможет работать с API только после его смены.
+func (s *SecretGuestService) ResetUserPassword(ctx context.Context, actorID, userID uuid.UUID) (*ResetUserPasswordResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
 
-		// Всё ОК, установим 'draft'
-		if err := s.repo.UpdateReportStatusAsStaff(taskCtx, report.ID, models.ReportStatusGenerating, models.ReportStatusDraft); err != nil {
-			log.Error(taskCtx, "Failed to update report status to draft after schema generation", zap.Error(err))
-			// В этом случае не вызываем handleFailure, так как схема уже сохранена.
-			// Это критическая ошибка, которую нужно мониторить, но отчет уже не в 'generating'.
-			return
-		}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from repository: %w", err)
+	}
+
+	// Пароль сервисной учетной записи дал бы вход по логину в обход API-ключей
+	if user.IsServiceAccount {
+		return nil, models.ErrServiceAccount
+	}
+
+	if user.DeletedAt != nil {
+		return nil, models.ErrUserDeleted
+	}
+
+	tempPassword, err := generateTemporaryPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(tempPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash temporary password: %w", err)
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionUserPasswordReset, models.AuditEntityUser, userID.String(), nil)
+
+	if err := s.repo.ResetUserPassword(ctx, userID, string(hash), entry); err != nil {
+		return nil, fmt.Errorf("failed to reset user password: %w", err)
+	}
+
+	log.Info(ctx, "User password reset by admin", zap.String("actor_id", actorID.String()), zap.String("user_id", userID.String()))
+
+	return &ResetUserPasswordResponseDTO{TemporaryPassword: tempPassword}, nil
+}
+
+func generateTemporaryPassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate temporary password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func toUserResponseDTO(u *models.User) *UserResponseDTO {
+	if u == nil {
+		return nil
+	}
+
+	var email *string
+	if u.Email != "" {
+		email = &u.Email
+	}
+
+	return &UserResponseDTO{
+		ID:                     u.ID,
+		Username:               u.Username,
+		Email:                  email,
+		RoleID:                 u.RoleID,
+		RoleName:               u.RoleName,
+		CreatedAt:              u.CreatedAt,
+		IsBlocked:              u.BlockedAt != nil,
+		BlockedAt:              u.BlockedAt,
+		BlockedReason:          u.BlockedReason,
+		PasswordChangeRequired: u.PasswordChangeRequired,
+		IsServiceAccount:       u.IsServiceAccount,
+		DeletedAt:              u.DeletedAt,
+	}
+}
+
+// audit_log
+
+func (s *SecretGuestService) GetAuditLog(ctx context.Context, dto GetAuditLogRequestDTO) (*AuditLogResponse, error) {
+	offset := (dto.Page - 1) * dto.Limit
+
+	filter := repository.AuditLogFilter{
+		ActorID:    dto.ActorID,
+		EntityType: dto.EntityType,
+		EntityID:   dto.EntityID,
+		Actions:    dto.Actions,
+		Limit:      dto.Limit,
+		Offset:     offset,
+	}
+
+	entries, total, err := s.repo.GetAuditLog(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log from repository: %w", err)
+	}
+
+	responseDTOs := make([]*AuditLogEntryResponseDTO, 0, len(entries))
+	for _, e := range entries {
+		responseDTOs = append(responseDTOs, &AuditLogEntryResponseDTO{
+			ID:            e.ID,
+			ActorID:       e.ActorID,
+			ActorUsername: e.ActorUsername,
+			Action:        e.Action,
+			EntityType:    e.EntityType,
+			EntityID:      e.EntityID,
+			Details:       e.Details,
+			CreatedAt:     e.CreatedAt,
+		})
+	}
+
+	return &AuditLogResponse{
+		Entries: responseDTOs,
+		Total:   total,
+		Page:    dto.Page,
+	}, nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Роли и права
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+func isBuiltInRole(roleID int) bool {
+	return roleID == models.AdminRoleID || roleID == models.ModeratorRoleID || roleID == models.GuestRoleID
+}
+
+func toRoleResponseDTO(role *models.Role) *RoleResponseDTO {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return &RoleResponseDTO{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+		IsBuiltIn:   isBuiltInRole(role.ID),
+	}
+}
+
+// normalizePermissions убирает пробелы и дубликаты, сохраняя порядок
+func normalizePermissions(permissions []string) []string {
+	seen := make(map[string]struct{}, len(permissions))
+	result := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		result = append(result, p)
+	}
+	return result
+}
+
+func (s *SecretGuestService) GetPermissions(ctx context.Context) ([]*PermissionResponseDTO, error) {
+	permissions, err := s.repo.GetPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions from repository: %w", err)
+	}
+
+	response := make([]*PermissionResponseDTO, 0, len(permissions))
+	for _, p := range permissions {
+		response = append(response, &PermissionResponseDTO{
+			ID:          p.ID,
+			Slug:        p.Slug,
+			Description: p.Description,
+		})
+	}
+	return response, nil
+}
+
+func (s *SecretGuestService) GetRoles(ctx context.Context) ([]*RoleResponseDTO, error) {
+	roles, err := s.repo.GetRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles from repository: %w", err)
+	}
+
+	response := make([]*RoleResponseDTO, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, toRoleResponseDTO(role))
+	}
+	return response, nil
+}
+
+func (s *SecretGuestService) GetRoleByID(ctx context.Context, roleID int) (*RoleResponseDTO, error) {
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role from repository: %w", err)
+	}
+	return toRoleResponseDTO(role), nil
+}
+
+func (s *SecretGuestService) CreateRole(ctx context.Context, actorID uuid.UUID, dto CreateRoleRequestDTO) (*RoleResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	name := strings.TrimSpace(dto.Name)
+	if !roleNamePattern.MatchString(name) {
+		return nil, models.ErrInvalidRoleName
+	}
+
+	role := &models.Role{
+		Name:        name,
+		Description: dto.Description,
+		Permissions: normalizePermissions(dto.Permissions),
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionRoleCreated, models.AuditEntityRole, "", map[string]any{
+		"name":        role.Name,
+		"permissions": role.Permissions,
+	})
+
+	roleID, err := s.repo.CreateRole(ctx, role, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	log.Info(ctx, "Role created", zap.String("actor_id", actorID.String()), zap.Int("role_id", roleID), zap.String("name", role.Name))
+	return s.GetRoleByID(ctx, roleID)
+}
+
+func (s *SecretGuestService) UpdateRole(ctx context.Context, actorID uuid.UUID, roleID int, dto UpdateRoleRequestDTO) (*RoleResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	details := map[string]any{}
+
+	var name *string
+	if dto.Name != nil {
+		trimmed := strings.TrimSpace(*dto.Name)
+		if !roleNamePattern.MatchString(trimmed) {
+			return nil, models.ErrInvalidRoleName
+		}
+		// Имена встроенных ролей используются в сидах и миграциях
+		if isBuiltInRole(roleID) {
+			return nil, models.ErrRoleProtected
+		}
+		name = &trimmed
+		details["name"] = trimmed
+	}
+	if dto.Description != nil {
+		details["description"] = *dto.Description
+	}
+
+	if name == nil && dto.Description == nil {
+		return s.GetRoleByID(ctx, roleID)
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionRoleUpdated, models.AuditEntityRole, strconv.Itoa(roleID), details)
+
+	if err := s.repo.UpdateRole(ctx, roleID, name, dto.Description, entry); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	log.Info(ctx, "Role updated", zap.String("actor_id", actorID.String()), zap.Int("role_id", roleID))
+	return s.GetRoleByID(ctx, roleID)
+}
+
+func (s *SecretGuestService) SetRolePermissions(ctx context.Context, actorID uuid.UUID, roleID int, dto SetRolePermissionsRequestDTO) (*RoleResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	// Права администратора не редактируются, иначе можно потерять доступ к управлению ролями
+	if roleID == models.AdminRoleID {
+		return nil, models.ErrRoleProtected
+	}
+
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role from repository: %w", err)
+	}
+
+	permissions := normalizePermissions(dto.Permissions)
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionRolePermissionsChanged, models.AuditEntityRole, strconv.Itoa(roleID), map[string]any{
+		"old_permissions": role.Permissions,
+		"new_permissions": permissions,
+	})
+
+	if err := s.repo.SetRolePermissions(ctx, roleID, permissions, entry); err != nil {
+		return nil, fmt.Errorf("failed to set role permissions: %w", err)
+	}
+
+	log.Info(ctx, "Role permissions changed",
+		zap.String("actor_id", actorID.String()),
+		zap.Int("role_id", roleID),
+		zap.Strings("permissions", permissions),
+	)
+	return s.GetRoleByID(ctx, roleID)
+}
+
+func (s *SecretGuestService) DeleteRole(ctx context.Context, actorID uuid.UUID, roleID int) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	if isBuiltInRole(roleID) {
+		return models.ErrRoleProtected
+	}
+
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return fmt.Errorf("failed to get role from repository: %w", err)
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionRoleDeleted, models.AuditEntityRole, strconv.Itoa(roleID), map[string]any{
+		"name":        role.Name,
+		"permissions": role.Permissions,
+	})
+
+	if err := s.repo.DeleteRole(ctx, roleID, entry); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	log.Info(ctx, "Role deleted", zap.String("actor_id", actorID.String()), zap.Int("role_id", roleID))
+	return nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Сервисные учетные записи и API-ключи
+
+func toAPIKeyResponseDTO(k *models.APIKey) APIKeyResponseDTO {
+	isActive := k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()))
+	return APIKeyResponseDTO{
+		ID:         k.ID,
+		UserID:     k.UserID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedBy:  k.CreatedBy,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		IsActive:   isActive,
+	}
+}
+
+func (s *SecretGuestService) CreateServiceAccount(ctx context.Context, actorID uuid.UUID, dto CreateServiceAccountRequestDTO) (*UserResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	username := strings.TrimSpace(dto.Username)
+	if username == "" {
+		return nil, models.ErrInvalidUsername
+	}
+
+	user := &models.User{
+		ID:       uuid.New(),
+		Username: username,
+		RoleID:   dto.RoleID,
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionServiceAccountCreated, models.AuditEntityUser, user.ID.String(), map[string]any{
+		"username": username,
+		"role_id":  dto.RoleID,
+	})
+
+	if err := s.repo.CreateServiceAccount(ctx, user, entry); err != nil {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	log.Info(ctx, "Service account created", zap.String("actor_id", actorID.String()), zap.String("user_id", user.ID.String()))
+	return s.GetUserByID_AsStaff(ctx, user.ID)
+}
+
+func (s *SecretGuestService) getServiceAccount(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from repository: %w", err)
+	}
+	if !user.IsServiceAccount {
+		return nil, models.ErrNotServiceAccount
+	}
+	return user, nil
+}
+
+func (s *SecretGuestService) GetAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKeyResponseDTO, error) {
+	if _, err := s.getServiceAccount(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.repo.GetAPIKeysByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys from repository: %w", err)
+	}
+
+	response := make([]APIKeyResponseDTO, 0, len(keys))
+	for _, k := range keys {
+		response = append(response, toAPIKeyResponseDTO(k))
+	}
+	return response, nil
+}
+
+// CreateAPIKey выпускает ключ. Scopes должны входить в права роли учетной записи;
+// если права роли позже сократятся, ключ тоже потеряет соответствующий доступ.
+func (s *SecretGuestService) CreateAPIKey(ctx context.Context, actorID, userID uuid.UUID, dto CreateAPIKeyRequestDTO) (*CreateAPIKeyResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	user, err := s.getServiceAccount(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if dto.ExpiresAt != nil && !dto.ExpiresAt.After(time.Now()) {
+		return nil, models.ErrValidationFailed
+	}
+
+	role, err := s.repo.GetRoleByID(ctx, user.RoleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service account role: %w", err)
+	}
+
+	scopes := normalizePermissions(dto.Scopes)
+	if len(scopes) == 0 {
+		return nil, models.ErrInvalidAPIKeyScope
+	}
+	rolePermissions := make(map[string]struct{}, len(role.Permissions))
+	for _, p := range role.Permissions {
+		rolePermissions[p] = struct{}{}
+	}
+	for _, scope := range scopes {
+		if _, ok := rolePermissions[scope]; !ok {
+			return nil, models.ErrInvalidAPIKeyScope
+		}
+	}
+
+	rawKey, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      strings.TrimSpace(dto.Name),
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    scopes,
+		CreatedBy: &actorID,
+		ExpiresAt: dto.ExpiresAt,
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionAPIKeyCreated, models.AuditEntityAPIKey, key.ID.String(), map[string]any{
+		"user_id":    userID,
+		"name":       key.Name,
+		"scopes":     scopes,
+		"expires_at": dto.ExpiresAt,
+	})
+
+	if err := s.repo.CreateAPIKey(ctx, key, entry); err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	log.Info(ctx, "API key created",
+		zap.String("actor_id", actorID.String()),
+		zap.String("user_id", userID.String()),
+		zap.String("api_key_id", key.ID.String()),
+	)
+
+	return &CreateAPIKeyResponseDTO{
+		APIKeyResponseDTO: toAPIKeyResponseDTO(key),
+		Key:               rawKey,
+	}, nil
+}
+
+func (s *SecretGuestService) RevokeAPIKey(ctx context.Context, actorID, keyID uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	key, err := s.repo.GetAPIKeyByID(ctx, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to get API key from repository: %w", err)
+	}
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionAPIKeyRevoked, models.AuditEntityAPIKey, keyID.String(), map[string]any{
+		"user_id": key.UserID,
+		"name":    key.Name,
+	})
+
+	if err := s.repo.RevokeAPIKey(ctx, keyID, entry); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	log.Info(ctx, "API key revoked", zap.String("actor_id", actorID.String()), zap.String("api_key_id", keyID.String()))
+	return nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Заявки на участие в программе
+
+func toGuestApplicationResponseDTO(a *models.GuestApplication) *GuestApplicationResponseDTO {
+	socialLinks := a.SocialLinks
+	if socialLinks == nil {
+		socialLinks = []string{}
+	}
+	return &GuestApplicationResponseDTO{
+		ID:       a.ID,
+		UserID:   a.UserID,
+		Username: a.Username,
+		Status: StatusResponse{
+			ID:   a.Status.ID,
+			Slug: a.Status.Slug,
+			Name: a.Status.Name,
+		},
+		Motivation:      a.Motivation,
+		TravelFrequency: a.TravelFrequency,
+		HomeCity:        a.HomeCity,
+		SocialLinks:     socialLinks,
+		CreatedAt:       a.CreatedAt,
+		UpdatedAt:       a.UpdatedAt,
+		ReviewedBy:      a.ReviewedBy,
+		ReviewedAt:      a.ReviewedAt,
+		ReviewComment:   a.ReviewComment,
+	}
+}
+
+// ensureApprovedGuest - свободные предложения доступны только участникам с одобренной заявкой
+func (s *SecretGuestService) ensureApprovedGuest(ctx context.Context, userID uuid.UUID) error {
+	application, err := s.repo.GetGuestApplicationByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrApplicationNotFound) {
+			return models.ErrApplicationNotApproved
+		}
+		return fmt.Errorf("failed to get guest application: %w", err)
+	}
+	if application.StatusID != models.GuestApplicationStatusApproved {
+		return models.ErrApplicationNotApproved
+	}
+	return nil
+}
+
+func (s *SecretGuestService) GetMyApplication(ctx context.Context, userID uuid.UUID) (*GuestApplicationResponseDTO, error) {
+	application, err := s.repo.GetGuestApplicationByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guest application: %w", err)
+	}
+	return toGuestApplicationResponseDTO(application), nil
+}
+
+// SubmitMyApplication подает заявку или редактирует ее, пока по ней не принято решение
+func (s *SecretGuestService) SubmitMyApplication(ctx context.Context, userID uuid.UUID, dto SubmitGuestApplicationRequestDTO) (*GuestApplicationResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	travelFrequency := dto.TravelFrequency
+	socialLinks := make([]string, 0, len(dto.SocialLinks))
+	for _, link := range dto.SocialLinks {
+		if link = strings.TrimSpace(link); link != "" {
+			socialLinks = append(socialLinks, link)
+		}
+	}
+
+	application := &models.GuestApplication{
+		ID:              uuid.New(),
+		UserID:          userID,
+		Motivation:      strings.TrimSpace(dto.Motivation),
+		TravelFrequency: &travelFrequency,
+		HomeCity:        strings.TrimSpace(dto.HomeCity),
+		SocialLinks:     socialLinks,
+	}
+
+	if err := s.repo.SaveGuestApplication(ctx, application); err != nil {
+		return nil, fmt.Errorf("failed to save guest application: %w", err)
+	}
+
+	log.Info(ctx, "Guest application submitted", zap.String("user_id", userID.String()), zap.String("application_id", application.ID.String()))
+	return s.GetMyApplication(ctx, userID)
+}
+
+func (s *SecretGuestService) GetGuestApplications(ctx context.Context, dto GetGuestApplicationsRequestDTO) (*GuestApplicationsResponse, error) {
+	statusIDs := dto.StatusIDs
+	if len(statusIDs) == 0 {
+		// По умолчанию - очередь нерассмотренных заявок
+		statusIDs = []int{models.GuestApplicationStatusPending, models.GuestApplicationStatusWaitlisted}
+	}
+
+	filter := repository.GuestApplicationsFilter{
+		StatusIDs: statusIDs,
+		Limit:     dto.Limit,
+		Offset:    (dto.Page - 1) * dto.Limit,
+	}
+
+	applications, total, err := s.repo.GetGuestApplications(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guest applications from repository: %w", err)
+	}
+
+	responseDTOs := make([]*GuestApplicationResponseDTO, 0, len(applications))
+	for _, a := range applications {
+		responseDTOs = append(responseDTOs, toGuestApplicationResponseDTO(a))
+	}
+
+	return &GuestApplicationsResponse{
+		Applications: responseDTOs,
+		Total:        total,
+		Page:         dto.Page,
+	}, nil
+}
+
+func (s *SecretGuestService) GetGuestApplicationByID(ctx context.Context, applicationID uuid.UUID) (*GuestApplicationResponseDTO, error) {
+	application, err := s.repo.GetGuestApplicationByID(ctx, applicationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guest application: %w", err)
+	}
+	return toGuestApplicationResponseDTO(application), nil
+}
+
+// ReviewGuestApplication - решение персонала по заявке: одобрить, отклонить или перевести в лист ожидания
+func (s *SecretGuestService) ReviewGuestApplication(ctx context.Context, actorID, applicationID uuid.UUID, statusID int, dto ReviewGuestApplicationRequestDTO) (*GuestApplicationResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	var action string
+	switch statusID {
+	case models.GuestApplicationStatusApproved:
+		action = models.AuditActionApplicationApproved
+	case models.GuestApplicationStatusRejected:
+		action = models.AuditActionApplicationRejected
+	case models.GuestApplicationStatusWaitlisted:
+		action = models.AuditActionApplicationWaitlisted
+	default:
+		return nil, models.ErrValidationFailed
+	}
+
+	application, err := s.repo.GetGuestApplicationByID(ctx, applicationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get guest application: %w", err)
+	}
+	if application.UserID == actorID {
+		return nil, models.ErrCannotModifySelf
+	}
+
+	var comment *string
+	if dto.Comment != nil {
+		if trimmed := strings.TrimSpace(*dto.Comment); trimmed != "" {
+			comment = &trimmed
+		}
+	}
+
+	entry := models.NewAuditLogEntry(actorID, action, models.AuditEntityGuestApplication, applicationID.String(), map[string]any{
+		"user_id":        application.UserID,
+		"from_status_id": application.StatusID,
+		"comment":        comment,
+	})
+
+	if err := s.repo.ReviewGuestApplication(ctx, applicationID, statusID, actorID, comment, entry); err != nil {
+		return nil, fmt.Errorf("failed to review guest application: %w", err)
+	}
+
+	log.Info(ctx, "Guest application reviewed",
+		zap.String("actor_id", actorID.String()),
+		zap.String("application_id", applicationID.String()),
+		zap.String("action", action),
+	)
+
+	return s.GetGuestApplicationByID(ctx, applicationID)
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Генерация схемы отчета
+
+func (s *SecretGuestService) generateChecklistSchemaForReport(ctx context.Context, report *models.Report) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		bgCtx := context.Background()
+		bgCtx = logger.ContextWithLogger(bgCtx, logger.GetLoggerFromCtx(ctx))
+		log := logger.GetLoggerFromCtx(bgCtx)
+
+		taskCtx, cancel := context.WithTimeout(bgCtx, 1*time.Minute)
+		defer cancel()
+
+		log.Info(taskCtx, "Starting background generation of checklist schema", zap.String("report_id", report.ID.String()))
+
+		// Вспомогательная функция для обработки сбоев
+		handleFailure := func(err error, message string) {
+			log.Error(taskCtx, message, zap.Error(err), zap.String("report_id", report.ID.String()))
+
+			updateErr := s.repo.UpdateReportStatusAsStaff(taskCtx, report.ID, models.ReportStatusGenerating, models.ReportStatusGenerationFailed)
+			if updateErr != nil {
+				log.Error(taskCtx, "Failed to update report status to generation_failed", zap.Error(updateErr), zap.String("report_id", report.ID.String()))
+			}
+		}
+
+		listingTypeID, err := s.repo.GetListingTypeID(taskCtx, report.ListingID)
+		if err != nil {
+			handleFailure(err, "Failed to get listing type ID for schema generation")
+			return
+		}
+
+		dbSections, dbItems, err := s.repo.GetChecklistTemplate(taskCtx, listingTypeID)
+		if err != nil {
+			handleFailure(err, "Failed to get checklist template from repo")
+			return
+		}
+
+		itemsBySection := make(map[int][]*ItemSchema)
+		for _, dbItem := range dbItems {
+			answer := &Answer{}
+			item := &ItemSchema{
+				ID:          dbItem.ID,
+				Slug:        dbItem.Slug,
+				Title:       dbItem.Title,
+				Description: dbItem.Description,
+				AnswerTypes: &AnswerTypesSchema{
+					Slug: dbItem.AnswerTypeSlug,
+					Name: dbItem.AnswerTypeName,
+					Meta: dbItem.AnswerTypeMeta,
+				},
+				MediaRequirement: dbItem.MediaRequirementSlug,
+				SortOrder:        dbItem.SortOrder,
+				Answer:           answer,
+			}
+
+			emptyString := ""
+			answer.Result = &emptyString
+			answer.Comment = &emptyString
+
+			if dbItem.MediaRequirementSlug != models.MediaRequirementNone {
+				item.MediaAllowedTypes = dbItem.MediaAllowedTypes
+				maxFiles := dbItem.MediaMaxFiles
+				item.MediaMaxFiles = &maxFiles
+				answer.Media = []*MediaFile{}
+			}
+
+			itemsBySection[dbItem.SectionID] = append(itemsBySection[dbItem.SectionID], item)
+		}
+
+		// Собираем секции, добавляя в них сгруппированные пункты
+		schemaSections := make([]*SectionSchema, 0, len(dbSections))
+		for _, dbSection := range dbSections {
+			section := &SectionSchema{
+				ID:        dbSection.ID,
+				Slug:      dbSection.Slug,
+				Title:     dbSection.Title,
+				SortOrder: dbSection.SortOrder,
+				Items:     itemsBySection[dbSection.ID],
+			}
+			schemaSections = append(schemaSections, section)
+		}
+
+		finalSchema := &ChecklistSchema{
+			Version:  "1.0",
+			Sections: schemaSections,
+		}
+
+		schemaBytes, err := json.Marshal(finalSchema)
+		if err != nil {
+			handleFailure(err, "Failed to marshal checklist schema to JSON")
+			return
+		}
+
+		// для мапы
+		var schemaForDB models.ChecklistSchema
+		if err := json.Unmarshal(schemaBytes, &schemaForDB); err != nil {
+			handleFailure(err, "Failed to unmarshal checklist schema to map for DB")
+			return
+		}
+		if err := s.repo.UpdateReportSchema(taskCtx, report.ID, schemaForDB); err != nil {
+			handleFailure(err, "Failed to save checklist schema to DB")
+			return
+		}
+
+		// Всё ОК, установим 'draft'
+		if err := s.repo.UpdateReportStatusAsStaff(taskCtx, report.ID, models.ReportStatusGenerating, models.ReportStatusDraft); err != nil {
+			log.Error(taskCtx, "Failed to update report status to draft after schema generation", zap.Error(err))
+			// В этом случае не вызываем handleFailure, так как схема уже сохранена.
+			// Это критическая ошибка, которую нужно мониторить, но отчет уже не в 'generating'.
+			return
+		}
 
 		log.Info(taskCtx, "Checklist schema generated and saved successfully", zap.String("report_id", report.ID.String()))
 	}()
 }
 
-// Загрузка файлов
+// Загрузка файлов
+
+func (s *SecretGuestService) GenerateUploadURL(ctx context.Context, userID uuid.UUID, dto GenerateUploadURLRequest) (*GenerateUploadURLResponse, error) {
+
+	log := logger.GetLoggerFromCtx(ctx)
+
+	// TO DO: Хз, нужно ли нам? Оставил для универсальности
+	params := storage.UploadParams{
+		FileName: dto.FileName,
+		Path:     fmt.Sprintf("users/%s", userID.String()), // Файлы по папкам пользователей
+		UserID:   userID.String(),
+	}
+
+	uploadData, err := s.storageProvider.GenerateUploadURL(ctx, params)
+	if err != nil {
+		log.Error(ctx, "Failed to generate upload URL from storage provider", zap.Error(err))
+		return nil, fmt.Errorf("could not generate upload URL: %w", err)
+	}
+
+	return toGenerateUploadURLResponseDTO(uploadData), nil
+}
+
+func toGenerateUploadURLResponseDTO(s *storage.UploadResponse) *GenerateUploadURLResponse {
+	if s == nil {
+		return nil
+	}
+
+	return &GenerateUploadURLResponse{
+		UploadURL: s.UploadURL,
+		Method:    s.Method,
+		FormData:  UploadFormDataDTO(s.FormData),
+		Headers:   s.Headers,
+	}
+}
+
+// Profile
+
+// resolveRank находит текущий и следующий ранг по сумме очков. tiers упорядочены по возрастанию порога.
+func resolveRank(tiers []*models.RankTier, points int) (current, next *models.RankTier) {
+	for _, tier := range tiers {
+		if points >= tier.MinPoints {
+			current = tier
+			continue
+		}
+		next = tier
+		break
+	}
+	return current, next
+}
+
+// localizedName - название на языке запроса, иначе на языке по умолчанию, иначе любое
+func localizedName(names map[string]string, lang string) string {
+	if name, ok := names[lang]; ok && name != "" {
+		return name
+	}
+	if name, ok := names[models.DefaultLanguage]; ok && name != "" {
+		return name
+	}
+	for _, name := range names {
+		return name
+	}
+	return ""
+}
+
+func toRankDTO(tier *models.RankTier, lang string) *RankDTO {
+	if tier == nil {
+		return nil
+	}
+	return &RankDTO{
+		Slug:      tier.Slug,
+		Name:      localizedName(tier.Names, lang),
+		MinPoints: tier.MinPoints,
+	}
+}
+
+// parseProfileInfo разбирает additional_info; пустое или не соответствующее схеме значение дает пустой профиль
+func parseProfileInfo(raw json.RawMessage) models.ProfileInfo {
+	var info models.ProfileInfo
+	if len(raw) == 0 {
+		return info
+	}
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return models.ProfileInfo{}
+	}
+	return info
+}
+
+func toProfileInfoDTO(info models.ProfileInfo) ProfileInfoDTO {
+	availability := make([]AvailabilityPeriodDTO, 0, len(info.Availability))
+	for _, period := range info.Availability {
+		availability = append(availability, AvailabilityPeriodDTO{From: period.From, To: period.To})
+	}
+
+	listingTypeIDs := info.PreferredListingTypeIDs
+	if listingTypeIDs == nil {
+		listingTypeIDs = []int{}
+	}
+	languages := info.Languages
+	if languages == nil {
+		languages = []string{}
+	}
+
+	return ProfileInfoDTO{
+		DisplayName:             info.DisplayName,
+		AvatarURL:               info.AvatarURL,
+		HomeCity:                info.HomeCity,
+		PreferredListingTypeIDs: listingTypeIDs,
+		Availability:            availability,
+		Languages:               languages,
+		HideFromLeaderboard:     info.HideFromLeaderboard,
+	}
+}
+
+func toProfileResponseDTO(p *models.UserProfile, tiers []*models.RankTier, lang string) *ProfileResponseDTO {
+
+	var rank RankDTO
+	if current, _ := resolveRank(tiers, p.Points); current != nil {
+		rank = *toRankDTO(current, lang)
+	}
+
+	return &ProfileResponseDTO{
+		ID:                    p.ID,
+		UserID:                p.UserID,
+		Username:              p.Username,
+		Email:                 p.Email,
+		AcceptedOffersCount:   p.AcceptedOffersCount,
+		SubmittedReportsCount: p.SubmittedReportsCount,
+		CorrectReportsCount:   p.CorrectReportsCount,
+		RegisteredAt:          p.RegisteredAt,
+		LastActiveAt:          p.LastActiveAt,
+		AdditionalInfo:        toProfileInfoDTO(parseProfileInfo(p.AdditionalInfo)),
+
+		Points:   p.Points,
+		Rank:     rank.Name,
+		RankSlug: rank.Slug,
+		Badges:   toEarnedBadgeDTOs(p.Badges, lang),
+	}
+}
+
+func (s *SecretGuestService) GetMyProfile(ctx context.Context, userID uuid.UUID, lang string) (*ProfileResponseDTO, error) {
+	profile, err := s.repo.GetUserProfileByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user profile by id %s from repository: %w", userID.String(), err)
+	}
+
+	tiers, err := s.repo.GetRankTiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rank tiers from repository: %w", err)
+	}
+
+	if err := s.attachProfileBadges(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	return toProfileResponseDTO(profile, tiers, lang), nil
+}
+
+func (s *SecretGuestService) UpdateMyProfile(ctx context.Context, userID uuid.UUID, dto UpdateMyProfileRequestDTO, lang string) (*ProfileResponseDTO, error) {
+	profile, err := s.repo.GetUserProfileByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user profile by id %s from repository: %w", userID.String(), err)
+	}
+
+	info := parseProfileInfo(profile.AdditionalInfo)
+
+	if dto.DisplayName != nil {
+		info.DisplayName = strings.TrimSpace(*dto.DisplayName)
+	}
+
+	if dto.AvatarPath != nil {
+		avatarURL, err := s.resolveAvatarURL(ctx, userID, *dto.AvatarPath)
+		if err != nil {
+			return nil, err
+		}
+		info.AvatarURL = avatarURL
+	}
+
+	if dto.HomeCity != nil {
+		info.HomeCity = strings.TrimSpace(*dto.HomeCity)
+	}
+
+	if dto.PreferredListingTypeIDs != nil {
+		listingTypeIDs, err := s.normalizeListingTypeIDs(ctx, *dto.PreferredListingTypeIDs)
+		if err != nil {
+			return nil, err
+		}
+		info.PreferredListingTypeIDs = listingTypeIDs
+	}
+
+	if dto.Availability != nil {
+		availability, err := normalizeAvailability(*dto.Availability)
+		if err != nil {
+			return nil, err
+		}
+		info.Availability = availability
+	}
+
+	if dto.Languages != nil {
+		info.Languages = normalizeLanguages(*dto.Languages)
+	}
+
+	if dto.HideFromLeaderboard != nil {
+		info.HideFromLeaderboard = *dto.HideFromLeaderboard
+	}
+
+	raw, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal profile info: %w", err)
+	}
+
+	if err := s.repo.UpdateUserProfileInfo(ctx, userID, raw); err != nil {
+		return nil, fmt.Errorf("failed to update user profile info in repository: %w", err)
+	}
+
+	tiers, err := s.repo.GetRankTiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rank tiers from repository: %w", err)
+	}
+
+	if err := s.attachProfileBadges(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	profile.AdditionalInfo = raw
+	return toProfileResponseDTO(profile, tiers, lang), nil
+}
+
+// resolveAvatarURL принимает только файлы из папки пользователя, в которую грузит /uploads/generate-url
+func (s *SecretGuestService) resolveAvatarURL(ctx context.Context, userID uuid.UUID, avatarPath string) (string, error) {
+	avatarPath = strings.TrimLeft(strings.TrimSpace(avatarPath), "/")
+	if avatarPath == "" {
+		return "", nil
+	}
+
+	folder := fmt.Sprintf("users/%s/", userID.String())
+	if !strings.HasPrefix(avatarPath, folder) || len(avatarPath) == len(folder) || strings.Contains(avatarPath, "..") {
+		return "", fmt.Errorf("%w: avatar must be uploaded to the %s folder", models.ErrValidationFailed, folder)
+	}
+
+	avatarURL, err := s.storageProvider.FileURL(ctx, avatarPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve avatar URL: %w", err)
+	}
+	return avatarURL, nil
+}
+
+func (s *SecretGuestService) normalizeListingTypeIDs(ctx context.Context, ids []int) ([]int, error) {
+	seen := make(map[int]struct{}, len(ids))
+	result := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	listingTypes, err := s.repo.GetListingTypes(ctx, repository.ListingTypesFilter{IDs: result})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing types from repository: %w", err)
+	}
+	if len(listingTypes) != len(result) {
+		return nil, fmt.Errorf("%w: unknown listing type", models.ErrValidationFailed)
+	}
+
+	return result, nil
+}
+
+func normalizeLanguages(languages []string) []string {
+	seen := make(map[string]struct{}, len(languages))
+	result := make([]string, 0, len(languages))
+	for _, lang := range languages {
+		if _, ok := seen[lang]; ok {
+			continue
+		}
+		seen[lang] = struct{}{}
+		result = append(result, lang)
+	}
+	return result
+}
+
+// normalizeAvailability проверяет периоды и упорядочивает их по дате начала
+func normalizeAvailability(periods []AvailabilityPeriodDTO) ([]models.AvailabilityPeriod, error) {
+	result := make([]models.AvailabilityPeriod, 0, len(periods))
+	for _, period := range periods {
+		from, err := time.Parse(time.DateOnly, period.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid availability date %q", models.ErrValidationFailed, period.From)
+		}
+		to, err := time.Parse(time.DateOnly, period.To)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid availability date %q", models.ErrValidationFailed, period.To)
+		}
+		if to.Before(from) {
+			return nil, fmt.Errorf("%w: availability period ends before it starts", models.ErrValidationFailed)
+		}
+		result = append(result, models.AvailabilityPeriod{From: period.From, To: period.To})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].From < result[j].From })
+	return result, nil
+}
+
+// getAssignmentRanking собирает предпочтения гостя для сортировки свободных предложений.
+// Без профиля или предпочтений возвращает nil - сортировка по дате создания
+func (s *SecretGuestService) getAssignmentRanking(ctx context.Context, userID uuid.UUID) (*repository.AssignmentRanking, error) {
+	profile, err := s.repo.GetUserProfileByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user profile by id %s from repository: %w", userID.String(), err)
+	}
+
+	info := parseProfileInfo(profile.AdditionalInfo)
+
+	ranking := &repository.AssignmentRanking{
+		HomeCity:       info.HomeCity,
+		ListingTypeIDs: info.PreferredListingTypeIDs,
+	}
+
+	today := time.Now().Truncate(24 * time.Hour)
+	for _, period := range info.Availability {
+		from, errFrom := time.Parse(time.DateOnly, period.From)
+		to, errTo := time.Parse(time.DateOnly, period.To)
+		if errFrom != nil || errTo != nil || to.Before(today) {
+			continue
+		}
+		ranking.AvailableFrom = append(ranking.AvailableFrom, from)
+		ranking.AvailableTo = append(ranking.AvailableTo, to)
+	}
+
+	if ranking.HomeCity == "" && len(ranking.ListingTypeIDs) == 0 && len(ranking.AvailableFrom) == 0 {
+		return nil, nil
+	}
+
+	return ranking, nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Таблица лидеров
+
+// leaderboardPeriodStart - первый день периода по UTC, nil - за все время
+func leaderboardPeriodStart(period string, now time.Time) *time.Time {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var from time.Time
+	switch period {
+	case models.LeaderboardPeriodWeek:
+		// неделя начинается с понедельника
+		from = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	case models.LeaderboardPeriodMonth:
+		from = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return nil
+	}
+	return &from
+}
+
+func toLeaderboardEntryDTO(e *models.LeaderboardEntry) *LeaderboardEntryDTO {
+	info := parseProfileInfo(e.AdditionalInfo)
+
+	displayName := info.DisplayName
+	if displayName == "" {
+		displayName = e.Username
+	}
+
+	return &LeaderboardEntryDTO{
+		Position:    e.Position,
+		UserID:      e.UserID,
+		DisplayName: displayName,
+		AvatarURL:   info.AvatarURL,
+		HomeCity:    info.HomeCity,
+		Points:      e.Points,
+	}
+}
+
+// GetLeaderboard - гости по очкам за период. Гости, скрывшие себя в профиле(hide_from_leaderboard), не показываются
+func (s *SecretGuestService) GetLeaderboard(ctx context.Context, userID uuid.UUID, dto GetLeaderboardRequestDTO) (*LeaderboardResponse, error) {
+	if dto.Period == "" {
+		dto.Period = models.LeaderboardPeriodAll
+	}
+	switch dto.Period {
+	case models.LeaderboardPeriodWeek, models.LeaderboardPeriodMonth, models.LeaderboardPeriodAll:
+	default:
+		return nil, fmt.Errorf("%w: unknown period %q", models.ErrValidationFailed, dto.Period)
+	}
+
+	filter := repository.LeaderboardFilter{
+		From:   leaderboardPeriodStart(dto.Period, time.Now()),
+		City:   strings.TrimSpace(dto.City),
+		Limit:  dto.Limit,
+		Offset: (dto.Page - 1) * dto.Limit,
+	}
+
+	entries, total, err := s.repo.GetLeaderboard(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get leaderboard from repository: %w", err)
+	}
+
+	response := &LeaderboardResponse{
+		Period:  dto.Period,
+		City:    filter.City,
+		Entries: make([]*LeaderboardEntryDTO, 0, len(entries)),
+		Total:   total,
+		Page:    dto.Page,
+	}
+	if filter.From != nil {
+		from := filter.From.Format(time.DateOnly)
+		response.From = &from
+	}
+	for _, e := range entries {
+		response.Entries = append(response.Entries, toLeaderboardEntryDTO(e))
+	}
+
+	me, err := s.repo.GetLeaderboardEntry(ctx, filter, userID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get leaderboard entry from repository: %w", err)
+	}
+	if me != nil {
+		response.Me = toLeaderboardEntryDTO(me)
+	}
+
+	return response, nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Удержание взятых предложений
+
+// expiredHoldsBatch - сколько истекших удержаний обрабатывается за один проход
+const expiredHoldsBatch = 100
+
+// assignmentHoldDeadline - до какого момента взятое предложение закреплено за гостем: AssignmentHoldMinutes с момента,
+// когда его можно принять(за AssignmentDeadlineHours до заезда), но не позже expires_at. nil - без ограничения
+func (s *SecretGuestService) assignmentHoldDeadline(takenAt, expiresAt time.Time) *time.Time {
+	if s.cfg.AssignmentHoldMinutes <= 0 {
+		return nil
+	}
+
+	start := expiresAt.Add(-time.Duration(s.cfg.AssignmentDeadlineHours) * time.Hour)
+	if start.Before(takenAt) {
+		start = takenAt
+	}
+	deadline := start.Add(time.Duration(s.cfg.AssignmentHoldMinutes) * time.Minute)
+	if deadline.After(expiresAt) {
+		deadline = expiresAt
+	}
+	return &deadline
+}
+
+// StartAssignmentHoldSweeper запускает фоновую задачу, которая раз в AssignmentHoldSweepSeconds возвращает в свободные
+// предложения с истекшим сроком принятия(взятые гостем и приглашения персонала) и уведомляет гостей.
+// Задача завершается по отмене ctx, Wait дожидается ее завершения
+func (s *SecretGuestService) StartAssignmentHoldSweeper(ctx context.Context) {
+	if s.cfg.AssignmentHoldSweepSeconds <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(time.Duration(s.cfg.AssignmentHoldSweepSeconds) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.ReleaseExpiredAssignmentHolds(ctx)
+			}
+		}
+	}()
+}
+
+// ReleaseExpiredAssignmentHolds возвращает в свободные предложения с истекшим сроком принятия и возвращает их число
+func (s *SecretGuestService) ReleaseExpiredAssignmentHolds(ctx context.Context) int {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	taskCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	holds, err := s.repo.GetExpiredAssignmentHolds(taskCtx, time.Now(), expiredHoldsBatch)
+	if err != nil {
+		log.Error(taskCtx, "Failed to get expired assignment holds", zap.Error(err))
+		return 0
+	}
+
+	released := 0
+	for _, hold := range holds {
+		notification, err := newHoldExpiredNotification(hold)
+		if err != nil {
+			log.Error(taskCtx, "Failed to build hold expired notification", zap.Error(err))
+			continue
+		}
+
+		offer, err := s.newWaitlistOffer(hold.AssignmentID, hold.ListingTitle, hold.City, hold.CheckinDate, hold.ExpiresAt, time.Now())
+		if err != nil {
+			log.Error(taskCtx, "Failed to build waitlist offer", zap.Error(err))
+			continue
+		}
+
+		waitlistUserID, err := s.repo.ReleaseAssignmentHold(taskCtx, hold, notification, offer)
+		if errors.Is(err, models.ErrAssignmentNotFound) {
+			// гость успел принять предложение или отказаться
+			continue
+		}
+		if err != nil {
+			log.Error(taskCtx, "Failed to release assignment hold", zap.Error(err), zap.String("assignment_id", hold.AssignmentID.String()))
+			continue
+		}
+
+		released++
+		fields := []zap.Field{
+			zap.String("assignment_id", hold.AssignmentID.String()),
+			zap.String("reporter_id", hold.ReporterID.String()),
+			zap.Time("accept_deadline", hold.AcceptDeadline),
+		}
+		if waitlistUserID != nil {
+			fields = append(fields, zap.String("waitlist_user_id", waitlistUserID.String()))
+		}
+		log.Info(taskCtx, "Assignment hold expired, assignment released", fields...)
+	}
+
+	return released
+}
+
+func newHoldExpiredNotification(hold *models.ExpiredAssignmentHold) (*models.Notification, error) {
+	payload, err := json.Marshal(map[string]any{
+		"assignment_id":   hold.AssignmentID,
+		"accept_deadline": hold.AcceptDeadline,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	what := "Срок принятия предложения"
+	if hold.InvitedAt != nil {
+		what = "Срок принятия приглашения"
+	}
+	return &models.Notification{
+		UserID: hold.ReporterID,
+		Type:   models.NotificationAssignmentHoldExpired,
+		Title:  "Предложение больше не закреплено за вами",
+		Body: fmt.Sprintf("%s на проверку «%s» (%s), заезд %s, истек %s. Предложение вернулось в список свободных",
+			what, hold.ListingTitle, hold.City, hold.CheckinDate.Format("02.01.2006"), hold.AcceptDeadline.Format("02.01.2006 15:04")),
+		Payload: payload,
+	}, nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Операции персонала с предложениями
+
+// newReleasedNotification - уведомление гостю, с которого персонал снял предложение(получателя выбирает репозиторий)
+func newReleasedNotification(assignment *models.Assignment) (*models.Notification, error) {
+	payload, err := json.Marshal(map[string]any{"assignment_id": assignment.ID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+	return &models.Notification{
+		Type:  models.NotificationAssignmentReleased,
+		Title: "Предложение больше не закреплено за вами",
+		Body: fmt.Sprintf("Сотрудник снял вас с предложения «%s» (%s), заезд %s",
+			assignment.Listing.Title, assignment.Listing.City, assignment.CheckinDate.Format("02.01.2006")),
+		Payload: payload,
+	}, nil
+}
+
+// ReleaseAssignment - персонал снимает гостя с закрепленного за ним предложения. Предложение получает следующий гость
+// из листа ожидания, если такого нет - оно возвращается в свободные
+func (s *SecretGuestService) ReleaseAssignment(ctx context.Context, actorID, assignmentID uuid.UUID) (*AssignmentResponseDTO, error) {
+	if err := s.releaseAssignment(ctx, actorID, assignmentID, nil); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
+	}
+	return toAssignmentResponseDTO(updated), nil
+}
+
+// releaseAssignment - bulkID связывает записи журнала одной массовой операции(nil - одиночная)
+func (s *SecretGuestService) releaseAssignment(ctx context.Context, actorID, assignmentID uuid.UUID, bulkID *uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	assignment, err := s.repo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
+	}
+	if assignment.StatusID != models.AssignmentStatusOffered || assignment.ReporterID == uuid.Nil {
+		return models.ErrAssignmentNotHeld
+	}
+
+	now := time.Now()
+	offer, err := s.newWaitlistOffer(assignment.ID, assignment.Listing.Title, assignment.Listing.City, assignment.CheckinDate, assignment.ExpiresAt, now)
+	if err != nil {
+		return err
+	}
+
+	notification, err := newReleasedNotification(assignment)
+	if err != nil {
+		return err
+	}
+
+	details := map[string]any{"reporter_id": assignment.ReporterID}
+	if bulkID != nil {
+		details["bulk_id"] = bulkID
+	}
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionAssignmentReleased, models.AuditEntityAssignment, assignment.ID.String(), details)
+
+	waitlistUserID, err := s.repo.ReleaseAssignment(ctx, assignmentID, notification, offer, entry)
+	if err != nil {
+		return fmt.Errorf("failed to release assignment %s: %w", assignmentID.String(), err)
+	}
+
+	fields := []zap.Field{
+		zap.String("assignment_id", assignmentID.String()),
+		zap.String("reporter_id", assignment.ReporterID.String()),
+		zap.String("actor_id", actorID.String()),
+	}
+	if waitlistUserID != nil {
+		fields = append(fields, zap.String("waitlist_user_id", waitlistUserID.String()))
+	}
+	log.Info(ctx, "Assignment released by staff", fields...)
+	return nil
+}
+
+// ReassignAssignment - персонал закрепляет открытое предложение за другим гостем как приглашение. Прежний гость
+// получает assignment.released, новый - assignment.invited
+func (s *SecretGuestService) ReassignAssignment(ctx context.Context, actorID, assignmentID uuid.UUID, dto ReassignAssignmentRequestDTO) (*AssignmentResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	assignment, err := s.repo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
+	}
+
+	now := time.Now()
+	if assignment.StatusID != models.AssignmentStatusOffered || !assignment.ExpiresAt.After(now) {
+		return nil, models.ErrAssignmentCannotBeReassigned
+	}
+
+	if err := s.ensureApprovedGuest(ctx, dto.ReporterID); err != nil {
+		return nil, err
+	}
+
+	deadline, err := s.inviteAcceptDeadline(dto.AcceptDeadline, assignment.ExpiresAt, now)
+	if err != nil {
+		return nil, err
+	}
+
+	released, err := newReleasedNotification(assignment)
+	if err != nil {
+		return nil, err
+	}
+	invited, err := newInvitedNotification(assignment.ID, assignment.Listing.Title, assignment.Listing.City, assignment.CheckinDate, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{
+		"reporter_id":     dto.ReporterID,
+		"accept_deadline": deadline,
+	}
+	if assignment.ReporterID != uuid.Nil {
+		details["previous_reporter_id"] = assignment.ReporterID
+	}
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionAssignmentReassigned, models.AuditEntityAssignment, assignment.ID.String(), details)
+
+	previousID, err := s.repo.ReassignAssignment(ctx, assignmentID, dto.ReporterID, &deadline, now, released, invited, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassign assignment %s to %s: %w", assignmentID.String(), dto.ReporterID.String(), err)
+	}
+
+	fields := []zap.Field{
+		zap.String("assignment_id", assignmentID.String()),
+		zap.String("reporter_id", dto.ReporterID.String()),
+		zap.String("actor_id", actorID.String()),
+	}
+	if previousID != nil {
+		fields = append(fields, zap.String("previous_reporter_id", previousID.String()))
+	}
+	log.Info(ctx, "Assignment reassigned by staff", fields...)
+
+	updated, err := s.repo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
+	}
+	return toAssignmentResponseDTO(updated), nil
+}
+
+// ExtendAssignment - персонал продлевает срок действия(expires_at) предложения Offered, в т.ч. уже истекшего
+func (s *SecretGuestService) ExtendAssignment(ctx context.Context, actorID, assignmentID uuid.UUID, dto ExtendAssignmentRequestDTO) (*AssignmentResponseDTO, error) {
+	if err := s.extendAssignment(ctx, actorID, assignmentID, dto.ExpiresAt, nil); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
+	}
+	return toAssignmentResponseDTO(updated), nil
+}
+
+// extendAssignment - новый срок позже текущего и наступает до выезда. bulkID - как в cancelAssignment
+func (s *SecretGuestService) extendAssignment(ctx context.Context, actorID, assignmentID uuid.UUID, expiresAt time.Time, bulkID *uuid.UUID) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	assignment, err := s.repo.GetAssignmentByID(ctx, assignmentID)
+	if err != nil {
+		return fmt.Errorf("failed to get assignment by id %s: %w", assignmentID.String(), err)
+	}
+	if assignment.StatusID != models.AssignmentStatusOffered {
+		return models.ErrAssignmentCannotBeExtended
+	}
+	now := time.Now()
+	if !expiresAt.After(assignment.ExpiresAt) || !expiresAt.After(now) || !expiresAt.Before(assignment.CheckoutDate) {
+		return fmt.Errorf("%w: expires_at must be in the future, later than the current one and before checkout_date", models.ErrInvalidAssignmentDates)
+	}
+
+	acceptDeadline, err := s.extendedAcceptDeadline(assignment, expiresAt, now)
+	if err != nil {
+		return err
+	}
+
+	details := map[string]any{
+		"previous_expires_at": assignment.ExpiresAt,
+		"expires_at":          expiresAt,
+	}
+	if acceptDeadline != nil {
+		details["accept_deadline"] = acceptDeadline
+	}
+	if bulkID != nil {
+		details["bulk_id"] = bulkID
+	}
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionAssignmentExtended, models.AuditEntityAssignment, assignment.ID.String(), details)
+
+	// Срок принятия считался для текущего гостя: если предложение успели перезакрепить, продление не применяется
+	var reporterID *uuid.UUID
+	if assignment.ReporterID != uuid.Nil {
+		reporterID = &assignment.ReporterID
+	}
+	if err := s.repo.ExtendAssignment(ctx, assignmentID, reporterID, expiresAt, acceptDeadline, entry); err != nil {
+		return fmt.Errorf("failed to extend assignment %s: %w", assignmentID.String(), err)
+	}
+
+	log.Info(ctx, "Assignment extended by staff",
+		zap.String("assignment_id", assignmentID.String()),
+		zap.String("actor_id", actorID.String()),
+		zap.Time("expires_at", expiresAt),
+	)
+	return nil
+}
+
+// extendedAcceptDeadline пересчитывает срок принятия закрепленного предложения от нового expires_at так же,
+// как при закреплении: приглашение персонала - до expires_at, взятое гостем - срок удержания от момента взятия.
+// У свободного предложения срока принятия нет
+func (s *SecretGuestService) extendedAcceptDeadline(assignment *models.Assignment, expiresAt, now time.Time) (*time.Time, error) {
+	switch {
+	case assignment.ReporterID == uuid.Nil:
+		return nil, nil
+	case assignment.InvitedAt != nil:
+		deadline, err := s.inviteAcceptDeadline(nil, expiresAt, now)
+		if err != nil {
+			return nil, err
+		}
+		return &deadline, nil
+	default:
+		takenAt := now
+		if assignment.TakedAt != nil {
+			takenAt = *assignment.TakedAt
+		}
+		return s.assignmentHoldDeadline(takenAt, expiresAt), nil
+	}
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Подтверждение проживания
+
+const earthRadiusMeters = 6371000
+
+// distanceMeters - расстояние по поверхности Земли между двумя точками(формула гаверсинусов)
+func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
+
+func toAssignmentStayDTO(stay *models.AssignmentStay) *AssignmentStayDTO {
+	if stay == nil || (stay.CheckIn == nil && stay.CheckOut == nil) {
+		return nil
+	}
+	toDTO := func(c *models.StayCheck) *StayCheckDTO {
+		if c == nil {
+			return nil
+		}
+		return &StayCheckDTO{
+			At:             c.At,
+			Latitude:       c.Latitude,
+			Longitude:      c.Longitude,
+			DistanceMeters: c.DistanceMeters,
+		}
+	}
+	return &AssignmentStayDTO{
+		CheckIn:  toDTO(stay.CheckIn),
+		CheckOut: toDTO(stay.CheckOut),
+	}
+}
+
+// inZone - дата из брони(местное время объекта без зоны, читается как UTC) как момент времени в зоне loc
+func inZone(floating time.Time, loc *time.Location) time.Time {
+	return time.Date(floating.Year(), floating.Month(), floating.Day(),
+		floating.Hour(), floating.Minute(), floating.Second(), floating.Nanosecond(), loc)
+}
+
+// newStayCheck проверяет отметку гостя: время устройства близко к серверному и попадает в окно [from, to],
+// устройство находится не дальше StayCheckRadiusMeters от объекта. Если у объекта нет координат, расстояние не проверяется.
+// from и to - местное время объекта, как даты брони. Зона объекта не хранится, поэтому окно переводится в смещение
+// от UTC из времени устройства: гость находится у объекта, а смещение должно соответствовать долготе объекта
+func (s *SecretGuestService) newStayCheck(assignment *models.Assignment, dto StayCheckRequestDTO, from, to time.Time) (*models.StayCheck, error) {
+	skew := time.Since(dto.Timestamp)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > models.StayCheckMaxClockSkew {
+		return nil, fmt.Errorf("%w: device time differs from server time by more than %s", models.ErrValidationFailed, models.StayCheckMaxClockSkew)
+	}
+
+	listing := assignment.Listing
+	hasCoordinates := listing.Latitude != 0 || listing.Longitude != 0
+
+	_, offsetSeconds := dto.Timestamp.Zone()
+	if hasCoordinates {
+		solar := time.Duration(listing.Longitude / 15 * float64(time.Hour))
+		deviation := time.Duration(offsetSeconds)*time.Second - solar
+		if deviation < 0 {
+			deviation = -deviation
+		}
+		if deviation > models.StayCheckMaxOffsetDeviation {
+			return nil, fmt.Errorf("%w: timestamp UTC offset does not match the listing location, send device local time with its offset",
+				models.ErrValidationFailed)
+		}
+	}
+
+	loc := dto.Timestamp.Location()
+	from, to = inZone(from, loc), inZone(to, loc)
+	if dto.Timestamp.Before(from) || dto.Timestamp.After(to) {
+		return nil, fmt.Errorf("%w: allowed from %s to %s", models.ErrStayCheckOutOfWindow,
+			from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	check := &models.StayCheck{
+		At:        dto.Timestamp,
+		Latitude:  *dto.Latitude,
+		Longitude: *dto.Longitude,
+	}
+
+	if !hasCoordinates {
+		return check, nil
+	}
+	distance := distanceMeters(listing.Latitude, listing.Longitude, check.Latitude, check.Longitude)
+	if distance > float64(s.cfg.StayCheckRadiusMeters) {
+		return nil, fmt.Errorf("%w: %.0f m from the listing, allowed %d m", models.ErrStayCheckTooFar, distance, s.cfg.StayCheckRadiusMeters)
+	}
+	distance = math.Round(distance)
+	check.DistanceMeters = &distance
+	return check, nil
+}
+
+// getAcceptedAssignment - предложение гостя, по которому можно отмечать заезд и выезд
+func (s *SecretGuestService) getAcceptedAssignment(ctx context.Context, userID, assignmentID uuid.UUID) (*models.Assignment, error) {
+	assignment, err := s.repo.GetAssignmentByIDAndOwner(ctx, assignmentID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment by id %s for owner %s: %w", assignmentID.String(), userID.String(), err)
+	}
+	if assignment.StatusID != models.AssignmentStatusAccepted {
+		return nil, models.ErrStayCheckNotAllowed
+	}
+	return assignment, nil
+}
+
+func (s *SecretGuestService) getAssignmentStayDTO(ctx context.Context, assignmentID uuid.UUID) (*AssignmentStayDTO, error) {
+	stay, err := s.repo.GetAssignmentStay(ctx, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stay of assignment %s: %w", assignmentID.String(), err)
+	}
+	return toAssignmentStayDTO(stay), nil
+}
+
+// CheckInMyAssignment - гость отмечает заезд по принятому предложению. Отметка принимается не раньше
+// StayCheckToleranceHours до даты заезда и не позже даты выезда
+func (s *SecretGuestService) CheckInMyAssignment(ctx context.Context, userID, assignmentID uuid.UUID, dto StayCheckRequestDTO) (*AssignmentStayDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	assignment, err := s.getAcceptedAssignment(ctx, userID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	tolerance := time.Duration(s.cfg.StayCheckToleranceHours) * time.Hour
+	check, err := s.newStayCheck(assignment, dto, assignment.CheckinDate.Add(-tolerance), assignment.CheckoutDate)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CheckInAssignment(ctx, assignmentID, userID, check); err != nil {
+		return nil, fmt.Errorf("failed to check in assignment %s for user %s: %w", assignmentID.String(), userID.String(), err)
+	}
+	log.Info(ctx, "Guest checked in", zap.String("assignment_id", assignmentID.String()), zap.String("user_id", userID.String()))
+
+	return s.getAssignmentStayDTO(ctx, assignmentID)
+}
+
+// CheckOutMyAssignment - гость отмечает выезд после отметки заезда. Отметка принимается не раньше даты заезда
+// и не позже StayCheckToleranceHours после даты выезда
+func (s *SecretGuestService) CheckOutMyAssignment(ctx context.Context, userID, assignmentID uuid.UUID, dto StayCheckRequestDTO) (*AssignmentStayDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	assignment, err := s.getAcceptedAssignment(ctx, userID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	tolerance := time.Duration(s.cfg.StayCheckToleranceHours) * time.Hour
+	check, err := s.newStayCheck(assignment, dto, assignment.CheckinDate, assignment.CheckoutDate.Add(tolerance))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CheckOutAssignment(ctx, assignmentID, userID, check); err != nil {
+		return nil, fmt.Errorf("failed to check out assignment %s for user %s: %w", assignmentID.String(), userID.String(), err)
+	}
+	log.Info(ctx, "Guest checked out", zap.String("assignment_id", assignmentID.String()), zap.String("user_id", userID.String()))
+
+	return s.getAssignmentStayDTO(ctx, assignmentID)
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Сроки сдачи отчетов
+
+// dueReportsBatch - сколько отчетов с подходящим или прошедшим сроком обрабатывается за один проход
+const dueReportsBatch = 100
+
+// reportDueWindow - сколько времени после выезда дается на сдачу отчета
+func (s *SecretGuestService) reportDueWindow() time.Duration {
+	return time.Duration(s.cfg.ReportDeadlineHoursAfterCheckout) * time.Hour
+}
+
+// reportReminderThresholds - за сколько до срока напоминать о сдаче отчета, по убыванию и без повторов
+func (s *SecretGuestService) reportReminderThresholds() []time.Duration {
+	hours := make([]int, 0, len(s.cfg.ReportReminderHoursBeforeDue))
+	for _, h := range s.cfg.ReportReminderHoursBeforeDue {
+		if h > 0 {
+			hours = append(hours, h)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(hours)))
+
+	thresholds := make([]time.Duration, 0, len(hours))
+	for i, h := range hours {
+		if i > 0 && hours[i-1] == h {
+			continue
+		}
+		thresholds = append(thresholds, time.Duration(h)*time.Hour)
+	}
+	return thresholds
+}
+
+// StartReportDeadlineSweeper запускает фоновую задачу, которая раз в ReportDeadlineSweepSeconds напоминает гостям
+// о приближении срока сдачи отчетов и переводит не сданные к сроку черновики в просроченные со штрафом.
+// Задача завершается по отмене ctx, Wait дожидается ее завершения
+func (s *SecretGuestService) StartReportDeadlineSweeper(ctx context.Context) {
+	if s.cfg.ReportDeadlineSweepSeconds <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		s.BackfillReportDueDates(ctx)
+
+		ticker := time.NewTicker(time.Duration(s.cfg.ReportDeadlineSweepSeconds) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.SendReportDueReminders(ctx)
+				s.MarkOverdueReports(ctx)
+			}
+		}
+	}()
+}
+
+// BackfillReportDueDates проставляет срок сдачи отчетам, созданным до появления сроков, по тому же окну
+// REPORT_DEADLINE_HOURS_AFTER_CHECKOUT, что и при принятии предложения, и возвращает число обновленных отчетов
+func (s *SecretGuestService) BackfillReportDueDates(ctx context.Context) int64 {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	taskCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	updated, err := s.repo.BackfillReportDueDates(taskCtx, time.Now(), s.reportDueWindow())
+	if err != nil {
+		log.Error(taskCtx, "Failed to backfill report due dates", zap.Error(err))
+		return 0
+	}
+	if updated > 0 {
+		log.Info(taskCtx, "Report due dates backfilled", zap.Int64("reports", updated))
+	}
+	return updated
+}
+
+// SendReportDueReminders напоминает гостям о приближении срока сдачи черновиков и возвращает число напоминаний.
+// По каждому порогу REPORT_REMINDER_HOURS_BEFORE_DUE отправляется не больше одного напоминания; пропущенные
+// пороги(отчет создан ближе к сроку или задача не работала) закрываются одним напоминанием
+func (s *SecretGuestService) SendReportDueReminders(ctx context.Context) int {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	thresholds := s.reportReminderThresholds()
+	if len(thresholds) == 0 {
+		return 0
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	now := time.Now()
+	reports, err := s.repo.GetReportsDueSoon(taskCtx, now, now.Add(thresholds[0]), len(thresholds), dueReportsBatch)
+	if err != nil {
+		log.Error(taskCtx, "Failed to get reports due soon", zap.Error(err))
+		return 0
+	}
+
+	reminded := 0
+	for _, report := range reports {
+		left := report.DueAt.Sub(now)
+		passed := 0
+		for _, t := range thresholds {
+			if left <= t {
+				passed++
+			}
+		}
+		if passed <= report.RemindersSent {
+			continue
+		}
+
+		notification, err := newReportDueSoonNotification(report)
+		if err != nil {
+			log.Error(taskCtx, "Failed to build report due soon notification", zap.Error(err))
+			continue
+		}
+
+		err = s.repo.MarkReportReminded(taskCtx, report.ReportID, passed, notification)
+		if errors.Is(err, models.ErrReportNotFound) {
+			// гость успел сдать отчет или отказаться
+			continue
+		}
+		if err != nil {
+			log.Error(taskCtx, "Failed to mark report reminded", zap.Error(err), zap.String("report_id", report.ReportID.String()))
+			continue
+		}
+
+		reminded++
+		log.Info(taskCtx, "Report due reminder sent",
+			zap.String("report_id", report.ReportID.String()),
+			zap.String("reporter_id", report.ReporterID.String()),
+			zap.Time("due_at", report.DueAt),
+		)
+	}
+
+	return reminded
+}
+
+// MarkOverdueReports переводит черновики, не сданные к сроку и в окне поздней сдачи(grace_hours правила report_late),
+// в просроченные и возвращает их число. Начисление по отчету аннулируется, гостю записывается штраф report_overdue
+func (s *SecretGuestService) MarkOverdueReports(ctx context.Context) int {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	taskCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	reports, err := s.repo.GetOverdueReports(taskCtx, time.Now(), dueReportsBatch)
+	if err != nil {
+		log.Error(taskCtx, "Failed to get overdue reports", zap.Error(err))
+		return 0
+	}
+
+	overdue := 0
+	for _, report := range reports {
+		notification, err := newReportOverdueNotification(report)
+		if err != nil {
+			log.Error(taskCtx, "Failed to build report overdue notification", zap.Error(err))
+			continue
+		}
+
+		err = s.repo.MarkReportOverdue(taskCtx, report.ReportID, time.Now(), notification)
+		if errors.Is(err, models.ErrReportNotFound) {
+			// гость успел сдать отчет или отказаться
+			continue
+		}
+		if err != nil {
+			log.Error(taskCtx, "Failed to mark report overdue", zap.Error(err), zap.String("report_id", report.ReportID.String()))
+			continue
+		}
+
+		overdue++
+		log.Info(taskCtx, "Report is overdue",
+			zap.String("report_id", report.ReportID.String()),
+			zap.String("reporter_id", report.ReporterID.String()),
+			zap.Time("due_at", report.DueAt),
+		)
+	}
+
+	return overdue
+}
+
+func newReportDueSoonNotification(report *models.DueReport) (*models.Notification, error) {
+	payload, err := json.Marshal(map[string]any{
+		"report_id":     report.ReportID,
+		"assignment_id": report.AssignmentID,
+		"due_at":        report.DueAt,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-func (s *SecretGuestService) GenerateUploadURL(ctx context.Context, userID uuid.UUID, dto GenerateUploadURLRequest) (*GenerateUploadURLResponse, error) {
+	return &models.Notification{
+		UserID: report.ReporterID,
+		Type:   models.NotificationReportDueSoon,
+		Title:  "Скоро срок сдачи отчета",
+		Body: fmt.Sprintf("Отчет по проверке «%s» (%s) нужно сдать до %s. За сдачу после срока и за просрочку списываются очки",
+			report.ListingTitle, report.City, report.DueAt.Format("02.01.2006 15:04")),
+		Payload: payload,
+	}, nil
+}
+
+func newReportOverdueNotification(report *models.DueReport) (*models.Notification, error) {
+	payload, err := json.Marshal(map[string]any{
+		"report_id":     report.ReportID,
+		"assignment_id": report.AssignmentID,
+		"due_at":        report.DueAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Notification{
+		UserID: report.ReporterID,
+		Type:   models.NotificationReportOverdue,
+		Title:  "Отчет просрочен",
+		Body: fmt.Sprintf("Срок сдачи отчета по проверке «%s» (%s) истек %s. Отчет больше нельзя сдать, вознаграждение аннулировано и списаны очки за просрочку",
+			report.ListingTitle, report.City, report.DueAt.Format("02.01.2006 15:04")),
+		Payload: payload,
+	}, nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Отказы от предложений
+
+// declineReasonIDs - причины отказа из DeclineAssignmentRequestDTO.Reason
+var declineReasonIDs = map[string]int{
+	"dates":        models.DeclineReasonDates,
+	"location":     models.DeclineReasonLocation,
+	"listing_type": models.DeclineReasonListingType,
+	"other":        models.DeclineReasonOther,
+}
+
+func (s *SecretGuestService) GetDeclineAnalytics(ctx context.Context, dto GetDeclineAnalyticsRequestDTO) (*DeclineAnalyticsResponse, error) {
+	if dto.GroupBy == "" {
+		dto.GroupBy = models.DeclineGroupByReason
+	}
+	switch dto.GroupBy {
+	case models.DeclineGroupByReason, models.DeclineGroupByCity, models.DeclineGroupByListing:
+	default:
+		return nil, fmt.Errorf("%w: group_by must be reason, city or listing", models.ErrValidationFailed)
+	}
+
+	filter := repository.DeclineAnalyticsFilter{
+		GroupBy: dto.GroupBy,
+		From:    dto.From,
+		To:      dto.To,
+		Limit:   dto.Limit,
+		Offset:  (dto.Page - 1) * dto.Limit,
+	}
+
+	stats, total, err := s.repo.GetDeclineAnalytics(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decline analytics from repository: %w", err)
+	}
+
+	groups := make([]*DeclineStatDTO, 0, len(stats))
+	for _, st := range stats {
+		groups = append(groups, &DeclineStatDTO{
+			Key:         st.Key,
+			Label:       st.Label,
+			Declines:    st.Declines,
+			Assignments: st.Assignments,
+			Guests:      st.Guests,
+			ByReason:    st.ByReason,
+		})
+	}
+
+	return &DeclineAnalyticsResponse{
+		GroupBy: dto.GroupBy,
+		Groups:  groups,
+		Total:   total,
+		Page:    dto.Page,
+	}, nil
+}
+
+// GetRepeatedlyDeclinedAssignments - открытые предложения с повторными отказами: кандидаты на изменение цели или вознаграждения
+func (s *SecretGuestService) GetRepeatedlyDeclinedAssignments(ctx context.Context, dto GetDeclinedAssignmentsRequestDTO) (*DeclinedAssignmentsResponse, error) {
+	if dto.MinDeclines <= 0 {
+		dto.MinDeclines = models.DefaultRepeatedDeclines
+	}
+
+	assignments, total, err := s.repo.GetRepeatedlyDeclinedAssignments(ctx, dto.MinDeclines, dto.Limit, (dto.Page-1)*dto.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repeatedly declined assignments from repository: %w", err)
+	}
+
+	responseDTOs := make([]*DeclinedAssignmentDTO, 0, len(assignments))
+	for _, a := range assignments {
+		responseDTOs = append(responseDTOs, &DeclinedAssignmentDTO{
+			AssignmentID: a.AssignmentID,
+			Listing: DeclinedAssignmentListing{
+				ID:    a.ListingID,
+				Title: a.ListingTitle,
+				City:  a.City,
+			},
+			Purpose: a.Purpose,
+			Dates: AssignmentReservationDates{
+				Checkin:  a.CheckinDate,
+				Checkout: a.CheckoutDate,
+			},
+			ExpiresAt:      a.ExpiresAt,
+			CampaignID:     a.CampaignID,
+			Declines:       a.Declines,
+			LastDeclinedAt: a.LastDeclinedAt,
+			ByReason:       a.ByReason,
+			Comments:       a.Comments,
+		})
+	}
+
+	return &DeclinedAssignmentsResponse{
+		Assignments: responseDTOs,
+		Total:       total,
+		Page:        dto.Page,
+	}, nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Приоритет проверки объектов
 
+func toListingPriorityDTO(p *models.ListingPriority) ListingPriorityDTO {
+	return ListingPriorityDTO{
+		Score:             p.Score,
+		LastInspectedAt:   p.LastInspectedAt,
+		LastQualityScore:  p.LastQualityScore,
+		Complaints:        p.Complaints,
+		ListingTypeWeight: p.ListingTypeWeight,
+	}
+}
+
+// needsAssignment решает, создавать ли предложение по бронированию OTA: приоритет проверки объекта не ниже
+// порога ASSIGNMENT_PRIORITY_THRESHOLD или объект входит в активную кампанию. При ошибке - создавать
+func (s *SecretGuestService) needsAssignment(ctx context.Context, listingID uuid.UUID, checkinDate time.Time) bool {
 	log := logger.GetLoggerFromCtx(ctx)
 
-	// TO DO: Хз, нужно ли нам? Оставил для универсальности
-	params := storage.UploadParams{
-		FileName: dto.FileName,
-		Path:     fmt.Sprintf("users/%s", userID.String()), // Файлы по папкам пользователей
-		UserID:   userID.String(),
+	if s.cfg.AssignmentPriorityThreshold <= 0 {
+		return true
 	}
 
-	uploadData, err := s.storageProvider.GenerateUploadURL(ctx, params)
+	priority, err := s.repo.GetListingPriority(ctx, listingID)
 	if err != nil {
-		log.Error(ctx, "Failed to generate upload URL from storage provider", zap.Error(err))
-		return nil, fmt.Errorf("could not generate upload URL: %w", err)
+		log.Error(ctx, "Failed to get listing priority, assignment will be created", zap.Error(err), zap.String("listing_id", listingID.String()))
+		return true
+	}
+	if priority.Score >= s.cfg.AssignmentPriorityThreshold {
+		return true
 	}
 
-	return toGenerateUploadURLResponseDTO(uploadData), nil
+	_, err = s.repo.FindCampaignForListing(ctx, listingID, checkinDate)
+	switch {
+	case err == nil:
+		return true
+	case !errors.Is(err, models.ErrCampaignNotFound):
+		log.Error(ctx, "Failed to find campaign for listing, assignment will be created", zap.Error(err), zap.String("listing_id", listingID.String()))
+		return true
+	}
+
+	log.Info(ctx, "Listing priority is below threshold, assignment not created",
+		zap.String("listing_id", listingID.String()),
+		zap.Float64("score", priority.Score),
+		zap.Float64("threshold", s.cfg.AssignmentPriorityThreshold),
+	)
+	return false
 }
 
-func toGenerateUploadURLResponseDTO(s *storage.UploadResponse) *GenerateUploadURLResponse {
-	if s == nil {
-		return nil
+func (s *SecretGuestService) GetPrioritizedListings(ctx context.Context, dto GetPrioritizedListingsRequestDTO) (*PrioritizedListingsResponse, error) {
+	filter := repository.PrioritizedListingsFilter{
+		City:           dto.City,
+		ListingTypeIDs: dto.ListingTypeIDs,
+		MinScore:       dto.MinScore,
+		Limit:          dto.Limit,
+		Offset:         (dto.Page - 1) * dto.Limit,
 	}
 
-	return &GenerateUploadURLResponse{
-		UploadURL: s.UploadURL,
-		Method:    s.Method,
-		FormData:  UploadFormDataDTO(s.FormData),
-		Headers:   s.Headers,
+	listings, total, err := s.repo.GetPrioritizedListings(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prioritized listings from repository: %w", err)
+	}
+
+	responseDTOs := make([]*PrioritizedListingDTO, 0, len(listings))
+	for _, l := range listings {
+		responseDTOs = append(responseDTOs, &PrioritizedListingDTO{
+			Listing:  *toListingResponseDTO(l),
+			Priority: toListingPriorityDTO(l.Priority),
+		})
 	}
+
+	return &PrioritizedListingsResponse{
+		Listings: responseDTOs,
+		Total:    total,
+		Page:     dto.Page,
+	}, nil
 }
 
-// Profile
+func (s *SecretGuestService) GetPrioritizedListing(ctx context.Context, listingID uuid.UUID) (*PrioritizedListingDTO, error) {
+	listing, err := s.repo.GetListingByID(ctx, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing by id %s from repository: %w", listingID.String(), err)
+	}
+
+	priority, err := s.repo.GetListingPriority(ctx, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing priority %s from repository: %w", listingID.String(), err)
+	}
 
-func calculateUserPointsAndRank(AcceptedOffersCount, SubmittedReportsCount, CorrectReportsCount int) (int, string) {
+	return &PrioritizedListingDTO{
+		Listing:  *toListingResponseDTO(listing),
+		Priority: toListingPriorityDTO(priority),
+	}, nil
+}
+
+func (s *SecretGuestService) GetListingTypePriorityWeights(ctx context.Context) ([]*ListingTypePriorityWeightDTO, error) {
+	weights, err := s.repo.GetListingTypePriorityWeights(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing type priority weights from repository: %w", err)
+	}
+
+	responseDTOs := make([]*ListingTypePriorityWeightDTO, 0, len(weights))
+	for _, w := range weights {
+		responseDTOs = append(responseDTOs, &ListingTypePriorityWeightDTO{
+			ListingType: ListingTypeResponse{
+				ID:   w.ListingTypeID,
+				Slug: w.ListingTypeSlug,
+				Name: w.ListingTypeName,
+			},
+			Weight: w.Weight,
+		})
+	}
+	return responseDTOs, nil
+}
+
+func (s *SecretGuestService) UpdateListingTypePriorityWeight(ctx context.Context, actorID uuid.UUID, listingTypeID int, dto UpdateListingTypePriorityWeightRequestDTO) (*ListingTypePriorityWeightDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	entry := models.NewAuditLogEntry(actorID, models.AuditActionListingPriorityWeightUpdated, models.AuditEntityListingType, strconv.Itoa(listingTypeID), map[string]any{
+		"weight": *dto.Weight,
+	})
+
+	if err := s.repo.SaveListingTypePriorityWeight(ctx, listingTypeID, *dto.Weight, entry); err != nil {
+		return nil, fmt.Errorf("failed to save listing type priority weight: %w", err)
+	}
 
-	points := 0
-	rank := "Новичок"
+	log.Info(ctx, "Listing type priority weight updated",
+		zap.String("actor_id", actorID.String()),
+		zap.Int("listing_type_id", listingTypeID),
+		zap.Int("weight", *dto.Weight),
+	)
+
+	weights, err := s.GetListingTypePriorityWeights(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range weights {
+		if w.ListingType.ID == listingTypeID {
+			return w, nil
+		}
+	}
+	return nil, models.ErrListingTypeNotFound
+}
 
-	points += AcceptedOffersCount * 10
-	points += SubmittedReportsCount * 5
-	points += CorrectReportsCount * 20
+// CreateListingComplaint сохраняет жалобу на объект от OTA, она повышает приоритет проверки объекта
+func (s *SecretGuestService) CreateListingComplaint(ctx context.Context, actorID uuid.UUID, dto CreateListingComplaintRequestDTO) (*ListingComplaintResponseDTO, error) {
+	log := logger.GetLoggerFromCtx(ctx)
 
-	if points >= 100 {
-		rank = "Опытный"
+	listing, err := s.repo.GetListingByCode(ctx, dto.ListingCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get listing by code %s from repository: %w", dto.ListingCode.String(), err)
 	}
-	if points >= 300 {
-		rank = "Профи"
+
+	complaint := &models.ListingComplaint{
+		ListingID:  listing.ID,
+		ExternalID: dto.ExternalID,
+		Category:   strings.TrimSpace(dto.Category),
+		Comment:    dto.Comment,
+		ReceivedAt: time.Now(),
+		CreatedBy:  &actorID,
 	}
-	if points >= 600 {
-		rank = "Мастер"
+	if dto.ReceivedAt != nil {
+		complaint.ReceivedAt = *dto.ReceivedAt
 	}
-	if points >= 1000 {
-		rank = "Легенда"
+
+	if err := s.repo.CreateListingComplaint(ctx, complaint); err != nil {
+		return nil, fmt.Errorf("failed to create listing complaint in repository: %w", err)
 	}
 
-	return points, rank
+	log.Info(ctx, "Listing complaint received",
+		zap.String("complaint_id", complaint.ID.String()),
+		zap.String("listing_id", listing.ID.String()),
+		zap.String("category", complaint.Category),
+	)
+
+	return &ListingComplaintResponseDTO{
+		ID:         complaint.ID,
+		ListingID:  complaint.ListingID,
+		ExternalID: complaint.ExternalID,
+		Category:   complaint.Category,
+		Comment:    complaint.Comment,
+		ReceivedAt: complaint.ReceivedAt,
+		CreatedAt:  complaint.CreatedAt,
+	}, nil
 }
 
-func toProfileResponseDTO(p *models.UserProfile) *ProfileResponseDTO {
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
 
-	points, rank := calculateUserPointsAndRank(p.AcceptedOffersCount, p.SubmittedReportsCount, p.CorrectReportsCount)
+// Уведомления
 
-	return &ProfileResponseDTO{
-		ID:                    p.ID,
-		UserID:                p.UserID,
-		Username:              p.Username,
-		Email:                 p.Email,
-		AcceptedOffersCount:   p.AcceptedOffersCount,
-		SubmittedReportsCount: p.SubmittedReportsCount,
-		CorrectReportsCount:   p.CorrectReportsCount,
-		RegisteredAt:          p.RegisteredAt,
-		LastActiveAt:          p.LastActiveAt,
-		AdditionalInfo:        p.AdditionalInfo,
+func toNotificationResponseDTO(n *models.Notification) *NotificationResponseDTO {
+	return &NotificationResponseDTO{
+		ID:        n.ID,
+		Type:      n.Type,
+		Title:     n.Title,
+		Body:      n.Body,
+		Payload:   n.Payload,
+		CreatedAt: n.CreatedAt,
+		ReadAt:    n.ReadAt,
+	}
+}
+
+func (s *SecretGuestService) GetMyNotifications(ctx context.Context, dto GetMyNotificationsRequestDTO) (*NotificationsResponse, error) {
+	filter := repository.NotificationsFilter{
+		UserID:     dto.UserID,
+		UnreadOnly: dto.UnreadOnly,
+		Limit:      dto.Limit,
+		Offset:     (dto.Page - 1) * dto.Limit,
+	}
+
+	notifications, total, unread, err := s.repo.GetNotifications(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notifications from repository: %w", err)
+	}
+
+	responseDTOs := make([]*NotificationResponseDTO, 0, len(notifications))
+	for _, n := range notifications {
+		responseDTOs = append(responseDTOs, toNotificationResponseDTO(n))
+	}
 
-		Points: points,
-		Rank:   rank,
+	return &NotificationsResponse{
+		Notifications: responseDTOs,
+		Total:         total,
+		Unread:        unread,
+		Page:          dto.Page,
+	}, nil
+}
+
+func (s *SecretGuestService) MarkMyNotificationRead(ctx context.Context, userID, notificationID uuid.UUID) error {
+	if err := s.repo.MarkNotificationRead(ctx, notificationID, userID); err != nil {
+		return fmt.Errorf("failed to mark notification %s as read: %w", notificationID.String(), err)
 	}
+	return nil
 }
 
-func (s *SecretGuestService) GetMyProfile(ctx context.Context, userID uuid.UUID) (*ProfileResponseDTO, error) {
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
+// Выгрузка персональных данных и удаление учетной записи
+
+// exportPageSize - размер страницы при выборке предложений и отчетов для выгрузки
+const exportPageSize = 100
+
+// ExportMyData собирает ZIP-архив с JSON-файлами: учетная запись, профиль, заявка,
+// предложения, отчеты, ссылки на загруженные в отчеты медиафайлы, начисления и история очков
+func (s *SecretGuestService) ExportMyData(ctx context.Context, userID uuid.UUID) (*PersonalDataExport, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from repository: %w", err)
+	}
+
+	files := map[string]any{
+		"user.json": toUserResponseDTO(user),
+	}
+
 	profile, err := s.repo.GetUserProfileByID(ctx, userID)
+	if err != nil && !errors.Is(err, models.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get user profile from repository: %w", err)
+	}
+	if profile != nil {
+		tiers, err := s.repo.GetRankTiers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rank tiers from repository: %w", err)
+		}
+		if err := s.attachProfileBadges(ctx, profile); err != nil {
+			return nil, err
+		}
+		files["profile.json"] = toProfileResponseDTO(profile, tiers, models.DefaultLanguage)
+	}
+
+	application, err := s.repo.GetGuestApplicationByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, models.ErrApplicationNotFound) {
+		return nil, fmt.Errorf("failed to get guest application from repository: %w", err)
+	}
+	if application != nil {
+		files["application.json"] = toGuestApplicationResponseDTO(application)
+	}
+
+	assignments := make([]*AssignmentResponseDTO, 0)
+	for offset := 0; ; offset += exportPageSize {
+		page, total, err := s.repo.GetAssignments(ctx, repository.AssignmentsFilter{ReporterID: &userID, Limit: exportPageSize, Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get assignments from repository: %w", err)
+		}
+		for _, a := range page {
+			assignments = append(assignments, toAssignmentResponseDTO(a))
+		}
+		if len(page) == 0 || offset+exportPageSize >= total {
+			break
+		}
+	}
+	files["assignments.json"] = assignments
+
+	reports := make([]*ReportResponseDTO, 0)
+	media := make([]ExportedMediaFile, 0)
+	for offset := 0; ; offset += exportPageSize {
+		page, total, err := s.repo.GetReports(ctx, repository.ReportsFilter{ReporterID: &userID, Limit: exportPageSize, Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get reports from repository: %w", err)
+		}
+		for _, r := range page {
+			reports = append(reports, toReportResponseDTO(r))
+			media = append(media, collectReportMedia(r)...)
+		}
+		if len(page) == 0 || offset+exportPageSize >= total {
+			break
+		}
+	}
+	files["reports.json"] = reports
+	files["media.json"] = media
+
+	rewards := make([]*RewardResponseDTO, 0)
+	for offset := 0; ; offset += exportPageSize {
+		page, total, err := s.repo.GetRewards(ctx, repository.RewardsFilter{UserID: &userID, Limit: exportPageSize, Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rewards from repository: %w", err)
+		}
+		for _, rw := range page {
+			rewards = append(rewards, toRewardResponseDTO(rw))
+		}
+		if len(page) == 0 || offset+exportPageSize >= total {
+			break
+		}
+	}
+	files["rewards.json"] = rewards
+
+	pointEvents := make([]*PointEventDTO, 0)
+	for offset := 0; ; offset += exportPageSize {
+		page, total, err := s.repo.GetPointEvents(ctx, repository.PointEventsFilter{UserID: userID, Limit: exportPageSize, Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get point events from repository: %w", err)
+		}
+		for _, e := range page {
+			pointEvents = append(pointEvents, toPointEventDTO(e))
+		}
+		if len(page) == 0 || offset+exportPageSize >= total {
+			break
+		}
+	}
+	files["points.json"] = pointEvents
+
+	archive, err := buildZipArchive(files)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user profile by id %s from repository: %w", userID.String(), err)
+		return nil, err
+	}
+
+	return &PersonalDataExport{
+		FileName: fmt.Sprintf("personal-data-%s-%s.zip", user.Username, time.Now().Format("20060102")),
+		Content:  archive,
+	}, nil
+}
+
+// collectReportMedia достает ссылки на медиафайлы из ответов чек-листа
+func collectReportMedia(r *models.Report) []ExportedMediaFile {
+	if len(r.ChecklistSchema) == 0 {
+		return nil
+	}
+
+	raw, err := json.Marshal(r.ChecklistSchema)
+	if err != nil {
+		return nil
+	}
+	var schema ChecklistSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil
+	}
+
+	var files []ExportedMediaFile
+	for _, section := range schema.Sections {
+		if section == nil {
+			continue
+		}
+		for _, item := range section.Items {
+			if item == nil || item.Answer == nil {
+				continue
+			}
+			for _, m := range item.Answer.Media {
+				if m == nil || m.URL == "" {
+					continue
+				}
+				files = append(files, ExportedMediaFile{
+					ReportID:  r.ID,
+					ItemSlug:  item.Slug,
+					URL:       m.URL,
+					MediaType: m.MediaType,
+				})
+			}
+		}
+	}
+	return files
+}
+
+func buildZipArchive(files map[string]any) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		content, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", name, err)
+		}
+		fw, err := zw.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to archive: %w", name, err)
+		}
+		if _, err := fw.Write(content); err != nil {
+			return nil, fmt.Errorf("failed to write %s to archive: %w", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize archive: %w", err)
 	}
 
-	return toProfileResponseDTO(profile), nil
+	return buf.Bytes(), nil
 }
 
+// DeleteMyAccount удаляет учетную запись гостя по его запросу. Персональные данные обезличиваются,
+// текущие сессии перестают действовать, одобренные отчеты остаются в системе без указания автора.
+func (s *SecretGuestService) DeleteMyAccount(ctx context.Context, userID uuid.UUID, dto DeleteMyAccountRequestDTO) error {
+	log := logger.GetLoggerFromCtx(ctx)
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user from repository: %w", err)
+	}
+
+	if user.IsServiceAccount {
+		return models.ErrServiceAccount
+	}
+	// Учетные записи персонала удаляются только через администратора
+	if user.RoleID != models.GuestRoleID {
+		return models.ErrAccountNotDeletable
+	}
+
+	// "!" - учетная запись без пароля(вход только через OIDC), подтверждением служит сам access-токен
+	if user.PasswordHash != "!" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(dto.Password)); err != nil {
+			log.Info(ctx, "Password mismatch on account deletion", zap.String("user_id", userID.String()))
+			return models.ErrInvalidCredentials
+		}
+	}
+
+	anonymizedUsername := "deleted_" + strings.ReplaceAll(userID.String(), "-", "")[:12]
+
+	// Взятые гостем предложения освобождаются и предлагаются следующим гостям из листов ожидания
+	taken, _, err := s.repo.GetAssignments(ctx, repository.AssignmentsFilter{
+		ReporterID: &userID,
+		StatusIDs:  []int{models.AssignmentStatusOffered},
+		Limit:      models.MaxBulkAssignments,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get taken assignments: %w", err)
+	}
+	now := time.Now()
+	offers := make(map[uuid.UUID]*models.WaitlistOffer, len(taken))
+	for _, a := range taken {
+		offer, err := s.newWaitlistOffer(a.ID, a.Listing.Title, a.Listing.City, a.CheckinDate, a.ExpiresAt, now)
+		if err != nil {
+			return err
+		}
+		offers[a.ID] = offer
+	}
+
+	entry := models.NewAuditLogEntry(userID, models.AuditActionUserDeleted, models.AuditEntityUser, userID.String(), nil)
+
+	if err := s.repo.DeleteUserAccount(ctx, userID, anonymizedUsername, offers, entry); err != nil {
+		return fmt.Errorf("failed to delete user account: %w", err)
+	}
+
+	log.Info(ctx, "User account deleted by owner", zap.String("user_id", userID.String()))
+	return nil
+}
+
+// - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+
 func (s *SecretGuestService) GetAllProfiles(ctx context.Context, dto GetAllProfilesRequestDTO) (*ProfilesResponse, error) {
 	offset := (dto.Page - 1) * dto.Limit
 
@@ -1520,9 +4314,18 @@ func (s *SecretGuestService) GetAllProfiles(ctx context.Context, dto GetAllProfi
 		return nil, fmt.Errorf("failed to get all user profiles from repository: %w", err)
 	}
 
+	tiers, err := s.repo.GetRankTiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rank tiers from repository: %w", err)
+	}
+
+	if err := s.attachProfileBadges(ctx, dbProfiles...); err != nil {
+		return nil, err
+	}
+
 	responseDTOs := make([]*ProfileResponseDTO, 0, len(dbProfiles))
 	for _, p := range dbProfiles {
-		responseDTOs = append(responseDTOs, toProfileResponseDTO(p))
+		responseDTOs = append(responseDTOs, toProfileResponseDTO(p, tiers, dto.Lang))
 	}
 
 	response := &ProfilesResponse{
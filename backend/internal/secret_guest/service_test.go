@@ -0,0 +1,1884 @@
+package secret_guest
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/config"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/mocks"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/secret_guest/repository"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var _ SecretGuestRepository = (*mocks.SecretGuestRepository)(nil)
+
+// newTestService создает сервис с моком репозитория для тестов
+func newTestService(repo *mocks.SecretGuestRepository, cfg *config.Config) *SecretGuestService {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	return NewSecretGuestService(cfg, repo, nil)
+}
+
+func TestValidateBadgeRule(t *testing.T) {
+	leaf := func(metric, op string, value int) models.BadgeRule {
+		return models.BadgeRule{Metric: metric, Op: op, Value: value}
+	}
+
+	cases := []struct {
+		name    string
+		rule    models.BadgeRule
+		wantErr bool
+	}{
+		{"simple rule", leaf(models.BadgeMetricCities, ">=", 10), false},
+		{"quality score rule", leaf(models.BadgeMetricMaxQualityScore, ">=", 90), false},
+		{"all operators", models.BadgeRule{All: []models.BadgeRule{
+			leaf(models.BadgeMetricPoints, ">", 1),
+			leaf(models.BadgeMetricPoints, "=", 1),
+			leaf(models.BadgeMetricPoints, "<=", 1),
+			leaf(models.BadgeMetricPoints, "<", 1),
+		}}, false},
+		{"nested groups", models.BadgeRule{Any: []models.BadgeRule{
+			{All: []models.BadgeRule{leaf(models.BadgeMetricApprovedReports, ">=", 5), leaf(models.BadgeMetricRejectedReports, "=", 0)}},
+			leaf(models.BadgeMetricApprovedStreak, ">=", 10),
+		}}, false},
+		{"unknown metric", leaf("stars", ">=", 5), true},
+		{"empty metric", models.BadgeRule{Op: ">=", Value: 1}, true},
+		{"unknown op", leaf(models.BadgeMetricCities, "!=", 1), true},
+		{"group with both all and any", models.BadgeRule{
+			All: []models.BadgeRule{leaf(models.BadgeMetricCities, ">=", 1)},
+			Any: []models.BadgeRule{leaf(models.BadgeMetricPoints, ">=", 1)},
+		}, true},
+		{"group with metric", models.BadgeRule{
+			Metric: models.BadgeMetricCities,
+			All:    []models.BadgeRule{leaf(models.BadgeMetricCities, ">=", 1)},
+		}, true},
+		{"invalid child", models.BadgeRule{All: []models.BadgeRule{leaf("stars", ">=", 1)}}, true},
+		{"too deep", models.BadgeRule{All: []models.BadgeRule{{All: []models.BadgeRule{{All: []models.BadgeRule{{All: []models.BadgeRule{
+			leaf(models.BadgeMetricCities, ">=", 1),
+		}}}}}}}}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateBadgeRule(tc.rule, 0)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, models.ErrInvalidBadgeRule)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestEvaluateBadgeRule(t *testing.T) {
+	metrics := map[string]int{
+		models.BadgeMetricApprovedReports:       5,
+		models.BadgeMetricRejectedReports:       0,
+		models.BadgeMetricCities:                3,
+		models.BadgeMetricOnTimeApprovedReports: 1,
+		models.BadgeMetricMaxQualityScore:       85,
+	}
+	leaf := func(metric, op string, value int) models.BadgeRule {
+		return models.BadgeRule{Metric: metric, Op: op, Value: value}
+	}
+
+	cases := []struct {
+		name string
+		rule models.BadgeRule
+		want bool
+	}{
+		{">= reached", leaf(models.BadgeMetricApprovedReports, ">=", 5), true},
+		{">= not reached", leaf(models.BadgeMetricCities, ">=", 10), false},
+		{"> on boundary", leaf(models.BadgeMetricApprovedReports, ">", 5), false},
+		{"= matches", leaf(models.BadgeMetricRejectedReports, "=", 0), true},
+		{"<= on boundary", leaf(models.BadgeMetricCities, "<=", 3), true},
+		{"< on boundary", leaf(models.BadgeMetricCities, "<", 3), false},
+		{"missing metric counts as zero", leaf(models.BadgeMetricPoints, "=", 0), true},
+		{"five-star needs quality score", leaf(models.BadgeMetricMaxQualityScore, ">=", 90), false},
+		{"on time report", leaf(models.BadgeMetricOnTimeApprovedReports, ">=", 1), true},
+		{"all satisfied", models.BadgeRule{All: []models.BadgeRule{
+			leaf(models.BadgeMetricApprovedReports, ">=", 5),
+			leaf(models.BadgeMetricRejectedReports, "=", 0),
+		}}, true},
+		{"all with one failing", models.BadgeRule{All: []models.BadgeRule{
+			leaf(models.BadgeMetricApprovedReports, ">=", 5),
+			leaf(models.BadgeMetricCities, ">=", 10),
+		}}, false},
+		{"any with one matching", models.BadgeRule{Any: []models.BadgeRule{
+			leaf(models.BadgeMetricCities, ">=", 10),
+			leaf(models.BadgeMetricMaxQualityScore, ">=", 80),
+		}}, true},
+		{"any with none matching", models.BadgeRule{Any: []models.BadgeRule{
+			leaf(models.BadgeMetricCities, ">=", 10),
+			leaf(models.BadgeMetricMaxQualityScore, ">=", 90),
+		}}, false},
+		{"unknown op", leaf(models.BadgeMetricCities, "!=", 1), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, evaluateBadgeRule(tc.rule, metrics))
+		})
+	}
+}
+
+func TestDistanceMeters(t *testing.T) {
+	cases := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", 55.7558, 37.6173, 55.7558, 37.6173, 0},
+		{"one degree of longitude on equator", 0, 0, 0, 1, 111195},
+		{"one degree of latitude", 10, 20, 11, 20, 111195},
+		{"across antimeridian", 0, 179.5, 0, -179.5, 111195},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := distanceMeters(tc.lat1, tc.lon1, tc.lat2, tc.lon2)
+			assert.InDelta(t, tc.want, math.Round(got), 1)
+		})
+	}
+}
+
+func TestNewStayCheck(t *testing.T) {
+	s := &SecretGuestService{cfg: &config.Config{StayCheckRadiusMeters: 500}}
+	vladivostok := time.FixedZone("UTC+10", 10*60*60)
+	now := time.Now().In(vladivostok)
+	// даты брони хранятся как местное время объекта без зоны и читаются как UTC
+	floating := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	}
+	listing := models.ListingShortInfo{Latitude: 43.1155, Longitude: 131.8855}
+	request := func(at time.Time, lat, lon float64) StayCheckRequestDTO {
+		return StayCheckRequestDTO{Latitude: &lat, Longitude: &lon, Timestamp: at}
+	}
+
+	cases := []struct {
+		name     string
+		listing  models.ListingShortInfo
+		request  StayCheckRequestDTO
+		from, to time.Time
+		wantErr  error
+	}{
+		{
+			name:    "inside window at listing",
+			listing: listing,
+			request: request(now, 43.1160, 131.8860),
+			from:    floating(now.Add(-time.Hour)),
+			to:      floating(now.Add(time.Hour)),
+		},
+		{
+			// в UTC момент устройства на 10 часов раньше окна, но в местном времени объекта он внутри
+			name:    "window is listing local time",
+			listing: listing,
+			request: request(now, 43.1160, 131.8860),
+			from:    floating(now.Add(-5 * time.Minute)),
+			to:      floating(now.Add(5 * time.Minute)),
+		},
+		{
+			name:    "before window",
+			listing: listing,
+			request: request(now, 43.1160, 131.8860),
+			from:    floating(now.Add(time.Hour)),
+			to:      floating(now.Add(2 * time.Hour)),
+			wantErr: models.ErrStayCheckOutOfWindow,
+		},
+		{
+			name:    "after window",
+			listing: listing,
+			request: request(now, 43.1160, 131.8860),
+			from:    floating(now.Add(-2 * time.Hour)),
+			to:      floating(now.Add(-time.Hour)),
+			wantErr: models.ErrStayCheckOutOfWindow,
+		},
+		{
+			name:    "too far from listing",
+			listing: listing,
+			request: request(now, 43.1255, 131.8855),
+			from:    floating(now.Add(-time.Hour)),
+			to:      floating(now.Add(time.Hour)),
+			wantErr: models.ErrStayCheckTooFar,
+		},
+		{
+			name:    "offset does not match listing longitude",
+			listing: listing,
+			request: request(now.UTC(), 43.1160, 131.8860),
+			from:    floating(now.Add(-time.Hour)),
+			to:      floating(now.Add(time.Hour)),
+			wantErr: models.ErrValidationFailed,
+		},
+		{
+			name:    "listing without coordinates",
+			request: request(now, 0, 0),
+			from:    floating(now.Add(-time.Hour)),
+			to:      floating(now.Add(time.Hour)),
+		},
+		{
+			name:    "device clock skew",
+			listing: listing,
+			request: request(now.Add(-time.Hour), 43.1160, 131.8860),
+			from:    floating(now.Add(-2 * time.Hour)),
+			to:      floating(now.Add(time.Hour)),
+			wantErr: models.ErrValidationFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assignment := &models.Assignment{Listing: tc.listing}
+			check, err := s.newStayCheck(assignment, tc.request, tc.from, tc.to)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.request.Timestamp, check.At)
+			if tc.listing.Latitude == 0 && tc.listing.Longitude == 0 {
+				assert.Nil(t, check.DistanceMeters)
+			} else {
+				assert.NotNil(t, check.DistanceMeters)
+			}
+		})
+	}
+}
+
+func TestEscapeICSText(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain text", "Проживание: Отель", "Проживание: Отель"},
+		{"semicolon", "a;b", `a\;b`},
+		{"comma", "Москва, Россия", `Москва\, Россия`},
+		{"backslash", `C:\temp`, `C:\\temp`},
+		{"newline", "Номер брони: 1\nСрок", `Номер брони: 1\nСрок`},
+		{"crlf", "a\r\nb", `a\nb`},
+		{"backslash before escaped chars", `\;,`, `\\\;\,`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, escapeICSText(tc.text))
+		})
+	}
+}
+
+func TestWriteICSLine(t *testing.T) {
+	cases := []struct {
+		name      string
+		line      string
+		wantLines int
+	}{
+		{"short line", "BEGIN:VEVENT", 1},
+		{"exactly 75 octets", "SUMMARY:" + strings.Repeat("a", 67), 1},
+		{"76 octets", "SUMMARY:" + strings.Repeat("a", 68), 2},
+		// "SUMMARY:" - 8 байт, кириллица - по 2 байта: 75-й байт приходится на середину символа
+		{"cyrillic across fold boundary", "SUMMARY:" + strings.Repeat("ж", 40), 2},
+		{"cyrillic with odd prefix", "SUMMARY:a" + strings.Repeat("ж", 40), 2},
+		{"several continuation lines", "DESCRIPTION:" + escapeICSText(strings.Repeat("Номер брони: 1, срок; ", 10)), 6},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var b strings.Builder
+			writeICSLine(&b, tc.line)
+			out := b.String()
+
+			assert.True(t, strings.HasSuffix(out, "\r\n"))
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			assert.Len(t, lines, tc.wantLines)
+			for i, line := range lines {
+				assert.LessOrEqual(t, len(line), 75, "line %d", i)
+				assert.True(t, utf8.ValidString(line), "line %d is not valid UTF-8", i)
+				if i > 0 {
+					assert.True(t, strings.HasPrefix(line, " "), "continuation line %d must start with a space", i)
+				}
+			}
+
+			// разворачивание(удаление CRLF и пробела) возвращает исходную строку
+			assert.Equal(t, tc.line+"\r\n", strings.ReplaceAll(out, "\r\n ", ""))
+		})
+	}
+}
+
+func TestReportReminderThresholds(t *testing.T) {
+	s := newTestService(new(mocks.SecretGuestRepository), &config.Config{ReportReminderHoursBeforeDue: []int{2, 24, 0, 24, -1}})
+
+	assert.Equal(t, []time.Duration{24 * time.Hour, 2 * time.Hour}, s.reportReminderThresholds())
+}
+
+func TestSendReportDueReminders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("one reminder per passed threshold", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, &config.Config{ReportReminderHoursBeforeDue: []int{24, 2}})
+
+		now := time.Now()
+		firstReminder := &models.DueReport{ReportID: uuid.New(), ReporterID: uuid.New(), DueAt: now.Add(20 * time.Hour)}
+		secondReminder := &models.DueReport{ReportID: uuid.New(), ReporterID: uuid.New(), DueAt: now.Add(time.Hour), RemindersSent: 1}
+		alreadyReminded := &models.DueReport{ReportID: uuid.New(), ReporterID: uuid.New(), DueAt: now.Add(20 * time.Hour), RemindersSent: 1}
+		// оба порога пропущены - одно напоминание закрывает оба, но гость успел сдать отчет
+		submitted := &models.DueReport{ReportID: uuid.New(), ReporterID: uuid.New(), DueAt: now.Add(time.Hour)}
+
+		var window time.Duration
+		mockRepo.On("GetReportsDueSoon", mock.Anything, mock.Anything, mock.Anything, 2, dueReportsBatch).
+			Run(func(args mock.Arguments) {
+				window = args.Get(2).(time.Time).Sub(args.Get(1).(time.Time))
+			}).
+			Return([]*models.DueReport{firstReminder, secondReminder, alreadyReminded, submitted}, nil)
+		isDueSoon := func(report *models.DueReport) interface{} {
+			return mock.MatchedBy(func(n *models.Notification) bool {
+				return n.UserID == report.ReporterID && n.Type == models.NotificationReportDueSoon
+			})
+		}
+		mockRepo.On("MarkReportReminded", mock.Anything, firstReminder.ReportID, 1, isDueSoon(firstReminder)).Return(nil)
+		mockRepo.On("MarkReportReminded", mock.Anything, secondReminder.ReportID, 2, isDueSoon(secondReminder)).Return(nil)
+		mockRepo.On("MarkReportReminded", mock.Anything, submitted.ReportID, 2, isDueSoon(submitted)).Return(models.ErrReportNotFound)
+
+		// Act
+		reminded := s.SendReportDueReminders(ctx)
+
+		// Assert
+		assert.Equal(t, 2, reminded)
+		assert.Equal(t, 24*time.Hour, window)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "MarkReportReminded", mock.Anything, alreadyReminded.ReportID, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reminders disabled", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, &config.Config{ReportReminderHoursBeforeDue: []int{0}})
+
+		assert.Equal(t, 0, s.SendReportDueReminders(ctx))
+		mockRepo.AssertNotCalled(t, "GetReportsDueSoon", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, &config.Config{ReportReminderHoursBeforeDue: []int{24}})
+		mockRepo.On("GetReportsDueSoon", mock.Anything, mock.Anything, mock.Anything, 1, dueReportsBatch).Return(nil, errors.New("db down"))
+
+		assert.Equal(t, 0, s.SendReportDueReminders(ctx))
+	})
+}
+
+func TestMarkOverdueReports(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange
+	mockRepo := new(mocks.SecretGuestRepository)
+	s := newTestService(mockRepo, &config.Config{})
+
+	overdue := &models.DueReport{ReportID: uuid.New(), ReporterID: uuid.New(), DueAt: time.Now().Add(-48 * time.Hour)}
+	submitted := &models.DueReport{ReportID: uuid.New(), ReporterID: uuid.New(), DueAt: time.Now().Add(-48 * time.Hour)}
+	failed := &models.DueReport{ReportID: uuid.New(), ReporterID: uuid.New(), DueAt: time.Now().Add(-48 * time.Hour)}
+
+	mockRepo.On("GetOverdueReports", mock.Anything, mock.Anything, dueReportsBatch).Return([]*models.DueReport{overdue, submitted, failed}, nil)
+	mockRepo.On("MarkReportOverdue", mock.Anything, overdue.ReportID, mock.Anything, mock.MatchedBy(func(n *models.Notification) bool {
+		return n.UserID == overdue.ReporterID && n.Type == models.NotificationReportOverdue
+	})).Return(nil)
+	mockRepo.On("MarkReportOverdue", mock.Anything, submitted.ReportID, mock.Anything, mock.Anything).Return(models.ErrReportNotFound)
+	mockRepo.On("MarkReportOverdue", mock.Anything, failed.ReportID, mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	// Act
+	marked := s.MarkOverdueReports(ctx)
+
+	// Assert
+	assert.Equal(t, 1, marked)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBackfillReportDueDates(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("uses the same window as new reports", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, &config.Config{ReportDeadlineHoursAfterCheckout: 36})
+		mockRepo.On("BackfillReportDueDates", mock.Anything, mock.Anything, s.reportDueWindow()).Return(int64(3), nil)
+
+		assert.Equal(t, int64(3), s.BackfillReportDueDates(ctx))
+		assert.Equal(t, 36*time.Hour, s.reportDueWindow())
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, &config.Config{ReportDeadlineHoursAfterCheckout: 48})
+		mockRepo.On("BackfillReportDueDates", mock.Anything, mock.Anything, 48*time.Hour).Return(int64(0), errors.New("db down"))
+
+		assert.Equal(t, int64(0), s.BackfillReportDueDates(ctx))
+	})
+}
+
+func TestCalculateReward(t *testing.T) {
+	maxAmount := func(v int) *int { return &v }
+
+	cases := []struct {
+		name         string
+		pricing      string
+		policy       models.RewardPolicy
+		wantAmount   int
+		wantCurrency string
+		wantErr      bool
+	}{
+		{"percent of total", `{"currency": "RUB", "total": 10000}`, models.RewardPolicy{Percent: 30}, 3000, "RUB", false},
+		{"free stay", `{"currency": "USD", "total": 250}`, models.RewardPolicy{Percent: 100}, 250, "USD", false},
+		{"zero percent", `{"currency": "RUB", "total": 10000}`, models.RewardPolicy{Percent: 0}, 0, "RUB", false},
+		{"rounded down", `{"currency": "RUB", "total": 999}`, models.RewardPolicy{Percent: 15}, 149, "RUB", false},
+		{"capped by max amount", `{"currency": "RUB", "total": 50000}`, models.RewardPolicy{Percent: 50, MaxAmount: maxAmount(5000)}, 5000, "RUB", false},
+		{"below max amount", `{"currency": "RUB", "total": 8000}`, models.RewardPolicy{Percent: 50, MaxAmount: maxAmount(5000)}, 4000, "RUB", false},
+		{"zero total", `{"currency": "RUB", "total": 0}`, models.RewardPolicy{Percent: 50}, 0, "RUB", false},
+		{"missing total", `{"currency": "RUB"}`, models.RewardPolicy{Percent: 50}, 0, "", true},
+		{"null total", `{"currency": "RUB", "total": null}`, models.RewardPolicy{Percent: 50}, 0, "", true},
+		{"missing currency", `{"total": 1000}`, models.RewardPolicy{Percent: 50}, 0, "", true},
+		{"negative total", `{"currency": "RUB", "total": -1}`, models.RewardPolicy{Percent: 50}, 0, "", true},
+		{"invalid json", `{"currency": `, models.RewardPolicy{Percent: 50}, 0, "", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			amount, currency, err := calculateReward(json.RawMessage(tc.pricing), &tc.policy)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantAmount, amount)
+			assert.Equal(t, tc.wantCurrency, currency)
+		})
+	}
+}
+
+func TestNewAssignmentReward(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	newAssignment := func(pricing string) *models.Assignment {
+		return &models.Assignment{
+			ID:        uuid.New(),
+			ListingID: uuid.New(),
+			Pricing:   json.RawMessage(pricing),
+			Listing:   models.ListingShortInfo{ListingTypeID: 2},
+		}
+	}
+
+	t.Run("reward by listing type policy", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		assignment := newAssignment(`{"currency": "RUB", "total": 12000}`)
+		mockRepo.On("GetRewardPolicy", ctx, 2).Return(&models.RewardPolicy{ListingTypeID: 2, Percent: 25}, nil)
+
+		// Act
+		reward := s.newAssignmentReward(ctx, assignment, userID)
+
+		// Assert
+		if assert.NotNil(t, reward) {
+			assert.Equal(t, assignment.ID, reward.AssignmentID)
+			assert.Equal(t, &userID, reward.UserID)
+			assert.Equal(t, assignment.ListingID, reward.ListingID)
+			assert.Equal(t, 2, reward.ListingTypeID)
+			assert.Equal(t, 3000, reward.Amount)
+			assert.Equal(t, "RUB", reward.Currency)
+			assert.Equal(t, 25, reward.Percent)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("manual assignment without pricing", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+
+		assert.Nil(t, s.newAssignmentReward(ctx, newAssignment(""), userID))
+		mockRepo.AssertNotCalled(t, "GetRewardPolicy", mock.Anything, mock.Anything)
+	})
+
+	t.Run("pricing without total", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		mockRepo.On("GetRewardPolicy", ctx, 2).Return(&models.RewardPolicy{ListingTypeID: 2, Percent: 25}, nil)
+
+		assert.Nil(t, s.newAssignmentReward(ctx, newAssignment(`{"currency": "RUB"}`), userID))
+	})
+
+	t.Run("policy error", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		mockRepo.On("GetRewardPolicy", ctx, 2).Return(nil, errors.New("db down"))
+
+		assert.Nil(t, s.newAssignmentReward(ctx, newAssignment(`{"currency": "RUB", "total": 12000}`), userID))
+	})
+}
+
+func TestReviewReportSettlesReward(t *testing.T) {
+	ctx := context.Background()
+	staffID, reportID, reporterID := uuid.New(), uuid.New(), uuid.New()
+	score := 95
+
+	cases := []struct {
+		name         string
+		review       func(s *SecretGuestService) error
+		wantStatusID int
+		wantScore    *int
+	}{
+		{"approve", func(s *SecretGuestService) error { return s.ApproveReport(ctx, staffID, reportID, &score) }, models.ReportStatusApproved, &score},
+		{"reject", func(s *SecretGuestService) error { return s.RejectReport(ctx, staffID, reportID) }, models.ReportStatusRejected, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange: начисление переводится репозиторием в той же транзакции, что и решение по отчету
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			mockRepo.On("ReviewReport", ctx, reportID, tc.wantStatusID, staffID, tc.wantScore).Return(&reporterID, nil)
+			mockRepo.On("GetBadges", ctx, true).Return([]*models.Badge{}, nil)
+			mockRepo.On("GetBadgeMetrics", ctx, reporterID).Return(map[string]int{}, nil)
+
+			// Act
+			err := tc.review(s)
+
+			// Assert
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("report already reviewed", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		mockRepo.On("ReviewReport", ctx, reportID, models.ReportStatusRejected, staffID, (*int)(nil)).Return(nil, models.ErrReportCannotBeRejected)
+
+		assert.ErrorIs(t, s.RejectReport(ctx, staffID, reportID), models.ErrReportCannotBeRejected)
+		mockRepo.AssertNotCalled(t, "GetBadges", mock.Anything, mock.Anything)
+	})
+}
+
+func TestExtendAssignment(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{AssignmentDeadlineHours: 24, AssignmentHoldMinutes: 120}
+	actorID, reporterID := uuid.New(), uuid.New()
+	now := time.Now()
+	checkout := now.AddDate(0, 0, 20).Truncate(time.Hour)
+	current := now.AddDate(0, 0, 5).Truncate(time.Hour)
+
+	t.Run("bounds", func(t *testing.T) {
+		cases := []struct {
+			name      string
+			current   time.Time
+			expiresAt time.Time
+		}{
+			{"same as current", current, current},
+			{"earlier than current", current, current.Add(-time.Hour)},
+			{"expired and still in the past", now.AddDate(0, 0, -3), now.AddDate(0, 0, -1)},
+			{"at checkout", current, checkout},
+			{"after checkout", current, checkout.Add(time.Hour)},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				// Arrange
+				mockRepo := new(mocks.SecretGuestRepository)
+				s := newTestService(mockRepo, cfg)
+				assignment := &models.Assignment{ID: uuid.New(), StatusID: models.AssignmentStatusOffered, ExpiresAt: tc.current, CheckoutDate: checkout}
+				mockRepo.On("GetAssignmentByID", ctx, assignment.ID).Return(assignment, nil)
+
+				// Act
+				err := s.extendAssignment(ctx, actorID, assignment.ID, tc.expiresAt, nil)
+
+				// Assert
+				assert.ErrorIs(t, err, models.ErrInvalidAssignmentDates)
+				mockRepo.AssertNotCalled(t, "ExtendAssignment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("not offered", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, cfg)
+		assignment := &models.Assignment{ID: uuid.New(), StatusID: models.AssignmentStatusAccepted, ExpiresAt: current, CheckoutDate: checkout}
+		mockRepo.On("GetAssignmentByID", ctx, assignment.ID).Return(assignment, nil)
+
+		assert.ErrorIs(t, s.extendAssignment(ctx, actorID, assignment.ID, current.Add(time.Hour), nil), models.ErrAssignmentCannotBeExtended)
+	})
+
+	// Срок принятия пересчитывается от нового expires_at в том же обновлении
+	newExpiresAt := current.AddDate(0, 0, 3)
+	takenAt := now.Add(-time.Hour)
+	invitedAt := now.AddDate(0, 0, -1)
+	cases := []struct {
+		name         string
+		reporterID   uuid.UUID
+		takedAt      *time.Time
+		invitedAt    *time.Time
+		wantReporter *uuid.UUID
+		wantDeadline *time.Time
+	}{
+		{"free assignment", uuid.Nil, nil, nil, nil, nil},
+		{"staff invite lasts until expires_at", reporterID, &invitedAt, &invitedAt, &reporterID, &newExpiresAt},
+		{"taken assignment gets the hold from the new accept window", reporterID, &takenAt, nil, &reporterID, ptr(newExpiresAt.Add(-22 * time.Hour))},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, cfg)
+			assignment := &models.Assignment{
+				ID:             uuid.New(),
+				StatusID:       models.AssignmentStatusOffered,
+				ReporterID:     tc.reporterID,
+				TakedAt:        tc.takedAt,
+				InvitedAt:      tc.invitedAt,
+				ExpiresAt:      current,
+				AcceptDeadline: &current,
+				CheckoutDate:   checkout,
+			}
+			mockRepo.On("GetAssignmentByID", ctx, assignment.ID).Return(assignment, nil)
+
+			var gotReporter *uuid.UUID
+			var gotDeadline *time.Time
+			var gotEntry *models.AuditLogEntry
+			mockRepo.On("ExtendAssignment", ctx, assignment.ID, mock.Anything, newExpiresAt, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					gotReporter = args.Get(2).(*uuid.UUID)
+					gotDeadline = args.Get(4).(*time.Time)
+					gotEntry = args.Get(5).(*models.AuditLogEntry)
+				}).Return(nil)
+
+			// Act
+			err := s.extendAssignment(ctx, actorID, assignment.ID, newExpiresAt, nil)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantReporter, gotReporter)
+			if tc.wantDeadline == nil {
+				assert.Nil(t, gotDeadline)
+			} else if assert.NotNil(t, gotDeadline) {
+				assert.True(t, tc.wantDeadline.Equal(*gotDeadline), "accept_deadline %s, want %s", gotDeadline, tc.wantDeadline)
+			}
+			if assert.NotNil(t, gotEntry) {
+				assert.Equal(t, models.AuditActionAssignmentExtended, gotEntry.Action)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestResolveRank(t *testing.T) {
+	novice := &models.RankTier{Slug: "novice", MinPoints: 0}
+	experienced := &models.RankTier{Slug: "experienced", MinPoints: 100}
+	expert := &models.RankTier{Slug: "expert", MinPoints: 500}
+	tiers := []*models.RankTier{novice, experienced, expert}
+
+	cases := []struct {
+		name        string
+		tiers       []*models.RankTier
+		points      int
+		wantCurrent *models.RankTier
+		wantNext    *models.RankTier
+	}{
+		{"no points", tiers, 0, novice, experienced},
+		{"below the next threshold", tiers, 99, novice, experienced},
+		{"exactly at the threshold", tiers, 100, experienced, expert},
+		{"top tier has no next", tiers, 900, expert, nil},
+		{"negative points below every tier", tiers, -10, nil, novice},
+		{"no tiers", nil, 50, nil, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			current, next := resolveRank(tc.tiers, tc.points)
+			assert.Equal(t, tc.wantCurrent, current)
+			assert.Equal(t, tc.wantNext, next)
+		})
+	}
+}
+
+func TestUpdatePointRule(t *testing.T) {
+	ctx := context.Background()
+	actorID := uuid.New()
+
+	cases := []struct {
+		name           string
+		eventType      string
+		current        *models.PointRule
+		dto            UpdatePointRuleRequestDTO
+		wantPoints     int
+		wantGraceHours *int
+	}{
+		{
+			name:       "weight of a regular event",
+			eventType:  models.PointEventReportApproved,
+			current:    &models.PointRule{EventType: models.PointEventReportApproved, Points: 10},
+			dto:        UpdatePointRuleRequestDTO{Points: ptr(15)},
+			wantPoints: 15,
+		},
+		{
+			name:           "grace hours of report_late",
+			eventType:      models.PointEventReportLate,
+			current:        &models.PointRule{EventType: models.PointEventReportLate, Points: -5, GraceHours: ptr(24)},
+			dto:            UpdatePointRuleRequestDTO{Points: ptr(-8), GraceHours: ptr(48)},
+			wantPoints:     -8,
+			wantGraceHours: ptr(48),
+		},
+		{
+			name:           "report_late keeps its grace hours when they are not passed",
+			eventType:      models.PointEventReportLate,
+			current:        &models.PointRule{EventType: models.PointEventReportLate, Points: -5, GraceHours: ptr(24)},
+			dto:            UpdatePointRuleRequestDTO{Points: ptr(-3)},
+			wantPoints:     -3,
+			wantGraceHours: ptr(24),
+		},
+		{
+			name:       "grace hours are ignored for other events",
+			eventType:  models.PointEventReportSubmitted,
+			current:    &models.PointRule{EventType: models.PointEventReportSubmitted, Points: 5},
+			dto:        UpdatePointRuleRequestDTO{Points: ptr(5), GraceHours: ptr(48)},
+			wantPoints: 5,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			updated := &models.PointRule{EventType: tc.eventType, Points: tc.wantPoints, GraceHours: tc.wantGraceHours}
+			mockRepo.On("GetPointRule", ctx, tc.eventType).Return(tc.current, nil).Once()
+			mockRepo.On("GetPointRule", ctx, tc.eventType).Return(updated, nil).Once()
+
+			var gotRule *models.PointRule
+			var gotEntry *models.AuditLogEntry
+			mockRepo.On("UpdatePointRule", ctx, mock.Anything, actorID, mock.Anything).Run(func(args mock.Arguments) {
+				gotRule = args.Get(1).(*models.PointRule)
+				gotEntry = args.Get(3).(*models.AuditLogEntry)
+			}).Return(nil)
+
+			// Act
+			response, err := s.UpdatePointRule(ctx, actorID, tc.eventType, tc.dto)
+
+			// Assert
+			assert.NoError(t, err)
+			if assert.NotNil(t, gotRule) {
+				assert.Equal(t, tc.eventType, gotRule.EventType)
+				assert.Equal(t, tc.wantPoints, gotRule.Points)
+				assert.Equal(t, tc.wantGraceHours, gotRule.GraceHours)
+			}
+			if assert.NotNil(t, gotEntry) {
+				var details map[string]any
+				assert.NoError(t, json.Unmarshal(gotEntry.Details, &details))
+				assert.EqualValues(t, tc.current.Points, details["old_points"])
+				assert.EqualValues(t, tc.wantPoints, details["new_points"])
+			}
+			assert.Equal(t, tc.wantPoints, response.Points)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("points are required", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+
+		_, err := s.UpdatePointRule(ctx, actorID, models.PointEventReportApproved, UpdatePointRuleRequestDTO{})
+		assert.ErrorIs(t, err, models.ErrValidationFailed)
+		mockRepo.AssertNotCalled(t, "UpdatePointRule", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRecomputePointsRecordsWeights(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	actorID := uuid.New()
+	mockRepo := new(mocks.SecretGuestRepository)
+	s := newTestService(mockRepo, nil)
+	mockRepo.On("GetPointRules", ctx).Return([]*models.PointRule{
+		{EventType: models.PointEventReportApproved, Points: 12},
+		{EventType: models.PointEventReportLate, Points: -4},
+	}, nil)
+
+	var gotEntry *models.AuditLogEntry
+	mockRepo.On("RecomputePoints", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		gotEntry = args.Get(1).(*models.AuditLogEntry)
+	}).Return(int64(7), nil)
+
+	// Act
+	response, err := s.RecomputePoints(ctx, actorID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), response.UpdatedEvents)
+	if assert.NotNil(t, gotEntry) {
+		assert.Equal(t, models.AuditActionPointsRecomputed, gotEntry.Action)
+		assert.JSONEq(t, `{"weights": {"report_approved": 12, "report_late": -4}}`, string(gotEntry.Details))
+	}
+}
+
+func TestReplaceRankTiers(t *testing.T) {
+	ctx := context.Background()
+	actorID := uuid.New()
+
+	invalid := []struct {
+		name  string
+		tiers []RankTierDTO
+	}{
+		{"no zero threshold", []RankTierDTO{{Slug: "experienced", MinPoints: 100}}},
+		{"duplicate slug", []RankTierDTO{{Slug: "novice", MinPoints: 0}, {Slug: " novice ", MinPoints: 100}}},
+		{"duplicate threshold", []RankTierDTO{{Slug: "novice", MinPoints: 0}, {Slug: "beginner", MinPoints: 0}}},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+
+			_, err := s.ReplaceRankTiers(ctx, actorID, ReplaceRankTiersRequestDTO{Tiers: tc.tiers})
+			assert.ErrorIs(t, err, models.ErrInvalidRankTiers)
+			mockRepo.AssertNotCalled(t, "ReplaceRankTiers", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("tiers are normalized and sorted by threshold", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+
+		var got []*models.RankTier
+		mockRepo.On("ReplaceRankTiers", ctx, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			got = args.Get(1).([]*models.RankTier)
+		}).Return(nil)
+		mockRepo.On("GetRankTiers", ctx).Return([]*models.RankTier{}, nil)
+
+		// Act
+		_, err := s.ReplaceRankTiers(ctx, actorID, ReplaceRankTiersRequestDTO{Tiers: []RankTierDTO{
+			{Slug: " expert ", MinPoints: 500, Names: map[string]string{"RU": " Эксперт "}},
+			{Slug: "novice", MinPoints: 0, Names: map[string]string{"en": "Novice"}},
+		}})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.RankTier{
+			{Slug: "novice", MinPoints: 0, Names: map[string]string{"en": "Novice"}},
+			{Slug: "expert", MinPoints: 500, Names: map[string]string{"ru": "Эксперт"}},
+		}, got)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestGetUserPoints(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	userID := uuid.New()
+	assignmentID := uuid.New()
+	mockRepo := new(mocks.SecretGuestRepository)
+	s := newTestService(mockRepo, nil)
+
+	mockRepo.On("GetPointBreakdown", ctx, userID).Return([]*models.PointBreakdownItem{
+		{EventType: models.PointEventReportApproved, EventName: "Отчет одобрен", Count: 12, Points: 120},
+		{EventType: models.PointEventReportLate, EventName: "Сдача отчета после срока", Count: 2, Points: -10},
+	}, nil)
+	mockRepo.On("GetRankTiers", ctx).Return([]*models.RankTier{
+		{Slug: "novice", MinPoints: 0, Names: map[string]string{"ru": "Новичок"}},
+		{Slug: "experienced", MinPoints: 100, Names: map[string]string{"ru": "Опытный", "en": "Experienced"}},
+		{Slug: "expert", MinPoints: 500, Names: map[string]string{"ru": "Эксперт"}},
+	}, nil)
+	history := []*models.PointEvent{
+		{ID: uuid.New(), UserID: userID, EventType: models.PointEventReportLate, EventName: "Сдача отчета после срока", Points: -5, AssignmentID: &assignmentID},
+	}
+	mockRepo.On("GetPointEvents", ctx, repository.PointEventsFilter{UserID: userID, Limit: 10, Offset: 10}).Return(history, 11, nil)
+
+	// Act
+	response, err := s.GetUserPoints(ctx, userID, GetPointsRequestDTO{Page: 2, Limit: 10, Lang: "en"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 110, response.Points)
+	assert.Equal(t, RankDTO{Slug: "experienced", Name: "Experienced", MinPoints: 100}, response.Rank)
+	if assert.NotNil(t, response.NextRank) && assert.NotNil(t, response.PointsToNextRank) {
+		assert.Equal(t, "expert", response.NextRank.Slug)
+		assert.Equal(t, 390, *response.PointsToNextRank)
+	}
+	assert.Len(t, response.Breakdown, 2)
+	if assert.Len(t, response.Events, 1) {
+		assert.Equal(t, models.PointEventReportLate, response.Events[0].EventType)
+		assert.Equal(t, -5, response.Events[0].Points)
+		assert.Equal(t, &assignmentID, response.Events[0].AssignmentID)
+	}
+	assert.Equal(t, 11, response.Total)
+	assert.Equal(t, 2, response.Page)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateAssignmentFromOTAReservationCampaign(t *testing.T) {
+	ctx := context.Background()
+	reservation := &models.OTAReservation{
+		ID:           uuid.New(),
+		ListingID:    uuid.New(),
+		CheckinDate:  time.Date(2030, 5, 10, 0, 0, 0, 0, time.UTC),
+		CheckoutDate: time.Date(2030, 5, 12, 0, 0, 0, 0, time.UTC),
+	}
+	campaign := &models.Campaign{ID: uuid.New(), Purpose: "Проверка летнего сезона"}
+
+	cases := []struct {
+		name         string
+		campaign     *models.Campaign
+		campaignErr  error
+		wantCampaign *uuid.UUID
+		wantPurpose  string
+	}{
+		{"counted in the matching campaign", campaign, nil, &campaign.ID, campaign.Purpose},
+		{"no campaign or its quota and budget are exhausted", nil, models.ErrCampaignNotFound, nil, "Проверка объекта по бронированию от OTA"},
+		{"campaign lookup fails", nil, errors.New("db down"), nil, "Проверка объекта по бронированию от OTA"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			mockRepo.On("GetOTAReservationByID", mock.Anything, reservation.ID).Return(reservation, nil)
+			mockRepo.On("FindCampaignForListing", mock.Anything, reservation.ListingID, reservation.CheckinDate).Return(tc.campaign, tc.campaignErr)
+
+			var created *models.Assignment
+			mockRepo.On("CreateAssignment", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				created = args.Get(1).(*models.Assignment)
+			}).Return(uuid.New(), nil)
+
+			// Act
+			s.createAssignmentFromOTAReservation(ctx, reservation.ID)
+			s.Wait()
+
+			// Assert: кампания не мешает созданию предложения, даже если ее не удалось найти
+			if assert.NotNil(t, created) {
+				assert.Equal(t, tc.wantCampaign, created.CampaignID)
+				assert.Equal(t, tc.wantPurpose, created.Purpose)
+				assert.Equal(t, reservation.ListingID, created.ListingID)
+				assert.Equal(t, reservation.CheckinDate, created.ExpiresAt)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNeedsAssignment(t *testing.T) {
+	ctx := context.Background()
+	listingID := uuid.New()
+	checkin := time.Date(2030, 5, 10, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		threshold    float64
+		priority     *models.ListingPriority
+		priorityErr  error
+		campaign     *models.Campaign
+		campaignErr  error
+		wantCampaign bool // проверяется ли кампания
+		want         bool
+	}{
+		{name: "threshold disabled", threshold: 0, want: true},
+		{name: "priority above threshold", threshold: 50, priority: &models.ListingPriority{Score: 72.5}, want: true},
+		{name: "priority exactly at threshold", threshold: 50, priority: &models.ListingPriority{Score: 50}, want: true},
+		{name: "priority lookup fails", threshold: 50, priorityErr: errors.New("db down"), want: true},
+		{
+			name: "below threshold but listing is in a campaign", threshold: 50, priority: &models.ListingPriority{Score: 20},
+			campaign: &models.Campaign{ID: uuid.New()}, wantCampaign: true, want: true,
+		},
+		{
+			name: "below threshold without campaign", threshold: 50, priority: &models.ListingPriority{Score: 20},
+			campaignErr: models.ErrCampaignNotFound, wantCampaign: true, want: false,
+		},
+		{
+			name: "below threshold and campaign lookup fails", threshold: 50, priority: &models.ListingPriority{Score: 20},
+			campaignErr: errors.New("db down"), wantCampaign: true, want: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, &config.Config{AssignmentPriorityThreshold: tc.threshold})
+			if tc.threshold > 0 {
+				mockRepo.On("GetListingPriority", ctx, listingID).Return(tc.priority, tc.priorityErr)
+			}
+			if tc.wantCampaign {
+				mockRepo.On("FindCampaignForListing", ctx, listingID, checkin).Return(tc.campaign, tc.campaignErr)
+			}
+
+			// Act
+			got := s.needsAssignment(ctx, listingID, checkin)
+
+			// Assert
+			assert.Equal(t, tc.want, got)
+			mockRepo.AssertExpectations(t)
+			if !tc.wantCampaign {
+				mockRepo.AssertNotCalled(t, "FindCampaignForListing", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestResolveBulkAssignmentIDs(t *testing.T) {
+	ctx := context.Background()
+	reporterID := uuid.New()
+	idsOf := func(n int) []uuid.UUID {
+		ids := make([]uuid.UUID, n)
+		for i := range ids {
+			ids[i] = uuid.New()
+		}
+		return ids
+	}
+
+	invalid := []struct {
+		name string
+		dto  BulkAssignmentsRequestDTO
+	}{
+		{"neither ids nor filter", BulkAssignmentsRequestDTO{}},
+		{"both ids and filter", BulkAssignmentsRequestDTO{IDs: idsOf(1), Filter: &BulkAssignmentsFilterDTO{City: "Москва"}}},
+		{"filter without conditions", BulkAssignmentsRequestDTO{Filter: &BulkAssignmentsFilterDTO{City: "  "}}},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+
+			_, err := s.resolveBulkAssignmentIDs(ctx, tc.dto)
+			assert.ErrorIs(t, err, models.ErrValidationFailed)
+			mockRepo.AssertNotCalled(t, "GetAssignmentIDs", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("ids are deduplicated in order", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		ids := idsOf(3)
+
+		got, err := s.resolveBulkAssignmentIDs(ctx, BulkAssignmentsRequestDTO{IDs: []uuid.UUID{ids[0], ids[1], ids[0], ids[2], ids[1]}})
+		assert.NoError(t, err)
+		assert.Equal(t, ids, got)
+	})
+
+	limits := []struct {
+		name    string
+		matched int
+		wantErr error
+	}{
+		{"filter within the limit", models.MaxBulkAssignments, nil},
+		{"filter over the limit", models.MaxBulkAssignments + 1, models.ErrValidationFailed},
+	}
+	for _, tc := range limits {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange: репозиторий запрашивается на одно предложение больше лимита, чтобы распознать превышение
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			matched := idsOf(tc.matched)
+			filter := repository.BulkAssignmentsFilter{StatusIDs: []int{models.AssignmentStatusOffered}, City: "Москва", ReporterID: &reporterID}
+			mockRepo.On("GetAssignmentIDs", ctx, filter, models.MaxBulkAssignments+1).Return(matched, nil)
+
+			// Act
+			got, err := s.resolveBulkAssignmentIDs(ctx, BulkAssignmentsRequestDTO{Filter: &BulkAssignmentsFilterDTO{
+				StatusIDs:  []int{models.AssignmentStatusOffered},
+				City:       " Москва ",
+				ReporterID: &reporterID,
+			}})
+
+			// Assert
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, matched, got)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBulkCancelAssignments(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	actorID := uuid.New()
+	mockRepo := new(mocks.SecretGuestRepository)
+	s := newTestService(mockRepo, nil)
+
+	cancelled, notCancellable, broken, missing := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{cancelled, notCancellable, broken} {
+		mockRepo.On("GetAssignmentByID", ctx, id).Return(&models.Assignment{ID: id, StatusID: models.AssignmentStatusOffered}, nil)
+	}
+	mockRepo.On("GetAssignmentByID", ctx, missing).Return(nil, models.ErrAssignmentNotFound)
+
+	entries := map[uuid.UUID]*models.AuditLogEntry{}
+	record := func(args mock.Arguments) {
+		entries[args.Get(1).(uuid.UUID)] = args.Get(3).(*models.AuditLogEntry)
+	}
+	mockRepo.On("CancelAssignment", ctx, cancelled, mock.Anything, mock.Anything).Run(record).Return(nil)
+	mockRepo.On("CancelAssignment", ctx, notCancellable, mock.Anything, mock.Anything).Run(record).Return(models.ErrAssignmentCannotBeCancelled)
+	mockRepo.On("CancelAssignment", ctx, broken, mock.Anything, mock.Anything).Run(record).Return(errors.New("db down"))
+
+	// Act
+	response, err := s.BulkCancelAssignments(ctx, actorID, BulkAssignmentsRequestDTO{IDs: []uuid.UUID{cancelled, notCancellable, broken, missing}})
+
+	// Assert: неудача по одному предложению не прерывает остальные
+	assert.NoError(t, err)
+	assert.Equal(t, 1, response.Succeeded)
+	assert.Equal(t, 3, response.Failed)
+	assert.Equal(t, []*BulkAssignmentResultDTO{
+		{AssignmentID: cancelled, OK: true},
+		{AssignmentID: notCancellable, Error: models.ErrAssignmentCannotBeCancelled.Error()},
+		{AssignmentID: broken, Error: "internal error"},
+		{AssignmentID: missing, Error: models.ErrAssignmentNotFound.Error()},
+	}, response.Results)
+
+	// Все записи журнала по операции помечены одним bulk_id из ответа
+	assert.Len(t, entries, 3)
+	for id, entry := range entries {
+		var details map[string]any
+		assert.NoError(t, json.Unmarshal(entry.Details, &details))
+		assert.Equal(t, response.BulkID.String(), details["bulk_id"], "assignment %s", id)
+		assert.Equal(t, models.AuditActionAssignmentCancelled, entry.Action)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBulkExtendAssignmentsRecordsBulkID(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	actorID := uuid.New()
+	mockRepo := new(mocks.SecretGuestRepository)
+	s := newTestService(mockRepo, nil)
+
+	now := time.Now()
+	expiresAt := now.AddDate(0, 0, 5)
+	extended, invalid := uuid.New(), uuid.New()
+	mockRepo.On("GetAssignmentByID", ctx, extended).Return(&models.Assignment{
+		ID: extended, StatusID: models.AssignmentStatusOffered, ExpiresAt: now.AddDate(0, 0, 1), CheckoutDate: now.AddDate(0, 0, 10),
+	}, nil)
+	// Новый срок после выезда
+	mockRepo.On("GetAssignmentByID", ctx, invalid).Return(&models.Assignment{
+		ID: invalid, StatusID: models.AssignmentStatusOffered, ExpiresAt: now.AddDate(0, 0, 1), CheckoutDate: now.AddDate(0, 0, 3),
+	}, nil)
+
+	var entry *models.AuditLogEntry
+	mockRepo.On("ExtendAssignment", ctx, extended, (*uuid.UUID)(nil), expiresAt, (*time.Time)(nil), mock.Anything).Run(func(args mock.Arguments) {
+		entry = args.Get(5).(*models.AuditLogEntry)
+	}).Return(nil)
+
+	// Act
+	response, err := s.BulkExtendAssignments(ctx, actorID, BulkExtendAssignmentsRequestDTO{
+		BulkAssignmentsRequestDTO: BulkAssignmentsRequestDTO{IDs: []uuid.UUID{extended, invalid}},
+		ExpiresAt:                 expiresAt,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, response.Succeeded)
+	assert.Equal(t, 1, response.Failed)
+	if assert.Len(t, response.Results, 2) {
+		assert.True(t, response.Results[0].OK)
+		assert.False(t, response.Results[1].OK)
+		// Ошибка дат раскрывается с подробностями
+		assert.Contains(t, response.Results[1].Error, "before checkout_date")
+	}
+	if assert.NotNil(t, entry) {
+		var details map[string]any
+		assert.NoError(t, json.Unmarshal(entry.Details, &details))
+		assert.Equal(t, response.BulkID.String(), details["bulk_id"])
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeclineMyAssignment(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	cfg := &config.Config{AssignmentDeadlineHours: 24, AssignmentHoldMinutes: 120}
+
+	invalid := []struct {
+		name string
+		dto  DeclineAssignmentRequestDTO
+	}{
+		{"unknown reason", DeclineAssignmentRequestDTO{Reason: "weather"}},
+		{"other without comment", DeclineAssignmentRequestDTO{Reason: "other"}},
+		{"other with a blank comment", DeclineAssignmentRequestDTO{Reason: "other", Comment: "   "}},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, cfg)
+
+			err := s.DeclineMyAssignment(ctx, userID, uuid.New(), tc.dto)
+			assert.ErrorIs(t, err, models.ErrValidationFailed)
+			mockRepo.AssertNotCalled(t, "GetAssignmentByIDAndOwner", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	cases := []struct {
+		name        string
+		dto         DeclineAssignmentRequestDTO
+		wantReason  *int
+		wantComment *string
+	}{
+		{"without reason", DeclineAssignmentRequestDTO{}, nil, nil},
+		{"reason without comment", DeclineAssignmentRequestDTO{Reason: "location"}, ptr(models.DeclineReasonLocation), nil},
+		{"comment is trimmed", DeclineAssignmentRequestDTO{Reason: "dates", Comment: "  не успеваю  "}, ptr(models.DeclineReasonDates), ptr("не успеваю")},
+		{"other with comment", DeclineAssignmentRequestDTO{Reason: "other", Comment: "заболел"}, ptr(models.DeclineReasonOther), ptr("заболел")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, cfg)
+			expiresAt := time.Now().AddDate(0, 0, 7)
+			assignment := &models.Assignment{
+				ID:          uuid.New(),
+				StatusID:    models.AssignmentStatusOffered,
+				ExpiresAt:   expiresAt,
+				CheckinDate: expiresAt,
+				Listing:     models.ListingShortInfo{Title: "Отель у моря", City: "Сочи"},
+			}
+			mockRepo.On("GetAssignmentByIDAndOwner", ctx, assignment.ID, userID).Return(assignment, nil)
+
+			var offer *models.WaitlistOffer
+			mockRepo.On("DeclineMyAssignment", ctx, assignment.ID, userID, mock.Anything, tc.wantReason, tc.wantComment, mock.Anything).
+				Run(func(args mock.Arguments) {
+					offer = args.Get(6).(*models.WaitlistOffer)
+				}).Return((*uuid.UUID)(nil), nil)
+
+			// Act
+			err := s.DeclineMyAssignment(ctx, userID, assignment.ID, tc.dto)
+
+			// Assert: освободившееся предложение предлагается листу ожидания со сроком удержания от окна принятия
+			assert.NoError(t, err)
+			if assert.NotNil(t, offer) && assert.NotNil(t, offer.AcceptDeadline) {
+				assert.True(t, expiresAt.Add(-22*time.Hour).Equal(*offer.AcceptDeadline))
+				assert.Equal(t, models.NotificationWaitlistOffered, offer.Notification.Type)
+				assert.Contains(t, offer.Notification.Body, "Отель у моря")
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("assignment is not offered", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, cfg)
+		assignmentID := uuid.New()
+		mockRepo.On("GetAssignmentByIDAndOwner", ctx, assignmentID, userID).Return(&models.Assignment{ID: assignmentID, StatusID: models.AssignmentStatusAccepted}, nil)
+
+		assert.ErrorIs(t, s.DeclineMyAssignment(ctx, userID, assignmentID, DeclineAssignmentRequestDTO{}), models.ErrAssignmentNotFound)
+		mockRepo.AssertNotCalled(t, "DeclineMyAssignment", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestEnsureApprovedGuest(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	cases := []struct {
+		name        string
+		application *models.GuestApplication
+		repoErr     error
+		wantErr     error
+	}{
+		{"approved", &models.GuestApplication{StatusID: models.GuestApplicationStatusApproved}, nil, nil},
+		{"pending", &models.GuestApplication{StatusID: models.GuestApplicationStatusPending}, nil, models.ErrApplicationNotApproved},
+		{"waitlisted", &models.GuestApplication{StatusID: models.GuestApplicationStatusWaitlisted}, nil, models.ErrApplicationNotApproved},
+		{"rejected", &models.GuestApplication{StatusID: models.GuestApplicationStatusRejected}, nil, models.ErrApplicationNotApproved},
+		{"no application", nil, models.ErrApplicationNotFound, models.ErrApplicationNotApproved},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			mockRepo.On("GetGuestApplicationByUserID", ctx, userID).Return(tc.application, tc.repoErr)
+
+			err := s.ensureApprovedGuest(ctx, userID)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("repository error is not reported as not approved", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		mockRepo.On("GetGuestApplicationByUserID", ctx, userID).Return(nil, errors.New("db down"))
+
+		err := s.ensureApprovedGuest(ctx, userID)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, models.ErrApplicationNotApproved)
+	})
+
+	// Свободные предложения закрыты для гостей без одобренной заявки
+	guarded := []struct {
+		name string
+		call func(s *SecretGuestService) error
+	}{
+		{"free assignments", func(s *SecretGuestService) error {
+			_, err := s.GetFreeAssignments(ctx, GetFreeAssignmentsRequestDTO{UserID: userID, Page: 1, Limit: 10})
+			return err
+		}},
+		{"free assignment by id", func(s *SecretGuestService) error {
+			_, err := s.GetFreeAssignmentsByID(ctx, userID, uuid.New())
+			return err
+		}},
+		{"take", func(s *SecretGuestService) error { return s.TakeFreeAssignmentsByID(ctx, userID, uuid.New()) }},
+		{"join waitlist", func(s *SecretGuestService) error {
+			_, err := s.JoinAssignmentWaitlist(ctx, userID, uuid.New())
+			return err
+		}},
+	}
+	for _, tc := range guarded {
+		t.Run(tc.name+" requires an approved application", func(t *testing.T) {
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			mockRepo.On("GetGuestApplicationByUserID", ctx, userID).Return(&models.GuestApplication{StatusID: models.GuestApplicationStatusPending}, nil)
+
+			assert.ErrorIs(t, tc.call(s), models.ErrApplicationNotApproved)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReviewGuestApplication(t *testing.T) {
+	ctx := context.Background()
+	actorID, applicantID, applicationID := uuid.New(), uuid.New(), uuid.New()
+
+	cases := []struct {
+		name        string
+		statusID    int
+		comment     *string
+		wantAction  string
+		wantComment *string
+	}{
+		{"approve", models.GuestApplicationStatusApproved, nil, models.AuditActionApplicationApproved, nil},
+		{"reject with comment", models.GuestApplicationStatusRejected, ptr("  мало поездок  "), models.AuditActionApplicationRejected, ptr("мало поездок")},
+		{"waitlist with a blank comment", models.GuestApplicationStatusWaitlisted, ptr("   "), models.AuditActionApplicationWaitlisted, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			application := &models.GuestApplication{ID: applicationID, UserID: applicantID, StatusID: models.GuestApplicationStatusPending}
+			mockRepo.On("GetGuestApplicationByID", ctx, applicationID).Return(application, nil)
+
+			var entry *models.AuditLogEntry
+			mockRepo.On("ReviewGuestApplication", ctx, applicationID, tc.statusID, actorID, tc.wantComment, mock.Anything).Run(func(args mock.Arguments) {
+				entry = args.Get(5).(*models.AuditLogEntry)
+			}).Return(nil)
+
+			// Act
+			_, err := s.ReviewGuestApplication(ctx, actorID, applicationID, tc.statusID, ReviewGuestApplicationRequestDTO{Comment: tc.comment})
+
+			// Assert
+			assert.NoError(t, err)
+			if assert.NotNil(t, entry) {
+				assert.Equal(t, tc.wantAction, entry.Action)
+				var details map[string]any
+				assert.NoError(t, json.Unmarshal(entry.Details, &details))
+				assert.Equal(t, applicantID.String(), details["user_id"])
+				assert.EqualValues(t, models.GuestApplicationStatusPending, details["from_status_id"])
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("pending is not a decision", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+
+		_, err := s.ReviewGuestApplication(ctx, actorID, applicationID, models.GuestApplicationStatusPending, ReviewGuestApplicationRequestDTO{})
+		assert.ErrorIs(t, err, models.ErrValidationFailed)
+		mockRepo.AssertNotCalled(t, "GetGuestApplicationByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("own application", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		mockRepo.On("GetGuestApplicationByID", ctx, applicationID).Return(&models.GuestApplication{ID: applicationID, UserID: actorID}, nil)
+
+		_, err := s.ReviewGuestApplication(ctx, actorID, applicationID, models.GuestApplicationStatusApproved, ReviewGuestApplicationRequestDTO{})
+		assert.ErrorIs(t, err, models.ErrCannotModifySelf)
+		mockRepo.AssertNotCalled(t, "ReviewGuestApplication", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestExportMyData(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	mockRepo := new(mocks.SecretGuestRepository)
+	s := newTestService(mockRepo, nil)
+	userID := uuid.New()
+
+	mockRepo.On("GetUserByID", ctx, userID).Return(&models.User{ID: userID, Username: "guest", RoleID: models.GuestRoleID}, nil)
+	mockRepo.On("GetUserProfileByID", ctx, userID).Return(nil, models.ErrNotFound)
+	mockRepo.On("GetGuestApplicationByUserID", ctx, userID).Return(nil, models.ErrApplicationNotFound)
+	mockRepo.On("GetAssignments", ctx, mock.Anything).Return([]*models.Assignment{}, 0, nil)
+	mockRepo.On("GetRewards", ctx, mock.Anything).Return([]*models.Reward{}, 0, nil)
+	mockRepo.On("GetPointEvents", ctx, mock.Anything).Return([]*models.PointEvent{}, 0, nil)
+
+	// Отчетов больше одной страницы: выгрузка проходит все страницы, медиафайлы собираются из ответов чек-листа
+	withMedia := &models.Report{ID: uuid.New(), ChecklistSchema: models.ChecklistSchema{
+		"sections": []any{map[string]any{
+			"slug": "room",
+			"items": []any{
+				map[string]any{"slug": "bed", "answer": map[string]any{"media": []any{
+					map[string]any{"url": "https://cdn.example.com/bed.jpg", "media_type": "image"},
+					map[string]any{"url": ""},
+				}}},
+				map[string]any{"slug": "window"},
+			},
+		}},
+	}}
+	firstPage := make([]*models.Report, exportPageSize)
+	for i := range firstPage {
+		firstPage[i] = &models.Report{ID: uuid.New()}
+	}
+	total := exportPageSize + 1
+	var offsets []int
+	recordOffset := func(args mock.Arguments) {
+		filter := args.Get(1).(repository.ReportsFilter)
+		assert.Equal(t, userID, *filter.ReporterID)
+		offsets = append(offsets, filter.Offset)
+	}
+	mockRepo.On("GetReports", ctx, mock.Anything).Run(recordOffset).Return(firstPage, total, nil).Once()
+	mockRepo.On("GetReports", ctx, mock.Anything).Run(recordOffset).Return([]*models.Report{withMedia}, total, nil).Once()
+
+	// Act
+	export, err := s.ExportMyData(ctx, userID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, exportPageSize}, offsets)
+
+	zr, err := zip.NewReader(bytes.NewReader(export.Content), int64(len(export.Content)))
+	assert.NoError(t, err)
+	files := make(map[string][]byte)
+	names := make([]string, 0, len(zr.File))
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		assert.NoError(t, err)
+		rc.Close()
+		files[f.Name] = content
+	}
+
+	// Профиля и заявки нет - файлов для них тоже нет
+	assert.ElementsMatch(t, []string{"user.json", "assignments.json", "reports.json", "media.json", "rewards.json", "points.json"}, names)
+
+	var reports []map[string]any
+	assert.NoError(t, json.Unmarshal(files["reports.json"], &reports))
+	assert.Len(t, reports, total)
+
+	var media []ExportedMediaFile
+	assert.NoError(t, json.Unmarshal(files["media.json"], &media))
+	assert.Equal(t, []ExportedMediaFile{{ReportID: withMedia.ID, ItemSlug: "bed", URL: "https://cdn.example.com/bed.jpg", MediaType: "image"}}, media)
+
+	var user map[string]any
+	assert.NoError(t, json.Unmarshal(files["user.json"], &user))
+	assert.NotContains(t, user, "password_hash")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestDeleteMyAccount(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	t.Run("anonymizes the account and offers taken assignments to the waitlist", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		taken := &models.Assignment{ID: uuid.New(), CheckinDate: time.Now().AddDate(0, 0, 10), ExpiresAt: time.Now().AddDate(0, 0, 10)}
+		taken.Listing.Title = "Отель"
+
+		mockRepo.On("GetUserByID", ctx, userID).Return(&models.User{ID: userID, RoleID: models.GuestRoleID, PasswordHash: string(hash)}, nil)
+		mockRepo.On("GetAssignments", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			filter := args.Get(1).(repository.AssignmentsFilter)
+			assert.Equal(t, userID, *filter.ReporterID)
+			assert.Equal(t, []int{models.AssignmentStatusOffered}, filter.StatusIDs)
+		}).Return([]*models.Assignment{taken}, 1, nil)
+
+		var (
+			username string
+			offers   map[uuid.UUID]*models.WaitlistOffer
+			entry    *models.AuditLogEntry
+		)
+		mockRepo.On("DeleteUserAccount", ctx, userID, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			username = args.String(2)
+			offers = args.Get(3).(map[uuid.UUID]*models.WaitlistOffer)
+			entry = args.Get(4).(*models.AuditLogEntry)
+		}).Return(nil)
+
+		// Act
+		err := s.DeleteMyAccount(ctx, userID, DeleteMyAccountRequestDTO{Password: "secret"})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "deleted_"+strings.ReplaceAll(userID.String(), "-", "")[:12], username)
+		if assert.Contains(t, offers, taken.ID) {
+			assert.Contains(t, offers[taken.ID].Notification.Body, "Отель")
+		}
+		assert.Equal(t, models.AuditActionUserDeleted, entry.Action)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("account without password needs no confirmation", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		mockRepo.On("GetUserByID", ctx, userID).Return(&models.User{ID: userID, RoleID: models.GuestRoleID, PasswordHash: "!"}, nil)
+		mockRepo.On("GetAssignments", ctx, mock.Anything).Return([]*models.Assignment{}, 0, nil)
+		mockRepo.On("DeleteUserAccount", ctx, userID, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		assert.NoError(t, s.DeleteMyAccount(ctx, userID, DeleteMyAccountRequestDTO{}))
+		mockRepo.AssertExpectations(t)
+	})
+
+	rejected := []struct {
+		name     string
+		user     *models.User
+		password string
+		wantErr  error
+	}{
+		{"wrong password", &models.User{ID: userID, RoleID: models.GuestRoleID, PasswordHash: string(hash)}, "wrong", models.ErrInvalidCredentials},
+		{"staff account", &models.User{ID: userID, RoleID: models.ModeratorRoleID, PasswordHash: string(hash)}, "secret", models.ErrAccountNotDeletable},
+		{"service account", &models.User{ID: userID, RoleID: models.GuestRoleID, IsServiceAccount: true}, "", models.ErrServiceAccount},
+	}
+	for _, tc := range rejected {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			mockRepo.On("GetUserByID", ctx, userID).Return(tc.user, nil)
+
+			err := s.DeleteMyAccount(ctx, userID, DeleteMyAccountRequestDTO{Password: tc.password})
+			assert.ErrorIs(t, err, tc.wantErr)
+			mockRepo.AssertNotCalled(t, "DeleteUserAccount", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetDeclineAnalytics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("groups by reason by default", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		from := time.Now().AddDate(0, -1, 0)
+		mockRepo.On("GetDeclineAnalytics", ctx, repository.DeclineAnalyticsFilter{
+			GroupBy: models.DeclineGroupByReason, From: &from, Limit: 20, Offset: 40,
+		}).Return([]*models.DeclineStat{{Key: "dates", Label: "Не подходят даты", Declines: 5, ByReason: map[string]int{"dates": 5}}}, 1, nil)
+
+		resp, err := s.GetDeclineAnalytics(ctx, GetDeclineAnalyticsRequestDTO{From: &from, Page: 3, Limit: 20})
+		assert.NoError(t, err)
+		assert.Equal(t, models.DeclineGroupByReason, resp.GroupBy)
+		if assert.Len(t, resp.Groups, 1) {
+			assert.Equal(t, 5, resp.Groups[0].Declines)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown grouping", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+
+		_, err := s.GetDeclineAnalytics(ctx, GetDeclineAnalyticsRequestDTO{GroupBy: "guest", Page: 1, Limit: 20})
+		assert.ErrorIs(t, err, models.ErrValidationFailed)
+		mockRepo.AssertNotCalled(t, "GetDeclineAnalytics", mock.Anything, mock.Anything)
+	})
+
+	t.Run("repeated declines threshold defaults", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		mockRepo.On("GetRepeatedlyDeclinedAssignments", ctx, models.DefaultRepeatedDeclines, 10, 0).Return([]*models.DeclinedAssignment{}, 0, nil)
+
+		_, err := s.GetRepeatedlyDeclinedAssignments(ctx, GetDeclinedAssignmentsRequestDTO{Page: 1, Limit: 10})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestLeaderboardPeriodStart(t *testing.T) {
+	// Период считается по дате UTC: 23:30 по Москве в среду - 20:30 UTC того же дня
+	msk := time.FixedZone("MSK", 3*60*60)
+	wednesday := time.Date(2025, 10, 15, 23, 30, 0, 0, msk)
+
+	cases := []struct {
+		name   string
+		period string
+		now    time.Time
+		want   *time.Time
+	}{
+		{"week starts on monday", models.LeaderboardPeriodWeek, wednesday, ptr(time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC))},
+		{"week on monday", models.LeaderboardPeriodWeek, time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC), ptr(time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC))},
+		{"week on sunday", models.LeaderboardPeriodWeek, time.Date(2025, 10, 19, 23, 59, 0, 0, time.UTC), ptr(time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC))},
+		{"week across months", models.LeaderboardPeriodWeek, time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC), ptr(time.Date(2025, 9, 29, 0, 0, 0, 0, time.UTC))},
+		{"week by utc date", models.LeaderboardPeriodWeek, time.Date(2025, 10, 20, 1, 0, 0, 0, msk), ptr(time.Date(2025, 10, 13, 0, 0, 0, 0, time.UTC))},
+		{"month", models.LeaderboardPeriodMonth, wednesday, ptr(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC))},
+		{"month by utc date", models.LeaderboardPeriodMonth, time.Date(2025, 11, 1, 1, 0, 0, 0, msk), ptr(time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC))},
+		{"all time", models.LeaderboardPeriodAll, wednesday, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, leaderboardPeriodStart(tc.period, tc.now))
+		})
+	}
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	t.Run("all time by default with own entry", func(t *testing.T) {
+		// Arrange
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		filter := repository.LeaderboardFilter{City: "Казань", Limit: 10, Offset: 10}
+		mockRepo.On("GetLeaderboard", ctx, filter).Return([]*models.LeaderboardEntry{
+			{Position: 11, UserID: uuid.New(), Username: "guest", AdditionalInfo: json.RawMessage(`{"display_name": "Гость", "home_city": "Казань"}`), Points: 40},
+			{Position: 12, UserID: userID, Username: "me", Points: 30},
+		}, 12, nil)
+		mockRepo.On("GetLeaderboardEntry", ctx, filter, userID).Return(&models.LeaderboardEntry{Position: 12, UserID: userID, Username: "me", Points: 30}, nil)
+
+		// Act
+		resp, err := s.GetLeaderboard(ctx, userID, GetLeaderboardRequestDTO{City: "  Казань ", Page: 2, Limit: 10})
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, models.LeaderboardPeriodAll, resp.Period)
+		assert.Nil(t, resp.From)
+		assert.Equal(t, "Казань", resp.City)
+		if assert.Len(t, resp.Entries, 2) {
+			assert.Equal(t, "Гость", resp.Entries[0].DisplayName)
+			assert.Equal(t, "me", resp.Entries[1].DisplayName)
+		}
+		if assert.NotNil(t, resp.Me) {
+			assert.Equal(t, 12, resp.Me.Position)
+		}
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("period start is passed to the repository", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		var filter repository.LeaderboardFilter
+		mockRepo.On("GetLeaderboard", ctx, mock.Anything).Run(func(args mock.Arguments) {
+			filter = args.Get(1).(repository.LeaderboardFilter)
+		}).Return([]*models.LeaderboardEntry{}, 0, nil)
+		mockRepo.On("GetLeaderboardEntry", ctx, mock.Anything, userID).Return(nil, models.ErrNotFound)
+
+		resp, err := s.GetLeaderboard(ctx, userID, GetLeaderboardRequestDTO{Period: models.LeaderboardPeriodMonth, Page: 1, Limit: 10})
+		assert.NoError(t, err)
+		assert.Nil(t, resp.Me)
+		if assert.NotNil(t, filter.From) && assert.NotNil(t, resp.From) {
+			assert.Equal(t, 1, filter.From.Day())
+			assert.Equal(t, filter.From.Format(time.DateOnly), *resp.From)
+		}
+	})
+
+	t.Run("unknown period", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+
+		_, err := s.GetLeaderboard(ctx, userID, GetLeaderboardRequestDTO{Period: "year", Page: 1, Limit: 10})
+		assert.ErrorIs(t, err, models.ErrValidationFailed)
+		mockRepo.AssertNotCalled(t, "GetLeaderboard", mock.Anything, mock.Anything)
+	})
+}
+
+// fakeStorage - хранилище с публичными URL вида https://cdn.example.com/<путь>
+type fakeStorage struct{}
+
+func (fakeStorage) GenerateUploadURL(ctx context.Context, params storage.UploadParams) (*storage.UploadResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeStorage) FileURL(ctx context.Context, filePath string) (string, error) {
+	return "https://cdn.example.com/" + filePath, nil
+}
+
+func TestUpdateMyProfile(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	existing := json.RawMessage(`{"display_name": "Старое имя", "home_city": "Казань", "languages": ["ru"]}`)
+
+	// update применяет dto к профилю с existing и возвращает сохраненные данные профиля
+	update := func(t *testing.T, dto UpdateMyProfileRequestDTO, setup func(m *mocks.SecretGuestRepository)) (models.ProfileInfo, error) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := NewSecretGuestService(&config.Config{}, mockRepo, fakeStorage{})
+		mockRepo.On("GetUserProfileByID", ctx, userID).Return(&models.UserProfile{UserID: userID, AdditionalInfo: existing}, nil)
+		mockRepo.On("GetRankTiers", ctx).Return([]*models.RankTier{}, nil).Maybe()
+		mockRepo.On("GetUserBadges", ctx, []uuid.UUID{userID}).Return(map[uuid.UUID][]*models.UserBadge{}, nil).Maybe()
+		if setup != nil {
+			setup(mockRepo)
+		}
+
+		var saved models.ProfileInfo
+		mockRepo.On("UpdateUserProfileInfo", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+			assert.NoError(t, json.Unmarshal(args.Get(2).(json.RawMessage), &saved))
+		}).Return(nil).Maybe()
+
+		_, err := s.UpdateMyProfile(ctx, userID, dto, models.DefaultLanguage)
+		if err != nil {
+			mockRepo.AssertNotCalled(t, "UpdateUserProfileInfo", mock.Anything, mock.Anything, mock.Anything)
+		}
+		return saved, err
+	}
+
+	t.Run("partial update keeps other fields", func(t *testing.T) {
+		saved, err := update(t, UpdateMyProfileRequestDTO{HomeCity: ptr("  Сочи "), HideFromLeaderboard: ptr(true)}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ProfileInfo{
+			DisplayName:         "Старое имя",
+			HomeCity:            "Сочи",
+			Languages:           []string{"ru"},
+			HideFromLeaderboard: true,
+		}, saved)
+	})
+
+	t.Run("normalizes preferences", func(t *testing.T) {
+		saved, err := update(t, UpdateMyProfileRequestDTO{
+			DisplayName:             ptr(" Новое имя "),
+			PreferredListingTypeIDs: &[]int{2, 1, 2},
+			Availability: &[]AvailabilityPeriodDTO{
+				{From: "2026-08-01", To: "2026-08-10"},
+				{From: "2026-06-01", To: "2026-06-01"},
+			},
+			Languages: &[]string{"en", "ru", "en"},
+		}, func(m *mocks.SecretGuestRepository) {
+			m.On("GetListingTypes", ctx, repository.ListingTypesFilter{IDs: []int{2, 1}}).
+				Return([]*models.ListingType{{ID: 1}, {ID: 2}}, nil)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Новое имя", saved.DisplayName)
+		assert.Equal(t, []int{2, 1}, saved.PreferredListingTypeIDs)
+		assert.Equal(t, []models.AvailabilityPeriod{{From: "2026-06-01", To: "2026-06-01"}, {From: "2026-08-01", To: "2026-08-10"}}, saved.Availability)
+		assert.Equal(t, []string{"en", "ru"}, saved.Languages)
+	})
+
+	t.Run("avatar from the user folder", func(t *testing.T) {
+		saved, err := update(t, UpdateMyProfileRequestDTO{AvatarPath: ptr("/users/" + userID.String() + "/avatar.png")}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://cdn.example.com/users/"+userID.String()+"/avatar.png", saved.AvatarURL)
+	})
+
+	t.Run("empty avatar path removes the avatar", func(t *testing.T) {
+		saved, err := update(t, UpdateMyProfileRequestDTO{AvatarPath: ptr("")}, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, saved.AvatarURL)
+	})
+
+	invalid := []struct {
+		name  string
+		dto   UpdateMyProfileRequestDTO
+		setup func(m *mocks.SecretGuestRepository)
+	}{
+		{"avatar of another user", UpdateMyProfileRequestDTO{AvatarPath: ptr("users/" + uuid.NewString() + "/avatar.png")}, nil},
+		{"avatar outside the folder", UpdateMyProfileRequestDTO{AvatarPath: ptr("users/" + userID.String() + "/../x.png")}, nil},
+		{"avatar folder without file", UpdateMyProfileRequestDTO{AvatarPath: ptr("users/" + userID.String() + "/")}, nil},
+		{"unknown listing type", UpdateMyProfileRequestDTO{PreferredListingTypeIDs: &[]int{1, 999}}, func(m *mocks.SecretGuestRepository) {
+			m.On("GetListingTypes", ctx, mock.Anything).Return([]*models.ListingType{{ID: 1}}, nil)
+		}},
+		{"availability ends before it starts", UpdateMyProfileRequestDTO{Availability: &[]AvailabilityPeriodDTO{{From: "2026-08-10", To: "2026-08-01"}}}, nil},
+		{"malformed availability date", UpdateMyProfileRequestDTO{Availability: &[]AvailabilityPeriodDTO{{From: "01.08.2026", To: "2026-08-10"}}}, nil},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := update(t, tc.dto, tc.setup)
+			assert.ErrorIs(t, err, models.ErrValidationFailed)
+		})
+	}
+}
+
+func TestGetAssignmentRanking(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	today := time.Now()
+	future := today.AddDate(0, 1, 0).Format(time.DateOnly)
+	past := today.AddDate(0, -1, 0).Format(time.DateOnly)
+
+	cases := []struct {
+		name    string
+		profile *models.UserProfile
+		repoErr error
+		want    *repository.AssignmentRanking
+	}{
+		{"no profile", nil, models.ErrNotFound, nil},
+		{"no preferences", &models.UserProfile{AdditionalInfo: json.RawMessage(`{"display_name": "Гость"}`)}, nil, nil},
+		{"only past availability", &models.UserProfile{AdditionalInfo: json.RawMessage(`{"availability": [{"from": "` + past + `", "to": "` + past + `"}]}`)}, nil, nil},
+		{
+			"preferences",
+			&models.UserProfile{AdditionalInfo: json.RawMessage(`{"home_city": "Сочи", "preferred_listing_type_ids": [3], "availability": [
+				{"from": "` + past + `", "to": "` + past + `"},
+				{"from": "` + past + `", "to": "` + future + `"}
+			]}`)},
+			nil,
+			&repository.AssignmentRanking{
+				HomeCity:       "Сочи",
+				ListingTypeIDs: []int{3},
+				AvailableFrom:  []time.Time{mustParseDate(t, past)},
+				AvailableTo:    []time.Time{mustParseDate(t, future)},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.SecretGuestRepository)
+			s := newTestService(mockRepo, nil)
+			mockRepo.On("GetUserProfileByID", ctx, userID).Return(tc.profile, tc.repoErr)
+
+			ranking, err := s.getAssignmentRanking(ctx, userID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, ranking)
+		})
+	}
+
+	t.Run("free assignments are ranked by the caller's preferences", func(t *testing.T) {
+		mockRepo := new(mocks.SecretGuestRepository)
+		s := newTestService(mockRepo, nil)
+		mockRepo.On("GetGuestApplicationByUserID", ctx, userID).Return(&models.GuestApplication{StatusID: models.GuestApplicationStatusApproved}, nil)
+		mockRepo.On("GetUserProfileByID", ctx, userID).Return(&models.UserProfile{AdditionalInfo: json.RawMessage(`{"home_city": "Сочи"}`)}, nil)
+		mockRepo.On("GetFreeAssignments", ctx, mock.MatchedBy(func(f repository.AssignmentsFilter) bool {
+			return f.Ranking != nil && f.Ranking.HomeCity == "Сочи"
+		})).Return([]*models.Assignment{}, 0, nil)
+
+		_, err := s.GetFreeAssignments(ctx, GetFreeAssignmentsRequestDTO{UserID: userID, Page: 1, Limit: 10})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func mustParseDate(t *testing.T, s string) time.Time {
+	d, err := time.Parse(time.DateOnly, s)
+	assert.NoError(t, err)
+	return d
+}
@@ -0,0 +1,68 @@
+package secret_guest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+)
+
+// Лист ожидания предложений
+
+// newWaitlistOffer - как закрепить освободившееся предложение за следующим гостем из листа ожидания: свой срок
+// удержания и уведомление(получателя выбирает репозиторий)
+func (s *SecretGuestService) newWaitlistOffer(assignmentID uuid.UUID, listingTitle, city string, checkin, expiresAt, now time.Time) (*models.WaitlistOffer, error) {
+	deadline := s.assignmentHoldDeadline(now, expiresAt)
+
+	payload, err := json.Marshal(map[string]any{
+		"assignment_id":   assignmentID,
+		"accept_deadline": deadline,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+
+	body := fmt.Sprintf("Освободилось предложение из листа ожидания: «%s» (%s), заезд %s. Оно закреплено за вами",
+		listingTitle, city, checkin.Format("02.01.2006"))
+	if deadline != nil {
+		body += ", примите его до " + deadline.Format("02.01.2006 15:04")
+	}
+
+	return &models.WaitlistOffer{
+		AcceptDeadline: deadline,
+		Notification: &models.Notification{
+			Type:    models.NotificationWaitlistOffered,
+			Title:   "Предложение из листа ожидания",
+			Body:    body,
+			Payload: payload,
+		},
+	}, nil
+}
+
+// JoinAssignmentWaitlist ставит гостя в лист ожидания предложения, закрепленного за другим гостем
+func (s *SecretGuestService) JoinAssignmentWaitlist(ctx context.Context, userID, assignmentID uuid.UUID) (*WaitlistEntryResponseDTO, error) {
+	if err := s.ensureApprovedGuest(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	entry, err := s.repo.JoinAssignmentWaitlist(ctx, assignmentID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join waitlist of assignment %s: %w", assignmentID.String(), err)
+	}
+
+	return &WaitlistEntryResponseDTO{
+		AssignmentID: entry.AssignmentID,
+		Position:     entry.Position,
+		CreatedAt:    entry.CreatedAt,
+	}, nil
+}
+
+func (s *SecretGuestService) LeaveAssignmentWaitlist(ctx context.Context, userID, assignmentID uuid.UUID) error {
+	if err := s.repo.LeaveAssignmentWaitlist(ctx, assignmentID, userID); err != nil {
+		return fmt.Errorf("failed to leave waitlist of assignment %s: %w", assignmentID.String(), err)
+	}
+	return nil
+}
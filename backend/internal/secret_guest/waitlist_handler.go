@@ -0,0 +1,96 @@
+package secret_guest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/models"
+	"github.com/ostrovok-hackathon-2025/koshka-musya/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// assignment waitlist
+
+// @Summary      Join Assignment Waitlist
+// @Security     BearerAuth
+// @Description  Puts the current user at the end of the waitlist of an assignment that is currently held by another guest. When the holder declines, the hold expires or staff release the assignment, it is bound to the first eligible guest from the waitlist (approved application, no other offered assignment and no overlapping dates) with its own hold window, and the guest gets an assignment.waitlist_offered notification. A free assignment should be taken instead.
+// @Tags         Assignments (User)
+// @Produce      json
+// @Param        id path string true "Assignment ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      201 {object} secret_guest.WaitlistEntryResponseDTO
+// @Failure      400 {object} ErrorResponse "Invalid assignment ID format"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      403 {object} ErrorResponse "Participation application is not approved"
+// @Failure      409 {object} ErrorResponse "Assignment is not held by another guest or the user is already in the waitlist"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /assignments/{id}/waitlist [post]
+func (h *SecretGuestHandler) JoinAssignmentWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	assignmentID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	entry, err := h.service.JoinAssignmentWaitlist(ctx, userID, assignmentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrApplicationNotApproved):
+			h.writeErrorResponse(ctx, w, http.StatusForbidden, err.Error())
+		case errors.Is(err, models.ErrWaitlistNotAvailable), errors.Is(err, models.ErrAlreadyInWaitlist):
+			h.writeErrorResponse(ctx, w, http.StatusConflict, err.Error())
+		default:
+			log.Error(ctx, "Failed to join assignment waitlist", zap.Error(err))
+			h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	h.writeJSONResponse(ctx, w, http.StatusCreated, entry)
+}
+
+// @Summary      Leave Assignment Waitlist
+// @Security     BearerAuth
+// @Description  Removes the current user from the waitlist of an assignment.
+// @Tags         Assignments (User)
+// @Param        id path string true "Assignment ID" format(uuid)
+// @Param Authorization header string true "Bearer Access Token"
+// @Success      204 "No Content"
+// @Failure      400 {object} ErrorResponse "Invalid assignment ID format"
+// @Failure      401 {object} ErrorResponse "Unauthorized"
+// @Failure      404 {object} ErrorResponse "User is not in the waitlist"
+// @Failure      500 {object} ErrorResponse "Internal server error"
+// @Router       /assignments/{id}/waitlist [delete]
+func (h *SecretGuestHandler) LeaveAssignmentWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logger.GetLoggerFromCtx(ctx)
+
+	userID, ok := h.parseUserAndID(w, r)
+	if !ok {
+		return
+	}
+
+	assignmentID, ok := h.parseUUIDFromPath(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.LeaveAssignmentWaitlist(ctx, userID, assignmentID); err != nil {
+		if errors.Is(err, models.ErrNotInWaitlist) {
+			h.writeErrorResponse(ctx, w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Error(ctx, "Failed to leave assignment waitlist", zap.Error(err))
+		h.writeErrorResponse(ctx, w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -2,8 +2,10 @@ package imagekit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/ostrovok-hackathon-2025/koshka-musya/internal/storage"
 
@@ -79,3 +81,11 @@ func (p *ImageKitProvider) GenerateUploadURL(ctx context.Context, params storage
 	}, nil
 
 }
+
+func (p *ImageKitProvider) FileURL(ctx context.Context, filePath string) (string, error) {
+	if p.cfg.UrlEndpoint == "" {
+		return "", errors.New("ImageKit URL endpoint is not configured")
+	}
+
+	return strings.TrimRight(p.cfg.UrlEndpoint, "/") + "/" + strings.TrimLeft(filePath, "/"), nil
+}
@@ -38,4 +38,7 @@ type UploadParams struct {
 
 type FileStorageProvider interface {
 	GenerateUploadURL(ctx context.Context, params UploadParams) (*UploadResponse, error)
+
+	// Публичный URL загруженного файла по его пути в хранилище (папка + имя файла).
+	FileURL(ctx context.Context, filePath string) (string, error)
 }
@@ -0,0 +1,23 @@
+-- Create "user_totp" table - настройки двухфакторной аутентификации (TOTP)
+CREATE TABLE "public"."user_totp" (
+  "user_id" uuid NOT NULL,
+  "secret" text NOT NULL, -- base32 секрет для генерации одноразовых кодов
+  "enabled" boolean NOT NULL DEFAULT false, -- true после подтверждения первым кодом
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "confirmed_at" timestamp NULL,
+  "last_used_step" bigint NULL, -- последний использованный временной шаг(защита от повторного использования кода)
+  PRIMARY KEY ("user_id"),
+  CONSTRAINT "user_totp_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- Create "user_recovery_codes" table - одноразовые коды восстановления доступа
+CREATE TABLE "public"."user_recovery_codes" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "code_hash" text NOT NULL, -- sha256 от нормализованного кода
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "used_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_recovery_codes_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX "user_recovery_codes_user_id_idx" ON "public"."user_recovery_codes" ("user_id");
@@ -0,0 +1,23 @@
+-- Блокировка пользователей и принудительная смена пароля
+ALTER TABLE "public"."users"
+  ADD COLUMN "blocked_at" timestamp NULL, -- дата блокировки(NULL - не заблокирован)
+  ADD COLUMN "blocked_reason" text NULL,
+  ADD COLUMN "blocked_by" uuid NULL, -- администратор, заблокировавший пользователя
+  ADD COLUMN "password_change_required" boolean NOT NULL DEFAULT false, -- пароль сброшен администратором, требуется смена
+  ADD CONSTRAINT "users_blocked_by_fkey" FOREIGN KEY ("blocked_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL;
+
+-- Create "audit_log" table - журнал действий персонала
+CREATE TABLE "public"."audit_log" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "actor_id" uuid NULL, -- кто выполнил действие
+  "action" text NOT NULL, -- код действия, например user.blocked
+  "entity_type" text NOT NULL, -- тип сущности, над которой выполнено действие
+  "entity_id" text NULL,
+  "details" jsonb NULL, -- параметры действия(старое/новое значение, причина и т.п.)
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "audit_log_actor_id_fkey" FOREIGN KEY ("actor_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+CREATE INDEX "audit_log_entity_idx" ON "public"."audit_log" ("entity_type", "entity_id");
+CREATE INDEX "audit_log_actor_id_idx" ON "public"."audit_log" ("actor_id");
+CREATE INDEX "audit_log_created_at_idx" ON "public"."audit_log" ("created_at");
@@ -0,0 +1,58 @@
+-- Роли добавлялись с явными id, выравниваем последовательность для создания новых ролей через API
+SELECT setval(pg_get_serial_sequence('public.roles', 'id'), (SELECT COALESCE(MAX(id), 1) FROM "public"."roles"));
+
+-- Create "permissions" table - права доступа
+CREATE TABLE "public"."permissions" (
+  "id" serial NOT NULL,
+  "slug" text NOT NULL, -- код права, например reports.approve
+  "description" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "permissions_slug_key" UNIQUE ("slug")
+);
+
+-- Create "role_permissions" table - права ролей
+CREATE TABLE "public"."role_permissions" (
+  "role_id" integer NOT NULL,
+  "permission_id" integer NOT NULL,
+  PRIMARY KEY ("role_id", "permission_id"),
+  CONSTRAINT "role_permissions_role_id_fkey" FOREIGN KEY ("role_id") REFERENCES "public"."roles" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "role_permissions_permission_id_fkey" FOREIGN KEY ("permission_id") REFERENCES "public"."permissions" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+
+INSERT INTO permissions (slug, description) VALUES
+    ('statistics.view',      'Просмотр статистики'),
+    ('listings.create',      'Создание объектов размещения'),
+    ('reservations.view',    'Просмотр бронирований OTA'),
+    ('reservations.create',  'Создание бронирований OTA'),
+    ('reservations.manage',  'Изменение статуса бронирований OTA'),
+    ('assignments.view',     'Просмотр всех предложений'),
+    ('assignments.manage',   'Управление предложениями (отмена)'),
+    ('reports.view',         'Просмотр всех отчетов'),
+    ('reports.approve',      'Модерация отчетов (одобрение/отклонение)'),
+    ('users.view',           'Просмотр пользователей'),
+    ('users.manage',         'Управление пользователями (роль, блокировка, сброс пароля)'),
+    ('profiles.view',        'Просмотр профилей пользователей'),
+    ('checklists.view',      'Просмотр справочников чек-листа'),
+    ('checklists.edit',      'Редактирование справочников чек-листа'),
+    ('audit.view',           'Просмотр журнала действий'),
+    ('roles.manage',         'Управление ролями и правами');
+
+-- Администратор получает все права
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin';
+
+-- Модератор - права персонала(как раньше у /staff)
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug IN (
+    'statistics.view',
+    'reservations.view',
+    'reservations.manage',
+    'assignments.view',
+    'assignments.manage',
+    'reports.view',
+    'reports.approve',
+    'users.view',
+    'profiles.view',
+    'checklists.view',
+    'checklists.edit'
+) WHERE r.name = 'moderator';
@@ -0,0 +1,29 @@
+-- Сервисные учетные записи для интеграций(OTA, BI-выгрузки). Вход по паролю для них невозможен.
+ALTER TABLE "public"."users"
+  ADD COLUMN "is_service_account" boolean NOT NULL DEFAULT false;
+
+-- Create "api_keys" table - API-ключи сервисных учетных записей
+CREATE TABLE "public"."api_keys" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL, -- сервисная учетная запись
+  "name" text NOT NULL,
+  "prefix" text NOT NULL, -- начало ключа для отображения(сам ключ не хранится)
+  "key_hash" text NOT NULL, -- sha256 от ключа
+  "scopes" text[] NOT NULL DEFAULT '{}', -- коды прав(permissions.slug), доступных по ключу
+  "created_by" uuid NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "expires_at" timestamp NULL, -- NULL - бессрочный ключ
+  "last_used_at" timestamp NULL,
+  "revoked_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "api_keys_key_hash_key" UNIQUE ("key_hash"),
+  CONSTRAINT "api_keys_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT "api_keys_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+CREATE INDEX "api_keys_user_id_idx" ON "public"."api_keys" ("user_id");
+
+INSERT INTO permissions (slug, description) VALUES
+    ('service_accounts.manage', 'Управление сервисными учетными записями и API-ключами');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'service_accounts.manage' WHERE r.name = 'admin';
@@ -0,0 +1,25 @@
+-- Create "user_identities" table - привязка учетных записей внешних провайдеров OpenID Connect
+CREATE TABLE "public"."user_identities" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "provider" text NOT NULL, -- имя провайдера из конфигурации(OIDC_PROVIDER_NAME)
+  "subject" text NOT NULL, -- claim sub из id_token
+  "email" text NULL, -- email на момент привязки
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "last_login_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_identities_provider_subject_key" UNIQUE ("provider", "subject"),
+  CONSTRAINT "user_identities_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE CASCADE ON DELETE CASCADE
+);
+CREATE INDEX "user_identities_user_id_idx" ON "public"."user_identities" ("user_id");
+
+-- Create "oidc_login_states" table - незавершенные входы через OIDC(state, nonce и PKCE code_verifier)
+CREATE TABLE "public"."oidc_login_states" (
+  "state" text NOT NULL,
+  "nonce" text NOT NULL,
+  "code_verifier" text NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "expires_at" timestamp NOT NULL,
+  PRIMARY KEY ("state")
+);
+CREATE INDEX "oidc_login_states_expires_at_idx" ON "public"."oidc_login_states" ("expires_at");
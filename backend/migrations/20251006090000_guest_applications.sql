@@ -0,0 +1,49 @@
+-- Create "guest_application_statuses" table - статусы заявок на участие в программе
+CREATE TABLE "public"."guest_application_statuses" (
+  "id" serial NOT NULL,
+  "slug" text NOT NULL,
+  "name" text NOT NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "guest_application_statuses_slug_key" UNIQUE ("slug")
+);
+
+INSERT INTO guest_application_statuses (id, slug, name) VALUES
+    (1, 'pending', 'На рассмотрении'),
+    (2, 'approved', 'Одобрена'),
+    (3, 'rejected', 'Отклонена'),
+    (4, 'waitlisted', 'Лист ожидания');
+
+-- Create "guest_applications" table - заявка пользователя на участие в программе тайных гостей(одна на пользователя)
+CREATE TABLE "public"."guest_applications" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "status_id" integer NOT NULL DEFAULT 1,
+  "motivation" text NOT NULL DEFAULT '',
+  "travel_frequency" text NULL, -- rarely, few_times_a_year, monthly, weekly
+  "home_city" text NOT NULL DEFAULT '',
+  "social_links" text[] NOT NULL DEFAULT '{}',
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "reviewed_by" uuid NULL,
+  "reviewed_at" timestamp NULL,
+  "review_comment" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "guest_applications_user_id_key" UNIQUE ("user_id"),
+  CONSTRAINT "guest_applications_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE CASCADE ON DELETE CASCADE,
+  CONSTRAINT "guest_applications_status_id_fkey" FOREIGN KEY ("status_id") REFERENCES "public"."guest_application_statuses" ("id") ON UPDATE NO ACTION ON DELETE RESTRICT,
+  CONSTRAINT "guest_applications_reviewed_by_fkey" FOREIGN KEY ("reviewed_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+CREATE INDEX "guest_applications_status_id_created_at_idx" ON "public"."guest_applications" ("status_id", "created_at");
+
+-- Уже зарегистрированные пользователи участвуют в программе без заявки - считаем их заявки одобренными
+INSERT INTO guest_applications (user_id, status_id, reviewed_at, review_comment)
+SELECT u.id, 2, CURRENT_TIMESTAMP, 'Участник программы до введения заявок'
+FROM users u
+WHERE NOT u.is_service_account
+ON CONFLICT (user_id) DO NOTHING;
+
+INSERT INTO permissions (slug, description) VALUES
+    ('applications.review', 'Просмотр и рассмотрение заявок на участие в программе');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'applications.review' WHERE r.name IN ('admin', 'moderator');
@@ -0,0 +1,7 @@
+-- Удаление учетной записи по запросу пользователя: персональные данные обезличиваются,
+-- одобренные отчеты по объектам сохраняются без привязки к автору
+ALTER TABLE "public"."users"
+  ADD COLUMN "deleted_at" timestamp NULL; -- дата удаления учетной записи(NULL - активна)
+
+ALTER TABLE "public"."reports"
+  ALTER COLUMN "reporter_id" DROP NOT NULL; -- NULL - автор удалил учетную запись
@@ -0,0 +1,6 @@
+-- Вход администратора от имени пользователя для поддержки. Каждое действие в такой сессии пишется в audit_log
+INSERT INTO permissions (slug, description) VALUES
+    ('users.impersonate', 'Вход от имени пользователя для поддержки');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'users.impersonate' WHERE r.name = 'admin';
@@ -0,0 +1,89 @@
+-- Create "reward_policies" table - вознаграждение гостя по типу объекта: доля стоимости брони(100 - бесплатное проживание)
+CREATE TABLE "public"."reward_policies" (
+  "listing_type_id" integer NOT NULL,
+  "percent" integer NOT NULL DEFAULT 100,
+  "max_amount" integer NULL, -- ограничение суммы в валюте брони
+  "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_by" uuid NULL,
+  PRIMARY KEY ("listing_type_id"),
+  CONSTRAINT "reward_policies_percent_check" CHECK (percent BETWEEN 0 AND 100),
+  CONSTRAINT "reward_policies_max_amount_check" CHECK (max_amount IS NULL OR max_amount >= 0),
+  CONSTRAINT "reward_policies_listing_type_id_fkey" FOREIGN KEY ("listing_type_id") REFERENCES "public"."listing_types" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "reward_policies_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+
+-- Create "reward_statuses" table
+CREATE TABLE "public"."reward_statuses" (
+  "id" serial NOT NULL,
+  "slug" text NOT NULL,
+  "name" text NOT NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "reward_statuses_slug_key" UNIQUE ("slug")
+);
+
+INSERT INTO reward_statuses (id, slug, name) VALUES
+    (1, 'pending', 'Ожидает проверки отчета'),
+    (2, 'approved', 'К выплате'),
+    (3, 'paid', 'Выплачено'),
+    (4, 'voided', 'Аннулировано');
+
+-- Create "rewards" table - начисления гостям: создаются при принятии задания, одобряются вместе с отчетом
+CREATE TABLE "public"."rewards" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "assignment_id" uuid NOT NULL,
+  "report_id" uuid NULL,
+  "user_id" uuid NULL,
+  "listing_id" uuid NOT NULL,
+  "listing_type_id" integer NOT NULL,
+  "amount" integer NOT NULL, -- в валюте брони, как pricing.total
+  "currency" text NOT NULL,
+  "percent" integer NOT NULL, -- доля стоимости брони на момент начисления
+  "status_id" integer NOT NULL DEFAULT 1,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "approved_at" timestamp NULL,
+  "approved_by" uuid NULL,
+  "paid_at" timestamp NULL,
+  "paid_by" uuid NULL,
+  "payout_reference" text NULL, -- номер платежного поручения/ваучера
+  "voided_at" timestamp NULL,
+  "voided_by" uuid NULL,
+  "void_reason" text NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "rewards_assignment_id_key" UNIQUE ("assignment_id"),
+  CONSTRAINT "rewards_amount_check" CHECK (amount >= 0),
+  CONSTRAINT "rewards_assignment_id_fkey" FOREIGN KEY ("assignment_id") REFERENCES "public"."assignments" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "rewards_report_id_fkey" FOREIGN KEY ("report_id") REFERENCES "public"."reports" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "rewards_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "rewards_listing_id_fkey" FOREIGN KEY ("listing_id") REFERENCES "public"."listings" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "rewards_status_id_fkey" FOREIGN KEY ("status_id") REFERENCES "public"."reward_statuses" ("id") ON UPDATE NO ACTION ON DELETE RESTRICT,
+  CONSTRAINT "rewards_approved_by_fkey" FOREIGN KEY ("approved_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "rewards_paid_by_fkey" FOREIGN KEY ("paid_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "rewards_voided_by_fkey" FOREIGN KEY ("voided_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+CREATE INDEX "rewards_user_id_created_at_idx" ON "public"."rewards" ("user_id", "created_at");
+CREATE INDEX "rewards_status_id_approved_at_idx" ON "public"."rewards" ("status_id", "approved_at");
+CREATE INDEX "rewards_report_id_idx" ON "public"."rewards" ("report_id");
+
+-- Начисления по уже принятым заданиям: одобренные отчеты - к выплате, незавершенные - ожидают проверки
+INSERT INTO rewards (assignment_id, report_id, user_id, listing_id, listing_type_id, amount, currency, percent, status_id, approved_at)
+SELECT
+    r.assignment_id, r.id, r.reporter_id, r.listing_id, l.listing_type_id,
+    (r.pricing->>'total')::integer, r.pricing->>'currency', 100,
+    CASE WHEN r.status_id = 5 THEN 2 ELSE 1 END,
+    CASE WHEN r.status_id = 5 THEN COALESCE(r.updated_at, r.created_at) END
+FROM reports r
+JOIN listings l ON l.id = r.listing_id
+WHERE r.status_id IN (1, 2, 3, 5, 7)
+  AND r.pricing ? 'total' AND r.pricing ? 'currency'
+ON CONFLICT (assignment_id) DO NOTHING;
+
+INSERT INTO permissions (slug, description) VALUES
+    ('rewards.view', 'Просмотр начислений тайным гостям'),
+    ('rewards.manage', 'Выплата и аннулирование начислений, выгрузка для бухгалтерии, настройка политики вознаграждений');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'rewards.view' WHERE r.name IN ('admin', 'moderator');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'rewards.manage' WHERE r.name = 'admin';
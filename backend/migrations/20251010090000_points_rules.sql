@@ -0,0 +1,92 @@
+-- Create "point_rules" table - веса событий, за которые гостю начисляются очки(отрицательные - штрафы)
+CREATE TABLE "public"."point_rules" (
+  "event_type" text NOT NULL,
+  "name" text NOT NULL,
+  "points" integer NOT NULL,
+  "grace_hours" integer NULL, -- для report_late: сколько часов после выезда отчет считается сданным вовремя
+  "updated_at" timestamp NULL,
+  "updated_by" uuid NULL,
+  PRIMARY KEY ("event_type"),
+  CONSTRAINT "point_rules_grace_hours_check" CHECK (grace_hours IS NULL OR grace_hours >= 0),
+  CONSTRAINT "point_rules_updated_by_fkey" FOREIGN KEY ("updated_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+
+-- Значения совпадают с прежним расчетом в коде, штрафы по умолчанию выключены
+INSERT INTO point_rules (event_type, name, points, grace_hours) VALUES
+    ('assignment_accepted', 'Принятие предложения', 10, NULL),
+    ('report_submitted', 'Сдача отчета на проверку', 5, NULL),
+    ('report_approved', 'Одобрение отчета', 20, NULL),
+    ('report_rejected', 'Отклонение отчета', 0, NULL),
+    ('report_late', 'Сдача отчета после срока', 0, 72);
+
+-- Create "rank_tiers" table - ранги гостей по сумме очков, названия на нескольких языках
+CREATE TABLE "public"."rank_tiers" (
+  "id" serial NOT NULL,
+  "slug" text NOT NULL,
+  "min_points" integer NOT NULL,
+  "names" jsonb NOT NULL, -- {"ru": "...", "en": "..."}
+  PRIMARY KEY ("id"),
+  CONSTRAINT "rank_tiers_slug_key" UNIQUE ("slug"),
+  CONSTRAINT "rank_tiers_min_points_key" UNIQUE ("min_points")
+);
+
+INSERT INTO rank_tiers (slug, min_points, names) VALUES
+    ('novice', 0, '{"ru": "Новичок", "en": "Novice"}'),
+    ('experienced', 100, '{"ru": "Опытный", "en": "Experienced"}'),
+    ('pro', 300, '{"ru": "Профи", "en": "Pro"}'),
+    ('master', 600, '{"ru": "Мастер", "en": "Master"}'),
+    ('legend', 1000, '{"ru": "Легенда", "en": "Legend"}');
+
+-- Create "point_events" table - история начислений очков, по ней считается сумма и объясняется ранг
+CREATE TABLE "public"."point_events" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "event_type" text NOT NULL,
+  "points" integer NOT NULL, -- вес правила на момент начисления или последнего пересчета
+  "assignment_id" uuid NULL,
+  "report_id" uuid NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "recomputed_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "point_events_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "point_events_event_type_fkey" FOREIGN KEY ("event_type") REFERENCES "public"."point_rules" ("event_type") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "point_events_assignment_id_fkey" FOREIGN KEY ("assignment_id") REFERENCES "public"."assignments" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "point_events_report_id_fkey" FOREIGN KEY ("report_id") REFERENCES "public"."reports" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+-- Событие одного типа по одному заданию начисляется один раз
+CREATE UNIQUE INDEX "point_events_user_id_event_type_assignment_id_idx" ON "public"."point_events" ("user_id", "event_type", "assignment_id");
+CREATE INDEX "point_events_user_id_created_at_idx" ON "public"."point_events" ("user_id", "created_at");
+
+-- История по уже существующим заданиям и отчетам
+INSERT INTO point_events (user_id, event_type, points, assignment_id, report_id, created_at)
+SELECT a.reporter_id, 'assignment_accepted', 10, a.id, r.id, a.accepted_at
+FROM assignments a
+LEFT JOIN reports r ON r.assignment_id = a.id
+WHERE a.accepted_at IS NOT NULL AND a.reporter_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO point_events (user_id, event_type, points, assignment_id, report_id, created_at)
+SELECT r.reporter_id, 'report_submitted', 5, r.assignment_id, r.id, r.submitted_at
+FROM reports r
+WHERE r.submitted_at IS NOT NULL AND r.reporter_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO point_events (user_id, event_type, points, assignment_id, report_id, created_at)
+SELECT r.reporter_id, 'report_late', 0, r.assignment_id, r.id, r.submitted_at
+FROM reports r
+WHERE r.submitted_at > r.checkout_date + INTERVAL '72 hours' AND r.reporter_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO point_events (user_id, event_type, points, assignment_id, report_id, created_at)
+SELECT r.reporter_id, CASE WHEN r.status_id = 5 THEN 'report_approved' ELSE 'report_rejected' END,
+       CASE WHEN r.status_id = 5 THEN 20 ELSE 0 END,
+       r.assignment_id, r.id, COALESCE(r.updated_at, r.submitted_at, r.created_at)
+FROM reports r
+WHERE r.status_id IN (5, 6) AND r.reporter_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+INSERT INTO permissions (slug, description) VALUES
+    ('points.manage', 'Настройка весов очков и рангов гостей, пересчет очков');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'points.manage' WHERE r.name = 'admin';
@@ -0,0 +1,49 @@
+-- Create "badges" table - значки гостей. Условие получения(rule) проверяется по истории событий гостя(point_events):
+-- {"metric": "cities", "op": ">=", "value": 10} или группа {"all": [...]} / {"any": [...]}
+CREATE TABLE "public"."badges" (
+  "id" serial NOT NULL,
+  "slug" text NOT NULL,
+  "names" jsonb NOT NULL, -- {"ru": "...", "en": "..."}
+  "descriptions" jsonb NULL,
+  "rule" jsonb NOT NULL,
+  "is_active" boolean NOT NULL DEFAULT true, -- неактивный значок не выдается, но остается у получивших
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "badges_slug_key" UNIQUE ("slug")
+);
+
+INSERT INTO badges (slug, names, descriptions, rule) VALUES
+    ('first_report',
+     '{"ru": "Первый отчет", "en": "First report"}',
+     '{"ru": "Первый одобренный отчет", "en": "First approved report"}',
+     '{"metric": "approved_reports", "op": ">=", "value": 1}'),
+    ('five_star_report',
+     '{"ru": "Отчет на пять звезд", "en": "Five-star report"}',
+     '{"ru": "Отчет одобрен и сдан вовремя", "en": "A report approved and submitted on time"}',
+     '{"metric": "on_time_approved_reports", "op": ">=", "value": 1}'),
+    ('ten_cities',
+     '{"ru": "10 городов", "en": "10 cities"}',
+     '{"ru": "Одобренные отчеты из 10 разных городов", "en": "Approved reports from 10 different cities"}',
+     '{"metric": "cities", "op": ">=", "value": 10}'),
+    ('no_rejections_streak',
+     '{"ru": "Без отклонений", "en": "No rejections in a row"}',
+     '{"ru": "5 одобренных отчетов подряд без отклонений", "en": "5 approved reports in a row without rejections"}',
+     '{"metric": "approved_streak", "op": ">=", "value": 5}');
+
+-- Create "user_badges" table - полученные значки
+CREATE TABLE "public"."user_badges" (
+  "user_id" uuid NOT NULL,
+  "badge_id" integer NOT NULL,
+  "earned_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("user_id", "badge_id"),
+  CONSTRAINT "user_badges_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "user_badges_badge_id_fkey" FOREIGN KEY ("badge_id") REFERENCES "public"."badges" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+CREATE INDEX "user_badges_badge_id_idx" ON "public"."user_badges" ("badge_id");
+
+INSERT INTO permissions (slug, description) VALUES
+    ('badges.manage', 'Настройка значков гостей и их перепроверка');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'badges.manage' WHERE r.name = 'admin';
@@ -0,0 +1,15 @@
+-- Create "user_points_daily" table - очки гостей по дням, обновляются вместе с point_events.
+-- По нему считается таблица лидеров за неделю, месяц и все время без суммирования всей истории событий
+CREATE TABLE "public"."user_points_daily" (
+  "user_id" uuid NOT NULL,
+  "day" date NOT NULL,
+  "points" integer NOT NULL,
+  PRIMARY KEY ("user_id", "day"),
+  CONSTRAINT "user_points_daily_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+CREATE INDEX "user_points_daily_day_idx" ON "public"."user_points_daily" ("day");
+
+INSERT INTO user_points_daily (user_id, day, points)
+SELECT user_id, created_at::date, SUM(points)
+FROM point_events
+GROUP BY user_id, created_at::date;
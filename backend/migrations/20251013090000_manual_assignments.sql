@@ -0,0 +1,26 @@
+-- Предложения, созданные персоналом вручную: без бронирования от OTA, можно сразу пригласить конкретного гостя
+ALTER TABLE "public"."assignments"
+  ADD COLUMN "created_by" uuid NULL, -- сотрудник, создавший предложение(NULL - из бронирования OTA)
+  ADD COLUMN "invited_at" timestamp NULL, -- дата приглашения гостя(reporter_id) персоналом
+  ADD COLUMN "accept_deadline" timestamp NULL, -- до какой даты приглашенный гость должен принять предложение
+  ADD CONSTRAINT "assignments_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL;
+
+-- У отчетов по ручным предложениям нет брони в OTA
+ALTER TABLE "public"."reports"
+  ALTER COLUMN "ota_id" DROP NOT NULL,
+  ALTER COLUMN "booking_number" DROP NOT NULL;
+
+-- Create "notifications" table - уведомления пользователей в приложении
+CREATE TABLE "public"."notifications" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "user_id" uuid NOT NULL,
+  "type" text NOT NULL, -- assignment.invited, ...
+  "title" text NOT NULL,
+  "body" text NOT NULL,
+  "payload" jsonb NULL, -- данные для перехода, например {"assignment_id": "..."}
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "read_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "notifications_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+CREATE INDEX "notifications_user_id_created_at_idx" ON "public"."notifications" ("user_id", "created_at");
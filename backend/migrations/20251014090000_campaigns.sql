@@ -0,0 +1,41 @@
+-- Create "campaigns" table - кампании проверок: цель, отбор объектов, квота, бюджет.
+-- Бронирования от OTA по подходящим объектам автоматически попадают в активную кампанию
+CREATE TABLE "public"."campaigns" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "name" text NOT NULL,
+  "purpose" text NOT NULL, -- подставляется в purpose предложений кампании
+  -- Отбор объектов: пустой список типов и NULL - без ограничения
+  "listing_type_ids" integer[] NOT NULL DEFAULT '{}',
+  "city" text NULL,
+  "not_inspected_days" integer NULL, -- объект без одобренных отчетов за последние N дней
+  "starts_at" date NOT NULL,
+  "ends_at" date NOT NULL, -- включительно, по дате заезда
+  "quota" integer NULL, -- максимум предложений в кампании
+  "budget" integer NULL, -- максимум суммы вознаграждений в валюте currency
+  "currency" text NULL,
+  "is_active" boolean NOT NULL DEFAULT true,
+  "created_by" uuid NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "campaigns_dates_check" CHECK (ends_at >= starts_at),
+  CONSTRAINT "campaigns_quota_check" CHECK (quota IS NULL OR quota > 0),
+  CONSTRAINT "campaigns_budget_check" CHECK (budget IS NULL OR (budget >= 0 AND currency IS NOT NULL)),
+  CONSTRAINT "campaigns_not_inspected_days_check" CHECK (not_inspected_days IS NULL OR not_inspected_days > 0),
+  CONSTRAINT "campaigns_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+
+ALTER TABLE "public"."assignments"
+  ADD COLUMN "campaign_id" uuid NULL,
+  ADD CONSTRAINT "assignments_campaign_id_fkey" FOREIGN KEY ("campaign_id") REFERENCES "public"."campaigns" ("id") ON UPDATE NO ACTION ON DELETE SET NULL;
+CREATE INDEX "assignments_campaign_id_idx" ON "public"."assignments" ("campaign_id");
+
+INSERT INTO permissions (slug, description) VALUES
+    ('campaigns.view', 'Просмотр кампаний проверок и их прогресса'),
+    ('campaigns.manage', 'Создание и изменение кампаний проверок');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'campaigns.view' WHERE r.name IN ('admin', 'moderator');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'campaigns.manage' WHERE r.name = 'admin';
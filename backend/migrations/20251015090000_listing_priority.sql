@@ -0,0 +1,37 @@
+-- Оценка качества объекта модератором при одобрении отчета: 0 - очень плохо, 100 - отлично
+ALTER TABLE "public"."reports"
+  ADD COLUMN "quality_score" smallint NULL,
+  ADD CONSTRAINT "reports_quality_score_check" CHECK (quality_score IS NULL OR quality_score BETWEEN 0 AND 100);
+
+-- Вес типа объекта в приоритете проверки, в процентах
+ALTER TABLE "public"."listing_types"
+  ADD COLUMN "priority_weight" integer NOT NULL DEFAULT 100,
+  ADD CONSTRAINT "listing_types_priority_weight_check" CHECK (priority_weight BETWEEN 0 AND 1000);
+
+-- Create "listing_complaints" table - жалобы гостей на объект, которые передает OTA
+CREATE TABLE "public"."listing_complaints" (
+  "id" uuid NOT NULL DEFAULT gen_random_uuid(),
+  "listing_id" uuid NOT NULL,
+  "external_id" text NULL, -- идентификатор жалобы в OTA, повторная отправка не создает дубль
+  "category" text NOT NULL,
+  "comment" text NULL,
+  "received_at" timestamp NOT NULL, -- когда жалоба поступила в OTA
+  "created_by" uuid NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "listing_complaints_external_id_key" UNIQUE ("external_id"),
+  CONSTRAINT "listing_complaints_listing_id_fkey" FOREIGN KEY ("listing_id") REFERENCES "public"."listings" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "listing_complaints_created_by_fkey" FOREIGN KEY ("created_by") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+CREATE INDEX "listing_complaints_listing_id_received_at_idx" ON "public"."listing_complaints" ("listing_id", "received_at");
+
+INSERT INTO permissions (slug, description) VALUES
+    ('listings.view', 'Просмотр объектов с приоритетом проверки'),
+    ('listings.priority.manage', 'Изменение весов типов объектов в приоритете проверки'),
+    ('complaints.create', 'Передача жалоб на объекты(интеграция OTA)');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug = 'listings.view' WHERE r.name IN ('admin', 'moderator');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.slug IN ('listings.priority.manage', 'complaints.create') WHERE r.name = 'admin';
@@ -0,0 +1,22 @@
+-- Create "decline_reasons" table - причины отказа гостя от предложения
+CREATE TABLE "public"."decline_reasons" (
+  "id" serial NOT NULL,
+  "slug" text NOT NULL,
+  "name" text NOT NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "decline_reasons_slug_key" UNIQUE ("slug")
+);
+
+INSERT INTO decline_reasons (id, slug, name) VALUES
+    (1, 'dates', 'Не подходят даты'),
+    (2, 'location', 'Не подходит расположение'),
+    (3, 'listing_type', 'Не подходит тип объекта'),
+    (4, 'other', 'Другое');
+
+-- Причина отказа; NULL - не указана(отказы до появления причин и без тела запроса)
+ALTER TABLE "public"."assignment_declines"
+  ADD COLUMN "reason_id" integer NULL,
+  ADD COLUMN "comment" text NULL,
+  ADD CONSTRAINT "assignment_declines_reason_id_fkey" FOREIGN KEY ("reason_id") REFERENCES "public"."decline_reasons" ("id") ON UPDATE NO ACTION ON DELETE SET NULL;
+CREATE INDEX "assignment_declines_assignment_id_idx" ON "public"."assignment_declines" ("assignment_id");
+CREATE INDEX "assignment_declines_declined_at_idx" ON "public"."assignment_declines" ("declined_at");
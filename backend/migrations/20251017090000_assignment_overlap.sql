@@ -0,0 +1,10 @@
+-- Гость не может держать предложения(Offered или Accepted) с пересекающимися датами проживания.
+-- Выезд и заезд в один день не считаются пересечением(полуинтервал [checkin_date, checkout_date)).
+-- Если в данных уже есть пересечения, их нужно разрешить(отменить лишние предложения) до применения миграции
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE "public"."assignments"
+  ADD CONSTRAINT "assignments_reporter_stay_excl" EXCLUDE USING gist (
+    "reporter_id" WITH =,
+    tsrange("checkin_date", "checkout_date") WITH &&
+  ) WHERE ("reporter_id" IS NOT NULL AND "status_id" IN (1, 2)); -- Offered, Accepted
@@ -0,0 +1,4 @@
+-- У гостя не больше одного предложения в статусе Offered(взятое или приглашение). Проверки в коде выполняются
+-- под блокировкой гостя, индекс - страховка от гонок параллельных запросов
+CREATE UNIQUE INDEX "assignments_reporter_offered_key" ON "public"."assignments" ("reporter_id")
+  WHERE "reporter_id" IS NOT NULL AND "status_id" = 1; -- Offered
@@ -0,0 +1,12 @@
+-- Лист ожидания предложений, закрепленных за другим гостем. Когда предложение освобождается(отказ, истек срок принятия,
+-- персонал снял гостя), оно закрепляется за первым подходящим гостем из листа со своим сроком принятия
+CREATE TABLE "public"."assignment_waitlist" (
+  "assignment_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP, -- очередность
+  PRIMARY KEY ("assignment_id", "user_id"),
+  CONSTRAINT "assignment_waitlist_assignment_id_fkey" FOREIGN KEY ("assignment_id") REFERENCES "public"."assignments" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "assignment_waitlist_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+CREATE INDEX "assignment_waitlist_assignment_id_created_at_idx" ON "public"."assignment_waitlist" ("assignment_id", "created_at");
+CREATE INDEX "assignment_waitlist_user_id_idx" ON "public"."assignment_waitlist" ("user_id");
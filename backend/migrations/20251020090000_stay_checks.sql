@@ -0,0 +1,11 @@
+-- Подтверждение проживания: гость отмечает заезд и выезд с координатами устройства.
+-- distance_m - расстояние до объекта в метрах(NULL - у объекта нет координат, расстояние не проверялось)
+ALTER TABLE "public"."assignments"
+  ADD COLUMN "checked_in_at" timestamp NULL, -- время заезда по часам устройства
+  ADD COLUMN "checked_in_latitude" double precision NULL,
+  ADD COLUMN "checked_in_longitude" double precision NULL,
+  ADD COLUMN "checked_in_distance_m" double precision NULL,
+  ADD COLUMN "checked_out_at" timestamp NULL, -- время выезда по часам устройства
+  ADD COLUMN "checked_out_latitude" double precision NULL,
+  ADD COLUMN "checked_out_longitude" double precision NULL,
+  ADD COLUMN "checked_out_distance_m" double precision NULL;
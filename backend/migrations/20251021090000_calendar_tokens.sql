@@ -0,0 +1,10 @@
+-- Секретные токены календарной ленты(.ics) принятых предложений гостя. Один токен на пользователя,
+-- перевыпуск заменяет старый - прежняя ссылка перестает работать
+CREATE TABLE "public"."calendar_tokens" (
+  "user_id" uuid NOT NULL,
+  "token_hash" text NOT NULL, -- sha256 от токена(сам токен не хранится)
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("user_id"),
+  CONSTRAINT "calendar_tokens_token_hash_key" UNIQUE ("token_hash"),
+  CONSTRAINT "calendar_tokens_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
@@ -0,0 +1,18 @@
+-- Срок сдачи отчета: due_at = дата выезда + REPORT_DEADLINE_HOURS_AFTER_CHECKOUT, задается при принятии предложения.
+-- Черновик, не сданный к сроку, переводится в статус "Просрочен" с аннулированием начисления и штрафом report_overdue
+ALTER TABLE "public"."reports"
+  ADD COLUMN "due_at" timestamp NULL,
+  ADD COLUMN "due_reminders_sent" integer NOT NULL DEFAULT 0, -- сколько напоминаний о сроке уже отправлено
+  ADD COLUMN "overdue_at" timestamp NULL;
+
+-- Срок для уже созданных отчетов проставляет сервис при запуске задачи сроков(BackfillReportDueDates) по тому же
+-- REPORT_DEADLINE_HOURS_AFTER_CHECKOUT, что и для новых отчетов: миграция не знает значения из окружения
+
+-- Поиск черновиков с подходящим сроком
+CREATE INDEX "reports_draft_due_at_idx" ON "public"."reports" ("due_at") WHERE status_id = 2;
+
+INSERT INTO report_statuses (id, slug, name) VALUES
+    (8, 'overdue', 'Просрочен');
+
+INSERT INTO point_rules (event_type, name, points, grace_hours) VALUES
+    ('report_overdue', 'Отчет не сдан в срок', -10, NULL);
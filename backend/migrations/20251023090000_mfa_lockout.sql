@@ -0,0 +1,7 @@
+-- Защита второго шага входа от перебора кодов: неверные коды считаются по пользователю,
+-- после MFA_MAX_FAILED_ATTEMPTS подряд ввод кодов блокируется на MFA_LOCKOUT_SECONDS,
+-- а все выданные до блокировки промежуточные токены(mfa_challenge) перестают действовать
+ALTER TABLE "public"."user_totp"
+  ADD COLUMN "failed_attempts" integer NOT NULL DEFAULT 0, -- неверных кодов подряд с последнего успешного ввода или блокировки
+  ADD COLUMN "locked_until" timestamp NULL,
+  ADD COLUMN "challenges_valid_after" timestamp NULL; -- промежуточные токены, выданные не позже этого момента, недействительны
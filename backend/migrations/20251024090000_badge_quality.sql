@@ -0,0 +1,14 @@
+-- Значок five_star_report выдавался за одобренный отчет, сданный вовремя, - оценка качества в нем не участвовала.
+-- Переименовываем его по смыслу(у получивших он остается), а "пять звезд" теперь выдается по оценке качества
+-- модератором(reports.quality_score, показатель max_quality_score)
+UPDATE badges
+SET slug = 'on_time_report',
+    names = '{"ru": "Точно в срок", "en": "On time"}',
+    updated_at = CURRENT_TIMESTAMP
+WHERE slug = 'five_star_report';
+
+INSERT INTO badges (slug, names, descriptions, rule) VALUES
+    ('five_star_report',
+     '{"ru": "Отчет на пять звезд", "en": "Five-star report"}',
+     '{"ru": "Одобренный отчет с оценкой качества объекта от 90", "en": "An approved report with a listing quality score of 90 or more"}',
+     '{"metric": "max_quality_score", "op": ">=", "value": 90}');
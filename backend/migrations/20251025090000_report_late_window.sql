@@ -0,0 +1,8 @@
+-- Срок сдачи отчета один - reports.due_at. grace_hours правила report_late теперь считается от него:
+-- столько часов после срока черновик еще можно сдать со штрафом report_late, после этого он переводится в "Просрочен"
+-- со штрафом report_overdue. 24 часа при сроке по умолчанию(48 часов после выезда) сохраняют прежнюю границу
+-- опоздания - 72 часа после выезда
+UPDATE point_rules
+SET grace_hours = 24,
+    updated_at = CURRENT_TIMESTAMP
+WHERE event_type = 'report_late';